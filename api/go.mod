@@ -3,9 +3,18 @@ module git.neds.sh/matty/entain/api
 go 1.16
 
 require (
+	github.com/BurntSushi/toml v0.3.1
+	github.com/andybalholm/brotli v1.0.4
+	github.com/golang-jwt/jwt/v4 v4.5.2
+	github.com/google/go-cmp v0.5.4
 	github.com/grpc-ecosystem/grpc-gateway/v2 v2.3.0
+	github.com/jhump/protoreflect v1.9.0
+	github.com/prometheus/client_golang v1.9.0
+	github.com/sony/gobreaker v0.5.0
+	go.uber.org/zap v1.16.0
 	google.golang.org/genproto v0.0.0-20210226172003-ab064af71705
-	google.golang.org/grpc v1.36.0
+	google.golang.org/grpc v1.42.0
 	google.golang.org/grpc/cmd/protoc-gen-go-grpc v1.1.0
 	google.golang.org/protobuf v1.25.1-0.20201208041424-160c7477e0e8
+	gopkg.in/yaml.v3 v3.0.1
 )
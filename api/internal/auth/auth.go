@@ -0,0 +1,232 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"git.neds.sh/matty/entain/api/internal/config"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Metadata keys used to forward the caller identity to the backends.
+// They must match the keys read by the backend auth interceptors.
+const (
+	MetadataSubject = "x-auth-subject"
+	MetadataRoles   = "x-auth-roles"
+)
+
+// signingMethods are the accepted token algorithms. HMAC and "none" are
+// deliberately excluded so a public key can never be used as a shared secret.
+var signingMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+
+// Identity describes an authenticated caller.
+type Identity struct {
+	Subject string
+	Roles   []string
+}
+
+type identityKey struct{}
+
+// NewContext returns a copy of ctx carrying the identity.
+func NewContext(ctx context.Context, id Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, id)
+}
+
+// FromContext returns the identity stored in ctx. ok is false for anonymous callers.
+func FromContext(ctx context.Context) (id Identity, ok bool) {
+	id, ok = ctx.Value(identityKey{}).(Identity)
+	return id, ok
+}
+
+// Authenticator validates bearer tokens against the keys of a JWKS file.
+type Authenticator struct {
+	keys       map[string]crypto.PublicKey
+	issuer     string
+	audience   string
+	rolesClaim string
+	logger     *zap.Logger
+}
+
+// NewAuthenticator loads the signing keys named by cfg. When cfg.JWKSFile is
+// empty authentication is disabled: bearer tokens are ignored and every caller
+// is anonymous.
+func NewAuthenticator(cfg config.AuthConfig, logger *zap.Logger) (*Authenticator, error) {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	a := &Authenticator{
+		issuer:     cfg.Issuer,
+		audience:   cfg.Audience,
+		rolesClaim: cfg.RolesClaim,
+		logger:     logger,
+	}
+
+	if cfg.JWKSFile != "" {
+		keys, err := LoadJWKS(cfg.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		a.keys = keys
+	}
+
+	return a, nil
+}
+
+// Enabled reports whether bearer tokens are validated.
+func (a *Authenticator) Enabled() bool {
+	return a.keys != nil
+}
+
+// Authenticate validates a raw JWT and returns the identity it carries.
+func (a *Authenticator) Authenticate(token string) (Identity, error) {
+	parser := jwt.NewParser(jwt.WithValidMethods(signingMethods))
+
+	claims := jwt.MapClaims{}
+	if _, err := parser.ParseWithClaims(token, claims, a.keyFunc); err != nil {
+		return Identity{}, err
+	}
+
+	// MapClaims.Valid only checks exp when present; tokens must expire.
+	if _, ok := claims["exp"]; !ok {
+		return Identity{}, errors.New("token has no expiry")
+	}
+	if a.issuer != "" && !claims.VerifyIssuer(a.issuer, true) {
+		return Identity{}, errors.New("token has an unexpected issuer")
+	}
+	if a.audience != "" && !claims.VerifyAudience(a.audience, true) {
+		return Identity{}, errors.New("token has an unexpected audience")
+	}
+
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		return Identity{}, errors.New("token has no subject")
+	}
+
+	roles, err := parseRoles(claims[a.rolesClaim])
+	if err != nil {
+		return Identity{}, fmt.Errorf("invalid %s claim: %w", a.rolesClaim, err)
+	}
+
+	return Identity{Subject: subject, Roles: roles}, nil
+}
+
+// keyFunc selects the verification key by the token's kid header. Tokens
+// without a kid are accepted only when the JWKS holds a single key.
+func (a *Authenticator) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" && len(a.keys) == 1 {
+		for _, key := range a.keys {
+			return key, nil
+		}
+	}
+
+	key, ok := a.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key ID %q", kid)
+	}
+	return key, nil
+}
+
+// parseRoles accepts either a JSON array of strings or a space separated string.
+func parseRoles(claim interface{}) ([]string, error) {
+	switch v := claim.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return strings.Fields(v), nil
+	case []interface{}:
+		roles := make([]string, 0, len(v))
+		for _, r := range v {
+			role, ok := r.(string)
+			if !ok {
+				return nil, fmt.Errorf("role %v is not a string", r)
+			}
+			roles = append(roles, role)
+		}
+		return roles, nil
+	default:
+		return nil, fmt.Errorf("unexpected type %T", claim)
+	}
+}
+
+// Middleware authenticates requests before they reach next. Requests without
+// an Authorization header proceed anonymously; requests with an invalid token
+// are rejected with 401 through the mux's error handler. Client supplied
+// identity metadata headers are always removed so they cannot be spoofed.
+func (a *Authenticator) Middleware(mux *runtime.ServeMux, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stripIdentityHeaders(r.Header)
+
+		header := r.Header.Get("Authorization")
+		if header == "" || !a.Enabled() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		token, ok := bearerToken(header)
+		if !ok {
+			a.reject(mux, w, r, "authorization header must use the Bearer scheme")
+			return
+		}
+
+		id, err := a.Authenticate(token)
+		if err != nil {
+			a.logger.Debug("Rejected bearer token", zap.Error(err))
+			a.reject(mux, w, r, "invalid bearer token")
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), id)))
+	})
+}
+
+// reject writes a 401 response with a WWW-Authenticate challenge.
+func (a *Authenticator) reject(mux *runtime.ServeMux, w http.ResponseWriter, r *http.Request, msg string) {
+	w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer error="invalid_token", error_description=%q`, msg))
+	runtime.HTTPError(r.Context(), mux, &runtime.JSONPb{}, w, r, status.Error(codes.Unauthenticated, msg))
+}
+
+// Metadata is a runtime.WithMetadata annotator forwarding the identity
+// established by Middleware to the backends.
+func Metadata(ctx context.Context, r *http.Request) metadata.MD {
+	id, ok := FromContext(r.Context())
+	if !ok {
+		return nil
+	}
+
+	md := metadata.Pairs(MetadataSubject, id.Subject)
+	if len(id.Roles) > 0 {
+		md.Set(MetadataRoles, id.Roles...)
+	}
+	return md
+}
+
+// bearerToken extracts the token from an Authorization header value.
+func bearerToken(header string) (string, bool) {
+	const prefix = "bearer "
+	if len(header) <= len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+		return "", false
+	}
+	return strings.TrimSpace(header[len(prefix):]), true
+}
+
+// stripIdentityHeaders removes headers that the gateway would otherwise
+// forward as identity metadata.
+func stripIdentityHeaders(h http.Header) {
+	prefix := strings.ToLower(runtime.MetadataHeaderPrefix + "x-auth-")
+	for key := range h {
+		if strings.HasPrefix(strings.ToLower(key), prefix) {
+			h.Del(key)
+		}
+	}
+}
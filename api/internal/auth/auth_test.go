@@ -0,0 +1,369 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"git.neds.sh/matty/entain/api/internal/config"
+	"git.neds.sh/matty/entain/api/proto/racing"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/go-cmp/cmp"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	testIssuer   = "https://auth.example.com/"
+	testAudience = "entain-api"
+)
+
+// testKeys holds the signing keys published in the test JWKS
+type testKeys struct {
+	rsa  *rsa.PrivateKey
+	ec   *ecdsa.PrivateKey
+	path string
+}
+
+// newTestKeys generates an RSA and an EC key and writes their public halves to a JWKS file
+func newTestKeys(t *testing.T) *testKeys {
+	t.Helper()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("newTestKeys() failed to generate RSA key: %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("newTestKeys() failed to generate EC key: %v", err)
+	}
+
+	b64 := func(i *big.Int) string { return base64.RawURLEncoding.EncodeToString(i.Bytes()) }
+	set := map[string]interface{}{
+		"keys": []map[string]string{
+			{"kty": "RSA", "kid": "rsa-1", "use": "sig", "n": b64(rsaKey.N), "e": b64(big.NewInt(int64(rsaKey.E)))},
+			{"kty": "EC", "kid": "ec-1", "crv": "P-256", "x": b64(ecKey.X), "y": b64(ecKey.Y)},
+			{"kty": "RSA", "kid": "enc-1", "use": "enc", "n": "ignored", "e": "ignored"},
+		},
+	}
+	data, err := json.Marshal(set)
+	if err != nil {
+		t.Fatalf("newTestKeys() failed to marshal JWKS: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("newTestKeys() failed to write JWKS: %v", err)
+	}
+
+	return &testKeys{rsa: rsaKey, ec: ecKey, path: path}
+}
+
+// validClaims returns claims accepted by an authenticator using testIssuer and testAudience
+func validClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub":   "user-1",
+		"iss":   testIssuer,
+		"aud":   testAudience,
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"roles": []string{"trader"},
+	}
+}
+
+// sign signs claims with the given method and key, setting kid when not empty
+func sign(t *testing.T, method jwt.SigningMethod, key interface{}, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("sign() failed: %v", err)
+	}
+	return signed
+}
+
+func newTestAuthenticator(t *testing.T, keys *testKeys) *Authenticator {
+	t.Helper()
+
+	a, err := NewAuthenticator(config.AuthConfig{
+		JWKSFile:   keys.path,
+		Issuer:     testIssuer,
+		Audience:   testAudience,
+		RolesClaim: "roles",
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewAuthenticator() failed: %v", err)
+	}
+	return a
+}
+
+func TestAuthenticator_Authenticate(t *testing.T) {
+	keys := newTestKeys(t)
+	a := newTestAuthenticator(t, keys)
+
+	with := func(modify func(c jwt.MapClaims)) jwt.MapClaims {
+		c := validClaims()
+		modify(c)
+		return c
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		want    Identity
+		wantErr bool
+	}{
+		{
+			name:  "RSA signed",
+			token: sign(t, jwt.SigningMethodRS256, keys.rsa, "rsa-1", validClaims()),
+			want:  Identity{Subject: "user-1", Roles: []string{"trader"}},
+		},
+		{
+			name:  "EC signed",
+			token: sign(t, jwt.SigningMethodES256, keys.ec, "ec-1", validClaims()),
+			want:  Identity{Subject: "user-1", Roles: []string{"trader"}},
+		},
+		{
+			name:  "space separated roles",
+			token: sign(t, jwt.SigningMethodRS256, keys.rsa, "rsa-1", with(func(c jwt.MapClaims) { c["roles"] = "trader auditor" })),
+			want:  Identity{Subject: "user-1", Roles: []string{"trader", "auditor"}},
+		},
+		{
+			name:  "no roles",
+			token: sign(t, jwt.SigningMethodRS256, keys.rsa, "rsa-1", with(func(c jwt.MapClaims) { delete(c, "roles") })),
+			want:  Identity{Subject: "user-1"},
+		},
+		{
+			name:    "unknown key ID",
+			token:   sign(t, jwt.SigningMethodRS256, keys.rsa, "rsa-2", validClaims()),
+			wantErr: true,
+		},
+		{
+			name:    "missing key ID with several keys",
+			token:   sign(t, jwt.SigningMethodRS256, keys.rsa, "", validClaims()),
+			wantErr: true,
+		},
+		{
+			name:    "signed with the wrong key",
+			token:   sign(t, jwt.SigningMethodES256, keys.ec, "rsa-1", validClaims()),
+			wantErr: true,
+		},
+		{
+			name:    "HMAC is rejected",
+			token:   sign(t, jwt.SigningMethodHS256, []byte("secret"), "rsa-1", validClaims()),
+			wantErr: true,
+		},
+		{
+			name:    "expired",
+			token:   sign(t, jwt.SigningMethodRS256, keys.rsa, "rsa-1", with(func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Minute).Unix() })),
+			wantErr: true,
+		},
+		{
+			name:    "no expiry",
+			token:   sign(t, jwt.SigningMethodRS256, keys.rsa, "rsa-1", with(func(c jwt.MapClaims) { delete(c, "exp") })),
+			wantErr: true,
+		},
+		{
+			name:    "wrong issuer",
+			token:   sign(t, jwt.SigningMethodRS256, keys.rsa, "rsa-1", with(func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com/" })),
+			wantErr: true,
+		},
+		{
+			name:    "wrong audience",
+			token:   sign(t, jwt.SigningMethodRS256, keys.rsa, "rsa-1", with(func(c jwt.MapClaims) { c["aud"] = "other-api" })),
+			wantErr: true,
+		},
+		{
+			name:    "no subject",
+			token:   sign(t, jwt.SigningMethodRS256, keys.rsa, "rsa-1", with(func(c jwt.MapClaims) { delete(c, "sub") })),
+			wantErr: true,
+		},
+		{
+			name:    "malformed roles",
+			token:   sign(t, jwt.SigningMethodRS256, keys.rsa, "rsa-1", with(func(c jwt.MapClaims) { c["roles"] = 42 })),
+			wantErr: true,
+		},
+		{
+			name:    "garbage",
+			token:   "not.a.token",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := a.Authenticate(tt.token)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Authenticate() = %+v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Authenticate() failed: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("Authenticate() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestLoadJWKS_Errors(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		name string
+		path string
+	}{
+		{"missing file", filepath.Join(dir, "missing.json")},
+		{"invalid JSON", write("invalid.json", "{")},
+		{"no keys", write("empty.json", `{"keys":[]}`)},
+		{"unsupported key type", write("oct.json", `{"keys":[{"kty":"oct","k":"c2VjcmV0"}]}`)},
+		{"unsupported curve", write("curve.json", `{"keys":[{"kty":"EC","crv":"P-224","x":"AQ","y":"AQ"}]}`)},
+		{"point not on curve", write("point.json", `{"keys":[{"kty":"EC","crv":"P-256","x":"AQ","y":"AQ"}]}`)},
+		{"missing modulus", write("rsa.json", `{"keys":[{"kty":"RSA","e":"AQAB"}]}`)},
+		{"duplicate key ID", write("dup.json", `{"keys":[{"kty":"RSA","kid":"a","n":"AQAB","e":"AQAB"},{"kty":"RSA","kid":"a","n":"AQAB","e":"AQAB"}]}`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadJWKS(tt.path); err == nil {
+				t.Errorf("LoadJWKS(%s) returned no error", tt.path)
+			}
+		})
+	}
+}
+
+// metadataRacingServer records the incoming metadata of GetRace calls
+type metadataRacingServer struct {
+	racing.UnimplementedRacingServer
+	md metadata.MD
+}
+
+func (s *metadataRacingServer) GetRace(ctx context.Context, in *racing.GetRaceRequest) (*racing.GetRaceResponse, error) {
+	s.md, _ = metadata.FromIncomingContext(ctx)
+	return &racing.GetRaceResponse{Race: &racing.Race{Id: in.Id}}, nil
+}
+
+func TestAuthenticator_Middleware(t *testing.T) {
+	keys := newTestKeys(t)
+	token := sign(t, jwt.SigningMethodRS256, keys.rsa, "rsa-1", validClaims())
+
+	tests := []struct {
+		name          string
+		disabled      bool
+		headers       map[string]string
+		wantStatus    int
+		wantChallenge bool
+		wantSubject   []string
+		wantRoles     []string
+	}{
+		{
+			name:       "anonymous",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:        "valid token",
+			headers:     map[string]string{"Authorization": "Bearer " + token},
+			wantStatus:  http.StatusOK,
+			wantSubject: []string{"user-1"},
+			wantRoles:   []string{"trader"},
+		},
+		{
+			name:          "invalid token",
+			headers:       map[string]string{"Authorization": "Bearer " + token + "x"},
+			wantStatus:    http.StatusUnauthorized,
+			wantChallenge: true,
+		},
+		{
+			name:          "wrong scheme",
+			headers:       map[string]string{"Authorization": "Basic dXNlcjpwYXNz"},
+			wantStatus:    http.StatusUnauthorized,
+			wantChallenge: true,
+		},
+		{
+			name: "spoofed identity headers are dropped",
+			headers: map[string]string{
+				"Grpc-Metadata-X-Auth-Subject": "admin",
+				"Grpc-Metadata-X-Auth-Roles":   "trader",
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:     "disabled authentication ignores tokens and spoofed headers",
+			disabled: true,
+			headers: map[string]string{
+				"Authorization":              "Bearer " + token,
+				"Grpc-Metadata-X-Auth-Roles": "trader",
+			},
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.AuthConfig{JWKSFile: keys.path, RolesClaim: "roles"}
+			if tt.disabled {
+				cfg.JWKSFile = ""
+			}
+			a, err := NewAuthenticator(cfg, nil)
+			if err != nil {
+				t.Fatalf("NewAuthenticator() failed: %v", err)
+			}
+
+			server := &metadataRacingServer{}
+			mux := runtime.NewServeMux(runtime.WithMetadata(Metadata))
+			if err := racing.RegisterRacingHandlerServer(context.Background(), mux, server); err != nil {
+				t.Fatalf("RegisterRacingHandlerServer() failed: %v", err)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/v1/races/1", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			a.Middleware(mux, mux).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body: %s)", rec.Code, tt.wantStatus, rec.Body)
+			}
+			challenge := rec.Header().Get("WWW-Authenticate")
+			if got := strings.HasPrefix(challenge, "Bearer "); got != tt.wantChallenge {
+				t.Errorf("WWW-Authenticate = %q, want challenge %t", challenge, tt.wantChallenge)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			if diff := cmp.Diff(tt.wantSubject, server.md.Get(MetadataSubject)); diff != "" {
+				t.Errorf("forwarded subject mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.wantRoles, server.md.Get(MetadataRoles)); diff != "" {
+				t.Errorf("forwarded roles mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
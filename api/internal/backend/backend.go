@@ -0,0 +1,215 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"git.neds.sh/matty/entain/api/internal/config"
+	"git.neds.sh/matty/entain/api/internal/lb"
+	"github.com/sony/gobreaker"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	// Registers the client side of the gRPC health checking protocol
+	_ "google.golang.org/grpc/health"
+	"google.golang.org/grpc/status"
+)
+
+// DialOptions returns the options bounding calls to the backend called name:
+// per-method deadlines, a retry policy for the idempotent methods listed in
+// retryMethods (full names such as "/racing.Racing/GetRace") and, when
+// enabled, a circuit breaker that fails fast with UNAVAILABLE, which the
+// gateway maps to 503. Calls are spread across the backend's replicas by the
+// configured load balancing policy; dial lb.Target(endpoint) to use them.
+func DialOptions(name string, cfg config.BackendsConfig, retryMethods []string, metrics *Metrics, logger *zap.Logger) ([]grpc.DialOption, error) {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	serviceConfig, err := ServiceConfig(cfg, retryMethods)
+	if err != nil {
+		return nil, err
+	}
+
+	interceptors := []grpc.UnaryClientInterceptor{
+		TimeoutInterceptor(cfg.Timeout, cfg.MethodTimeouts),
+	}
+	if cfg.Breaker.Enabled {
+		interceptors = append(interceptors, NewBreaker(name, cfg.Breaker, metrics, logger).UnaryClientInterceptor())
+	}
+
+	return []grpc.DialOption{
+		grpc.WithDefaultServiceConfig(serviceConfig),
+		grpc.WithChainUnaryInterceptor(interceptors...),
+		grpc.WithResolvers(lb.Resolvers(cfg.LoadBalancing.RefreshInterval, logger)...),
+	}, nil
+}
+
+// methodName is a method selector of the gRPC service config.
+type methodName struct {
+	Service string `json:"service"`
+	Method  string `json:"method"`
+}
+
+// retryPolicy is the retryPolicy object of the gRPC service config.
+type retryPolicy struct {
+	MaxAttempts          int      `json:"maxAttempts"`
+	InitialBackoff       string   `json:"initialBackoff"`
+	MaxBackoff           string   `json:"maxBackoff"`
+	BackoffMultiplier    float64  `json:"backoffMultiplier"`
+	RetryableStatusCodes []string `json:"retryableStatusCodes"`
+}
+
+// healthCheckConfig is the healthCheckConfig object of the gRPC service
+// config. The empty service name checks the health of the whole server.
+type healthCheckConfig struct {
+	ServiceName string `json:"serviceName"`
+}
+
+// ServiceConfig renders a gRPC service config selecting the load balancing
+// policy, health checking of replicas, and retries of methods on UNAVAILABLE.
+// Only UNAVAILABLE is retried: it means the call never reached a healthy
+// server, whereas DEADLINE_EXCEEDED would multiply load on a slow one.
+func ServiceConfig(cfg config.BackendsConfig, methods []string) (string, error) {
+	type methodConfig struct {
+		Name        []methodName `json:"name"`
+		RetryPolicy *retryPolicy `json:"retryPolicy,omitempty"`
+	}
+	var sc struct {
+		LoadBalancingConfig []map[string]struct{} `json:"loadBalancingConfig,omitempty"`
+		HealthCheckConfig   *healthCheckConfig    `json:"healthCheckConfig,omitempty"`
+		MethodConfig        []methodConfig        `json:"methodConfig,omitempty"`
+	}
+
+	if policy := cfg.LoadBalancing.Policy; policy != "" {
+		sc.LoadBalancingConfig = []map[string]struct{}{{policy: {}}}
+		// pick_first ignores health checks
+		if cfg.LoadBalancing.HealthCheck && policy != config.PolicyPickFirst {
+			sc.HealthCheckConfig = &healthCheckConfig{}
+		}
+	}
+
+	if retry := cfg.Retry; retry.MaxAttempts > 1 && len(methods) > 0 {
+		mc := methodConfig{
+			RetryPolicy: &retryPolicy{
+				MaxAttempts:          retry.MaxAttempts,
+				InitialBackoff:       durationString(retry.InitialBackoff),
+				MaxBackoff:           durationString(retry.MaxBackoff),
+				BackoffMultiplier:    retry.BackoffMultiplier,
+				RetryableStatusCodes: []string{"UNAVAILABLE"},
+			},
+		}
+		for _, method := range methods {
+			parts := strings.Split(strings.TrimPrefix(method, "/"), "/")
+			if len(parts) != 2 {
+				return "", fmt.Errorf("invalid method name %q", method)
+			}
+			mc.Name = append(mc.Name, methodName{Service: parts[0], Method: parts[1]})
+		}
+		sc.MethodConfig = append(sc.MethodConfig, mc)
+	}
+
+	out, err := json.Marshal(sc)
+	if err != nil {
+		return "", fmt.Errorf("failed to render service config: %w", err)
+	}
+	return string(out), nil
+}
+
+// durationString formats d as the service config expects, e.g. "0.1s".
+func durationString(d time.Duration) string {
+	return fmt.Sprintf("%gs", d.Seconds())
+}
+
+// TimeoutInterceptor bounds each call by the timeout of its method, falling
+// back to defaultTimeout. A shorter deadline already set by the caller wins.
+func TimeoutInterceptor(defaultTimeout time.Duration, methodTimeouts map[string]time.Duration) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		timeout := defaultTimeout
+		if t, ok := methodTimeouts[method]; ok {
+			timeout = t
+		}
+
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// Breaker is a circuit breaker guarding one backend.
+type Breaker struct {
+	name    string
+	cb      *gobreaker.CircuitBreaker
+	metrics *Metrics
+}
+
+// NewBreaker creates a closed circuit breaker for the backend called name.
+// A nil metrics disables instrumentation.
+func NewBreaker(name string, cfg config.BreakerConfig, metrics *Metrics, logger *zap.Logger) *Breaker {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	b := &Breaker{name: name, metrics: metrics}
+	b.cb = gobreaker.NewCircuitBreaker(gobreaker.Settings{
+		Name:        name,
+		MaxRequests: cfg.HalfOpenRequests,
+		Timeout:     cfg.OpenTimeout,
+		ReadyToTrip: func(counts gobreaker.Counts) bool {
+			return counts.ConsecutiveFailures >= cfg.FailureThreshold
+		},
+		IsSuccessful: isHealthy,
+		OnStateChange: func(name string, from, to gobreaker.State) {
+			logger.Warn("Backend circuit breaker changed state",
+				zap.String("backend", name),
+				zap.String("from", from.String()),
+				zap.String("to", to.String()),
+			)
+			metrics.transition(name, to)
+		},
+	})
+	metrics.setState(name, gobreaker.StateClosed)
+
+	return b
+}
+
+// State returns the current state of the circuit.
+func (b *Breaker) State() gobreaker.State {
+	return b.cb.State()
+}
+
+// UnaryClientInterceptor runs calls through the breaker. While the circuit is
+// open, calls fail immediately with UNAVAILABLE without reaching the backend.
+func (b *Breaker) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		_, err := b.cb.Execute(func() (interface{}, error) {
+			return nil, invoker(ctx, method, req, reply, cc, opts...)
+		})
+
+		if errors.Is(err, gobreaker.ErrOpenState) || errors.Is(err, gobreaker.ErrTooManyRequests) {
+			b.metrics.reject(b.name)
+			return status.Errorf(codes.Unavailable, "%s backend is unavailable: circuit breaker is %s", b.name, b.cb.State())
+		}
+		return err
+	}
+}
+
+// isHealthy reports whether err says nothing about the backend's health.
+// Application errors, such as an unknown race or an invalid filter, and calls
+// cancelled by the client must not open the circuit.
+func isHealthy(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Internal, codes.DataLoss, codes.ResourceExhausted:
+		return false
+	default:
+		return true
+	}
+}
@@ -0,0 +1,273 @@
+package backend
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"git.neds.sh/matty/entain/api/internal/config"
+	"git.neds.sh/matty/entain/api/proto/racing"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sony/gobreaker"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestServiceConfig(t *testing.T) {
+	retry := config.RetryConfig{
+		MaxAttempts:       3,
+		InitialBackoff:    100 * time.Millisecond,
+		MaxBackoff:        time.Second,
+		BackoffMultiplier: 2,
+	}
+	roundRobin := config.LoadBalancingConfig{Policy: config.PolicyRoundRobin, HealthCheck: true}
+
+	tests := []struct {
+		name    string
+		cfg     config.BackendsConfig
+		methods []string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "retry policy for listed methods",
+			cfg:     config.BackendsConfig{Retry: retry},
+			methods: []string{"/racing.Racing/GetRace", "/racing.Racing/ListRaces"},
+			want: `{"methodConfig":[{"name":[{"service":"racing.Racing","method":"GetRace"},{"service":"racing.Racing","method":"ListRaces"}],` +
+				`"retryPolicy":{"maxAttempts":3,"initialBackoff":"0.1s","maxBackoff":"1s","backoffMultiplier":2,"retryableStatusCodes":["UNAVAILABLE"]}}]}`,
+		},
+		{
+			name:    "single attempt disables retries",
+			cfg:     config.BackendsConfig{Retry: config.RetryConfig{MaxAttempts: 1}},
+			methods: []string{"/racing.Racing/GetRace"},
+			want:    `{}`,
+		},
+		{
+			name: "no methods",
+			cfg:  config.BackendsConfig{Retry: retry},
+			want: `{}`,
+		},
+		{
+			name: "balancing policy with health checks",
+			cfg:  config.BackendsConfig{LoadBalancing: roundRobin},
+			want: `{"loadBalancingConfig":[{"round_robin":{}}],"healthCheckConfig":{"serviceName":""}}`,
+		},
+		{
+			name: "health checks disabled",
+			cfg:  config.BackendsConfig{LoadBalancing: config.LoadBalancingConfig{Policy: config.PolicyLeastRequest}},
+			want: `{"loadBalancingConfig":[{"least_request":{}}]}`,
+		},
+		{
+			name: "pick_first does not health check",
+			cfg:  config.BackendsConfig{LoadBalancing: config.LoadBalancingConfig{Policy: config.PolicyPickFirst, HealthCheck: true}},
+			want: `{"loadBalancingConfig":[{"pick_first":{}}]}`,
+		},
+		{
+			name:    "invalid method name",
+			cfg:     config.BackendsConfig{Retry: retry},
+			methods: []string{"GetRace"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ServiceConfig(tt.cfg, tt.methods)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ServiceConfig() = %s, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ServiceConfig() failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ServiceConfig() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTimeoutInterceptor(t *testing.T) {
+	interceptor := TimeoutInterceptor(5*time.Second, map[string]time.Duration{
+		"/racing.Racing/ListRaces": time.Second,
+	})
+
+	tests := []struct {
+		name          string
+		method        string
+		callerTimeout time.Duration
+		want          time.Duration
+	}{
+		{"default timeout", "/racing.Racing/GetRace", 0, 5 * time.Second},
+		{"method timeout", "/racing.Racing/ListRaces", 0, time.Second},
+		{"shorter caller deadline wins", "/racing.Racing/ListRaces", 100 * time.Millisecond, 100 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.callerTimeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.callerTimeout)
+				defer cancel()
+			}
+
+			var got time.Duration
+			invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+				deadline, ok := ctx.Deadline()
+				if !ok {
+					t.Fatal("call has no deadline")
+				}
+				got = time.Until(deadline)
+				return nil
+			}
+
+			if err := interceptor(ctx, tt.method, nil, nil, nil, invoker); err != nil {
+				t.Fatalf("interceptor() failed: %v", err)
+			}
+			if got > tt.want || got < tt.want-time.Second/2 {
+				t.Errorf("deadline in %v, want about %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBreaker(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	metrics := NewMetrics(registry)
+	breaker := NewBreaker("racing", config.BreakerConfig{
+		Enabled:          true,
+		FailureThreshold: 2,
+		OpenTimeout:      50 * time.Millisecond,
+		HalfOpenRequests: 1,
+	}, metrics, nil)
+	interceptor := breaker.UnaryClientInterceptor()
+
+	var calls int
+	call := func(err error) error {
+		invoker := func(context.Context, string, interface{}, interface{}, *grpc.ClientConn, ...grpc.CallOption) error {
+			calls++
+			return err
+		}
+		return interceptor(context.Background(), "/racing.Racing/GetRace", nil, nil, nil, invoker)
+	}
+
+	state := func() float64 {
+		return testutil.ToFloat64(metrics.state.WithLabelValues("racing"))
+	}
+
+	// Application errors do not count as failures
+	for i := 0; i < 3; i++ {
+		call(status.Error(codes.Unknown, "race with ID 1 not found"))
+		call(status.Error(codes.Canceled, "context canceled"))
+	}
+	if got := breaker.State(); got != gobreaker.StateClosed {
+		t.Fatalf("state after application errors = %v, want %v", got, gobreaker.StateClosed)
+	}
+
+	// Consecutive transport failures open the circuit
+	call(status.Error(codes.Unavailable, "connection refused"))
+	call(status.Error(codes.DeadlineExceeded, "deadline exceeded"))
+	if got := breaker.State(); got != gobreaker.StateOpen {
+		t.Fatalf("state after failures = %v, want %v", got, gobreaker.StateOpen)
+	}
+	if got := state(); got != float64(gobreaker.StateOpen) {
+		t.Errorf("state metric = %v, want %v", got, float64(gobreaker.StateOpen))
+	}
+
+	// An open circuit fails fast without calling the backend
+	calls = 0
+	err := call(nil)
+	if got := status.Code(err); got != codes.Unavailable {
+		t.Errorf("call while open code = %v, want %v", got, codes.Unavailable)
+	}
+	if calls != 0 {
+		t.Errorf("backend called %d times while open, want 0", calls)
+	}
+	if got := testutil.ToFloat64(metrics.rejected.WithLabelValues("racing")); got != 1 {
+		t.Errorf("rejected metric = %v, want 1", got)
+	}
+
+	// After the open timeout a successful trial call closes the circuit
+	time.Sleep(60 * time.Millisecond)
+	if err := call(nil); err != nil {
+		t.Fatalf("trial call failed: %v", err)
+	}
+	if got := breaker.State(); got != gobreaker.StateClosed {
+		t.Errorf("state after trial call = %v, want %v", got, gobreaker.StateClosed)
+	}
+	if got := state(); got != float64(gobreaker.StateClosed) {
+		t.Errorf("state metric = %v, want %v", got, float64(gobreaker.StateClosed))
+	}
+	if got := testutil.ToFloat64(metrics.transitions.WithLabelValues("racing", "open")); got != 1 {
+		t.Errorf("transitions to open = %v, want 1", got)
+	}
+}
+
+// flakyRacingServer fails the first calls of each method with UNAVAILABLE
+type flakyRacingServer struct {
+	racing.UnimplementedRacingServer
+	failures int32
+	getCalls int32
+	// listCalls counts ListRaces calls, which always fail
+	listCalls int32
+}
+
+func (s *flakyRacingServer) GetRace(ctx context.Context, in *racing.GetRaceRequest) (*racing.GetRaceResponse, error) {
+	if atomic.AddInt32(&s.getCalls, 1) <= s.failures {
+		return nil, status.Error(codes.Unavailable, "warming up")
+	}
+	return &racing.GetRaceResponse{Race: &racing.Race{Id: in.Id}}, nil
+}
+
+func (s *flakyRacingServer) ListRaces(ctx context.Context, in *racing.ListRacesRequest) (*racing.ListRacesResponse, error) {
+	atomic.AddInt32(&s.listCalls, 1)
+	return nil, status.Error(codes.Unavailable, "warming up")
+}
+
+func TestDialOptions_RetriesIdempotentReads(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	flaky := &flakyRacingServer{failures: 2}
+	server := grpc.NewServer()
+	racing.RegisterRacingServer(server, flaky)
+	go server.Serve(lis)
+	defer server.Stop()
+
+	cfg := config.Default().Backends
+	cfg.Retry.InitialBackoff = time.Millisecond
+	cfg.Retry.MaxBackoff = 10 * time.Millisecond
+
+	opts, err := DialOptions("racing", cfg, []string{"/racing.Racing/GetRace"}, nil, nil)
+	if err != nil {
+		t.Fatalf("DialOptions() failed: %v", err)
+	}
+	conn, err := grpc.Dial(lis.Addr().String(), append(opts, grpc.WithInsecure())...)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer conn.Close()
+	client := racing.NewRacingClient(conn)
+
+	if _, err := client.GetRace(context.Background(), &racing.GetRaceRequest{Id: 1}); err != nil {
+		t.Errorf("GetRace() failed despite retries: %v", err)
+	}
+	if got := atomic.LoadInt32(&flaky.getCalls); got != 3 {
+		t.Errorf("GetRace attempts = %d, want 3", got)
+	}
+
+	if _, err := client.ListRaces(context.Background(), &racing.ListRacesRequest{}); status.Code(err) != codes.Unavailable {
+		t.Errorf("ListRaces() error = %v, want UNAVAILABLE", err)
+	}
+	if got := atomic.LoadInt32(&flaky.listCalls); got != 1 {
+		t.Errorf("ListRaces attempts = %d, want 1 as it is not in the retry list", got)
+	}
+}
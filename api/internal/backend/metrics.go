@@ -0,0 +1,68 @@
+package backend
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sony/gobreaker"
+)
+
+// Metrics exposes the circuit breaker state of every backend.
+type Metrics struct {
+	state       *prometheus.GaugeVec
+	transitions *prometheus.CounterVec
+	rejected    *prometheus.CounterVec
+}
+
+// NewMetrics creates the breaker metrics and registers them with reg.
+func NewMetrics(reg prometheus.Registerer) *Metrics {
+	m := &Metrics{
+		state: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "gateway",
+			Subsystem: "backend",
+			Name:      "circuit_breaker_state",
+			Help:      "Circuit breaker state per backend: 0 closed, 1 half-open, 2 open.",
+		}, []string{"backend"}),
+		transitions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "gateway",
+			Subsystem: "backend",
+			Name:      "circuit_breaker_transitions_total",
+			Help:      "Circuit breaker state changes per backend and new state.",
+		}, []string{"backend", "state"}),
+		rejected: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "gateway",
+			Subsystem: "backend",
+			Name:      "circuit_breaker_rejected_total",
+			Help:      "Calls failed fast because the backend's circuit was open.",
+		}, []string{"backend"}),
+	}
+
+	reg.MustRegister(m.state, m.transitions, m.rejected)
+	return m
+}
+
+// setState records the current state. It is a no-op on a nil receiver.
+func (m *Metrics) setState(backend string, state gobreaker.State) {
+	if m == nil {
+		return
+	}
+
+	// gobreaker orders its states closed, half-open, open
+	m.state.WithLabelValues(backend).Set(float64(state))
+}
+
+// transition records a state change. It is a no-op on a nil receiver.
+func (m *Metrics) transition(backend string, to gobreaker.State) {
+	if m == nil {
+		return
+	}
+
+	m.setState(backend, to)
+	m.transitions.WithLabelValues(backend, to.String()).Inc()
+}
+
+// reject counts a call failed by an open circuit. It is a no-op on a nil receiver.
+func (m *Metrics) reject(backend string) {
+	if m == nil {
+		return
+	}
+	m.rejected.WithLabelValues(backend).Inc()
+}
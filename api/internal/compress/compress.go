@@ -0,0 +1,283 @@
+package compress
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"git.neds.sh/matty/entain/api/internal/config"
+	"git.neds.sh/matty/entain/api/internal/grpcweb"
+	"github.com/andybalholm/brotli"
+)
+
+// Content codings the gateway compresses responses with.
+const (
+	EncodingBrotli = "br"
+	EncodingGzip   = "gzip"
+)
+
+// encodings lists the content codings in order of preference, for clients
+// that accept several equally. Brotli gives smaller JSON bodies.
+var encodings = []string{EncodingBrotli, EncodingGzip}
+
+// Compressor compresses responses with the content coding the client prefers.
+type Compressor struct {
+	minSize int
+}
+
+// New creates a Compressor with the given threshold.
+func New(cfg config.CompressionConfig) *Compressor {
+	return &Compressor{minSize: cfg.MinSize}
+}
+
+// Middleware compresses responses of at least the threshold size, when the
+// client accepts gzip or brotli.
+//
+// A compressed body is a different representation, so strong ETags get the
+// coding appended, as in "abc-gzip". The suffix is removed from If-None-Match
+// before the request reaches the handler, so that validators set on the
+// uncompressed body still match. Responses that are already encoded, have no
+// body, or are grpc-web calls, whose frames are streamed, are left alone.
+func (c *Compressor) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if grpcweb.IsGRPCWeb(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		encoding := Negotiate(r.Header.Get("Accept-Encoding"))
+		if encoding == "" || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &writer{ResponseWriter: w, encoding: encoding, minSize: c.minSize}
+		if inm := r.Header.Get("If-None-Match"); inm != "" {
+			r = r.Clone(r.Context())
+			var stripped string
+			stripped, cw.notModifiedSuffix = stripSuffixes(inm)
+			r.Header.Set("If-None-Match", stripped)
+		}
+
+		next.ServeHTTP(cw, r)
+		cw.close()
+	})
+}
+
+// Negotiate returns the content coding an Accept-Encoding header prefers, or
+// "" if the response should not be compressed.
+func Negotiate(acceptEncoding string) string {
+	if strings.TrimSpace(acceptEncoding) == "" {
+		return ""
+	}
+
+	qualities := make(map[string]float64)
+	for _, entry := range strings.Split(acceptEncoding, ",") {
+		params := strings.Split(entry, ";")
+		coding := strings.ToLower(strings.TrimSpace(params[0]))
+		if coding == "x-gzip" {
+			coding = EncodingGzip
+		}
+
+		q := 1.0
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if len(param) > 2 && strings.EqualFold(param[:2], "q=") {
+				v, err := strconv.ParseFloat(param[2:], 64)
+				if err != nil || v < 0 || v > 1 {
+					v = 0
+				}
+				q = v
+			}
+		}
+		qualities[coding] = q
+	}
+
+	best, bestQ := "", 0.0
+	for _, coding := range encodings {
+		q, ok := qualities[coding]
+		if !ok {
+			q = qualities["*"]
+		}
+		if q > bestQ {
+			best, bestQ = coding, q
+		}
+	}
+
+	// identity is always acceptable unless refused, and wins if preferred
+	if identity, ok := qualities["identity"]; ok && identity > bestQ {
+		return ""
+	}
+	return best
+}
+
+// stripSuffixes removes the coding suffixes from the entity tags of an
+// If-None-Match header. It returns the header and the suffix of the first
+// tag that had one, which a 304 response must carry on its ETag.
+func stripSuffixes(header string) (string, string) {
+	var suffix string
+	tags := strings.Split(header, ",")
+	for i, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		for _, coding := range encodings {
+			if s := "-" + coding + `"`; strings.HasSuffix(tag, s) {
+				tag = strings.TrimSuffix(tag, s) + `"`
+				if suffix == "" {
+					suffix = "-" + coding
+				}
+				break
+			}
+		}
+		tags[i] = tag
+	}
+	return strings.Join(tags, ", "), suffix
+}
+
+// withSuffix appends suffix to a strong entity tag. Weak tags only promise
+// semantic equivalence, which holds across codings, and are left alone.
+func withSuffix(etag, suffix string) string {
+	if suffix == "" || !strings.HasPrefix(etag, `"`) || !strings.HasSuffix(etag, `"`) || len(etag) < 2 {
+		return etag
+	}
+	return strings.TrimSuffix(etag, `"`) + suffix + `"`
+}
+
+// writer holds back a response until it knows whether to compress it: once
+// the body reaches the threshold it is compressed, and if it ends or is
+// flushed before then it is sent as is.
+type writer struct {
+	http.ResponseWriter
+	encoding string
+	minSize  int
+	// notModifiedSuffix is set on the ETag of 304 responses.
+	notModifiedSuffix string
+
+	status  int
+	buf     []byte
+	decided bool
+	encoder io.WriteCloser
+}
+
+// flusher is implemented by both encoders.
+type flusher interface {
+	Flush() error
+}
+
+func (w *writer) WriteHeader(status int) {
+	if w.status != 0 {
+		return
+	}
+	w.status = status
+	if !w.compressible() {
+		w.passthrough()
+	}
+}
+
+func (w *writer) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.decided {
+		if w.encoder != nil {
+			return w.encoder.Write(b)
+		}
+		return w.ResponseWriter.Write(b)
+	}
+
+	w.buf = append(w.buf, b...)
+	if len(w.buf) >= w.minSize {
+		if err := w.compress(); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *writer) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Flush sends what has been written so far. A body flushed before reaching
+// the threshold is not compressed.
+func (w *writer) Flush() {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.decided {
+		w.passthrough()
+	}
+	if f, ok := w.encoder.(flusher); ok {
+		f.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// close completes the response once the handler returns.
+func (w *writer) close() {
+	if w.status == 0 {
+		// The handler wrote nothing, leave the server to send its default
+		return
+	}
+	if !w.decided {
+		w.passthrough()
+	}
+	if w.encoder != nil {
+		w.encoder.Close()
+	}
+}
+
+// compressible reports whether the response may be compressed, going by its
+// status and headers.
+func (w *writer) compressible() bool {
+	if w.status < http.StatusOK || w.status == http.StatusNoContent || w.status == http.StatusNotModified {
+		return false
+	}
+	return w.Header().Get("Content-Encoding") == ""
+}
+
+// passthrough sends the response uncompressed, starting with what is buffered.
+func (w *writer) passthrough() {
+	w.decided = true
+	if w.status == http.StatusNotModified {
+		if etag := w.Header().Get("ETag"); etag != "" {
+			w.Header().Set("ETag", withSuffix(etag, w.notModifiedSuffix))
+		}
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	if len(w.buf) > 0 {
+		w.ResponseWriter.Write(w.buf)
+		w.buf = nil
+	}
+}
+
+// compress sends the response compressed, starting with what is buffered.
+func (w *writer) compress() error {
+	w.decided = true
+
+	h := w.Header()
+	h.Set("Content-Encoding", w.encoding)
+	h.Del("Content-Length")
+	if etag := h.Get("ETag"); etag != "" {
+		h.Set("ETag", withSuffix(etag, "-"+w.encoding))
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+
+	switch w.encoding {
+	case EncodingBrotli:
+		w.encoder = brotli.NewWriterLevel(w.ResponseWriter, brotli.DefaultCompression)
+	default:
+		w.encoder = gzip.NewWriter(w.ResponseWriter)
+	}
+
+	buf := w.buf
+	w.buf = nil
+	_, err := w.encoder.Write(buf)
+	return err
+}
@@ -0,0 +1,255 @@
+package compress
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"git.neds.sh/matty/entain/api/internal/config"
+	"github.com/andybalholm/brotli"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{header: "", want: ""},
+		{header: "gzip", want: EncodingGzip},
+		{header: "x-gzip", want: EncodingGzip},
+		{header: "gzip, deflate, br", want: EncodingBrotli},
+		{header: "br;q=0.5, gzip", want: EncodingGzip},
+		{header: "GZIP;Q=0.8, br;q=0.8", want: EncodingBrotli},
+		{header: "br;q=0, gzip;q=0", want: ""},
+		{header: "*", want: EncodingBrotli},
+		{header: "gzip;q=0, *;q=0.5", want: EncodingBrotli},
+		{header: "identity", want: ""},
+		{header: "identity;q=1, gzip;q=0.5", want: ""},
+		{header: "identity;q=0.1, gzip", want: EncodingGzip},
+		{header: "deflate", want: ""},
+		{header: "gzip;q=2", want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := Negotiate(tt.header); got != tt.want {
+			t.Errorf("Negotiate(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestCompressor_Middleware(t *testing.T) {
+	large := strings.Repeat(`{"name":"Race 1"},`, 100)
+
+	tests := []struct {
+		name     string
+		method   string
+		headers  map[string]string
+		handler  http.HandlerFunc
+		wantCode int
+		// wantHeaders are compared one by one; "" means absent
+		wantHeaders map[string]string
+		// wantINM is the If-None-Match the handler sees
+		wantINM  string
+		wantBody string
+	}{
+		{
+			name:    "gzip above the threshold",
+			headers: map[string]string{"Accept-Encoding": "gzip"},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("ETag", `"abc"`)
+				w.Header().Set("Content-Length", "1800")
+				io.WriteString(w, large)
+			},
+			wantCode: http.StatusOK,
+			wantHeaders: map[string]string{
+				"Content-Encoding": EncodingGzip,
+				"Content-Length":   "",
+				"ETag":             `"abc-gzip"`,
+				"Vary":             "Accept-Encoding",
+			},
+			wantBody: large,
+		},
+		{
+			name:    "brotli above the threshold, in small writes",
+			headers: map[string]string{"Accept-Encoding": "gzip, br"},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				for _, c := range large {
+					io.WriteString(w, string(c))
+				}
+			},
+			wantCode:    http.StatusOK,
+			wantHeaders: map[string]string{"Content-Encoding": EncodingBrotli},
+			wantBody:    large,
+		},
+		{
+			name:    "below the threshold",
+			headers: map[string]string{"Accept-Encoding": "gzip"},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("ETag", `"abc"`)
+				w.WriteHeader(http.StatusCreated)
+				io.WriteString(w, `{"id":1}`)
+			},
+			wantCode: http.StatusCreated,
+			wantHeaders: map[string]string{
+				"Content-Encoding": "",
+				"ETag":             `"abc"`,
+				"Vary":             "Accept-Encoding",
+			},
+			wantBody: `{"id":1}`,
+		},
+		{
+			name:    "not accepted",
+			headers: map[string]string{"Accept-Encoding": "identity"},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				io.WriteString(w, large)
+			},
+			wantCode:    http.StatusOK,
+			wantHeaders: map[string]string{"Content-Encoding": "", "Vary": "Accept-Encoding"},
+			wantBody:    large,
+		},
+		{
+			name:    "already encoded",
+			headers: map[string]string{"Accept-Encoding": "gzip"},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Encoding", "deflate")
+				io.WriteString(w, large)
+			},
+			wantCode:    http.StatusOK,
+			wantHeaders: map[string]string{"Content-Encoding": "deflate"},
+			wantBody:    large,
+		},
+		{
+			name:    "not modified keeps the suffix the client sent",
+			headers: map[string]string{"Accept-Encoding": "gzip", "If-None-Match": `"abc-gzip", W/"def"`},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("ETag", `"abc"`)
+				w.WriteHeader(http.StatusNotModified)
+			},
+			wantCode: http.StatusNotModified,
+			wantHeaders: map[string]string{
+				"Content-Encoding": "",
+				"ETag":             `"abc-gzip"`,
+			},
+			wantINM: `"abc", W/"def"`,
+		},
+		{
+			name:    "not modified for an uncompressed representation",
+			headers: map[string]string{"Accept-Encoding": "br", "If-None-Match": `"abc"`},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("ETag", `"abc"`)
+				w.WriteHeader(http.StatusNotModified)
+			},
+			wantCode:    http.StatusNotModified,
+			wantHeaders: map[string]string{"ETag": `"abc"`},
+			wantINM:     `"abc"`,
+		},
+		{
+			name:    "flushed before the threshold",
+			headers: map[string]string{"Accept-Encoding": "gzip"},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				io.WriteString(w, "data: 1\n\n")
+				w.(http.Flusher).Flush()
+				io.WriteString(w, large)
+			},
+			wantCode:    http.StatusOK,
+			wantHeaders: map[string]string{"Content-Encoding": ""},
+			wantBody:    "data: 1\n\n" + large,
+		},
+		{
+			name:   "grpc-web",
+			method: http.MethodPost,
+			headers: map[string]string{
+				"Accept-Encoding": "gzip",
+				"Content-Type":    "application/grpc-web+proto",
+			},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				io.WriteString(w, large)
+			},
+			wantCode:    http.StatusOK,
+			wantHeaders: map[string]string{"Content-Encoding": "", "Vary": ""},
+			wantBody:    large,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotINM string
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotINM = r.Header.Get("If-None-Match")
+				tt.handler(w, r)
+			})
+
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			req := httptest.NewRequest(method, "/v1/list-races", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			New(config.CompressionConfig{Enabled: true, MinSize: 1024}).Middleware(next).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantCode)
+			}
+			for k, want := range tt.wantHeaders {
+				if got := rec.Header().Get(k); got != want {
+					t.Errorf("header %s = %q, want %q", k, got, want)
+				}
+			}
+			if gotINM != tt.wantINM {
+				t.Errorf("If-None-Match = %q, want %q", gotINM, tt.wantINM)
+			}
+			if got := decodeBody(t, rec.Header().Get("Content-Encoding"), rec.Body.Bytes()); got != tt.wantBody {
+				t.Errorf("body = %q, want %q", got, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestCompressor_Middleware_Unwraps(t *testing.T) {
+	handler := New(config.CompressionConfig{Enabled: true, MinSize: 1}).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+			t.Errorf("SetWriteDeadline() failed: %v", err)
+		}
+	}))
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	resp.Body.Close()
+}
+
+func decodeBody(t *testing.T, encoding string, body []byte) string {
+	t.Helper()
+
+	var r io.Reader = bytes.NewReader(body)
+	switch encoding {
+	case EncodingGzip:
+		gr, err := gzip.NewReader(r)
+		if err != nil {
+			t.Fatalf("invalid gzip body: %v", err)
+		}
+		r = gr
+	case EncodingBrotli:
+		r = brotli.NewReader(r)
+	}
+
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatalf("failed to decode %s body: %v", encoding, err)
+	}
+	return string(b)
+}
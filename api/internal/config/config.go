@@ -0,0 +1,898 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"git.neds.sh/matty/entain/api/internal/logger"
+	"github.com/BurntSushi/toml"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/yaml.v3"
+)
+
+// Environment variable names for gateway configuration.
+// LOG_LEVEL and ENVIRONMENT are shared with the logger package.
+const (
+	EnvConfigFile         = "CONFIG_FILE"
+	EnvAPIEndpoint        = "API_ENDPOINT"
+	EnvRacingGRPCEndpoint = "RACING_GRPC_ENDPOINT"
+	EnvSportsGRPCEndpoint = "SPORTS_GRPC_ENDPOINT"
+	EnvTLSEnabled         = "TLS_ENABLED"
+	EnvTLSCAFile          = "TLS_CA_FILE"
+	EnvTLSCertFile        = "TLS_CERT_FILE"
+	EnvTLSKeyFile         = "TLS_KEY_FILE"
+	EnvTLSServerName      = "TLS_SERVER_NAME"
+	EnvTLSReloadInterval  = "TLS_RELOAD_INTERVAL"
+	EnvReadHeaderTimeout  = "READ_HEADER_TIMEOUT"
+	EnvReadTimeout        = "READ_TIMEOUT"
+	EnvWriteTimeout       = "WRITE_TIMEOUT"
+	EnvIdleTimeout        = "IDLE_TIMEOUT"
+	EnvShutdownTimeout    = "SHUTDOWN_TIMEOUT"
+	EnvAuthJWKSFile       = "AUTH_JWKS_FILE"
+	EnvAuthIssuer         = "AUTH_ISSUER"
+	EnvAuthAudience       = "AUTH_AUDIENCE"
+	EnvAuthRolesClaim     = "AUTH_ROLES_CLAIM"
+	EnvBackendTimeout     = "BACKEND_TIMEOUT"
+	EnvRetryMaxAttempts   = "RETRY_MAX_ATTEMPTS"
+	EnvBreakerFailures    = "BREAKER_FAILURE_THRESHOLD"
+	EnvBreakerOpenTimeout = "BREAKER_OPEN_TIMEOUT"
+	EnvLBPolicy           = "LB_POLICY"
+	EnvLBHealthCheck      = "LB_HEALTH_CHECK"
+	EnvLBRefreshInterval  = "LB_REFRESH_INTERVAL"
+	EnvRateLimitEnabled   = "RATE_LIMIT_ENABLED"
+	EnvRateLimitRate      = "RATE_LIMIT_RATE"
+	EnvRateLimitBurst     = "RATE_LIMIT_BURST"
+	EnvHTTPCacheEnabled   = "HTTP_CACHE_ENABLED"
+	EnvHTTPCacheMinAge    = "HTTP_CACHE_MIN_AGE"
+	EnvHTTPCacheMaxAge    = "HTTP_CACHE_MAX_AGE"
+	EnvGRPCWebEnabled     = "GRPC_WEB_ENABLED"
+	EnvCORSEnabled        = "CORS_ENABLED"
+	EnvCORSAllowedOrigins = "CORS_ALLOWED_ORIGINS"
+	EnvCompressionEnabled = "COMPRESSION_ENABLED"
+	EnvCompressionMinSize = "COMPRESSION_MIN_SIZE"
+)
+
+// Config is the complete, typed configuration of the API gateway.
+type Config struct {
+	HTTP        HTTPConfig        `yaml:"http"`
+	Backends    BackendsConfig    `yaml:"backends"`
+	TLS         TLSConfig         `yaml:"tls"`
+	Timeouts    TimeoutsConfig    `yaml:"timeouts"`
+	Auth        AuthConfig        `yaml:"auth"`
+	RateLimit   RateLimitConfig   `yaml:"rate_limit"`
+	HTTPCache   HTTPCacheConfig   `yaml:"http_cache"`
+	GRPCWeb     GRPCWebConfig     `yaml:"grpc_web"`
+	CORS        CORSConfig        `yaml:"cors"`
+	Compression CompressionConfig `yaml:"compression"`
+	Log         LogConfig         `yaml:"log"`
+}
+
+// HTTPConfig holds the REST listener settings.
+type HTTPConfig struct {
+	// Endpoint is the host:port the gateway listens on.
+	Endpoint string `yaml:"endpoint"`
+}
+
+// BackendsConfig holds the gRPC endpoints the gateway forwards to and how
+// calls to them are bounded.
+//
+// An endpoint is a single host:port, a comma-separated list of them, a
+// dns:///host:port name resolving to every replica, or a file:///path listing
+// one host:port per line.
+type BackendsConfig struct {
+	Racing string `yaml:"racing"`
+	Sports string `yaml:"sports"`
+	// Timeout is the deadline of a backend call, including retries. Zero means none.
+	Timeout time.Duration `yaml:"timeout"`
+	// MethodTimeouts overrides Timeout per gRPC method, e.g. "/racing.Racing/ListRaces".
+	MethodTimeouts map[string]time.Duration `yaml:"method_timeouts"`
+	Retry          RetryConfig              `yaml:"retry"`
+	Breaker        BreakerConfig            `yaml:"breaker"`
+	LoadBalancing  LoadBalancingConfig      `yaml:"load_balancing"`
+}
+
+// RetryConfig is the retry policy applied to idempotent reads that fail with
+// UNAVAILABLE.
+type RetryConfig struct {
+	// MaxAttempts includes the original call; 1 disables retries. gRPC caps it at 5.
+	MaxAttempts       int           `yaml:"max_attempts"`
+	InitialBackoff    time.Duration `yaml:"initial_backoff"`
+	MaxBackoff        time.Duration `yaml:"max_backoff"`
+	BackoffMultiplier float64       `yaml:"backoff_multiplier"`
+}
+
+// BreakerConfig configures the circuit breaker kept for each backend.
+type BreakerConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// FailureThreshold is the number of consecutive failures that opens the circuit.
+	FailureThreshold uint32 `yaml:"failure_threshold"`
+	// OpenTimeout is how long the circuit stays open before trial calls are let through.
+	OpenTimeout time.Duration `yaml:"open_timeout"`
+	// HalfOpenRequests is the number of trial calls allowed while half-open.
+	HalfOpenRequests uint32 `yaml:"half_open_requests"`
+}
+
+// Load balancing policies supported for backends with several replicas.
+const (
+	PolicyRoundRobin   = "round_robin"
+	PolicyLeastRequest = "least_request"
+	PolicyPickFirst    = "pick_first"
+)
+
+// LoadBalancingConfig controls how calls are spread across backend replicas.
+type LoadBalancingConfig struct {
+	// Policy is round_robin, least_request or pick_first.
+	Policy string `yaml:"policy"`
+	// HealthCheck skips replicas that do not report SERVING over the gRPC
+	// health checking protocol. It has no effect with pick_first.
+	HealthCheck bool `yaml:"health_check"`
+	// RefreshInterval is how often file:// endpoint lists are re-read.
+	RefreshInterval time.Duration `yaml:"refresh_interval"`
+}
+
+// TLSConfig holds the transport security settings used to dial the backends.
+type TLSConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// CAFile is a PEM bundle used to verify the backend certificates.
+	CAFile string `yaml:"ca_file"`
+	// CertFile and KeyFile are the client certificate presented for mutual TLS.
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+	// ServerName overrides the name checked against the backend certificates.
+	ServerName string `yaml:"server_name"`
+	// ReloadInterval is how often the files are checked for rotation.
+	ReloadInterval time.Duration `yaml:"reload_interval"`
+}
+
+// TimeoutsConfig holds the HTTP server timeouts.
+type TimeoutsConfig struct {
+	ReadHeader time.Duration `yaml:"read_header"`
+	Read       time.Duration `yaml:"read"`
+	Write      time.Duration `yaml:"write"`
+	Idle       time.Duration `yaml:"idle"`
+	// Shutdown bounds how long in-flight requests may run after a stop signal.
+	Shutdown time.Duration `yaml:"shutdown"`
+}
+
+// AuthConfig holds the bearer token validation settings.
+// Authentication is disabled, and every caller is anonymous, when JWKSFile is empty.
+type AuthConfig struct {
+	// JWKSFile is a JSON Web Key Set holding the token signing keys.
+	JWKSFile string `yaml:"jwks_file"`
+	// Issuer and Audience, when set, must match the iss and aud claims.
+	Issuer   string `yaml:"issuer"`
+	Audience string `yaml:"audience"`
+	// RolesClaim names the claim holding the caller's roles.
+	RolesClaim string `yaml:"roles_claim"`
+}
+
+// RateLimitConfig holds the per-client request limits.
+type RateLimitConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Default applies to routes without their own limit.
+	Default RateLimit `yaml:"default"`
+	// Routes maps "METHOD /path" to a dedicated limit. Path segments written
+	// as {name} match any value, e.g. "GET /v1/races/{id}".
+	Routes map[string]RateLimit `yaml:"routes"`
+	// APIKeys lists the X-API-Key values that get their own quota. Unknown
+	// keys are ignored and the caller is limited by IP address.
+	APIKeys []string `yaml:"api_keys"`
+	// TrustForwardedFor uses the first X-Forwarded-For address as the client
+	// IP. Only enable it behind a proxy that sets the header.
+	TrustForwardedFor bool `yaml:"trust_forwarded_for"`
+}
+
+// RateLimit is a token bucket refilled at RequestsPerSecond up to Burst tokens.
+type RateLimit struct {
+	RequestsPerSecond float64 `yaml:"requests_per_second"`
+	Burst             int     `yaml:"burst"`
+}
+
+// HTTPCacheConfig controls the cache validators and freshness lifetimes of
+// GET responses.
+type HTTPCacheConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// MinAge is the shortest max-age given, to races and events about to start.
+	MinAge time.Duration `yaml:"min_age"`
+	// MaxAge is the longest max-age given, to items starting well in the
+	// future or already started.
+	MaxAge time.Duration `yaml:"max_age"`
+}
+
+// GRPCWebConfig controls grpc-web calls to the backend services, made by
+// browsers directly on the gateway listener.
+// Pages on other origins also need to be allowed by the CORS policy.
+type GRPCWebConfig struct {
+	Enabled bool `yaml:"enabled"`
+}
+
+// CORSConfig is the policy for requests made by pages on other origins.
+// Same-origin pages need no entry.
+type CORSConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// AllowedOrigins lists origins such as "https://app.example.com".
+	// "https://*.example.com" allows every subdomain of example.com, but not
+	// example.com itself, and "*" allows any origin.
+	AllowedOrigins []string `yaml:"allowed_origins"`
+	AllowedMethods []string `yaml:"allowed_methods"`
+	// AllowedHeaders lists the request headers pages may send. "*" allows any.
+	AllowedHeaders []string `yaml:"allowed_headers"`
+	// ExposedHeaders lists the response headers pages may read, beyond the
+	// few that browsers always expose.
+	ExposedHeaders []string `yaml:"exposed_headers"`
+	// AllowCredentials lets pages send cookies and read the responses. It
+	// cannot be combined with the "*" origin.
+	AllowCredentials bool `yaml:"allow_credentials"`
+	// MaxAge is how long browsers may cache a preflight response.
+	MaxAge time.Duration `yaml:"max_age"`
+}
+
+// CompressionConfig controls the compression of responses, with gzip or
+// brotli as the client accepts.
+type CompressionConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// MinSize is the smallest body, in bytes, worth compressing.
+	MinSize int `yaml:"min_size"`
+}
+
+// LogConfig holds the logger settings.
+type LogConfig struct {
+	Level       string `yaml:"level"`
+	Environment string `yaml:"environment"`
+}
+
+// Default returns the configuration used when nothing else is specified.
+func Default() Config {
+	return Config{
+		HTTP: HTTPConfig{
+			Endpoint: "localhost:8000",
+		},
+		Backends: BackendsConfig{
+			Racing:  "localhost:9000",
+			Sports:  "localhost:9001",
+			Timeout: 5 * time.Second,
+			Retry: RetryConfig{
+				MaxAttempts:       3,
+				InitialBackoff:    100 * time.Millisecond,
+				MaxBackoff:        time.Second,
+				BackoffMultiplier: 2,
+			},
+			Breaker: BreakerConfig{
+				Enabled:          true,
+				FailureThreshold: 5,
+				OpenTimeout:      30 * time.Second,
+				HalfOpenRequests: 1,
+			},
+			LoadBalancing: LoadBalancingConfig{
+				Policy:          PolicyRoundRobin,
+				HealthCheck:     true,
+				RefreshInterval: 5 * time.Second,
+			},
+		},
+		TLS: TLSConfig{
+			ReloadInterval: 30 * time.Second,
+		},
+		Timeouts: TimeoutsConfig{
+			ReadHeader: 5 * time.Second,
+			Read:       15 * time.Second,
+			Write:      30 * time.Second,
+			Idle:       120 * time.Second,
+			Shutdown:   10 * time.Second,
+		},
+		Auth: AuthConfig{
+			RolesClaim: "roles",
+		},
+		RateLimit: RateLimitConfig{
+			Enabled: true,
+			Default: RateLimit{RequestsPerSecond: 20, Burst: 40},
+			Routes: map[string]RateLimit{
+				"POST /v1/list-races":  {RequestsPerSecond: 10, Burst: 20},
+				"POST /v1/list-events": {RequestsPerSecond: 10, Burst: 20},
+				"GET /v1/races":        {RequestsPerSecond: 10, Burst: 20},
+				"GET /v1/events":       {RequestsPerSecond: 10, Burst: 20},
+			},
+		},
+		HTTPCache: HTTPCacheConfig{
+			Enabled: true,
+			MinAge:  time.Second,
+			MaxAge:  time.Minute,
+		},
+		GRPCWeb: GRPCWebConfig{
+			Enabled: true,
+		},
+		CORS: CORSConfig{
+			Enabled:        true,
+			AllowedMethods: []string{http.MethodGet, http.MethodPost},
+			AllowedHeaders: []string{
+				"Authorization", "Content-Type", "If-None-Match", "X-API-Key", "X-Request-Id",
+				"X-Grpc-Web", "X-User-Agent", "Grpc-Timeout",
+			},
+			ExposedHeaders: []string{
+				"ETag", "Retry-After", "X-Request-Id", "X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset",
+				"Grpc-Status", "Grpc-Message",
+			},
+			MaxAge: 10 * time.Minute,
+		},
+		Compression: CompressionConfig{
+			Enabled: true,
+			MinSize: 1024,
+		},
+		Log: LogConfig{
+			Level:       string(logger.InfoLevel),
+			Environment: string(logger.Production),
+		},
+	}
+}
+
+// Load builds the effective configuration by layering, in increasing order of
+// precedence: the defaults, the YAML or TOML file named by --config or CONFIG_FILE,
+// environment variables and finally command-line flags that were explicitly set.
+// The result is validated before it is returned.
+func Load(args []string) (Config, error) {
+	return load(args, os.LookupEnv)
+}
+
+func load(args []string, lookupEnv func(string) (string, bool)) (Config, error) {
+	cfg := Default()
+
+	fs := flag.NewFlagSet("api", flag.ContinueOnError)
+	configFile := fs.String("config", "", "Path to a YAML or TOML configuration file")
+	registerFlags(fs, cfg)
+	if err := fs.Parse(args); err != nil {
+		return Config{}, err
+	}
+
+	path := *configFile
+	if path == "" {
+		path, _ = lookupEnv(EnvConfigFile)
+	}
+	if path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return Config{}, err
+		}
+	}
+
+	if err := cfg.applyEnv(lookupEnv); err != nil {
+		return Config{}, err
+	}
+
+	cfg.applyFlags(fs)
+
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+
+	return cfg, nil
+}
+
+// registerFlags defines one flag per setting. Defaults are only shown in
+// --help; applyFlags copies values for flags that were actually set.
+func registerFlags(fs *flag.FlagSet, def Config) {
+	fs.String("api-endpoint", def.HTTP.Endpoint, "API endpoint")
+	fs.String("racing-grpc-endpoint", def.Backends.Racing, "Racing gRPC server endpoint")
+	fs.String("sports-grpc-endpoint", def.Backends.Sports, "Sports gRPC server endpoint")
+	fs.Duration("backend-timeout", def.Backends.Timeout, "Deadline of backend calls; 0 means none")
+	fs.Int("retry-max-attempts", def.Backends.Retry.MaxAttempts, "Attempts for idempotent backend reads; 1 disables retries")
+	fs.Bool("breaker-enabled", def.Backends.Breaker.Enabled, "Fail fast when a backend keeps failing")
+	fs.Uint("breaker-failure-threshold", uint(def.Backends.Breaker.FailureThreshold), "Consecutive failures that open a backend's circuit")
+	fs.Duration("breaker-open-timeout", def.Backends.Breaker.OpenTimeout, "How long an open circuit rejects calls")
+	fs.String("lb-policy", def.Backends.LoadBalancing.Policy, "Backend load balancing policy: round_robin, least_request or pick_first")
+	fs.Bool("lb-health-check", def.Backends.LoadBalancing.HealthCheck, "Skip backend replicas that fail gRPC health checks")
+	fs.Duration("lb-refresh-interval", def.Backends.LoadBalancing.RefreshInterval, "How often file:// backend endpoint lists are re-read")
+	fs.Bool("tls-enabled", def.TLS.Enabled, "Dial the backends over TLS")
+	fs.String("tls-ca-file", def.TLS.CAFile, "CA bundle used to verify backend certificates (PEM)")
+	fs.String("tls-cert-file", def.TLS.CertFile, "Client certificate presented to the backends (PEM)")
+	fs.String("tls-key-file", def.TLS.KeyFile, "Client private key (PEM)")
+	fs.String("tls-server-name", def.TLS.ServerName, "Server name expected in backend certificates")
+	fs.Duration("tls-reload-interval", def.TLS.ReloadInterval, "How often TLS files are checked for rotation")
+	fs.Duration("read-header-timeout", def.Timeouts.ReadHeader, "Timeout for reading request headers")
+	fs.Duration("read-timeout", def.Timeouts.Read, "Timeout for reading a whole request")
+	fs.Duration("write-timeout", def.Timeouts.Write, "Timeout for writing a response, exports excepted")
+	fs.Duration("idle-timeout", def.Timeouts.Idle, "Keep-alive idle timeout")
+	fs.Duration("shutdown-timeout", def.Timeouts.Shutdown, "Grace period for in-flight requests on shutdown")
+	fs.String("auth-jwks-file", def.Auth.JWKSFile, "JWKS file with the keys used to verify bearer tokens; empty disables authentication")
+	fs.String("auth-issuer", def.Auth.Issuer, "Required token issuer")
+	fs.String("auth-audience", def.Auth.Audience, "Required token audience")
+	fs.String("auth-roles-claim", def.Auth.RolesClaim, "Token claim holding the caller's roles")
+	fs.Bool("rate-limit-enabled", def.RateLimit.Enabled, "Limit requests per client")
+	fs.Float64("rate-limit-rate", def.RateLimit.Default.RequestsPerSecond, "Default sustained requests per second per client")
+	fs.Int("rate-limit-burst", def.RateLimit.Default.Burst, "Default burst size per client")
+	fs.Bool("http-cache-enabled", def.HTTPCache.Enabled, "Send ETag and Cache-Control headers on GET responses")
+	fs.Duration("http-cache-min-age", def.HTTPCache.MinAge, "Shortest max-age, for items about to start")
+	fs.Duration("http-cache-max-age", def.HTTPCache.MaxAge, "Longest max-age, for items starting well in the future")
+	fs.Bool("grpc-web-enabled", def.GRPCWeb.Enabled, "Accept grpc-web calls to the backend services")
+	fs.Bool("cors-enabled", def.CORS.Enabled, "Apply the CORS policy to requests from other origins")
+	fs.String("cors-allowed-origins", strings.Join(def.CORS.AllowedOrigins, ","), "Comma separated origins allowed to call the gateway from browsers")
+	fs.Bool("compression-enabled", def.Compression.Enabled, "Compress responses with gzip or brotli")
+	fs.Int("compression-min-size", def.Compression.MinSize, "Smallest response body, in bytes, that is compressed")
+	fs.String("log-level", def.Log.Level, "Log level: debug, info, warn or error")
+	fs.String("environment", def.Log.Environment, "Deployment environment: development, production or testing")
+}
+
+// loadFile overlays the YAML file at path onto the configuration, or the TOML
+// file if path ends in .toml. Unknown keys are rejected so that typos do not
+// go unnoticed.
+func (c *Config) loadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open config file: %w", err)
+	}
+	defer f.Close()
+
+	var in io.Reader = f
+	if strings.EqualFold(filepath.Ext(path), ".toml") {
+		// TOML is read as the YAML document it maps to, so that both formats
+		// share the keys, duration parsing and unknown key check
+		var doc map[string]interface{}
+		if _, err := toml.DecodeReader(f, &doc); err != nil {
+			return fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+		b, err := yaml.Marshal(doc)
+		if err != nil {
+			return fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+		in = bytes.NewReader(b)
+	}
+
+	dec := yaml.NewDecoder(in)
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// applyEnv overlays environment variables onto the configuration.
+func (c *Config) applyEnv(lookupEnv func(string) (string, bool)) error {
+	stringVars := map[string]*string{
+		EnvAPIEndpoint:        &c.HTTP.Endpoint,
+		EnvRacingGRPCEndpoint: &c.Backends.Racing,
+		EnvSportsGRPCEndpoint: &c.Backends.Sports,
+		EnvLBPolicy:           &c.Backends.LoadBalancing.Policy,
+		EnvTLSCAFile:          &c.TLS.CAFile,
+		EnvTLSCertFile:        &c.TLS.CertFile,
+		EnvTLSKeyFile:         &c.TLS.KeyFile,
+		EnvTLSServerName:      &c.TLS.ServerName,
+		EnvAuthJWKSFile:       &c.Auth.JWKSFile,
+		EnvAuthIssuer:         &c.Auth.Issuer,
+		EnvAuthAudience:       &c.Auth.Audience,
+		EnvAuthRolesClaim:     &c.Auth.RolesClaim,
+		logger.EnvLogLevel:    &c.Log.Level,
+		logger.EnvEnvironment: &c.Log.Environment,
+	}
+	for name, field := range stringVars {
+		if v, ok := lookupEnv(name); ok {
+			*field = v
+		}
+	}
+
+	if v, ok := lookupEnv(EnvCORSAllowedOrigins); ok {
+		c.CORS.AllowedOrigins = splitList(v)
+	}
+
+	if v, ok := lookupEnv(EnvCompressionMinSize); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", EnvCompressionMinSize, err)
+		}
+		c.Compression.MinSize = n
+	}
+	if v, ok := lookupEnv(EnvRetryMaxAttempts); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", EnvRetryMaxAttempts, err)
+		}
+		c.Backends.Retry.MaxAttempts = n
+	}
+	if v, ok := lookupEnv(EnvBreakerFailures); ok {
+		n, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", EnvBreakerFailures, err)
+		}
+		c.Backends.Breaker.FailureThreshold = uint32(n)
+	}
+
+	boolVars := map[string]*bool{
+		EnvTLSEnabled:         &c.TLS.Enabled,
+		EnvRateLimitEnabled:   &c.RateLimit.Enabled,
+		EnvLBHealthCheck:      &c.Backends.LoadBalancing.HealthCheck,
+		EnvHTTPCacheEnabled:   &c.HTTPCache.Enabled,
+		EnvGRPCWebEnabled:     &c.GRPCWeb.Enabled,
+		EnvCORSEnabled:        &c.CORS.Enabled,
+		EnvCompressionEnabled: &c.Compression.Enabled,
+	}
+	for name, field := range boolVars {
+		if v, ok := lookupEnv(name); ok {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return fmt.Errorf("invalid %s: %w", name, err)
+			}
+			*field = b
+		}
+	}
+
+	if v, ok := lookupEnv(EnvRateLimitRate); ok {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", EnvRateLimitRate, err)
+		}
+		c.RateLimit.Default.RequestsPerSecond = f
+	}
+	if v, ok := lookupEnv(EnvRateLimitBurst); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", EnvRateLimitBurst, err)
+		}
+		c.RateLimit.Default.Burst = n
+	}
+
+	durationVars := map[string]*time.Duration{
+		EnvBackendTimeout:     &c.Backends.Timeout,
+		EnvBreakerOpenTimeout: &c.Backends.Breaker.OpenTimeout,
+		EnvLBRefreshInterval:  &c.Backends.LoadBalancing.RefreshInterval,
+		EnvTLSReloadInterval:  &c.TLS.ReloadInterval,
+		EnvReadHeaderTimeout:  &c.Timeouts.ReadHeader,
+		EnvReadTimeout:        &c.Timeouts.Read,
+		EnvWriteTimeout:       &c.Timeouts.Write,
+		EnvIdleTimeout:        &c.Timeouts.Idle,
+		EnvShutdownTimeout:    &c.Timeouts.Shutdown,
+		EnvHTTPCacheMinAge:    &c.HTTPCache.MinAge,
+		EnvHTTPCacheMaxAge:    &c.HTTPCache.MaxAge,
+	}
+	for name, field := range durationVars {
+		if v, ok := lookupEnv(name); ok {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return fmt.Errorf("invalid %s: %w", name, err)
+			}
+			*field = d
+		}
+	}
+
+	return nil
+}
+
+// applyFlags copies the values of explicitly set flags onto the configuration.
+func (c *Config) applyFlags(fs *flag.FlagSet) {
+	fs.Visit(func(f *flag.Flag) {
+		value := f.Value.(flag.Getter).Get()
+		switch f.Name {
+		case "api-endpoint":
+			c.HTTP.Endpoint = value.(string)
+		case "racing-grpc-endpoint":
+			c.Backends.Racing = value.(string)
+		case "sports-grpc-endpoint":
+			c.Backends.Sports = value.(string)
+		case "backend-timeout":
+			c.Backends.Timeout = value.(time.Duration)
+		case "retry-max-attempts":
+			c.Backends.Retry.MaxAttempts = value.(int)
+		case "breaker-enabled":
+			c.Backends.Breaker.Enabled = value.(bool)
+		case "breaker-failure-threshold":
+			c.Backends.Breaker.FailureThreshold = uint32(value.(uint))
+		case "breaker-open-timeout":
+			c.Backends.Breaker.OpenTimeout = value.(time.Duration)
+		case "lb-policy":
+			c.Backends.LoadBalancing.Policy = value.(string)
+		case "lb-health-check":
+			c.Backends.LoadBalancing.HealthCheck = value.(bool)
+		case "lb-refresh-interval":
+			c.Backends.LoadBalancing.RefreshInterval = value.(time.Duration)
+		case "tls-enabled":
+			c.TLS.Enabled = value.(bool)
+		case "tls-ca-file":
+			c.TLS.CAFile = value.(string)
+		case "tls-cert-file":
+			c.TLS.CertFile = value.(string)
+		case "tls-key-file":
+			c.TLS.KeyFile = value.(string)
+		case "tls-server-name":
+			c.TLS.ServerName = value.(string)
+		case "tls-reload-interval":
+			c.TLS.ReloadInterval = value.(time.Duration)
+		case "read-header-timeout":
+			c.Timeouts.ReadHeader = value.(time.Duration)
+		case "read-timeout":
+			c.Timeouts.Read = value.(time.Duration)
+		case "write-timeout":
+			c.Timeouts.Write = value.(time.Duration)
+		case "idle-timeout":
+			c.Timeouts.Idle = value.(time.Duration)
+		case "shutdown-timeout":
+			c.Timeouts.Shutdown = value.(time.Duration)
+		case "auth-jwks-file":
+			c.Auth.JWKSFile = value.(string)
+		case "auth-issuer":
+			c.Auth.Issuer = value.(string)
+		case "auth-audience":
+			c.Auth.Audience = value.(string)
+		case "auth-roles-claim":
+			c.Auth.RolesClaim = value.(string)
+		case "rate-limit-enabled":
+			c.RateLimit.Enabled = value.(bool)
+		case "rate-limit-rate":
+			c.RateLimit.Default.RequestsPerSecond = value.(float64)
+		case "rate-limit-burst":
+			c.RateLimit.Default.Burst = value.(int)
+		case "http-cache-enabled":
+			c.HTTPCache.Enabled = value.(bool)
+		case "http-cache-min-age":
+			c.HTTPCache.MinAge = value.(time.Duration)
+		case "http-cache-max-age":
+			c.HTTPCache.MaxAge = value.(time.Duration)
+		case "grpc-web-enabled":
+			c.GRPCWeb.Enabled = value.(bool)
+		case "cors-enabled":
+			c.CORS.Enabled = value.(bool)
+		case "cors-allowed-origins":
+			c.CORS.AllowedOrigins = splitList(value.(string))
+		case "compression-enabled":
+			c.Compression.Enabled = value.(bool)
+		case "compression-min-size":
+			c.Compression.MinSize = value.(int)
+		case "log-level":
+			c.Log.Level = value.(string)
+		case "environment":
+			c.Log.Environment = value.(string)
+		}
+	})
+}
+
+// Validate checks the configuration for missing or inconsistent settings.
+func (c Config) Validate() error {
+	var problems []string
+
+	if _, _, err := net.SplitHostPort(c.HTTP.Endpoint); err != nil {
+		problems = append(problems, fmt.Sprintf("http.endpoint %q is not a valid host:port", c.HTTP.Endpoint))
+	}
+
+	backends := []struct {
+		name  string
+		value string
+	}{
+		{"backends.racing", c.Backends.Racing},
+		{"backends.sports", c.Backends.Sports},
+	}
+	for _, b := range backends {
+		if err := validateBackend(b.value); err != nil {
+			problems = append(problems, fmt.Sprintf("%s %q %s", b.name, b.value, err))
+		}
+		// Several replicas share no single host name to verify certificates against
+		multiple := strings.Contains(b.value, ",") || strings.HasPrefix(b.value, "file:")
+		if c.TLS.Enabled && c.TLS.ServerName == "" && multiple {
+			problems = append(problems, fmt.Sprintf("%s lists several endpoints, which requires tls.server_name", b.name))
+		}
+	}
+
+	if c.Backends.Timeout < 0 {
+		problems = append(problems, "backends.timeout must not be negative")
+	}
+	for method, timeout := range c.Backends.MethodTimeouts {
+		if !strings.HasPrefix(method, "/") || strings.Count(method, "/") != 2 {
+			problems = append(problems, fmt.Sprintf("backends.method_timeouts key %q must look like \"/package.Service/Method\"", method))
+		}
+		if timeout <= 0 {
+			problems = append(problems, fmt.Sprintf("backends.method_timeouts[%q] must be positive", method))
+		}
+	}
+	if r := c.Backends.Retry; r.MaxAttempts > 1 {
+		if r.MaxAttempts > 5 {
+			problems = append(problems, "backends.retry.max_attempts must be at most 5")
+		}
+		if r.InitialBackoff <= 0 || r.MaxBackoff < r.InitialBackoff || r.BackoffMultiplier <= 0 {
+			problems = append(problems, "backends.retry needs a positive initial_backoff and backoff_multiplier, and max_backoff >= initial_backoff")
+		}
+	}
+	if b := c.Backends.Breaker; b.Enabled && (b.FailureThreshold < 1 || b.OpenTimeout <= 0 || b.HalfOpenRequests < 1) {
+		problems = append(problems, "backends.breaker needs a positive failure_threshold, open_timeout and half_open_requests")
+	}
+
+	switch lb := c.Backends.LoadBalancing; {
+	case lb.Policy != PolicyRoundRobin && lb.Policy != PolicyLeastRequest && lb.Policy != PolicyPickFirst:
+		problems = append(problems, fmt.Sprintf("backends.load_balancing.policy %q must be %s, %s or %s", lb.Policy, PolicyRoundRobin, PolicyLeastRequest, PolicyPickFirst))
+	case lb.RefreshInterval <= 0:
+		problems = append(problems, "backends.load_balancing.refresh_interval must be positive")
+	}
+
+	if (c.TLS.CertFile == "") != (c.TLS.KeyFile == "") {
+		problems = append(problems, "tls.cert_file and tls.key_file must be set together")
+	}
+	if !c.TLS.Enabled && (c.TLS.CAFile != "" || c.TLS.CertFile != "") {
+		problems = append(problems, "tls files are set but tls.enabled is false")
+	}
+	if c.TLS.Enabled && c.TLS.ReloadInterval <= 0 {
+		problems = append(problems, "tls.reload_interval must be positive")
+	}
+
+	timeouts := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"timeouts.read_header", c.Timeouts.ReadHeader},
+		{"timeouts.read", c.Timeouts.Read},
+		{"timeouts.write", c.Timeouts.Write},
+		{"timeouts.idle", c.Timeouts.Idle},
+		{"timeouts.shutdown", c.Timeouts.Shutdown},
+	}
+	for _, t := range timeouts {
+		if t.value < 0 {
+			problems = append(problems, t.name+" must not be negative")
+		}
+	}
+
+	if c.Auth.JWKSFile == "" && (c.Auth.Issuer != "" || c.Auth.Audience != "") {
+		problems = append(problems, "auth.issuer and auth.audience require auth.jwks_file")
+	}
+	if c.Auth.JWKSFile != "" && c.Auth.RolesClaim == "" {
+		problems = append(problems, "auth.roles_claim is required")
+	}
+
+	if c.RateLimit.Enabled {
+		limits := map[string]RateLimit{"rate_limit.default": c.RateLimit.Default}
+		for route, limit := range c.RateLimit.Routes {
+			if len(strings.Fields(route)) != 2 || !strings.HasPrefix(strings.Fields(route)[1], "/") {
+				problems = append(problems, fmt.Sprintf("rate_limit.routes key %q must look like \"METHOD /path\"", route))
+			}
+			limits[fmt.Sprintf("rate_limit.routes[%q]", route)] = limit
+		}
+		for name, limit := range limits {
+			if limit.RequestsPerSecond <= 0 || limit.Burst < 1 {
+				problems = append(problems, name+" needs a positive requests_per_second and burst")
+			}
+		}
+	}
+
+	if h := c.HTTPCache; h.Enabled && (h.MinAge <= 0 || h.MaxAge < h.MinAge) {
+		problems = append(problems, "http_cache needs a positive min_age and max_age >= min_age")
+	}
+
+	if c.CORS.Enabled {
+		problems = append(problems, c.CORS.validate()...)
+	}
+
+	if c.Compression.Enabled && c.Compression.MinSize < 0 {
+		problems = append(problems, "compression.min_size must not be negative")
+	}
+
+	if _, err := logger.ParseLevel(c.Log.Level); err != nil {
+		problems = append(problems, "log.level: "+err.Error())
+	}
+	if _, err := logger.ParseEnvironment(c.Log.Environment); err != nil {
+		problems = append(problems, "log.environment: "+err.Error())
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid configuration: %s", strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+// validateBackend checks that target is one of the endpoint forms described
+// on BackendsConfig.
+func validateBackend(target string) error {
+	switch {
+	case strings.HasPrefix(target, "dns:///"):
+		if _, _, err := net.SplitHostPort(strings.TrimPrefix(target, "dns:///")); err != nil {
+			return errors.New("must look like dns:///host:port")
+		}
+	case strings.HasPrefix(target, "file:"):
+		if strings.Trim(strings.TrimPrefix(target, "file:"), "/") == "" {
+			return errors.New("must look like file:///path")
+		}
+	default:
+		for _, endpoint := range strings.Split(target, ",") {
+			if _, _, err := net.SplitHostPort(strings.TrimSpace(endpoint)); err != nil {
+				return errors.New("is not a valid host:port or comma-separated list of them")
+			}
+		}
+	}
+	return nil
+}
+
+// validate checks the CORS policy.
+func (c CORSConfig) validate() []string {
+	var problems []string
+	for _, origin := range c.AllowedOrigins {
+		if origin == "*" {
+			if c.AllowCredentials {
+				problems = append(problems, "cors.allow_credentials cannot be combined with the \"*\" origin")
+			}
+			continue
+		}
+		u, err := url.Parse(strings.Replace(origin, "://*.", "://", 1))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.Path != "" || u.RawQuery != "" || strings.Contains(u.Host, "*") {
+			problems = append(problems, fmt.Sprintf("cors.allowed_origins entry %q must look like \"https://host[:port]\" or \"https://*.domain\"", origin))
+		}
+	}
+	if len(c.AllowedMethods) == 0 {
+		problems = append(problems, "cors.allowed_methods must not be empty")
+	}
+	if c.MaxAge < 0 {
+		problems = append(problems, "cors.max_age must not be negative")
+	}
+	return problems
+}
+
+// splitList splits a comma separated list, dropping empty entries.
+func splitList(v string) []string {
+	var items []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Logger returns the logger configuration. It assumes Validate has passed.
+func (c Config) Logger() logger.Config {
+	level, _ := logger.ParseLevel(c.Log.Level)
+	env, _ := logger.ParseEnvironment(c.Log.Environment)
+	return logger.Config{
+		Level:       level,
+		Environment: env,
+	}
+}
+
+// MarshalLogObject renders the effective configuration for structured logging.
+func (c Config) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("http.endpoint", c.HTTP.Endpoint)
+	enc.AddString("backends.racing", c.Backends.Racing)
+	enc.AddString("backends.sports", c.Backends.Sports)
+	enc.AddDuration("backends.timeout", c.Backends.Timeout)
+	enc.AddInt("backends.method_timeouts", len(c.Backends.MethodTimeouts))
+	enc.AddInt("backends.retry.max_attempts", c.Backends.Retry.MaxAttempts)
+	enc.AddBool("backends.breaker.enabled", c.Backends.Breaker.Enabled)
+	enc.AddUint32("backends.breaker.failure_threshold", c.Backends.Breaker.FailureThreshold)
+	enc.AddDuration("backends.breaker.open_timeout", c.Backends.Breaker.OpenTimeout)
+	enc.AddString("backends.load_balancing.policy", c.Backends.LoadBalancing.Policy)
+	enc.AddBool("backends.load_balancing.health_check", c.Backends.LoadBalancing.HealthCheck)
+	enc.AddDuration("backends.load_balancing.refresh_interval", c.Backends.LoadBalancing.RefreshInterval)
+	enc.AddBool("tls.enabled", c.TLS.Enabled)
+	enc.AddString("tls.ca_file", c.TLS.CAFile)
+	enc.AddString("tls.cert_file", c.TLS.CertFile)
+	enc.AddString("tls.key_file", c.TLS.KeyFile)
+	enc.AddString("tls.server_name", c.TLS.ServerName)
+	enc.AddDuration("tls.reload_interval", c.TLS.ReloadInterval)
+	enc.AddDuration("timeouts.read_header", c.Timeouts.ReadHeader)
+	enc.AddDuration("timeouts.read", c.Timeouts.Read)
+	enc.AddDuration("timeouts.write", c.Timeouts.Write)
+	enc.AddDuration("timeouts.idle", c.Timeouts.Idle)
+	enc.AddDuration("timeouts.shutdown", c.Timeouts.Shutdown)
+	enc.AddString("auth.jwks_file", c.Auth.JWKSFile)
+	enc.AddString("auth.issuer", c.Auth.Issuer)
+	enc.AddString("auth.audience", c.Auth.Audience)
+	enc.AddString("auth.roles_claim", c.Auth.RolesClaim)
+	enc.AddBool("rate_limit.enabled", c.RateLimit.Enabled)
+	enc.AddFloat64("rate_limit.default.requests_per_second", c.RateLimit.Default.RequestsPerSecond)
+	enc.AddInt("rate_limit.default.burst", c.RateLimit.Default.Burst)
+	enc.AddInt("rate_limit.routes", len(c.RateLimit.Routes))
+	enc.AddInt("rate_limit.api_keys", len(c.RateLimit.APIKeys))
+	enc.AddBool("http_cache.enabled", c.HTTPCache.Enabled)
+	enc.AddDuration("http_cache.min_age", c.HTTPCache.MinAge)
+	enc.AddDuration("http_cache.max_age", c.HTTPCache.MaxAge)
+	enc.AddBool("grpc_web.enabled", c.GRPCWeb.Enabled)
+	enc.AddBool("cors.enabled", c.CORS.Enabled)
+	enc.AddString("cors.allowed_origins", strings.Join(c.CORS.AllowedOrigins, ","))
+	enc.AddBool("cors.allow_credentials", c.CORS.AllowCredentials)
+	enc.AddDuration("cors.max_age", c.CORS.MaxAge)
+	enc.AddBool("compression.enabled", c.Compression.Enabled)
+	enc.AddInt("compression.min_size", c.Compression.MinSize)
+	enc.AddString("log.level", c.Log.Level)
+	enc.AddString("log.environment", c.Log.Environment)
+	return nil
+}
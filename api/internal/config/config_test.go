@@ -0,0 +1,429 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// envMap returns a lookup function backed by the given map
+func envMap(env map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+}
+
+// writeConfigFile writes a YAML config file into a temp dir and returns its path
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "api.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("writeConfigFile() failed: %v", err)
+	}
+	return path
+}
+
+// writeTOMLConfigFile writes a TOML config file into a temp dir and returns its path
+func writeTOMLConfigFile(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "api.toml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("writeTOMLConfigFile() failed: %v", err)
+	}
+	return path
+}
+
+func TestLoad_Layering(t *testing.T) {
+	path := writeConfigFile(t, `
+http:
+  endpoint: 0.0.0.0:8080
+backends:
+  racing: racing:9000
+  sports: sports:9001
+timeouts:
+  write: 1m
+`)
+
+	tests := []struct {
+		name string
+		args []string
+		env  map[string]string
+		want func(c *Config)
+	}{
+		{
+			name: "defaults",
+			want: func(c *Config) {},
+		},
+		{
+			name: "file overrides defaults",
+			args: []string{"--config", path},
+			want: func(c *Config) {
+				c.HTTP.Endpoint = "0.0.0.0:8080"
+				c.Backends.Racing, c.Backends.Sports = "racing:9000", "sports:9001"
+				c.Timeouts.Write = time.Minute
+			},
+		},
+		{
+			name: "env overrides file",
+			args: []string{"--config", path},
+			env: map[string]string{
+				EnvSportsGRPCEndpoint: "sports-canary:9001",
+				EnvWriteTimeout:       "10s",
+			},
+			want: func(c *Config) {
+				c.HTTP.Endpoint = "0.0.0.0:8080"
+				c.Backends.Racing, c.Backends.Sports = "racing:9000", "sports-canary:9001"
+				c.Timeouts.Write = 10 * time.Second
+			},
+		},
+		{
+			name: "legacy endpoint flags override everything",
+			args: []string{"--config", path, "--racing-grpc-endpoint", "localhost:19000", "--api-endpoint", "localhost:18000"},
+			env:  map[string]string{EnvRacingGRPCEndpoint: "racing-env:9000"},
+			want: func(c *Config) {
+				c.HTTP.Endpoint = "localhost:18000"
+				c.Backends.Racing, c.Backends.Sports = "localhost:19000", "sports:9001"
+				c.Timeouts.Write = time.Minute
+			},
+		},
+		{
+			name: "backend resilience from env and flags",
+			args: []string{"--breaker-failure-threshold", "10", "--breaker-enabled=false"},
+			env: map[string]string{
+				EnvBackendTimeout:     "2s",
+				EnvRetryMaxAttempts:   "1",
+				EnvBreakerOpenTimeout: "1m",
+			},
+			want: func(c *Config) {
+				c.Backends.Timeout = 2 * time.Second
+				c.Backends.Retry.MaxAttempts = 1
+				c.Backends.Breaker.Enabled = false
+				c.Backends.Breaker.FailureThreshold = 10
+				c.Backends.Breaker.OpenTimeout = time.Minute
+			},
+		},
+		{
+			name: "load balancing from env and flags",
+			args: []string{"--lb-policy", "least_request", "--racing-grpc-endpoint", "racing-1:9000,racing-2:9000"},
+			env: map[string]string{
+				EnvLBHealthCheck:      "false",
+				EnvLBRefreshInterval:  "1m",
+				EnvSportsGRPCEndpoint: "dns:///sports:9001",
+			},
+			want: func(c *Config) {
+				c.Backends.Racing, c.Backends.Sports = "racing-1:9000,racing-2:9000", "dns:///sports:9001"
+				c.Backends.LoadBalancing = LoadBalancingConfig{Policy: PolicyLeastRequest, RefreshInterval: time.Minute}
+			},
+		},
+		{
+			name: "rate limit from env and flags",
+			args: []string{"--rate-limit-burst", "100"},
+			env: map[string]string{
+				EnvRateLimitRate:    "50",
+				EnvRateLimitEnabled: "false",
+			},
+			want: func(c *Config) {
+				c.RateLimit.Enabled = false
+				c.RateLimit.Default = RateLimit{RequestsPerSecond: 50, Burst: 100}
+			},
+		},
+		{
+			name: "http cache from env and flags",
+			args: []string{"--http-cache-max-age", "5m"},
+			env:  map[string]string{EnvHTTPCacheMinAge: "2s"},
+			want: func(c *Config) {
+				c.HTTPCache = HTTPCacheConfig{Enabled: true, MinAge: 2 * time.Second, MaxAge: 5 * time.Minute}
+			},
+		},
+		{
+			name: "grpc-web from env",
+			env:  map[string]string{EnvGRPCWebEnabled: "false"},
+			want: func(c *Config) {
+				c.GRPCWeb.Enabled = false
+			},
+		},
+		{
+			name: "cors origins from env and flag",
+			args: []string{"--cors-allowed-origins", "https://app.example.com, https://*.example.com"},
+			env:  map[string]string{EnvCORSAllowedOrigins: "*", EnvCORSEnabled: "true"},
+			want: func(c *Config) {
+				c.CORS.AllowedOrigins = []string{"https://app.example.com", "https://*.example.com"}
+			},
+		},
+		{
+			name: "cors origins from env",
+			env:  map[string]string{EnvCORSAllowedOrigins: "https://app.example.com,"},
+			want: func(c *Config) {
+				c.CORS.AllowedOrigins = []string{"https://app.example.com"}
+			},
+		},
+		{
+			name: "compression from env and flags",
+			args: []string{"--compression-min-size", "256"},
+			env:  map[string]string{EnvCompressionEnabled: "false", EnvCompressionMinSize: "512"},
+			want: func(c *Config) {
+				c.Compression = CompressionConfig{Enabled: false, MinSize: 256}
+			},
+		},
+		{
+			name: "auth from env and flags",
+			args: []string{"--auth-audience", "entain-api"},
+			env: map[string]string{
+				EnvAuthJWKSFile: "/etc/entain/jwks.json",
+				EnvAuthIssuer:   "https://auth.example.com/",
+			},
+			want: func(c *Config) {
+				c.Auth = AuthConfig{
+					JWKSFile:   "/etc/entain/jwks.json",
+					Issuer:     "https://auth.example.com/",
+					Audience:   "entain-api",
+					RolesClaim: "roles",
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := load(tt.args, envMap(tt.env))
+			if err != nil {
+				t.Fatalf("load(%v) failed: %v", tt.args, err)
+			}
+
+			want := Default()
+			tt.want(&want)
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("load(%v) mismatch (-want +got):\n%s", tt.args, diff)
+			}
+		})
+	}
+}
+
+func TestLoad_TOML(t *testing.T) {
+	path := writeTOMLConfigFile(t, `
+[http]
+endpoint = "0.0.0.0:8080"
+
+[backends]
+racing = "racing:9000"
+sports = "sports:9001"
+
+[timeouts]
+write = "1m"
+`)
+
+	got, err := load([]string{"--config", path}, envMap(nil))
+	if err != nil {
+		t.Fatalf("load() failed: %v", err)
+	}
+
+	want := Default()
+	want.HTTP.Endpoint = "0.0.0.0:8080"
+	want.Backends.Racing, want.Backends.Sports = "racing:9000", "sports:9001"
+	want.Timeouts.Write = time.Minute
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("load() mismatch (-want +got):\n%s", diff)
+	}
+
+	// Unknown keys are rejected as in YAML files
+	_, err = load([]string{"--config", writeTOMLConfigFile(t, "[http]\nendpiont = \"0.0.0.0:8080\"\n")}, envMap(nil))
+	if err == nil || !strings.Contains(err.Error(), "failed to parse config file") {
+		t.Errorf("load() error = %v, want an unknown key error", err)
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(c *Config)
+		wantErr string
+	}{
+		{
+			name:   "defaults are valid",
+			modify: func(c *Config) {},
+		},
+		{
+			name:    "invalid backend endpoint",
+			modify:  func(c *Config) { c.Backends.Sports = "sports" },
+			wantErr: "backends.sports",
+		},
+		{
+			name: "backend endpoint forms",
+			modify: func(c *Config) {
+				c.Backends.Racing = "racing-1:9000, racing-2:9000"
+				c.Backends.Sports = "file:///etc/entain/sports.endpoints"
+			},
+		},
+		{
+			name:    "invalid endpoint in list",
+			modify:  func(c *Config) { c.Backends.Racing = "racing-1:9000,racing-2" },
+			wantErr: "backends.racing",
+		},
+		{
+			name:    "dns target without port",
+			modify:  func(c *Config) { c.Backends.Racing = "dns:///racing" },
+			wantErr: "dns:///host:port",
+		},
+		{
+			name: "several endpoints over tls without server name",
+			modify: func(c *Config) {
+				c.Backends.Racing = "racing-1:9000,racing-2:9000"
+				c.TLS.Enabled = true
+			},
+			wantErr: "tls.server_name",
+		},
+		{
+			name:    "unknown load balancing policy",
+			modify:  func(c *Config) { c.Backends.LoadBalancing.Policy = "random" },
+			wantErr: "backends.load_balancing.policy",
+		},
+		{
+			name: "client cert without key",
+			modify: func(c *Config) {
+				c.TLS = TLSConfig{Enabled: true, CertFile: "client.pem"}
+			},
+			wantErr: "must be set together",
+		},
+		{
+			name:    "tls files without tls",
+			modify:  func(c *Config) { c.TLS.CAFile = "ca.pem" },
+			wantErr: "tls.enabled is false",
+		},
+		{
+			name:    "negative timeout",
+			modify:  func(c *Config) { c.Timeouts.Idle = -time.Second },
+			wantErr: "timeouts.idle",
+		},
+		{
+			name:    "issuer without jwks file",
+			modify:  func(c *Config) { c.Auth.Issuer = "https://auth.example.com/" },
+			wantErr: "auth.jwks_file",
+		},
+		{
+			name: "jwks file without roles claim",
+			modify: func(c *Config) {
+				c.Auth = AuthConfig{JWKSFile: "jwks.json"}
+			},
+			wantErr: "auth.roles_claim",
+		},
+		{
+			name: "method timeout with route key",
+			modify: func(c *Config) {
+				c.Backends.MethodTimeouts = map[string]time.Duration{"GET /v1/races/{id}": time.Second}
+			},
+			wantErr: "/package.Service/Method",
+		},
+		{
+			name:    "too many retry attempts",
+			modify:  func(c *Config) { c.Backends.Retry.MaxAttempts = 6 },
+			wantErr: "at most 5",
+		},
+		{
+			name: "retries disabled need no backoff",
+			modify: func(c *Config) {
+				c.Backends.Retry = RetryConfig{MaxAttempts: 1}
+			},
+		},
+		{
+			name:    "breaker without threshold",
+			modify:  func(c *Config) { c.Backends.Breaker.FailureThreshold = 0 },
+			wantErr: "backends.breaker",
+		},
+		{
+			name: "rate limit route without burst",
+			modify: func(c *Config) {
+				c.RateLimit.Routes["GET /v1/races/{id}"] = RateLimit{RequestsPerSecond: 5}
+			},
+			wantErr: `rate_limit.routes["GET /v1/races/{id}"]`,
+		},
+		{
+			name: "malformed rate limit route",
+			modify: func(c *Config) {
+				c.RateLimit.Routes["/v1/list-races"] = RateLimit{RequestsPerSecond: 5, Burst: 5}
+			},
+			wantErr: "METHOD /path",
+		},
+		{
+			name: "disabled rate limit is not validated",
+			modify: func(c *Config) {
+				c.RateLimit = RateLimitConfig{Enabled: false}
+			},
+		},
+		{
+			name: "http cache max age below min age",
+			modify: func(c *Config) {
+				c.HTTPCache.MinAge, c.HTTPCache.MaxAge = time.Minute, time.Second
+			},
+			wantErr: "http_cache",
+		},
+		{
+			name: "cors origins",
+			modify: func(c *Config) {
+				c.CORS.AllowedOrigins = []string{"*", "https://app.example.com", "http://localhost:3000", "https://*.example.com"}
+			},
+		},
+		{
+			name: "cors origin with a path",
+			modify: func(c *Config) {
+				c.CORS.AllowedOrigins = []string{"https://app.example.com/"}
+			},
+			wantErr: "cors.allowed_origins",
+		},
+		{
+			name: "cors wildcard inside a host",
+			modify: func(c *Config) {
+				c.CORS.AllowedOrigins = []string{"https://app*.example.com"}
+			},
+			wantErr: "cors.allowed_origins",
+		},
+		{
+			name: "cors credentials for any origin",
+			modify: func(c *Config) {
+				c.CORS.AllowedOrigins = []string{"*"}
+				c.CORS.AllowCredentials = true
+			},
+			wantErr: "cors.allow_credentials",
+		},
+		{
+			name: "disabled cors is not validated",
+			modify: func(c *Config) {
+				c.CORS = CORSConfig{AllowedOrigins: []string{"example.com"}}
+			},
+		},
+		{
+			name:    "negative compression min size",
+			modify:  func(c *Config) { c.Compression.MinSize = -1 },
+			wantErr: "compression.min_size",
+		},
+		{
+			name:    "unknown environment",
+			modify:  func(c *Config) { c.Log.Environment = "staging" },
+			wantErr: "log.environment",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Default()
+			tt.modify(&cfg)
+
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
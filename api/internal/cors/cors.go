@@ -0,0 +1,139 @@
+package cors
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"git.neds.sh/matty/entain/api/internal/config"
+)
+
+// Policy decides which cross-origin requests browsers may make.
+type Policy struct {
+	exact            map[string]bool
+	wildcards        []wildcard
+	anyOrigin        bool
+	allowedMethods   string
+	allowedHeaders   string
+	anyHeader        bool
+	exposedHeaders   string
+	allowCredentials bool
+	maxAge           string
+}
+
+// wildcard matches the subdomains of an origin, from a pattern such as
+// "https://*.example.com".
+type wildcard struct {
+	prefix string // "https://"
+	suffix string // ".example.com", with the port if any
+}
+
+// New creates the policy described by cfg, which must have passed validation.
+func New(cfg config.CORSConfig) *Policy {
+	p := &Policy{
+		exact:            make(map[string]bool),
+		allowedMethods:   strings.Join(cfg.AllowedMethods, ", "),
+		allowedHeaders:   strings.Join(cfg.AllowedHeaders, ", "),
+		exposedHeaders:   strings.Join(cfg.ExposedHeaders, ", "),
+		allowCredentials: cfg.AllowCredentials,
+		maxAge:           strconv.Itoa(int(cfg.MaxAge.Seconds())),
+	}
+	for _, origin := range cfg.AllowedOrigins {
+		switch {
+		case origin == "*":
+			p.anyOrigin = true
+		case strings.Contains(origin, "://*."):
+			i := strings.Index(origin, "://*.")
+			p.wildcards = append(p.wildcards, wildcard{prefix: strings.ToLower(origin[:i+3]), suffix: strings.ToLower(origin[i+4:])})
+		default:
+			p.exact[strings.ToLower(origin)] = true
+		}
+	}
+	for _, header := range cfg.AllowedHeaders {
+		if header == "*" {
+			p.anyHeader = true
+		}
+	}
+	return p
+}
+
+// Allowed reports whether pages on origin may read responses.
+func (p *Policy) Allowed(origin string) bool {
+	if origin == "" {
+		return false
+	}
+	if p.anyOrigin {
+		return true
+	}
+
+	origin = strings.ToLower(origin)
+	if p.exact[origin] {
+		return true
+	}
+	for _, w := range p.wildcards {
+		if !strings.HasPrefix(origin, w.prefix) || !strings.HasSuffix(origin, w.suffix) {
+			continue
+		}
+		sub := origin[len(w.prefix) : len(origin)-len(w.suffix)]
+		if sub != "" && !strings.ContainsAny(sub, "/:@") {
+			return true
+		}
+	}
+	return false
+}
+
+// Middleware applies the policy. Preflight requests are answered directly,
+// without reaching next, so they never call a backend or use up a rate
+// limit. Other requests from allowed origins get the CORS response headers,
+// whatever their outcome, so that pages can read errors too.
+func (p *Policy) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			p.preflight(w, r, origin)
+			return
+		}
+
+		w.Header().Add("Vary", "Origin")
+		if p.Allowed(origin) {
+			p.allowOrigin(w, origin)
+			if p.exposedHeaders != "" {
+				w.Header().Set("Access-Control-Expose-Headers", p.exposedHeaders)
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// preflight answers a preflight request. Disallowed origins get no CORS
+// headers, which the browser treats as a refusal; the methods and headers
+// are checked by the browser against the lists returned.
+func (p *Policy) preflight(w http.ResponseWriter, r *http.Request, origin string) {
+	h := w.Header()
+	h.Add("Vary", "Origin")
+	h.Add("Vary", "Access-Control-Request-Method")
+	h.Add("Vary", "Access-Control-Request-Headers")
+
+	if p.Allowed(origin) {
+		p.allowOrigin(w, origin)
+		h.Set("Access-Control-Allow-Methods", p.allowedMethods)
+		if p.anyHeader {
+			// "*" is not a wildcard for Authorization, so the request's own list is echoed
+			if requested := r.Header.Get("Access-Control-Request-Headers"); requested != "" {
+				h.Set("Access-Control-Allow-Headers", requested)
+			}
+		} else if p.allowedHeaders != "" {
+			h.Set("Access-Control-Allow-Headers", p.allowedHeaders)
+		}
+		h.Set("Access-Control-Max-Age", p.maxAge)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (p *Policy) allowOrigin(w http.ResponseWriter, origin string) {
+	w.Header().Set("Access-Control-Allow-Origin", origin)
+	if p.allowCredentials {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+}
@@ -0,0 +1,204 @@
+package cors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"git.neds.sh/matty/entain/api/internal/config"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPolicy_Allowed(t *testing.T) {
+	p := New(config.CORSConfig{
+		AllowedOrigins: []string{"https://app.example.com", "https://*.entain.com", "http://localhost:3000"},
+	})
+
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{origin: "https://app.example.com", want: true},
+		{origin: "HTTPS://APP.EXAMPLE.COM", want: true},
+		{origin: "http://app.example.com", want: false},
+		{origin: "https://app.example.com:8443", want: false},
+		{origin: "https://www.entain.com", want: true},
+		{origin: "https://a.b.entain.com", want: true},
+		{origin: "https://entain.com", want: false},
+		{origin: "https://evilentain.com", want: false},
+		{origin: "https://www.entain.com.evil.com", want: false},
+		{origin: "https://evil.com:.entain.com", want: false},
+		{origin: "http://www.entain.com", want: false},
+		{origin: "http://localhost:3000", want: true},
+		{origin: "http://localhost:3001", want: false},
+		{origin: "null", want: false},
+		{origin: "", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := p.Allowed(tt.origin); got != tt.want {
+			t.Errorf("Allowed(%q) = %v, want %v", tt.origin, got, tt.want)
+		}
+	}
+}
+
+func TestPolicy_Middleware(t *testing.T) {
+	cfg := config.CORSConfig{
+		AllowedOrigins: []string{"https://app.example.com", "https://*.entain.com"},
+		AllowedMethods: []string{http.MethodGet, http.MethodPost},
+		AllowedHeaders: []string{"Authorization", "Content-Type"},
+		ExposedHeaders: []string{"ETag", "X-Request-Id"},
+		MaxAge:         10 * time.Minute,
+	}
+
+	tests := []struct {
+		name        string
+		cfg         func(c *config.CORSConfig)
+		method      string
+		headers     map[string]string
+		wantStatus  int
+		wantNext    bool
+		wantHeaders http.Header
+	}{
+		{
+			name:   "preflight from an allowed origin",
+			method: http.MethodOptions,
+			headers: map[string]string{
+				"Origin":                         "https://app.example.com",
+				"Access-Control-Request-Method":  http.MethodPost,
+				"Access-Control-Request-Headers": "authorization,content-type",
+			},
+			wantStatus: http.StatusNoContent,
+			wantHeaders: http.Header{
+				"Access-Control-Allow-Origin":  {"https://app.example.com"},
+				"Access-Control-Allow-Methods": {"GET, POST"},
+				"Access-Control-Allow-Headers": {"Authorization, Content-Type"},
+				"Access-Control-Max-Age":       {"600"},
+				"Vary":                         {"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"},
+			},
+		},
+		{
+			name:   "preflight from a wildcard subdomain with credentials",
+			cfg:    func(c *config.CORSConfig) { c.AllowCredentials = true },
+			method: http.MethodOptions,
+			headers: map[string]string{
+				"Origin":                        "https://www.entain.com",
+				"Access-Control-Request-Method": http.MethodGet,
+			},
+			wantStatus: http.StatusNoContent,
+			wantHeaders: http.Header{
+				"Access-Control-Allow-Origin":      {"https://www.entain.com"},
+				"Access-Control-Allow-Credentials": {"true"},
+				"Access-Control-Allow-Methods":     {"GET, POST"},
+				"Access-Control-Allow-Headers":     {"Authorization, Content-Type"},
+				"Access-Control-Max-Age":           {"600"},
+				"Vary":                             {"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"},
+			},
+		},
+		{
+			name:   "preflight allowing any header",
+			cfg:    func(c *config.CORSConfig) { c.AllowedHeaders = []string{"*"} },
+			method: http.MethodOptions,
+			headers: map[string]string{
+				"Origin":                         "https://app.example.com",
+				"Access-Control-Request-Method":  http.MethodPost,
+				"Access-Control-Request-Headers": "authorization,x-custom",
+			},
+			wantStatus: http.StatusNoContent,
+			wantHeaders: http.Header{
+				"Access-Control-Allow-Origin":  {"https://app.example.com"},
+				"Access-Control-Allow-Methods": {"GET, POST"},
+				"Access-Control-Allow-Headers": {"authorization,x-custom"},
+				"Access-Control-Max-Age":       {"600"},
+				"Vary":                         {"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"},
+			},
+		},
+		{
+			name:   "preflight from another origin",
+			method: http.MethodOptions,
+			headers: map[string]string{
+				"Origin":                        "https://evil.example.com",
+				"Access-Control-Request-Method": http.MethodPost,
+			},
+			wantStatus: http.StatusNoContent,
+			wantHeaders: http.Header{
+				"Vary": {"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"},
+			},
+		},
+		{
+			name:       "simple request from an allowed origin",
+			method:     http.MethodGet,
+			headers:    map[string]string{"Origin": "https://app.example.com"},
+			wantStatus: http.StatusOK,
+			wantNext:   true,
+			wantHeaders: http.Header{
+				"Access-Control-Allow-Origin":   {"https://app.example.com"},
+				"Access-Control-Expose-Headers": {"ETag, X-Request-Id"},
+				"Vary":                          {"Origin"},
+			},
+		},
+		{
+			name:       "simple request from another origin",
+			method:     http.MethodGet,
+			headers:    map[string]string{"Origin": "https://evil.example.com"},
+			wantStatus: http.StatusOK,
+			wantNext:   true,
+			wantHeaders: http.Header{
+				"Vary": {"Origin"},
+			},
+		},
+		{
+			name:       "same-origin request",
+			method:     http.MethodPost,
+			wantStatus: http.StatusOK,
+			wantNext:   true,
+			wantHeaders: http.Header{
+				"Vary": {"Origin"},
+			},
+		},
+		{
+			name:       "OPTIONS without a preflight is passed on",
+			method:     http.MethodOptions,
+			headers:    map[string]string{"Origin": "https://app.example.com"},
+			wantStatus: http.StatusOK,
+			wantNext:   true,
+			wantHeaders: http.Header{
+				"Access-Control-Allow-Origin":   {"https://app.example.com"},
+				"Access-Control-Expose-Headers": {"ETag, X-Request-Id"},
+				"Vary":                          {"Origin"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := cfg
+			if tt.cfg != nil {
+				tt.cfg(&c)
+			}
+
+			var reachedNext bool
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				reachedNext = true
+			})
+
+			req := httptest.NewRequest(tt.method, "/v1/races", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			New(c).Middleware(next).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if reachedNext != tt.wantNext {
+				t.Errorf("reached next = %v, want %v", reachedNext, tt.wantNext)
+			}
+			if diff := cmp.Diff(tt.wantHeaders, rec.Header()); diff != "" {
+				t.Errorf("headers mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
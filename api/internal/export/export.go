@@ -0,0 +1,527 @@
+package export
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"git.neds.sh/matty/entain/api/internal/query"
+	"git.neds.sh/matty/entain/api/proto/racing"
+	"git.neds.sh/matty/entain/api/proto/sports"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/utilities"
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Routes of the exports.
+const (
+	RacesPath    = "/v1/races/export"
+	EventsPath   = "/v1/events/export"
+	CalendarPath = "/v1/events/calendar.ics"
+)
+
+// Formats of the race and event exports, chosen with the format query
+// parameter. The Accept header is not used: the gateway narrows it to the
+// media types of its protobuf responses.
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+)
+
+// Media types of the exports.
+const (
+	ContentTypeCSV      = "text/csv; charset=utf-8"
+	ContentTypeNDJSON   = "application/x-ndjson"
+	ContentTypeCalendar = "text/calendar; charset=utf-8"
+)
+
+// flushRows is how many rows are written between flushes. Batching keeps
+// bodies large enough to be compressed, while long exports still reach the
+// client as they are written instead of once complete.
+const flushRows = 100
+
+// jsonMarshaler renders NDJSON rows as the gateway renders resources by default.
+var jsonMarshaler = &runtime.JSONPb{MarshalOptions: protojson.MarshalOptions{EmitUnpopulated: true}}
+
+// Handler serves exports of the races and events the list routes return, and
+// a calendar of upcoming events.
+type Handler struct {
+	racing racing.RacingClient
+	sports sports.SportsClient
+	logger *zap.Logger
+	now    func() time.Time
+}
+
+// New creates an export handler reading from the racing and sports backends.
+func New(racingClient racing.RacingClient, sportsClient sports.SportsClient, logger *zap.Logger) *Handler {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &Handler{racing: racingClient, sports: sportsClient, logger: logger, now: time.Now}
+}
+
+// Register adds the export routes to mux, which also provides error handling
+// and the metadata forwarded to the backends. They must be registered after
+// the backends' handlers, so that they take precedence over /v1/races/{id}
+// and /v1/events/{id}.
+func (h *Handler) Register(mux *runtime.ServeMux) error {
+	routes := map[string]func(*runtime.ServeMux, http.ResponseWriter, *http.Request){
+		RacesPath:    h.races,
+		EventsPath:   h.events,
+		CalendarPath: h.calendar,
+	}
+	for path, serve := range routes {
+		path, serve := path, serve
+		if err := mux.HandlePath(http.MethodGet, path, func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+			// The server's write timeout bounds responses rendered in full,
+			// while an export takes as long as its rows take to arrive
+			if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+				h.logger.Debug("Failed to clear the write deadline", zap.String("path", path), zap.Error(err))
+			}
+			serve(mux, w, r)
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// format returns the export format asked for, CSV by default.
+func format(r *http.Request) (string, error) {
+	switch f := strings.ToLower(r.URL.Query().Get("format")); f {
+	case "", FormatCSV:
+		return FormatCSV, nil
+	case FormatNDJSON:
+		return FormatNDJSON, nil
+	}
+	return "", invalidParam("format", "format must be csv or ndjson")
+}
+
+// invalidParam returns an InvalidArgument status naming the invalid parameter.
+func invalidParam(name, reason string) error {
+	st := status.New(codes.InvalidArgument, reason)
+	detailed, err := st.WithDetails(&errdetails.BadRequest{
+		FieldViolations: []*errdetails.BadRequest_FieldViolation{{Field: name, Description: reason}},
+	})
+	if err != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
+
+// parseRequest fills req with the list filters of the query string, read as
+// the GET list routes read them.
+func parseRequest(r *http.Request, req proto.Message) error {
+	if err := (query.Parser{}).Parse(req, r.URL.Query(), utilities.NewDoubleArray(nil)); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return nil
+}
+
+// fail reports err with the gateway's error handler. Errors are problem
+// details whatever the client accepts.
+func fail(ctx context.Context, mux *runtime.ServeMux, w http.ResponseWriter, r *http.Request, err error) {
+	runtime.HTTPError(ctx, mux, jsonMarshaler, w, r, err)
+}
+
+func (h *Handler) races(mux *runtime.ServeMux, w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	f, err := format(r)
+	if err != nil {
+		fail(ctx, mux, w, r, err)
+		return
+	}
+	req := &racing.ListRacesRequest{}
+	if err := parseRequest(r, req); err != nil {
+		fail(ctx, mux, w, r, err)
+		return
+	}
+
+	ctx, err = runtime.AnnotateContext(ctx, mux, r, "/racing.Racing/StreamRaces")
+	if err != nil {
+		fail(ctx, mux, w, r, err)
+		return
+	}
+	stream, err := h.racing.StreamRaces(ctx, req)
+	if err != nil {
+		fail(ctx, mux, w, r, err)
+		return
+	}
+
+	h.writeRows(ctx, mux, w, r, newRowWriter(w, f, "races", raceColumns), func() (proto.Message, []string, error) {
+		race, err := stream.Recv()
+		if err != nil {
+			return nil, nil, err
+		}
+		return race, raceRecord(race), nil
+	})
+}
+
+func (h *Handler) events(mux *runtime.ServeMux, w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	f, err := format(r)
+	if err != nil {
+		fail(ctx, mux, w, r, err)
+		return
+	}
+	req := &sports.ListEventsRequest{}
+	if err := parseRequest(r, req); err != nil {
+		fail(ctx, mux, w, r, err)
+		return
+	}
+
+	ctx, err = runtime.AnnotateContext(ctx, mux, r, "/sports.Sports/StreamEvents")
+	if err != nil {
+		fail(ctx, mux, w, r, err)
+		return
+	}
+	stream, err := h.sports.StreamEvents(ctx, req)
+	if err != nil {
+		fail(ctx, mux, w, r, err)
+		return
+	}
+
+	h.writeRows(ctx, mux, w, r, newRowWriter(w, f, "events", eventColumns), func() (proto.Message, []string, error) {
+		event, err := stream.Recv()
+		if err != nil {
+			return nil, nil, err
+		}
+		return event, eventRecord(event), nil
+	})
+}
+
+// writeRows writes each row next returns as it arrives, until next returns
+// io.EOF. A backend error before the first row is reported as a problem. Once
+// a row is sent the status cannot change, so the response is aborted instead,
+// for the client to see that the export is incomplete.
+func (h *Handler) writeRows(ctx context.Context, mux *runtime.ServeMux, w http.ResponseWriter, r *http.Request, rows *rowWriter,
+	next func() (proto.Message, []string, error)) {
+	written := 0
+	for {
+		msg, record, err := next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			if written == 0 {
+				fail(ctx, mux, w, r, err)
+				return
+			}
+			h.logger.Warn("Export interrupted by the backend", zap.String("export", rows.name), zap.Int("written", written), zap.Error(err))
+			panic(http.ErrAbortHandler)
+		}
+
+		if rows.format == FormatNDJSON {
+			err = rows.writeMessage(msg)
+		} else {
+			err = rows.writeRecord(record)
+		}
+		if err == nil && (written+1)%flushRows == 0 {
+			err = rows.flush()
+		}
+		if err != nil {
+			h.logger.Warn("Export interrupted", zap.String("export", rows.name), zap.Int("written", written), zap.Error(err))
+			return
+		}
+		written++
+	}
+
+	if err := rows.close(); err != nil {
+		h.logger.Warn("Export interrupted", zap.String("export", rows.name), zap.Int("written", written), zap.Error(err))
+	}
+}
+
+// CSV columns of the exports, in order.
+var (
+	raceColumns = []string{
+		"id", "meeting_id", "name", "number", "visible", "status",
+		"advertised_start_time", "advertised_start_local", "venue_id",
+	}
+	eventColumns = []string{
+		"id", "name", "sport_type", "visible", "status", "suspended",
+		"advertised_start_time", "advertised_start_local", "venue_id", "venue",
+		"competition_id", "season_id", "home_team_id", "away_team_id",
+	}
+)
+
+// raceRecord returns the CSV fields of a race, in the order of raceColumns.
+func raceRecord(race *racing.Race) []string {
+	return []string{
+		strconv.FormatInt(race.GetId(), 10),
+		strconv.FormatInt(race.GetMeetingId(), 10),
+		race.GetName(),
+		strconv.FormatInt(race.GetNumber(), 10),
+		strconv.FormatBool(race.GetVisible()),
+		race.GetStatus().String(),
+		race.GetAdvertisedStartTime().AsTime().Format(time.RFC3339),
+		race.GetAdvertisedStartLocal(),
+		strconv.FormatInt(race.GetVenueId(), 10),
+	}
+}
+
+// eventRecord returns the CSV fields of an event, in the order of eventColumns.
+func eventRecord(event *sports.Event) []string {
+	return []string{
+		strconv.FormatInt(event.GetId(), 10),
+		event.GetName(),
+		event.GetSportType(),
+		strconv.FormatBool(event.GetVisible()),
+		event.GetStatus().String(),
+		strconv.FormatBool(event.GetSuspended()),
+		event.GetAdvertisedStartTime().AsTime().Format(time.RFC3339),
+		event.GetAdvertisedStartLocal(),
+		strconv.FormatInt(event.GetVenueId(), 10),
+		event.GetVenue(),
+		strconv.FormatInt(event.GetCompetitionId(), 10),
+		strconv.FormatInt(event.GetSeasonId(), 10),
+		strconv.FormatInt(event.GetHomeTeamId(), 10),
+		strconv.FormatInt(event.GetAwayTeamId(), 10),
+	}
+}
+
+// rowWriter writes the rows of an export straight to the response. The
+// headers are sent with the first row, or on close for an empty export.
+type rowWriter struct {
+	w       http.ResponseWriter
+	format  string
+	name    string
+	columns []string
+	csv     *csv.Writer
+	started bool
+}
+
+func newRowWriter(w http.ResponseWriter, format, name string, columns []string) *rowWriter {
+	return &rowWriter{w: w, format: format, name: name, columns: columns}
+}
+
+// start sends the headers, and the header row of a CSV export.
+func (rw *rowWriter) start() error {
+	if rw.started {
+		return nil
+	}
+	rw.started = true
+
+	h := rw.w.Header()
+	h.Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, rw.name, rw.format))
+	if rw.format == FormatNDJSON {
+		h.Set("Content-Type", ContentTypeNDJSON)
+		return nil
+	}
+
+	h.Set("Content-Type", ContentTypeCSV)
+	rw.csv = csv.NewWriter(rw.w)
+	return rw.csv.Write(rw.columns)
+}
+
+func (rw *rowWriter) writeRecord(record []string) error {
+	if err := rw.start(); err != nil {
+		return err
+	}
+	return rw.csv.Write(record)
+}
+
+func (rw *rowWriter) writeMessage(msg proto.Message) error {
+	if err := rw.start(); err != nil {
+		return err
+	}
+	line, err := jsonMarshaler.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	if _, err := rw.w.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return nil
+}
+
+// flush sends the rows written so far to the client.
+func (rw *rowWriter) flush() error {
+	if rw.csv != nil {
+		rw.csv.Flush()
+		if err := rw.csv.Error(); err != nil {
+			return err
+		}
+	}
+	if f, ok := rw.w.(http.Flusher); ok {
+		f.Flush()
+	}
+	return nil
+}
+
+// close completes the export, sending the CSV header row if there were no rows.
+func (rw *rowWriter) close() error {
+	if err := rw.start(); err != nil {
+		return err
+	}
+	if rw.csv != nil {
+		rw.csv.Flush()
+		return rw.csv.Error()
+	}
+	return nil
+}
+
+// Calendar properties. The refresh hints ask calendar clients to poll hourly,
+// as start times move and new events are scheduled.
+const (
+	calendarName    = "Upcoming events"
+	calendarRefresh = "PT1H"
+	calendarProdID  = "-//Entain//Sports events//EN"
+	// calendarDuration is the length given to events, which have no end time
+	calendarDuration = "PT2H"
+)
+
+// calendar serves the open events as an iCalendar feed (RFC 5545). It takes
+// the filters of the events list, such as filter.sport_types, and writes each
+// event as it arrives.
+func (h *Handler) calendar(mux *runtime.ServeMux, w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	req := &sports.ListEventsRequest{}
+	if err := parseRequest(r, req); err != nil {
+		fail(ctx, mux, w, r, err)
+		return
+	}
+	// The backend leaves out the events that have started
+	if req.Filter == nil {
+		req.Filter = &sports.ListEventsRequestFilter{}
+	}
+	open := true
+	req.Filter.OpenOnly = &open
+
+	ctx, err := runtime.AnnotateContext(ctx, mux, r, "/sports.Sports/StreamEvents")
+	if err != nil {
+		fail(ctx, mux, w, r, err)
+		return
+	}
+	stream, err := h.sports.StreamEvents(ctx, req)
+	if err != nil {
+		fail(ctx, mux, w, r, err)
+		return
+	}
+
+	stamp := h.now().UTC().Format(icsTime)
+	cal := &icsWriter{w: w}
+	started := false
+	// start sends the headers and opens the calendar, with the first event or
+	// once the stream ends, so that an error before them is still a problem
+	start := func() {
+		if started {
+			return
+		}
+		started = true
+
+		w.Header().Set("Content-Type", ContentTypeCalendar)
+		w.Header().Set("Content-Disposition", `inline; filename="events.ics"`)
+		cal.line("BEGIN:VCALENDAR")
+		cal.line("VERSION:2.0")
+		cal.line("PRODID:" + calendarProdID)
+		cal.line("CALSCALE:GREGORIAN")
+		cal.line("METHOD:PUBLISH")
+		cal.line("X-WR-CALNAME:" + icsText(calendarName))
+		cal.line("REFRESH-INTERVAL;VALUE=DURATION:" + calendarRefresh)
+		cal.line("X-PUBLISHED-TTL:" + calendarRefresh)
+	}
+
+	written := 0
+	for cal.err == nil {
+		event, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			if !started {
+				fail(ctx, mux, w, r, err)
+				return
+			}
+			h.logger.Warn("Calendar export interrupted by the backend", zap.Int("written", written), zap.Error(err))
+			panic(http.ErrAbortHandler)
+		}
+
+		start()
+		cal.line("BEGIN:VEVENT")
+		cal.line(fmt.Sprintf("UID:event-%d@%s", event.GetId(), r.Host))
+		cal.line("DTSTAMP:" + stamp)
+		cal.line("DTSTART:" + event.GetAdvertisedStartTime().AsTime().UTC().Format(icsTime))
+		cal.line("DURATION:" + calendarDuration)
+		cal.line("SUMMARY:" + icsText(event.GetName()))
+		if venue := event.GetVenue(); venue != "" {
+			cal.line("LOCATION:" + icsText(venue))
+		}
+		if sportType := event.GetSportType(); sportType != "" {
+			cal.line("CATEGORIES:" + icsText(sportType))
+		}
+		cal.line("END:VEVENT")
+
+		written++
+		if written%flushRows == 0 && cal.err == nil {
+			if f, ok := w.(http.Flusher); ok {
+				f.Flush()
+			}
+		}
+	}
+	start()
+	cal.line("END:VCALENDAR")
+
+	if cal.err != nil {
+		h.logger.Warn("Calendar export interrupted", zap.Int("written", written), zap.Error(cal.err))
+	}
+}
+
+// icsTime is the layout of UTC date-times in iCalendar.
+const icsTime = "20060102T150405Z"
+
+// icsLineLength is the longest a content line may be, in octets, before it
+// must be folded.
+const icsLineLength = 75
+
+// icsText escapes a TEXT property value.
+var icsText = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`).Replace
+
+// icsWriter writes the content lines of a calendar, folded and CRLF
+// terminated. After a write fails, it keeps the error and writes nothing more.
+type icsWriter struct {
+	w   io.Writer
+	err error
+}
+
+// line writes a content line, folding it into lines of at most icsLineLength
+// octets without splitting UTF-8 sequences.
+func (c *icsWriter) line(s string) {
+	if c.err != nil {
+		return
+	}
+
+	var b strings.Builder
+	limit := icsLineLength
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		b.WriteString(s[:cut])
+		b.WriteString("\r\n ")
+		s = s[cut:]
+		// Continuation lines start with the folding space
+		limit = icsLineLength - 1
+	}
+	b.WriteString(s)
+	b.WriteString("\r\n")
+	_, c.err = io.WriteString(c.w, b.String())
+}
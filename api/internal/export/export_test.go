@@ -0,0 +1,392 @@
+package export
+
+import (
+	"context"
+	"encoding/csv"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"git.neds.sh/matty/entain/api/proto/racing"
+	"git.neds.sh/matty/entain/api/proto/sports"
+	"github.com/google/go-cmp/cmp"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// fakeRacingClient streams fixed races, each delay after the previous one,
+// and records the request it receives. err is returned once errAfter races
+// have been received.
+type fakeRacingClient struct {
+	racing.RacingClient
+	races    []*racing.Race
+	delay    time.Duration
+	err      error
+	errAfter int
+	req      *racing.ListRacesRequest
+}
+
+func (c *fakeRacingClient) StreamRaces(ctx context.Context, in *racing.ListRacesRequest, opts ...grpc.CallOption) (racing.Racing_StreamRacesClient, error) {
+	c.req = in
+	return &fakeRacesStream{races: c.races, delay: c.delay, err: c.err, errAfter: c.errAfter}, nil
+}
+
+// fakeRacesStream is a racing.Racing_StreamRacesClient receiving races until
+// errAfter of them are received, then err if it is set
+type fakeRacesStream struct {
+	grpc.ClientStream
+	races    []*racing.Race
+	delay    time.Duration
+	err      error
+	errAfter int
+}
+
+func (s *fakeRacesStream) Recv() (*racing.Race, error) {
+	time.Sleep(s.delay)
+	if s.err != nil && s.errAfter == 0 {
+		return nil, s.err
+	}
+	if len(s.races) == 0 {
+		return nil, io.EOF
+	}
+	race := s.races[0]
+	s.races = s.races[1:]
+	s.errAfter--
+	return race, nil
+}
+
+// fakeSportsClient streams fixed events, leaving out closed events when asked
+// for open ones only, and records the request it receives
+type fakeSportsClient struct {
+	sports.SportsClient
+	events []*sports.Event
+	err    error
+	req    *sports.ListEventsRequest
+}
+
+func (c *fakeSportsClient) StreamEvents(ctx context.Context, in *sports.ListEventsRequest, opts ...grpc.CallOption) (sports.Sports_StreamEventsClient, error) {
+	c.req = in
+	var events []*sports.Event
+	for _, event := range c.events {
+		if in.GetFilter().GetOpenOnly() && event.GetStatus() != sports.EventStatus_OPEN {
+			continue
+		}
+		events = append(events, event)
+	}
+	return &fakeEventsStream{events: events, err: c.err}, nil
+}
+
+// fakeEventsStream is a sports.Sports_StreamEventsClient receiving events, or
+// err if it is set
+type fakeEventsStream struct {
+	grpc.ClientStream
+	events []*sports.Event
+	err    error
+}
+
+func (s *fakeEventsStream) Recv() (*sports.Event, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	if len(s.events) == 0 {
+		return nil, io.EOF
+	}
+	event := s.events[0]
+	s.events = s.events[1:]
+	return event, nil
+}
+
+var start = time.Date(2026, 11, 3, 4, 0, 0, 0, time.UTC)
+
+func serve(t *testing.T, racingClient *fakeRacingClient, sportsClient *fakeSportsClient, target string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	h := New(racingClient, sportsClient, nil)
+	h.now = func() time.Time { return start.Add(-time.Hour) }
+
+	mux := runtime.NewServeMux()
+	if err := h.Register(mux); err != nil {
+		t.Fatalf("Register() failed: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+	return rec
+}
+
+func TestHandler_Races(t *testing.T) {
+	races := []*racing.Race{
+		{Id: 1, MeetingId: 5, Name: "Cup, \"Final\"", Number: 3, Visible: true, AdvertisedStartTime: timestamppb.New(start), VenueId: 2},
+		{Id: 2, MeetingId: 5, Name: "Plate", Number: 4, Status: racing.RaceStatus_CLOSED, AdvertisedStartTime: timestamppb.New(start)},
+	}
+
+	t.Run("csv", func(t *testing.T) {
+		client := &fakeRacingClient{races: races}
+		rec := serve(t, client, nil, RacesPath+"?filter.meeting_ids=5&filter.visible_only=true")
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d (body: %s)", rec.Code, http.StatusOK, rec.Body)
+		}
+		if got := rec.Header().Get("Content-Type"); got != ContentTypeCSV {
+			t.Errorf("Content-Type = %q, want %q", got, ContentTypeCSV)
+		}
+		if got, want := rec.Header().Get("Content-Disposition"), `attachment; filename="races.csv"`; got != want {
+			t.Errorf("Content-Disposition = %q, want %q", got, want)
+		}
+		if diff := cmp.Diff([]int64{5}, client.req.GetFilter().GetMeetingIds()); diff != "" {
+			t.Errorf("meeting IDs mismatch (-want +got):\n%s", diff)
+		}
+		if !client.req.GetFilter().GetVisibleOnly() {
+			t.Error("visible_only filter not forwarded")
+		}
+
+		records, err := csv.NewReader(rec.Body).ReadAll()
+		if err != nil {
+			t.Fatalf("failed to read CSV: %v", err)
+		}
+		want := [][]string{
+			raceColumns,
+			{"1", "5", "Cup, \"Final\"", "3", "true", "OPEN", "2026-11-03T04:00:00Z", "", "2"},
+			{"2", "5", "Plate", "4", "false", "CLOSED", "2026-11-03T04:00:00Z", "", "0"},
+		}
+		if diff := cmp.Diff(want, records); diff != "" {
+			t.Errorf("CSV mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("ndjson", func(t *testing.T) {
+		rec := serve(t, &fakeRacingClient{races: races}, nil, RacesPath+"?format=ndjson")
+
+		if got := rec.Header().Get("Content-Type"); got != ContentTypeNDJSON {
+			t.Errorf("Content-Type = %q, want %q", got, ContentTypeNDJSON)
+		}
+		lines := strings.Split(strings.TrimSuffix(rec.Body.String(), "\n"), "\n")
+		if len(lines) != len(races) {
+			t.Fatalf("got %d lines, want %d: %q", len(lines), len(races), rec.Body)
+		}
+		for i, line := range lines {
+			var race racing.Race
+			if err := protojson.Unmarshal([]byte(line), &race); err != nil {
+				t.Fatalf("failed to decode line %q: %v", line, err)
+			}
+			if race.GetId() != races[i].GetId() {
+				t.Errorf("line %d has race %d, want %d", i, race.GetId(), races[i].GetId())
+			}
+		}
+	})
+
+	t.Run("empty csv has a header row", func(t *testing.T) {
+		rec := serve(t, &fakeRacingClient{}, nil, RacesPath)
+
+		if got, want := rec.Body.String(), strings.Join(raceColumns, ",")+"\n"; got != want {
+			t.Errorf("body = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("flushes long exports", func(t *testing.T) {
+		many := make([]*racing.Race, flushRows+1)
+		for i := range many {
+			many[i] = &racing.Race{Id: int64(i + 1)}
+		}
+		rec := serve(t, &fakeRacingClient{races: many}, nil, RacesPath)
+
+		if !rec.Flushed {
+			t.Error("response not flushed")
+		}
+		if got, want := strings.Count(rec.Body.String(), "\n"), len(many)+1; got != want {
+			t.Errorf("got %d lines, want %d", got, want)
+		}
+	})
+}
+
+func TestHandler_Errors(t *testing.T) {
+	tests := []struct {
+		name       string
+		target     string
+		racingErr  error
+		sportsErr  error
+		wantStatus int
+	}{
+		{name: "unknown format", target: RacesPath + "?format=xml", wantStatus: http.StatusBadRequest},
+		{name: "invalid filter", target: RacesPath + "?filter.meeting_ids=one", wantStatus: http.StatusBadRequest},
+		{name: "backend error", target: RacesPath, racingErr: status.Error(codes.Unavailable, "down"), wantStatus: http.StatusServiceUnavailable},
+		{name: "invalid event filter", target: EventsPath + "?filter.visible_only=maybe", wantStatus: http.StatusBadRequest},
+		{name: "events backend error", target: EventsPath, sportsErr: status.Error(codes.InvalidArgument, "bad filter"), wantStatus: http.StatusBadRequest},
+		{name: "calendar backend error", target: CalendarPath, sportsErr: status.Error(codes.Unavailable, "down"), wantStatus: http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(t, &fakeRacingClient{err: tt.racingErr}, &fakeSportsClient{err: tt.sportsErr}, tt.target)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d (body: %s)", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if got := rec.Header().Get("Content-Disposition"); got != "" {
+				t.Errorf("error response has Content-Disposition %q", got)
+			}
+		})
+	}
+}
+
+func TestHandler_BackendFailsMidExport(t *testing.T) {
+	many := make([]*racing.Race, flushRows+1)
+	for i := range many {
+		many[i] = &racing.Race{Id: int64(i + 1)}
+	}
+	client := &fakeRacingClient{races: many, err: status.Error(codes.Unavailable, "down"), errAfter: flushRows}
+
+	// The rows sent so far cannot be taken back, so the response is aborted
+	defer func() {
+		if got := recover(); got != http.ErrAbortHandler {
+			t.Errorf("recovered %v, want http.ErrAbortHandler", got)
+		}
+	}()
+	serve(t, client, nil, RacesPath)
+}
+
+func TestHandler_OutlastsWriteTimeout(t *testing.T) {
+	client := &fakeRacingClient{races: []*racing.Race{{Id: 1}, {Id: 2}}, delay: 100 * time.Millisecond}
+	mux := runtime.NewServeMux()
+	if err := New(client, nil, nil).Register(mux); err != nil {
+		t.Fatalf("Register() failed: %v", err)
+	}
+	server := httptest.NewUnstartedServer(mux)
+	server.Config.WriteTimeout = 50 * time.Millisecond
+	server.Start()
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + RacesPath)
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	defer resp.Body.Close()
+	records, err := csv.NewReader(resp.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to read the export: %v", err)
+	}
+	if len(records) != 3 {
+		t.Errorf("got %d records, want the header and 2 races", len(records))
+	}
+}
+
+func TestHandler_Events(t *testing.T) {
+	events := []*sports.Event{
+		{Id: 7, Name: "Final", SportType: "football", Visible: true, AdvertisedStartTime: timestamppb.New(start), VenueId: 3, Venue: "MCG", HomeTeamId: 1, AwayTeamId: 2},
+	}
+	client := &fakeSportsClient{events: events}
+	rec := serve(t, nil, client, EventsPath+"?filter.sport_types=football")
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d (body: %s)", rec.Code, http.StatusOK, rec.Body)
+	}
+	if diff := cmp.Diff([]string{"football"}, client.req.GetFilter().GetSportTypes()); diff != "" {
+		t.Errorf("sport types mismatch (-want +got):\n%s", diff)
+	}
+
+	records, err := csv.NewReader(rec.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to read CSV: %v", err)
+	}
+	want := [][]string{
+		eventColumns,
+		{"7", "Final", "football", "true", "OPEN", "false", "2026-11-03T04:00:00Z", "", "3", "MCG", "0", "0", "1", "2"},
+	}
+	if diff := cmp.Diff(want, records); diff != "" {
+		t.Errorf("CSV mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestHandler_Calendar(t *testing.T) {
+	events := []*sports.Event{
+		{Id: 7, Name: "Final; Home v Away, " + strings.Repeat("long ", 20), SportType: "football", Venue: "MCG", AdvertisedStartTime: timestamppb.New(start)},
+		{Id: 8, Name: "Done", SportType: "football", Status: sports.EventStatus_CLOSED, AdvertisedStartTime: timestamppb.New(start)},
+	}
+	client := &fakeSportsClient{events: events}
+	rec := serve(t, nil, client, CalendarPath+"?sport_types=football")
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d (body: %s)", rec.Code, http.StatusOK, rec.Body)
+	}
+	if got := rec.Header().Get("Content-Type"); got != ContentTypeCalendar {
+		t.Errorf("Content-Type = %q, want %q", got, ContentTypeCalendar)
+	}
+	if diff := cmp.Diff([]string{"football"}, client.req.GetFilter().GetSportTypes()); diff != "" {
+		t.Errorf("sport types mismatch (-want +got):\n%s", diff)
+	}
+	if !client.req.GetFilter().GetOpenOnly() {
+		t.Error("calendar did not ask the backend for open events only")
+	}
+
+	body := rec.Body.String()
+	if !strings.HasSuffix(body, "\r\n") || strings.Contains(strings.ReplaceAll(body, "\r\n", ""), "\n") {
+		t.Errorf("lines are not CRLF terminated: %q", body)
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(body, "\r\n"), "\r\n") {
+		if len(line) > icsLineLength {
+			t.Errorf("line longer than %d octets: %q", icsLineLength, line)
+		}
+	}
+
+	// Unfolded, the calendar holds the open event only
+	unfolded := strings.ReplaceAll(body, "\r\n ", "")
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"UID:event-7@example.com\r\n",
+		"DTSTAMP:20261103T030000Z\r\n",
+		"DTSTART:20261103T040000Z\r\n",
+		`SUMMARY:Final\; Home v Away\, long `,
+		"LOCATION:MCG\r\n",
+		"CATEGORIES:football\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(unfolded, want) {
+			t.Errorf("calendar missing %q:\n%s", want, unfolded)
+		}
+	}
+	if strings.Contains(unfolded, "event-8@") {
+		t.Errorf("calendar lists a closed event:\n%s", unfolded)
+	}
+}
+
+func TestHandler_CalendarFlushes(t *testing.T) {
+	events := make([]*sports.Event, flushRows)
+	for i := range events {
+		events[i] = &sports.Event{Id: int64(i + 1), AdvertisedStartTime: timestamppb.New(start)}
+	}
+	rec := serve(t, nil, &fakeSportsClient{events: events}, CalendarPath)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d (body: %s)", rec.Code, http.StatusOK, rec.Body)
+	}
+	if !rec.Flushed {
+		t.Errorf("calendar of %d events not flushed", flushRows)
+	}
+}
+
+func TestICSWriter_FoldsMultibyteText(t *testing.T) {
+	var b strings.Builder
+	cal := &icsWriter{w: &b}
+	cal.line("SUMMARY:" + strings.Repeat("é", 60))
+
+	lines := strings.Split(strings.TrimSuffix(b.String(), "\r\n"), "\r\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2: %q", len(lines), b.String())
+	}
+	for _, line := range lines {
+		if len(line) > icsLineLength || !utf8.ValidString(line) {
+			t.Errorf("invalid folded line %q", line)
+		}
+	}
+	if got, want := strings.ReplaceAll(b.String(), "\r\n ", ""), "SUMMARY:"+strings.Repeat("é", 60)+"\r\n"; got != want {
+		t.Errorf("unfolded = %q, want %q", got, want)
+	}
+}
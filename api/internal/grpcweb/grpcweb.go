@@ -0,0 +1,383 @@
+package grpcweb
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"git.neds.sh/matty/entain/api/internal/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// Content types of grpc-web calls, optionally followed by "+proto". The text
+// variant is base64 encoded, for clients that cannot stream binary responses.
+const (
+	contentType     = "application/grpc-web"
+	contentTypeText = "application/grpc-web-text"
+)
+
+// Flags of the frame header. A frame is a flag byte, a big-endian uint32
+// length and the payload: a message, or the trailers as HTTP/1 header lines.
+const (
+	flagCompressed = 0x01
+	flagTrailer    = 0x80
+)
+
+// maxMessageSize bounds request messages, as the gRPC servers do by default.
+const maxMessageSize = 4 << 20
+
+// Handler translates grpc-web calls into gRPC calls on the backends. The
+// request and response messages are passed through as bytes, so any method of
+// a registered service works, including server streams. Client streams are
+// not part of grpc-web.
+type Handler struct {
+	conns map[string]grpc.ClientConnInterface
+}
+
+// New creates a grpc-web handler. conns maps full service names, such as
+// "racing.Racing", to the connection of the backend serving them. Their
+// descriptors must be registered, which importing the generated packages does.
+func New(conns map[string]grpc.ClientConnInterface) *Handler {
+	return &Handler{conns: conns}
+}
+
+// IsGRPCWeb reports whether r is a grpc-web call.
+func IsGRPCWeb(r *http.Request) bool {
+	return r.Method == http.MethodPost && strings.HasPrefix(r.Header.Get("Content-Type"), contentType)
+}
+
+// Middleware serves grpc-web calls and passes any other request to next.
+func (h *Handler) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if IsGRPCWeb(r) {
+			h.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ServeHTTP makes the grpc-web call r on the backend serving its method.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rw := &responseWriter{w: w, text: strings.HasPrefix(r.Header.Get("Content-Type"), contentTypeText)}
+
+	conn, method, err := h.lookup(r.URL.Path)
+	if err != nil {
+		rw.fail(err)
+		return
+	}
+
+	var body io.Reader = r.Body
+	if rw.text {
+		body = base64.NewDecoder(base64.StdEncoding, r.Body)
+	}
+	req, err := readMessage(body)
+	if err != nil {
+		rw.fail(err)
+		return
+	}
+
+	ctx, cancel, err := callContext(r)
+	if err != nil {
+		rw.fail(err)
+		return
+	}
+	defer cancel()
+
+	if method.IsStreamingServer() {
+		h.stream(ctx, rw, conn, r.URL.Path, req)
+	} else {
+		h.unary(ctx, rw, conn, r.URL.Path, req)
+	}
+}
+
+// unary makes a unary call through Invoke, so that the deadline, retry and
+// circuit breaker settings of the backend apply.
+func (h *Handler) unary(ctx context.Context, rw *responseWriter, conn grpc.ClientConnInterface, method string, req []byte) {
+	var resp []byte
+	var header, trailer metadata.MD
+	err := conn.Invoke(ctx, method, &req, &resp, grpc.ForceCodec(codec{}), grpc.Header(&header), grpc.Trailer(&trailer))
+
+	rw.writeHeader(header)
+	if err == nil {
+		rw.writeFrame(0, resp)
+	}
+	rw.writeTrailer(status.Convert(err), trailer)
+}
+
+// stream makes a server streaming call, writing each message as it arrives.
+func (h *Handler) stream(ctx context.Context, rw *responseWriter, conn grpc.ClientConnInterface, method string, req []byte) {
+	desc := &grpc.StreamDesc{ServerStreams: true}
+	stream, err := conn.NewStream(ctx, desc, method, grpc.ForceCodec(codec{}))
+	if err != nil {
+		rw.fail(err)
+		return
+	}
+
+	// A failed send ends the stream, and RecvMsg reports why
+	if err := stream.SendMsg(&req); err == nil {
+		_ = stream.CloseSend()
+	}
+
+	header, _ := stream.Header()
+	rw.writeHeader(header)
+	for {
+		var msg []byte
+		err = stream.RecvMsg(&msg)
+		if err != nil {
+			break
+		}
+		rw.writeFrame(0, msg)
+	}
+	if errors.Is(err, io.EOF) {
+		err = nil
+	}
+	rw.writeTrailer(status.Convert(err), stream.Trailer())
+}
+
+// lookup returns the connection and descriptor of the method at path, which
+// looks like "/racing.Racing/GetRace".
+func (h *Handler) lookup(path string) (grpc.ClientConnInterface, protoreflect.MethodDescriptor, error) {
+	unknown := status.Errorf(codes.Unimplemented, "unknown method %s", path)
+
+	parts := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	if len(parts) != 2 {
+		return nil, nil, unknown
+	}
+	conn, ok := h.conns[parts[0]]
+	if !ok {
+		return nil, nil, unknown
+	}
+	d, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(parts[0]))
+	if err != nil {
+		return nil, nil, unknown
+	}
+	service, ok := d.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, nil, unknown
+	}
+	method := service.Methods().ByName(protoreflect.Name(parts[1]))
+	if method == nil {
+		return nil, nil, unknown
+	}
+	if method.IsStreamingClient() {
+		return nil, nil, status.Errorf(codes.Unimplemented, "%s streams requests, which grpc-web does not support", path)
+	}
+	return conn, method, nil
+}
+
+// callContext returns the context of the backend call: the identity
+// established by the gateway's authentication and the client's grpc-timeout.
+// Other request headers are not forwarded.
+func callContext(r *http.Request) (context.Context, context.CancelFunc, error) {
+	ctx := r.Context()
+	if md := auth.Metadata(ctx, r); md != nil {
+		ctx = metadata.NewOutgoingContext(ctx, md)
+	}
+
+	v := r.Header.Get("Grpc-Timeout")
+	if v == "" {
+		ctx, cancel := context.WithCancel(ctx)
+		return ctx, cancel, nil
+	}
+	timeout, err := parseTimeout(v)
+	if err != nil {
+		return nil, nil, status.Errorf(codes.InvalidArgument, "invalid grpc-timeout %q", v)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return ctx, cancel, nil
+}
+
+// parseTimeout parses a grpc-timeout value: at most 8 digits and a unit.
+func parseTimeout(v string) (time.Duration, error) {
+	if len(v) < 2 || len(v) > 9 {
+		return 0, fmt.Errorf("malformed timeout %q", v)
+	}
+	units := map[byte]time.Duration{
+		'H': time.Hour,
+		'M': time.Minute,
+		'S': time.Second,
+		'm': time.Millisecond,
+		'u': time.Microsecond,
+		'n': time.Nanosecond,
+	}
+	unit, ok := units[v[len(v)-1]]
+	if !ok {
+		return 0, fmt.Errorf("unknown timeout unit in %q", v)
+	}
+	n, err := strconv.ParseUint(v[:len(v)-1], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("malformed timeout %q: %w", v, err)
+	}
+	return time.Duration(n) * unit, nil
+}
+
+// readMessage reads the single request message of a unary or server
+// streaming call.
+func readMessage(r io.Reader) ([]byte, error) {
+	var prefix [5]byte
+	if _, err := io.ReadFull(r, prefix[:]); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "missing request message")
+	}
+	if prefix[0]&flagCompressed != 0 {
+		return nil, status.Error(codes.Unimplemented, "compressed request messages are not supported")
+	}
+	if prefix[0] != 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "unexpected frame flags %#x", prefix[0])
+	}
+	length := binary.BigEndian.Uint32(prefix[1:])
+	if length > maxMessageSize {
+		return nil, status.Errorf(codes.ResourceExhausted, "request message of %d bytes exceeds the %d byte limit", length, maxMessageSize)
+	}
+
+	msg := make([]byte, length)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "truncated request message")
+	}
+	if n, _ := r.Read(make([]byte, 1)); n > 0 {
+		return nil, status.Error(codes.InvalidArgument, "more than one request message")
+	}
+	return msg, nil
+}
+
+// responseWriter writes the frames of a grpc-web response.
+type responseWriter struct {
+	w           http.ResponseWriter
+	text        bool
+	wroteHeader bool
+}
+
+// writeHeader sends the response headers, carrying the header metadata of
+// the backend.
+func (rw *responseWriter) writeHeader(md metadata.MD) {
+	if rw.wroteHeader {
+		return
+	}
+	rw.wroteHeader = true
+
+	for key, values := range md {
+		if isReserved(key) {
+			continue
+		}
+		for _, v := range values {
+			rw.w.Header().Add(key, encodeValue(key, v))
+		}
+	}
+	if rw.text {
+		rw.w.Header().Set("Content-Type", contentTypeText+"+proto")
+	} else {
+		rw.w.Header().Set("Content-Type", contentType+"+proto")
+	}
+	rw.w.WriteHeader(http.StatusOK)
+}
+
+// writeFrame writes and flushes one frame.
+func (rw *responseWriter) writeFrame(flag byte, payload []byte) {
+	frame := make([]byte, 5+len(payload))
+	frame[0] = flag
+	binary.BigEndian.PutUint32(frame[1:5], uint32(len(payload)))
+	copy(frame[5:], payload)
+
+	if rw.text {
+		frame = []byte(base64.StdEncoding.EncodeToString(frame))
+	}
+	if _, err := rw.w.Write(frame); err != nil {
+		// The client went away; the call context is cancelled with the request
+		return
+	}
+	if f, ok := rw.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// writeTrailer ends the response with the status and trailer metadata.
+func (rw *responseWriter) writeTrailer(st *status.Status, md metadata.MD) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "grpc-status: %d\r\n", st.Code())
+	if st.Message() != "" {
+		fmt.Fprintf(&b, "grpc-message: %s\r\n", encodeMessage(st.Message()))
+	}
+	for key, values := range md {
+		if isReserved(key) {
+			continue
+		}
+		for _, v := range values {
+			fmt.Fprintf(&b, "%s: %s\r\n", strings.ToLower(key), encodeValue(key, v))
+		}
+	}
+	rw.writeFrame(flagTrailer, b.Bytes())
+}
+
+// fail ends the response with err, before any message was sent.
+func (rw *responseWriter) fail(err error) {
+	rw.writeHeader(nil)
+	rw.writeTrailer(status.Convert(err), nil)
+}
+
+// isReserved reports whether key is set by the transport rather than the
+// backend's handler.
+func isReserved(key string) bool {
+	key = strings.ToLower(key)
+	return key == "content-type" || strings.HasPrefix(key, "grpc-") || strings.HasPrefix(key, ":")
+}
+
+// encodeValue encodes binary metadata values in base64, as in gRPC headers.
+func encodeValue(key, v string) string {
+	if strings.HasSuffix(strings.ToLower(key), "-bin") {
+		return base64.StdEncoding.EncodeToString([]byte(v))
+	}
+	return v
+}
+
+// encodeMessage percent-encodes a status message as the gRPC protocol
+// requires: bytes outside printable ASCII, and "%", are escaped.
+func encodeMessage(msg string) string {
+	var b strings.Builder
+	for i := 0; i < len(msg); i++ {
+		c := msg[i]
+		if c >= ' ' && c <= '~' && c != '%' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// codec passes messages through as bytes.
+type codec struct{}
+
+func (codec) Marshal(v interface{}) ([]byte, error) {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return nil, fmt.Errorf("unexpected message type %T", v)
+	}
+	return *b, nil
+}
+
+func (codec) Unmarshal(data []byte, v interface{}) error {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return fmt.Errorf("unexpected message type %T", v)
+	}
+	*b = append((*b)[:0], data...)
+	return nil
+}
+
+// Name is "proto" so that the backends see the usual content subtype.
+func (codec) Name() string {
+	return "proto"
+}
@@ -0,0 +1,284 @@
+package grpcweb
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"git.neds.sh/matty/entain/api/internal/auth"
+	"git.neds.sh/matty/entain/api/proto/racing"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+)
+
+// racingServer returns race 1, named after the subject of the caller, with a
+// header. Other IDs are not found.
+type racingServer struct {
+	racing.UnimplementedRacingServer
+}
+
+func (s *racingServer) GetRace(ctx context.Context, in *racing.GetRaceRequest) (*racing.GetRaceResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if in.Id != 1 {
+		return nil, status.Errorf(codes.NotFound, "race with ID %d not found – try 1%%", in.Id)
+	}
+	grpc.SetHeader(ctx, metadata.Pairs("x-served-by", "racing-1"))
+	return &racing.GetRaceResponse{Race: &racing.Race{Id: 1, Name: strings.Join(md.Get(auth.MetadataSubject), ",")}}, nil
+}
+
+// startBackend serves racing and health checks, and returns a connection to them.
+func startBackend(t *testing.T) *grpc.ClientConn {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	server := grpc.NewServer()
+	racing.RegisterRacingServer(server, &racingServer{})
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// frame encodes msg as a grpc-web data frame.
+func frame(t *testing.T, msg proto.Message) []byte {
+	t.Helper()
+
+	b, err := proto.Marshal(msg)
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+	out := make([]byte, 5+len(b))
+	binary.BigEndian.PutUint32(out[1:5], uint32(len(b)))
+	copy(out[5:], b)
+	return out
+}
+
+// response is a decoded grpc-web response body.
+type response struct {
+	messages [][]byte
+	trailer  string
+}
+
+func decode(t *testing.T, body []byte) response {
+	t.Helper()
+
+	var resp response
+	r := bytes.NewReader(body)
+	for {
+		var prefix [5]byte
+		if _, err := io.ReadFull(r, prefix[:]); err == io.EOF {
+			return resp
+		} else if err != nil {
+			t.Fatalf("truncated frame header: %v", err)
+		}
+		payload := make([]byte, binary.BigEndian.Uint32(prefix[1:]))
+		if _, err := io.ReadFull(r, payload); err != nil {
+			t.Fatalf("truncated frame: %v", err)
+		}
+		if prefix[0]&flagTrailer != 0 {
+			resp.trailer = string(payload)
+		} else {
+			resp.messages = append(resp.messages, payload)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	conn := startBackend(t)
+	h := New(map[string]grpc.ClientConnInterface{
+		"racing.Racing":         conn,
+		"grpc.health.v1.Health": conn,
+	})
+
+	tests := []struct {
+		name         string
+		path         string
+		contentType  string
+		headers      map[string]string
+		identity     *auth.Identity
+		body         []byte
+		wantMessages []proto.Message
+		wantTrailer  string
+		wantHeaders  map[string]string
+	}{
+		{
+			name:         "unary call",
+			path:         "/racing.Racing/GetRace",
+			contentType:  "application/grpc-web+proto",
+			identity:     &auth.Identity{Subject: "trader-1"},
+			body:         frame(t, &racing.GetRaceRequest{Id: 1}),
+			wantMessages: []proto.Message{&racing.GetRaceResponse{Race: &racing.Race{Id: 1, Name: "trader-1"}}},
+			wantTrailer:  "grpc-status: 0\r\n",
+			wantHeaders: map[string]string{
+				"Content-Type": "application/grpc-web+proto",
+				"X-Served-By":  "racing-1",
+			},
+		},
+		{
+			name:         "text encoding",
+			path:         "/racing.Racing/GetRace",
+			contentType:  "application/grpc-web-text",
+			body:         []byte(base64.StdEncoding.EncodeToString(frame(t, &racing.GetRaceRequest{Id: 1}))),
+			wantMessages: []proto.Message{&racing.GetRaceResponse{Race: &racing.Race{Id: 1}}},
+			wantTrailer:  "grpc-status: 0\r\n",
+			wantHeaders:  map[string]string{"Content-Type": "application/grpc-web-text+proto"},
+		},
+		{
+			name:        "backend error",
+			path:        "/racing.Racing/GetRace",
+			contentType: "application/grpc-web+proto",
+			body:        frame(t, &racing.GetRaceRequest{Id: 7}),
+			wantTrailer: "grpc-status: 5\r\ngrpc-message: race with ID 7 not found %E2%80%93 try 1%25\r\n",
+		},
+		{
+			name:         "server stream",
+			path:         "/grpc.health.v1.Health/Watch",
+			contentType:  "application/grpc-web+proto",
+			headers:      map[string]string{"Grpc-Timeout": "100m"},
+			body:         frame(t, &healthpb.HealthCheckRequest{}),
+			wantMessages: []proto.Message{&healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING}},
+			wantTrailer:  "grpc-status: 4\r\ngrpc-message: context deadline exceeded\r\n",
+		},
+		{
+			name:        "unknown service",
+			path:        "/sports.Sports/ListEvents",
+			contentType: "application/grpc-web+proto",
+			body:        frame(t, &racing.GetRaceRequest{Id: 1}),
+			wantTrailer: "grpc-status: 12\r\ngrpc-message: unknown method /sports.Sports/ListEvents\r\n",
+		},
+		{
+			name:        "missing message",
+			path:        "/racing.Racing/GetRace",
+			contentType: "application/grpc-web+proto",
+			wantTrailer: "grpc-status: 3\r\ngrpc-message: missing request message\r\n",
+		},
+		{
+			name:        "malformed timeout",
+			path:        "/racing.Racing/GetRace",
+			contentType: "application/grpc-web+proto",
+			headers:     map[string]string{"Grpc-Timeout": "soon"},
+			body:        frame(t, &racing.GetRaceRequest{Id: 1}),
+			wantTrailer: "grpc-status: 3\r\ngrpc-message: invalid grpc-timeout \"soon\"\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, bytes.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			if tt.identity != nil {
+				req = req.WithContext(auth.NewContext(req.Context(), *tt.identity))
+			}
+			rec := httptest.NewRecorder()
+			h.Middleware(http.NotFoundHandler()).ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200", rec.Code)
+			}
+			for k, want := range tt.wantHeaders {
+				if got := rec.Header().Get(k); got != want {
+					t.Errorf("header %s = %q, want %q", k, got, want)
+				}
+			}
+
+			body := rec.Body.Bytes()
+			if strings.HasPrefix(tt.contentType, contentTypeText) {
+				body = decodeText(t, body)
+			}
+			resp := decode(t, body)
+
+			if resp.trailer != tt.wantTrailer {
+				t.Errorf("trailer = %q, want %q", resp.trailer, tt.wantTrailer)
+			}
+			if len(resp.messages) != len(tt.wantMessages) {
+				t.Fatalf("got %d messages, want %d", len(resp.messages), len(tt.wantMessages))
+			}
+			for i, want := range tt.wantMessages {
+				got := want.ProtoReflect().New().Interface()
+				if err := proto.Unmarshal(resp.messages[i], got); err != nil {
+					t.Fatalf("failed to unmarshal message %d: %v", i, err)
+				}
+				if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+					t.Errorf("message %d mismatch (-want +got):\n%s", i, diff)
+				}
+			}
+		})
+	}
+}
+
+// decodeText decodes a grpc-web-text body, which is a sequence of base64
+// chunks, each padded on its own.
+func decodeText(t *testing.T, body []byte) []byte {
+	t.Helper()
+
+	var out []byte
+	for len(body) > 0 {
+		end := bytes.IndexByte(body, '=')
+		chunk := body
+		if end >= 0 {
+			for end < len(body) && body[end] == '=' {
+				end++
+			}
+			chunk = body[:end]
+		}
+		b, err := base64.StdEncoding.DecodeString(string(chunk))
+		if err != nil {
+			t.Fatalf("failed to decode %q: %v", chunk, err)
+		}
+		out = append(out, b...)
+		body = body[len(chunk):]
+	}
+	return out
+}
+
+func TestParseTimeout(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "10S", want: "10s"},
+		{in: "250m", want: "250ms"},
+		{in: "2H", want: "2h0m0s"},
+		{in: "S", wantErr: true},
+		{in: "10s", wantErr: true},
+		{in: "123456789S", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseTimeout(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseTimeout(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if err == nil && got.String() != tt.want {
+			t.Errorf("parseTimeout(%q) = %v, want %s", tt.in, got, tt.want)
+		}
+	}
+}
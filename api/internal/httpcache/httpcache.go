@@ -0,0 +1,248 @@
+package httpcache
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"git.neds.sh/matty/entain/api/internal/auth"
+	"git.neds.sh/matty/entain/api/internal/config"
+	"git.neds.sh/matty/entain/api/proto/racing"
+	"git.neds.sh/matty/entain/api/proto/sports"
+	"google.golang.org/protobuf/proto"
+)
+
+// startFraction is the share of the time left before the earliest open item
+// starts that a response may be cached for. Races and events close when they
+// start, so a response must go stale well before that.
+const startFraction = 10
+
+// Cache adds validators and freshness lifetimes to GET responses.
+//
+// Every successful GET response gets a strong ETag, the hash of its body, and
+// requests whose If-None-Match holds that ETag get 304 Not Modified. Races and
+// events carry no modification time, so there is no Last-Modified and
+// If-Modified-Since is ignored.
+//
+// Responses listing races or events also get a Cache-Control max-age that
+// shrinks as the earliest open item gets closer to its start.
+type Cache struct {
+	cfg      config.HTTPCacheConfig
+	now      func() time.Time
+	streamed map[string]bool
+}
+
+// New creates a Cache with the given lifetimes.
+func New(cfg config.HTTPCacheConfig) *Cache {
+	return &Cache{cfg: cfg, now: time.Now, streamed: make(map[string]bool)}
+}
+
+// Stream makes the middleware pass the responses of paths through untouched,
+// as they are written and without an ETag. It is meant for exports, which
+// may be too large to hold in memory, and must be called before serving.
+func (c *Cache) Stream(paths ...string) {
+	for _, path := range paths {
+		c.streamed[path] = true
+	}
+}
+
+// cacheableKey marks the context of GET requests, whose responses are cached.
+type cacheableKey struct{}
+
+// Middleware computes ETags and answers conditional requests. It buffers GET
+// responses, which the gateway renders in full before writing anyway, except
+// those of the paths given to Stream. Responses flushed before they are
+// complete are streamed from then on and get no ETag.
+func (c *Cache) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || c.streamed[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		buf := &bufferedWriter{ResponseWriter: w}
+		next.ServeHTTP(buf, r.WithContext(context.WithValue(r.Context(), cacheableKey{}, true)))
+		if buf.streaming {
+			return
+		}
+
+		if buf.status == 0 {
+			buf.status = http.StatusOK
+		}
+		if buf.status != http.StatusOK {
+			w.WriteHeader(buf.status)
+			w.Write(buf.body.Bytes())
+			return
+		}
+
+		etag := ETag(buf.body.Bytes())
+		w.Header().Set("ETag", etag)
+		// Hidden items are only listed for traders, so responses depend on the caller
+		w.Header().Add("Vary", "Authorization")
+
+		if matches(r.Header.Get("If-None-Match"), etag) {
+			w.Header().Del("Content-Type")
+			w.Header().Del("Content-Length")
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write(buf.body.Bytes())
+	})
+}
+
+// ForwardResponseOption sets Cache-Control on GET responses listing races or
+// events. It is meant for runtime.WithForwardResponseOption.
+func (c *Cache) ForwardResponseOption(ctx context.Context, w http.ResponseWriter, msg proto.Message) error {
+	if cacheable, _ := ctx.Value(cacheableKey{}).(bool); !cacheable {
+		return nil
+	}
+
+	starts, ok := startTimes(msg)
+	if !ok {
+		return nil
+	}
+
+	// Shared caches must not hand a trader's view to anyone else
+	scope := "public"
+	if _, ok := auth.FromContext(ctx); ok {
+		scope = "private"
+	}
+
+	maxAge := c.MaxAge(starts)
+	w.Header().Set("Cache-Control", fmt.Sprintf("%s, max-age=%d", scope, int(maxAge/time.Second)))
+	return nil
+}
+
+// MaxAge returns how long a response holding items starting at starts stays
+// fresh: a tenth of the time until the earliest future start, bounded by the
+// configured minimum and maximum. Items that have started do not shorten it.
+func (c *Cache) MaxAge(starts []time.Time) time.Duration {
+	now := c.now()
+
+	maxAge := c.cfg.MaxAge
+	for _, start := range starts {
+		if !start.After(now) {
+			continue
+		}
+		if age := start.Sub(now) / startFraction; age < maxAge {
+			maxAge = age
+		}
+	}
+
+	if maxAge < c.cfg.MinAge {
+		maxAge = c.cfg.MinAge
+	}
+	return maxAge.Truncate(time.Second)
+}
+
+// startTimes returns the start times of the races or events in msg, and
+// whether msg is a response whose lifetime depends on them.
+func startTimes(msg proto.Message) ([]time.Time, bool) {
+	var starts []time.Time
+	switch m := msg.(type) {
+	case *racing.GetRaceResponse:
+		// Prices may move at any time, so a race with its prices gets no lifetime
+		if len(m.GetRunners()) > 0 {
+			return nil, false
+		}
+		starts = append(starts, m.GetRace().GetAdvertisedStartTime().AsTime())
+	case *racing.ListRacesResponse:
+		for _, race := range m.GetRaces() {
+			starts = append(starts, race.GetAdvertisedStartTime().AsTime())
+		}
+	case *sports.GetEventResponse:
+		// Market prices move too, as do their statuses
+		if len(m.GetMarkets()) > 0 {
+			return nil, false
+		}
+		starts = append(starts, m.GetEvent().GetAdvertisedStartTime().AsTime())
+	case *sports.ListEventsResponse:
+		for _, event := range m.GetEvents() {
+			starts = append(starts, event.GetAdvertisedStartTime().AsTime())
+		}
+	case *sports.ListCompetitionEventsResponse:
+		for _, event := range m.GetEvents() {
+			starts = append(starts, event.GetAdvertisedStartTime().AsTime())
+		}
+	default:
+		return nil, false
+	}
+	return starts, true
+}
+
+// ETag returns the strong entity tag of a response body.
+func ETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// matches reports whether an If-None-Match header holds etag. The comparison
+// is weak, as RFC 7232 requires for If-None-Match.
+func matches(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// bufferedWriter holds back the status and body of a response, until the
+// response is flushed. From then on it is streamed.
+type bufferedWriter struct {
+	http.ResponseWriter
+	status    int
+	body      bytes.Buffer
+	streaming bool
+}
+
+func (w *bufferedWriter) WriteHeader(status int) {
+	if w.streaming {
+		return
+	}
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *bufferedWriter) Write(b []byte) (int, error) {
+	if w.streaming {
+		return w.ResponseWriter.Write(b)
+	}
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.body.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *bufferedWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Flush sends the response held back so far and streams the rest of it.
+func (w *bufferedWriter) Flush() {
+	if !w.streaming {
+		w.streaming = true
+		if w.status == 0 {
+			w.status = http.StatusOK
+		}
+		w.ResponseWriter.WriteHeader(w.status)
+		w.ResponseWriter.Write(w.body.Bytes())
+		w.body.Reset()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,292 @@
+package httpcache
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"git.neds.sh/matty/entain/api/internal/auth"
+	"git.neds.sh/matty/entain/api/internal/config"
+	"git.neds.sh/matty/entain/api/proto/racing"
+	"git.neds.sh/matty/entain/api/proto/sports"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var testConfig = config.HTTPCacheConfig{Enabled: true, MinAge: time.Second, MaxAge: time.Minute}
+
+// fixedClock returns a Cache whose clock is stopped at now
+func fixedClock(now time.Time) *Cache {
+	c := New(testConfig)
+	c.now = func() time.Time { return now }
+	return c
+}
+
+func TestCache_MaxAge(t *testing.T) {
+	now := time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		starts []time.Duration
+		want   time.Duration
+	}{
+		{name: "nothing listed", want: time.Minute},
+		{name: "starting in an hour", starts: []time.Duration{time.Hour}, want: time.Minute},
+		{name: "starting in five minutes", starts: []time.Duration{5 * time.Minute}, want: 30 * time.Second},
+		{name: "earliest start wins", starts: []time.Duration{time.Hour, 2 * time.Minute, 5 * time.Minute}, want: 12 * time.Second},
+		{name: "about to start", starts: []time.Duration{3 * time.Second}, want: time.Second},
+		{name: "already started", starts: []time.Duration{-time.Minute}, want: time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var starts []time.Time
+			for _, d := range tt.starts {
+				starts = append(starts, now.Add(d))
+			}
+			if got := fixedClock(now).MaxAge(starts); got != tt.want {
+				t.Errorf("MaxAge() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStartTimes(t *testing.T) {
+	start := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+	event := &sports.Event{Id: 1, AdvertisedStartTime: timestamppb.New(start)}
+
+	tests := []struct {
+		name     string
+		msg      proto.Message
+		wantLife bool
+	}{
+		{name: "event", msg: &sports.GetEventResponse{Event: event}, wantLife: true},
+		{name: "event with markets", msg: &sports.GetEventResponse{Event: event, Markets: []*sports.Market{{Id: 1}}}},
+		{name: "markets", msg: &sports.ListMarketsResponse{Markets: []*sports.Market{{Id: 1}}}},
+		{name: "competition events", msg: &sports.ListCompetitionEventsResponse{Events: []*sports.Event{event}}, wantLife: true},
+		{name: "competitions", msg: &sports.ListCompetitionsResponse{}},
+		{name: "race", msg: &racing.GetRaceResponse{Race: &racing.Race{AdvertisedStartTime: timestamppb.New(start)}}, wantLife: true},
+		{name: "race with prices", msg: &racing.GetRaceResponse{Runners: []*racing.RunnerPrices{{RunnerNumber: 1}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, got := startTimes(tt.msg); got != tt.wantLife {
+				t.Errorf("startTimes() lifetime = %v, want %v", got, tt.wantLife)
+			}
+		})
+	}
+}
+
+// racingServer serves a fixed set of races
+type racingServer struct {
+	racing.UnimplementedRacingServer
+	races []*racing.Race
+}
+
+func (s *racingServer) GetRace(_ context.Context, in *racing.GetRaceRequest) (*racing.GetRaceResponse, error) {
+	for _, race := range s.races {
+		if race.Id == in.Id {
+			resp := &racing.GetRaceResponse{Race: race}
+			if in.IncludePrices {
+				resp.Runners = []*racing.RunnerPrices{{RunnerNumber: 1, Current: &racing.Price{Win: 2.5, Place: 1.4}}}
+			}
+			return resp, nil
+		}
+	}
+	return nil, status.Errorf(codes.NotFound, "race with ID %d not found", in.Id)
+}
+
+func (s *racingServer) ListRaces(context.Context, *racing.ListRacesRequest) (*racing.ListRacesResponse, error) {
+	return &racing.ListRacesResponse{Races: s.races}, nil
+}
+
+// newHandler returns the gateway for server with the cache in front of it
+func newHandler(t *testing.T, now time.Time, server racing.RacingServer) http.Handler {
+	t.Helper()
+
+	cache := fixedClock(now)
+	mux := runtime.NewServeMux(runtime.WithForwardResponseOption(cache.ForwardResponseOption))
+	if err := racing.RegisterRacingHandlerServer(context.Background(), mux, server); err != nil {
+		t.Fatalf("failed to register racing handler: %v", err)
+	}
+	return cache.Middleware(mux)
+}
+
+func TestCache_Middleware(t *testing.T) {
+	now := time.Now()
+	server := &racingServer{races: []*racing.Race{
+		{Id: 1, AdvertisedStartTime: timestamppb.New(now.Add(5 * time.Minute))},
+		{Id: 2, AdvertisedStartTime: timestamppb.New(now.Add(2 * time.Hour))},
+	}}
+	handler := newHandler(t, now, server)
+
+	get := func(path, ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	first := get("/v1/races/1", "")
+	if first.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d (body: %s)", first.Code, http.StatusOK, first.Body)
+	}
+	etag := first.Header().Get("ETag")
+	if want := ETag(first.Body.Bytes()); etag != want {
+		t.Errorf("ETag = %q, want %q", etag, want)
+	}
+	if got, want := first.Header().Get("Cache-Control"), "public, max-age=30"; got != want {
+		t.Errorf("Cache-Control = %q, want %q", got, want)
+	}
+
+	t.Run("matching If-None-Match", func(t *testing.T) {
+		for _, header := range []string{etag, `"other", ` + etag, "W/" + etag, "*"} {
+			rec := get("/v1/races/1", header)
+			if rec.Code != http.StatusNotModified {
+				t.Errorf("If-None-Match %s: status = %d, want %d", header, rec.Code, http.StatusNotModified)
+			}
+			if rec.Body.Len() != 0 {
+				t.Errorf("If-None-Match %s: 304 has a body: %s", header, rec.Body)
+			}
+			if rec.Header().Get("ETag") != etag || rec.Header().Get("Cache-Control") == "" {
+				t.Errorf("If-None-Match %s: 304 is missing its validators: %v", header, rec.Header())
+			}
+		}
+	})
+
+	t.Run("stale If-None-Match", func(t *testing.T) {
+		rec := get("/v1/races/1", `"stale"`)
+		if rec.Code != http.StatusOK || rec.Body.String() != first.Body.String() {
+			t.Errorf("status = %d, body = %s; want the full response", rec.Code, rec.Body)
+		}
+	})
+
+	t.Run("other resources have other tags", func(t *testing.T) {
+		rec := get("/v1/races/2", etag)
+		if rec.Code != http.StatusOK {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusOK)
+		}
+		if got, want := rec.Header().Get("Cache-Control"), "public, max-age=60"; got != want {
+			t.Errorf("Cache-Control = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("prices have no lifetime", func(t *testing.T) {
+		rec := get("/v1/races/1?include_prices=true", "")
+		if rec.Header().Get("ETag") == "" {
+			t.Error("response with prices has no ETag")
+		}
+		if got := rec.Header().Get("Cache-Control"); got != "" {
+			t.Errorf("Cache-Control = %q, want none", got)
+		}
+	})
+
+	t.Run("list", func(t *testing.T) {
+		rec := get("/v1/races", "")
+		if rec.Header().Get("ETag") == "" {
+			t.Error("list response has no ETag")
+		}
+		if got, want := rec.Header().Get("Cache-Control"), "public, max-age=30"; got != want {
+			t.Errorf("Cache-Control = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("errors are not tagged", func(t *testing.T) {
+		rec := get("/v1/races/3", "*")
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusNotFound)
+		}
+		if rec.Header().Get("ETag") != "" || rec.Header().Get("Cache-Control") != "" {
+			t.Errorf("error response carries cache headers: %v", rec.Header())
+		}
+	})
+}
+
+func TestCache_Middleware_PrivateForAuthenticatedCallers(t *testing.T) {
+	now := time.Now()
+	handler := newHandler(t, now, &racingServer{races: []*racing.Race{
+		{Id: 1, AdvertisedStartTime: timestamppb.New(now.Add(time.Hour))},
+	}})
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/races/1", nil)
+	req = req.WithContext(auth.NewContext(req.Context(), auth.Identity{Subject: "trader-1"}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("Cache-Control"); !strings.HasPrefix(got, "private,") {
+		t.Errorf("Cache-Control = %q, want private", got)
+	}
+}
+
+func TestCache_Middleware_SkipsPOST(t *testing.T) {
+	handler := newHandler(t, time.Now(), &racingServer{})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/list-races", strings.NewReader("{}")))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d (body: %s)", rec.Code, http.StatusOK, rec.Body)
+	}
+	if rec.Header().Get("ETag") != "" || rec.Header().Get("Cache-Control") != "" {
+		t.Errorf("POST response carries cache headers: %v", rec.Header())
+	}
+}
+
+func TestCache_Middleware_StreamsFlushedResponses(t *testing.T) {
+	handler := New(testConfig).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Write([]byte("id\n1\n"))
+		w.(http.Flusher).Flush()
+		w.Write([]byte("2\n"))
+	}))
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/v1/races/export", nil)
+	req.Header.Set("If-None-Match", "*")
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if !rec.Flushed {
+		t.Error("response not flushed")
+	}
+	if got := rec.Body.String(); got != "id\n1\n2\n" {
+		t.Errorf("body = %q, want %q", got, "id\n1\n2\n")
+	}
+	if got := rec.Header().Get("ETag"); got != "" {
+		t.Errorf("ETag = %q, want none for a streamed response", got)
+	}
+}
+
+func TestCache_Middleware_PassesStreamedPathsThrough(t *testing.T) {
+	rec := httptest.NewRecorder()
+	cache := New(testConfig)
+	cache.Stream("/v1/races/export")
+	handler := cache.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("id\n1\n"))
+		// Nothing is held back, even before the first flush
+		if got := rec.Body.String(); got != "id\n1\n" {
+			t.Errorf("body written so far = %q, want %q", got, "id\n1\n")
+		}
+	}))
+
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/races/export", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if got := rec.Header().Get("ETag"); got != "" {
+		t.Errorf("ETag = %q, want none for a streamed path", got)
+	}
+}
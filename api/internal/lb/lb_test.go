@@ -0,0 +1,356 @@
+package lb
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"git.neds.sh/matty/entain/api/proto/racing"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestTarget(t *testing.T) {
+	tests := []struct {
+		endpoint string
+		want     string
+	}{
+		{"localhost:9000", "localhost:9000"},
+		{"racing-1:9000,racing-2:9000", "list:///racing-1:9000,racing-2:9000"},
+		{"dns:///racing:9000", "dns:///racing:9000"},
+		{"file:///etc/racing.endpoints", "file:///etc/racing.endpoints"},
+	}
+
+	for _, tt := range tests {
+		if got := Target(tt.endpoint); got != tt.want {
+			t.Errorf("Target(%q) = %q, want %q", tt.endpoint, got, tt.want)
+		}
+	}
+}
+
+func TestParseEndpoints(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "comma-separated",
+			in:   "racing-1:9000, racing-2:9000",
+			want: []string{"racing-1:9000", "racing-2:9000"},
+		},
+		{
+			name: "one per line with comments",
+			in:   "# racing replicas\nracing-1:9000\n\n  racing-2:9000  \n",
+			want: []string{"racing-1:9000", "racing-2:9000"},
+		},
+		{
+			name:    "missing port",
+			in:      "racing-1:9000\nracing-2",
+			wantErr: true,
+		},
+		{
+			name:    "only comments",
+			in:      "# no replicas yet\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseEndpoints(tt.in)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParseEndpoints() = %v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseEndpoints() failed: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("ParseEndpoints() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// replica is an in-process racing server counting the calls it receives
+type replica struct {
+	racing.UnimplementedRacingServer
+	addr   string
+	health *health.Server
+	calls  int32
+	// While holding is set, calls block until release is closed
+	holding int32
+	release chan struct{}
+}
+
+func (r *replica) GetRace(ctx context.Context, in *racing.GetRaceRequest) (*racing.GetRaceResponse, error) {
+	atomic.AddInt32(&r.calls, 1)
+	if atomic.LoadInt32(&r.holding) == 1 {
+		<-r.release
+	}
+	return &racing.GetRaceResponse{Race: &racing.Race{Id: in.Id}}, nil
+}
+
+func (r *replica) callCount() int {
+	return int(atomic.LoadInt32(&r.calls))
+}
+
+// startReplicas starts n racing servers on random ports
+func startReplicas(t *testing.T, n int) []*replica {
+	t.Helper()
+
+	replicas := make([]*replica, n)
+	for i := range replicas {
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("failed to listen: %v", err)
+		}
+
+		r := &replica{addr: lis.Addr().String(), health: health.NewServer(), release: make(chan struct{})}
+		server := grpc.NewServer()
+		racing.RegisterRacingServer(server, r)
+		healthpb.RegisterHealthServer(server, r.health)
+		go server.Serve(lis)
+		t.Cleanup(server.Stop)
+
+		replicas[i] = r
+	}
+	return replicas
+}
+
+// addrs returns the addresses of replicas
+func addrs(replicas []*replica) []string {
+	out := make([]string, len(replicas))
+	for i, r := range replicas {
+		out[i] = r.addr
+	}
+	return out
+}
+
+// dial connects to target with the given balancing policy and health checks
+func dial(t *testing.T, target, policy string, refresh time.Duration) racing.RacingClient {
+	t.Helper()
+
+	serviceConfig := fmt.Sprintf(`{"loadBalancingConfig":[{%q:{}}],"healthCheckConfig":{"serviceName":""}}`, policy)
+	conn, err := grpc.Dial(target,
+		grpc.WithInsecure(),
+		grpc.WithDefaultServiceConfig(serviceConfig),
+		grpc.WithResolvers(Resolvers(refresh, nil)...),
+	)
+	if err != nil {
+		t.Fatalf("failed to dial %s: %v", target, err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return racing.NewRacingClient(conn)
+}
+
+// getRaces makes n sequential calls, waiting for the replicas to be ready
+func getRaces(t *testing.T, client racing.RacingClient, n int) {
+	t.Helper()
+
+	for i := 0; i < n; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		_, err := client.GetRace(ctx, &racing.GetRaceRequest{Id: int64(i)}, grpc.WaitForReady(true))
+		cancel()
+		if err != nil {
+			t.Fatalf("GetRace() failed: %v", err)
+		}
+	}
+}
+
+// counts returns the calls received by each replica
+func counts(replicas []*replica) []int {
+	out := make([]int, len(replicas))
+	for i, r := range replicas {
+		out[i] = r.callCount()
+	}
+	return out
+}
+
+// waitUntil polls cond until it holds or the test times out
+func waitUntil(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting until %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestRoundRobin_SpreadsCallsAcrossList(t *testing.T) {
+	replicas := startReplicas(t, 3)
+	client := dial(t, Target(strings.Join(addrs(replicas), ",")), "round_robin", time.Second)
+
+	// Wait until every replica is connected so the spread is exact
+	waitUntil(t, "all replicas are in rotation", func() bool {
+		getRaces(t, client, 1)
+		for _, n := range counts(replicas) {
+			if n == 0 {
+				return false
+			}
+		}
+		return true
+	})
+	before := counts(replicas)
+
+	getRaces(t, client, 30)
+
+	for i, r := range replicas {
+		if got := r.callCount() - before[i]; got != 10 {
+			t.Errorf("replica %d received %d of 30 calls, want 10", i, got)
+		}
+	}
+}
+
+func TestRoundRobin_SkipsUnhealthyReplicas(t *testing.T) {
+	replicas := startReplicas(t, 3)
+	replicas[1].health.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+
+	client := dial(t, Target(strings.Join(addrs(replicas), ",")), "round_robin", time.Second)
+	waitUntil(t, "both healthy replicas are in rotation", func() bool {
+		getRaces(t, client, 1)
+		return replicas[0].callCount() > 0 && replicas[2].callCount() > 0
+	})
+	getRaces(t, client, 30)
+
+	if n := replicas[1].callCount(); n != 0 {
+		t.Errorf("unhealthy replica received %d calls, want 0", n)
+	}
+
+	// A replica that becomes healthy again is put back into rotation
+	replicas[1].health.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	waitUntil(t, "the recovered replica receives calls", func() bool {
+		getRaces(t, client, 1)
+		return replicas[1].callCount() > 0
+	})
+}
+
+func TestLeastRequest_AvoidsBusyReplica(t *testing.T) {
+	replicas := startReplicas(t, 3)
+	client := dial(t, Target(strings.Join(addrs(replicas), ",")), LeastRequest, time.Second)
+
+	waitUntil(t, "all replicas are in rotation", func() bool {
+		getRaces(t, client, 1)
+		for _, n := range counts(replicas) {
+			if n == 0 {
+				return false
+			}
+		}
+		return true
+	})
+
+	// Occupy every replica, then let the calls finish everywhere but on the
+	// busy one, which keeps a call in flight
+	for _, r := range replicas {
+		atomic.StoreInt32(&r.holding, 1)
+	}
+	before := counts(replicas)
+	done := make(chan struct{}, len(replicas))
+	for range replicas {
+		go func() {
+			client.GetRace(context.Background(), &racing.GetRaceRequest{Id: 1})
+			done <- struct{}{}
+		}()
+	}
+	waitUntil(t, "every replica holds a call", func() bool {
+		for i, n := range counts(replicas) {
+			if n == before[i] {
+				return false
+			}
+		}
+		return true
+	})
+
+	busy := replicas[0]
+	defer close(busy.release)
+	for _, r := range replicas[1:] {
+		atomic.StoreInt32(&r.holding, 0)
+		close(r.release)
+	}
+	for range replicas[1:] {
+		<-done
+	}
+	before = counts(replicas)
+
+	getRaces(t, client, 20)
+
+	got := counts(replicas)
+	if n := got[0] - before[0]; n != 0 {
+		t.Errorf("busy replica received %d calls, want 0", n)
+	}
+	if got[1] == before[1] || got[2] == before[2] {
+		t.Errorf("idle replicas received %v calls, want both to be used", []int{got[1] - before[1], got[2] - before[2]})
+	}
+}
+
+func TestFileResolver_PicksUpChanges(t *testing.T) {
+	replicas := startReplicas(t, 3)
+
+	path := filepath.Join(t.TempDir(), "racing.endpoints")
+	write := func(endpoints []string) {
+		t.Helper()
+		content := "# racing replicas\n" + strings.Join(endpoints, "\n") + "\n"
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("failed to write endpoints file: %v", err)
+		}
+	}
+
+	write(addrs(replicas[:2]))
+	client := dial(t, "file://"+path, "round_robin", 10*time.Millisecond)
+
+	getRaces(t, client, 20)
+	if n := replicas[2].callCount(); n != 0 {
+		t.Fatalf("unlisted replica received %d calls, want 0", n)
+	}
+
+	// Add the third replica and remove the first
+	write(addrs(replicas[1:]))
+	waitUntil(t, "the added replica receives calls", func() bool {
+		getRaces(t, client, 1)
+		return replicas[2].callCount() > 0
+	})
+
+	// An invalid file keeps the previous endpoints
+	if err := os.WriteFile(path, []byte("not an endpoint\n"), 0o600); err != nil {
+		t.Fatalf("failed to write endpoints file: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	before := counts(replicas)
+	getRaces(t, client, 20)
+	got := counts(replicas)
+	if got[0] != before[0] {
+		t.Errorf("removed replica received %d calls, want 0", got[0]-before[0])
+	}
+	if got[1] == before[1] || got[2] == before[2] {
+		t.Errorf("listed replicas received %v calls, want both to be used", []int{got[1] - before[1], got[2] - before[2]})
+	}
+}
+
+func TestFileResolver_MissingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing.endpoints")
+
+	conn, err := grpc.Dial("file://"+path,
+		grpc.WithInsecure(),
+		grpc.WithResolvers(Resolvers(time.Second, nil)...),
+	)
+	if err == nil {
+		conn.Close()
+		t.Fatal("Dial() succeeded without an endpoints file, want error")
+	}
+}
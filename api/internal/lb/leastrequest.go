@@ -0,0 +1,76 @@
+package lb
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"git.neds.sh/matty/entain/api/internal/config"
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/base"
+)
+
+// LeastRequest is the name of the least-request balancing policy.
+const LeastRequest = config.PolicyLeastRequest
+
+func init() {
+	balancer.Register(base.NewBalancerBuilder(LeastRequest, leastRequestPickerBuilder{}, base.Config{HealthCheck: true}))
+}
+
+// leastRequestPickerBuilder builds pickers over the ready, and when health
+// checking is on, healthy subchannels.
+type leastRequestPickerBuilder struct{}
+
+func (leastRequestPickerBuilder) Build(info base.PickerBuildInfo) balancer.Picker {
+	if len(info.ReadySCs) == 0 {
+		return base.NewErrPicker(balancer.ErrNoSubConnAvailable)
+	}
+
+	p := &leastRequestPicker{}
+	for sc := range info.ReadySCs {
+		p.subConns = append(p.subConns, &countedSubConn{sc: sc})
+	}
+	return p
+}
+
+// countedSubConn is a subchannel and its number of outstanding calls.
+type countedSubConn struct {
+	sc       balancer.SubConn
+	inFlight int64
+}
+
+// leastRequestPicker sends each call to the subchannel with the fewest calls
+// in flight, rotating the starting point so ties are spread round-robin.
+//
+// Counts are per picker: when the set of ready subchannels changes, the new
+// picker starts from zero while calls picked by the old one finish. The skew
+// only lasts as long as those calls.
+type leastRequestPicker struct {
+	subConns []*countedSubConn
+
+	mu   sync.Mutex
+	next int
+}
+
+func (p *leastRequestPicker) Pick(balancer.PickInfo) (balancer.PickResult, error) {
+	p.mu.Lock()
+	start := p.next
+	p.next = (p.next + 1) % len(p.subConns)
+	p.mu.Unlock()
+
+	var best *countedSubConn
+	bestCount := int64(-1)
+	for i := range p.subConns {
+		c := p.subConns[(start+i)%len(p.subConns)]
+		if n := atomic.LoadInt64(&c.inFlight); bestCount < 0 || n < bestCount {
+			best, bestCount = c, n
+		}
+	}
+
+	atomic.AddInt64(&best.inFlight, 1)
+	return balancer.PickResult{
+		SubConn: best.sc,
+		Done: func(balancer.DoneInfo) {
+			atomic.AddInt64(&best.inFlight, -1)
+		},
+	}, nil
+}
@@ -0,0 +1,223 @@
+package lb
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/resolver"
+)
+
+// Resolver schemes handled by this package. "dns" is provided by gRPC.
+const (
+	ListScheme = "list"
+	FileScheme = "file"
+)
+
+// Target turns a configured backend endpoint into a gRPC dial target.
+// A comma-separated list of host:port pairs becomes a list:/// target;
+// anything else, a single host:port or a dns:/// or file:// target, is
+// returned unchanged.
+func Target(endpoint string) string {
+	if strings.Contains(endpoint, ",") && !strings.Contains(endpoint, "://") {
+		return ListScheme + ":///" + endpoint
+	}
+	return endpoint
+}
+
+// defaultRefreshInterval is used when no refresh interval is given.
+const defaultRefreshInterval = 5 * time.Second
+
+// Resolvers returns the resolvers for the list and file schemes. File targets
+// are re-read every refreshInterval. They are meant to be passed to
+// grpc.WithResolvers rather than registered globally.
+func Resolvers(refreshInterval time.Duration, logger *zap.Logger) []resolver.Builder {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	if refreshInterval <= 0 {
+		refreshInterval = defaultRefreshInterval
+	}
+	return []resolver.Builder{
+		listBuilder{},
+		&fileBuilder{refreshInterval: refreshInterval, logger: logger},
+	}
+}
+
+// ParseEndpoints parses a list of host:port pairs separated by commas or
+// newlines. Blank lines and lines starting with # are ignored.
+func ParseEndpoints(s string) ([]string, error) {
+	var endpoints []string
+	scanner := bufio.NewScanner(strings.NewReader(s))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		for _, endpoint := range strings.Split(line, ",") {
+			endpoint = strings.TrimSpace(endpoint)
+			if _, _, err := net.SplitHostPort(endpoint); err != nil {
+				return nil, fmt.Errorf("endpoint %q is not a valid host:port", endpoint)
+			}
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(endpoints) == 0 {
+		return nil, fmt.Errorf("no endpoints")
+	}
+	return endpoints, nil
+}
+
+// state converts endpoints to a resolver update.
+func state(endpoints []string) resolver.State {
+	addrs := make([]resolver.Address, len(endpoints))
+	for i, endpoint := range endpoints {
+		addrs[i] = resolver.Address{Addr: endpoint}
+	}
+	return resolver.State{Addresses: addrs}
+}
+
+// listBuilder resolves list:///host1:port,host2:port to a fixed set of addresses.
+type listBuilder struct{}
+
+func (listBuilder) Scheme() string { return ListScheme }
+
+func (listBuilder) Build(target resolver.Target, cc resolver.ClientConn, _ resolver.BuildOptions) (resolver.Resolver, error) {
+	endpoints, err := ParseEndpoints(strings.TrimPrefix(target.URL.Path, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s target: %w", ListScheme, err)
+	}
+	// Like the passthrough resolver, errors are left to the balancer to report
+	cc.UpdateState(state(endpoints))
+	return nopResolver{}, nil
+}
+
+// nopResolver is a resolver whose addresses never change.
+type nopResolver struct{}
+
+func (nopResolver) ResolveNow(resolver.ResolveNowOptions) {}
+func (nopResolver) Close()                                {}
+
+// fileBuilder resolves file:///path to the endpoints listed in the file, one
+// per line. The file is polled so replicas can be added or removed without
+// restarting the gateway.
+type fileBuilder struct {
+	refreshInterval time.Duration
+	logger          *zap.Logger
+}
+
+func (*fileBuilder) Scheme() string { return FileScheme }
+
+func (b *fileBuilder) Build(target resolver.Target, cc resolver.ClientConn, _ resolver.BuildOptions) (resolver.Resolver, error) {
+	path := target.URL.Opaque
+	if path == "" {
+		path = target.URL.Host + target.URL.Path
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &fileResolver{
+		path:   path,
+		cc:     cc,
+		logger: b.logger.With(zap.String("endpoints_file", path)),
+		cancel: cancel,
+		now:    make(chan struct{}, 1),
+	}
+
+	// A missing or empty file at startup is a configuration error
+	if err := r.resolve(); err != nil {
+		cancel()
+		return nil, err
+	}
+
+	r.wg.Add(1)
+	go r.watch(ctx, b.refreshInterval)
+
+	return r, nil
+}
+
+// fileResolver pushes the endpoints of a file to a channel whenever the file
+// changes. Unreadable or empty versions of the file are logged and ignored, so
+// the channel keeps the last good set of endpoints.
+type fileResolver struct {
+	path   string
+	cc     resolver.ClientConn
+	logger *zap.Logger
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	now    chan struct{}
+
+	modTime time.Time
+	size    int64
+}
+
+// ResolveNow re-reads the file without waiting for the next refresh.
+func (r *fileResolver) ResolveNow(resolver.ResolveNowOptions) {
+	select {
+	case r.now <- struct{}{}:
+	default:
+	}
+}
+
+// Close stops watching the file.
+func (r *fileResolver) Close() {
+	r.cancel()
+	r.wg.Wait()
+}
+
+func (r *fileResolver) watch(ctx context.Context, interval time.Duration) {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-r.now:
+		}
+
+		if err := r.resolve(); err != nil {
+			r.logger.Error("Failed to reload backend endpoints, keeping the previous ones", zap.Error(err))
+		}
+	}
+}
+
+// resolve reads the file and updates the channel if it changed since the last
+// successful read.
+func (r *fileResolver) resolve() error {
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return fmt.Errorf("failed to read endpoints file: %w", err)
+	}
+	if info.ModTime().Equal(r.modTime) && info.Size() == r.size {
+		return nil
+	}
+
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		return fmt.Errorf("failed to read endpoints file: %w", err)
+	}
+	endpoints, err := ParseEndpoints(string(data))
+	if err != nil {
+		return fmt.Errorf("invalid endpoints file %s: %w", r.path, err)
+	}
+
+	r.modTime, r.size = info.ModTime(), info.Size()
+
+	r.logger.Info("Loaded backend endpoints", zap.Strings("endpoints", endpoints))
+	if err := r.cc.UpdateState(state(endpoints)); err != nil {
+		r.logger.Warn("Backend endpoints were rejected by the balancer", zap.Error(err))
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Environment variable names for logger configuration
+const (
+	EnvLogLevel    = "LOG_LEVEL"
+	EnvEnvironment = "ENVIRONMENT"
+)
+
+// LogLevel represents different log levels
+type LogLevel string
+
+// Available log levels
+const (
+	DebugLevel LogLevel = "debug"
+	InfoLevel  LogLevel = "info"
+	WarnLevel  LogLevel = "warn"
+	ErrorLevel LogLevel = "error"
+)
+
+// Environment represents different deployment environments
+type Environment string
+
+// Available deployment environments
+const (
+	Development Environment = "development"
+	Production  Environment = "production"
+	Testing     Environment = "testing"
+)
+
+// Config holds logger configuration
+type Config struct {
+	Level       LogLevel
+	Environment Environment
+}
+
+// NewFromEnv creates a logger configuration from environment variables
+func NewFromEnv() Config {
+	return Config{
+		Level:       getLogLevelFromEnv(),
+		Environment: getEnvironmentFromEnv(),
+	}
+}
+
+// getLogLevelFromEnv reads log level from LOG_LEVEL environment variable
+func getLogLevelFromEnv() LogLevel {
+	level, err := ParseLevel(os.Getenv(EnvLogLevel))
+	if err != nil {
+		return InfoLevel
+	}
+	return level
+}
+
+// getEnvironmentFromEnv reads environment from ENVIRONMENT environment variable
+func getEnvironmentFromEnv() Environment {
+	env, err := ParseEnvironment(os.Getenv(EnvEnvironment))
+	if err != nil {
+		return Production
+	}
+	return env
+}
+
+// ParseLevel converts a case-insensitive level name into a LogLevel.
+// An empty string yields InfoLevel.
+func ParseLevel(s string) (LogLevel, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return DebugLevel, nil
+	case "info", "":
+		return InfoLevel, nil
+	case "warn":
+		return WarnLevel, nil
+	case "error":
+		return ErrorLevel, nil
+	default:
+		return InfoLevel, fmt.Errorf("unknown log level %q", s)
+	}
+}
+
+// ParseEnvironment converts a case-insensitive environment name, including the
+// short aliases "dev", "prod" and "test", into an Environment.
+// An empty string yields Production.
+func ParseEnvironment(s string) (Environment, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "development", "dev":
+		return Development, nil
+	case "production", "prod", "":
+		return Production, nil
+	case "testing", "test":
+		return Testing, nil
+	default:
+		return Production, fmt.Errorf("unknown environment %q", s)
+	}
+}
+
+// New creates a new zap logger based on configuration
+func New(config Config) (*zap.Logger, error) {
+	var zapConfig zap.Config
+
+	switch config.Environment {
+	case Production:
+		zapConfig = zap.NewProductionConfig()
+		// JSON format for production (easier for log aggregators)
+		zapConfig.Encoding = "json"
+
+	case Testing:
+		zapConfig = zap.NewDevelopmentConfig()
+		zapConfig.Encoding = "console"
+		zapConfig.DisableCaller = true
+
+	case Development:
+		fallthrough
+	default:
+		zapConfig = zap.NewDevelopmentConfig()
+		zapConfig.Encoding = "console"
+		zapConfig.EncoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
+	}
+
+	// Always log to stdout/stderr (Kubernetes will handle collection)
+	zapConfig.OutputPaths = []string{"stdout"}
+	zapConfig.ErrorOutputPaths = []string{"stderr"}
+
+	// Set log level
+	zapConfig.Level = zap.NewAtomicLevelAt(mapLogLevel(config.Level))
+
+	return zapConfig.Build()
+}
+
+// NewForProduction creates a production logger
+func NewForProduction() (*zap.Logger, error) {
+	config := Config{
+		Level:       InfoLevel,
+		Environment: Production,
+	}
+	return New(config)
+}
+
+// NewForDevelopment creates a development logger
+func NewForDevelopment() (*zap.Logger, error) {
+	config := Config{
+		Level:       DebugLevel,
+		Environment: Development,
+	}
+	return New(config)
+}
+
+// NewForTesting creates a testing logger
+func NewForTesting() (*zap.Logger, error) {
+	config := Config{
+		Level:       ErrorLevel,
+		Environment: Testing,
+	}
+	return New(config)
+}
+
+// NewNop returns a no-op logger
+func NewNop() *zap.Logger {
+	return zap.NewNop()
+}
+
+// mapLogLevel converts our LogLevel to zap level
+func mapLogLevel(level LogLevel) zapcore.Level {
+	switch level {
+	case DebugLevel:
+		return zapcore.DebugLevel
+	case InfoLevel:
+		return zapcore.InfoLevel
+	case WarnLevel:
+		return zapcore.WarnLevel
+	case ErrorLevel:
+		return zapcore.ErrorLevel
+	default:
+		return zapcore.InfoLevel
+	}
+}
@@ -0,0 +1,154 @@
+package negotiation
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"git.neds.sh/matty/entain/api/internal/grpcweb"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// Media types the gateway renders responses in.
+const (
+	ContentTypeJSON     = "application/json"
+	ContentTypeProtobuf = "application/x-protobuf"
+)
+
+// supported lists the media types in order of preference, for clients that
+// accept several equally.
+var supported = []string{ContentTypeJSON, ContentTypeProtobuf}
+
+// protoMarshaler renders messages in the protobuf binary format.
+type protoMarshaler struct {
+	runtime.ProtoMarshaller
+}
+
+// ContentType labels responses as application/x-protobuf, rather than the
+// application/octet-stream of runtime.ProtoMarshaller.
+func (protoMarshaler) ContentType(interface{}) string {
+	return ContentTypeProtobuf
+}
+
+// MuxOptions registers a marshaler for each supported media type. The JSON
+// marshaler is the gateway's default one, registered under its own name so
+// that JSON can be asked for explicitly.
+func MuxOptions() []runtime.ServeMuxOption {
+	return []runtime.ServeMuxOption{
+		runtime.WithMarshalerOption(ContentTypeJSON, &runtime.HTTPBodyMarshaler{
+			Marshaler: &runtime.JSONPb{
+				MarshalOptions:   protojson.MarshalOptions{EmitUnpopulated: true},
+				UnmarshalOptions: protojson.UnmarshalOptions{DiscardUnknown: true},
+			},
+		}),
+		runtime.WithMarshalerOption(ContentTypeProtobuf, &protoMarshaler{}),
+	}
+}
+
+// Middleware picks the media type of the response from the Accept header.
+//
+// The gateway only matches Accept values exactly, so the header is replaced
+// by the best supported type, or removed if none is acceptable, in which case
+// the response is JSON. Media ranges, quality values and lists are therefore
+// honoured. grpc-web calls carry their own framing and are left alone.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if grpcweb.IsGRPCWeb(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Accept")
+
+		accept := strings.Join(r.Header.Values("Accept"), ",")
+		r = r.Clone(r.Context())
+		if mediaType := Negotiate(accept); mediaType != "" {
+			r.Header.Set("Accept", mediaType)
+		} else {
+			r.Header.Del("Accept")
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Negotiate returns the supported media type an Accept header prefers, or ""
+// if it accepts none of them. An empty header accepts anything.
+func Negotiate(accept string) string {
+	if strings.TrimSpace(accept) == "" {
+		return supported[0]
+	}
+
+	ranges := parseAccept(accept)
+	best, bestQ := "", 0.0
+	for _, mediaType := range supported {
+		if q := quality(ranges, mediaType); q > bestQ {
+			best, bestQ = mediaType, q
+		}
+	}
+	return best
+}
+
+// mediaRange is one entry of an Accept header.
+type mediaRange struct {
+	typ, subtype string
+	q            float64
+}
+
+func parseAccept(accept string) []mediaRange {
+	var ranges []mediaRange
+	for _, entry := range strings.Split(accept, ",") {
+		params := strings.Split(entry, ";")
+		typ, subtype, ok := cut(strings.ToLower(strings.TrimSpace(params[0])), "/")
+		if !ok {
+			continue
+		}
+
+		mr := mediaRange{typ: typ, subtype: subtype, q: 1}
+		for _, param := range params[1:] {
+			name, value, _ := cut(strings.TrimSpace(param), "=")
+			if strings.EqualFold(name, "q") {
+				q, err := strconv.ParseFloat(value, 64)
+				if err != nil || q < 0 || q > 1 {
+					q = 0
+				}
+				mr.q = q
+			}
+		}
+		ranges = append(ranges, mr)
+	}
+	return ranges
+}
+
+// quality returns the quality value the most specific matching range gives
+// mediaType, as RFC 7231 section 5.3.2 describes.
+func quality(ranges []mediaRange, mediaType string) float64 {
+	typ, subtype, _ := cut(mediaType, "/")
+
+	q, specificity := 0.0, -1
+	for _, mr := range ranges {
+		var s int
+		switch {
+		case mr.typ == typ && mr.subtype == subtype:
+			s = 2
+		case mr.typ == typ && mr.subtype == "*":
+			s = 1
+		case mr.typ == "*" && mr.subtype == "*":
+			s = 0
+		default:
+			continue
+		}
+		if s > specificity {
+			q, specificity = mr.q, s
+		}
+	}
+	return q
+}
+
+// cut slices s around the first sep.
+func cut(s, sep string) (before, after string, found bool) {
+	if i := strings.Index(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
@@ -0,0 +1,102 @@
+package negotiation
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"git.neds.sh/matty/entain/api/proto/racing"
+	"github.com/google/go-cmp/cmp"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{accept: "", want: ContentTypeJSON},
+		{accept: "application/json", want: ContentTypeJSON},
+		{accept: "application/x-protobuf", want: ContentTypeProtobuf},
+		{accept: "Application/X-Protobuf", want: ContentTypeProtobuf},
+		{accept: "*/*", want: ContentTypeJSON},
+		{accept: "application/*", want: ContentTypeJSON},
+		{accept: "application/x-protobuf, */*;q=0.1", want: ContentTypeProtobuf},
+		{accept: "application/json;q=0.5, application/x-protobuf;q=0.9", want: ContentTypeProtobuf},
+		{accept: "application/x-protobuf;q=0.5, application/*", want: ContentTypeJSON},
+		{accept: "application/json;q=0, */*", want: ContentTypeProtobuf},
+		{accept: "text/html", want: ""},
+		{accept: "text/html, application/xhtml+xml, */*;q=0.8", want: ContentTypeJSON},
+		{accept: "garbage", want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := Negotiate(tt.accept); got != tt.want {
+			t.Errorf("Negotiate(%q) = %q, want %q", tt.accept, got, tt.want)
+		}
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	race := &racing.GetRaceResponse{Race: &racing.Race{Id: 1, Name: "Race 1", Visible: true}}
+
+	mux := runtime.NewServeMux(MuxOptions()...)
+	err := mux.HandlePath(http.MethodGet, "/v1/races/1", func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		_, outbound := runtime.MarshalerForRequest(mux, r)
+		runtime.ForwardResponseMessage(r.Context(), mux, outbound, w, r, race)
+	})
+	if err != nil {
+		t.Fatalf("failed to register handler: %v", err)
+	}
+
+	tests := []struct {
+		name            string
+		accept          string
+		wantContentType string
+	}{
+		{name: "no Accept", wantContentType: ContentTypeJSON},
+		{name: "JSON", accept: "application/json", wantContentType: ContentTypeJSON},
+		{name: "protobuf", accept: "application/x-protobuf", wantContentType: ContentTypeProtobuf},
+		{name: "protobuf among others", accept: "application/x-protobuf, application/json;q=0.5", wantContentType: ContentTypeProtobuf},
+		{name: "unsupported", accept: "text/csv", wantContentType: ContentTypeJSON},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/v1/races/1", nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			rec := httptest.NewRecorder()
+			Middleware(mux).ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200", rec.Code)
+			}
+			if got := rec.Header().Get("Content-Type"); got != tt.wantContentType {
+				t.Errorf("Content-Type = %q, want %q", got, tt.wantContentType)
+			}
+			if got := rec.Header().Get("Vary"); got != "Accept" {
+				t.Errorf("Vary = %q, want Accept", got)
+			}
+			if req.Header.Get("Accept") != tt.accept {
+				t.Errorf("request Accept changed to %q", req.Header.Get("Accept"))
+			}
+
+			got := &racing.GetRaceResponse{}
+			unmarshal := protojson.Unmarshal
+			if tt.wantContentType == ContentTypeProtobuf {
+				unmarshal = proto.Unmarshal
+			}
+			if err := unmarshal(rec.Body.Bytes(), got); err != nil {
+				t.Fatalf("failed to unmarshal %q: %v", rec.Body.String(), err)
+			}
+			if diff := cmp.Diff(race, got, protocmp.Transform()); diff != "" {
+				t.Errorf("body mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,327 @@
+package nexttogo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"git.neds.sh/matty/entain/api/proto/racing"
+	"git.neds.sh/matty/entain/api/proto/sports"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Path is the route of the feed.
+const Path = "/v1/next-to-go"
+
+// jsonMarshaler renders resources as the gateway does by default, for clients
+// asking for a format the feed cannot be given in.
+var jsonMarshaler = &runtime.JSONPb{MarshalOptions: protojson.MarshalOptions{EmitUnpopulated: true}}
+
+// Item types, used as the discriminator of feed entries.
+const (
+	TypeRace  = "race"
+	TypeEvent = "event"
+)
+
+// CategoryRacing selects races. Every other category is a sport type, such as
+// "football", selecting the sports events of that type.
+const CategoryRacing = "racing"
+
+// Bounds of the limit query parameter.
+const (
+	DefaultLimit = 10
+	MaxLimit     = 100
+)
+
+// Item is an entry of the feed: a race or a sports event, whichever Type says.
+// Race and Event hold the resource as the backend's REST endpoints render it.
+type Item struct {
+	Type                string          `json:"type"`
+	AdvertisedStartTime time.Time       `json:"advertisedStartTime"`
+	Race                json.RawMessage `json:"race,omitempty"`
+	Event               json.RawMessage `json:"event,omitempty"`
+
+	// resource is the race or event, rendered once the feed is cut to size
+	resource proto.Message
+}
+
+// Warning reports a backend whose items are missing from a partial feed.
+type Warning struct {
+	Backend string `json:"backend"`
+	Message string `json:"message"`
+}
+
+// Response is the body of GET /v1/next-to-go.
+type Response struct {
+	Items    []Item    `json:"items"`
+	Warnings []Warning `json:"warnings,omitempty"`
+}
+
+// Handler serves the next-to-go feed: the open races and sports events
+// starting soonest, merged into a single list ordered by start time.
+type Handler struct {
+	racing racing.RacingClient
+	sports sports.SportsClient
+	logger *zap.Logger
+}
+
+// New creates a feed handler reading from the racing and sports backends.
+func New(racingClient racing.RacingClient, sportsClient sports.SportsClient, logger *zap.Logger) *Handler {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &Handler{racing: racingClient, sports: sportsClient, logger: logger}
+}
+
+// Register adds the feed to mux, which also provides its marshaler, error
+// handling and the metadata forwarded to the backends.
+func (h *Handler) Register(mux *runtime.ServeMux) error {
+	return mux.HandlePath(http.MethodGet, Path, func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		h.serve(mux, w, r)
+	})
+}
+
+// query holds the parsed query parameters.
+type query struct {
+	limit      int
+	races      bool
+	sportTypes []string
+	// events is set when events are wanted, of any type if sportTypes is empty
+	events bool
+}
+
+// parseQuery reads limit and categories. Categories may be repeated or
+// comma-separated; none selects everything.
+func parseQuery(values url.Values) (query, error) {
+	q := query{limit: DefaultLimit}
+
+	if v := values.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > MaxLimit {
+			return query{}, invalidParam("limit", fmt.Sprintf("limit must be a number between 1 and %d", MaxLimit))
+		}
+		q.limit = n
+	}
+
+	seen := make(map[string]bool)
+	for _, value := range values["categories"] {
+		for _, category := range strings.Split(value, ",") {
+			category = strings.ToLower(strings.TrimSpace(category))
+			if category == "" || seen[category] {
+				continue
+			}
+			seen[category] = true
+
+			if category == CategoryRacing {
+				q.races = true
+			} else {
+				q.events = true
+				q.sportTypes = append(q.sportTypes, category)
+			}
+		}
+	}
+	if len(seen) == 0 {
+		q.races, q.events = true, true
+	}
+
+	return q, nil
+}
+
+// invalidParam returns an InvalidArgument status naming the invalid parameter.
+func invalidParam(name, reason string) error {
+	st := status.New(codes.InvalidArgument, reason)
+	detailed, err := st.WithDetails(&errdetails.BadRequest{
+		FieldViolations: []*errdetails.BadRequest_FieldViolation{{Field: name, Description: reason}},
+	})
+	if err != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
+
+// backendResult is the outcome of one backend call.
+type backendResult struct {
+	backend string
+	items   []Item
+	err     error
+}
+
+func (h *Handler) serve(mux *runtime.ServeMux, w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	_, outbound := runtime.MarshalerForRequest(mux, r)
+	if outbound.ContentType(nil) != "application/json" {
+		// The feed is not a protobuf message, so it is JSON whatever the client accepts
+		outbound = jsonMarshaler
+	}
+
+	q, err := parseQuery(r.URL.Query())
+	if err != nil {
+		runtime.HTTPError(ctx, mux, outbound, w, r, err)
+		return
+	}
+
+	// Both backends are called concurrently; each result lands in its own slot
+	var calls []func() backendResult
+	if q.races {
+		calls = append(calls, func() backendResult { return h.races(ctx, mux, r, q.limit) })
+	}
+	if q.events {
+		calls = append(calls, func() backendResult { return h.events(ctx, mux, r, q.sportTypes, q.limit) })
+	}
+
+	results := make([]backendResult, len(calls))
+	var wg sync.WaitGroup
+	for i, call := range calls {
+		wg.Add(1)
+		go func(i int, call func() backendResult) {
+			defer wg.Done()
+			results[i] = call()
+		}(i, call)
+	}
+	wg.Wait()
+
+	resp := Response{Items: []Item{}}
+	var firstErr error
+	for _, result := range results {
+		if result.err != nil {
+			h.logger.Warn("Next to go backend call failed",
+				zap.String("backend", result.backend),
+				zap.Error(result.err),
+			)
+			if firstErr == nil {
+				firstErr = result.err
+			}
+			resp.Warnings = append(resp.Warnings, Warning{
+				Backend: result.backend,
+				Message: status.Convert(result.err).Message(),
+			})
+			continue
+		}
+		resp.Items = append(resp.Items, result.items...)
+	}
+
+	// Partial results are only useful if at least one backend answered
+	if len(resp.Warnings) == len(results) {
+		runtime.HTTPError(ctx, mux, outbound, w, r, firstErr)
+		return
+	}
+
+	// Each backend returned its first items; the feed is the first of both
+	sortItems(resp.Items)
+	if len(resp.Items) > q.limit {
+		resp.Items = resp.Items[:q.limit]
+	}
+
+	for i := range resp.Items {
+		if err := render(outbound, &resp.Items[i]); err != nil {
+			runtime.HTTPError(ctx, mux, outbound, w, r, err)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.logger.Error("Failed to write next to go response", zap.Error(err))
+	}
+}
+
+// races lists the first limit visible open races by start time.
+func (h *Handler) races(ctx context.Context, mux *runtime.ServeMux, r *http.Request, limit int) backendResult {
+	result := backendResult{backend: "racing"}
+
+	ctx, err := runtime.AnnotateContext(ctx, mux, r, "/racing.Racing/ListRaces")
+	if err != nil {
+		result.err = err
+		return result
+	}
+
+	// The feed is public, so hidden races stay out even for traders
+	visible, open, n := true, true, int32(limit)
+	resp, err := h.racing.ListRaces(ctx, &racing.ListRacesRequest{
+		Filter: &racing.ListRacesRequestFilter{VisibleOnly: &visible, OpenOnly: &open, Limit: &n},
+	})
+	if err != nil {
+		result.err = err
+		return result
+	}
+
+	for _, race := range resp.GetRaces() {
+		result.items = append(result.items, Item{
+			Type:                TypeRace,
+			AdvertisedStartTime: race.GetAdvertisedStartTime().AsTime(),
+			resource:            race,
+		})
+	}
+	return result
+}
+
+// events lists the first limit visible open events by start time, of
+// sportTypes or of every type if empty.
+func (h *Handler) events(ctx context.Context, mux *runtime.ServeMux, r *http.Request, sportTypes []string, limit int) backendResult {
+	result := backendResult{backend: "sports"}
+
+	ctx, err := runtime.AnnotateContext(ctx, mux, r, "/sports.Sports/ListEvents")
+	if err != nil {
+		result.err = err
+		return result
+	}
+
+	// The feed is public, so hidden events stay out even for traders
+	visible, open, n := true, true, int32(limit)
+	resp, err := h.sports.ListEvents(ctx, &sports.ListEventsRequest{
+		Filter: &sports.ListEventsRequestFilter{SportTypes: sportTypes, VisibleOnly: &visible, OpenOnly: &open, Limit: &n},
+	})
+	if err != nil {
+		result.err = err
+		return result
+	}
+
+	for _, event := range resp.GetEvents() {
+		result.items = append(result.items, Item{
+			Type:                TypeEvent,
+			AdvertisedStartTime: event.GetAdvertisedStartTime().AsTime(),
+			resource:            event,
+		})
+	}
+	return result
+}
+
+// render marshals the item's resource with the gateway's marshaler.
+func render(m runtime.Marshaler, item *Item) error {
+	raw, err := m.Marshal(item.resource)
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to marshal %s: %v", item.Type, err)
+	}
+
+	if item.Type == TypeRace {
+		item.Race = raw
+	} else {
+		item.Event = raw
+	}
+	return nil
+}
+
+// sortItems orders items by start time. Races come before events starting at
+// the same time, and otherwise the backends' order is kept.
+func sortItems(items []Item) {
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := items[i], items[j]
+		if !a.AdvertisedStartTime.Equal(b.AdvertisedStartTime) {
+			return a.AdvertisedStartTime.Before(b.AdvertisedStartTime)
+		}
+		return a.Type > b.Type
+	})
+}
@@ -0,0 +1,341 @@
+package nexttogo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"git.neds.sh/matty/entain/api/internal/auth"
+	"git.neds.sh/matty/entain/api/proto/racing"
+	"git.neds.sh/matty/entain/api/proto/sports"
+	"github.com/google/go-cmp/cmp"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// fakeRacingClient returns fixed races, honouring the visible only, open only
+// and limit filters, and records the calls it receives
+type fakeRacingClient struct {
+	racing.RacingClient
+	races  []*racing.Race
+	err    error
+	calls  int32
+	md     metadata.MD
+	filter *racing.ListRacesRequestFilter
+}
+
+func (c *fakeRacingClient) ListRaces(ctx context.Context, in *racing.ListRacesRequest, opts ...grpc.CallOption) (*racing.ListRacesResponse, error) {
+	atomic.AddInt32(&c.calls, 1)
+	c.md, _ = metadata.FromOutgoingContext(ctx)
+	c.filter = in.GetFilter()
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	var races []*racing.Race
+	for _, race := range c.races {
+		if in.GetFilter().GetVisibleOnly() && !race.GetVisible() {
+			continue
+		}
+		if in.GetFilter().GetOpenOnly() && race.GetStatus() != racing.RaceStatus_OPEN {
+			continue
+		}
+		if limit := in.GetFilter().GetLimit(); limit > 0 && len(races) == int(limit) {
+			break
+		}
+		races = append(races, race)
+	}
+	return &racing.ListRacesResponse{Races: races}, nil
+}
+
+// fakeSportsClient returns fixed events, honouring the visible only and limit
+// filters, and records the calls it receives
+type fakeSportsClient struct {
+	sports.SportsClient
+	events []*sports.Event
+	err    error
+	calls  int32
+	filter *sports.ListEventsRequestFilter
+}
+
+func (c *fakeSportsClient) ListEvents(ctx context.Context, in *sports.ListEventsRequest, opts ...grpc.CallOption) (*sports.ListEventsResponse, error) {
+	atomic.AddInt32(&c.calls, 1)
+	c.filter = in.GetFilter()
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	var events []*sports.Event
+	for _, event := range c.events {
+		if in.GetFilter().GetVisibleOnly() && !event.GetVisible() {
+			continue
+		}
+		if limit := in.GetFilter().GetLimit(); limit > 0 && len(events) == int(limit) {
+			break
+		}
+		events = append(events, event)
+	}
+	return &sports.ListEventsResponse{Events: events}, nil
+}
+
+// feed is the decoded response, with each item summarised as "type id"
+type feed struct {
+	items    []string
+	warnings []Warning
+}
+
+func decodeFeed(t *testing.T, body []byte) feed {
+	t.Helper()
+
+	var resp Response
+	if err := json.Unmarshal(body, &resp); err != nil {
+		t.Fatalf("failed to decode response %s: %v", body, err)
+	}
+
+	f := feed{items: []string{}, warnings: resp.Warnings}
+	for _, item := range resp.Items {
+		var id int64
+		switch item.Type {
+		case TypeRace:
+			var race racing.Race
+			if err := protojson.Unmarshal(item.Race, &race); err != nil {
+				t.Fatalf("failed to decode race %s: %v", item.Race, err)
+			}
+			id = race.Id
+		case TypeEvent:
+			var event sports.Event
+			if err := protojson.Unmarshal(item.Event, &event); err != nil {
+				t.Fatalf("failed to decode event %s: %v", item.Event, err)
+			}
+			id = event.Id
+		}
+		f.items = append(f.items, fmt.Sprintf("%s %d", item.Type, id))
+	}
+	return f
+}
+
+func TestHandler(t *testing.T) {
+	now := time.Now()
+	at := func(d time.Duration) *timestamppb.Timestamp { return timestamppb.New(now.Add(d)) }
+
+	races := []*racing.Race{
+		{Id: 1, Status: racing.RaceStatus_CLOSED, Visible: true, AdvertisedStartTime: at(-5 * time.Minute)},
+		{Id: 2, Visible: true, AdvertisedStartTime: at(10 * time.Minute)},
+		{Id: 3, Visible: true, AdvertisedStartTime: at(30 * time.Minute)},
+	}
+	events := []*sports.Event{
+		{Id: 11, Visible: true, AdvertisedStartTime: at(5 * time.Minute)},
+		{Id: 12, Visible: true, AdvertisedStartTime: at(10 * time.Minute)},
+		{Id: 13, Visible: true, AdvertisedStartTime: at(20 * time.Minute)},
+	}
+	unavailable := status.Error(codes.Unavailable, "connection refused")
+
+	tests := []struct {
+		name           string
+		query          string
+		racingErr      error
+		sportsErr      error
+		wantStatus     int
+		want           feed
+		wantRaceCalls  int32
+		wantEventCalls int32
+		wantSportTypes []string
+		wantLimit      int32
+	}{
+		{
+			name:           "merges open races and events by start time",
+			wantStatus:     http.StatusOK,
+			want:           feed{items: []string{"event 11", "race 2", "event 12", "event 13", "race 3"}},
+			wantRaceCalls:  1,
+			wantEventCalls: 1,
+			wantLimit:      DefaultLimit,
+		},
+		{
+			name:           "limit",
+			query:          "limit=2",
+			wantStatus:     http.StatusOK,
+			want:           feed{items: []string{"event 11", "race 2"}},
+			wantRaceCalls:  1,
+			wantEventCalls: 1,
+			wantLimit:      2,
+		},
+		{
+			name:          "racing only",
+			query:         "categories=Racing",
+			wantStatus:    http.StatusOK,
+			want:          feed{items: []string{"race 2", "race 3"}},
+			wantRaceCalls: 1,
+			wantLimit:     DefaultLimit,
+		},
+		{
+			name:           "sport types only",
+			query:          "categories=football,tennis&categories=football",
+			wantStatus:     http.StatusOK,
+			want:           feed{items: []string{"event 11", "event 12", "event 13"}},
+			wantEventCalls: 1,
+			wantSportTypes: []string{"football", "tennis"},
+			wantLimit:      DefaultLimit,
+		},
+		{
+			name:       "partial results when a backend is down",
+			sportsErr:  unavailable,
+			wantStatus: http.StatusOK,
+			want: feed{
+				items:    []string{"race 2", "race 3"},
+				warnings: []Warning{{Backend: "sports", Message: "connection refused"}},
+			},
+			wantRaceCalls:  1,
+			wantEventCalls: 1,
+			wantLimit:      DefaultLimit,
+		},
+		{
+			name:           "every backend down",
+			racingErr:      unavailable,
+			sportsErr:      unavailable,
+			wantStatus:     http.StatusServiceUnavailable,
+			wantRaceCalls:  1,
+			wantEventCalls: 1,
+		},
+		{
+			name:          "only requested backend down",
+			query:         "categories=racing",
+			racingErr:     unavailable,
+			wantStatus:    http.StatusServiceUnavailable,
+			wantRaceCalls: 1,
+		},
+		{
+			name:       "invalid limit",
+			query:      "limit=1000",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			racingClient := &fakeRacingClient{races: races, err: tt.racingErr}
+			sportsClient := &fakeSportsClient{events: events, err: tt.sportsErr}
+
+			mux := runtime.NewServeMux()
+			if err := New(racingClient, sportsClient, nil).Register(mux); err != nil {
+				t.Fatalf("Register() failed: %v", err)
+			}
+
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, Path+"?"+tt.query, nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body: %s)", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantStatus == http.StatusOK {
+				got := decodeFeed(t, rec.Body.Bytes())
+				if diff := cmp.Diff(tt.want, got, cmp.AllowUnexported(feed{})); diff != "" {
+					t.Errorf("feed mismatch (-want +got):\n%s", diff)
+				}
+			}
+
+			if racingClient.calls != tt.wantRaceCalls {
+				t.Errorf("ListRaces called %d times, want %d", racingClient.calls, tt.wantRaceCalls)
+			}
+			if sportsClient.calls != tt.wantEventCalls {
+				t.Errorf("ListEvents called %d times, want %d", sportsClient.calls, tt.wantEventCalls)
+			}
+			if diff := cmp.Diff(tt.wantSportTypes, sportsClient.filter.GetSportTypes()); diff != "" {
+				t.Errorf("sport types mismatch (-want +got):\n%s", diff)
+			}
+
+			// Each backend is asked for its first visible open items only
+			if tt.wantStatus == http.StatusOK && racingClient.calls > 0 {
+				if f := racingClient.filter; !f.GetVisibleOnly() || !f.GetOpenOnly() || f.GetLimit() != tt.wantLimit {
+					t.Errorf("ListRaces filter = %v, want visible open races limited to %d", f, tt.wantLimit)
+				}
+			}
+			if tt.wantStatus == http.StatusOK && sportsClient.calls > 0 {
+				if f := sportsClient.filter; !f.GetVisibleOnly() || !f.GetOpenOnly() || f.GetLimit() != tt.wantLimit {
+					t.Errorf("ListEvents filter = %v, want visible open events limited to %d", f, tt.wantLimit)
+				}
+			}
+		})
+	}
+}
+
+func TestHandler_ForwardsIdentity(t *testing.T) {
+	racingClient := &fakeRacingClient{}
+	mux := runtime.NewServeMux(runtime.WithMetadata(auth.Metadata))
+	if err := New(racingClient, &fakeSportsClient{}, nil).Register(mux); err != nil {
+		t.Fatalf("Register() failed: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, Path+"?categories=racing", nil)
+	req = req.WithContext(auth.NewContext(req.Context(), auth.Identity{Subject: "user-1", Roles: []string{"trader"}}))
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d (body: %s)", rec.Code, http.StatusOK, rec.Body)
+	}
+	if got := racingClient.md.Get(auth.MetadataSubject); len(got) != 1 || got[0] != "user-1" {
+		t.Errorf("%s metadata = %v, want [user-1]", auth.MetadataSubject, got)
+	}
+	if got := racingClient.md.Get(auth.MetadataRoles); len(got) != 1 || got[0] != "trader" {
+		t.Errorf("%s metadata = %v, want [trader]", auth.MetadataRoles, got)
+	}
+}
+
+func TestHandler_HiddenItemsStayOut(t *testing.T) {
+	now := time.Now()
+	at := func(d time.Duration) *timestamppb.Timestamp { return timestamppb.New(now.Add(d)) }
+	racingClient := &fakeRacingClient{races: []*racing.Race{{Id: 1, AdvertisedStartTime: at(time.Minute)}, {Id: 2, Visible: true, AdvertisedStartTime: at(2 * time.Minute)}}}
+	sportsClient := &fakeSportsClient{events: []*sports.Event{{Id: 11, AdvertisedStartTime: at(time.Minute)}, {Id: 12, Visible: true, AdvertisedStartTime: at(3 * time.Minute)}}}
+	mux := runtime.NewServeMux(runtime.WithMetadata(auth.Metadata))
+	if err := New(racingClient, sportsClient, nil).Register(mux); err != nil {
+		t.Fatalf("Register() failed: %v", err)
+	}
+
+	// Traders are listed hidden items by the backends, unless asked not to
+	req := httptest.NewRequest(http.MethodGet, Path, nil)
+	req = req.WithContext(auth.NewContext(req.Context(), auth.Identity{Subject: "user-1", Roles: []string{"trader"}}))
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d (body: %s)", rec.Code, http.StatusOK, rec.Body)
+	}
+	got := decodeFeed(t, rec.Body.Bytes())
+	if diff := cmp.Diff([]string{"race 2", "event 12"}, got.items); diff != "" {
+		t.Errorf("feed items mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestHandler_AlwaysJSON(t *testing.T) {
+	mux := runtime.NewServeMux(runtime.WithMarshalerOption("application/x-protobuf", &runtime.ProtoMarshaller{}))
+	racingClient := &fakeRacingClient{races: []*racing.Race{{Id: 2, Name: "race 2", Visible: true, AdvertisedStartTime: timestamppb.Now()}}}
+	if err := New(racingClient, &fakeSportsClient{}, nil).Register(mux); err != nil {
+		t.Fatalf("Register() failed: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, Path+"?categories=racing", nil)
+	req.Header.Set("Accept", "application/x-protobuf")
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d (body: %s)", rec.Code, http.StatusOK, rec.Body)
+	}
+	if got := rec.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", got)
+	}
+	want := feed{items: []string{"race 2"}}
+	if diff := cmp.Diff(want, decodeFeed(t, rec.Body.Bytes()), cmp.AllowUnexported(feed{})); diff != "" {
+		t.Errorf("feed mismatch (-want +got):\n%s", diff)
+	}
+}
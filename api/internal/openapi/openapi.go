@@ -0,0 +1,42 @@
+package openapi
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+// The document is generated from the api copies of the protos, so it describes
+// the routes the gateway serves. TestSpecUpToDate fails when it is stale.
+//go:generate protoc -I ../../proto --openapiv2_out . --openapiv2_opt allow_merge=true,merge_file_name=api,disable_default_errors=true,openapi_configuration=openapi.yaml racing/racing.proto sports/sports.proto --experimental_allow_proto3_optional
+
+//go:embed api.swagger.json
+var spec []byte
+
+//go:embed ui
+var ui embed.FS
+
+// Spec returns the OpenAPI v2 document of the racing and sports routes.
+func Spec() []byte {
+	return spec
+}
+
+// SpecHandler serves the OpenAPI document.
+func SpecHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(spec)
+	})
+}
+
+// DocsHandler serves Swagger UI under prefix, which must end in a slash. The
+// Swagger UI assets are vendored in ui, so the page reads the document from
+// /openapi.json and needs no network access.
+func DocsHandler(prefix string) http.Handler {
+	files, err := fs.Sub(ui, "ui")
+	if err != nil {
+		// ui is embedded, so it is always there
+		panic(err)
+	}
+	return http.StripPrefix(prefix, http.FileServer(http.FS(files)))
+}
@@ -0,0 +1,132 @@
+package openapi
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// generatorParameter must match the --openapiv2_opt of the go:generate
+// directive in openapi.go.
+const generatorParameter = "allow_merge=true,merge_file_name=api,disable_default_errors=true,openapi_configuration=openapi.yaml"
+
+// TestSpecUpToDate runs protoc-gen-openapiv2 on the api protos, parsed from
+// source so that comments are included, and compares its output with the
+// embedded document.
+func TestSpecUpToDate(t *testing.T) {
+	files := []string{"racing/racing.proto", "sports/sports.proto"}
+
+	parser := protoparse.Parser{
+		ImportPaths:           []string{"../../proto"},
+		IncludeSourceCodeInfo: true,
+	}
+	parsed, err := parser.ParseFiles(files...)
+	if err != nil {
+		t.Fatalf("failed to parse protos: %v", err)
+	}
+
+	req := &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: files,
+		Parameter:      proto.String(generatorParameter),
+	}
+	seen := map[string]bool{}
+	for _, fd := range parsed {
+		req.ProtoFile = appendWithDependencies(req.ProtoFile, fd, seen)
+	}
+
+	plugin := filepath.Join(t.TempDir(), "protoc-gen-openapiv2")
+	build := exec.Command("go", "build", "-o", plugin, "github.com/grpc-ecosystem/grpc-gateway/v2/protoc-gen-openapiv2")
+	if out, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("failed to build protoc-gen-openapiv2: %v\n%s", err, out)
+	}
+
+	in, err := proto.Marshal(req)
+	if err != nil {
+		t.Fatalf("failed to marshal request: %v", err)
+	}
+	var stdout, stderr bytes.Buffer
+	run := exec.Command(plugin)
+	run.Stdin = bytes.NewReader(in)
+	run.Stdout = &stdout
+	run.Stderr = &stderr
+	if err := run.Run(); err != nil {
+		t.Fatalf("protoc-gen-openapiv2 failed: %v\n%s", err, stderr.String())
+	}
+
+	var resp pluginpb.CodeGeneratorResponse
+	if err := proto.Unmarshal(stdout.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if resp.Error != nil {
+		t.Fatalf("protoc-gen-openapiv2 failed: %s", resp.GetError())
+	}
+	if len(resp.File) != 1 || resp.File[0].GetName() != "api.swagger.json" {
+		t.Fatalf("unexpected generated files %v", resp.File)
+	}
+
+	if resp.File[0].GetContent() != string(Spec()) {
+		t.Errorf("api.swagger.json is out of date with the protos, run go generate ./... in api")
+	}
+}
+
+// appendWithDependencies appends fd to files after its dependencies, as
+// protoc orders them.
+func appendWithDependencies(files []*descriptorpb.FileDescriptorProto, fd *desc.FileDescriptor, seen map[string]bool) []*descriptorpb.FileDescriptorProto {
+	if seen[fd.GetName()] {
+		return files
+	}
+	seen[fd.GetName()] = true
+	for _, dep := range fd.GetDependencies() {
+		files = appendWithDependencies(files, dep, seen)
+	}
+	return append(files, fd.AsFileDescriptorProto())
+}
+
+func TestHandlers(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("/openapi.json", SpecHandler())
+	mux.Handle("/docs/", DocsHandler("/docs/"))
+
+	tests := []struct {
+		name            string
+		path            string
+		wantStatus      int
+		wantContentType string
+		wantBody        string
+	}{
+		{name: "spec", path: "/openapi.json", wantStatus: http.StatusOK, wantContentType: "application/json", wantBody: `"swagger": "2.0"`},
+		{name: "docs", path: "/docs/", wantStatus: http.StatusOK, wantContentType: "text/html; charset=utf-8", wantBody: `<script src="swagger-ui-bundle.js"`},
+		{name: "docs initializer", path: "/docs/swagger-initializer.js", wantStatus: http.StatusOK, wantContentType: "text/javascript; charset=utf-8", wantBody: `url: "../openapi.json"`},
+		{name: "swagger ui script", path: "/docs/swagger-ui-bundle.js", wantStatus: http.StatusOK, wantContentType: "text/javascript; charset=utf-8", wantBody: "SwaggerUIBundle"},
+		{name: "swagger ui styles", path: "/docs/swagger-ui.css", wantStatus: http.StatusOK, wantContentType: "text/css; charset=utf-8", wantBody: ".swagger-ui"},
+		{name: "docs without slash", path: "/docs", wantStatus: http.StatusMovedPermanently},
+		{name: "missing file", path: "/docs/docs.js", wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := rec.Header().Get("Content-Type"); tt.wantContentType != "" && got != tt.wantContentType {
+				t.Errorf("Content-Type = %q, want %q", got, tt.wantContentType)
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("body does not contain %q:\n%s", tt.wantBody, rec.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,11 @@
+// Loads the gateway's document into Swagger UI. Both are served by the
+// gateway, so the page needs no network access beyond it.
+window.onload = function () {
+  window.ui = SwaggerUIBundle({
+    url: "../openapi.json",
+    dom_id: "#swagger-ui",
+    deepLinking: true,
+    presets: [SwaggerUIBundle.presets.apis],
+    layout: "BaseLayout",
+  });
+};
@@ -0,0 +1,157 @@
+package problem
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"git.neds.sh/matty/entain/api/internal/requestid"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ContentType is the media type of problem details, from RFC 7807.
+const ContentType = "application/problem+json"
+
+// TypePrefix starts the type URI of every problem. The rest is the gRPC code
+// in kebab case, e.g. "/problems/invalid-argument".
+const TypePrefix = "/problems/"
+
+// TypeMethodNotAllowed is the type of requests to a known path with the wrong
+// method, which have no gRPC code of their own.
+const TypeMethodNotAllowed = TypePrefix + "method-not-allowed"
+
+// Problem is an RFC 7807 problem details object.
+type Problem struct {
+	// Type identifies the kind of problem; it is stable and safe to match on.
+	Type string `json:"type"`
+	// Title is a short summary of the type, the same for every occurrence.
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	// Detail explains this occurrence of the problem.
+	Detail string `json:"detail,omitempty"`
+	// Instance is the path of the request that failed.
+	Instance  string `json:"instance,omitempty"`
+	RequestID string `json:"requestId,omitempty"`
+	// InvalidParams lists the request fields that failed validation.
+	InvalidParams []InvalidParam `json:"invalid-params,omitempty"`
+}
+
+// InvalidParam is a request field that failed validation. Name is the field
+// path in the request message, e.g. "filter.meeting_ids".
+type InvalidParam struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// Handler renders gateway errors as problem details.
+type Handler struct {
+	logger *zap.Logger
+}
+
+// New creates a problem details handler.
+func New(logger *zap.Logger) *Handler {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &Handler{logger: logger}
+}
+
+// HandleError is a runtime.ErrorHandlerFunc. The status code follows
+// runtime.HTTPStatusFromCode, and google.rpc.BadRequest details become
+// invalid-params. Messages of server errors are logged rather than returned,
+// as they may reveal internals.
+func (h *Handler) HandleError(ctx context.Context, _ *runtime.ServeMux, _ runtime.Marshaler, w http.ResponseWriter, r *http.Request, err error) {
+	st := status.Convert(err)
+	httpStatus := runtime.HTTPStatusFromCode(st.Code())
+
+	p := Problem{
+		Type:     TypePrefix + kebab(st.Code().String()),
+		Title:    title(st.Code().String()),
+		Status:   httpStatus,
+		Detail:   st.Message(),
+		Instance: r.URL.Path,
+	}
+
+	for _, detail := range st.Details() {
+		if badRequest, ok := detail.(*errdetails.BadRequest); ok {
+			for _, violation := range badRequest.GetFieldViolations() {
+				p.InvalidParams = append(p.InvalidParams, InvalidParam{
+					Name:   violation.GetField(),
+					Reason: violation.GetDescription(),
+				})
+			}
+		}
+	}
+
+	if httpStatus >= http.StatusInternalServerError && st.Code() != codes.Unavailable && st.Code() != codes.DeadlineExceeded {
+		h.logger.Error("Request failed",
+			zap.String("request_id", requestid.FromContext(ctx)),
+			zap.String("path", r.URL.Path),
+			zap.Stringer("code", st.Code()),
+			zap.String("message", st.Message()),
+		)
+		p.Detail = "The request could not be completed, quote the request ID when reporting it."
+	}
+
+	h.write(ctx, w, p)
+}
+
+// HandleRoutingError is a runtime.RoutingErrorHandlerFunc for requests that
+// match no route.
+func (h *Handler) HandleRoutingError(ctx context.Context, mux *runtime.ServeMux, m runtime.Marshaler, w http.ResponseWriter, r *http.Request, httpStatus int) {
+	switch httpStatus {
+	case http.StatusNotFound:
+		h.HandleError(ctx, mux, m, w, r, status.Errorf(codes.NotFound, "no route for %s %s", r.Method, r.URL.Path))
+	case http.StatusMethodNotAllowed:
+		h.write(ctx, w, Problem{
+			Type:     TypeMethodNotAllowed,
+			Title:    "Method not allowed",
+			Status:   httpStatus,
+			Detail:   r.Method + " is not supported on " + r.URL.Path,
+			Instance: r.URL.Path,
+		})
+	default:
+		h.HandleError(ctx, mux, m, w, r, status.Error(codes.InvalidArgument, http.StatusText(httpStatus)))
+	}
+}
+
+func (h *Handler) write(ctx context.Context, w http.ResponseWriter, p Problem) {
+	p.RequestID = requestid.FromContext(ctx)
+
+	w.Header().Del("Trailer")
+	w.Header().Del("Transfer-Encoding")
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(p.Status)
+	if err := json.NewEncoder(w).Encode(p); err != nil {
+		h.logger.Warn("Failed to write problem details", zap.Error(err))
+	}
+}
+
+// kebab turns a gRPC code name such as "InvalidArgument" into "invalid-argument".
+func kebab(name string) string {
+	return strings.ReplaceAll(strings.ToLower(spaced(name)), " ", "-")
+}
+
+// title turns a gRPC code name such as "InvalidArgument" into "Invalid argument".
+func title(name string) string {
+	s := strings.ToLower(spaced(name))
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// spaced separates the words of a CamelCase name with spaces.
+func spaced(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		if i > 0 && unicode.IsUpper(r) {
+			b.WriteByte(' ')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,175 @@
+package problem
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"git.neds.sh/matty/entain/api/internal/requestid"
+	"git.neds.sh/matty/entain/api/proto/racing"
+	"github.com/google/go-cmp/cmp"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// failingRacingServer fails every GetRace call with err
+type failingRacingServer struct {
+	racing.UnimplementedRacingServer
+	err error
+}
+
+func (s *failingRacingServer) GetRace(context.Context, *racing.GetRaceRequest) (*racing.GetRaceResponse, error) {
+	return nil, s.err
+}
+
+// validationError is a backend validation failure with a field violation
+func validationError(t *testing.T) error {
+	t.Helper()
+
+	st, err := status.New(codes.InvalidArgument, "validation failed: meeting_ids validation failed: duplicate meeting ID: 1").
+		WithDetails(&errdetails.BadRequest{FieldViolations: []*errdetails.BadRequest_FieldViolation{
+			{Field: "filter.meeting_ids", Description: "duplicate meeting ID: 1"},
+		}})
+	if err != nil {
+		t.Fatalf("failed to attach details: %v", err)
+	}
+	return st.Err()
+}
+
+func TestHandler(t *testing.T) {
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		backendErr  error
+		wantStatus  int
+		wantProblem Problem
+	}{
+		{
+			name:       "validation failure",
+			method:     http.MethodGet,
+			path:       "/v1/races/1",
+			backendErr: validationError(t),
+			wantStatus: http.StatusBadRequest,
+			wantProblem: Problem{
+				Type:          "/problems/invalid-argument",
+				Title:         "Invalid argument",
+				Status:        http.StatusBadRequest,
+				Detail:        "validation failed: meeting_ids validation failed: duplicate meeting ID: 1",
+				Instance:      "/v1/races/1",
+				RequestID:     "req-1",
+				InvalidParams: []InvalidParam{{Name: "filter.meeting_ids", Reason: "duplicate meeting ID: 1"}},
+			},
+		},
+		{
+			name:       "not found",
+			method:     http.MethodGet,
+			path:       "/v1/races/7",
+			backendErr: status.Error(codes.NotFound, "failed to retrieve race: race with ID 7 not found"),
+			wantStatus: http.StatusNotFound,
+			wantProblem: Problem{
+				Type:      "/problems/not-found",
+				Title:     "Not found",
+				Status:    http.StatusNotFound,
+				Detail:    "failed to retrieve race: race with ID 7 not found",
+				Instance:  "/v1/races/7",
+				RequestID: "req-1",
+			},
+		},
+		{
+			name:       "unavailable backend",
+			method:     http.MethodGet,
+			path:       "/v1/races/1",
+			backendErr: status.Error(codes.Unavailable, "racing backend is unavailable: circuit breaker is open"),
+			wantStatus: http.StatusServiceUnavailable,
+			wantProblem: Problem{
+				Type:      "/problems/unavailable",
+				Title:     "Unavailable",
+				Status:    http.StatusServiceUnavailable,
+				Detail:    "racing backend is unavailable: circuit breaker is open",
+				Instance:  "/v1/races/1",
+				RequestID: "req-1",
+			},
+		},
+		{
+			name:       "internal errors are not revealed",
+			method:     http.MethodGet,
+			path:       "/v1/races/1",
+			backendErr: status.Error(codes.Unknown, "failed to retrieve race: database is locked"),
+			wantStatus: http.StatusInternalServerError,
+			wantProblem: Problem{
+				Type:      "/problems/unknown",
+				Title:     "Unknown",
+				Status:    http.StatusInternalServerError,
+				Detail:    "The request could not be completed, quote the request ID when reporting it.",
+				Instance:  "/v1/races/1",
+				RequestID: "req-1",
+			},
+		},
+		{
+			name:       "no route",
+			method:     http.MethodGet,
+			path:       "/v1/horses",
+			wantStatus: http.StatusNotFound,
+			wantProblem: Problem{
+				Type:      "/problems/not-found",
+				Title:     "Not found",
+				Status:    http.StatusNotFound,
+				Detail:    "no route for GET /v1/horses",
+				Instance:  "/v1/horses",
+				RequestID: "req-1",
+			},
+		},
+		{
+			name:       "wrong method",
+			method:     http.MethodDelete,
+			path:       "/v1/races/1",
+			wantStatus: http.StatusMethodNotAllowed,
+			wantProblem: Problem{
+				Type:      TypeMethodNotAllowed,
+				Title:     "Method not allowed",
+				Status:    http.StatusMethodNotAllowed,
+				Detail:    "DELETE is not supported on /v1/races/1",
+				Instance:  "/v1/races/1",
+				RequestID: "req-1",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := New(nil)
+			mux := runtime.NewServeMux(
+				runtime.WithErrorHandler(h.HandleError),
+				runtime.WithRoutingErrorHandler(h.HandleRoutingError),
+			)
+			if err := racing.RegisterRacingHandlerServer(context.Background(), mux, &failingRacingServer{err: tt.backendErr}); err != nil {
+				t.Fatalf("failed to register racing handler: %v", err)
+			}
+
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set(requestid.Header, "req-1")
+			rec := httptest.NewRecorder()
+			requestid.Middleware(mux).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := rec.Header().Get("Content-Type"); got != ContentType {
+				t.Errorf("Content-Type = %q, want %q", got, ContentType)
+			}
+
+			var got Problem
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatalf("failed to decode problem %s: %v", rec.Body, err)
+			}
+			if diff := cmp.Diff(tt.wantProblem, got); diff != "" {
+				t.Errorf("problem mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
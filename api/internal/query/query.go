@@ -0,0 +1,92 @@
+package query
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/utilities"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// FilterField is the request field whose fields may be given unqualified in
+// the query string.
+const FilterField = "filter"
+
+// Parser populates requests from query parameters like the gateway's default
+// parser, except that fields of a request's filter may be named directly:
+// GET /v1/races?meeting_ids=1&sort_field=NAME is read as if it were
+// ?filter.meeting_ids=1&filter.sort_field=NAME. Qualified names keep working,
+// and enum values are accepted by name or number.
+//
+// Unlike the default parser, the key[value] syntax for map fields is not
+// supported; no request has map fields.
+type Parser struct{}
+
+// Parse populates msg from values, skipping parameters that start with a path
+// in filter, which are bound from the URL path or body instead.
+func (Parser) Parse(msg proto.Message, values url.Values, filter *utilities.DoubleArray) error {
+	desc := msg.ProtoReflect().Descriptor()
+	for key, vals := range values {
+		fieldPath := qualify(desc, strings.Split(key, "."))
+		if filter.HasCommonPrefix(fieldPath) {
+			continue
+		}
+
+		if field := lookup(desc, fieldPath); field != nil && !field.IsList() && len(vals) > 1 {
+			return fmt.Errorf("too many values for field %q: %s", field.Name(), strings.Join(vals, ", "))
+		}
+
+		// Repeated fields are appended to, so each value is set in turn
+		path := strings.Join(fieldPath, ".")
+		for _, value := range vals {
+			if err := runtime.PopulateFieldFromPath(msg, path, value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// qualify prefixes fieldPath with the filter field when desc has no field of
+// that name but its filter does.
+func qualify(desc protoreflect.MessageDescriptor, fieldPath []string) []string {
+	if findField(desc, fieldPath[0]) != nil {
+		return fieldPath
+	}
+
+	filterField := desc.Fields().ByName(FilterField)
+	if filterField == nil || filterField.Message() == nil || filterField.IsList() {
+		return fieldPath
+	}
+	if findField(filterField.Message(), fieldPath[0]) == nil {
+		return fieldPath
+	}
+
+	return append([]string{FilterField}, fieldPath...)
+}
+
+// findField looks a field up by its proto name, then by its JSON name.
+func findField(desc protoreflect.MessageDescriptor, name string) protoreflect.FieldDescriptor {
+	if field := desc.Fields().ByName(protoreflect.Name(name)); field != nil {
+		return field
+	}
+	return desc.Fields().ByJSONName(name)
+}
+
+// lookup returns the field at fieldPath, or nil if there is none.
+func lookup(desc protoreflect.MessageDescriptor, fieldPath []string) protoreflect.FieldDescriptor {
+	var field protoreflect.FieldDescriptor
+	for _, name := range fieldPath {
+		if desc == nil {
+			return nil
+		}
+		if field = findField(desc, name); field == nil {
+			return nil
+		}
+		desc = field.Message()
+	}
+	return field
+}
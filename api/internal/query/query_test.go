@@ -0,0 +1,152 @@
+package query
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"git.neds.sh/matty/entain/api/proto/racing"
+	"git.neds.sh/matty/entain/api/proto/sports"
+	"github.com/google/go-cmp/cmp"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/protobuf/testing/protocmp"
+)
+
+// recordingRacingServer records the ListRaces requests it receives
+type recordingRacingServer struct {
+	racing.UnimplementedRacingServer
+	got *racing.ListRacesRequest
+}
+
+func (s *recordingRacingServer) ListRaces(_ context.Context, in *racing.ListRacesRequest) (*racing.ListRacesResponse, error) {
+	s.got = in
+	return &racing.ListRacesResponse{}, nil
+}
+
+// recordingSportsServer records the ListEvents requests it receives
+type recordingSportsServer struct {
+	sports.UnimplementedSportsServer
+	got *sports.ListEventsRequest
+}
+
+func (s *recordingSportsServer) ListEvents(_ context.Context, in *sports.ListEventsRequest) (*sports.ListEventsResponse, error) {
+	s.got = in
+	return &sports.ListEventsResponse{}, nil
+}
+
+// newMux returns a gateway mux using Parser, serving both backends in process
+func newMux(t *testing.T, racingServer racing.RacingServer, sportsServer sports.SportsServer) *runtime.ServeMux {
+	t.Helper()
+
+	mux := runtime.NewServeMux(runtime.SetQueryParameterParser(Parser{}))
+	if err := racing.RegisterRacingHandlerServer(context.Background(), mux, racingServer); err != nil {
+		t.Fatalf("failed to register racing handler: %v", err)
+	}
+	if err := sports.RegisterSportsHandlerServer(context.Background(), mux, sportsServer); err != nil {
+		t.Fatalf("failed to register sports handler: %v", err)
+	}
+	return mux
+}
+
+func TestParser_ListRaces(t *testing.T) {
+	visible := true
+	name := racing.SortField_NAME
+	desc := racing.SortDirection_DESC
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		body       string
+		want       *racing.ListRacesRequest
+		wantStatus int
+	}{
+		{
+			name:       "unqualified filters",
+			method:     http.MethodGet,
+			target:     "/v1/races?meeting_ids=1&meeting_ids=2&visible_only=true&sort_field=NAME&sort_direction=DESC",
+			want:       &racing.ListRacesRequest{Filter: &racing.ListRacesRequestFilter{MeetingIds: []int64{1, 2}, VisibleOnly: &visible, SortField: &name, SortDirection: &desc}},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "qualified and JSON names",
+			method:     http.MethodGet,
+			target:     "/v1/races?filter.meetingIds=3&sortDirection=1",
+			want:       &racing.ListRacesRequest{Filter: &racing.ListRacesRequestFilter{MeetingIds: []int64{3}, SortDirection: &desc}},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "no filters",
+			method:     http.MethodGet,
+			target:     "/v1/races",
+			want:       &racing.ListRacesRequest{},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "unknown parameters are ignored",
+			method:     http.MethodGet,
+			target:     "/v1/races?utm_source=newsletter",
+			want:       &racing.ListRacesRequest{},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "unknown enum value",
+			method:     http.MethodGet,
+			target:     "/v1/races?sort_field=DISTANCE",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "repeated singular field",
+			method:     http.MethodGet,
+			target:     "/v1/races?visible_only=true&visible_only=false",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "POST route is kept",
+			method:     http.MethodPost,
+			target:     "/v1/list-races",
+			body:       `{"filter": {"meetingIds": ["5"], "sortField": "NAME"}}`,
+			want:       &racing.ListRacesRequest{Filter: &racing.ListRacesRequestFilter{MeetingIds: []int64{5}, SortField: &name}},
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &recordingRacingServer{}
+			mux := newMux(t, server, &recordingSportsServer{})
+
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body)))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body: %s)", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if diff := cmp.Diff(tt.want, server.got, protocmp.Transform()); diff != "" {
+				t.Errorf("ListRaces request mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParser_ListEvents(t *testing.T) {
+	server := &recordingSportsServer{}
+	mux := newMux(t, &recordingRacingServer{}, server)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/events?sport_types=football&sport_types=tennis&sort_field=SPORT_TYPE", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d (body: %s)", rec.Code, http.StatusOK, rec.Body)
+	}
+
+	want := &sports.ListEventsRequest{Filter: &sports.ListEventsRequestFilter{
+		SportTypes: []string{"football", "tennis"},
+		SortField:  sports.SortField_SPORT_TYPE.Enum(),
+	}}
+	if diff := cmp.Diff(want, server.got, protocmp.Transform()); diff != "" {
+		t.Errorf("ListEvents request mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,202 @@
+package ratelimit
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"git.neds.sh/matty/entain/api/internal/auth"
+	"git.neds.sh/matty/entain/api/internal/config"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// APIKeyHeader carries the API key of clients with their own quota.
+const APIKeyHeader = "X-API-Key"
+
+// route is a limit bound to a method and path pattern.
+type route struct {
+	name     string
+	method   string
+	segments []string
+	limit    Limit
+}
+
+// Limiter enforces per-client, per-route token bucket limits.
+type Limiter struct {
+	store             Store
+	defaultLimit      Limit
+	routes            []route
+	apiKeys           map[string]bool
+	trustForwardedFor bool
+	logger            *zap.Logger
+	now               func() time.Time
+}
+
+// New creates a limiter from cfg. A nil store means an in-memory store.
+func New(cfg config.RateLimitConfig, store Store, logger *zap.Logger) *Limiter {
+	if store == nil {
+		store = NewMemoryStore()
+	}
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	l := &Limiter{
+		store:             store,
+		defaultLimit:      Limit{Rate: cfg.Default.RequestsPerSecond, Burst: cfg.Default.Burst},
+		apiKeys:           make(map[string]bool, len(cfg.APIKeys)),
+		trustForwardedFor: cfg.TrustForwardedFor,
+		logger:            logger,
+		now:               time.Now,
+	}
+
+	for name, limit := range cfg.Routes {
+		fields := strings.Fields(name)
+		if len(fields) != 2 {
+			continue
+		}
+		l.routes = append(l.routes, route{
+			name:     name,
+			method:   strings.ToUpper(fields[0]),
+			segments: strings.Split(strings.Trim(fields[1], "/"), "/"),
+			limit:    Limit{Rate: limit.RequestsPerSecond, Burst: limit.Burst},
+		})
+	}
+
+	// Literal paths win over patterns so that, e.g., "/v1/races/next" can be
+	// limited separately from "/v1/races/{id}"
+	sort.Slice(l.routes, func(i, j int) bool {
+		wi, wj := wildcards(l.routes[i].segments), wildcards(l.routes[j].segments)
+		if wi != wj {
+			return wi < wj
+		}
+		return l.routes[i].name < l.routes[j].name
+	})
+
+	for _, key := range cfg.APIKeys {
+		l.apiKeys[key] = true
+	}
+
+	return l
+}
+
+// Middleware limits requests before they reach next. Requests over the limit
+// are rejected with 429 through the mux's error handler. Every response
+// carries the X-RateLimit-* headers of the bucket that was charged.
+// It must run after the auth middleware so JWT subjects can be used as keys.
+func (l *Limiter) Middleware(mux *runtime.ServeMux, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		routeName, limit := l.match(r)
+		key := l.clientKey(r) + "|" + routeName
+
+		result, err := l.store.Take(r.Context(), key, limit, l.now())
+		if err != nil {
+			// Failing open keeps the API up when a shared store is unavailable
+			l.logger.Warn("Rate limit store failed, allowing request", zap.Error(err))
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		h := w.Header()
+		h.Set("X-RateLimit-Limit", strconv.Itoa(limit.Burst))
+		h.Set("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		h.Set("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+
+		if !result.Allowed {
+			h.Set("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+			l.logger.Debug("Rate limit exceeded",
+				zap.String("route", routeName),
+				zap.String("key", key),
+			)
+			err := status.Errorf(codes.ResourceExhausted, "rate limit exceeded, retry in %s", result.RetryAfter.Round(time.Millisecond))
+			runtime.HTTPError(r.Context(), mux, &runtime.JSONPb{}, w, r, err)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// match returns the configured route matching r, or the default limit.
+func (l *Limiter) match(r *http.Request) (string, Limit) {
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	for _, rt := range l.routes {
+		if rt.method == r.Method && matchSegments(rt.segments, segments) {
+			return rt.name, rt.limit
+		}
+	}
+	return "default", l.defaultLimit
+}
+
+// matchSegments compares path segments, treating {name} as a wildcard.
+func matchSegments(pattern, path []string) bool {
+	if len(pattern) != len(path) {
+		return false
+	}
+	for i, p := range pattern {
+		if isWildcard(p) {
+			continue
+		}
+		if p != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// isWildcard reports whether a pattern segment is a {name} placeholder.
+func isWildcard(segment string) bool {
+	return strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}")
+}
+
+// wildcards counts the placeholders in a pattern.
+func wildcards(segments []string) int {
+	n := 0
+	for _, s := range segments {
+		if isWildcard(s) {
+			n++
+		}
+	}
+	return n
+}
+
+// clientKey identifies the caller, preferring a verified JWT subject, then a
+// known API key, then the client IP address.
+func (l *Limiter) clientKey(r *http.Request) string {
+	if id, ok := auth.FromContext(r.Context()); ok {
+		return "sub:" + id.Subject
+	}
+	if key := r.Header.Get(APIKeyHeader); key != "" && l.apiKeys[key] {
+		return "key:" + key
+	}
+	return "ip:" + l.clientIP(r)
+}
+
+// clientIP returns the address of the caller.
+func (l *Limiter) clientIP(r *http.Request) string {
+	if l.trustForwardedFor {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			if ip := strings.TrimSpace(strings.Split(forwarded, ",")[0]); ip != "" {
+				return ip
+			}
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// ceilSeconds rounds d up to whole seconds, as used by the rate limit headers.
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
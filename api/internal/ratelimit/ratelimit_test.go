@@ -0,0 +1,247 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"git.neds.sh/matty/entain/api/internal/auth"
+	"git.neds.sh/matty/entain/api/internal/config"
+	"github.com/google/go-cmp/cmp"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+)
+
+func TestMemoryStore_Take(t *testing.T) {
+	limit := Limit{Rate: 2, Burst: 3}
+	start := time.Unix(1700000000, 0)
+
+	steps := []struct {
+		name  string
+		after time.Duration
+		want  Result
+	}{
+		{"first request starts with a full bucket", 0, Result{Allowed: true, Remaining: 2, Reset: 500 * time.Millisecond}},
+		{"second request", 0, Result{Allowed: true, Remaining: 1, Reset: time.Second}},
+		{"third request empties the bucket", 0, Result{Allowed: true, Remaining: 0, Reset: 1500 * time.Millisecond}},
+		{"fourth request is rejected", 0, Result{Allowed: false, Remaining: 0, RetryAfter: 500 * time.Millisecond, Reset: 1500 * time.Millisecond}},
+		{"half a token later still rejected", 250 * time.Millisecond, Result{Allowed: false, Remaining: 0, RetryAfter: 250 * time.Millisecond, Reset: 1250 * time.Millisecond}},
+		{"a whole token later allowed", 500 * time.Millisecond, Result{Allowed: true, Remaining: 0, Reset: 1250 * time.Millisecond}},
+		{"refill never exceeds burst", time.Hour, Result{Allowed: true, Remaining: 2, Reset: 500 * time.Millisecond}},
+	}
+
+	store := NewMemoryStore()
+	now := start
+	for _, step := range steps {
+		now = now.Add(step.after)
+		got, err := store.Take(context.Background(), "client", limit, now)
+		if err != nil {
+			t.Fatalf("%s: Take() failed: %v", step.name, err)
+		}
+		if diff := cmp.Diff(step.want, got); diff != "" {
+			t.Errorf("%s: Take() mismatch (-want +got):\n%s", step.name, diff)
+		}
+	}
+
+	// Another key has its own bucket
+	got, _ := store.Take(context.Background(), "other", limit, now)
+	if !got.Allowed || got.Remaining != 2 {
+		t.Errorf("Take() for a new key = %+v, want a full bucket", got)
+	}
+}
+
+func TestMemoryStore_EvictsRefilledBuckets(t *testing.T) {
+	store := NewMemoryStore()
+	now := time.Unix(1700000000, 0)
+	limit := Limit{Rate: 1, Burst: 1}
+
+	store.Take(context.Background(), "a", limit, now)
+	store.Take(context.Background(), "b", limit, now.Add(sweepInterval+time.Second))
+
+	if _, ok := store.buckets["a"]; ok {
+		t.Errorf("bucket %q was not evicted after refilling", "a")
+	}
+	if _, ok := store.buckets["b"]; !ok {
+		t.Errorf("bucket %q was evicted while not full", "b")
+	}
+}
+
+// failingStore always returns an error
+type failingStore struct{}
+
+func (failingStore) Take(context.Context, string, Limit, time.Time) (Result, error) {
+	return Result{}, errors.New("store unavailable")
+}
+
+func newTestLimiter(store Store) *Limiter {
+	l := New(config.RateLimitConfig{
+		Enabled: true,
+		Default: config.RateLimit{RequestsPerSecond: 1, Burst: 2},
+		Routes: map[string]config.RateLimit{
+			"POST /v1/list-races":  {RequestsPerSecond: 1, Burst: 1},
+			"GET /v1/races/{id}":   {RequestsPerSecond: 1, Burst: 3},
+			"GET /v1/races/latest": {RequestsPerSecond: 1, Burst: 4},
+		},
+		APIKeys: []string{"partner-key"},
+	}, store, nil)
+
+	now := time.Unix(1700000000, 0)
+	l.now = func() time.Time { return now }
+	return l
+}
+
+// serve sends a request through the limiter and returns the recorded response
+func serve(t *testing.T, l *Limiter, req *http.Request) *httptest.ResponseRecorder {
+	t.Helper()
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	rec := httptest.NewRecorder()
+	l.Middleware(runtime.NewServeMux(), ok).ServeHTTP(rec, req)
+	return rec
+}
+
+func TestLimiter_Middleware(t *testing.T) {
+	l := newTestLimiter(nil)
+
+	req := func() *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/v1/list-races", nil)
+		r.RemoteAddr = "192.0.2.1:1234"
+		return r
+	}
+
+	rec := serve(t, l, req())
+	if rec.Code != http.StatusOK {
+		t.Fatalf("first request status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	wantHeaders := map[string]string{
+		"X-Ratelimit-Limit":     "1",
+		"X-Ratelimit-Remaining": "0",
+		"X-Ratelimit-Reset":     "1",
+		"Retry-After":           "",
+	}
+	for name, want := range wantHeaders {
+		if got := rec.Header().Get(name); got != want {
+			t.Errorf("first request %s = %q, want %q", name, got, want)
+		}
+	}
+
+	rec = serve(t, l, req())
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("second request status = %d, want %d", rec.Code, http.StatusTooManyRequests)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "1" {
+		t.Errorf("second request Retry-After = %q, want %q", got, "1")
+	}
+}
+
+func TestLimiter_Keys(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		setup  func(r *http.Request) *http.Request
+		want   string
+	}{
+		{
+			name:   "client IP for anonymous callers",
+			method: http.MethodPost,
+			path:   "/v1/list-races",
+			want:   "ip:192.0.2.1|POST /v1/list-races",
+		},
+		{
+			name:   "JWT subject wins over API key",
+			method: http.MethodPost,
+			path:   "/v1/list-races",
+			setup: func(r *http.Request) *http.Request {
+				r.Header.Set(APIKeyHeader, "partner-key")
+				return r.WithContext(auth.NewContext(r.Context(), auth.Identity{Subject: "user-1"}))
+			},
+			want: "sub:user-1|POST /v1/list-races",
+		},
+		{
+			name:   "known API key",
+			method: http.MethodPost,
+			path:   "/v1/list-events",
+			setup: func(r *http.Request) *http.Request {
+				r.Header.Set(APIKeyHeader, "partner-key")
+				return r
+			},
+			want: "key:partner-key|default",
+		},
+		{
+			name:   "unknown API key falls back to IP",
+			method: http.MethodPost,
+			path:   "/v1/list-events",
+			setup: func(r *http.Request) *http.Request {
+				r.Header.Set(APIKeyHeader, "made-up")
+				return r
+			},
+			want: "ip:192.0.2.1|default",
+		},
+		{
+			name:   "forwarded for is ignored by default",
+			method: http.MethodGet,
+			path:   "/v1/races/7",
+			setup: func(r *http.Request) *http.Request {
+				r.Header.Set("X-Forwarded-For", "203.0.113.9")
+				return r
+			},
+			want: "ip:192.0.2.1|GET /v1/races/{id}",
+		},
+		{
+			name:   "literal route wins over pattern",
+			method: http.MethodGet,
+			path:   "/v1/races/latest",
+			want:   "ip:192.0.2.1|GET /v1/races/latest",
+		},
+		{
+			name:   "method must match",
+			method: http.MethodGet,
+			path:   "/v1/list-races",
+			want:   "ip:192.0.2.1|default",
+		},
+	}
+
+	l := newTestLimiter(nil)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.path, nil)
+			r.RemoteAddr = "192.0.2.1:1234"
+			if tt.setup != nil {
+				r = tt.setup(r)
+			}
+
+			routeName, _ := l.match(r)
+			if got := l.clientKey(r) + "|" + routeName; got != tt.want {
+				t.Errorf("key = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLimiter_TrustForwardedFor(t *testing.T) {
+	l := New(config.RateLimitConfig{TrustForwardedFor: true}, nil, nil)
+
+	r := httptest.NewRequest(http.MethodGet, "/v1/races/7", nil)
+	r.Header.Set("X-Forwarded-For", "203.0.113.9, 10.0.0.1")
+
+	if got := l.clientKey(r); got != "ip:203.0.113.9" {
+		t.Errorf("clientKey() = %q, want %q", got, "ip:203.0.113.9")
+	}
+}
+
+func TestLimiter_StoreFailureAllowsRequest(t *testing.T) {
+	l := newTestLimiter(failingStore{})
+
+	for i := 0; i < 3; i++ {
+		rec := serve(t, l, httptest.NewRequest(http.MethodPost, "/v1/list-races", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("request %d status = %d, want %d", i, rec.Code, http.StatusOK)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Limit describes a token bucket: it holds at most Burst tokens and is
+// refilled at Rate tokens per second. Each request takes one token.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Result is the outcome of taking a token.
+type Result struct {
+	// Allowed reports whether a token was available.
+	Allowed bool
+	// Remaining is the number of whole tokens left in the bucket.
+	Remaining int
+	// RetryAfter is how long until a token is available. Zero when Allowed.
+	RetryAfter time.Duration
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+}
+
+// Store keeps the token buckets. The in-memory store is used by default;
+// implementations backed by a shared database let several gateway replicas
+// enforce a single limit. Take must be atomic per key.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error)
+}
+
+// bucket is the state of one token bucket.
+type bucket struct {
+	tokens float64
+	last   time.Time
+	// full is when the bucket will be full again, used for eviction.
+	full time.Time
+}
+
+// MemoryStore is a Store local to this process.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// sweepInterval is how often buckets that have refilled are evicted.
+const sweepInterval = time.Minute
+
+// NewMemoryStore returns an empty in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*bucket)}
+}
+
+// Take implements Store.
+func (s *MemoryStore) Take(_ context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(now)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), last: now}
+		s.buckets[key] = b
+	}
+
+	result := take(b, limit, now)
+	b.full = now.Add(result.Reset)
+	return result, nil
+}
+
+// sweep evicts buckets that are full again; a full bucket behaves exactly
+// like a missing one.
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+
+	for key, b := range s.buckets {
+		if !now.Before(b.full) {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+// take refills b up to now and removes one token if available.
+func take(b *bucket, limit Limit, now time.Time) Result {
+	burst := float64(limit.Burst)
+
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(burst, b.tokens+elapsed*limit.Rate)
+		b.last = now
+	}
+
+	result := Result{}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((1 - b.tokens) / limit.Rate)
+	}
+
+	result.Remaining = int(b.tokens)
+	result.Reset = seconds((burst - b.tokens) / limit.Rate)
+	return result
+}
+
+// seconds converts fractional seconds to a duration.
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,48 @@
+package requestid
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name     string
+		incoming string
+		wantKept bool
+	}{
+		{name: "no id", incoming: ""},
+		{name: "client id", incoming: "req-42.edge:7", wantKept: true},
+		{name: "unsafe characters", incoming: "req\r\nSet-Cookie: x"},
+		{name: "too long", incoming: strings.Repeat("a", maxLength+1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var seen string
+			handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seen = FromContext(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/v1/races/1", nil)
+			if tt.incoming != "" {
+				req.Header.Set(Header, tt.incoming)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			got := rec.Header().Get(Header)
+			if got != seen {
+				t.Errorf("response header %q differs from context %q", got, seen)
+			}
+			if tt.wantKept && got != tt.incoming {
+				t.Errorf("request ID = %q, want %q", got, tt.incoming)
+			}
+			if !tt.wantKept && (got == tt.incoming || len(got) != 32) {
+				t.Errorf("request ID = %q, want a generated one", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,271 @@
+package tlsutil
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"git.neds.sh/matty/entain/api/proto/racing"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+)
+
+// testCA is an in-memory certificate authority used to issue test certificates
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+// newTestCA creates a self-signed CA
+func newTestCA(t *testing.T, name string) *testCA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("newTestCA() failed to generate key: %v", err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("newTestCA() failed to create certificate: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+
+	return &testCA{
+		cert: cert,
+		key:  key,
+		pem:  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	}
+}
+
+// issue signs a leaf certificate for localhost and returns the PEM encoded pair
+func (ca *testCA) issue(t *testing.T, usage x509.ExtKeyUsage) (certPEM, keyPEM []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("issue() failed to generate key: %v", err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("issue() failed to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("issue() failed to marshal key: %v", err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+// serverTLS returns a server config presenting a certificate issued by ca.
+// When clientCA is not nil, client certificates signed by it are required.
+func (ca *testCA) serverTLS(t *testing.T, clientCA *testCA) *tls.Config {
+	t.Helper()
+
+	certPEM, keyPEM := ca.issue(t, x509.ExtKeyUsageServerAuth)
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatalf("serverTLS() failed: %v", err)
+	}
+
+	cfg := &tls.Config{Certificates: []tls.Certificate{cert}}
+	if clientCA != nil {
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+		cfg.ClientCAs = x509.NewCertPool()
+		cfg.ClientCAs.AddCert(clientCA.cert)
+	}
+	return cfg
+}
+
+// writeFile writes data to dir/name and stamps it with the given modification time
+func writeFile(t *testing.T, dir, name string, data []byte, modTime time.Time) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("writeFile(%s) failed: %v", name, err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatalf("writeFile(%s) failed to set times: %v", name, err)
+	}
+	return path
+}
+
+// callRacing starts a TLS gRPC server with an unimplemented racing service and
+// calls it through the reloader's client config. Transport failures surface as
+// codes.Unavailable; a working connection yields codes.Unimplemented.
+func callRacing(t *testing.T, serverCfg *tls.Config, r *Reloader) codes.Code {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("callRacing() failed to listen: %v", err)
+	}
+
+	server := grpc.NewServer(grpc.Creds(credentials.NewTLS(serverCfg)))
+	racing.RegisterRacingServer(server, &racing.UnimplementedRacingServer{})
+	go server.Serve(lis)
+	defer server.Stop()
+
+	conn, err := grpc.Dial(lis.Addr().String(),
+		grpc.WithTransportCredentials(credentials.NewTLS(r.ClientConfig("localhost"))))
+	if err != nil {
+		t.Fatalf("callRacing() failed to dial: %v", err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err = racing.NewRacingClient(conn).GetRace(ctx, &racing.GetRaceRequest{Id: 1})
+	return status.Code(err)
+}
+
+func TestReloader_ClientConfig(t *testing.T) {
+	ca := newTestCA(t, "backend-ca")
+	otherCA := newTestCA(t, "other-ca")
+	dir := t.TempDir()
+	now := time.Now()
+
+	caFile := writeFile(t, dir, "ca.pem", ca.pem, now)
+	clientCert, clientKey := ca.issue(t, x509.ExtKeyUsageClientAuth)
+	certFile := writeFile(t, dir, "client.pem", clientCert, now)
+	keyFile := writeFile(t, dir, "client-key.pem", clientKey, now)
+
+	tests := []struct {
+		name      string
+		serverCfg *tls.Config
+		certFile  string
+		keyFile   string
+		want      codes.Code
+	}{
+		{
+			name:      "trusted server",
+			serverCfg: ca.serverTLS(t, nil),
+			want:      codes.Unimplemented,
+		},
+		{
+			name:      "untrusted server",
+			serverCfg: otherCA.serverTLS(t, nil),
+			want:      codes.Unavailable,
+		},
+		{
+			name:      "mutual TLS with client certificate",
+			serverCfg: ca.serverTLS(t, ca),
+			certFile:  certFile,
+			keyFile:   keyFile,
+			want:      codes.Unimplemented,
+		},
+		{
+			name:      "mutual TLS without client certificate",
+			serverCfg: ca.serverTLS(t, ca),
+			want:      codes.Unavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := NewReloader(caFile, tt.certFile, tt.keyFile, nil)
+			if err != nil {
+				t.Fatalf("NewReloader() failed: %v", err)
+			}
+
+			if got := callRacing(t, tt.serverCfg, r); got != tt.want {
+				t.Errorf("GetRace() code = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReloader_Reload(t *testing.T) {
+	oldCA := newTestCA(t, "old-ca")
+	newCA := newTestCA(t, "new-ca")
+	dir := t.TempDir()
+	start := time.Now().Add(-time.Minute)
+
+	caFile := writeFile(t, dir, "ca.pem", oldCA.pem, start)
+	r, err := NewReloader(caFile, "", "", nil)
+	if err != nil {
+		t.Fatalf("NewReloader() failed: %v", err)
+	}
+
+	// The backend has already been rotated to the new CA.
+	serverCfg := newCA.serverTLS(t, nil)
+	if got := callRacing(t, serverCfg, r); got != codes.Unavailable {
+		t.Fatalf("GetRace() before reload code = %v, want %v", got, codes.Unavailable)
+	}
+
+	writeFile(t, dir, "ca.pem", newCA.pem, start.Add(time.Second))
+	reloaded, err := r.Reload()
+	if err != nil || !reloaded {
+		t.Fatalf("Reload() = (%t, %v), want (true, nil)", reloaded, err)
+	}
+
+	if got := callRacing(t, serverCfg, r); got != codes.Unimplemented {
+		t.Errorf("GetRace() after reload code = %v, want %v", got, codes.Unimplemented)
+	}
+
+	reloaded, err = r.Reload()
+	if err != nil || reloaded {
+		t.Errorf("Reload() with unchanged files = (%t, %v), want (false, nil)", reloaded, err)
+	}
+}
+
+func TestNewReloader_Errors(t *testing.T) {
+	dir := t.TempDir()
+	bogus := writeFile(t, dir, "bogus.pem", []byte("not pem"), time.Now())
+
+	tests := []struct {
+		name     string
+		caFile   string
+		certFile string
+		keyFile  string
+	}{
+		{"missing CA bundle", filepath.Join(dir, "missing.pem"), "", ""},
+		{"CA bundle without certificates", bogus, "", ""},
+		{"invalid client key pair", "", bogus, bogus},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewReloader(tt.caFile, tt.certFile, tt.keyFile, nil); err == nil {
+				t.Errorf("NewReloader(%q, %q, %q) returned no error", tt.caFile, tt.certFile, tt.keyFile)
+			}
+		})
+	}
+}
@@ -2,58 +2,253 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
-	"log"
+	"fmt"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 
+	"git.neds.sh/matty/entain/api/internal/auth"
+	"git.neds.sh/matty/entain/api/internal/backend"
+	"git.neds.sh/matty/entain/api/internal/compress"
+	"git.neds.sh/matty/entain/api/internal/config"
+	"git.neds.sh/matty/entain/api/internal/cors"
+	"git.neds.sh/matty/entain/api/internal/export"
+	"git.neds.sh/matty/entain/api/internal/grpcweb"
+	"git.neds.sh/matty/entain/api/internal/httpcache"
+	"git.neds.sh/matty/entain/api/internal/lb"
+	"git.neds.sh/matty/entain/api/internal/logger"
+	"git.neds.sh/matty/entain/api/internal/negotiation"
+	"git.neds.sh/matty/entain/api/internal/nexttogo"
+	"git.neds.sh/matty/entain/api/internal/openapi"
+	"git.neds.sh/matty/entain/api/internal/problem"
+	"git.neds.sh/matty/entain/api/internal/query"
+	"git.neds.sh/matty/entain/api/internal/ratelimit"
+	"git.neds.sh/matty/entain/api/internal/requestid"
+	"git.neds.sh/matty/entain/api/internal/tlsutil"
 	"git.neds.sh/matty/entain/api/proto/racing"
 	"git.neds.sh/matty/entain/api/proto/sports"
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
 	"google.golang.org/grpc"
-)
-
-var (
-	apiEndpoint        = flag.String("api-endpoint", "localhost:8000", "API endpoint")
-	racingGrpcEndpoint = flag.String("racing-grpc-endpoint", "localhost:9000", "Racing gRPC server endpoint")
-	sportsGrpcEndpoint = flag.String("sports-grpc-endpoint", "localhost:9001", "Sports gRPC server endpoint")
+	"google.golang.org/grpc/credentials"
 )
 
 func main() {
-	flag.Parse()
+	cfg, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
+		os.Exit(1)
+	}
+
+	log, err := logger.New(cfg.Logger())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to initialize logger: %v\n", err)
+		os.Exit(1)
+	}
+	defer log.Sync()
+
+	log.Debug("Effective configuration", zap.Object("config", cfg))
 
-	if err := run(); err != nil {
-		log.Printf("failed running api server: %s\n", err)
+	if err := run(cfg, log); err != nil {
+		log.Error("failed running api server", zap.Error(err))
 	}
 }
 
-func run() error {
-	ctx := context.Background()
-	ctx, cancel := context.WithCancel(ctx)
+// Idempotent reads that are retried when a backend is unavailable.
+var (
+	racingReads = []string{
+		"/racing.Racing/ListRaces", "/racing.Racing/GetRace", "/racing.Racing/BatchGetRaces", "/racing.Racing/GetPrices",
+		"/racing.Racing/ListVenues", "/racing.Racing/StreamRaces",
+	}
+	sportsReads = []string{
+		"/sports.Sports/ListEvents", "/sports.Sports/GetEvent", "/sports.Sports/BatchGetEvents", "/sports.Sports/ListMarkets",
+		"/sports.Sports/ListSports", "/sports.Sports/ListCompetitions", "/sports.Sports/ListCompetitionEvents", "/sports.Sports/ListTeams",
+		"/sports.Sports/ListVenues", "/sports.Sports/StreamEvents",
+	}
+)
+
+func run(cfg config.Config, log *zap.Logger) error {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer cancel()
 
-	mux := runtime.NewServeMux()
-	
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+	)
+	backendMetrics := backend.NewMetrics(registry)
+
+	transportOpts, err := backendDialOptions(ctx, cfg.TLS, log)
+	if err != nil {
+		return err
+	}
+
+	racingOpts, err := backend.DialOptions("racing", cfg.Backends, racingReads, backendMetrics, log)
+	if err != nil {
+		return err
+	}
+	sportsOpts, err := backend.DialOptions("sports", cfg.Backends, sportsReads, backendMetrics, log)
+	if err != nil {
+		return err
+	}
+
+	authenticator, err := auth.NewAuthenticator(cfg.Auth, log)
+	if err != nil {
+		return fmt.Errorf("failed to set up authentication: %w", err)
+	}
+	if authenticator.Enabled() {
+		log.Info("Bearer token authentication enabled", zap.String("jwks_file", cfg.Auth.JWKSFile))
+	} else {
+		log.Warn("Authentication disabled, all requests are anonymous")
+	}
+
+	problems := problem.New(log)
+	muxOpts := []runtime.ServeMuxOption{
+		runtime.WithMetadata(auth.Metadata),
+		runtime.WithErrorHandler(problems.HandleError),
+		runtime.WithRoutingErrorHandler(problems.HandleRoutingError),
+		// Filters of the GET list routes may be given without the filter. prefix
+		runtime.SetQueryParameterParser(query.Parser{}),
+	}
+	// Responses are JSON unless the client asks for binary protobuf
+	muxOpts = append(muxOpts, negotiation.MuxOptions()...)
+	cache := httpcache.New(cfg.HTTPCache)
+	// Exports are streamed, instead of held in memory to compute their ETag
+	cache.Stream(export.RacesPath, export.EventsPath, export.CalendarPath)
+	if cfg.HTTPCache.Enabled {
+		muxOpts = append(muxOpts, runtime.WithForwardResponseOption(cache.ForwardResponseOption))
+	} else {
+		log.Warn("HTTP caching headers disabled")
+	}
+	mux := runtime.NewServeMux(muxOpts...)
+
+	racingConn, err := grpc.DialContext(ctx, lb.Target(cfg.Backends.Racing), append(racingOpts, transportOpts...)...)
+	if err != nil {
+		return fmt.Errorf("failed to dial racing backend: %w", err)
+	}
+	defer racingConn.Close()
+
+	sportsConn, err := grpc.DialContext(ctx, lb.Target(cfg.Backends.Sports), append(sportsOpts, transportOpts...)...)
+	if err != nil {
+		return fmt.Errorf("failed to dial sports backend: %w", err)
+	}
+	defer sportsConn.Close()
+
 	// Register racing service
-	if err := racing.RegisterRacingHandlerFromEndpoint(
-		ctx,
-		mux,
-		*racingGrpcEndpoint,
-		[]grpc.DialOption{grpc.WithInsecure()},
-	); err != nil {
+	if err := racing.RegisterRacingHandler(ctx, mux, racingConn); err != nil {
 		return err
 	}
-	
+
 	// Register sports service
-	if err := sports.RegisterSportsHandlerFromEndpoint(
-		ctx,
-		mux,
-		*sportsGrpcEndpoint,
-		[]grpc.DialOption{grpc.WithInsecure()},
-	); err != nil {
+	if err := sports.RegisterSportsHandler(ctx, mux, sportsConn); err != nil {
+		return err
+	}
+
+	// Register the next to go feed, which merges both backends
+	nextToGo := nexttogo.New(racing.NewRacingClient(racingConn), sports.NewSportsClient(sportsConn), log)
+	if err := nextToGo.Register(mux); err != nil {
+		return err
+	}
+
+	// Register the exports, after the services so they win over /v1/races/{id}
+	exports := export.New(racing.NewRacingClient(racingConn), sports.NewSportsClient(sportsConn), log)
+	if err := exports.Register(mux); err != nil {
+		return err
+	}
+
+	// Requests are authenticated first so the rate limiter can key on the subject
+	var handler http.Handler = mux
+	if cfg.GRPCWeb.Enabled {
+		// Browsers may call the backend services directly, bypassing transcoding
+		handler = grpcweb.New(map[string]grpc.ClientConnInterface{
+			"racing.Racing": racingConn,
+			"sports.Sports": sportsConn,
+		}).Middleware(handler)
+	}
+	// ETags are computed per representation, after the media type is chosen
+	handler = negotiation.Middleware(handler)
+	if cfg.HTTPCache.Enabled {
+		handler = cache.Middleware(handler)
+	}
+	if cfg.RateLimit.Enabled {
+		handler = ratelimit.New(cfg.RateLimit, nil, log).Middleware(mux, handler)
+	} else {
+		log.Warn("Rate limiting disabled")
+	}
+	handler = authenticator.Middleware(mux, handler)
+	if cfg.CORS.Enabled {
+		// Preflight requests are answered before authentication and rate limiting
+		handler = cors.New(cfg.CORS).Middleware(handler)
+	}
+	if cfg.Compression.Enabled {
+		handler = compress.New(cfg.Compression).Middleware(handler)
+	} else {
+		log.Warn("Response compression disabled")
+	}
+	// Every response, including rejections, carries a request ID
+	handler = requestid.Middleware(handler)
+
+	// Metrics and documentation bypass authentication and rate limiting
+	root := http.NewServeMux()
+	root.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	root.Handle("/openapi.json", openapi.SpecHandler())
+	root.Handle("/docs/", openapi.DocsHandler("/docs/"))
+	root.Handle("/", handler)
+
+	server := &http.Server{
+		Addr:              cfg.HTTP.Endpoint,
+		Handler:           root,
+		ReadHeaderTimeout: cfg.Timeouts.ReadHeader,
+		ReadTimeout:       cfg.Timeouts.Read,
+		WriteTimeout:      cfg.Timeouts.Write,
+		IdleTimeout:       cfg.Timeouts.Idle,
+	}
+
+	log.Info("API server listening", zap.String("address", cfg.HTTP.Endpoint))
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
 		return err
+	case <-ctx.Done():
+	}
+
+	log.Info("Shutting down API server", zap.Duration("timeout", cfg.Timeouts.Shutdown))
+
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), cfg.Timeouts.Shutdown)
+	defer cancelShutdown()
+
+	return server.Shutdown(shutdownCtx)
+}
+
+// backendDialOptions returns the transport options used to dial the backends.
+// With TLS enabled the certificates are watched for rotation until ctx is done.
+func backendDialOptions(ctx context.Context, cfg config.TLSConfig, log *zap.Logger) ([]grpc.DialOption, error) {
+	if !cfg.Enabled {
+		log.Warn("TLS disabled, dialing backends in plaintext")
+		return []grpc.DialOption{grpc.WithInsecure()}, nil
+	}
+
+	reloader, err := tlsutil.NewReloader(cfg.CAFile, cfg.CertFile, cfg.KeyFile, log)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS certificates: %w", err)
 	}
+	go reloader.Watch(ctx, cfg.ReloadInterval)
 
-	log.Printf("API server listening on: %s\n", *apiEndpoint)
+	log.Info("TLS enabled for backends", zap.Bool("mutual_tls", cfg.CertFile != ""))
 
-	return http.ListenAndServe(*apiEndpoint, mux)
+	creds := credentials.NewTLS(reloader.ClientConfig(cfg.ServerName))
+	return []grpc.DialOption{grpc.WithTransportCredentials(creds)}, nil
 }
@@ -0,0 +1,224 @@
+package db
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+	"google.golang.org/protobuf/proto"
+
+	"git.neds.sh/matty/entain/racing/internal/clock"
+	"git.neds.sh/matty/entain/racing/proto/racing"
+)
+
+// maxCacheEntries bounds the number of distinct filters kept in the cache.
+const maxCacheEntries = 1024
+
+// raceCacheEntry is a cached List result.
+type raceCacheEntry struct {
+	races   []*racing.Race
+	expires time.Time
+}
+
+// CachedRacesRepo is a RacesRepo that serves List from a short-lived
+// read-through cache keyed by the normalised filter. Concurrent misses for the
+// same filter share a single query. Races are cloned on the way out and their
+// status is recomputed, so a race that starts while cached is still reported
+// as closed, or left out of a list of open races. Such a list may then come
+// out shorter than its limit until the entry expires.
+//
+// The underlying repository is deliberately not embedded: every method that
+// writes races must be wrapped here and call Invalidate.
+type CachedRacesRepo struct {
+	repo RacesRepo
+	ttl  time.Duration
+	now  func() time.Time
+
+	group singleflight.Group
+
+	mu         sync.Mutex
+	entries    map[string]raceCacheEntry
+	generation uint64
+}
+
+// NewCachedRacesRepo wraps repo with a cache holding List results for ttl,
+// measured on clk like the status of the races. A nil clk is the system clock.
+func NewCachedRacesRepo(repo RacesRepo, ttl time.Duration, clk clock.Clock) *CachedRacesRepo {
+	if clk == nil {
+		clk = clock.System
+	}
+	return &CachedRacesRepo{
+		repo:    repo,
+		ttl:     ttl,
+		now:     clk.Now,
+		entries: make(map[string]raceCacheEntry),
+	}
+}
+
+// Init initialises the underlying repository.
+func (c *CachedRacesRepo) Init() error {
+	return c.repo.Init()
+}
+
+// List returns the races matching filter, from the cache when possible.
+func (c *CachedRacesRepo) List(filter *racing.ListRacesRequestFilter) ([]*racing.Race, error) {
+	key, err := raceFilterKey(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	generation := c.generation
+	c.mu.Unlock()
+
+	if ok && c.now().Before(entry.expires) {
+		return cloneRaces(entry.races, c.now(), filter.GetOpenOnly()), nil
+	}
+
+	// Callers arriving after an invalidation must not join a query that
+	// started before it, so the generation is part of the flight key.
+	v, err, _ := c.group.Do(fmt.Sprintf("%d/%s", generation, key), func() (interface{}, error) {
+		races, err := c.repo.List(filter)
+		if err != nil {
+			return nil, err
+		}
+
+		c.store(key, generation, races)
+		return races, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return cloneRaces(v.([]*racing.Race), c.now(), filter.GetOpenOnly()), nil
+}
+
+// Stream is not cached; it serves exports, which would fill the cache with
+// races read once.
+func (c *CachedRacesRepo) Stream(filter *racing.ListRacesRequestFilter, send func(*racing.Race) error) error {
+	return c.repo.Stream(filter, send)
+}
+
+// GetByID is not cached; primary key lookups are cheap.
+func (c *CachedRacesRepo) GetByID(id int64) (*racing.Race, error) {
+	return c.repo.GetByID(id)
+}
+
+// GetByIDs is not cached either; it is a single primary key lookup.
+func (c *CachedRacesRepo) GetByIDs(ids []int64) ([]*racing.Race, error) {
+	return c.repo.GetByIDs(ids)
+}
+
+// Import creates or updates races and, unless it is a dry run, invalidates
+// the cache.
+func (c *CachedRacesRepo) Import(races []*racing.ImportRacesRequest, actor string, dryRun bool) (int, int, error) {
+	if !dryRun {
+		defer c.Invalidate()
+	}
+	return c.repo.Import(races, actor, dryRun)
+}
+
+// History is not cached; it is only read by traders.
+func (c *CachedRacesRepo) History(raceID int64, field string) ([]*racing.RaceChange, error) {
+	return c.repo.History(raceID, field)
+}
+
+// Invalidate drops every cached result. Queries already in flight will not
+// populate the cache.
+func (c *CachedRacesRepo) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	c.entries = make(map[string]raceCacheEntry)
+}
+
+// store caches races unless the cache was invalidated since generation.
+func (c *CachedRacesRepo) store(key string, generation uint64, races []*racing.Race) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+
+	now := c.now()
+	if len(c.entries) >= maxCacheEntries {
+		for k, e := range c.entries {
+			if !now.Before(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+		if len(c.entries) >= maxCacheEntries {
+			c.entries = make(map[string]raceCacheEntry)
+		}
+	}
+
+	c.entries[key] = raceCacheEntry{races: races, expires: now.Add(c.ttl)}
+}
+
+// raceFilterKey returns a cache key that is equal for filters selecting the
+// same races in the same order: meeting IDs are sorted and de-duplicated,
+// venue IDs are sorted and defaults are made explicit. Marshalling the whole
+// message keeps the key correct when fields are added to the filter.
+func raceFilterKey(filter *racing.ListRacesRequestFilter) (string, error) {
+	normalised := &racing.ListRacesRequestFilter{}
+	if filter != nil {
+		normalised = proto.Clone(filter).(*racing.ListRacesRequestFilter)
+	}
+
+	ids := normalised.MeetingIds
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	unique := ids[:0]
+	for i, id := range ids {
+		if i == 0 || id != ids[i-1] {
+			unique = append(unique, id)
+		}
+	}
+	normalised.MeetingIds = unique
+
+	// Venue IDs are validated to be unique, so sorting is enough
+	venues := normalised.VenueIds
+	sort.Slice(venues, func(i, j int) bool { return venues[i] < venues[j] })
+
+	if normalised.VisibleOnly == nil {
+		visible := false
+		normalised.VisibleOnly = &visible
+	}
+	if normalised.SortField == nil {
+		field := racing.SortField_ADVERTISED_START_TIME
+		normalised.SortField = &field
+	}
+	if normalised.SortDirection == nil {
+		direction := racing.SortDirection_ASC
+		normalised.SortDirection = &direction
+	}
+
+	key, err := proto.MarshalOptions{Deterministic: true}.Marshal(normalised)
+	if err != nil {
+		return "", fmt.Errorf("failed to build cache key: %w", err)
+	}
+	return string(key), nil
+}
+
+// cloneRaces deep-copies races and recomputes their status at now. With
+// openOnly, the races closed at now are left out.
+func cloneRaces(races []*racing.Race, now time.Time, openOnly bool) []*racing.Race {
+	if races == nil {
+		return nil
+	}
+
+	clones := make([]*racing.Race, 0, len(races))
+	for _, race := range races {
+		clone := proto.Clone(race).(*racing.Race)
+		setRaceStatus(clone, clone.AdvertisedStartTime.AsTime(), now)
+		if openOnly && clone.Status == racing.RaceStatus_CLOSED {
+			continue
+		}
+		clones = append(clones, clone)
+	}
+	return clones
+}
@@ -0,0 +1,303 @@
+package db
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"git.neds.sh/matty/entain/racing/internal/clock"
+	"git.neds.sh/matty/entain/racing/proto/racing"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// countingRacesRepo counts List calls and optionally blocks them until released
+type countingRacesRepo struct {
+	races   []*racing.Race
+	err     error
+	calls   int32
+	release chan struct{}
+}
+
+func (r *countingRacesRepo) Init() error { return nil }
+
+func (r *countingRacesRepo) List(*racing.ListRacesRequestFilter) ([]*racing.Race, error) {
+	atomic.AddInt32(&r.calls, 1)
+	if r.release != nil {
+		<-r.release
+	}
+	return r.races, r.err
+}
+
+func (r *countingRacesRepo) Stream(*racing.ListRacesRequestFilter, func(*racing.Race) error) error {
+	return errors.New("not implemented")
+}
+
+func (r *countingRacesRepo) GetByID(int64) (*racing.Race, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (r *countingRacesRepo) GetByIDs([]int64) ([]*racing.Race, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (r *countingRacesRepo) Import(races []*racing.ImportRacesRequest, actor string, dryRun bool) (int, int, error) {
+	return len(races), 0, nil
+}
+
+func (r *countingRacesRepo) History(int64, string) ([]*racing.RaceChange, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (r *countingRacesRepo) callCount() int {
+	return int(atomic.LoadInt32(&r.calls))
+}
+
+// newTestCache returns a cache with a controllable clock
+func newTestCache(repo RacesRepo, ttl time.Duration) (*CachedRacesRepo, *time.Time) {
+	now := time.Unix(1700000000, 0)
+	c := NewCachedRacesRepo(repo, ttl, clock.Func(func() time.Time { return now }))
+	return c, &now
+}
+
+func TestCachedRacesRepo_List(t *testing.T) {
+	repo := &countingRacesRepo{
+		races: []*racing.Race{{Id: 1, Name: "Race 1", AdvertisedStartTime: timestamppb.New(time.Now().Add(time.Hour))}},
+	}
+	cache, now := newTestCache(repo, time.Second)
+
+	steps := []struct {
+		name      string
+		filter    *racing.ListRacesRequestFilter
+		advance   time.Duration
+		wantCalls int
+	}{
+		{"first call misses", &racing.ListRacesRequestFilter{MeetingIds: []int64{2, 1}}, 0, 1},
+		{"same filter hits", &racing.ListRacesRequestFilter{MeetingIds: []int64{2, 1}}, 0, 1},
+		{"reordered and duplicated meeting IDs hit", &racing.ListRacesRequestFilter{MeetingIds: []int64{1, 2, 2}}, 0, 1},
+		{"explicit defaults hit", &racing.ListRacesRequestFilter{
+			MeetingIds:    []int64{1, 2},
+			VisibleOnly:   boolPtr(false),
+			SortField:     sortFieldPtr(racing.SortField_ADVERTISED_START_TIME),
+			SortDirection: sortDirectionPtr(racing.SortDirection_ASC),
+		}, 0, 1},
+		{"different filter misses", &racing.ListRacesRequestFilter{MeetingIds: []int64{1, 2}, VisibleOnly: boolPtr(true)}, 0, 2},
+		{"nil and empty filters share a key", nil, 0, 3},
+		{"empty filter hits", &racing.ListRacesRequestFilter{}, 0, 3},
+		{"expired entry misses", &racing.ListRacesRequestFilter{}, time.Second, 4},
+		{"venue filter misses", &racing.ListRacesRequestFilter{VenueIds: []int64{3, 1}}, 0, 5},
+		{"reordered venues hit", &racing.ListRacesRequestFilter{VenueIds: []int64{1, 3}}, 0, 5},
+		{"same IDs as meetings miss", &racing.ListRacesRequestFilter{MeetingIds: []int64{1, 3}}, 0, 6},
+	}
+
+	for _, step := range steps {
+		*now = now.Add(step.advance)
+
+		got, err := cache.List(step.filter)
+		if err != nil {
+			t.Fatalf("%s: List() failed: %v", step.name, err)
+		}
+		if diff := cmp.Diff(repo.races, got, protocmp.Transform(), protocmp.IgnoreFields(&racing.Race{}, "status")); diff != "" {
+			t.Errorf("%s: List() mismatch (-want +got):\n%s", step.name, diff)
+		}
+		if calls := repo.callCount(); calls != step.wantCalls {
+			t.Errorf("%s: repository called %d times, want %d", step.name, calls, step.wantCalls)
+		}
+	}
+}
+
+func TestCachedRacesRepo_List_RecomputesStatus(t *testing.T) {
+	repo := &countingRacesRepo{}
+	cache, now := newTestCache(repo, time.Hour)
+	repo.races = []*racing.Race{{
+		Id:                  1,
+		Status:              racing.RaceStatus_OPEN,
+		AdvertisedStartTime: timestamppb.New(now.Add(time.Second)),
+	}}
+
+	// The race is cached as open, then its start time passes
+	steps := []struct {
+		advance time.Duration
+		want    racing.RaceStatus
+	}{
+		{0, racing.RaceStatus_OPEN},
+		{time.Second, racing.RaceStatus_OPEN},
+		{time.Nanosecond, racing.RaceStatus_CLOSED},
+	}
+	for i, step := range steps {
+		*now = now.Add(step.advance)
+
+		got, err := cache.List(nil)
+		if err != nil {
+			t.Fatalf("List() failed: %v", err)
+		}
+		if got[0].Status != step.want {
+			t.Errorf("List() call %d status = %v, want %v", i, got[0].Status, step.want)
+		}
+
+		// Callers must not be able to modify the cached races
+		got[0].Name = "modified"
+	}
+
+	if repo.races[0].Name != "" {
+		t.Errorf("cached race was modified through a returned value")
+	}
+}
+
+func TestCachedRacesRepo_List_OpenOnly(t *testing.T) {
+	repo := &countingRacesRepo{}
+	cache, now := newTestCache(repo, time.Hour)
+	repo.races = []*racing.Race{
+		{Id: 1, AdvertisedStartTime: timestamppb.New(now.Add(time.Second))},
+		{Id: 2, AdvertisedStartTime: timestamppb.New(now.Add(time.Minute))},
+	}
+	open := true
+	filter := &racing.ListRacesRequestFilter{OpenOnly: &open}
+
+	// The first race starts while cached, so it drops out of the open races
+	for i, want := range [][]int64{{1, 2}, {2}} {
+		got, err := cache.List(filter)
+		if err != nil {
+			t.Fatalf("List() failed: %v", err)
+		}
+		var ids []int64
+		for _, race := range got {
+			ids = append(ids, race.Id)
+		}
+		if diff := cmp.Diff(want, ids); diff != "" {
+			t.Errorf("List() call %d race IDs mismatch (-want +got):\n%s", i, diff)
+		}
+		*now = now.Add(2 * time.Second)
+	}
+
+	if repo.callCount() != 1 {
+		t.Errorf("repository called %d times, want 1", repo.callCount())
+	}
+}
+
+func TestCachedRacesRepo_List_ErrorsAreNotCached(t *testing.T) {
+	repo := &countingRacesRepo{err: errors.New("database is locked")}
+	cache, _ := newTestCache(repo, time.Hour)
+
+	for i := 0; i < 2; i++ {
+		if _, err := cache.List(nil); err == nil {
+			t.Fatalf("List() call %d returned no error", i)
+		}
+	}
+	if calls := repo.callCount(); calls != 2 {
+		t.Errorf("repository called %d times, want 2", calls)
+	}
+}
+
+func TestCachedRacesRepo_Invalidate(t *testing.T) {
+	repo := &countingRacesRepo{}
+	cache, _ := newTestCache(repo, time.Hour)
+
+	cache.List(nil)
+	cache.Invalidate()
+	cache.List(nil)
+
+	if calls := repo.callCount(); calls != 2 {
+		t.Errorf("repository called %d times after invalidation, want 2", calls)
+	}
+}
+
+func TestCachedRacesRepo_Import_Invalidates(t *testing.T) {
+	tests := []struct {
+		name      string
+		dryRun    bool
+		wantCalls int
+	}{
+		{name: "import", wantCalls: 2},
+		{name: "dry run", dryRun: true, wantCalls: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &countingRacesRepo{}
+			cache, _ := newTestCache(repo, time.Hour)
+
+			cache.List(nil)
+			if _, _, err := cache.Import([]*racing.ImportRacesRequest{{SourceId: "feed-1"}}, "trader-1", tt.dryRun); err != nil {
+				t.Fatalf("Import() failed: %v", err)
+			}
+			cache.List(nil)
+
+			if calls := repo.callCount(); calls != tt.wantCalls {
+				t.Errorf("repository called %d times after the import, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestCachedRacesRepo_Invalidate_DuringQuery(t *testing.T) {
+	repo := &countingRacesRepo{release: make(chan struct{})}
+	cache, _ := newTestCache(repo, time.Hour)
+
+	done := make(chan struct{})
+	go func() {
+		cache.List(nil)
+		close(done)
+	}()
+
+	waitForCalls(t, repo, 1)
+	cache.Invalidate()
+	close(repo.release)
+	<-done
+
+	// The result read before the invalidation must not have been cached
+	cache.List(nil)
+	if calls := repo.callCount(); calls != 2 {
+		t.Errorf("repository called %d times, want 2", calls)
+	}
+}
+
+func TestCachedRacesRepo_List_CoalescesConcurrentMisses(t *testing.T) {
+	repo := &countingRacesRepo{
+		races:   []*racing.Race{{Id: 1, AdvertisedStartTime: timestamppb.Now()}},
+		release: make(chan struct{}),
+	}
+	cache, _ := newTestCache(repo, time.Hour)
+
+	const callers = 10
+	var wg sync.WaitGroup
+	results := make([][]*racing.Race, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], _ = cache.List(&racing.ListRacesRequestFilter{})
+		}(i)
+	}
+
+	waitForCalls(t, repo, 1)
+	// Give the remaining callers time to join the in-flight query
+	time.Sleep(50 * time.Millisecond)
+	close(repo.release)
+	wg.Wait()
+
+	if calls := repo.callCount(); calls != 1 {
+		t.Errorf("repository called %d times for concurrent misses, want 1", calls)
+	}
+	for i, got := range results {
+		if len(got) != 1 {
+			t.Errorf("caller %d got %d races, want 1", i, len(got))
+		}
+	}
+}
+
+// waitForCalls waits until the repository has been called n times
+func waitForCalls(t *testing.T, repo *countingRacesRepo, n int) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for repo.callCount() < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("repository was called %d times, want %d", repo.callCount(), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -1,30 +1,121 @@
 package db
 
 import (
-	"time"
-
-	"syreclabs.com/go/faker"
+	"database/sql"
 )
 
 func (r *racesRepo) seed() error {
-	statement, err := r.db.Prepare(`CREATE TABLE IF NOT EXISTS races (id INTEGER PRIMARY KEY, meeting_id INTEGER, name TEXT, number INTEGER, visible INTEGER, advertised_start_time DATETIME)`)
-	if err == nil {
-		_, err = statement.Exec()
-	}
-
-	for i := 1; i <= 100; i++ {
-		statement, err = r.db.Prepare(`INSERT OR IGNORE INTO races(id, meeting_id, name, number, visible, advertised_start_time) VALUES (?,?,?,?,?,?)`)
-		if err == nil {
-			_, err = statement.Exec(
-				i,
-				faker.Number().Between(1, 10),
-				faker.Team().Name(),
-				faker.Number().Between(1, 12),
-				faker.Number().Between(0, 1),
-				faker.Time().Between(time.Now().AddDate(0, 0, -1), time.Now().AddDate(0, 0, 2)).Format(time.RFC3339),
-			)
+	// Races are seeded around the time of the repository clock
+	_, err := Seed(r.db, DefaultSeedOptions(r.clock.Now()))
+	return err
+}
+
+// CreateTables creates the tables races are stored in, without the dummy races
+// Init seeds them with, for races to be imported into an empty database.
+func CreateTables(db *sql.DB) error {
+	return createRacesTable(db)
+}
+
+// createRacesTable creates the races table, the venues and meetings tables
+// races reference and the tables of their history, and adds the columns older
+// databases lack.
+func createRacesTable(db *sql.DB) error {
+	if err := seedVenues(db); err != nil {
+		return err
+	}
+	if err := createHistoryTables(db); err != nil {
+		return err
+	}
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS races (id INTEGER PRIMARY KEY, meeting_id INTEGER, name TEXT, number INTEGER, visible INTEGER, advertised_start_time DATETIME, source_id TEXT)`); err != nil {
+		return err
+	}
+
+	// Databases created before races could be imported lack the source ID
+	if err := addColumn(db, "races", "source_id", "TEXT"); err != nil {
+		return err
+	}
+	_, err := db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS races_source_id ON races (source_id)`)
+	return err
+}
+
+// seedVenues creates the venues table and the meetings table linking the
+// seeded meetings to them, and adds the venues.
+func seedVenues(db *sql.DB) error {
+	for _, query := range []string{
+		`CREATE TABLE IF NOT EXISTS venues (id INTEGER PRIMARY KEY, name TEXT UNIQUE, time_zone TEXT, country TEXT, latitude REAL, longitude REAL)`,
+		`CREATE TABLE IF NOT EXISTS meetings (id INTEGER PRIMARY KEY, venue_id INTEGER)`,
+	} {
+		if _, err := db.Exec(query); err != nil {
+			return err
+		}
+	}
+
+	venues := []struct {
+		name, zone, country string
+		latitude, longitude float64
+	}{
+		{"Flemington", "Australia/Melbourne", "AU", -37.7886, 144.9120},
+		{"Randwick", "Australia/Sydney", "AU", -33.9068, 151.2318},
+		{"Eagle Farm", "Australia/Brisbane", "AU", -27.4330, 153.0710},
+		{"Ascot", "Europe/London", "GB", 51.4120, -0.6760},
+		{"Sha Tin", "Asia/Hong_Kong", "HK", 22.4010, 114.2040},
+	}
+	for i, venue := range venues {
+		if _, err := db.Exec(`INSERT OR IGNORE INTO venues(id, name, time_zone, country, latitude, longitude) VALUES (?,?,?,?,?,?)`,
+			i+1, venue.name, venue.zone, venue.country, venue.latitude, venue.longitude); err != nil {
+			return err
+		}
+	}
+
+	// The seeded races are spread over meetings 1 to 10, two at each venue
+	for id := 1; id <= 10; id++ {
+		if _, err := db.Exec(`INSERT OR IGNORE INTO meetings(id, venue_id) VALUES (?,?)`, id, (id-1)%len(venues)+1); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// createPricesTables creates the runners and prices tables.
+func createPricesTables(db *sql.DB) error {
+	for _, query := range []string{
+		`CREATE TABLE IF NOT EXISTS runners (race_id INTEGER, number INTEGER, name TEXT, scratched INTEGER, PRIMARY KEY (race_id, number))`,
+		`CREATE TABLE IF NOT EXISTS prices (id INTEGER PRIMARY KEY AUTOINCREMENT, race_id INTEGER, runner_number INTEGER, win REAL, place REAL, updated_time DATETIME)`,
+	} {
+		if _, err := db.Exec(query); err != nil {
+			return err
 		}
 	}
+	return nil
+}
+
+// addColumn adds a column to an existing table unless it is already there.
+func addColumn(db *sql.DB, table, column, definition string) error {
+	rows, err := db.Query(`PRAGMA table_info(` + table + `)`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid          int
+			name, kind   string
+			notNull, pk  int
+			defaultValue sql.NullString
+		)
+		if err := rows.Scan(&cid, &name, &kind, &notNull, &defaultValue, &pk); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
 
+	_, err = db.Exec(`ALTER TABLE ` + table + ` ADD COLUMN ` + column + ` ` + definition)
 	return err
 }
@@ -0,0 +1,130 @@
+package db
+
+import (
+	"database/sql"
+	"strconv"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"git.neds.sh/matty/entain/racing/proto/racing"
+)
+
+// createHistoryTables creates the tables of the race history. Rows are only
+// ever added to them: triggers reject updates and deletes.
+func createHistoryTables(db *sql.DB) error {
+	for _, query := range []string{
+		`CREATE TABLE IF NOT EXISTS race_changes (id INTEGER PRIMARY KEY AUTOINCREMENT, race_id INTEGER NOT NULL, actor TEXT NOT NULL, type INTEGER NOT NULL, changed_time DATETIME NOT NULL)`,
+		`CREATE INDEX IF NOT EXISTS race_changes_race_id ON race_changes (race_id)`,
+		`CREATE TABLE IF NOT EXISTS race_change_fields (change_id INTEGER NOT NULL REFERENCES race_changes (id), field TEXT NOT NULL, old_value TEXT NOT NULL, new_value TEXT NOT NULL, PRIMARY KEY (change_id, field))`,
+	} {
+		if _, err := db.Exec(query); err != nil {
+			return err
+		}
+	}
+
+	for _, table := range []string{"race_changes", "race_change_fields"} {
+		for _, op := range []string{"UPDATE", "DELETE"} {
+			if _, err := db.Exec(`CREATE TRIGGER IF NOT EXISTS ` + table + `_no_` + op + ` BEFORE ` + op + ` ON ` + table +
+				` BEGIN SELECT RAISE(ABORT, 'race history is append-only'); END`); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// fieldValue is a recorded field of a race and its value as text.
+type fieldValue struct {
+	field, value string
+}
+
+// raceFields returns the recorded fields of a race, in the order of
+// racing.RaceHistoryFields.
+func raceFields(meetingID int64, name string, number int64, visible bool, start time.Time) []fieldValue {
+	return []fieldValue{
+		{"meeting_id", strconv.FormatInt(meetingID, 10)},
+		{"name", name},
+		{"number", strconv.FormatInt(number, 10)},
+		{"visible", strconv.FormatBool(visible)},
+		{"advertised_start_time", start.UTC().Format(time.RFC3339)},
+	}
+}
+
+// diffFields returns the fields whose value differs between before and after,
+// which hold the same fields in the same order. A nil before is a race being
+// created, so every field is returned.
+func diffFields(before, after []fieldValue) []*racing.FieldChange {
+	var changes []*racing.FieldChange
+	for i, field := range after {
+		change := &racing.FieldChange{Field: field.field, After: field.value}
+		if before != nil {
+			if before[i].value == field.value {
+				continue
+			}
+			change.Before = before[i].value
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// recordChange adds a change to the history of a race, unless it changes no
+// field.
+func recordChange(tx *sql.Tx, raceID int64, actor string, changeType racing.ChangeType, changedTime time.Time, fields []*racing.FieldChange) error {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	res, err := tx.Exec(getHistoryQueries()[historyInsertChange], raceID, actor, changeType, changedTime.UTC().Format(time.RFC3339))
+	if err != nil {
+		return err
+	}
+	changeID, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	for _, field := range fields {
+		if _, err := tx.Exec(getHistoryQueries()[historyInsertField], changeID, field.Field, field.Before, field.After); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// History returns the recorded changes to a race, oldest first. With a field,
+// only the changes to that field are returned, with only that field.
+func (r *racesRepo) History(raceID int64, field string) ([]*racing.RaceChange, error) {
+	rows, err := r.db.Query(getHistoryQueries()[historyList], raceID, field, field)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes []*racing.RaceChange
+	for rows.Next() {
+		var (
+			change      racing.RaceChange
+			changedTime time.Time
+			fieldChange racing.FieldChange
+		)
+		if err := rows.Scan(&change.Id, &change.RaceId, &change.Actor, &change.Type, &changedTime,
+			&fieldChange.Field, &fieldChange.Before, &fieldChange.After); err != nil {
+			return nil, err
+		}
+
+		// Rows of the same change are consecutive
+		if n := len(changes); n > 0 && changes[n-1].Id == change.Id {
+			changes[n-1].Fields = append(changes[n-1].Fields, &fieldChange)
+			continue
+		}
+		change.ChangedTime = timestamppb.New(changedTime)
+		change.Fields = []*racing.FieldChange{&fieldChange}
+		changes = append(changes, &change)
+	}
+
+	return changes, rows.Err()
+}
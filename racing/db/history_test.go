@@ -0,0 +1,130 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"git.neds.sh/matty/entain/racing/internal/clock"
+	"git.neds.sh/matty/entain/racing/proto/racing"
+)
+
+func TestRacesRepo_History(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	now := time.Date(2026, 11, 1, 9, 30, 0, 0, time.UTC)
+	repo := NewRacesRepo(db, clock.Func(func() time.Time { return now }))
+
+	start := time.Date(2026, 11, 3, 4, 0, 0, 0, time.UTC)
+	cup := &racing.ImportRacesRequest{SourceId: "feed-7", MeetingId: 2, Name: "Melbourne Cup", Number: 7, Visible: true, AdvertisedStartTime: timestamppb.New(start)}
+
+	// A dry run records nothing
+	if _, _, err := repo.Import([]*racing.ImportRacesRequest{cup}, "trader-1", true); err != nil {
+		t.Fatalf("Import() dry run failed: %v", err)
+	}
+	if _, _, err := repo.Import([]*racing.ImportRacesRequest{cup}, "trader-1", false); err != nil {
+		t.Fatalf("Import() failed: %v", err)
+	}
+
+	// Importing the race unchanged records nothing either
+	if _, _, err := repo.Import([]*racing.ImportRacesRequest{cup}, "trader-1", false); err != nil {
+		t.Fatalf("Import() unchanged failed: %v", err)
+	}
+
+	now = now.Add(time.Hour)
+	cup.AdvertisedStartTime = timestamppb.New(start.Add(15 * time.Minute))
+	cup.Visible = false
+	if _, _, err := repo.Import([]*racing.ImportRacesRequest{cup}, "trader-2", false); err != nil {
+		t.Fatalf("Import() update failed: %v", err)
+	}
+
+	created := &racing.RaceChange{
+		Id:          1,
+		RaceId:      1,
+		Actor:       "trader-1",
+		ChangedTime: timestamppb.New(time.Date(2026, 11, 1, 9, 30, 0, 0, time.UTC)),
+		Type:        racing.ChangeType_CHANGE_CREATED,
+		Fields: []*racing.FieldChange{
+			{Field: "meeting_id", After: "2"},
+			{Field: "name", After: "Melbourne Cup"},
+			{Field: "number", After: "7"},
+			{Field: "visible", After: "true"},
+			{Field: "advertised_start_time", After: "2026-11-03T04:00:00Z"},
+		},
+	}
+	updated := &racing.RaceChange{
+		Id:          2,
+		RaceId:      1,
+		Actor:       "trader-2",
+		ChangedTime: timestamppb.New(now),
+		Type:        racing.ChangeType_CHANGE_UPDATED,
+		Fields: []*racing.FieldChange{
+			{Field: "visible", Before: "true", After: "false"},
+			{Field: "advertised_start_time", Before: "2026-11-03T04:00:00Z", After: "2026-11-03T04:15:00Z"},
+		},
+	}
+
+	tests := []struct {
+		name  string
+		field string
+		want  []*racing.RaceChange
+	}{
+		{name: "every change", want: []*racing.RaceChange{created, updated}},
+		{
+			name:  "start time changes",
+			field: "advertised_start_time",
+			want: []*racing.RaceChange{
+				{Id: 1, RaceId: 1, Actor: "trader-1", ChangedTime: created.ChangedTime, Type: racing.ChangeType_CHANGE_CREATED,
+					Fields: []*racing.FieldChange{created.Fields[4]}},
+				{Id: 2, RaceId: 1, Actor: "trader-2", ChangedTime: updated.ChangedTime, Type: racing.ChangeType_CHANGE_UPDATED,
+					Fields: []*racing.FieldChange{updated.Fields[1]}},
+			},
+		},
+		{name: "unchanged field", field: "name", want: []*racing.RaceChange{
+			{Id: 1, RaceId: 1, Actor: "trader-1", ChangedTime: created.ChangedTime, Type: racing.ChangeType_CHANGE_CREATED,
+				Fields: []*racing.FieldChange{created.Fields[1]}},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := repo.History(1, tt.field)
+			if err != nil {
+				t.Fatalf("History() failed: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got, protocmp.Transform()); diff != "" {
+				t.Errorf("History() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	if got, err := repo.History(2, ""); err != nil || len(got) != 0 {
+		t.Errorf("History() of a race without changes = %v, %v, want none", got, err)
+	}
+}
+
+func TestHistoryTables_AppendOnly(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := NewRacesRepo(db, nil)
+
+	race := &racing.ImportRacesRequest{SourceId: "feed-1", MeetingId: 1, Name: "Race", Number: 1, AdvertisedStartTime: timestamppb.Now()}
+	if _, _, err := repo.Import([]*racing.ImportRacesRequest{race}, "trader-1", false); err != nil {
+		t.Fatalf("Import() failed: %v", err)
+	}
+
+	for _, query := range []string{
+		`UPDATE race_changes SET actor = 'someone else'`,
+		`DELETE FROM race_changes`,
+		`UPDATE race_change_fields SET new_value = ''`,
+		`DELETE FROM race_change_fields`,
+	} {
+		if _, err := db.Exec(query); err == nil {
+			t.Errorf("%s succeeded, want the history to be append-only", query)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"git.neds.sh/matty/entain/racing/proto/racing"
+)
+
+// PricesRepo provides repository access to the runners of races and their
+// fixed odds. Prices are never overwritten: every update is kept, and the
+// latest one is the current price.
+type PricesRepo interface {
+	// Init will initialise our prices repository.
+	Init() error
+
+	// Get will return the runners of a race, ordered by number, with their
+	// current prices and, if history is set, all their prices oldest first.
+	// Races without runners give an empty list.
+	Get(raceID int64, history bool) ([]*racing.RunnerPrices, error)
+
+	// Update will record the prices of runners of a race, set at the given
+	// time, in a single transaction. The runners must exist.
+	Update(raceID int64, prices []*racing.PriceUpdate, at time.Time) error
+}
+
+type pricesRepo struct {
+	db   *sql.DB
+	init sync.Once
+}
+
+// NewPricesRepo creates a new prices repository.
+func NewPricesRepo(db *sql.DB) PricesRepo {
+	return &pricesRepo{db: db}
+}
+
+// Init creates the runners and prices tables. The runners of the dummy races
+// and their opening prices are seeded with the races, by Seed.
+func (r *pricesRepo) Init() error {
+	var err error
+
+	r.init.Do(func() {
+		err = createPricesTables(r.db)
+	})
+
+	return err
+}
+
+// Get retrieves the runners of a race with their current prices, and their
+// price history when asked for.
+func (r *pricesRepo) Get(raceID int64, history bool) ([]*racing.RunnerPrices, error) {
+	rows, err := r.db.Query(getPriceQueries()[pricesCurrent], raceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	runners := []*racing.RunnerPrices{}
+	byNumber := make(map[int64]*racing.RunnerPrices)
+	for rows.Next() {
+		var (
+			runner      racing.RunnerPrices
+			win, place  sql.NullFloat64
+			updatedTime sql.NullTime
+		)
+		if err := rows.Scan(&runner.RunnerNumber, &runner.RunnerName, &runner.Scratched, &win, &place, &updatedTime); err != nil {
+			return nil, err
+		}
+
+		if updatedTime.Valid {
+			runner.Current = &racing.Price{
+				Win:         win.Float64,
+				Place:       place.Float64,
+				UpdatedTime: timestamppb.New(updatedTime.Time),
+			}
+		}
+
+		runners = append(runners, &runner)
+		byNumber[runner.RunnerNumber] = &runner
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if !history || len(runners) == 0 {
+		return runners, nil
+	}
+
+	rows, err = r.db.Query(getPriceQueries()[pricesHistory], raceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			number      int64
+			price       racing.Price
+			updatedTime time.Time
+		)
+		if err := rows.Scan(&number, &price.Win, &price.Place, &updatedTime); err != nil {
+			return nil, err
+		}
+		price.UpdatedTime = timestamppb.New(updatedTime)
+
+		if runner, ok := byNumber[number]; ok {
+			runner.History = append(runner.History, &price)
+		}
+	}
+
+	return runners, rows.Err()
+}
+
+// Update inserts a price for each runner. Either all prices are recorded or
+// none are.
+func (r *pricesRepo) Update(raceID int64, prices []*racing.PriceUpdate, at time.Time) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(getPriceQueries()[pricesInsert])
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	updatedTime := at.UTC().Format(time.RFC3339Nano)
+	for _, price := range prices {
+		if _, err := stmt.Exec(raceID, price.RunnerNumber, price.Win, price.Place, updatedTime); err != nil {
+			return fmt.Errorf("failed to record price of runner %d: %w", price.RunnerNumber, err)
+		}
+	}
+
+	return tx.Commit()
+}
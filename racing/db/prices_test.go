@@ -0,0 +1,140 @@
+package db
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"git.neds.sh/matty/entain/racing/proto/racing"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// setupPricesDB creates an in-memory SQLite database with the runner and
+// price tables, and three runners in race 1, the third scratched
+func setupPricesDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("setupPricesDB() failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	for _, query := range []string{
+		`CREATE TABLE runners (race_id INTEGER, number INTEGER, name TEXT, scratched INTEGER, PRIMARY KEY (race_id, number))`,
+		`CREATE TABLE prices (id INTEGER PRIMARY KEY AUTOINCREMENT, race_id INTEGER, runner_number INTEGER, win REAL, place REAL, updated_time DATETIME)`,
+		`INSERT INTO runners (race_id, number, name, scratched) VALUES (1, 1, 'Phar Lap', 0), (1, 2, 'Black Caviar', 0), (1, 3, 'Winx', 1), (2, 1, 'Makybe Diva', 0)`,
+	} {
+		if _, err := db.Exec(query); err != nil {
+			t.Fatalf("setupPricesDB() failed: %v", err)
+		}
+	}
+
+	return db
+}
+
+func TestPricesRepo_Get(t *testing.T) {
+	repo := NewPricesRepo(setupPricesDB(t))
+
+	first := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+	second := first.Add(90 * time.Second)
+	if err := repo.Update(1, []*racing.PriceUpdate{{RunnerNumber: 1, Win: 3.5, Place: 1.6}, {RunnerNumber: 2, Win: 2.2, Place: 1.2}}, first); err != nil {
+		t.Fatalf("Update() failed: %v", err)
+	}
+	if err := repo.Update(1, []*racing.PriceUpdate{{RunnerNumber: 1, Win: 3.1, Place: 1.5}}, second); err != nil {
+		t.Fatalf("Update() failed: %v", err)
+	}
+	if err := repo.Update(2, []*racing.PriceUpdate{{RunnerNumber: 1, Win: 9, Place: 3}}, second); err != nil {
+		t.Fatalf("Update() failed: %v", err)
+	}
+
+	price := func(win, place float64, at time.Time) *racing.Price {
+		return &racing.Price{Win: win, Place: place, UpdatedTime: timestamppb.New(at)}
+	}
+
+	tests := []struct {
+		name    string
+		raceID  int64
+		history bool
+		want    []*racing.RunnerPrices
+	}{
+		{
+			name:   "current prices",
+			raceID: 1,
+			want: []*racing.RunnerPrices{
+				{RunnerNumber: 1, RunnerName: "Phar Lap", Current: price(3.1, 1.5, second)},
+				{RunnerNumber: 2, RunnerName: "Black Caviar", Current: price(2.2, 1.2, first)},
+				{RunnerNumber: 3, RunnerName: "Winx", Scratched: true},
+			},
+		},
+		{
+			name:    "with history",
+			raceID:  1,
+			history: true,
+			want: []*racing.RunnerPrices{
+				{
+					RunnerNumber: 1,
+					RunnerName:   "Phar Lap",
+					Current:      price(3.1, 1.5, second),
+					History:      []*racing.Price{price(3.5, 1.6, first), price(3.1, 1.5, second)},
+				},
+				{
+					RunnerNumber: 2,
+					RunnerName:   "Black Caviar",
+					Current:      price(2.2, 1.2, first),
+					History:      []*racing.Price{price(2.2, 1.2, first)},
+				},
+				{RunnerNumber: 3, RunnerName: "Winx", Scratched: true},
+			},
+		},
+		{
+			name:   "other race",
+			raceID: 2,
+			want: []*racing.RunnerPrices{
+				{RunnerNumber: 1, RunnerName: "Makybe Diva", Current: price(9, 3, second)},
+			},
+		},
+		{
+			name:   "race without runners",
+			raceID: 3,
+			want:   []*racing.RunnerPrices{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := repo.Get(tt.raceID, tt.history)
+			if err != nil {
+				t.Fatalf("Get(%d, %v) failed: %v", tt.raceID, tt.history, err)
+			}
+			if diff := cmp.Diff(tt.want, got, protocmp.Transform()); diff != "" {
+				t.Errorf("Get(%d, %v) mismatch (-want +got):\n%s", tt.raceID, tt.history, diff)
+			}
+		})
+	}
+}
+
+func TestPricesRepo_Update_IsAtomic(t *testing.T) {
+	db := setupPricesDB(t)
+	repo := NewPricesRepo(db)
+
+	// The second insert is rejected, after the first one succeeded
+	if _, err := db.Exec(`CREATE TRIGGER reject_runner_2 BEFORE INSERT ON prices WHEN NEW.runner_number = 2 BEGIN SELECT RAISE(ABORT, 'rejected'); END`); err != nil {
+		t.Fatalf("failed to create trigger: %v", err)
+	}
+
+	err := repo.Update(1, []*racing.PriceUpdate{{RunnerNumber: 1, Win: 2, Place: 1.5}, {RunnerNumber: 2, Win: 2, Place: 1.5}}, time.Now())
+	if err == nil {
+		t.Fatal("Update() succeeded, want error")
+	}
+
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM prices`).Scan(&count); err != nil {
+		t.Fatalf("failed to count prices: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("%d prices recorded after a failed update, want 0", count)
+	}
+}
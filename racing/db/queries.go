@@ -3,6 +3,10 @@ package db
 const (
 	racesList  = "list"
 	racesGetByID = "getByID"
+	racesGetByIDs = "getByIDs"
+	racesImportUpdate = "importUpdate"
+	racesImportInsert = "importInsert"
+	racesImportGet = "importGet"
 )
 
 func getRaceQueries() map[string]string {
@@ -14,7 +18,9 @@ func getRaceQueries() map[string]string {
 				name, 
 				number, 
 				visible, 
-				advertised_start_time 
+				advertised_start_time, 
+				COALESCE((SELECT venue_id FROM meetings WHERE meetings.id = races.meeting_id), 0), 
+				COALESCE((SELECT v.time_zone FROM meetings m JOIN venues v ON v.id = m.venue_id WHERE m.id = races.meeting_id), '') 
 			FROM races
 		`,
 		racesGetByID: `
@@ -24,9 +30,133 @@ func getRaceQueries() map[string]string {
 				name, 
 				number, 
 				visible, 
-				advertised_start_time 
+				advertised_start_time, 
+				COALESCE((SELECT venue_id FROM meetings WHERE meetings.id = races.meeting_id), 0), 
+				COALESCE((SELECT v.time_zone FROM meetings m JOIN venues v ON v.id = m.venue_id WHERE m.id = races.meeting_id), '') 
 			FROM races 
 			WHERE id = ?
 		`,
+		racesGetByIDs: `
+			SELECT 
+				id, 
+				meeting_id, 
+				name, 
+				number, 
+				visible, 
+				advertised_start_time, 
+				COALESCE((SELECT venue_id FROM meetings WHERE meetings.id = races.meeting_id), 0), 
+				COALESCE((SELECT v.time_zone FROM meetings m JOIN venues v ON v.id = m.venue_id WHERE m.id = races.meeting_id), '') 
+			FROM races 
+			WHERE id IN (%s)
+		`,
+		racesImportUpdate: `
+			UPDATE races 
+			SET meeting_id = ?, name = ?, number = ?, visible = ?, advertised_start_time = ? 
+			WHERE source_id = ?
+		`,
+		racesImportInsert: `
+			INSERT INTO races (source_id, meeting_id, name, number, visible, advertised_start_time) 
+			VALUES (?, ?, ?, ?, ?, ?)
+		`,
+		racesImportGet: `
+			SELECT id, meeting_id, name, number, visible, advertised_start_time 
+			FROM races 
+			WHERE source_id = ?
+		`,
+	}
+}
+
+const (
+	historyInsertChange = "insertChange"
+	historyInsertField  = "insertField"
+	historyList         = "list"
+)
+
+func getHistoryQueries() map[string]string {
+	return map[string]string{
+		historyInsertChange: `
+			INSERT INTO race_changes (race_id, actor, type, changed_time) 
+			VALUES (?, ?, ?, ?)
+		`,
+		historyInsertField: `
+			INSERT INTO race_change_fields (change_id, field, old_value, new_value) 
+			VALUES (?, ?, ?, ?)
+		`,
+		historyList: `
+			SELECT c.id, c.race_id, c.actor, c.type, c.changed_time, f.field, f.old_value, f.new_value 
+			FROM race_changes c 
+			JOIN race_change_fields f ON f.change_id = c.id 
+			WHERE c.race_id = ? AND (? = '' OR f.field = ?) 
+			ORDER BY c.id, f.rowid
+		`,
+	}
+}
+
+const (
+	pricesCurrent = "current"
+	pricesHistory = "history"
+	pricesInsert  = "insert"
+)
+
+func getPriceQueries() map[string]string {
+	return map[string]string{
+		pricesCurrent: `
+			SELECT 
+				r.number, 
+				r.name, 
+				r.scratched, 
+				p.win, 
+				p.place, 
+				p.updated_time 
+			FROM runners r 
+			LEFT JOIN prices p ON p.id = (
+				SELECT MAX(id) 
+				FROM prices 
+				WHERE race_id = r.race_id AND runner_number = r.number
+			) 
+			WHERE r.race_id = ? 
+			ORDER BY r.number
+		`,
+		pricesHistory: `
+			SELECT 
+				runner_number, 
+				win, 
+				place, 
+				updated_time 
+			FROM prices 
+			WHERE race_id = ? 
+			ORDER BY id
+		`,
+		pricesInsert: `
+			INSERT INTO prices (race_id, runner_number, win, place, updated_time) 
+			VALUES (?, ?, ?, ?, ?)
+		`,
+	}
+}
+
+const (
+	venuesList  = "list"
+	venuesZones = "zones"
+)
+
+func getVenueQueries() map[string]string {
+	return map[string]string{
+		venuesList: `
+			SELECT 
+				id, 
+				name, 
+				time_zone, 
+				country, 
+				latitude, 
+				longitude 
+			FROM venues 
+			ORDER BY name
+		`,
+		venuesZones: `
+			SELECT 
+				id, 
+				time_zone 
+			FROM venues
+		`,
 	}
 }
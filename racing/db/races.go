@@ -2,6 +2,7 @@ package db
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"strings"
 	"sync"
@@ -10,9 +11,13 @@ import (
 	"github.com/golang/protobuf/ptypes"
 	_ "github.com/mattn/go-sqlite3"
 
+	"git.neds.sh/matty/entain/racing/internal/clock"
 	"git.neds.sh/matty/entain/racing/proto/racing"
 )
 
+// ErrNotFound is wrapped by the errors returned for races that do not exist.
+var ErrNotFound = errors.New("not found")
+
 // RacesRepo provides repository access to races.
 type RacesRepo interface {
 	// Init will initialise our races repository.
@@ -21,18 +26,42 @@ type RacesRepo interface {
 	// List will return a list of races.
 	List(filter *racing.ListRacesRequestFilter) ([]*racing.Race, error)
 
+	// Stream will call send with each race List would return, as it is read,
+	// so that long lists are never held in memory. It stops at the first
+	// error send returns.
+	Stream(filter *racing.ListRacesRequestFilter, send func(*racing.Race) error) error
+
 	// GetByID will return a single race by its ID.
 	GetByID(id int64) (*racing.Race, error)
+
+	// GetByIDs will return the races with the given IDs, in the order of ids.
+	// IDs that match no race are skipped.
+	GetByIDs(ids []int64) ([]*racing.Race, error)
+
+	// Import will create or update races by their source ID in a single
+	// transaction, returning how many were created and updated. The changes
+	// are recorded in the history of the races, made by actor. With dryRun
+	// the transaction is rolled back.
+	Import(races []*racing.ImportRacesRequest, actor string, dryRun bool) (created, updated int, err error)
+
+	// History will return the recorded changes to a race, oldest first, only
+	// those to field if it is not empty.
+	History(raceID int64, field string) ([]*racing.RaceChange, error)
 }
 
 type racesRepo struct {
-	db   *sql.DB
-	init sync.Once
+	db    *sql.DB
+	clock clock.Clock
+	init  sync.Once
 }
 
-// NewRacesRepo creates a new races repository.
-func NewRacesRepo(db *sql.DB) RacesRepo {
-	return &racesRepo{db: db}
+// NewRacesRepo creates a new races repository. Race status is derived from
+// clk, which defaults to the system clock when nil.
+func NewRacesRepo(db *sql.DB, clk clock.Clock) RacesRepo {
+	if clk == nil {
+		clk = clock.System
+	}
+	return &racesRepo{db: db, clock: clk}
 }
 
 // Init prepares the race repository dummy data.
@@ -48,26 +77,70 @@ func (r *racesRepo) Init() error {
 }
 
 // List retrieves races from the database based on the provided filter.
-// It supports filtering by meeting IDs and visibility status.
-// Results are ordered by advertised_start_time ASC by default, or by the specified sort field and direction.
+// It supports filtering by meeting IDs, venue IDs, visibility and open status,
+// and by races starting today at their venue.
+// Results are ordered by advertised_start_time ASC by default, or by the specified sort field and direction,
+// and cut to the filter's limit.
 func (r *racesRepo) List(filter *racing.ListRacesRequestFilter) ([]*racing.Race, error) {
-	var (
-		err   error
-		query string
-		args  []interface{}
-	)
+	var races []*racing.Race
+	err := r.Stream(filter, func(race *racing.Race) error {
+		races = append(races, race)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
 
-	query = getRaceQueries()[racesList]
+	return races, nil
+}
 
-	query, args = r.applyFilter(query, filter)
+// Stream reads the races List returns one row at a time, passing each race to
+// send as it is read.
+func (r *racesRepo) Stream(filter *racing.ListRacesRequestFilter, send func(*racing.Race) error) error {
+	query, args := r.applyFilter(getRaceQueries()[racesList], filter)
 	query = r.applySorting(query, filter)
 
+	// SQLite has no time zone rules, so races starting today at their venue
+	// are picked as they are read, and the limit is counted there too
+	var zones map[int64]string
+	if filter.GetTodayAtVenue() {
+		var err error
+		if zones, err = venueZones(r.db); err != nil {
+			return err
+		}
+	} else if filter.GetLimit() > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.GetLimit())
+	}
+
 	rows, err := r.db.Query(query, args...)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	defer rows.Close()
+
+	now := r.clock.Now()
+	sent := 0
+	for rows.Next() {
+		race, err := r.scanRace(rows)
+		if err != nil {
+			return err
+		}
+		if zones != nil && !startsToday(race.AdvertisedStartTime.AsTime(), now, zones[race.VenueId]) {
+			continue
+		}
+
+		if err := send(race); err != nil {
+			return err
+		}
+		// A limit of zero is no limit, and sent is never zero here
+		sent++
+		if sent == int(filter.GetLimit()) {
+			break
+		}
 	}
 
-	return r.scanRaces(rows)
+	return rows.Err()
 }
 
 // GetByID retrieves a single race from the database by its ID.
@@ -79,11 +152,12 @@ func (r *racesRepo) GetByID(id int64) (*racing.Race, error) {
 	
 	var race racing.Race
 	var advertisedStart time.Time
+	var zone string
 	
-	err := row.Scan(&race.Id, &race.MeetingId, &race.Name, &race.Number, &race.Visible, &advertisedStart)
+	err := row.Scan(&race.Id, &race.MeetingId, &race.Name, &race.Number, &race.Visible, &advertisedStart, &race.VenueId, &zone)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("race with ID %d not found", id)
+			return nil, fmt.Errorf("race with ID %d %w", id, ErrNotFound)
 		}
 		return nil, err
 	}
@@ -94,13 +168,132 @@ func (r *racesRepo) GetByID(id int64) (*racing.Race, error) {
 	}
 	
 	race.AdvertisedStartTime = ts
+	race.AdvertisedStartLocal = localStartTime(advertisedStart, zone)
 	
 	// Set race status based on advertised start time
-	setRaceStatus(&race, advertisedStart)
+	setRaceStatus(&race, advertisedStart, r.clock.Now())
 	
 	return &race, nil
 }
 
+// GetByIDs retrieves the races with the given IDs with a single query.
+// The races are returned in the order of ids; IDs without a race are skipped.
+func (r *racesRepo) GetByIDs(ids []int64) ([]*racing.Race, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	placeholders := strings.Repeat("?,", len(ids)-1) + "?"
+	query := fmt.Sprintf(getRaceQueries()[racesGetByIDs], placeholders)
+
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	found, err := r.scanRaces(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int64]*racing.Race, len(found))
+	for _, race := range found {
+		byID[race.Id] = race
+	}
+
+	races := make([]*racing.Race, 0, len(found))
+	for _, id := range ids {
+		if race, ok := byID[id]; ok {
+			races = append(races, race)
+		}
+	}
+
+	return races, nil
+}
+
+// Import upserts races by their source ID. Races are updated in place, so they
+// keep their ID, runners and prices. Every race created, and every race
+// updated with different values, gets a change in its history.
+func (r *racesRepo) Import(races []*racing.ImportRacesRequest, actor string, dryRun bool) (created, updated int, err error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+
+	get, err := tx.Prepare(getRaceQueries()[racesImportGet])
+	if err != nil {
+		return 0, 0, err
+	}
+	defer get.Close()
+
+	update, err := tx.Prepare(getRaceQueries()[racesImportUpdate])
+	if err != nil {
+		return 0, 0, err
+	}
+	defer update.Close()
+
+	insert, err := tx.Prepare(getRaceQueries()[racesImportInsert])
+	if err != nil {
+		return 0, 0, err
+	}
+	defer insert.Close()
+
+	now := r.clock.Now()
+	for _, race := range races {
+		startTime := race.AdvertisedStartTime.AsTime()
+		start := startTime.UTC().Format(time.RFC3339)
+		after := raceFields(race.MeetingId, race.Name, race.Number, race.Visible, startTime)
+
+		var (
+			id                int64
+			meetingID, number int64
+			name              string
+			visible           bool
+			advertisedStart   time.Time
+		)
+		err := get.QueryRow(race.SourceId).Scan(&id, &meetingID, &name, &number, &visible, &advertisedStart)
+		switch {
+		case err == nil:
+			if _, err := update.Exec(race.MeetingId, race.Name, race.Number, race.Visible, start, race.SourceId); err != nil {
+				return 0, 0, fmt.Errorf("failed to update race %q: %w", race.SourceId, err)
+			}
+			before := raceFields(meetingID, name, number, visible, advertisedStart)
+			if err := recordChange(tx, id, actor, racing.ChangeType_CHANGE_UPDATED, now, diffFields(before, after)); err != nil {
+				return 0, 0, fmt.Errorf("failed to record update of race %q: %w", race.SourceId, err)
+			}
+			updated++
+			continue
+		case !errors.Is(err, sql.ErrNoRows):
+			return 0, 0, fmt.Errorf("failed to get race %q: %w", race.SourceId, err)
+		}
+
+		res, err := insert.Exec(race.SourceId, race.MeetingId, race.Name, race.Number, race.Visible, start)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to create race %q: %w", race.SourceId, err)
+		}
+		if id, err = res.LastInsertId(); err != nil {
+			return 0, 0, fmt.Errorf("failed to create race %q: %w", race.SourceId, err)
+		}
+		if err := recordChange(tx, id, actor, racing.ChangeType_CHANGE_CREATED, now, diffFields(nil, after)); err != nil {
+			return 0, 0, fmt.Errorf("failed to record creation of race %q: %w", race.SourceId, err)
+		}
+		created++
+	}
+
+	if dryRun {
+		return created, updated, nil
+	}
+
+	return created, updated, tx.Commit()
+}
+
 // applyFilter modifies the base query to include WHERE clauses based on the filter.
 // It returns the modified query string and the corresponding arguments for parameterized queries.
 func (r *racesRepo) applyFilter(query string, filter *racing.ListRacesRequestFilter) (string, []interface{}) {
@@ -122,10 +315,24 @@ func (r *racesRepo) applyFilter(query string, filter *racing.ListRacesRequestFil
 		}
 	}
 
+	if len(filter.VenueIds) > 0 {
+		placeholders := strings.Repeat("?,", len(filter.VenueIds)-1) + "?"
+		clauses = append(clauses, "meeting_id IN (SELECT id FROM meetings WHERE venue_id IN ("+placeholders+"))")
+
+		for _, venueID := range filter.VenueIds {
+			args = append(args, venueID)
+		}
+	}
+
 	if filter.VisibleOnly != nil && *filter.VisibleOnly {
 		clauses = append(clauses, "visible = 1")
 	}
 
+	if filter.GetOpenOnly() {
+		clauses = append(clauses, "advertised_start_time >= ?")
+		args = append(args, openFrom(r.clock.Now()))
+	}
+
 	if len(clauses) > 0 {
 		query += " WHERE " + strings.Join(clauses, " AND ")
 	}
@@ -171,38 +378,58 @@ func (r *racesRepo) scanRaces(
 	var races []*racing.Race
 
 	for rows.Next() {
-		var race racing.Race
-		var advertisedStart time.Time
-
-		if err := rows.Scan(&race.Id, &race.MeetingId, &race.Name, &race.Number, &race.Visible, &advertisedStart); err != nil {
-			if err == sql.ErrNoRows {
-				return nil, nil
-			}
-
-			return nil, err
-		}
-
-		ts, err := ptypes.TimestampProto(advertisedStart)
+		race, err := r.scanRace(rows)
 		if err != nil {
 			return nil, err
 		}
 
-		race.AdvertisedStartTime = ts
+		races = append(races, race)
+	}
 
-		// Set race status based on advertised start time
-		setRaceStatus(&race, advertisedStart)
+	return races, rows.Err()
+}
+
+// scanRace reads the race in the current row of rows.
+func (r *racesRepo) scanRace(rows *sql.Rows) (*racing.Race, error) {
+	var race racing.Race
+	var advertisedStart time.Time
+	var zone string
 
-		races = append(races, &race)
+	if err := rows.Scan(&race.Id, &race.MeetingId, &race.Name, &race.Number, &race.Visible, &advertisedStart, &race.VenueId, &zone); err != nil {
+		return nil, err
 	}
 
-	return races, nil
+	ts, err := ptypes.TimestampProto(advertisedStart)
+	if err != nil {
+		return nil, err
+	}
+
+	race.AdvertisedStartTime = ts
+	race.AdvertisedStartLocal = localStartTime(advertisedStart, zone)
+
+	// Set race status based on advertised start time
+	setRaceStatus(&race, advertisedStart, r.clock.Now())
+
+	return &race, nil
 }
 
 // setRaceStatus sets the race status based on the advertised start time.
-// Races with advertised start time in the past are marked as CLOSED, others as OPEN.
-func setRaceStatus(race *racing.Race, advertisedStart time.Time) {
+// Races with advertised start time before now are marked as CLOSED, others,
+// including races starting exactly now, as OPEN.
+func setRaceStatus(race *racing.Race, advertisedStart, now time.Time) {
 	race.Status = racing.RaceStatus_OPEN
-	if advertisedStart.Before(time.Now()) {
+	if advertisedStart.Before(now) {
 		race.Status = racing.RaceStatus_CLOSED
 	}
 }
+
+// openFrom returns the earliest advertised start time of a race open at now,
+// formatted as start times are stored. They are stored to the second, so now
+// is rounded up to one.
+func openFrom(now time.Time) string {
+	from := now.Truncate(time.Second)
+	if from.Before(now) {
+		from = from.Add(time.Second)
+	}
+	return from.UTC().Format(time.RFC3339)
+}
@@ -2,14 +2,17 @@ package db
 
 import (
 	"database/sql"
+	"errors"
 	"testing"
 	"time"
 
+	"git.neds.sh/matty/entain/racing/internal/clock"
 	"git.neds.sh/matty/entain/racing/proto/racing"
 	"github.com/golang/protobuf/ptypes"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	_ "github.com/mattn/go-sqlite3"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 // setupTestDB creates an in-memory SQLite database for testing
@@ -21,18 +24,9 @@ func setupTestDB(t *testing.T) *sql.DB {
 		t.Fatalf("setupTestDB() failed to open database: %v", err)
 	}
 
-	query := `
-		CREATE TABLE races (
-			id INTEGER PRIMARY KEY,
-			meeting_id INTEGER,
-			name TEXT,
-			number INTEGER,
-			visible INTEGER,
-			advertised_start_time DATETIME
-		)
-	`
-	if _, err := db.Exec(query); err != nil {
-		t.Fatalf("setupTestDB() failed to create table: %v", err)
+	// Races read their venue through their meeting, so venues are seeded too
+	if err := createRacesTable(db); err != nil {
+		t.Fatalf("setupTestDB() failed to create tables: %v", err)
 	}
 
 	return db
@@ -62,6 +56,11 @@ func boolPtr(b bool) *bool {
 	return &b
 }
 
+// int32Ptr returns a pointer to the given int32 value
+func int32Ptr(n int32) *int32 {
+	return &n
+}
+
 // sortFieldPtr returns a pointer to the given SortField value
 func sortFieldPtr(sf racing.SortField) *racing.SortField {
 	return &sf
@@ -158,10 +157,11 @@ func TestRacesRepo_List(t *testing.T) {
 		}
 	}()
 
-	repo := NewRacesRepo(db)
+	// Start times are stored to the second, so the clock is fixed on one
+	now := time.Date(2026, 10, 18, 5, 0, 0, 0, time.UTC)
+	repo := NewRacesRepo(db, clock.Fixed(now))
 
-	// Setup test data
-	now := time.Now()
+	// Setup test data, with a closed race in each meeting
 	testRaces := []struct {
 		id        int
 		meetingID int
@@ -171,9 +171,9 @@ func TestRacesRepo_List(t *testing.T) {
 		startTime time.Time
 	}{
 		{1, 1, "Visible Race 1", 1, true, now.Add(time.Hour)},
-		{2, 1, "Hidden Race 1", 2, false, now.Add(2 * time.Hour)},
+		{2, 1, "Hidden Race 1", 2, false, now.Add(-2 * time.Hour)},
 		{3, 2, "Visible Race 2", 1, true, now.Add(3 * time.Hour)},
-		{4, 2, "Hidden Race 2", 2, false, now.Add(4 * time.Hour)},
+		{4, 2, "Hidden Race 2", 2, false, now.Add(-time.Second)},
 	}
 
 	for _, race := range testRaces {
@@ -219,6 +219,28 @@ func TestRacesRepo_List(t *testing.T) {
 			},
 			wantIDs: []int64{1},
 		},
+		{
+			name: "open only leaves out the races that started",
+			filter: &racing.ListRacesRequestFilter{
+				OpenOnly: boolPtr(true),
+			},
+			wantIDs: []int64{1, 3},
+		},
+		{
+			name: "limit returns the races starting first",
+			filter: &racing.ListRacesRequestFilter{
+				Limit: int32Ptr(2),
+			},
+			wantIDs: []int64{2, 4},
+		},
+		{
+			name: "limit applies after the other filters",
+			filter: &racing.ListRacesRequestFilter{
+				OpenOnly: boolPtr(true),
+				Limit:    int32Ptr(1),
+			},
+			wantIDs: []int64{1},
+		},
 		{
 			name: "non existent meeting id returns empty results",
 			filter: &racing.ListRacesRequestFilter{
@@ -273,7 +295,7 @@ func TestRacesRepo_List(t *testing.T) {
 				gotTime, err := ptypes.Timestamp(race.AdvertisedStartTime)
 				if err == nil {
 					expectedStatus := racing.RaceStatus_OPEN
-					if gotTime.Before(time.Now()) {
+					if gotTime.Before(now) {
 						expectedStatus = racing.RaceStatus_CLOSED
 					}
 					if race.Status != expectedStatus {
@@ -311,7 +333,7 @@ func TestRacesRepo_List_DataIntegrity(t *testing.T) {
 	db := setupTestDB(t)
 	defer db.Close()
 
-	repo := NewRacesRepo(db)
+	repo := NewRacesRepo(db, nil)
 
 	// Insert test race with specific known values
 	testTime := time.Date(2024, 1, 15, 14, 30, 0, 0, time.UTC)
@@ -365,7 +387,7 @@ func TestRacesRepo_List_DatabaseErrors(t *testing.T) {
 	db := setupTestDB(t)
 	db.Close() // Close immediately to cause errors
 
-	repo := NewRacesRepo(db)
+	repo := NewRacesRepo(db, nil)
 
 	_, err := repo.List(&racing.ListRacesRequestFilter{})
 	if err == nil {
@@ -373,11 +395,81 @@ func TestRacesRepo_List_DatabaseErrors(t *testing.T) {
 	}
 }
 
+func TestRacesRepo_GetByIDs(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	now := time.Date(2026, 10, 18, 5, 0, 0, 0, time.UTC)
+	repo := NewRacesRepo(db, clock.Fixed(now))
+
+	startTime := now.Add(time.Hour)
+	for id := 1; id <= 4; id++ {
+		insertTestRace(t, db, id, 1, id, "Race", id != 3, startTime)
+	}
+
+	tests := []struct {
+		name    string
+		ids     []int64
+		wantIDs []int64
+	}{
+		{"request order is preserved", []int64{4, 1, 3}, []int64{4, 1, 3}},
+		{"missing ids are skipped", []int64{9, 2, 7}, []int64{2}},
+		{"no race found", []int64{8}, []int64{}},
+		{"no ids", nil, []int64{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			races, err := repo.GetByIDs(tt.ids)
+			if err != nil {
+				t.Fatalf("GetByIDs(%v) failed: %v", tt.ids, err)
+			}
+
+			gotIDs := []int64{}
+			for _, race := range races {
+				gotIDs = append(gotIDs, race.Id)
+				if race.Status != racing.RaceStatus_OPEN {
+					t.Errorf("GetByIDs(%v): race %d status = %v, want %v", tt.ids, race.Id, race.Status, racing.RaceStatus_OPEN)
+				}
+			}
+			if diff := cmp.Diff(tt.wantIDs, gotIDs); diff != "" {
+				t.Errorf("GetByIDs(%v) IDs mismatch (-want +got):\n%s", tt.ids, diff)
+			}
+		})
+	}
+}
+
+func TestRacesRepo_Stream_StopsOnSendError(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	now := time.Date(2026, 10, 18, 5, 0, 0, 0, time.UTC)
+	for id := 1; id <= 3; id++ {
+		insertTestRace(t, db, id, 1, id, "Race", true, now.Add(time.Duration(id)*time.Hour))
+	}
+
+	var sent []int64
+	errGone := errors.New("client went away")
+	err := NewRacesRepo(db, clock.Fixed(now)).Stream(nil, func(race *racing.Race) error {
+		if len(sent) == 2 {
+			return errGone
+		}
+		sent = append(sent, race.Id)
+		return nil
+	})
+	if !errors.Is(err, errGone) {
+		t.Errorf("Stream() error = %v, want %v", err, errGone)
+	}
+	if diff := cmp.Diff([]int64{1, 2}, sent); diff != "" {
+		t.Errorf("Stream() sent races mismatch (-want +got):\n%s", diff)
+	}
+}
+
 func TestNewRacesRepo(t *testing.T) {
 	db := setupTestDB(t)
 	defer db.Close()
 
-	repo := NewRacesRepo(db)
+	repo := NewRacesRepo(db, nil)
 	if repo == nil {
 		t.Error("NewRacesRepo() returned nil, want non-nil repo")
 	}
@@ -476,7 +568,7 @@ func TestRacesRepo_List_Sorting(t *testing.T) {
 		}
 	}()
 
-	repo := NewRacesRepo(db)
+	repo := NewRacesRepo(db, nil)
 
 	// Setup test data with different start times for sorting
 	now := time.Now()
@@ -580,10 +672,11 @@ func TestRacesRepo_List_StatusLogic(t *testing.T) {
 		}
 	}()
 
-	repo := NewRacesRepo(db)
+	// Start times are stored to the second, so the clock is fixed on one
+	now := time.Date(2026, 10, 18, 5, 0, 0, 0, time.UTC)
+	repo := NewRacesRepo(db, clock.Fixed(now))
 
 	// Setup test data with past and future times
-	now := time.Now()
 	testRaces := []struct {
 		id             int
 		name           string
@@ -594,6 +687,9 @@ func TestRacesRepo_List_StatusLogic(t *testing.T) {
 		{2, "Future Race", now.Add(1 * time.Hour), racing.RaceStatus_OPEN},
 		{3, "Very Past Race", now.Add(-24 * time.Hour), racing.RaceStatus_CLOSED},
 		{4, "Very Future Race", now.Add(24 * time.Hour), racing.RaceStatus_OPEN},
+		{5, "Race Starting Now", now, racing.RaceStatus_OPEN},
+		{6, "Race Started A Second Ago", now.Add(-time.Second), racing.RaceStatus_CLOSED},
+		{7, "Race Starting In A Second", now.Add(time.Second), racing.RaceStatus_OPEN},
 	}
 
 	for _, race := range testRaces {
@@ -628,3 +724,78 @@ func TestRacesRepo_List_StatusLogic(t *testing.T) {
 		}
 	}
 }
+
+func TestRacesRepo_Import(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	repo := NewRacesRepo(db, nil)
+
+	start := time.Date(2026, 11, 3, 4, 0, 0, 0, time.UTC)
+	insertTestRace(t, db, 1, 1, 1, "Seeded race", true, start)
+
+	cup := &racing.ImportRacesRequest{SourceId: "feed-7", MeetingId: 2, Name: "Melbourne Cup", Number: 7, Visible: true, AdvertisedStartTime: timestamppb.New(start)}
+
+	// A dry run reports what would change without saving it
+	created, updated, err := repo.Import([]*racing.ImportRacesRequest{cup}, "trader-1", true)
+	if err != nil {
+		t.Fatalf("Import() dry run failed: %v", err)
+	}
+	if created != 1 || updated != 0 {
+		t.Errorf("Import() dry run = %d created, %d updated, want 1, 0", created, updated)
+	}
+	if races, _ := repo.GetByIDs([]int64{2}); len(races) != 0 {
+		t.Fatalf("Import() dry run saved race %v", races[0])
+	}
+
+	if _, _, err := repo.Import([]*racing.ImportRacesRequest{cup}, "trader-1", false); err != nil {
+		t.Fatalf("Import() failed: %v", err)
+	}
+
+	// Importing the race again updates it in place
+	cup.Name = "Melbourne Cup (Group 1)"
+	cup.Number = 8
+	created, updated, err = repo.Import([]*racing.ImportRacesRequest{cup}, "trader-1", false)
+	if err != nil {
+		t.Fatalf("Import() update failed: %v", err)
+	}
+	if created != 0 || updated != 1 {
+		t.Errorf("Import() update = %d created, %d updated, want 0, 1", created, updated)
+	}
+
+	races, err := repo.List(&racing.ListRacesRequestFilter{MeetingIds: []int64{2}})
+	if err != nil {
+		t.Fatalf("List() failed: %v", err)
+	}
+	if len(races) != 1 {
+		t.Fatalf("List() returned %d imported races, want 1", len(races))
+	}
+	got := races[0]
+	if got.Id != 2 || got.Name != cup.Name || got.Number != 8 || !got.Visible || !got.AdvertisedStartTime.AsTime().Equal(start) {
+		t.Errorf("imported race = %v, want ID 2 with the updated fields of %v", got, cup)
+	}
+}
+
+func TestCreateRacesTable_AddsSourceID(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	// Databases created before imports have no source ID column
+	if _, err := db.Exec(`CREATE TABLE races (id INTEGER PRIMARY KEY, meeting_id INTEGER, name TEXT, number INTEGER, visible INTEGER, advertised_start_time DATETIME)`); err != nil {
+		t.Fatalf("failed to create races: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := createRacesTable(db); err != nil {
+			t.Fatalf("createRacesTable() call %d failed: %v", i, err)
+		}
+	}
+
+	if _, err := db.Exec(`INSERT INTO races (id, source_id) VALUES (1, 'feed-1'), (2, NULL), (3, NULL)`); err != nil {
+		t.Fatalf("failed to insert races: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO races (id, source_id) VALUES (4, 'feed-1')`); err == nil {
+		t.Errorf("inserted a duplicate source ID, want a unique constraint error")
+	}
+}
@@ -0,0 +1,286 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+
+	"syreclabs.com/go/faker"
+
+	"git.neds.sh/matty/entain/racing/proto/racing"
+)
+
+// seedBatchSize is the number of rows inserted by each statement of Seed.
+const seedBatchSize = 500
+
+// SeedOptions control the races generated by Seed.
+type SeedOptions struct {
+	// RandSeed seeds the random generator, so the same options always
+	// generate the same races.
+	RandSeed int64
+	// Meetings is the number of meetings, spread over the seeded venues.
+	Meetings int
+	// RacesPerMeeting is the number of races at each meeting, numbered from
+	// 1 in order of their start time.
+	RacesPerMeeting int
+	// Start and Window bound the advertised start times of the races. The
+	// opening prices of the runners are stamped at Start.
+	Start  time.Time
+	Window time.Duration
+	// VisibleRatio is the share of races that are visible, from 0 to 1.
+	VisibleRatio float64
+	// Reset deletes the existing races, runners and prices first. Otherwise
+	// existing races keep their data, and get no runners or prices.
+	Reset bool
+}
+
+// DefaultSeedOptions returns the options of the dummy races seeded at startup:
+// 100 races starting from a day before now to two days after, half of them
+// visible.
+func DefaultSeedOptions(now time.Time) SeedOptions {
+	return SeedOptions{
+		RandSeed:        1,
+		Meetings:        10,
+		RacesPerMeeting: 10,
+		Start:           now.AddDate(0, 0, -1),
+		Window:          72 * time.Hour,
+		VisibleRatio:    0.5,
+	}
+}
+
+// Validate checks the options for counts and ratios out of range.
+func (o SeedOptions) Validate() error {
+	switch {
+	case o.Meetings < 1:
+		return errors.New("meetings must be at least 1")
+	case o.RacesPerMeeting < 1:
+		return errors.New("races per meeting must be at least 1")
+	case o.Window <= 0:
+		return errors.New("window must be positive")
+	case o.VisibleRatio < 0 || o.VisibleRatio > 1:
+		return errors.New("visible ratio must be from 0 to 1")
+	}
+	return nil
+}
+
+// Seed creates the race tables and generates the races described by opts in a
+// single transaction, each with 6 to 12 runners. One in ten runners is
+// scratched, and the others get an opening price. It returns the number of
+// races generated.
+//
+// Names come from faker, whose package-wide generator Seed reseeds, so Seed
+// must not run alongside other users of faker.
+func Seed(db *sql.DB, opts SeedOptions) (int, error) {
+	if err := opts.Validate(); err != nil {
+		return 0, fmt.Errorf("invalid seed options: %w", err)
+	}
+	if err := createRacesTable(db); err != nil {
+		return 0, err
+	}
+	if err := createPricesTables(db); err != nil {
+		return 0, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if opts.Reset {
+		if err := clearTables(tx, "races", "runners", "prices"); err != nil {
+			return 0, err
+		}
+	}
+
+	rng := rand.New(rand.NewSource(opts.RandSeed))
+	faker.Seed(opts.RandSeed)
+
+	meetings := newBatchInserter(tx, `INSERT OR IGNORE INTO meetings(id, venue_id)`, 2)
+	races := newBatchInserter(tx, `INSERT OR IGNORE INTO races(id, meeting_id, name, number, visible, advertised_start_time)`, 6)
+	runners := newBatchInserter(tx, `INSERT OR IGNORE INTO runners(race_id, number, name, scratched)`, 4)
+	prices := newBatchInserter(tx, `INSERT INTO prices(race_id, runner_number, win, place, updated_time)`, 5)
+
+	var venues int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM venues`).Scan(&venues); err != nil {
+		return 0, err
+	}
+
+	existing, err := existingRaceIDs(tx, opts.Meetings*opts.RacesPerMeeting)
+	if err != nil {
+		return 0, err
+	}
+	pricedAt := opts.Start.UTC().Format(time.RFC3339)
+
+	id := 0
+	starts := make([]time.Time, opts.RacesPerMeeting)
+	for meetingID := 1; meetingID <= opts.Meetings; meetingID++ {
+		if err := meetings.add(meetingID, (meetingID-1)%venues+1); err != nil {
+			return 0, err
+		}
+
+		// Races at a meeting are numbered in the order they start
+		for i := range starts {
+			starts[i] = opts.Start.Add(time.Duration(rng.Int63n(int64(opts.Window))))
+		}
+		sort.Slice(starts, func(i, j int) bool { return starts[i].Before(starts[j]) })
+
+		for number, start := range starts {
+			id++
+
+			// Existing races are skipped only after their values are drawn,
+			// so that the races after them are the same either way
+			name := faker.Team().Name()
+			visible := rng.Float64() < opts.VisibleRatio
+			field := seedRunners(rng)
+			if existing[id] {
+				continue
+			}
+
+			if err := races.add(id, meetingID, name, number+1, visible, start.UTC().Format(time.RFC3339)); err != nil {
+				return 0, err
+			}
+			for i, runner := range field {
+				if err := runners.add(id, i+1, runner.name, runner.scratched); err != nil {
+					return 0, err
+				}
+				if runner.scratched {
+					continue
+				}
+				if err := prices.add(id, i+1, runner.win, placeOdds(runner.win), pricedAt); err != nil {
+					return 0, err
+				}
+			}
+		}
+	}
+
+	for _, batch := range []*batchInserter{meetings, races, runners, prices} {
+		if err := batch.flush(); err != nil {
+			return 0, err
+		}
+	}
+
+	return id, tx.Commit()
+}
+
+// seedRunner is a generated runner and its opening win price.
+type seedRunner struct {
+	name      string
+	scratched bool
+	win       float64
+}
+
+// seedRunners generates the runners of a race, from 6 to 12 of them.
+func seedRunners(rng *rand.Rand) []seedRunner {
+	runners := make([]seedRunner, 6+rng.Intn(7))
+	for i := range runners {
+		runners[i] = seedRunner{
+			name:      faker.Name().FirstName() + " " + faker.Team().Creature(),
+			scratched: rng.Intn(10) == 0,
+			win:       float64(150+rng.Intn(4851)) / 100,
+		}
+	}
+	return runners
+}
+
+// placeOdds returns the place price matching a win price, at a quarter of the
+// odds, rounded to the cent.
+func placeOdds(win float64) float64 {
+	place := math.Max(1+(win-1)/4, racing.MinOdds)
+	return math.Round(place*100) / 100
+}
+
+// existingRaceIDs returns the IDs of the races already in the database, up to
+// maxID.
+func existingRaceIDs(tx *sql.Tx, maxID int) (map[int]bool, error) {
+	rows, err := tx.Query(`SELECT id FROM races WHERE id <= ?`, maxID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make(map[int]bool)
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids[id] = true
+	}
+	return ids, rows.Err()
+}
+
+// clearTables deletes all rows of the tables that exist.
+func clearTables(tx *sql.Tx, tables ...string) error {
+	for _, table := range tables {
+		var n int
+		if err := tx.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`, table).Scan(&n); err != nil {
+			return err
+		}
+		if n == 0 {
+			continue
+		}
+		if _, err := tx.Exec(`DELETE FROM ` + table); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// batchInserter inserts rows with multi-row INSERT statements of up to
+// seedBatchSize rows, reusing the statement prepared for full batches.
+type batchInserter struct {
+	tx      *sql.Tx
+	insert  string
+	columns int
+	full    *sql.Stmt
+	args    []interface{}
+}
+
+func newBatchInserter(tx *sql.Tx, insert string, columns int) *batchInserter {
+	return &batchInserter{tx: tx, insert: insert, columns: columns}
+}
+
+// add queues a row, inserting the batch once it is full.
+func (b *batchInserter) add(values ...interface{}) error {
+	b.args = append(b.args, values...)
+	if len(b.args) < seedBatchSize*b.columns {
+		return nil
+	}
+
+	if b.full == nil {
+		stmt, err := b.tx.Prepare(b.statement(seedBatchSize))
+		if err != nil {
+			return err
+		}
+		b.full = stmt
+	}
+	_, err := b.full.Exec(b.args...)
+	b.args = b.args[:0]
+	return err
+}
+
+// flush inserts the queued rows and releases the prepared statement.
+func (b *batchInserter) flush() error {
+	if b.full != nil {
+		defer b.full.Close()
+	}
+	if len(b.args) == 0 {
+		return nil
+	}
+
+	_, err := b.tx.Exec(b.statement(len(b.args)/b.columns), b.args...)
+	b.args = b.args[:0]
+	return err
+}
+
+// statement returns the INSERT statement for rows rows.
+func (b *batchInserter) statement(rows int) string {
+	row := "(" + strings.Repeat("?,", b.columns-1) + "?)"
+	return b.insert + " VALUES " + strings.Repeat(row+",", rows-1) + row
+}
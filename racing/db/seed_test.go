@@ -0,0 +1,261 @@
+package db
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"git.neds.sh/matty/entain/racing/proto/racing"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// openSeedDB opens an empty in-memory SQLite database
+func openSeedDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestSeed(t *testing.T) {
+	start := time.Date(2026, 11, 3, 0, 0, 0, 0, time.UTC)
+	opts := SeedOptions{
+		RandSeed:        42,
+		Meetings:        3,
+		RacesPerMeeting: 4,
+		Start:           start,
+		Window:          12 * time.Hour,
+		VisibleRatio:    0.5,
+	}
+
+	seeded := func() []*racing.Race {
+		db := openSeedDB(t)
+		n, err := Seed(db, opts)
+		if err != nil {
+			t.Fatalf("Seed() failed: %v", err)
+		}
+		if n != 12 {
+			t.Errorf("Seed() = %d races, want 12", n)
+		}
+
+		races, err := NewRacesRepo(db, nil).List(nil)
+		if err != nil {
+			t.Fatalf("List() failed: %v", err)
+		}
+		return races
+	}
+
+	races := seeded()
+	if diff := cmp.Diff(races, seeded(), protocmp.Transform(), protocmp.IgnoreFields(&racing.Race{}, "status")); diff != "" {
+		t.Errorf("Seed() is not reproducible (-first +second):\n%s", diff)
+	}
+
+	numbers := make(map[int64]int64)
+	for _, race := range races {
+		if got := race.AdvertisedStartTime.AsTime(); got.Before(start) || !got.Before(start.Add(opts.Window)) {
+			t.Errorf("race %d starts at %v, outside the window", race.Id, got)
+		}
+		if race.MeetingId < 1 || race.MeetingId > 3 || race.VenueId == 0 {
+			t.Errorf("race %d is at meeting %d and venue %d", race.Id, race.MeetingId, race.VenueId)
+		}
+
+		// Races are listed by start time, so each meeting's numbers go up by one
+		numbers[race.MeetingId]++
+		if race.Number != numbers[race.MeetingId] {
+			t.Errorf("race %d is number %d of meeting %d, want %d", race.Id, race.Number, race.MeetingId, numbers[race.MeetingId])
+		}
+	}
+}
+
+func TestSeed_Runners(t *testing.T) {
+	start := time.Date(2026, 11, 3, 0, 0, 0, 0, time.UTC)
+	opts := SeedOptions{RandSeed: 7, Meetings: 2, RacesPerMeeting: 3, Start: start, Window: 12 * time.Hour}
+
+	seeded := func() (*sql.DB, [][]*racing.RunnerPrices) {
+		db := openSeedDB(t)
+		if _, err := Seed(db, opts); err != nil {
+			t.Fatalf("Seed() failed: %v", err)
+		}
+
+		repo := NewPricesRepo(db)
+		var races [][]*racing.RunnerPrices
+		for raceID := int64(1); raceID <= 7; raceID++ {
+			runners, err := repo.Get(raceID, true)
+			if err != nil {
+				t.Fatalf("Get() failed: %v", err)
+			}
+			races = append(races, runners)
+		}
+		return db, races
+	}
+
+	db, races := seeded()
+	if _, again := seeded(); !cmp.Equal(races, again, protocmp.Transform()) {
+		t.Error("Seed() runners and prices are not reproducible")
+	}
+
+	for i, runners := range races[:6] {
+		if len(runners) < 6 || len(runners) > 12 {
+			t.Errorf("seeded %d runners in race %d, want 6 to 12", len(runners), i+1)
+		}
+		for _, runner := range runners {
+			if runner.Scratched != (runner.Current == nil) {
+				t.Errorf("race %d runner %d: scratched = %v, priced = %v", i+1, runner.RunnerNumber, runner.Scratched, runner.Current != nil)
+			}
+			if runner.Current == nil {
+				continue
+			}
+			if runner.Current.Win < racing.MinOdds || runner.Current.Place < racing.MinOdds {
+				t.Errorf("race %d runner %d: seeded odds %v below the minimum", i+1, runner.RunnerNumber, runner.Current)
+			}
+			if !runner.Current.UpdatedTime.AsTime().Equal(start) {
+				t.Errorf("race %d runner %d: priced at %v, want %v", i+1, runner.RunnerNumber, runner.Current.UpdatedTime.AsTime(), start)
+			}
+		}
+	}
+	if len(races[6]) != 0 {
+		t.Errorf("seeded %d runners in race 7, which was not generated", len(races[6]))
+	}
+
+	// Seeding more races leaves the existing ones and their runners alone
+	var before int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM prices`).Scan(&before); err != nil {
+		t.Fatalf("failed to count prices: %v", err)
+	}
+	opts.Meetings = 3
+	if _, err := Seed(db, opts); err != nil {
+		t.Fatalf("Seed() failed: %v", err)
+	}
+	var existing int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM prices WHERE race_id <= 6`).Scan(&existing); err != nil {
+		t.Fatalf("failed to count prices: %v", err)
+	}
+	if existing != before {
+		t.Errorf("%d prices in the existing races after seeding more, want %d", existing, before)
+	}
+	if runners, err := NewPricesRepo(db).Get(7, false); err != nil || len(runners) == 0 {
+		t.Errorf("Get() of a newly seeded race = %v, %v, want its runners", runners, err)
+	}
+}
+
+func TestSeed_VisibleRatio(t *testing.T) {
+	for _, ratio := range []float64{0, 1} {
+		db := openSeedDB(t)
+		opts := DefaultSeedOptions(time.Now())
+		opts.VisibleRatio = ratio
+		if _, err := Seed(db, opts); err != nil {
+			t.Fatalf("Seed() failed: %v", err)
+		}
+
+		var visible int
+		if err := db.QueryRow(`SELECT COUNT(*) FROM races WHERE visible = 1`).Scan(&visible); err != nil {
+			t.Fatalf("failed to count visible races: %v", err)
+		}
+		if want := int(ratio * 100); visible != want {
+			t.Errorf("ratio %v: %d visible races, want %d", ratio, visible, want)
+		}
+	}
+}
+
+func TestSeed_Reset(t *testing.T) {
+	db := openSeedDB(t)
+	opts := DefaultSeedOptions(time.Now())
+	if _, err := Seed(db, opts); err != nil {
+		t.Fatalf("Seed() failed: %v", err)
+	}
+
+	// Without a reset the existing races are kept
+	opts.Meetings = 1
+	opts.RandSeed = 2
+	if _, err := Seed(db, opts); err != nil {
+		t.Fatalf("Seed() failed: %v", err)
+	}
+	countRaces(t, db, 100)
+
+	opts.Reset = true
+	if _, err := Seed(db, opts); err != nil {
+		t.Fatalf("Seed() with reset failed: %v", err)
+	}
+	countRaces(t, db, 10)
+}
+
+func countRaces(t *testing.T, db *sql.DB, want int) {
+	t.Helper()
+
+	var n int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM races`).Scan(&n); err != nil {
+		t.Fatalf("failed to count races: %v", err)
+	}
+	if n != want {
+		t.Errorf("%d races, want %d", n, want)
+	}
+}
+
+func TestSeed_InvalidOptions(t *testing.T) {
+	valid := DefaultSeedOptions(time.Now())
+	tests := []struct {
+		name    string
+		modify  func(o *SeedOptions)
+		wantErr string
+	}{
+		{"no meetings", func(o *SeedOptions) { o.Meetings = 0 }, "meetings"},
+		{"no races", func(o *SeedOptions) { o.RacesPerMeeting = 0 }, "races per meeting"},
+		{"empty window", func(o *SeedOptions) { o.Window = 0 }, "window"},
+		{"ratio above one", func(o *SeedOptions) { o.VisibleRatio = 1.5 }, "visible ratio"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := valid
+			tt.modify(&opts)
+
+			_, err := Seed(openSeedDB(t), opts)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Seed() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func BenchmarkSeed(b *testing.B) {
+	opts := DefaultSeedOptions(time.Now())
+	opts.Meetings = 1000
+	opts.RacesPerMeeting = 100
+
+	for i := 0; i < b.N; i++ {
+		db, err := sql.Open("sqlite3", ":memory:")
+		if err != nil {
+			b.Fatalf("failed to open database: %v", err)
+		}
+		if _, err := Seed(db, opts); err != nil {
+			b.Fatalf("Seed() failed: %v", err)
+		}
+		db.Close()
+	}
+}
+
+func TestCreateTables_SeedsNoRaces(t *testing.T) {
+	db := openSeedDB(t)
+	if err := CreateTables(db); err != nil {
+		t.Fatalf("CreateTables() failed: %v", err)
+	}
+
+	// An import into a fresh database stores the feed and nothing else
+	if _, _, err := NewRacesRepo(db, nil).Import([]*racing.ImportRacesRequest{&racing.ImportRacesRequest{SourceId: "feed-7", MeetingId: 2, Name: "Melbourne Cup", Number: 7, Visible: true, AdvertisedStartTime: timestamppb.Now()}}, "trader-1", false); err != nil {
+		t.Fatalf("Import() failed: %v", err)
+	}
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM races`).Scan(&count); err != nil {
+		t.Fatalf("failed to count races: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("got %d races, want the imported one only", count)
+	}
+}
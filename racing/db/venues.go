@@ -0,0 +1,131 @@
+package db
+
+import (
+	"database/sql"
+	"sync"
+	"time"
+
+	// Venue time zones must load on hosts without a zoneinfo database
+	_ "time/tzdata"
+
+	"git.neds.sh/matty/entain/racing/proto/racing"
+)
+
+// VenuesRepo provides repository access to the venues races are run at.
+type VenuesRepo interface {
+	// Init will initialise our venues repository.
+	Init() error
+
+	// List will return every venue, by name.
+	List() ([]*racing.Venue, error)
+}
+
+type venuesRepo struct {
+	db   *sql.DB
+	init sync.Once
+}
+
+// NewVenuesRepo creates a new venues repository.
+func NewVenuesRepo(db *sql.DB) VenuesRepo {
+	return &venuesRepo{db: db}
+}
+
+// Init prepares the venues repository dummy data.
+func (r *venuesRepo) Init() error {
+	var err error
+
+	r.init.Do(func() {
+		err = seedVenues(r.db)
+	})
+
+	return err
+}
+
+// List retrieves every venue.
+func (r *venuesRepo) List() ([]*racing.Venue, error) {
+	rows, err := r.db.Query(getVenueQueries()[venuesList])
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	venues := []*racing.Venue{}
+	for rows.Next() {
+		var venue racing.Venue
+		if err := rows.Scan(&venue.Id, &venue.Name, &venue.TimeZone, &venue.Country, &venue.Latitude, &venue.Longitude); err != nil {
+			return nil, err
+		}
+		venues = append(venues, &venue)
+	}
+
+	return venues, rows.Err()
+}
+
+// venueZones returns the time zone of every venue by its ID.
+func venueZones(db *sql.DB) (map[int64]string, error) {
+	rows, err := db.Query(getVenueQueries()[venuesZones])
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	zones := make(map[int64]string)
+	for rows.Next() {
+		var (
+			id   int64
+			zone string
+		)
+		if err := rows.Scan(&id, &zone); err != nil {
+			return nil, err
+		}
+		zones[id] = zone
+	}
+
+	return zones, rows.Err()
+}
+
+// locations caches the loaded venue time zones by name.
+var locations sync.Map
+
+// loadLocation returns the named IANA time zone, loading it once.
+func loadLocation(zone string) (*time.Location, error) {
+	if loc, ok := locations.Load(zone); ok {
+		return loc.(*time.Location), nil
+	}
+
+	loc, err := time.LoadLocation(zone)
+	if err != nil {
+		return nil, err
+	}
+	locations.Store(zone, loc)
+	return loc, nil
+}
+
+// localStartTime renders start in a venue's time zone as RFC 3339 with the
+// venue's UTC offset. It is empty if the zone is unknown.
+func localStartTime(start time.Time, zone string) string {
+	if zone == "" {
+		return ""
+	}
+	loc, err := loadLocation(zone)
+	if err != nil {
+		return ""
+	}
+	return start.In(loc).Format(time.RFC3339)
+}
+
+// startsToday reports whether start falls on the same calendar date as now in
+// a venue's time zone. Starts at venues with an unknown zone never do.
+func startsToday(start, now time.Time, zone string) bool {
+	if zone == "" {
+		return false
+	}
+	loc, err := loadLocation(zone)
+	if err != nil {
+		return false
+	}
+
+	sy, sm, sd := start.In(loc).Date()
+	ny, nm, nd := now.In(loc).Date()
+	return sy == ny && sm == nm && sd == nd
+}
@@ -0,0 +1,135 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"git.neds.sh/matty/entain/racing/proto/racing"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestVenuesRepo_List(t *testing.T) {
+	repo := NewVenuesRepo(setupTestDB(t))
+	if err := repo.Init(); err != nil {
+		t.Fatalf("Init() failed: %v", err)
+	}
+
+	venues, err := repo.List()
+	if err != nil {
+		t.Fatalf("List() failed: %v", err)
+	}
+
+	var names []string
+	for _, venue := range venues {
+		names = append(names, venue.Name)
+		if _, err := time.LoadLocation(venue.TimeZone); err != nil {
+			t.Errorf("venue %q has an invalid time zone %q: %v", venue.Name, venue.TimeZone, err)
+		}
+	}
+	want := []string{"Ascot", "Eagle Farm", "Flemington", "Randwick", "Sha Tin"}
+	if diff := cmp.Diff(want, names); diff != "" {
+		t.Errorf("List() names mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRacesRepo_AdvertisedStartLocal(t *testing.T) {
+	db := setupTestDB(t)
+	start := time.Date(2026, 10, 18, 5, 0, 0, 0, time.UTC)
+	insertTestRace(t, db, 1, 1, 1, "Flemington race", true, start)
+	insertTestRace(t, db, 2, 4, 1, "Ascot race", true, start)
+	insertTestRace(t, db, 3, 42, 1, "Unknown meeting race", true, start)
+
+	races, err := NewRacesRepo(db, nil).GetByIDs([]int64{1, 2, 3})
+	if err != nil {
+		t.Fatalf("GetByIDs() failed: %v", err)
+	}
+
+	type local struct {
+		venue int64
+		start string
+	}
+	want := []local{
+		{1, "2026-10-18T16:00:00+11:00"},
+		{4, "2026-10-18T06:00:00+01:00"},
+		{0, ""},
+	}
+	var got []local
+	for _, race := range races {
+		got = append(got, local{race.VenueId, race.AdvertisedStartLocal})
+	}
+	if diff := cmp.Diff(want, got, cmp.AllowUnexported(local{})); diff != "" {
+		t.Errorf("local start times mismatch (-want +got):\n%s", diff)
+	}
+
+	race, err := NewRacesRepo(db, nil).GetByID(1)
+	if err != nil {
+		t.Fatalf("GetByID() failed: %v", err)
+	}
+	if race.AdvertisedStartLocal != want[0].start {
+		t.Errorf("GetByID() local start = %q, want %q", race.AdvertisedStartLocal, want[0].start)
+	}
+}
+
+func TestRacesRepo_List_VenueFilters(t *testing.T) {
+	db := setupTestDB(t)
+	now := time.Now().UTC()
+	insertTestRace(t, db, 1, 1, 1, "Flemington now", true, now)
+	insertTestRace(t, db, 2, 6, 1, "Flemington in two days", true, now.AddDate(0, 0, 2))
+	insertTestRace(t, db, 3, 5, 1, "Sha Tin now", true, now)
+	insertTestRace(t, db, 4, 42, 1, "Unknown meeting now", true, now)
+	repo := NewRacesRepo(db, nil)
+
+	tests := []struct {
+		name   string
+		filter *racing.ListRacesRequestFilter
+		want   []int64
+	}{
+		{name: "venue", filter: &racing.ListRacesRequestFilter{VenueIds: []int64{1}}, want: []int64{1, 2}},
+		{name: "venues and meetings", filter: &racing.ListRacesRequestFilter{VenueIds: []int64{1, 5}, MeetingIds: []int64{5, 6}}, want: []int64{3, 2}},
+		{name: "today at venue", filter: &racing.ListRacesRequestFilter{TodayAtVenue: boolPtr(true)}, want: []int64{1, 3}},
+		{name: "today at a venue", filter: &racing.ListRacesRequestFilter{VenueIds: []int64{1}, TodayAtVenue: boolPtr(true)}, want: []int64{1}},
+		{name: "today at venue, limited", filter: &racing.ListRacesRequestFilter{TodayAtVenue: boolPtr(true), Limit: int32Ptr(1)}, want: []int64{1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			races, err := repo.List(tt.filter)
+			if err != nil {
+				t.Fatalf("List() failed: %v", err)
+			}
+			var got []int64
+			for _, race := range races {
+				got = append(got, race.Id)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("List() IDs mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestStartsToday(t *testing.T) {
+	// 23:30 UTC on 17 October is already 18 October in Melbourne
+	now := time.Date(2026, 10, 17, 23, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		start time.Time
+		zone  string
+		want  bool
+	}{
+		{name: "same local day", start: time.Date(2026, 10, 18, 5, 0, 0, 0, time.UTC), zone: "Australia/Melbourne", want: true},
+		{name: "same UTC day, previous local day", start: time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC), zone: "Australia/Melbourne"},
+		{name: "next local day", start: time.Date(2026, 10, 18, 17, 0, 0, 0, time.UTC), zone: "Asia/Hong_Kong"},
+		{name: "unknown zone", start: now, zone: ""},
+		{name: "invalid zone", start: now, zone: "Mars/Olympus_Mons"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := startsToday(tt.start, now, tt.zone); got != tt.want {
+				t.Errorf("startsToday() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
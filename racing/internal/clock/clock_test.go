@@ -0,0 +1,52 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOffset(t *testing.T) {
+	base := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+	now := base
+	clk := Offset(Func(func() time.Time { return now }), -24*time.Hour)
+
+	if got, want := clk.Now(), base.Add(-24*time.Hour); !got.Equal(want) {
+		t.Errorf("Now() = %v, want %v", got, want)
+	}
+
+	// The offset clock keeps running with its base
+	now = now.Add(time.Minute)
+	if got, want := clk.Now(), base.Add(-24*time.Hour+time.Minute); !got.Equal(want) {
+		t.Errorf("Now() after a minute = %v, want %v", got, want)
+	}
+}
+
+func TestParseOffset(t *testing.T) {
+	now := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{name: "time", value: "2026-10-18T16:00:00+11:00", want: -4 * time.Hour},
+		{name: "future time", value: "2026-10-19T09:00:00Z", want: 24 * time.Hour},
+		{name: "negative duration", value: "-24h", want: -24 * time.Hour},
+		{name: "duration", value: "90m", want: 90 * time.Minute},
+		{name: "date only", value: "2026-10-17", wantErr: true},
+		{name: "garbage", value: "yesterday", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseOffset(tt.value, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseOffset(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseOffset(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// DefaultImportActor is who imports from the command line are recorded as
+// made by, unless another actor is given.
+const DefaultImportActor = "cli"
+
+// ImportConfig is the configuration of the import subcommand, which loads
+// races from a CSV or NDJSON feed.
+type ImportConfig struct {
+	// DSN is the database to import into, DB_DSN or the service default when unset.
+	DSN string
+	// File is the feed to import, or "-" for standard input.
+	File string
+	// Format is the encoding of the feed, csv or ndjson. When empty it is
+	// told from the extension of File.
+	Format string
+	// DryRun checks the feed and reports what would change without saving it.
+	DryRun bool
+	// Actor is who the changes are recorded as made by in the race history.
+	Actor string
+}
+
+// LoadImport parses the arguments of the import subcommand.
+func LoadImport(args []string) (ImportConfig, error) {
+	return loadImport(args, os.LookupEnv)
+}
+
+func loadImport(args []string, lookupEnv func(string) (string, bool)) (ImportConfig, error) {
+	dsn := Default().Database.DSN
+	if v, ok := lookupEnv(EnvDatabaseDSN); ok {
+		dsn = v
+	}
+
+	var cfg ImportConfig
+	fs := flag.NewFlagSet("racing import", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: racing import [flags] FILE\n\nImports the races of a CSV or NDJSON feed; FILE - reads standard input.\n\n")
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&cfg.DSN, "db-dsn", dsn, "Database data source name")
+	fs.StringVar(&cfg.Format, "format", "", "Feed format, csv or ndjson (default: from the file extension)")
+	fs.BoolVar(&cfg.DryRun, "dry-run", false, "Check the feed and report what would change without saving it")
+	fs.StringVar(&cfg.Actor, "actor", DefaultImportActor, "Who the changes are recorded as made by in the race history")
+	if err := fs.Parse(args); err != nil {
+		return ImportConfig{}, err
+	}
+	if fs.NArg() == 1 {
+		cfg.File = fs.Arg(0)
+	}
+
+	if fs.NArg() > 1 {
+		return ImportConfig{}, fmt.Errorf("invalid import configuration: expected one feed file, got %d", fs.NArg())
+	}
+	if err := cfg.Validate(); err != nil {
+		return ImportConfig{}, err
+	}
+
+	return cfg, nil
+}
+
+// Validate checks that there is a database and a feed to import.
+func (c ImportConfig) Validate() error {
+	var problems []string
+
+	if strings.TrimSpace(c.DSN) == "" {
+		problems = append(problems, "db-dsn must not be empty")
+	}
+	if strings.TrimSpace(c.Actor) == "" {
+		problems = append(problems, "actor must not be empty")
+	}
+	if c.File == "" {
+		problems = append(problems, "a feed file is required")
+	}
+	if c.File == "-" && c.Format == "" {
+		problems = append(problems, "format is required when reading standard input")
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid import configuration: %s", strings.Join(problems, "; "))
+	}
+
+	return nil
+}
@@ -0,0 +1,107 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLoadSeed(t *testing.T) {
+	defaults := SeedConfig{
+		DSN:             "./db/racing.db",
+		RandSeed:        1,
+		Meetings:        10,
+		RacesPerMeeting: 10,
+		Start:           "-24h",
+		Window:          72 * time.Hour,
+		VisibleRatio:    0.5,
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		env  map[string]string
+		want func(c *SeedConfig)
+	}{
+		{name: "defaults", want: func(c *SeedConfig) {}},
+		{
+			name: "env dsn",
+			env:  map[string]string{EnvDatabaseDSN: "/tmp/env.db"},
+			want: func(c *SeedConfig) { c.DSN = "/tmp/env.db" },
+		},
+		{
+			name: "flags override env",
+			args: []string{"--db-dsn", "/tmp/flag.db", "--rand-seed", "7", "--meetings", "1000", "--races-per-meeting", "100",
+				"--start", "2026-11-03T00:00:00+11:00", "--window", "24h", "--visible-ratio", "1", "--reset"},
+			env: map[string]string{EnvDatabaseDSN: "/tmp/env.db"},
+			want: func(c *SeedConfig) {
+				*c = SeedConfig{
+					DSN:             "/tmp/flag.db",
+					RandSeed:        7,
+					Meetings:        1000,
+					RacesPerMeeting: 100,
+					Start:           "2026-11-03T00:00:00+11:00",
+					Window:          24 * time.Hour,
+					VisibleRatio:    1,
+					Reset:           true,
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := loadSeed(tt.args, envMap(tt.env))
+			if err != nil {
+				t.Fatalf("loadSeed() failed: %v", err)
+			}
+
+			want := defaults
+			tt.want(&want)
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("loadSeed() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestLoadSeed_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{name: "empty dsn", args: []string{"--db-dsn", " "}, wantErr: "db-dsn"},
+		{name: "invalid start", args: []string{"--start", "yesterday"}, wantErr: "start"},
+		{name: "invalid count", args: []string{"--meetings", "many"}, wantErr: "invalid value"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadSeed(tt.args, envMap(nil))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("loadSeed(%v) error = %v, want containing %q", tt.args, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSeedConfig_StartTime(t *testing.T) {
+	now := time.Date(2026, 10, 18, 5, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		start string
+		want  time.Time
+	}{
+		{"-24h", now.Add(-24 * time.Hour)},
+		{"2026-11-03T00:00:00Z", time.Date(2026, 11, 3, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		if got := (SeedConfig{Start: tt.start}).StartTime(now); !got.Equal(tt.want) {
+			t.Errorf("StartTime() with start %q = %v, want %v", tt.start, got, tt.want)
+		}
+	}
+}
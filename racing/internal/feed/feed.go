@@ -0,0 +1,261 @@
+// Package feed reads races from CSV and NDJSON feeds and imports them. It is
+// shared by the ImportRaces RPC and the import command.
+package feed
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"git.neds.sh/matty/entain/racing/db"
+	"git.neds.sh/matty/entain/racing/proto/racing"
+)
+
+// Format is the encoding of a feed.
+type Format string
+
+// Supported feed formats.
+const (
+	// CSV feeds have a header row naming the columns, in any order.
+	CSV Format = "csv"
+	// NDJSON feeds have one ImportRacesRequest in protobuf JSON per line.
+	NDJSON Format = "ndjson"
+)
+
+// ParseFormat returns the format called name.
+func ParseFormat(name string) (Format, error) {
+	switch strings.ToLower(name) {
+	case "csv":
+		return CSV, nil
+	case "ndjson", "jsonl":
+		return NDJSON, nil
+	}
+	return "", fmt.Errorf("unknown feed format %q: must be csv or ndjson", name)
+}
+
+// FormatOf returns the format of the feed at path from its extension.
+func FormatOf(path string) (Format, error) {
+	ext := strings.TrimPrefix(filepath.Ext(path), ".")
+	if ext == "" {
+		return "", fmt.Errorf("feed %s has no extension to tell its format from", path)
+	}
+	return ParseFormat(ext)
+}
+
+// Record is a race read from a feed, or the error that kept it from being read.
+type Record struct {
+	// Row is the position of the record in the feed, from 1, not counting a
+	// CSV header or blank lines.
+	Row  int
+	Race *racing.ImportRacesRequest
+	Err  error
+}
+
+// CSV columns. The visible column is optional and defaults to false.
+const (
+	columnSourceID  = "source_id"
+	columnMeetingID = "meeting_id"
+	columnName      = "name"
+	columnNumber    = "number"
+	columnVisible   = "visible"
+	columnStart     = "advertised_start_time"
+)
+
+var requiredColumns = []string{columnSourceID, columnMeetingID, columnName, columnNumber, columnStart}
+
+// ReadRaces reads the races of a feed. Records that cannot be parsed are
+// returned with their error; an error is only returned for an unreadable
+// feed or one with more than racing.MaxImportRaces records.
+func ReadRaces(r io.Reader, format Format) ([]Record, error) {
+	switch format {
+	case CSV:
+		return readCSV(r)
+	case NDJSON:
+		return readNDJSON(r)
+	}
+	return nil, fmt.Errorf("unknown feed format %q", format)
+}
+
+func readCSV(r io.Reader) ([]Record, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch name {
+		case columnSourceID, columnMeetingID, columnName, columnNumber, columnVisible, columnStart:
+		default:
+			return nil, fmt.Errorf("unknown CSV column %q", name)
+		}
+		if _, ok := columns[name]; ok {
+			return nil, fmt.Errorf("duplicate CSV column %q", name)
+		}
+		columns[name] = i
+	}
+	for _, name := range requiredColumns {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("missing CSV column %q", name)
+		}
+	}
+
+	var records []Record
+	for {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		if len(records) == racing.MaxImportRaces {
+			return nil, fmt.Errorf("feed has more than %d races", racing.MaxImportRaces)
+		}
+
+		record := Record{Row: len(records) + 1}
+		var parseErr *csv.ParseError
+		switch {
+		case errors.As(err, &parseErr):
+			record.Err = parseErr.Err
+		case err != nil:
+			return nil, fmt.Errorf("failed to read CSV row %d: %w", record.Row, err)
+		default:
+			record.Race, record.Err = parseCSVRace(columns, fields)
+		}
+		records = append(records, record)
+	}
+}
+
+// parseCSVRace converts the fields of a CSV row into a race.
+func parseCSVRace(columns map[string]int, fields []string) (*racing.ImportRacesRequest, error) {
+	field := func(name string) string {
+		if i, ok := columns[name]; ok {
+			return strings.TrimSpace(fields[i])
+		}
+		return ""
+	}
+
+	race := &racing.ImportRacesRequest{
+		SourceId: field(columnSourceID),
+		Name:     field(columnName),
+	}
+
+	var err error
+	if race.MeetingId, err = strconv.ParseInt(field(columnMeetingID), 10, 64); err != nil {
+		return race, &racing.FieldError{Field: columnMeetingID, Description: fmt.Sprintf("invalid meeting ID %q", field(columnMeetingID))}
+	}
+	if race.Number, err = strconv.ParseInt(field(columnNumber), 10, 64); err != nil {
+		return race, &racing.FieldError{Field: columnNumber, Description: fmt.Sprintf("invalid race number %q", field(columnNumber))}
+	}
+	if visible := field(columnVisible); visible != "" {
+		if race.Visible, err = strconv.ParseBool(visible); err != nil {
+			return race, &racing.FieldError{Field: columnVisible, Description: fmt.Sprintf("invalid visible flag %q", visible)}
+		}
+	}
+	if start := field(columnStart); start != "" {
+		t, err := time.Parse(time.RFC3339, start)
+		if err != nil {
+			return race, &racing.FieldError{Field: columnStart, Description: fmt.Sprintf("invalid advertised start time %q: must be RFC 3339", start)}
+		}
+		race.AdvertisedStartTime = timestamppb.New(t)
+	}
+
+	return race, nil
+}
+
+func readNDJSON(r io.Reader) ([]Record, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var records []Record
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if len(records) == racing.MaxImportRaces {
+			return nil, fmt.Errorf("feed has more than %d races", racing.MaxImportRaces)
+		}
+
+		record := Record{Row: len(records) + 1, Race: &racing.ImportRacesRequest{}}
+		if err := protojson.Unmarshal([]byte(line), record.Race); err != nil {
+			record.Err = fmt.Errorf("invalid JSON: %w", err)
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read NDJSON feed: %w", err)
+	}
+
+	return records, nil
+}
+
+// Import validates records with the rules of racing.ImportRacesRequest and
+// upserts the valid races in one transaction. Source IDs must be unique
+// within the feed. The changes are recorded in the history of the races as
+// made by actor. Nothing is saved on a dry run or when any record failed, but
+// the counts still say what the import would do.
+func Import(repo db.RacesRepo, records []Record, actor string, dryRun bool) (*racing.ImportRacesResponse, error) {
+	resp := &racing.ImportRacesResponse{}
+
+	var races []*racing.ImportRacesRequest
+	firstRows := make(map[string]int)
+	for _, record := range records {
+		err := record.Err
+		if err == nil {
+			err = record.Race.Validate()
+		}
+		if err == nil {
+			if row, ok := firstRows[record.Race.SourceId]; ok {
+				err = &racing.FieldError{Field: "source_id", Description: fmt.Sprintf("duplicate source ID, first seen on row %d", row)}
+			}
+		}
+		if err != nil {
+			resp.Errors = append(resp.Errors, importError(record, err))
+			continue
+		}
+
+		firstRows[record.Race.SourceId] = record.Row
+		races = append(races, record.Race)
+	}
+
+	commit := !dryRun && len(resp.Errors) == 0
+	created, updated, err := repo.Import(races, actor, !commit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to import races: %w", err)
+	}
+
+	resp.Created = int32(created)
+	resp.Updated = int32(updated)
+	resp.Committed = commit
+	return resp, nil
+}
+
+// importError reports why a record failed.
+func importError(record Record, err error) *racing.ImportError {
+	importErr := &racing.ImportError{Row: int32(record.Row), Message: err.Error()}
+	if record.Race != nil {
+		importErr.SourceId = record.Race.SourceId
+	}
+
+	var fieldErr *racing.FieldError
+	if errors.As(err, &fieldErr) {
+		importErr.Field = fieldErr.Field
+	}
+
+	return importErr
+}
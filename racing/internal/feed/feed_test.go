@@ -0,0 +1,168 @@
+package feed
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"git.neds.sh/matty/entain/racing/proto/racing"
+)
+
+func TestReadRaces(t *testing.T) {
+	start := timestamppb.New(time.Date(2026, 11, 3, 4, 0, 0, 0, time.UTC))
+	cup := &racing.ImportRacesRequest{SourceId: "feed-7", MeetingId: 2, Name: "Melbourne Cup", Number: 7, Visible: true, AdvertisedStartTime: start}
+
+	tests := []struct {
+		name       string
+		feed       string
+		format     Format
+		want       []*racing.ImportRacesRequest
+		wantErrors map[int]string
+	}{
+		{
+			name:   "csv with columns in any order",
+			format: CSV,
+			feed: "name,source_id,number,meeting_id,advertised_start_time,visible\n" +
+				"Melbourne Cup,feed-7,7,2,2026-11-03T15:00:00+11:00,true\n",
+			want: []*racing.ImportRacesRequest{cup},
+		},
+		{
+			name:   "csv with invalid fields",
+			format: CSV,
+			feed: "source_id,meeting_id,name,number,advertised_start_time\n" +
+				"feed-1,one,Race,1,2026-11-03T04:00:00Z\n" +
+				"feed-2,1,Race,1,tomorrow\n" +
+				"feed-3,1,\"Race,1\n",
+			want: []*racing.ImportRacesRequest{
+				{SourceId: "feed-1", Name: "Race"},
+				{SourceId: "feed-2", MeetingId: 1, Name: "Race", Number: 1},
+				nil,
+			},
+			wantErrors: map[int]string{1: "invalid meeting ID", 2: "must be RFC 3339", 3: "quote"},
+		},
+		{
+			name:   "ndjson skipping blank lines",
+			format: NDJSON,
+			feed: `{"sourceId":"feed-7","meetingId":"2","name":"Melbourne Cup","number":"7","visible":true,"advertisedStartTime":"2026-11-03T04:00:00Z"}` + "\n\n" +
+				`{"sourceId":` + "\n",
+			want:       []*racing.ImportRacesRequest{cup, {}},
+			wantErrors: map[int]string{2: "invalid JSON"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := ReadRaces(strings.NewReader(tt.feed), tt.format)
+			if err != nil {
+				t.Fatalf("ReadRaces() failed: %v", err)
+			}
+
+			var got []*racing.ImportRacesRequest
+			for i, record := range records {
+				if record.Row != i+1 {
+					t.Errorf("record %d has row %d", i, record.Row)
+				}
+				got = append(got, record.Race)
+
+				wantErr := tt.wantErrors[record.Row]
+				if (wantErr == "") != (record.Err == nil) || record.Err != nil && !strings.Contains(record.Err.Error(), wantErr) {
+					t.Errorf("row %d error = %v, want containing %q", record.Row, record.Err, wantErr)
+				}
+			}
+			if diff := cmp.Diff(tt.want, got, protocmp.Transform()); diff != "" {
+				t.Errorf("ReadRaces() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestReadRaces_InvalidHeader(t *testing.T) {
+	tests := map[string]string{
+		"unknown column":   "source_id,meeting_id,name,number,advertised_start_time,venue\n",
+		"duplicate column": "source_id,meeting_id,name,number,advertised_start_time,name\n",
+		"missing column":   "source_id,meeting_id,name,advertised_start_time\n",
+	}
+
+	for name, feed := range tests {
+		if _, err := ReadRaces(strings.NewReader(feed), CSV); err == nil {
+			t.Errorf("ReadRaces() with %s succeeded, want an error", name)
+		}
+	}
+}
+
+func TestFormatOf(t *testing.T) {
+	tests := map[string]Format{"card.csv": CSV, "card.CSV": CSV, "card.ndjson": NDJSON, "card.jsonl": NDJSON}
+	for path, want := range tests {
+		if got, err := FormatOf(path); err != nil || got != want {
+			t.Errorf("FormatOf(%q) = %q, %v, want %q", path, got, err, want)
+		}
+	}
+
+	for _, path := range []string{"card", "card.xml"} {
+		if _, err := FormatOf(path); err == nil {
+			t.Errorf("FormatOf(%q) succeeded, want an error", path)
+		}
+	}
+}
+
+// testRepo is a db.RacesRepo importing every race as new, or failing with err
+type testRepo struct {
+	err    error
+	actor  string
+	dryRun bool
+	races  []*racing.ImportRacesRequest
+}
+
+func (r *testRepo) Init() error                                                           { return nil }
+func (r *testRepo) List(*racing.ListRacesRequestFilter) ([]*racing.Race, error)           { return nil, nil }
+func (r *testRepo) Stream(*racing.ListRacesRequestFilter, func(*racing.Race) error) error { return nil }
+func (r *testRepo) GetByID(int64) (*racing.Race, error)                                   { return nil, nil }
+func (r *testRepo) GetByIDs([]int64) ([]*racing.Race, error)                              { return nil, nil }
+
+func (r *testRepo) Import(races []*racing.ImportRacesRequest, actor string, dryRun bool) (int, int, error) {
+	r.races, r.actor, r.dryRun = races, actor, dryRun
+	return len(races), 0, r.err
+}
+
+func (r *testRepo) History(int64, string) ([]*racing.RaceChange, error) { return nil, nil }
+
+func TestImport(t *testing.T) {
+	start := timestamppb.New(time.Date(2026, 11, 3, 4, 0, 0, 0, time.UTC))
+	race := func(sourceID string) *racing.ImportRacesRequest {
+		return &racing.ImportRacesRequest{SourceId: sourceID, MeetingId: 1, Name: "Race", Number: 1, AdvertisedStartTime: start}
+	}
+
+	records := []Record{{Row: 1, Race: race("feed-1")}, {Row: 2, Race: race("feed-2")}}
+
+	repo := &testRepo{}
+	resp, err := Import(repo, records, "trader-1", false)
+	if err != nil {
+		t.Fatalf("Import() failed: %v", err)
+	}
+	if !resp.Committed || resp.Created != 2 || repo.dryRun {
+		t.Errorf("Import() = %v with dry run %t, want 2 races committed", resp, repo.dryRun)
+	}
+	if repo.actor != "trader-1" {
+		t.Errorf("Import() recorded the races as imported by %q, want trader-1", repo.actor)
+	}
+
+	// A failed record turns the import into a dry run of the others
+	records = append(records, Record{Row: 3, Err: errors.New("wrong number of fields")})
+	resp, err = Import(repo, records, "trader-1", false)
+	if err != nil {
+		t.Fatalf("Import() failed: %v", err)
+	}
+	if resp.Committed || !repo.dryRun || len(resp.Errors) != 1 || resp.Errors[0].Row != 3 {
+		t.Errorf("Import() = %v with dry run %t, want row 3 reported and nothing committed", resp, repo.dryRun)
+	}
+
+	repo.err = errors.New("database is locked")
+	if _, err := Import(repo, records[:2], "trader-1", false); err == nil || !strings.Contains(err.Error(), "database is locked") {
+		t.Errorf("Import() error = %v, want the repository error", err)
+	}
+}
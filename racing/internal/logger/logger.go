@@ -1,6 +1,7 @@
 package logger
 
 import (
+	"fmt"
 	"os"
 	"strings"
 
@@ -51,35 +52,52 @@ func NewFromEnv() Config {
 
 // getLogLevelFromEnv reads log level from LOG_LEVEL environment variable
 func getLogLevelFromEnv() LogLevel {
-	levelStr := strings.TrimSpace(os.Getenv(EnvLogLevel))
-	level := strings.ToLower(levelStr)
-	switch level {
-	case "debug":
-		return DebugLevel
-	case "info":
+	level, err := ParseLevel(os.Getenv(EnvLogLevel))
+	if err != nil {
 		return InfoLevel
+	}
+	return level
+}
+
+// getEnvironmentFromEnv reads environment from ENVIRONMENT environment variable
+func getEnvironmentFromEnv() Environment {
+	env, err := ParseEnvironment(os.Getenv(EnvEnvironment))
+	if err != nil {
+		return Production
+	}
+	return env
+}
+
+// ParseLevel converts a case-insensitive level name into a LogLevel.
+// An empty string yields InfoLevel.
+func ParseLevel(s string) (LogLevel, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return DebugLevel, nil
+	case "info", "":
+		return InfoLevel, nil
 	case "warn":
-		return WarnLevel
+		return WarnLevel, nil
 	case "error":
-		return ErrorLevel
+		return ErrorLevel, nil
 	default:
-		return InfoLevel
+		return InfoLevel, fmt.Errorf("unknown log level %q", s)
 	}
 }
 
-// getEnvironmentFromEnv reads environment from ENVIRONMENT environment variable
-func getEnvironmentFromEnv() Environment {
-	envStr := strings.TrimSpace(os.Getenv(EnvEnvironment))
-	env := strings.ToLower(envStr)
-	switch env {
+// ParseEnvironment converts a case-insensitive environment name, including the
+// short aliases "dev", "prod" and "test", into an Environment.
+// An empty string yields Production.
+func ParseEnvironment(s string) (Environment, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
 	case "development", "dev":
-		return Development
-	case "production", "prod":
-		return Production
+		return Development, nil
+	case "production", "prod", "":
+		return Production, nil
 	case "testing", "test":
-		return Testing
+		return Testing, nil
 	default:
-		return Production
+		return Production, fmt.Errorf("unknown environment %q", s)
 	}
 }
 
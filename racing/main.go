@@ -1,29 +1,70 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"flag"
 	"fmt"
 	"net"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"git.neds.sh/matty/entain/racing/db"
+	"git.neds.sh/matty/entain/racing/internal/auth"
+	"git.neds.sh/matty/entain/racing/internal/config"
+	"git.neds.sh/matty/entain/racing/internal/feed"
 	"git.neds.sh/matty/entain/racing/internal/logger"
+	"git.neds.sh/matty/entain/racing/internal/tlsutil"
 	"git.neds.sh/matty/entain/racing/proto/racing"
 	"git.neds.sh/matty/entain/racing/service"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
-)
-
-var (
-	grpcEndpoint = flag.String("grpc-endpoint", "localhost:9000", "gRPC server endpoint")
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
 )
 
 func main() {
-	flag.Parse()
+	// The seed and import subcommands fill the database and exit
+	if len(os.Args) > 1 {
+		var (
+			err    error
+			action string
+		)
+		switch os.Args[1] {
+		case "seed":
+			action, err = "seed database", seed(os.Args[2:])
+		case "import":
+			action, err = "import races", importFeed(os.Args[2:])
+		}
+		if action != "" {
+			if errors.Is(err, flag.ErrHelp) {
+				return
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to %s: %v\n", action, err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
 
-	// 1. init logger
-	loggerConfig := logger.NewFromEnv()
+	// 1. load configuration
+	cfg, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
+		os.Exit(1)
+	}
+
+	// 2. init logger
+	loggerConfig := cfg.Logger()
 	serviceLogger, err := logger.New(loggerConfig)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to initialize logger: %v\n", err)
@@ -37,54 +78,253 @@ func main() {
 	}()
 
 	serviceLogger.Info("Starting racing service",
-		zap.String("endpoint", *grpcEndpoint),
+		zap.String("endpoint", cfg.GRPC.Endpoint),
 		zap.String("log_level", string(loggerConfig.Level)),
 		zap.String("environment", string(loggerConfig.Environment)),
 	)
+	serviceLogger.Debug("Effective configuration", zap.Object("config", cfg))
 
-	// 2. run the service
-	if err := run(serviceLogger); err != nil {
+	// 3. run the service
+	if err := run(cfg, serviceLogger); err != nil {
 		serviceLogger.Fatal("Service failed to start", zap.Error(err))
 	}
 }
 
-func run(logger *zap.Logger) error {
+func run(cfg config.Config, logger *zap.Logger) error {
 	logger.Info("Initializing gRPC server")
 
-	conn, err := net.Listen("tcp", ":9000")
+	conn, err := net.Listen("tcp", cfg.GRPC.Endpoint)
 	if err != nil {
 		return fmt.Errorf("failed to listen: %w", err)
 	}
 
 	logger.Info("Setting up database connection")
-	racingDB, err := sql.Open("sqlite3", "./db/racing.db")
+	racingDB, err := sql.Open("sqlite3", cfg.Database.DSN)
 	if err != nil {
 		return fmt.Errorf("failed to open database: %w", err)
 	}
 	defer racingDB.Close()
 
+	// Race status, seeded start times and price stamps all read this clock
+	clk := cfg.NewClock()
+	if cfg.Clock.FakeTime != "" {
+		logger.Warn("Using a fake clock", zap.String("fake_time", cfg.Clock.FakeTime), zap.Time("now", clk.Now()))
+	}
+
 	logger.Info("Initializing repository")
-	racesRepo := db.NewRacesRepo(racingDB)
+	// Races reference the venues of their meetings, so venues come first
+	venuesRepo := db.NewVenuesRepo(racingDB)
+	if err := venuesRepo.Init(); err != nil {
+		logger.Error("Failed to initialize venues repository", zap.Error(err))
+		return fmt.Errorf("failed to initialize venues repository: %w", err)
+	}
+
+	racesRepo := db.NewRacesRepo(racingDB, clk)
 	if err := racesRepo.Init(); err != nil {
 		logger.Error("Failed to initialize repository", zap.Error(err))
 		return fmt.Errorf("failed to initialize repository: %w", err)
 	}
 
-	// 3. create acing service，inject logger
+	pricesRepo := db.NewPricesRepo(racingDB)
+	if err := pricesRepo.Init(); err != nil {
+		logger.Error("Failed to initialize prices repository", zap.Error(err))
+		return fmt.Errorf("failed to initialize prices repository: %w", err)
+	}
+
+	if cfg.Cache.TTL > 0 {
+		logger.Info("Caching race lists", zap.Duration("ttl", cfg.Cache.TTL))
+		racesRepo = db.NewCachedRacesRepo(racesRepo, cfg.Cache.TTL, clk)
+	}
+
+	// 4. create racing service，inject clock and logger
 	logger.Info("Creating racing service")
-	racingService := service.NewRacingService(racesRepo, logger)
+	racingService := service.NewRacingService(racesRepo, pricesRepo, venuesRepo, clk, logger)
 
 	logger.Info("Setting up gRPC server")
-	grpcServer := grpc.NewServer()
+	methodRoles := auth.MergeMethodRoles(service.MethodRoles, auth.HealthMethodRoles)
+	if cfg.GRPC.Reflection {
+		methodRoles = auth.MergeMethodRoles(methodRoles, auth.ReflectionMethodRoles)
+	}
+	serverOpts := []grpc.ServerOption{
+		grpc.ConnectionTimeout(cfg.Timeouts.Connection),
+		grpc.ChainUnaryInterceptor(
+			auth.UnaryServerInterceptor(methodRoles, logger),
+			service.UnaryErrorInterceptor(),
+		),
+		grpc.ChainStreamInterceptor(
+			auth.StreamServerInterceptor(methodRoles, logger),
+			service.StreamErrorInterceptor(),
+		),
+	}
+
+	if cfg.TLS.Enabled {
+		reloader, err := tlsutil.NewReloader(cfg.TLS.CertFile, cfg.TLS.KeyFile, cfg.TLS.ClientCAFile, logger)
+		if err != nil {
+			return fmt.Errorf("failed to load TLS certificates: %w", err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go reloader.Watch(ctx, cfg.TLS.ReloadInterval)
+
+		serverOpts = append(serverOpts, grpc.Creds(credentials.NewTLS(reloader.ServerConfig(cfg.TLS.RequireClientCert))))
+		logger.Info("TLS enabled", zap.Bool("mutual_tls", cfg.TLS.RequireClientCert))
+	} else {
+		logger.Warn("TLS disabled, serving plaintext gRPC")
+	}
+
+	grpcServer := grpc.NewServer(serverOpts...)
 
 	racing.RegisterRacingServer(grpcServer, racingService)
 
-	logger.Info("gRPC server listening", zap.String("address", *grpcEndpoint))
+	// Load balancers stop sending traffic to replicas that are not SERVING
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
+
+	if cfg.GRPC.Reflection {
+		reflection.Register(grpcServer)
+		logger.Info("gRPC server reflection enabled")
+	}
+
+	logger.Info("gRPC server listening", zap.String("address", cfg.GRPC.Endpoint))
+
+	return serve(grpcServer, healthServer, conn, cfg.Timeouts.Shutdown, logger)
+}
+
+// serve runs the gRPC server until it fails or the process receives SIGINT/SIGTERM.
+// On a signal, in-flight RPCs are given the shutdown timeout to finish before
+// the server is stopped forcefully.
+func serve(grpcServer *grpc.Server, healthServer *health.Server, conn net.Listener, shutdownTimeout time.Duration, logger *zap.Logger) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- grpcServer.Serve(conn)
+	}()
+
+	select {
+	case err := <-serveErr:
+		if err != nil {
+			logger.Error("gRPC server failed", zap.Error(err))
+			return fmt.Errorf("gRPC server failed: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+	}
+
+	logger.Info("Shutting down gRPC server", zap.Duration("timeout", shutdownTimeout))
+
+	// Report NOT_SERVING first so that clients balancing across replicas
+	// move new calls elsewhere while in-flight ones drain
+	healthServer.Shutdown()
+
+	stopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(shutdownTimeout):
+		logger.Warn("Graceful shutdown timed out, forcing stop")
+		grpcServer.Stop()
+	}
+
+	return nil
+}
+
+// seed generates the races described by the seed subcommand arguments.
+func seed(args []string) error {
+	cfg, err := config.LoadSeed(args)
+	if err != nil {
+		return err
+	}
+
+	database, err := sql.Open("sqlite3", cfg.DSN)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer database.Close()
+
+	started := time.Now()
+	races, err := db.Seed(database, db.SeedOptions{
+		RandSeed:        cfg.RandSeed,
+		Meetings:        cfg.Meetings,
+		RacesPerMeeting: cfg.RacesPerMeeting,
+		Start:           cfg.StartTime(started),
+		Window:          cfg.Window,
+		VisibleRatio:    cfg.VisibleRatio,
+		Reset:           cfg.Reset,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Seeded %d races at %d meetings into %s in %s\n", races, cfg.Meetings, cfg.DSN, time.Since(started).Round(time.Millisecond))
+	return nil
+}
+
+// importFeed loads the races of the feed named by the import subcommand
+// arguments, printing the rows that could not be imported.
+func importFeed(args []string) error {
+	cfg, err := config.LoadImport(args)
+	if err != nil {
+		return err
+	}
+
+	var format feed.Format
+	if cfg.Format != "" {
+		format, err = feed.ParseFormat(cfg.Format)
+	} else {
+		format, err = feed.FormatOf(cfg.File)
+	}
+	if err != nil {
+		return err
+	}
+
+	in := os.Stdin
+	if cfg.File != "-" {
+		if in, err = os.Open(cfg.File); err != nil {
+			return fmt.Errorf("failed to open feed: %w", err)
+		}
+		defer in.Close()
+	}
 
-	if err := grpcServer.Serve(conn); err != nil {
-		logger.Error("gRPC server failed", zap.Error(err))
-		return fmt.Errorf("gRPC server failed: %w", err)
+	records, err := feed.ReadRaces(in, format)
+	if err != nil {
+		return err
+	}
+
+	database, err := sql.Open("sqlite3", cfg.DSN)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
 	}
+	defer database.Close()
 
+	// Init would seed dummy races alongside the feed
+	if err := db.CreateTables(database); err != nil {
+		return fmt.Errorf("failed to create tables: %w", err)
+	}
+
+	resp, err := feed.Import(db.NewRacesRepo(database, nil), records, cfg.Actor, cfg.DryRun)
+	if err != nil {
+		return err
+	}
+
+	for _, importErr := range resp.Errors {
+		fmt.Fprintf(os.Stderr, "row %d (%s): %s\n", importErr.Row, importErr.SourceId, importErr.Message)
+	}
+
+	verb := "Imported"
+	if !resp.Committed {
+		verb = "Would import"
+	}
+	fmt.Printf("%s %d races from %s into %s: %d created, %d updated\n", verb, resp.Created+resp.Updated, cfg.File, cfg.DSN, resp.Created, resp.Updated)
+
+	if len(resp.Errors) > 0 {
+		return fmt.Errorf("%d of %d rows are invalid, nothing was saved", len(resp.Errors), len(records))
+	}
 	return nil
 }
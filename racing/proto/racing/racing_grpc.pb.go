@@ -20,8 +20,32 @@ const _ = grpc.SupportPackageIsVersion7
 type RacingClient interface {
 	// ListRaces will return a collection of all races.
 	ListRaces(ctx context.Context, in *ListRacesRequest, opts ...grpc.CallOption) (*ListRacesResponse, error)
+	// StreamRaces will return the races ListRaces would, one race per message,
+	// so that lists of any size stay within the message size limit.
+	StreamRaces(ctx context.Context, in *ListRacesRequest, opts ...grpc.CallOption) (Racing_StreamRacesClient, error)
 	// GetRace will return a single race by its ID.
 	GetRace(ctx context.Context, in *GetRaceRequest, opts ...grpc.CallOption) (*GetRaceResponse, error)
+	// BatchGetRaces will return the races with the given IDs in the order they
+	// were requested. IDs that match no race are reported in missing_ids rather
+	// than failing the call.
+	BatchGetRaces(ctx context.Context, in *BatchGetRacesRequest, opts ...grpc.CallOption) (*BatchGetRacesResponse, error)
+	// UpdatePrices will record new fixed win and place odds for runners of a
+	// race. Earlier prices are kept as the runners' price history. Scratched
+	// runners cannot be priced.
+	UpdatePrices(ctx context.Context, in *UpdatePricesRequest, opts ...grpc.CallOption) (*UpdatePricesResponse, error)
+	// GetPrices will return the current prices of the runners of a race, and
+	// their price history if asked for.
+	GetPrices(ctx context.Context, in *GetPricesRequest, opts ...grpc.CallOption) (*GetPricesResponse, error)
+	// ListVenues will return every venue races are run at.
+	ListVenues(ctx context.Context, in *ListVenuesRequest, opts ...grpc.CallOption) (*ListVenuesResponse, error)
+	// ImportRaces will create or update a stream of races from an external feed,
+	// one race per message, by their source ID. The import is all or nothing:
+	// it is only saved when every record is valid and dry_run is not set.
+	ImportRaces(ctx context.Context, opts ...grpc.CallOption) (Racing_ImportRacesClient, error)
+	// ListRaceHistory will return the recorded changes to a race, oldest first,
+	// with who made each change, when, and the values of the fields before and
+	// after it.
+	ListRaceHistory(ctx context.Context, in *ListRaceHistoryRequest, opts ...grpc.CallOption) (*ListRaceHistoryResponse, error)
 }
 
 type racingClient struct {
@@ -41,6 +65,38 @@ func (c *racingClient) ListRaces(ctx context.Context, in *ListRacesRequest, opts
 	return out, nil
 }
 
+func (c *racingClient) StreamRaces(ctx context.Context, in *ListRacesRequest, opts ...grpc.CallOption) (Racing_StreamRacesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Racing_ServiceDesc.Streams[0], "/racing.Racing/StreamRaces", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &racingStreamRacesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Racing_StreamRacesClient interface {
+	Recv() (*Race, error)
+	grpc.ClientStream
+}
+
+type racingStreamRacesClient struct {
+	grpc.ClientStream
+}
+
+func (x *racingStreamRacesClient) Recv() (*Race, error) {
+	m := new(Race)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 func (c *racingClient) GetRace(ctx context.Context, in *GetRaceRequest, opts ...grpc.CallOption) (*GetRaceResponse, error) {
 	out := new(GetRaceResponse)
 	err := c.cc.Invoke(ctx, "/racing.Racing/GetRace", in, out, opts...)
@@ -50,14 +106,117 @@ func (c *racingClient) GetRace(ctx context.Context, in *GetRaceRequest, opts ...
 	return out, nil
 }
 
+func (c *racingClient) BatchGetRaces(ctx context.Context, in *BatchGetRacesRequest, opts ...grpc.CallOption) (*BatchGetRacesResponse, error) {
+	out := new(BatchGetRacesResponse)
+	err := c.cc.Invoke(ctx, "/racing.Racing/BatchGetRaces", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *racingClient) UpdatePrices(ctx context.Context, in *UpdatePricesRequest, opts ...grpc.CallOption) (*UpdatePricesResponse, error) {
+	out := new(UpdatePricesResponse)
+	err := c.cc.Invoke(ctx, "/racing.Racing/UpdatePrices", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *racingClient) GetPrices(ctx context.Context, in *GetPricesRequest, opts ...grpc.CallOption) (*GetPricesResponse, error) {
+	out := new(GetPricesResponse)
+	err := c.cc.Invoke(ctx, "/racing.Racing/GetPrices", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *racingClient) ListVenues(ctx context.Context, in *ListVenuesRequest, opts ...grpc.CallOption) (*ListVenuesResponse, error) {
+	out := new(ListVenuesResponse)
+	err := c.cc.Invoke(ctx, "/racing.Racing/ListVenues", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *racingClient) ImportRaces(ctx context.Context, opts ...grpc.CallOption) (Racing_ImportRacesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Racing_ServiceDesc.Streams[1], "/racing.Racing/ImportRaces", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &racingImportRacesClient{stream}
+	return x, nil
+}
+
+type Racing_ImportRacesClient interface {
+	Send(*ImportRacesRequest) error
+	CloseAndRecv() (*ImportRacesResponse, error)
+	grpc.ClientStream
+}
+
+type racingImportRacesClient struct {
+	grpc.ClientStream
+}
+
+func (x *racingImportRacesClient) Send(m *ImportRacesRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *racingImportRacesClient) CloseAndRecv() (*ImportRacesResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(ImportRacesResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *racingClient) ListRaceHistory(ctx context.Context, in *ListRaceHistoryRequest, opts ...grpc.CallOption) (*ListRaceHistoryResponse, error) {
+	out := new(ListRaceHistoryResponse)
+	err := c.cc.Invoke(ctx, "/racing.Racing/ListRaceHistory", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // RacingServer is the server API for Racing service.
 // All implementations should embed UnimplementedRacingServer
 // for forward compatibility
 type RacingServer interface {
 	// ListRaces will return a collection of all races.
 	ListRaces(context.Context, *ListRacesRequest) (*ListRacesResponse, error)
+	// StreamRaces will return the races ListRaces would, one race per message,
+	// so that lists of any size stay within the message size limit.
+	StreamRaces(*ListRacesRequest, Racing_StreamRacesServer) error
 	// GetRace will return a single race by its ID.
 	GetRace(context.Context, *GetRaceRequest) (*GetRaceResponse, error)
+	// BatchGetRaces will return the races with the given IDs in the order they
+	// were requested. IDs that match no race are reported in missing_ids rather
+	// than failing the call.
+	BatchGetRaces(context.Context, *BatchGetRacesRequest) (*BatchGetRacesResponse, error)
+	// UpdatePrices will record new fixed win and place odds for runners of a
+	// race. Earlier prices are kept as the runners' price history. Scratched
+	// runners cannot be priced.
+	UpdatePrices(context.Context, *UpdatePricesRequest) (*UpdatePricesResponse, error)
+	// GetPrices will return the current prices of the runners of a race, and
+	// their price history if asked for.
+	GetPrices(context.Context, *GetPricesRequest) (*GetPricesResponse, error)
+	// ListVenues will return every venue races are run at.
+	ListVenues(context.Context, *ListVenuesRequest) (*ListVenuesResponse, error)
+	// ImportRaces will create or update a stream of races from an external feed,
+	// one race per message, by their source ID. The import is all or nothing:
+	// it is only saved when every record is valid and dry_run is not set.
+	ImportRaces(Racing_ImportRacesServer) error
+	// ListRaceHistory will return the recorded changes to a race, oldest first,
+	// with who made each change, when, and the values of the fields before and
+	// after it.
+	ListRaceHistory(context.Context, *ListRaceHistoryRequest) (*ListRaceHistoryResponse, error)
 }
 
 // UnimplementedRacingServer should be embedded to have forward compatible implementations.
@@ -67,9 +226,30 @@ type UnimplementedRacingServer struct {
 func (UnimplementedRacingServer) ListRaces(context.Context, *ListRacesRequest) (*ListRacesResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ListRaces not implemented")
 }
+func (UnimplementedRacingServer) StreamRaces(*ListRacesRequest, Racing_StreamRacesServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamRaces not implemented")
+}
 func (UnimplementedRacingServer) GetRace(context.Context, *GetRaceRequest) (*GetRaceResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetRace not implemented")
 }
+func (UnimplementedRacingServer) BatchGetRaces(context.Context, *BatchGetRacesRequest) (*BatchGetRacesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BatchGetRaces not implemented")
+}
+func (UnimplementedRacingServer) UpdatePrices(context.Context, *UpdatePricesRequest) (*UpdatePricesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdatePrices not implemented")
+}
+func (UnimplementedRacingServer) GetPrices(context.Context, *GetPricesRequest) (*GetPricesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPrices not implemented")
+}
+func (UnimplementedRacingServer) ListVenues(context.Context, *ListVenuesRequest) (*ListVenuesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListVenues not implemented")
+}
+func (UnimplementedRacingServer) ImportRaces(Racing_ImportRacesServer) error {
+	return status.Errorf(codes.Unimplemented, "method ImportRaces not implemented")
+}
+func (UnimplementedRacingServer) ListRaceHistory(context.Context, *ListRaceHistoryRequest) (*ListRaceHistoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListRaceHistory not implemented")
+}
 
 // UnsafeRacingServer may be embedded to opt out of forward compatibility for this service.
 // Use of this interface is not recommended, as added methods to RacingServer will
@@ -100,6 +280,27 @@ func _Racing_ListRaces_Handler(srv interface{}, ctx context.Context, dec func(in
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Racing_StreamRaces_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ListRacesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(RacingServer).StreamRaces(m, &racingStreamRacesServer{stream})
+}
+
+type Racing_StreamRacesServer interface {
+	Send(*Race) error
+	grpc.ServerStream
+}
+
+type racingStreamRacesServer struct {
+	grpc.ServerStream
+}
+
+func (x *racingStreamRacesServer) Send(m *Race) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 func _Racing_GetRace_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(GetRaceRequest)
 	if err := dec(in); err != nil {
@@ -118,6 +319,122 @@ func _Racing_GetRace_Handler(srv interface{}, ctx context.Context, dec func(inte
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Racing_BatchGetRaces_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchGetRacesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RacingServer).BatchGetRaces(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/racing.Racing/BatchGetRaces",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RacingServer).BatchGetRaces(ctx, req.(*BatchGetRacesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Racing_UpdatePrices_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdatePricesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RacingServer).UpdatePrices(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/racing.Racing/UpdatePrices",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RacingServer).UpdatePrices(ctx, req.(*UpdatePricesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Racing_GetPrices_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPricesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RacingServer).GetPrices(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/racing.Racing/GetPrices",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RacingServer).GetPrices(ctx, req.(*GetPricesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Racing_ListVenues_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListVenuesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RacingServer).ListVenues(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/racing.Racing/ListVenues",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RacingServer).ListVenues(ctx, req.(*ListVenuesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Racing_ImportRaces_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(RacingServer).ImportRaces(&racingImportRacesServer{stream})
+}
+
+type Racing_ImportRacesServer interface {
+	SendAndClose(*ImportRacesResponse) error
+	Recv() (*ImportRacesRequest, error)
+	grpc.ServerStream
+}
+
+type racingImportRacesServer struct {
+	grpc.ServerStream
+}
+
+func (x *racingImportRacesServer) SendAndClose(m *ImportRacesResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *racingImportRacesServer) Recv() (*ImportRacesRequest, error) {
+	m := new(ImportRacesRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _Racing_ListRaceHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRaceHistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RacingServer).ListRaceHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/racing.Racing/ListRaceHistory",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RacingServer).ListRaceHistory(ctx, req.(*ListRaceHistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Racing_ServiceDesc is the grpc.ServiceDesc for Racing service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -133,7 +450,38 @@ var Racing_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetRace",
 			Handler:    _Racing_GetRace_Handler,
 		},
+		{
+			MethodName: "BatchGetRaces",
+			Handler:    _Racing_BatchGetRaces_Handler,
+		},
+		{
+			MethodName: "UpdatePrices",
+			Handler:    _Racing_UpdatePrices_Handler,
+		},
+		{
+			MethodName: "GetPrices",
+			Handler:    _Racing_GetPrices_Handler,
+		},
+		{
+			MethodName: "ListVenues",
+			Handler:    _Racing_ListVenues_Handler,
+		},
+		{
+			MethodName: "ListRaceHistory",
+			Handler:    _Racing_ListRaceHistory_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamRaces",
+			Handler:       _Racing_StreamRaces_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "ImportRaces",
+			Handler:       _Racing_ImportRaces_Handler,
+			ClientStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "racing/racing.proto",
 }
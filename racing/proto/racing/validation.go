@@ -2,6 +2,8 @@ package racing
 
 import (
 	"fmt"
+	"math"
+	"strings"
 )
 
 const (
@@ -9,8 +11,209 @@ const (
 	MaxMeetingIDs = 100
 	// MaxMeetingID defines the maximum value for a single meeting ID
 	MaxMeetingID = 999999
+	// MaxVenueIDs defines the maximum number of venue IDs allowed in a single request
+	MaxVenueIDs = MaxMeetingIDs
+	// MaxBatchIDs defines the maximum number of race IDs allowed in a single batch get
+	MaxBatchIDs = MaxMeetingIDs
+	// MaxPriceUpdates defines the maximum number of prices allowed in a single update
+	MaxPriceUpdates = 50
+	// MinOdds defines the shortest decimal odds a runner may be priced at
+	MinOdds = 1.01
+	// MaxImportRaces defines the maximum number of races in a single import
+	MaxImportRaces = 10000
+	// MaxSourceIDLength defines the maximum length for the source ID of an imported race
+	MaxSourceIDLength = 100
+	// MaxRaceNameLength defines the maximum length for the name of an imported race
+	MaxRaceNameLength = 100
+	// MaxRaceNumber defines the largest number of a race at its meeting
+	MaxRaceNumber = 99
+	// MaxListLimit defines the largest limit of a single list
+	MaxListLimit = 1000
 )
 
+// RaceHistoryFields are the fields of a race whose changes are recorded in
+// its history, in the order they are listed in a change.
+var RaceHistoryFields = []string{"meeting_id", "name", "number", "visible", "advertised_start_time"}
+
+// FieldError is a validation failure of a single request field. Validation
+// errors wrap it, so callers can find the offending field with errors.As.
+type FieldError struct {
+	// Field is the path of the field in the request, e.g. "filter.meeting_ids".
+	Field string
+	// Description says what is wrong with the field.
+	Description string
+}
+
+func (e *FieldError) Error() string {
+	return e.Description
+}
+
+// fieldErrorf returns a FieldError for field with a formatted description.
+func fieldErrorf(field, format string, args ...interface{}) error {
+	return &FieldError{Field: field, Description: fmt.Sprintf(format, args...)}
+}
+
+// Validate validates the requested race IDs
+func (r *BatchGetRacesRequest) Validate() error {
+	if err := r.validateIds(); err != nil {
+		return fmt.Errorf("ids validation failed: %w", err)
+	}
+	return nil
+}
+
+// validateIds validates the race IDs constraints
+func (r *BatchGetRacesRequest) validateIds() error {
+	if len(r.Ids) == 0 {
+		return fieldErrorf("ids", "at least one ID is required")
+	}
+
+	if len(r.Ids) > MaxBatchIDs {
+		return fieldErrorf("ids", "too many IDs: got %d, max allowed %d",
+			len(r.Ids), MaxBatchIDs)
+	}
+
+	seen := make(map[int64]bool)
+	for i, id := range r.Ids {
+		if id <= 0 {
+			return fieldErrorf("ids", "invalid race ID at position %d: %d (must be positive)", i, id)
+		}
+
+		if seen[id] {
+			return fieldErrorf("ids", "duplicate race ID: %d", id)
+		}
+		seen[id] = true
+	}
+
+	return nil
+}
+
+// Validate validates the race ID and the new prices
+func (r *UpdatePricesRequest) Validate() error {
+	if r.RaceId <= 0 {
+		return fieldErrorf("race_id", "race ID must be greater than 0")
+	}
+
+	if err := r.validatePrices(); err != nil {
+		return fmt.Errorf("prices validation failed: %w", err)
+	}
+	return nil
+}
+
+// validatePrices validates the runner numbers and odds of each price
+func (r *UpdatePricesRequest) validatePrices() error {
+	if len(r.Prices) == 0 {
+		return fieldErrorf("prices", "at least one price is required")
+	}
+
+	if len(r.Prices) > MaxPriceUpdates {
+		return fieldErrorf("prices", "too many prices: got %d, max allowed %d",
+			len(r.Prices), MaxPriceUpdates)
+	}
+
+	seen := make(map[int64]bool)
+	for i, price := range r.Prices {
+		if price.RunnerNumber <= 0 {
+			return fieldErrorf(fmt.Sprintf("prices[%d].runner_number", i),
+				"invalid runner number: %d (must be positive)", price.RunnerNumber)
+		}
+
+		if seen[price.RunnerNumber] {
+			return fieldErrorf(fmt.Sprintf("prices[%d].runner_number", i),
+				"duplicate runner number: %d", price.RunnerNumber)
+		}
+		seen[price.RunnerNumber] = true
+
+		if err := validateOdds(fmt.Sprintf("prices[%d].win", i), price.Win); err != nil {
+			return err
+		}
+		if err := validateOdds(fmt.Sprintf("prices[%d].place", i), price.Place); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateOdds checks that odds are finite and at least MinOdds
+func validateOdds(field string, odds float64) error {
+	if math.IsNaN(odds) || math.IsInf(odds, 0) {
+		return fieldErrorf(field, "odds must be a finite number")
+	}
+
+	if odds < MinOdds {
+		return fieldErrorf(field, "odds too short: %g (min: %g)", odds, MinOdds)
+	}
+
+	return nil
+}
+
+// Validate validates the race ID
+func (r *GetPricesRequest) Validate() error {
+	if r.RaceId <= 0 {
+		return fieldErrorf("race_id", "race ID must be greater than 0")
+	}
+	return nil
+}
+
+// Validate validates the race ID and the field the history is limited to
+func (r *ListRaceHistoryRequest) Validate() error {
+	if r.RaceId <= 0 {
+		return fieldErrorf("race_id", "race ID must be greater than 0")
+	}
+	if r.Field == "" {
+		return nil
+	}
+	for _, field := range RaceHistoryFields {
+		if r.Field == field {
+			return nil
+		}
+	}
+	return fieldErrorf("field", "invalid field: %q (must be one of %s)", r.Field, strings.Join(RaceHistoryFields, ", "))
+}
+
+// Validate validates a race of an import feed
+func (r *ImportRacesRequest) Validate() error {
+	if strings.TrimSpace(r.SourceId) == "" {
+		return fieldErrorf("source_id", "source ID is required")
+	}
+
+	if len(r.SourceId) > MaxSourceIDLength {
+		return fieldErrorf("source_id", "source ID too long: %d characters (max: %d)",
+			len(r.SourceId), MaxSourceIDLength)
+	}
+
+	if r.MeetingId <= 0 || r.MeetingId > MaxMeetingID {
+		return fieldErrorf("meeting_id", "invalid meeting ID: %d (must be from 1 to %d)", r.MeetingId, MaxMeetingID)
+	}
+
+	if strings.TrimSpace(r.Name) == "" {
+		return fieldErrorf("name", "name is required")
+	}
+
+	if len(r.Name) > MaxRaceNameLength {
+		return fieldErrorf("name", "name too long: %d characters (max: %d)", len(r.Name), MaxRaceNameLength)
+	}
+
+	if r.Number <= 0 || r.Number > MaxRaceNumber {
+		return fieldErrorf("number", "invalid race number: %d (must be from 1 to %d)", r.Number, MaxRaceNumber)
+	}
+
+	if r.AdvertisedStartTime == nil {
+		return fieldErrorf("advertised_start_time", "advertised start time is required")
+	}
+
+	if err := r.AdvertisedStartTime.CheckValid(); err != nil {
+		return fieldErrorf("advertised_start_time", "invalid advertised start time: %v", err)
+	}
+
+	return nil
+}
+
+// Validate validates the ListVenues request, which has no fields yet
+func (r *ListVenuesRequest) Validate() error {
+	return nil
+}
+
 // Validate validates the entire request
 func (r *ListRacesRequest) Validate() error {
 	if r.Filter != nil {
@@ -25,6 +228,10 @@ func (f *ListRacesRequestFilter) Validate() error {
 		return fmt.Errorf("meeting_ids validation failed: %w", err)
 	}
 
+	if err := f.validateVenueIds(); err != nil {
+		return fmt.Errorf("venue_ids validation failed: %w", err)
+	}
+
 	if err := f.validateVisibleOnly(); err != nil {
 		return fmt.Errorf("visible_only validation failed: %w", err)
 	}
@@ -33,29 +240,63 @@ func (f *ListRacesRequestFilter) Validate() error {
 		return fmt.Errorf("sorting validation failed: %w", err)
 	}
 
+	if err := validateLimit(f.Limit); err != nil {
+		return fmt.Errorf("limit validation failed: %w", err)
+	}
+
+	return nil
+}
+
+// validateLimit validates the limit of a list, if set
+func validateLimit(limit *int32) error {
+	if limit != nil && (*limit < 1 || *limit > MaxListLimit) {
+		return fieldErrorf("filter.limit", "invalid limit: %d (must be between 1 and %d)", *limit, MaxListLimit)
+	}
 	return nil
 }
 
 // validateMeetingIds validates meeting IDs constraints
 func (f *ListRacesRequestFilter) validateMeetingIds() error {
 	if len(f.MeetingIds) > MaxMeetingIDs {
-		return fmt.Errorf("too many meeting IDs: got %d, max allowed %d",
+		return fieldErrorf("filter.meeting_ids", "too many meeting IDs: got %d, max allowed %d",
 			len(f.MeetingIds), MaxMeetingIDs)
 	}
 
 	seen := make(map[int64]bool)
 	for i, id := range f.MeetingIds {
 		if id <= 0 {
-			return fmt.Errorf("invalid meeting ID at position %d: %d (must be positive)", i, id)
+			return fieldErrorf("filter.meeting_ids", "invalid meeting ID at position %d: %d (must be positive)", i, id)
 		}
 
 		if id > MaxMeetingID {
-			return fmt.Errorf("meeting ID too large at position %d: %d (max: %d)",
+			return fieldErrorf("filter.meeting_ids", "meeting ID too large at position %d: %d (max: %d)",
 				i, id, MaxMeetingID)
 		}
 
 		if seen[id] {
-			return fmt.Errorf("duplicate meeting ID: %d", id)
+			return fieldErrorf("filter.meeting_ids", "duplicate meeting ID: %d", id)
+		}
+		seen[id] = true
+	}
+
+	return nil
+}
+
+// validateVenueIds validates venue IDs constraints
+func (f *ListRacesRequestFilter) validateVenueIds() error {
+	if len(f.VenueIds) > MaxVenueIDs {
+		return fieldErrorf("filter.venue_ids", "too many venue IDs: got %d, max allowed %d",
+			len(f.VenueIds), MaxVenueIDs)
+	}
+
+	seen := make(map[int64]bool)
+	for i, id := range f.VenueIds {
+		if id <= 0 {
+			return fieldErrorf("filter.venue_ids", "invalid venue ID at position %d: %d (must be positive)", i, id)
+		}
+
+		if seen[id] {
+			return fieldErrorf("filter.venue_ids", "duplicate venue ID: %d", id)
 		}
 		seen[id] = true
 	}
@@ -76,7 +317,7 @@ func (f *ListRacesRequestFilter) validateSorting() error {
 		case SortField_ADVERTISED_START_TIME, SortField_NAME, SortField_NUMBER:
 			// Valid sort fields
 		default:
-			return fmt.Errorf("invalid sort field: %v", *f.SortField)
+			return fieldErrorf("filter.sort_field", "invalid sort field: %v", *f.SortField)
 		}
 	}
 
@@ -86,7 +327,7 @@ func (f *ListRacesRequestFilter) validateSorting() error {
 		case SortDirection_ASC, SortDirection_DESC:
 			// Valid sort directions
 		default:
-			return fmt.Errorf("invalid sort direction: %v", *f.SortDirection)
+			return fieldErrorf("filter.sort_direction", "invalid sort direction: %v", *f.SortDirection)
 		}
 	}
 
@@ -1,8 +1,13 @@
 package racing
 
 import (
+	"errors"
+	"math"
 	"strings"
 	"testing"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 func TestListRacesRequestFilter_Validate(t *testing.T) {
@@ -53,6 +58,22 @@ func TestListRacesRequestFilter_Validate(t *testing.T) {
 			wantErr: true,
 			errMsg:  "duplicate meeting ID: 1",
 		},
+		{
+			name: "valid venue ids",
+			filter: &ListRacesRequestFilter{
+				VenueIds:     []int64{1, 5},
+				TodayAtVenue: boolPtr(true),
+			},
+			wantErr: false,
+		},
+		{
+			name: "duplicate venue ids",
+			filter: &ListRacesRequestFilter{
+				VenueIds: []int64{2, 2},
+			},
+			wantErr: true,
+			errMsg:  "duplicate venue ID: 2",
+		},
 		{
 			name: "meeting id too large",
 			filter: &ListRacesRequestFilter{
@@ -76,6 +97,30 @@ func TestListRacesRequestFilter_Validate(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "open races with a limit",
+			filter: &ListRacesRequestFilter{
+				OpenOnly: boolPtr(true),
+				Limit:    int32Ptr(MaxListLimit),
+			},
+			wantErr: false,
+		},
+		{
+			name: "zero limit",
+			filter: &ListRacesRequestFilter{
+				Limit: int32Ptr(0),
+			},
+			wantErr: true,
+			errMsg:  "invalid limit: 0",
+		},
+		{
+			name: "limit too large",
+			filter: &ListRacesRequestFilter{
+				Limit: int32Ptr(MaxListLimit + 1),
+			},
+			wantErr: true,
+			errMsg:  "invalid limit: 1001",
+		},
 		{
 			name: "valid sort field - ADVERTISED_START_TIME",
 			filter: &ListRacesRequestFilter{
@@ -147,6 +192,266 @@ func TestListRacesRequestFilter_Validate(t *testing.T) {
 	}
 }
 
+func TestBatchGetRacesRequest_Validate(t *testing.T) {
+	tooMany := make([]int64, MaxBatchIDs+1)
+	for i := range tooMany {
+		tooMany[i] = int64(i + 1)
+	}
+
+	tests := []struct {
+		name    string
+		ids     []int64
+		wantErr bool
+		errMsg  string
+	}{
+		{
+			name: "valid ids",
+			ids:  []int64{3, 1, 2},
+		},
+		{
+			name:    "no ids",
+			wantErr: true,
+			errMsg:  "at least one ID is required",
+		},
+		{
+			name:    "too many ids",
+			ids:     tooMany,
+			wantErr: true,
+			errMsg:  "too many IDs: got 101, max allowed 100",
+		},
+		{
+			name:    "zero id",
+			ids:     []int64{1, 0},
+			wantErr: true,
+			errMsg:  "invalid race ID at position 1: 0",
+		},
+		{
+			name:    "duplicate ids",
+			ids:     []int64{1, 2, 1},
+			wantErr: true,
+			errMsg:  "duplicate race ID: 1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := (&BatchGetRacesRequest{Ids: tt.ids}).Validate()
+
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+					t.Errorf("Validate() error = %v, want error containing %q", err, tt.errMsg)
+				}
+			} else if err != nil {
+				t.Errorf("Validate() error = %v, want nil", err)
+			}
+		})
+	}
+}
+
+func TestUpdatePricesRequest_Validate(t *testing.T) {
+	tooMany := make([]*PriceUpdate, MaxPriceUpdates+1)
+	for i := range tooMany {
+		tooMany[i] = &PriceUpdate{RunnerNumber: int64(i + 1), Win: 2, Place: 1.5}
+	}
+
+	tests := []struct {
+		name    string
+		req     *UpdatePricesRequest
+		wantErr bool
+		errMsg  string
+	}{
+		{
+			name: "valid prices",
+			req: &UpdatePricesRequest{RaceId: 1, Prices: []*PriceUpdate{
+				{RunnerNumber: 1, Win: 3.5, Place: 1.6},
+				{RunnerNumber: 2, Win: MinOdds, Place: MinOdds},
+			}},
+		},
+		{
+			name:    "invalid race id",
+			req:     &UpdatePricesRequest{Prices: []*PriceUpdate{{RunnerNumber: 1, Win: 2, Place: 2}}},
+			wantErr: true,
+			errMsg:  "race ID must be greater than 0",
+		},
+		{
+			name:    "no prices",
+			req:     &UpdatePricesRequest{RaceId: 1},
+			wantErr: true,
+			errMsg:  "at least one price is required",
+		},
+		{
+			name:    "too many prices",
+			req:     &UpdatePricesRequest{RaceId: 1, Prices: tooMany},
+			wantErr: true,
+			errMsg:  "too many prices: got 51, max allowed 50",
+		},
+		{
+			name:    "invalid runner number",
+			req:     &UpdatePricesRequest{RaceId: 1, Prices: []*PriceUpdate{{Win: 2, Place: 2}}},
+			wantErr: true,
+			errMsg:  "invalid runner number: 0",
+		},
+		{
+			name: "duplicate runner",
+			req: &UpdatePricesRequest{RaceId: 1, Prices: []*PriceUpdate{
+				{RunnerNumber: 4, Win: 2, Place: 2},
+				{RunnerNumber: 4, Win: 3, Place: 2},
+			}},
+			wantErr: true,
+			errMsg:  "duplicate runner number: 4",
+		},
+		{
+			name:    "win odds too short",
+			req:     &UpdatePricesRequest{RaceId: 1, Prices: []*PriceUpdate{{RunnerNumber: 1, Win: 1.005, Place: 1.5}}},
+			wantErr: true,
+			errMsg:  "odds too short: 1.005 (min: 1.01)",
+		},
+		{
+			name:    "missing place odds",
+			req:     &UpdatePricesRequest{RaceId: 1, Prices: []*PriceUpdate{{RunnerNumber: 1, Win: 2}}},
+			wantErr: true,
+			errMsg:  "odds too short: 0 (min: 1.01)",
+		},
+		{
+			name:    "infinite odds",
+			req:     &UpdatePricesRequest{RaceId: 1, Prices: []*PriceUpdate{{RunnerNumber: 1, Win: math.Inf(1), Place: 2}}},
+			wantErr: true,
+			errMsg:  "odds must be a finite number",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.req.Validate()
+
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+					t.Errorf("Validate() error = %v, want error containing %q", err, tt.errMsg)
+				}
+			} else if err != nil {
+				t.Errorf("Validate() error = %v, want nil", err)
+			}
+		})
+	}
+}
+
+func TestImportRacesRequest_Validate(t *testing.T) {
+	valid := func() *ImportRacesRequest {
+		return &ImportRacesRequest{
+			SourceId:            "feed-1",
+			MeetingId:           1,
+			Name:                "Melbourne Cup",
+			Number:              7,
+			AdvertisedStartTime: timestamppb.New(time.Date(2026, 11, 3, 4, 0, 0, 0, time.UTC)),
+		}
+	}
+
+	tests := []struct {
+		name      string
+		modify    func(r *ImportRacesRequest)
+		wantField string
+		errMsg    string
+	}{
+		{name: "valid race", modify: func(r *ImportRacesRequest) {}},
+		{name: "missing source id", modify: func(r *ImportRacesRequest) { r.SourceId = " " }, wantField: "source_id", errMsg: "source ID is required"},
+		{name: "long source id", modify: func(r *ImportRacesRequest) { r.SourceId = strings.Repeat("x", MaxSourceIDLength+1) }, wantField: "source_id", errMsg: "source ID too long"},
+		{name: "invalid meeting", modify: func(r *ImportRacesRequest) { r.MeetingId = 0 }, wantField: "meeting_id", errMsg: "invalid meeting ID: 0"},
+		{name: "meeting too large", modify: func(r *ImportRacesRequest) { r.MeetingId = MaxMeetingID + 1 }, wantField: "meeting_id", errMsg: "invalid meeting ID"},
+		{name: "missing name", modify: func(r *ImportRacesRequest) { r.Name = "" }, wantField: "name", errMsg: "name is required"},
+		{name: "long name", modify: func(r *ImportRacesRequest) { r.Name = strings.Repeat("x", MaxRaceNameLength+1) }, wantField: "name", errMsg: "name too long"},
+		{name: "invalid number", modify: func(r *ImportRacesRequest) { r.Number = MaxRaceNumber + 1 }, wantField: "number", errMsg: "invalid race number: 100"},
+		{name: "missing start time", modify: func(r *ImportRacesRequest) { r.AdvertisedStartTime = nil }, wantField: "advertised_start_time", errMsg: "advertised start time is required"},
+		{name: "invalid start time", modify: func(r *ImportRacesRequest) { r.AdvertisedStartTime.Nanos = -1 }, wantField: "advertised_start_time", errMsg: "invalid advertised start time"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := valid()
+			tt.modify(req)
+
+			err := req.Validate()
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v, want nil", err)
+				}
+				return
+			}
+
+			var fieldErr *FieldError
+			if !errors.As(err, &fieldErr) || fieldErr.Field != tt.wantField || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Validate() error = %v, want a %s error containing %q", err, tt.wantField, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestValidate_FieldErrors(t *testing.T) {
+	invalidSort := SortField(42)
+
+	tests := []struct {
+		name      string
+		validate  func() error
+		wantField string
+	}{
+		{
+			name:      "meeting ids",
+			validate:  (&ListRacesRequest{Filter: &ListRacesRequestFilter{MeetingIds: []int64{-1}}}).Validate,
+			wantField: "filter.meeting_ids",
+		},
+		{
+			name:      "venue ids",
+			validate:  (&ListRacesRequest{Filter: &ListRacesRequestFilter{VenueIds: make([]int64, MaxVenueIDs+1)}}).Validate,
+			wantField: "filter.venue_ids",
+		},
+		{
+			name:      "sort field",
+			validate:  (&ListRacesRequest{Filter: &ListRacesRequestFilter{SortField: &invalidSort}}).Validate,
+			wantField: "filter.sort_field",
+		},
+		{
+			name:      "price odds",
+			validate:  (&UpdatePricesRequest{RaceId: 1, Prices: []*PriceUpdate{{RunnerNumber: 1, Win: 2}, {RunnerNumber: 2, Win: 1, Place: 2}}}).Validate,
+			wantField: "prices[0].place",
+		},
+		{
+			name:      "prices race id",
+			validate:  (&GetPricesRequest{}).Validate,
+			wantField: "race_id",
+		},
+		{
+			name:      "batch ids",
+			validate:  (&BatchGetRacesRequest{}).Validate,
+			wantField: "ids",
+		},
+		{
+			name:      "history race id",
+			validate:  (&ListRaceHistoryRequest{Field: "name"}).Validate,
+			wantField: "race_id",
+		},
+		{
+			name:      "history field",
+			validate:  (&ListRaceHistoryRequest{RaceId: 1, Field: "status"}).Validate,
+			wantField: "field",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fieldErr *FieldError
+			if err := tt.validate(); !errors.As(err, &fieldErr) {
+				t.Fatalf("Validate() error = %v, want a FieldError", err)
+			}
+			if fieldErr.Field != tt.wantField {
+				t.Errorf("FieldError.Field = %q, want %q", fieldErr.Field, tt.wantField)
+			}
+		})
+	}
+}
+
+func int32Ptr(n int32) *int32 {
+	return &n
+}
+
 func boolPtr(b bool) *bool {
 	return &b
 }
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"git.neds.sh/matty/entain/racing/db"
+	"git.neds.sh/matty/entain/racing/proto/racing"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryErrorInterceptor converts the errors returned by the service into gRPC
+// statuses, so callers get a meaningful code instead of UNKNOWN:
+//
+//   - validation failures become INVALID_ARGUMENT, with the offending field
+//     attached as a google.rpc.BadRequest detail
+//   - missing races become NOT_FOUND
+//   - cancelled or expired requests become CANCELLED or DEADLINE_EXCEEDED
+//
+// Errors that already carry a status, and any others, are returned unchanged.
+func UnaryErrorInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		if err != nil {
+			return nil, toStatus(err)
+		}
+		return resp, nil
+	}
+}
+
+// StreamErrorInterceptor converts the errors returned by streaming RPCs into
+// gRPC statuses, as UnaryErrorInterceptor does for unary ones.
+func StreamErrorInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := handler(srv, ss); err != nil {
+			return toStatus(err)
+		}
+		return nil
+	}
+}
+
+// toStatus maps err to a gRPC status error, keeping its message.
+func toStatus(err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	var fieldErr *racing.FieldError
+	switch {
+	case errors.As(err, &fieldErr):
+		st := status.New(codes.InvalidArgument, err.Error())
+		detailed, detailErr := st.WithDetails(&errdetails.BadRequest{
+			FieldViolations: []*errdetails.BadRequest_FieldViolation{
+				{Field: fieldErr.Field, Description: fieldErr.Description},
+			},
+		})
+		if detailErr != nil {
+			return st.Err()
+		}
+		return detailed.Err()
+	case errors.Is(err, db.ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	}
+	return err
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"git.neds.sh/matty/entain/racing/db"
+	"git.neds.sh/matty/entain/racing/proto/racing"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/testing/protocmp"
+)
+
+func TestUnaryErrorInterceptor(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		wantCode    codes.Code
+		wantMessage string
+		wantDetails []interface{}
+	}{
+		{
+			name:        "validation failure",
+			err:         (&racing.ListRacesRequest{Filter: &racing.ListRacesRequestFilter{MeetingIds: []int64{1, 1}}}).Validate(),
+			wantCode:    codes.InvalidArgument,
+			wantMessage: "meeting_ids validation failed: duplicate meeting ID: 1",
+			wantDetails: []interface{}{&errdetails.BadRequest{
+				FieldViolations: []*errdetails.BadRequest_FieldViolation{
+					{Field: "filter.meeting_ids", Description: "duplicate meeting ID: 1"},
+				},
+			}},
+		},
+		{
+			name:        "not found",
+			err:         fmt.Errorf("failed to retrieve race: race with ID 7 %w", db.ErrNotFound),
+			wantCode:    codes.NotFound,
+			wantMessage: "failed to retrieve race: race with ID 7 not found",
+		},
+		{
+			name:        "cancelled",
+			err:         fmt.Errorf("request cancelled: %w", context.Canceled),
+			wantCode:    codes.Canceled,
+			wantMessage: "request cancelled: context canceled",
+		},
+		{
+			name:        "existing status",
+			err:         status.Error(codes.PermissionDenied, "method requires role \"trader\""),
+			wantCode:    codes.PermissionDenied,
+			wantMessage: "method requires role \"trader\"",
+		},
+		{
+			name:        "other errors",
+			err:         errors.New("failed to retrieve races: database is locked"),
+			wantCode:    codes.Unknown,
+			wantMessage: "failed to retrieve races: database is locked",
+		},
+	}
+
+	interceptor := UnaryErrorInterceptor()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := func(context.Context, interface{}) (interface{}, error) { return nil, tt.err }
+
+			_, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{}, handler)
+
+			st := status.Convert(err)
+			if st.Code() != tt.wantCode {
+				t.Errorf("code = %v, want %v", st.Code(), tt.wantCode)
+			}
+			if st.Message() != tt.wantMessage {
+				t.Errorf("message = %q, want %q", st.Message(), tt.wantMessage)
+			}
+			if diff := cmp.Diff(tt.wantDetails, st.Details(), protocmp.Transform(), cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("details mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
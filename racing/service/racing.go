@@ -2,13 +2,35 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 
 	"git.neds.sh/matty/entain/racing/db"
+	"git.neds.sh/matty/entain/racing/internal/auth"
+	"git.neds.sh/matty/entain/racing/internal/clock"
+	"git.neds.sh/matty/entain/racing/internal/feed"
 	"git.neds.sh/matty/entain/racing/proto/racing"
 	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
 )
 
+// MethodRoles maps each Racing RPC to the role required to call it.
+// Reads are open to everyone, but callers without auth.RoleTrader only ever
+// see visible races.
+var MethodRoles = map[string]string{
+	"/racing.Racing/ListRaces":     auth.RoleAnonymous,
+	"/racing.Racing/StreamRaces":   auth.RoleAnonymous,
+	"/racing.Racing/GetRace":       auth.RoleAnonymous,
+	"/racing.Racing/BatchGetRaces": auth.RoleAnonymous,
+	"/racing.Racing/UpdatePrices":  auth.RoleTrader,
+	"/racing.Racing/GetPrices":     auth.RoleAnonymous,
+	"/racing.Racing/ListVenues":    auth.RoleAnonymous,
+	"/racing.Racing/ImportRaces":   auth.RoleTrader,
+	// The history names who changed a race, and lists hidden races too
+	"/racing.Racing/ListRaceHistory": auth.RoleTrader,
+}
+
 // Racing defines the interface for racing-related operations.
 // It provides methods to interact with racing data and retrieve race information.
 type Racing interface {
@@ -18,26 +40,69 @@ type Racing interface {
 	// Returns a response with the filtered races or an error if the operation fails.
 	ListRaces(ctx context.Context, in *racing.ListRacesRequest) (*racing.ListRacesResponse, error)
 
+	// StreamRaces sends the races ListRaces would return one at a time, as
+	// they are read, so that lists of any size can be exported.
+	StreamRaces(in *racing.ListRacesRequest, stream racing.Racing_StreamRacesServer) error
+
 	// GetRace retrieves a single race by its ID.
 	// It accepts a context for request lifecycle management and cancellation,
 	// and a request containing the race ID to retrieve.
 	// Returns a response with the race or an error if the operation fails.
 	GetRace(ctx context.Context, in *racing.GetRaceRequest) (*racing.GetRaceResponse, error)
+
+	// BatchGetRaces retrieves several races by ID with a single query.
+	// Races are returned in the order of the requested IDs, and IDs that match
+	// no race are listed in the response's missing IDs instead of failing the call.
+	BatchGetRaces(ctx context.Context, in *racing.BatchGetRacesRequest) (*racing.BatchGetRacesResponse, error)
+
+	// UpdatePrices records new fixed odds for runners of a race, stamped with
+	// the current time. Scratched runners and runners not in the race are
+	// rejected, and no price is recorded unless all of them are valid.
+	// Returns the runners with their current prices after the update.
+	UpdatePrices(ctx context.Context, in *racing.UpdatePricesRequest) (*racing.UpdatePricesResponse, error)
+
+	// GetPrices retrieves the runners of a race with their current prices,
+	// and all their earlier prices if the request asks for the history.
+	GetPrices(ctx context.Context, in *racing.GetPricesRequest) (*racing.GetPricesResponse, error)
+
+	// ListVenues retrieves every venue with its time zone.
+	ListVenues(ctx context.Context, in *racing.ListVenuesRequest) (*racing.ListVenuesResponse, error)
+
+	// ImportRaces creates or updates the races streamed by the caller by their
+	// source ID. Every record is validated, and the races are saved in one
+	// transaction only when all of them are valid and it is not a dry run.
+	// Invalid records are reported in the response rather than failing the call.
+	ImportRaces(stream racing.Racing_ImportRacesServer) error
+
+	// ListRaceHistory retrieves the recorded changes to a race, oldest first,
+	// optionally only those to a single field.
+	ListRaceHistory(ctx context.Context, in *racing.ListRaceHistoryRequest) (*racing.ListRaceHistoryResponse, error)
 }
 
 type racingService struct {
-	racesRepo db.RacesRepo
-	logger    *zap.Logger
+	racesRepo  db.RacesRepo
+	pricesRepo db.PricesRepo
+	venuesRepo db.VenuesRepo
+	clock      clock.Clock
+	logger     *zap.Logger
 }
 
-// NewRacingService creates a new racing service with injected logger
-func NewRacingService(racesRepo db.RacesRepo, logger *zap.Logger) Racing {
+// NewRacingService creates a new racing service with injected clock and
+// logger. Prices are stamped with the time of clk, which defaults to the
+// system clock when nil.
+func NewRacingService(racesRepo db.RacesRepo, pricesRepo db.PricesRepo, venuesRepo db.VenuesRepo, clk clock.Clock, logger *zap.Logger) Racing {
+	if clk == nil {
+		clk = clock.System
+	}
 	if logger == nil {
 		logger = zap.NewNop()
 	}
 	return &racingService{
-		racesRepo: racesRepo,
-		logger:    logger,
+		racesRepo:  racesRepo,
+		pricesRepo: pricesRepo,
+		venuesRepo: venuesRepo,
+		clock:      clk,
+		logger:     logger,
 	}
 }
 
@@ -80,10 +145,16 @@ func (s *racingService) ListRaces(ctx context.Context, in *racing.ListRacesReque
 		return nil, fmt.Errorf("validation failed: %w", err)
 	}
 
+	// Hidden races are only visible to traders, whatever the filter asks for
+	filter := in.Filter
+	if !auth.FromContext(ctx).HasRole(auth.RoleTrader) {
+		filter = visibleOnly(filter)
+	}
+
 	reqLogger.Debug("Calling repository")
 
 	// Call repository
-	races, err := s.racesRepo.List(in.Filter)
+	races, err := s.racesRepo.List(filter)
 	if err != nil {
 		reqLogger.Error("Repository call failed",
 			zap.Error(err),
@@ -129,7 +200,7 @@ func (s *racingService) GetRace(ctx context.Context, in *racing.GetRaceRequest)
 		reqLogger.Warn("Request validation failed: invalid race ID",
 			zap.Int64("race_id", in.Id),
 		)
-		return nil, fmt.Errorf("race ID must be greater than 0")
+		return nil, &racing.FieldError{Field: "id", Description: "race ID must be greater than 0"}
 	}
 
 	reqLogger.Debug("Calling repository")
@@ -143,5 +214,522 @@ func (s *racingService) GetRace(ctx context.Context, in *racing.GetRaceRequest)
 		return nil, fmt.Errorf("failed to retrieve race: %w", err)
 	}
 
-	return &racing.GetRaceResponse{Race: race}, nil
+	// Report hidden races as missing so their existence is not leaked
+	if !race.Visible && !auth.FromContext(ctx).HasRole(auth.RoleTrader) {
+		reqLogger.Debug("Hidden race requested without trader role")
+		return nil, fmt.Errorf("failed to retrieve race: race with ID %d %w", in.Id, db.ErrNotFound)
+	}
+
+	resp := &racing.GetRaceResponse{Race: race}
+	if in.IncludePrices {
+		runners, err := s.pricesRepo.Get(in.Id, false)
+		if err != nil {
+			reqLogger.Error("Repository call failed",
+				zap.Error(err),
+			)
+			return nil, fmt.Errorf("failed to retrieve prices: %w", err)
+		}
+		resp.Runners = runners
+	}
+
+	return resp, nil
+}
+
+func (s *racingService) BatchGetRaces(ctx context.Context, in *racing.BatchGetRacesRequest) (*racing.BatchGetRacesResponse, error) {
+	reqLogger := s.logger.With(
+		zap.String("method", "BatchGetRaces"),
+		zap.Int("id_count", len(in.GetIds())),
+	)
+
+	reqLogger.Debug("Request started")
+
+	// Context validation
+	if ctx == nil {
+		reqLogger.Error("Context validation failed: nil context")
+		return nil, fmt.Errorf("context cannot be nil")
+	}
+
+	// Check if context is cancelled
+	select {
+	case <-ctx.Done():
+		reqLogger.Warn("Request cancelled",
+			zap.Error(ctx.Err()),
+		)
+		return nil, fmt.Errorf("request cancelled: %w", ctx.Err())
+	default:
+		// Continue processing
+	}
+
+	// Input validation
+	if in == nil {
+		reqLogger.Warn("Request validation failed: nil request")
+		return nil, fmt.Errorf("request cannot be nil")
+	}
+
+	if err := in.Validate(); err != nil {
+		reqLogger.Warn("Request validation failed",
+			zap.Error(err),
+		)
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+
+	reqLogger.Debug("Calling repository")
+
+	// Call repository
+	races, err := s.racesRepo.GetByIDs(in.Ids)
+	if err != nil {
+		reqLogger.Error("Repository call failed",
+			zap.Error(err),
+		)
+		return nil, fmt.Errorf("failed to retrieve races: %w", err)
+	}
+
+	// Hidden races are reported as missing, as GetRace does
+	isTrader := auth.FromContext(ctx).HasRole(auth.RoleTrader)
+	found := make(map[int64]bool, len(races))
+	resp := &racing.BatchGetRacesResponse{}
+	for _, race := range races {
+		if !race.Visible && !isTrader {
+			continue
+		}
+		found[race.Id] = true
+		resp.Races = append(resp.Races, race)
+	}
+	for _, id := range in.Ids {
+		if !found[id] {
+			resp.MissingIds = append(resp.MissingIds, id)
+		}
+	}
+
+	return resp, nil
+}
+
+func (s *racingService) StreamRaces(in *racing.ListRacesRequest, stream racing.Racing_StreamRacesServer) error {
+	reqLogger := s.logger.With(
+		zap.String("method", "StreamRaces"),
+	)
+
+	reqLogger.Debug("Request started", zap.Any("filter", in.GetFilter()))
+
+	ctx := stream.Context()
+
+	// Check if context is cancelled
+	select {
+	case <-ctx.Done():
+		reqLogger.Warn("Request cancelled",
+			zap.Error(ctx.Err()),
+		)
+		return fmt.Errorf("request cancelled: %w", ctx.Err())
+	default:
+		// Continue processing
+	}
+
+	// Input validation
+	if in == nil {
+		reqLogger.Warn("Request validation failed: nil request")
+		return fmt.Errorf("request cannot be nil")
+	}
+
+	// Validate request
+	if err := in.Validate(); err != nil {
+		reqLogger.Warn("Request validation failed",
+			zap.Error(err),
+			zap.Any("filter", in.Filter),
+		)
+		return fmt.Errorf("validation failed: %w", err)
+	}
+
+	// Hidden races are only visible to traders, whatever the filter asks for
+	filter := in.Filter
+	if !auth.FromContext(ctx).HasRole(auth.RoleTrader) {
+		filter = visibleOnly(filter)
+	}
+
+	reqLogger.Debug("Calling repository")
+
+	// Each race is sent as it is read; a failed send ends the read
+	sent := 0
+	err := s.racesRepo.Stream(filter, func(race *racing.Race) error {
+		if err := stream.Send(race); err != nil {
+			return err
+		}
+		sent++
+		return nil
+	})
+	if err != nil {
+		reqLogger.Error("Repository call failed",
+			zap.Int("sent", sent),
+			zap.Error(err),
+		)
+		return fmt.Errorf("failed to stream races: %w", err)
+	}
+
+	reqLogger.Debug("Races streamed", zap.Int("sent", sent))
+
+	return nil
+}
+
+// visibleOnly returns a copy of filter restricted to visible races.
+func visibleOnly(filter *racing.ListRacesRequestFilter) *racing.ListRacesRequestFilter {
+	restricted := &racing.ListRacesRequestFilter{}
+	if filter != nil {
+		restricted = proto.Clone(filter).(*racing.ListRacesRequestFilter)
+	}
+
+	visible := true
+	restricted.VisibleOnly = &visible
+
+	return restricted
+}
+
+func (s *racingService) UpdatePrices(ctx context.Context, in *racing.UpdatePricesRequest) (*racing.UpdatePricesResponse, error) {
+	reqLogger := s.logger.With(
+		zap.String("method", "UpdatePrices"),
+		zap.Int64("race_id", in.GetRaceId()),
+		zap.Int("price_count", len(in.GetPrices())),
+	)
+
+	reqLogger.Debug("Request started")
+
+	// Context validation
+	if ctx == nil {
+		reqLogger.Error("Context validation failed: nil context")
+		return nil, fmt.Errorf("context cannot be nil")
+	}
+
+	// Check if context is cancelled
+	select {
+	case <-ctx.Done():
+		reqLogger.Warn("Request cancelled",
+			zap.Error(ctx.Err()),
+		)
+		return nil, fmt.Errorf("request cancelled: %w", ctx.Err())
+	default:
+		// Continue processing
+	}
+
+	// Input validation
+	if in == nil {
+		reqLogger.Warn("Request validation failed: nil request")
+		return nil, fmt.Errorf("request cannot be nil")
+	}
+
+	if err := in.Validate(); err != nil {
+		reqLogger.Warn("Request validation failed",
+			zap.Error(err),
+		)
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+
+	reqLogger.Debug("Calling repository")
+
+	// The race must exist; only traders may call this method, so hidden races are priced too
+	if _, err := s.racesRepo.GetByID(in.RaceId); err != nil {
+		reqLogger.Warn("Repository call failed",
+			zap.Error(err),
+		)
+		return nil, fmt.Errorf("failed to retrieve race: %w", err)
+	}
+
+	runners, err := s.pricesRepo.Get(in.RaceId, false)
+	if err != nil {
+		reqLogger.Error("Repository call failed",
+			zap.Error(err),
+		)
+		return nil, fmt.Errorf("failed to retrieve runners: %w", err)
+	}
+
+	if err := validateRunners(in.Prices, runners); err != nil {
+		reqLogger.Warn("Request validation failed",
+			zap.Error(err),
+		)
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+
+	if err := s.pricesRepo.Update(in.RaceId, in.Prices, s.clock.Now()); err != nil {
+		reqLogger.Error("Repository call failed",
+			zap.Error(err),
+		)
+		return nil, fmt.Errorf("failed to update prices: %w", err)
+	}
+
+	runners, err = s.pricesRepo.Get(in.RaceId, false)
+	if err != nil {
+		reqLogger.Error("Repository call failed",
+			zap.Error(err),
+		)
+		return nil, fmt.Errorf("failed to retrieve prices: %w", err)
+	}
+
+	reqLogger.Info("Prices updated")
+
+	return &racing.UpdatePricesResponse{Runners: runners}, nil
+}
+
+func (s *racingService) GetPrices(ctx context.Context, in *racing.GetPricesRequest) (*racing.GetPricesResponse, error) {
+	reqLogger := s.logger.With(
+		zap.String("method", "GetPrices"),
+		zap.Int64("race_id", in.GetRaceId()),
+	)
+
+	reqLogger.Debug("Request started")
+
+	// Context validation
+	if ctx == nil {
+		reqLogger.Error("Context validation failed: nil context")
+		return nil, fmt.Errorf("context cannot be nil")
+	}
+
+	// Check if context is cancelled
+	select {
+	case <-ctx.Done():
+		reqLogger.Warn("Request cancelled",
+			zap.Error(ctx.Err()),
+		)
+		return nil, fmt.Errorf("request cancelled: %w", ctx.Err())
+	default:
+		// Continue processing
+	}
+
+	// Input validation
+	if in == nil {
+		reqLogger.Warn("Request validation failed: nil request")
+		return nil, fmt.Errorf("request cannot be nil")
+	}
+
+	if err := in.Validate(); err != nil {
+		reqLogger.Warn("Request validation failed",
+			zap.Error(err),
+		)
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+
+	reqLogger.Debug("Calling repository")
+
+	race, err := s.racesRepo.GetByID(in.RaceId)
+	if err != nil {
+		reqLogger.Error("Repository call failed",
+			zap.Error(err),
+		)
+		return nil, fmt.Errorf("failed to retrieve race: %w", err)
+	}
+
+	// Prices of hidden races are reported as missing, as GetRace does
+	if !race.Visible && !auth.FromContext(ctx).HasRole(auth.RoleTrader) {
+		reqLogger.Debug("Hidden race requested without trader role")
+		return nil, fmt.Errorf("failed to retrieve race: race with ID %d %w", in.RaceId, db.ErrNotFound)
+	}
+
+	runners, err := s.pricesRepo.Get(in.RaceId, in.IncludeHistory)
+	if err != nil {
+		reqLogger.Error("Repository call failed",
+			zap.Error(err),
+		)
+		return nil, fmt.Errorf("failed to retrieve prices: %w", err)
+	}
+
+	return &racing.GetPricesResponse{Runners: runners}, nil
+}
+
+func (s *racingService) ListVenues(ctx context.Context, in *racing.ListVenuesRequest) (*racing.ListVenuesResponse, error) {
+	reqLogger := s.logger.With(
+		zap.String("method", "ListVenues"),
+	)
+
+	reqLogger.Debug("Request started")
+
+	// Context validation
+	if ctx == nil {
+		reqLogger.Error("Context validation failed: nil context")
+		return nil, fmt.Errorf("context cannot be nil")
+	}
+
+	// Check if context is cancelled
+	select {
+	case <-ctx.Done():
+		reqLogger.Warn("Request cancelled",
+			zap.Error(ctx.Err()),
+		)
+		return nil, fmt.Errorf("request cancelled: %w", ctx.Err())
+	default:
+		// Continue processing
+	}
+
+	// Input validation
+	if in == nil {
+		reqLogger.Warn("Request validation failed: nil request")
+		return nil, fmt.Errorf("request cannot be nil")
+	}
+
+	if err := in.Validate(); err != nil {
+		reqLogger.Warn("Request validation failed",
+			zap.Error(err),
+		)
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+
+	reqLogger.Debug("Calling repository")
+
+	venues, err := s.venuesRepo.List()
+	if err != nil {
+		reqLogger.Error("Repository call failed",
+			zap.Error(err),
+		)
+		return nil, fmt.Errorf("failed to retrieve venues: %w", err)
+	}
+
+	return &racing.ListVenuesResponse{Venues: venues}, nil
+}
+
+func (s *racingService) ImportRaces(stream racing.Racing_ImportRacesServer) error {
+	reqLogger := s.logger.With(
+		zap.String("method", "ImportRaces"),
+	)
+
+	reqLogger.Debug("Request started")
+
+	ctx := stream.Context()
+
+	var (
+		records []feed.Record
+		dryRun  bool
+	)
+	for {
+		in, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			reqLogger.Warn("Failed to receive race",
+				zap.Error(err),
+			)
+			return fmt.Errorf("failed to receive race: %w", err)
+		}
+
+		if len(records) == racing.MaxImportRaces {
+			reqLogger.Warn("Request validation failed: too many races")
+			return &racing.FieldError{Field: "races", Description: fmt.Sprintf("too many races: more than %d", racing.MaxImportRaces)}
+		}
+
+		// The dry run flag of the first race applies to the whole import
+		if len(records) == 0 {
+			dryRun = in.DryRun
+		}
+		records = append(records, feed.Record{Row: len(records) + 1, Race: in})
+	}
+
+	// Check if context is cancelled
+	select {
+	case <-ctx.Done():
+		reqLogger.Warn("Request cancelled",
+			zap.Error(ctx.Err()),
+		)
+		return fmt.Errorf("request cancelled: %w", ctx.Err())
+	default:
+		// Continue processing
+	}
+
+	reqLogger.Debug("Calling repository",
+		zap.Int("race_count", len(records)),
+		zap.Bool("dry_run", dryRun),
+	)
+
+	resp, err := feed.Import(s.racesRepo, records, auth.FromContext(ctx).Subject, dryRun)
+	if err != nil {
+		reqLogger.Error("Repository call failed",
+			zap.Error(err),
+		)
+		return err
+	}
+
+	reqLogger.Info("Races imported",
+		zap.String("subject", auth.FromContext(ctx).Subject),
+		zap.Int32("created", resp.Created),
+		zap.Int32("updated", resp.Updated),
+		zap.Int("errors", len(resp.Errors)),
+		zap.Bool("committed", resp.Committed),
+	)
+
+	return stream.SendAndClose(resp)
+}
+
+func (s *racingService) ListRaceHistory(ctx context.Context, in *racing.ListRaceHistoryRequest) (*racing.ListRaceHistoryResponse, error) {
+	reqLogger := s.logger.With(
+		zap.String("method", "ListRaceHistory"),
+		zap.Int64("race_id", in.GetRaceId()),
+	)
+
+	reqLogger.Debug("Request started", zap.String("field", in.GetField()))
+
+	// Context validation
+	if ctx == nil {
+		reqLogger.Error("Context validation failed: nil context")
+		return nil, fmt.Errorf("context cannot be nil")
+	}
+
+	// Check if context is cancelled
+	select {
+	case <-ctx.Done():
+		reqLogger.Warn("Request cancelled",
+			zap.Error(ctx.Err()),
+		)
+		return nil, fmt.Errorf("request cancelled: %w", ctx.Err())
+	default:
+		// Continue processing
+	}
+
+	// Input validation
+	if in == nil {
+		reqLogger.Warn("Request validation failed: nil request")
+		return nil, fmt.Errorf("request cannot be nil")
+	}
+
+	if err := in.Validate(); err != nil {
+		reqLogger.Warn("Request validation failed",
+			zap.Error(err),
+		)
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+
+	reqLogger.Debug("Calling repository")
+
+	// The race must exist, so that an unknown race is not reported as unchanged
+	if _, err := s.racesRepo.GetByID(in.RaceId); err != nil {
+		reqLogger.Error("Repository call failed",
+			zap.Error(err),
+		)
+		return nil, fmt.Errorf("failed to retrieve race: %w", err)
+	}
+
+	changes, err := s.racesRepo.History(in.RaceId, in.Field)
+	if err != nil {
+		reqLogger.Error("Repository call failed",
+			zap.Error(err),
+		)
+		return nil, fmt.Errorf("failed to retrieve race history: %w", err)
+	}
+
+	return &racing.ListRaceHistoryResponse{Changes: changes}, nil
+}
+
+// validateRunners checks that every price is for a runner of the race that
+// has not been scratched.
+func validateRunners(prices []*racing.PriceUpdate, runners []*racing.RunnerPrices) error {
+	byNumber := make(map[int64]*racing.RunnerPrices, len(runners))
+	for _, runner := range runners {
+		byNumber[runner.RunnerNumber] = runner
+	}
+
+	for i, price := range prices {
+		field := fmt.Sprintf("prices[%d].runner_number", i)
+		runner, ok := byNumber[price.RunnerNumber]
+		if !ok {
+			return &racing.FieldError{Field: field, Description: fmt.Sprintf("runner %d is not in the race", price.RunnerNumber)}
+		}
+		if runner.Scratched {
+			return &racing.FieldError{Field: field, Description: fmt.Sprintf("runner %d is scratched and cannot be priced", price.RunnerNumber)}
+		}
+	}
+
+	return nil
 }
@@ -3,15 +3,22 @@ package service
 import (
 	"context"
 	"errors"
+	"io"
 	"strings"
 	"testing"
+	"time"
 
 	"git.neds.sh/matty/entain/racing/db"
+	"git.neds.sh/matty/entain/racing/internal/auth"
+	"git.neds.sh/matty/entain/racing/internal/clock"
 	"git.neds.sh/matty/entain/racing/proto/racing"
 	"github.com/google/go-cmp/cmp"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zaptest"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 // testRacesRepo is a simple mock implementation for testing
@@ -20,6 +27,9 @@ type testRacesRepo struct {
 	err        error
 	lastFilter *racing.ListRacesRequestFilter
 	initCalled bool
+	imported   []*racing.ImportRacesRequest
+	importedBy string
+	changes    []*racing.RaceChange
 }
 
 // GetByID implements the db.RacesRepo interface for testing.
@@ -32,6 +42,22 @@ func (t *testRacesRepo) GetByID(id int64) (*racing.Race, error) {
 	return nil, errors.New("race not found")
 }
 
+// GetByIDs implements the db.RacesRepo interface for testing.
+func (t *testRacesRepo) GetByIDs(ids []int64) ([]*racing.Race, error) {
+	if t.err != nil {
+		return nil, t.err
+	}
+	var races []*racing.Race
+	for _, id := range ids {
+		for _, race := range t.races {
+			if race.Id == id {
+				races = append(races, race)
+			}
+		}
+	}
+	return races, nil
+}
+
 // List implements the db.RacesRepo interface for testing.
 func (t *testRacesRepo) List(filter *racing.ListRacesRequestFilter) ([]*racing.Race, error) {
 	t.lastFilter = filter
@@ -41,6 +67,54 @@ func (t *testRacesRepo) List(filter *racing.ListRacesRequestFilter) ([]*racing.R
 	return t.races, nil
 }
 
+// Stream implements the db.RacesRepo interface for testing.
+func (t *testRacesRepo) Stream(filter *racing.ListRacesRequestFilter, send func(*racing.Race) error) error {
+	t.lastFilter = filter
+	if t.err != nil {
+		return t.err
+	}
+	for _, race := range t.races {
+		if err := send(race); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Import implements the db.RacesRepo interface for testing, recording the
+// races unless it is a dry run.
+func (t *testRacesRepo) Import(races []*racing.ImportRacesRequest, actor string, dryRun bool) (int, int, error) {
+	if t.err != nil {
+		return 0, 0, t.err
+	}
+	if !dryRun {
+		t.imported = append(t.imported, races...)
+		t.importedBy = actor
+	}
+	return len(races), 0, nil
+}
+
+// History implements the db.RacesRepo interface for testing, returning the
+// changes to the race that set field.
+func (t *testRacesRepo) History(raceID int64, field string) ([]*racing.RaceChange, error) {
+	if t.err != nil {
+		return nil, t.err
+	}
+	var changes []*racing.RaceChange
+	for _, change := range t.changes {
+		if change.RaceId != raceID {
+			continue
+		}
+		for _, fieldChange := range change.Fields {
+			if field == "" || fieldChange.Field == field {
+				changes = append(changes, change)
+				break
+			}
+		}
+	}
+	return changes, nil
+}
+
 func (t *testRacesRepo) Init() error {
 	t.initCalled = true
 	return t.err
@@ -51,6 +125,14 @@ func boolPtr(b bool) *bool {
 	return &b
 }
 
+// traderContext returns a context carrying an identity with the trader role
+func traderContext() context.Context {
+	return auth.NewContext(context.Background(), auth.Identity{
+		Subject: "trader-1",
+		Roles:   []string{auth.RoleTrader},
+	})
+}
+
 // Helper function to create test repository
 func newTestRepo(races []*racing.Race, err error) db.RacesRepo {
 	return &testRacesRepo{
@@ -79,7 +161,7 @@ func TestNewRacingService(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			service := NewRacingService(tt.repo, tt.logger)
+			service := NewRacingService(tt.repo, nil, nil, nil, tt.logger)
 			if service == nil {
 				t.Error("NewRacingService() = nil, want non-nil service")
 			}
@@ -107,7 +189,7 @@ func TestRacingService_ListRaces_Success(t *testing.T) {
 
 	repo := newTestRepo(testRaces, nil)
 	logger := zaptest.NewLogger(t)
-	service := NewRacingService(repo, logger)
+	service := NewRacingService(repo, nil, nil, nil, logger)
 
 	request := &racing.ListRacesRequest{
 		Filter: &racing.ListRacesRequestFilter{
@@ -134,7 +216,7 @@ func TestRacingService_ListRaces_Success(t *testing.T) {
 func TestRacingService_ListRaces_NilRequest(t *testing.T) {
 	repo := newTestRepo(nil, nil)
 	logger := zaptest.NewLogger(t)
-	service := NewRacingService(repo, logger)
+	service := NewRacingService(repo, nil, nil, nil, logger)
 
 	response, err := service.ListRaces(context.Background(), nil)
 
@@ -156,7 +238,7 @@ func TestRacingService_ListRaces_NilRequest(t *testing.T) {
 func TestRacingService_ListRaces_CancelledContext(t *testing.T) {
 	repo := newTestRepo(nil, nil)
 	logger := zaptest.NewLogger(t)
-	service := NewRacingService(repo, logger)
+	service := NewRacingService(repo, nil, nil, nil, logger)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	cancel() // Cancel immediately
@@ -188,7 +270,7 @@ func TestRacingService_ListRaces_NilFilter(t *testing.T) {
 
 	repo := newTestRepo(testRaces, nil)
 	logger := zaptest.NewLogger(t)
-	service := NewRacingService(repo, logger)
+	service := NewRacingService(repo, nil, nil, nil, logger)
 
 	request := &racing.ListRacesRequest{
 		Filter: nil,
@@ -219,7 +301,7 @@ func TestRacingService_ListRaces_EmptyFilter(t *testing.T) {
 
 	repo := newTestRepo(testRaces, nil)
 	logger := zaptest.NewLogger(t)
-	service := NewRacingService(repo, logger)
+	service := NewRacingService(repo, nil, nil, nil, logger)
 
 	request := &racing.ListRacesRequest{
 		Filter: &racing.ListRacesRequestFilter{},
@@ -247,7 +329,7 @@ func TestRacingService_ListRaces_EmptyResults(t *testing.T) {
 
 	repo := newTestRepo(emptyRaces, nil)
 	logger := zaptest.NewLogger(t)
-	service := NewRacingService(repo, logger)
+	service := NewRacingService(repo, nil, nil, nil, logger)
 
 	request := &racing.ListRacesRequest{
 		Filter: &racing.ListRacesRequestFilter{
@@ -277,7 +359,7 @@ func TestRacingService_ListRaces_RepositoryError(t *testing.T) {
 	expectedError := errors.New("database connection failed")
 	repo := newTestRepo(nil, expectedError)
 	logger := zaptest.NewLogger(t)
-	service := NewRacingService(repo, logger)
+	service := NewRacingService(repo, nil, nil, nil, logger)
 
 	request := &racing.ListRacesRequest{
 		Filter: &racing.ListRacesRequestFilter{
@@ -353,11 +435,12 @@ func TestRacingService_ListRaces_FilterPropagation(t *testing.T) {
 				err:   nil,
 			}
 			logger := zaptest.NewLogger(t)
-			service := NewRacingService(testRepo, logger)
+			service := NewRacingService(testRepo, nil, nil, nil, logger)
 
 			request := &racing.ListRacesRequest{Filter: tt.filter}
 
-			_, err := service.ListRaces(context.Background(), request)
+			// Traders get the filter passed through untouched
+			_, err := service.ListRaces(traderContext(), request)
 			if err != nil {
 				t.Errorf("ListRaces() failed: %v", err)
 				return
@@ -370,6 +453,64 @@ func TestRacingService_ListRaces_FilterPropagation(t *testing.T) {
 	}
 }
 
+func TestRacingService_ListRaces_VisibilityByRole(t *testing.T) {
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		filter     *racing.ListRacesRequestFilter
+		wantFilter *racing.ListRacesRequestFilter
+	}{
+		{
+			name:       "anonymous with nil filter only sees visible races",
+			ctx:        context.Background(),
+			filter:     nil,
+			wantFilter: &racing.ListRacesRequestFilter{VisibleOnly: boolPtr(true)},
+		},
+		{
+			name:       "anonymous cannot opt out of visible only",
+			ctx:        context.Background(),
+			filter:     &racing.ListRacesRequestFilter{MeetingIds: []int64{1}, VisibleOnly: boolPtr(false)},
+			wantFilter: &racing.ListRacesRequestFilter{MeetingIds: []int64{1}, VisibleOnly: boolPtr(true)},
+		},
+		{
+			name: "authenticated non-trader only sees visible races",
+			ctx: auth.NewContext(context.Background(), auth.Identity{
+				Subject: "punter-1",
+				Roles:   []string{"customer"},
+			}),
+			filter:     &racing.ListRacesRequestFilter{},
+			wantFilter: &racing.ListRacesRequestFilter{VisibleOnly: boolPtr(true)},
+		},
+		{
+			name:       "trader sees hidden races",
+			ctx:        traderContext(),
+			filter:     &racing.ListRacesRequestFilter{VisibleOnly: boolPtr(false)},
+			wantFilter: &racing.ListRacesRequestFilter{VisibleOnly: boolPtr(false)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testRepo := &testRacesRepo{}
+			service := NewRacingService(testRepo, nil, nil, nil, zaptest.NewLogger(t))
+
+			request := &racing.ListRacesRequest{Filter: tt.filter}
+			if _, err := service.ListRaces(tt.ctx, request); err != nil {
+				t.Fatalf("ListRaces() failed: %v", err)
+			}
+
+			if diff := cmp.Diff(tt.wantFilter, testRepo.lastFilter, protocmp.Transform()); diff != "" {
+				t.Errorf("ListRaces() repository filter mismatch (-want +got):\n%s", diff)
+			}
+
+			// The caller's request must not be modified
+			if diff := cmp.Diff(tt.filter, request.Filter, protocmp.Transform()); diff != "" {
+				t.Errorf("ListRaces() modified request filter (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
 func TestRacingService_ListRaces_ResponseValidation(t *testing.T) {
 	testRaces := []*racing.Race{
 		{
@@ -383,7 +524,7 @@ func TestRacingService_ListRaces_ResponseValidation(t *testing.T) {
 
 	repo := newTestRepo(testRaces, nil)
 	logger := zaptest.NewLogger(t)
-	service := NewRacingService(repo, logger)
+	service := NewRacingService(repo, nil, nil, nil, logger)
 
 	request := &racing.ListRacesRequest{
 		Filter: &racing.ListRacesRequestFilter{},
@@ -425,7 +566,7 @@ func TestRacingService_ListRaces_ResponseValidation(t *testing.T) {
 func TestRacingService_ListRaces_ValidationError(t *testing.T) {
 	repo := newTestRepo(nil, nil)
 	logger := zaptest.NewLogger(t)
-	service := NewRacingService(repo, logger)
+	service := NewRacingService(repo, nil, nil, nil, logger)
 
 	request := &racing.ListRacesRequest{
 		Filter: &racing.ListRacesRequestFilter{
@@ -469,7 +610,7 @@ func BenchmarkRacingService_ListRaces(b *testing.B) {
 
 	repo := newTestRepo(races, nil)
 	logger := zap.NewNop() // Use no-op logger for benchmarks
-	service := NewRacingService(repo, logger)
+	service := NewRacingService(repo, nil, nil, nil, logger)
 	request := &racing.ListRacesRequest{
 		Filter: &racing.ListRacesRequestFilter{
 			VisibleOnly: boolPtr(true),
@@ -551,7 +692,7 @@ func TestRacingService_ListRaces_TableDriven(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			repo := newTestRepo(tt.races, tt.repoError)
 			logger := zaptest.NewLogger(t)
-			service := NewRacingService(repo, logger)
+			service := NewRacingService(repo, nil, nil, nil, logger)
 
 			response, err := service.ListRaces(tt.ctx, tt.request)
 
@@ -592,7 +733,7 @@ func TestRacingService_GetRace_Success(t *testing.T) {
 
 	repo := newTestRepo([]*racing.Race{testRace}, nil)
 	logger := zaptest.NewLogger(t)
-	service := NewRacingService(repo, logger)
+	service := NewRacingService(repo, nil, nil, nil, logger)
 
 	request := &racing.GetRaceRequest{Id: 1}
 
@@ -612,10 +753,35 @@ func TestRacingService_GetRace_Success(t *testing.T) {
 	}
 }
 
+func TestRacingService_GetRace_HiddenRace(t *testing.T) {
+	hiddenRace := &racing.Race{
+		Id:        7,
+		MeetingId: 100,
+		Name:      "Hidden Race",
+		Number:    1,
+		Visible:   false,
+	}
+
+	service := NewRacingService(newTestRepo([]*racing.Race{hiddenRace}, nil), nil, nil, nil, zaptest.NewLogger(t))
+	request := &racing.GetRaceRequest{Id: 7}
+
+	if _, err := service.GetRace(context.Background(), request); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("GetRace() anonymous error = %v, want not found", err)
+	}
+
+	response, err := service.GetRace(traderContext(), request)
+	if err != nil {
+		t.Fatalf("GetRace() trader error = %v, want nil", err)
+	}
+	if diff := cmp.Diff(hiddenRace, response.Race, protocmp.Transform()); diff != "" {
+		t.Errorf("GetRace() trader race mismatch (-want +got):\n%s", diff)
+	}
+}
+
 func TestRacingService_GetRace_NotFound(t *testing.T) {
 	repo := newTestRepo([]*racing.Race{}, nil)
 	logger := zaptest.NewLogger(t)
-	service := NewRacingService(repo, logger)
+	service := NewRacingService(repo, nil, nil, nil, logger)
 
 	request := &racing.GetRaceRequest{Id: 999}
 
@@ -636,10 +802,90 @@ func TestRacingService_GetRace_NotFound(t *testing.T) {
 	}
 }
 
+func TestRacingService_BatchGetRaces(t *testing.T) {
+	races := []*racing.Race{
+		{Id: 1, Name: "Race 1", Visible: true},
+		{Id: 2, Name: "Race 2", Visible: false},
+		{Id: 3, Name: "Race 3", Visible: true},
+	}
+
+	tests := []struct {
+		name        string
+		ctx         context.Context
+		ids         []int64
+		repoErr     error
+		wantIDs     []int64
+		wantMissing []int64
+		wantErr     string
+	}{
+		{
+			name:    "request order is preserved",
+			ctx:     traderContext(),
+			ids:     []int64{3, 1, 2},
+			wantIDs: []int64{3, 1, 2},
+		},
+		{
+			name:        "missing ids are reported",
+			ctx:         traderContext(),
+			ids:         []int64{4, 1, 5},
+			wantIDs:     []int64{1},
+			wantMissing: []int64{4, 5},
+		},
+		{
+			name:        "hidden races are missing for anonymous callers",
+			ctx:         context.Background(),
+			ids:         []int64{2, 3},
+			wantIDs:     []int64{3},
+			wantMissing: []int64{2},
+		},
+		{
+			name:    "invalid ids",
+			ctx:     context.Background(),
+			ids:     []int64{1, 1},
+			wantErr: "duplicate race ID",
+		},
+		{
+			name:    "repository error",
+			ctx:     context.Background(),
+			ids:     []int64{1},
+			repoErr: errors.New("database is locked"),
+			wantErr: "failed to retrieve races",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewRacingService(newTestRepo(races, tt.repoErr), nil, nil, nil, zaptest.NewLogger(t))
+
+			response, err := service.BatchGetRaces(tt.ctx, &racing.BatchGetRacesRequest{Ids: tt.ids})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("BatchGetRaces() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("BatchGetRaces() error = %v, want nil", err)
+			}
+
+			var gotIDs []int64
+			for _, race := range response.Races {
+				gotIDs = append(gotIDs, race.Id)
+			}
+			if diff := cmp.Diff(tt.wantIDs, gotIDs); diff != "" {
+				t.Errorf("BatchGetRaces() race IDs mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.wantMissing, response.MissingIds); diff != "" {
+				t.Errorf("BatchGetRaces() missing IDs mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
 func TestRacingService_GetRace_NilRequest(t *testing.T) {
 	repo := newTestRepo(nil, nil)
 	logger := zaptest.NewLogger(t)
-	service := NewRacingService(repo, logger)
+	service := NewRacingService(repo, nil, nil, nil, logger)
 
 	response, err := service.GetRace(context.Background(), nil)
 
@@ -661,7 +907,7 @@ func TestRacingService_GetRace_NilRequest(t *testing.T) {
 func TestRacingService_GetRace_InvalidID(t *testing.T) {
 	repo := newTestRepo(nil, nil)
 	logger := zaptest.NewLogger(t)
-	service := NewRacingService(repo, logger)
+	service := NewRacingService(repo, nil, nil, nil, logger)
 
 	tests := []struct {
 		name string
@@ -698,7 +944,7 @@ func TestRacingService_GetRace_InvalidID(t *testing.T) {
 func TestRacingService_GetRace_CancelledContext(t *testing.T) {
 	repo := newTestRepo(nil, nil)
 	logger := zaptest.NewLogger(t)
-	service := NewRacingService(repo, logger)
+	service := NewRacingService(repo, nil, nil, nil, logger)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	cancel() // Cancel immediately
@@ -805,7 +1051,7 @@ func TestRacingService_GetRace_TableDriven(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			repo := newTestRepo(tt.races, tt.repoError)
 			logger := zaptest.NewLogger(t)
-			service := NewRacingService(repo, logger)
+			service := NewRacingService(repo, nil, nil, nil, logger)
 
 			response, err := service.GetRace(tt.ctx, tt.request)
 
@@ -849,7 +1095,7 @@ func BenchmarkRacingService_GetRace(b *testing.B) {
 
 	repo := newTestRepo([]*racing.Race{testRace}, nil)
 	logger := zap.NewNop() // Use no-op logger for benchmarks
-	service := NewRacingService(repo, logger)
+	service := NewRacingService(repo, nil, nil, nil, logger)
 	request := &racing.GetRaceRequest{Id: 1}
 
 	b.ResetTimer()
@@ -860,3 +1106,505 @@ func BenchmarkRacingService_GetRace(b *testing.B) {
 		}
 	}
 }
+
+// testPricesRepo is an in-memory db.PricesRepo keyed by race ID
+type testPricesRepo struct {
+	runners map[int64][]*racing.RunnerPrices
+	err     error
+	updates []*racing.PriceUpdate
+	at      time.Time
+}
+
+// Init implements the db.PricesRepo interface for testing.
+func (t *testPricesRepo) Init() error {
+	return t.err
+}
+
+// Get implements the db.PricesRepo interface for testing.
+func (t *testPricesRepo) Get(raceID int64, history bool) ([]*racing.RunnerPrices, error) {
+	if t.err != nil {
+		return nil, t.err
+	}
+	var runners []*racing.RunnerPrices
+	for _, runner := range t.runners[raceID] {
+		clone := proto.Clone(runner).(*racing.RunnerPrices)
+		if !history {
+			clone.History = nil
+		}
+		runners = append(runners, clone)
+	}
+	return runners, nil
+}
+
+// Update implements the db.PricesRepo interface for testing.
+func (t *testPricesRepo) Update(raceID int64, prices []*racing.PriceUpdate, at time.Time) error {
+	t.updates, t.at = prices, at
+	for _, update := range prices {
+		for _, runner := range t.runners[raceID] {
+			if runner.RunnerNumber == update.RunnerNumber {
+				price := &racing.Price{Win: update.Win, Place: update.Place, UpdatedTime: timestamppb.New(at)}
+				runner.Current = price
+				runner.History = append(runner.History, price)
+			}
+		}
+	}
+	return nil
+}
+
+// newTestPricesRepo returns runners 1 and 2 of race 1, and scratched runner 3
+func newTestPricesRepo() *testPricesRepo {
+	opening := &racing.Price{Win: 4, Place: 1.8, UpdatedTime: timestamppb.New(time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC))}
+	return &testPricesRepo{runners: map[int64][]*racing.RunnerPrices{
+		1: {
+			{RunnerNumber: 1, RunnerName: "Phar Lap", Current: opening, History: []*racing.Price{opening}},
+			{RunnerNumber: 2, RunnerName: "Black Caviar"},
+			{RunnerNumber: 3, RunnerName: "Winx", Scratched: true},
+		},
+		2: {
+			{RunnerNumber: 1, RunnerName: "Makybe Diva"},
+		},
+	}}
+}
+
+func TestRacingService_UpdatePrices(t *testing.T) {
+	now := time.Date(2026, 10, 18, 9, 30, 0, 0, time.UTC)
+	races := []*racing.Race{
+		{Id: 1, Name: "Race 1", Visible: true},
+		{Id: 2, Name: "Race 2", Visible: false},
+	}
+
+	tests := []struct {
+		name      string
+		req       *racing.UpdatePricesRequest
+		wantErr   string
+		wantField string
+		want      []*racing.RunnerPrices
+	}{
+		{
+			name: "prices are recorded",
+			req: &racing.UpdatePricesRequest{RaceId: 1, Prices: []*racing.PriceUpdate{
+				{RunnerNumber: 2, Win: 2.5, Place: 1.3},
+				{RunnerNumber: 1, Win: 3.8, Place: 1.7},
+			}},
+			want: []*racing.RunnerPrices{
+				{RunnerNumber: 1, RunnerName: "Phar Lap", Current: &racing.Price{Win: 3.8, Place: 1.7, UpdatedTime: timestamppb.New(now)}},
+				{RunnerNumber: 2, RunnerName: "Black Caviar", Current: &racing.Price{Win: 2.5, Place: 1.3, UpdatedTime: timestamppb.New(now)}},
+				{RunnerNumber: 3, RunnerName: "Winx", Scratched: true},
+			},
+		},
+		{
+			name: "hidden races may be priced",
+			req:  &racing.UpdatePricesRequest{RaceId: 2, Prices: []*racing.PriceUpdate{{RunnerNumber: 1, Win: 11, Place: 3}}},
+			want: []*racing.RunnerPrices{
+				{RunnerNumber: 1, RunnerName: "Makybe Diva", Current: &racing.Price{Win: 11, Place: 3, UpdatedTime: timestamppb.New(now)}},
+			},
+		},
+		{
+			name:      "odds too short",
+			req:       &racing.UpdatePricesRequest{RaceId: 1, Prices: []*racing.PriceUpdate{{RunnerNumber: 1, Win: 1, Place: 1.01}}},
+			wantErr:   "odds too short",
+			wantField: "prices[0].win",
+		},
+		{
+			name: "scratched runner",
+			req: &racing.UpdatePricesRequest{RaceId: 1, Prices: []*racing.PriceUpdate{
+				{RunnerNumber: 1, Win: 3, Place: 1.5},
+				{RunnerNumber: 3, Win: 5, Place: 2},
+			}},
+			wantErr:   "runner 3 is scratched",
+			wantField: "prices[1].runner_number",
+		},
+		{
+			name:      "runner not in the race",
+			req:       &racing.UpdatePricesRequest{RaceId: 1, Prices: []*racing.PriceUpdate{{RunnerNumber: 9, Win: 3, Place: 1.5}}},
+			wantErr:   "runner 9 is not in the race",
+			wantField: "prices[0].runner_number",
+		},
+		{
+			name:    "unknown race",
+			req:     &racing.UpdatePricesRequest{RaceId: 5, Prices: []*racing.PriceUpdate{{RunnerNumber: 1, Win: 3, Place: 1.5}}},
+			wantErr: "failed to retrieve race",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prices := newTestPricesRepo()
+			service := NewRacingService(newTestRepo(races, nil), prices, nil, clock.Fixed(now), zaptest.NewLogger(t))
+
+			response, err := service.UpdatePrices(traderContext(), tt.req)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("UpdatePrices() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				var fieldErr *racing.FieldError
+				if tt.wantField != "" && (!errors.As(err, &fieldErr) || fieldErr.Field != tt.wantField) {
+					t.Errorf("UpdatePrices() error = %v, want a FieldError for %q", err, tt.wantField)
+				}
+				if prices.updates != nil {
+					t.Errorf("UpdatePrices() recorded %v after an error", prices.updates)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("UpdatePrices() error = %v, want nil", err)
+			}
+
+			if diff := cmp.Diff(tt.want, response.Runners, protocmp.Transform()); diff != "" {
+				t.Errorf("UpdatePrices() runners mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRacingService_GetPrices(t *testing.T) {
+	races := []*racing.Race{
+		{Id: 1, Name: "Race 1", Visible: true},
+		{Id: 2, Name: "Race 2", Visible: false},
+	}
+
+	tests := []struct {
+		name        string
+		ctx         context.Context
+		req         *racing.GetPricesRequest
+		wantErr     string
+		wantHistory int
+		wantRunners int
+	}{
+		{
+			name:        "current prices",
+			ctx:         context.Background(),
+			req:         &racing.GetPricesRequest{RaceId: 1},
+			wantRunners: 3,
+		},
+		{
+			name:        "with history",
+			ctx:         context.Background(),
+			req:         &racing.GetPricesRequest{RaceId: 1, IncludeHistory: true},
+			wantRunners: 3,
+			wantHistory: 1,
+		},
+		{
+			name:    "hidden race for anonymous callers",
+			ctx:     context.Background(),
+			req:     &racing.GetPricesRequest{RaceId: 2},
+			wantErr: "not found",
+		},
+		{
+			name:        "hidden race for traders",
+			ctx:         traderContext(),
+			req:         &racing.GetPricesRequest{RaceId: 2},
+			wantRunners: 1,
+		},
+		{
+			name:    "invalid race id",
+			ctx:     context.Background(),
+			req:     &racing.GetPricesRequest{},
+			wantErr: "race ID must be greater than 0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewRacingService(newTestRepo(races, nil), newTestPricesRepo(), nil, nil, zaptest.NewLogger(t))
+
+			response, err := service.GetPrices(tt.ctx, tt.req)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("GetPrices() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetPrices() error = %v, want nil", err)
+			}
+
+			if len(response.Runners) != tt.wantRunners {
+				t.Fatalf("GetPrices() returned %d runners, want %d", len(response.Runners), tt.wantRunners)
+			}
+			if got := len(response.Runners[0].History); got != tt.wantHistory {
+				t.Errorf("GetPrices() returned %d prices of history, want %d", got, tt.wantHistory)
+			}
+		})
+	}
+}
+
+func TestRacingService_GetRace_IncludePrices(t *testing.T) {
+	races := []*racing.Race{{Id: 1, Name: "Race 1", Visible: true}}
+	service := NewRacingService(newTestRepo(races, nil), newTestPricesRepo(), nil, nil, zaptest.NewLogger(t))
+
+	response, err := service.GetRace(context.Background(), &racing.GetRaceRequest{Id: 1})
+	if err != nil {
+		t.Fatalf("GetRace() error = %v, want nil", err)
+	}
+	if response.Runners != nil {
+		t.Errorf("GetRace() without include_prices returned runners %v", response.Runners)
+	}
+
+	response, err = service.GetRace(context.Background(), &racing.GetRaceRequest{Id: 1, IncludePrices: true})
+	if err != nil {
+		t.Fatalf("GetRace() error = %v, want nil", err)
+	}
+	want := newTestPricesRepo().runners[1]
+	want[0].History = nil
+	if diff := cmp.Diff(want, response.Runners, protocmp.Transform()); diff != "" {
+		t.Errorf("GetRace() runners mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// testVenuesRepo is a db.VenuesRepo serving a single venue, or failing with err
+type testVenuesRepo struct {
+	err error
+}
+
+// Init implements the db.VenuesRepo interface for testing.
+func (testVenuesRepo) Init() error { return nil }
+
+// List implements the db.VenuesRepo interface for testing.
+func (r testVenuesRepo) List() ([]*racing.Venue, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	return []*racing.Venue{{Id: 1, Name: "Flemington", TimeZone: "Australia/Melbourne", Country: "AU"}}, nil
+}
+
+func TestRacingService_ListVenues(t *testing.T) {
+	service := NewRacingService(newTestRepo(nil, nil), nil, testVenuesRepo{}, nil, zaptest.NewLogger(t))
+
+	response, err := service.ListVenues(context.Background(), &racing.ListVenuesRequest{})
+	if err != nil {
+		t.Fatalf("ListVenues() error = %v, want nil", err)
+	}
+	want := []*racing.Venue{{Id: 1, Name: "Flemington", TimeZone: "Australia/Melbourne", Country: "AU"}}
+	if diff := cmp.Diff(want, response.Venues, protocmp.Transform()); diff != "" {
+		t.Errorf("ListVenues() mismatch (-want +got):\n%s", diff)
+	}
+
+	service = NewRacingService(newTestRepo(nil, nil), nil, testVenuesRepo{err: errors.New("database is locked")}, nil, zaptest.NewLogger(t))
+	if _, err := service.ListVenues(context.Background(), &racing.ListVenuesRequest{}); err == nil || !strings.Contains(err.Error(), "failed to retrieve venues") {
+		t.Errorf("ListVenues() error = %v, want a repository error", err)
+	}
+}
+
+// testImportStream is a racing.Racing_ImportRacesServer receiving races and
+// keeping the response.
+type testImportStream struct {
+	grpc.ServerStream
+	ctx   context.Context
+	races []*racing.ImportRacesRequest
+	resp  *racing.ImportRacesResponse
+}
+
+func (s *testImportStream) Context() context.Context { return s.ctx }
+
+func (s *testImportStream) Recv() (*racing.ImportRacesRequest, error) {
+	if len(s.races) == 0 {
+		return nil, io.EOF
+	}
+	race := s.races[0]
+	s.races = s.races[1:]
+	return race, nil
+}
+
+func (s *testImportStream) SendAndClose(resp *racing.ImportRacesResponse) error {
+	s.resp = resp
+	return nil
+}
+
+func TestRacingService_ImportRaces(t *testing.T) {
+	start := timestamppb.New(time.Date(2026, 11, 3, 4, 0, 0, 0, time.UTC))
+	valid := func(sourceID string) *racing.ImportRacesRequest {
+		return &racing.ImportRacesRequest{SourceId: sourceID, MeetingId: 1, Name: "Melbourne Cup", Number: 7, AdvertisedStartTime: start}
+	}
+	dryRun := valid("feed-1")
+	dryRun.DryRun = true
+	invalid := valid("feed-2")
+	invalid.Number = 0
+
+	tests := []struct {
+		name         string
+		races        []*racing.ImportRacesRequest
+		want         *racing.ImportRacesResponse
+		wantImported int
+	}{
+		{
+			name:         "valid races are saved",
+			races:        []*racing.ImportRacesRequest{valid("feed-1"), valid("feed-2")},
+			want:         &racing.ImportRacesResponse{Created: 2, Committed: true},
+			wantImported: 2,
+		},
+		{
+			name:  "dry run of the first race applies to all",
+			races: []*racing.ImportRacesRequest{dryRun, valid("feed-2")},
+			want:  &racing.ImportRacesResponse{Created: 2},
+		},
+		{
+			name:  "invalid races are reported and nothing is saved",
+			races: []*racing.ImportRacesRequest{valid("feed-1"), invalid, valid("feed-1")},
+			want: &racing.ImportRacesResponse{Created: 1, Errors: []*racing.ImportError{
+				{Row: 2, SourceId: "feed-2", Field: "number", Message: "invalid race number: 0 (must be from 1 to 99)"},
+				{Row: 3, SourceId: "feed-1", Field: "source_id", Message: "duplicate source ID, first seen on row 1"},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &testRacesRepo{}
+			service := NewRacingService(repo, nil, nil, nil, zaptest.NewLogger(t))
+
+			stream := &testImportStream{ctx: traderContext(), races: tt.races}
+			if err := service.ImportRaces(stream); err != nil {
+				t.Fatalf("ImportRaces() error = %v, want nil", err)
+			}
+
+			if diff := cmp.Diff(tt.want, stream.resp, protocmp.Transform()); diff != "" {
+				t.Errorf("ImportRaces() mismatch (-want +got):\n%s", diff)
+			}
+			if len(repo.imported) != tt.wantImported {
+				t.Errorf("ImportRaces() saved %d races, want %d", len(repo.imported), tt.wantImported)
+			}
+			if tt.wantImported > 0 && repo.importedBy != "trader-1" {
+				t.Errorf("ImportRaces() recorded the races as imported by %q, want the caller", repo.importedBy)
+			}
+		})
+	}
+}
+
+// testRacesStream is a racing.Racing_StreamRacesServer keeping the races sent,
+// and failing once it has sent failAfter of them if that is set.
+type testRacesStream struct {
+	grpc.ServerStream
+	ctx       context.Context
+	races     []*racing.Race
+	failAfter int
+}
+
+func (s *testRacesStream) Context() context.Context { return s.ctx }
+
+func (s *testRacesStream) Send(race *racing.Race) error {
+	if s.failAfter > 0 && len(s.races) == s.failAfter {
+		return errors.New("client went away")
+	}
+	s.races = append(s.races, race)
+	return nil
+}
+
+func TestRacingService_StreamRaces(t *testing.T) {
+	races := []*racing.Race{{Id: 1, Visible: true}, {Id: 2, Visible: true}, {Id: 3, Visible: true}}
+
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		req        *racing.ListRacesRequest
+		repoErr    error
+		failAfter  int
+		wantRaces  int
+		wantFilter *racing.ListRacesRequestFilter
+		wantErr    string
+	}{
+		{
+			name:       "anonymous callers only get visible races",
+			ctx:        context.Background(),
+			req:        &racing.ListRacesRequest{Filter: &racing.ListRacesRequestFilter{MeetingIds: []int64{1}}},
+			wantRaces:  3,
+			wantFilter: &racing.ListRacesRequestFilter{MeetingIds: []int64{1}, VisibleOnly: boolPtr(true)},
+		},
+		{
+			name:       "traders get the filter as sent",
+			ctx:        traderContext(),
+			req:        &racing.ListRacesRequest{},
+			wantRaces:  3,
+			wantFilter: nil,
+		},
+		{
+			name:    "invalid filter",
+			ctx:     context.Background(),
+			req:     &racing.ListRacesRequest{Filter: &racing.ListRacesRequestFilter{MeetingIds: []int64{-1}}},
+			wantErr: "validation failed",
+		},
+		{
+			name:       "repository error",
+			ctx:        context.Background(),
+			req:        &racing.ListRacesRequest{},
+			repoErr:    errors.New("database is locked"),
+			wantFilter: &racing.ListRacesRequestFilter{VisibleOnly: boolPtr(true)},
+			wantErr:    "failed to stream races: database is locked",
+		},
+		{
+			name:       "failed send stops the stream",
+			ctx:        context.Background(),
+			req:        &racing.ListRacesRequest{},
+			failAfter:  2,
+			wantRaces:  2,
+			wantFilter: &racing.ListRacesRequestFilter{VisibleOnly: boolPtr(true)},
+			wantErr:    "client went away",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &testRacesRepo{races: races, err: tt.repoErr}
+			service := NewRacingService(repo, nil, nil, nil, zaptest.NewLogger(t))
+
+			stream := &testRacesStream{ctx: tt.ctx, failAfter: tt.failAfter}
+			err := service.StreamRaces(tt.req, stream)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("StreamRaces() error = %v, want nil", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("StreamRaces() error = %v, want one containing %q", err, tt.wantErr)
+			}
+
+			if len(stream.races) != tt.wantRaces {
+				t.Errorf("StreamRaces() sent %d races, want %d", len(stream.races), tt.wantRaces)
+			}
+			if diff := cmp.Diff(tt.wantFilter, repo.lastFilter, protocmp.Transform()); diff != "" {
+				t.Errorf("StreamRaces() repository filter mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRacingService_ListRaceHistory(t *testing.T) {
+	moved := &racing.RaceChange{Id: 2, RaceId: 1, Actor: "trader-1", Type: racing.ChangeType_CHANGE_UPDATED, Fields: []*racing.FieldChange{
+		{Field: "advertised_start_time", Before: "2026-11-03T04:00:00Z", After: "2026-11-03T04:15:00Z"},
+	}}
+	renamed := &racing.RaceChange{Id: 3, RaceId: 1, Actor: "trader-2", Type: racing.ChangeType_CHANGE_UPDATED, Fields: []*racing.FieldChange{
+		{Field: "name", Before: "Cup", After: "Melbourne Cup"},
+	}}
+
+	tests := []struct {
+		name    string
+		req     *racing.ListRaceHistoryRequest
+		want    []*racing.RaceChange
+		wantErr string
+	}{
+		{name: "every change", req: &racing.ListRaceHistoryRequest{RaceId: 1}, want: []*racing.RaceChange{moved, renamed}},
+		{name: "start time changes", req: &racing.ListRaceHistoryRequest{RaceId: 1, Field: "advertised_start_time"}, want: []*racing.RaceChange{moved}},
+		{name: "unknown race", req: &racing.ListRaceHistoryRequest{RaceId: 9}, wantErr: "race not found"},
+		{name: "invalid field", req: &racing.ListRaceHistoryRequest{RaceId: 1, Field: "status"}, wantErr: "invalid field"},
+		{name: "invalid race id", req: &racing.ListRaceHistoryRequest{}, wantErr: "race ID must be greater than 0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &testRacesRepo{races: []*racing.Race{{Id: 1}}, changes: []*racing.RaceChange{moved, renamed}}
+			service := NewRacingService(repo, nil, nil, nil, zaptest.NewLogger(t))
+
+			response, err := service.ListRaceHistory(traderContext(), tt.req)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("ListRaceHistory() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ListRaceHistory() error = %v, want nil", err)
+			}
+			if diff := cmp.Diff(tt.want, response.Changes, protocmp.Transform()); diff != "" {
+				t.Errorf("ListRaceHistory() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,231 @@
+package db
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+	"google.golang.org/protobuf/proto"
+
+	"git.neds.sh/matty/entain/sports/internal/clock"
+	"git.neds.sh/matty/entain/sports/proto/sports"
+)
+
+// maxCacheEntries bounds the number of distinct filters kept in the cache.
+const maxCacheEntries = 1024
+
+// eventCacheEntry is a cached List result.
+type eventCacheEntry struct {
+	events  []*sports.Event
+	expires time.Time
+}
+
+// CachedEventsRepo is an EventsRepo that serves List from a short-lived
+// read-through cache keyed by the normalised filter. Concurrent misses for the
+// same filter share a single query. Events are cloned on the way out and their
+// status is recomputed, so an event that starts while cached is still reported
+// as closed, or left out of a list of open events. Such a list may then come
+// out shorter than its limit until the entry expires.
+//
+// The underlying repository is deliberately not embedded: every method that
+// writes events must be wrapped here and call Invalidate.
+type CachedEventsRepo struct {
+	repo EventsRepo
+	ttl  time.Duration
+	now  func() time.Time
+
+	group singleflight.Group
+
+	mu         sync.Mutex
+	entries    map[string]eventCacheEntry
+	generation uint64
+}
+
+// NewCachedEventsRepo wraps repo with a cache holding List results for ttl,
+// measured on clk like the status of the events. A nil clk is the system clock.
+func NewCachedEventsRepo(repo EventsRepo, ttl time.Duration, clk clock.Clock) *CachedEventsRepo {
+	if clk == nil {
+		clk = clock.System
+	}
+	return &CachedEventsRepo{
+		repo:    repo,
+		ttl:     ttl,
+		now:     clk.Now,
+		entries: make(map[string]eventCacheEntry),
+	}
+}
+
+// Init initialises the underlying repository.
+func (c *CachedEventsRepo) Init() error {
+	return c.repo.Init()
+}
+
+// List returns the events matching filter, from the cache when possible.
+func (c *CachedEventsRepo) List(filter *sports.ListEventsRequestFilter) ([]*sports.Event, error) {
+	key, err := eventFilterKey(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	generation := c.generation
+	c.mu.Unlock()
+
+	if ok && c.now().Before(entry.expires) {
+		return cloneEvents(entry.events, c.now(), filter.GetOpenOnly()), nil
+	}
+
+	// Callers arriving after an invalidation must not join a query that
+	// started before it, so the generation is part of the flight key.
+	v, err, _ := c.group.Do(fmt.Sprintf("%d/%s", generation, key), func() (interface{}, error) {
+		events, err := c.repo.List(filter)
+		if err != nil {
+			return nil, err
+		}
+
+		c.store(key, generation, events)
+		return events, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return cloneEvents(v.([]*sports.Event), c.now(), filter.GetOpenOnly()), nil
+}
+
+// Stream is not cached; it serves exports, which would fill the cache with
+// events read once.
+func (c *CachedEventsRepo) Stream(filter *sports.ListEventsRequestFilter, send func(*sports.Event) error) error {
+	return c.repo.Stream(filter, send)
+}
+
+// GetByID is not cached; primary key lookups are cheap.
+func (c *CachedEventsRepo) GetByID(id int64) (*sports.Event, error) {
+	return c.repo.GetByID(id)
+}
+
+// GetByIDs is not cached either; it is a single primary key lookup.
+func (c *CachedEventsRepo) GetByIDs(ids []int64) ([]*sports.Event, error) {
+	return c.repo.GetByIDs(ids)
+}
+
+// SetSuspended suspends or resumes an event and invalidates the cache.
+func (c *CachedEventsRepo) SetSuspended(id int64, suspended bool, actor string) error {
+	defer c.Invalidate()
+	return c.repo.SetSuspended(id, suspended, actor)
+}
+
+// Import creates or updates events and, unless it is a dry run, invalidates
+// the cache.
+func (c *CachedEventsRepo) Import(events []*sports.ImportEventsRequest, actor string, dryRun bool) (int, int, error) {
+	if !dryRun {
+		defer c.Invalidate()
+	}
+	return c.repo.Import(events, actor, dryRun)
+}
+
+// History is not cached; it is only read by traders.
+func (c *CachedEventsRepo) History(eventID int64, field string) ([]*sports.EventChange, error) {
+	return c.repo.History(eventID, field)
+}
+
+// Invalidate drops every cached result. Queries already in flight will not
+// populate the cache.
+func (c *CachedEventsRepo) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	c.entries = make(map[string]eventCacheEntry)
+}
+
+// store caches events unless the cache was invalidated since generation.
+func (c *CachedEventsRepo) store(key string, generation uint64, events []*sports.Event) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+
+	now := c.now()
+	if len(c.entries) >= maxCacheEntries {
+		for k, e := range c.entries {
+			if !now.Before(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+		if len(c.entries) >= maxCacheEntries {
+			c.entries = make(map[string]eventCacheEntry)
+		}
+	}
+
+	c.entries[key] = eventCacheEntry{events: events, expires: now.Add(c.ttl)}
+}
+
+// eventFilterKey returns a cache key that is equal for filters selecting the
+// same events in the same order: sport types are sorted and de-duplicated, ID
+// lists are sorted and defaults are made explicit. Marshalling the whole message keeps the key
+// correct when fields are added to the filter.
+func eventFilterKey(filter *sports.ListEventsRequestFilter) (string, error) {
+	normalised := &sports.ListEventsRequestFilter{}
+	if filter != nil {
+		normalised = proto.Clone(filter).(*sports.ListEventsRequestFilter)
+	}
+
+	types := normalised.SportTypes
+	sort.Strings(types)
+	unique := types[:0]
+	for i, sportType := range types {
+		if i == 0 || sportType != types[i-1] {
+			unique = append(unique, sportType)
+		}
+	}
+	normalised.SportTypes = unique
+
+	// The ID lists are validated to be unique, so sorting is enough
+	for _, ids := range [][]int64{normalised.CompetitionIds, normalised.SeasonIds, normalised.TeamIds, normalised.VenueIds} {
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	}
+
+	if normalised.VisibleOnly == nil {
+		visible := false
+		normalised.VisibleOnly = &visible
+	}
+	if normalised.SortField == nil {
+		field := sports.SortField_ADVERTISED_START_TIME
+		normalised.SortField = &field
+	}
+	if normalised.SortDirection == nil {
+		direction := sports.SortDirection_ASC
+		normalised.SortDirection = &direction
+	}
+
+	key, err := proto.MarshalOptions{Deterministic: true}.Marshal(normalised)
+	if err != nil {
+		return "", fmt.Errorf("failed to build cache key: %w", err)
+	}
+	return string(key), nil
+}
+
+// cloneEvents deep-copies events and recomputes their status at now. With
+// openOnly, the events closed at now are left out.
+func cloneEvents(events []*sports.Event, now time.Time, openOnly bool) []*sports.Event {
+	if events == nil {
+		return nil
+	}
+
+	clones := make([]*sports.Event, 0, len(events))
+	for _, event := range events {
+		clone := proto.Clone(event).(*sports.Event)
+		setEventStatus(clone, clone.AdvertisedStartTime.AsTime(), now)
+		if openOnly && clone.Status == sports.EventStatus_CLOSED {
+			continue
+		}
+		clones = append(clones, clone)
+	}
+	return clones
+}
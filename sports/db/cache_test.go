@@ -0,0 +1,339 @@
+package db
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"git.neds.sh/matty/entain/sports/internal/clock"
+	"git.neds.sh/matty/entain/sports/proto/sports"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// countingEventsRepo counts List calls and optionally blocks them until released
+type countingEventsRepo struct {
+	events  []*sports.Event
+	err     error
+	calls   int32
+	release chan struct{}
+}
+
+func (r *countingEventsRepo) Init() error { return nil }
+
+func (r *countingEventsRepo) List(*sports.ListEventsRequestFilter) ([]*sports.Event, error) {
+	atomic.AddInt32(&r.calls, 1)
+	if r.release != nil {
+		<-r.release
+	}
+	return r.events, r.err
+}
+
+func (r *countingEventsRepo) Stream(*sports.ListEventsRequestFilter, func(*sports.Event) error) error {
+	return errors.New("not implemented")
+}
+
+func (r *countingEventsRepo) GetByID(int64) (*sports.Event, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (r *countingEventsRepo) GetByIDs([]int64) ([]*sports.Event, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (r *countingEventsRepo) SetSuspended(int64, bool, string) error {
+	return r.err
+}
+
+func (r *countingEventsRepo) Import(events []*sports.ImportEventsRequest, actor string, dryRun bool) (int, int, error) {
+	return len(events), 0, r.err
+}
+
+func (r *countingEventsRepo) History(int64, string) ([]*sports.EventChange, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (r *countingEventsRepo) callCount() int {
+	return int(atomic.LoadInt32(&r.calls))
+}
+
+// boolPtr returns a pointer to the given bool value
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+// sortFieldPtr returns a pointer to the given SortField value
+func sortFieldPtr(sf sports.SortField) *sports.SortField {
+	return &sf
+}
+
+// sortDirectionPtr returns a pointer to the given SortDirection value
+func sortDirectionPtr(sd sports.SortDirection) *sports.SortDirection {
+	return &sd
+}
+
+// newTestCache returns a cache with a controllable clock
+func newTestCache(repo EventsRepo, ttl time.Duration) (*CachedEventsRepo, *time.Time) {
+	now := time.Unix(1700000000, 0)
+	c := NewCachedEventsRepo(repo, ttl, clock.Func(func() time.Time { return now }))
+	return c, &now
+}
+
+func TestCachedEventsRepo_List(t *testing.T) {
+	repo := &countingEventsRepo{
+		events: []*sports.Event{{Id: 1, Name: "Event 1", AdvertisedStartTime: timestamppb.New(time.Now().Add(time.Hour))}},
+	}
+	cache, now := newTestCache(repo, time.Second)
+
+	steps := []struct {
+		name      string
+		filter    *sports.ListEventsRequestFilter
+		advance   time.Duration
+		wantCalls int
+	}{
+		{"first call misses", &sports.ListEventsRequestFilter{SportTypes: []string{"tennis", "football"}}, 0, 1},
+		{"same filter hits", &sports.ListEventsRequestFilter{SportTypes: []string{"tennis", "football"}}, 0, 1},
+		{"reordered and duplicated sport types hit", &sports.ListEventsRequestFilter{SportTypes: []string{"football", "tennis", "tennis"}}, 0, 1},
+		{"explicit defaults hit", &sports.ListEventsRequestFilter{
+			SportTypes:    []string{"football", "tennis"},
+			VisibleOnly:   boolPtr(false),
+			SortField:     sortFieldPtr(sports.SortField_ADVERTISED_START_TIME),
+			SortDirection: sortDirectionPtr(sports.SortDirection_ASC),
+		}, 0, 1},
+		{"different filter misses", &sports.ListEventsRequestFilter{SportTypes: []string{"football", "tennis"}, VisibleOnly: boolPtr(true)}, 0, 2},
+		{"nil and empty filters share a key", nil, 0, 3},
+		{"empty filter hits", &sports.ListEventsRequestFilter{}, 0, 3},
+		{"expired entry misses", &sports.ListEventsRequestFilter{}, time.Second, 4},
+		{"team filter misses", &sports.ListEventsRequestFilter{TeamIds: []int64{3, 1}}, 0, 5},
+		{"reordered teams hit", &sports.ListEventsRequestFilter{TeamIds: []int64{1, 3}}, 0, 5},
+		{"same IDs as competitions miss", &sports.ListEventsRequestFilter{CompetitionIds: []int64{1, 3}}, 0, 6},
+		{"venue filter misses", &sports.ListEventsRequestFilter{VenueIds: []int64{5, 2}}, 0, 7},
+		{"reordered venues hit", &sports.ListEventsRequestFilter{VenueIds: []int64{2, 5}}, 0, 7},
+	}
+
+	for _, step := range steps {
+		*now = now.Add(step.advance)
+
+		got, err := cache.List(step.filter)
+		if err != nil {
+			t.Fatalf("%s: List() failed: %v", step.name, err)
+		}
+		if diff := cmp.Diff(repo.events, got, protocmp.Transform(), protocmp.IgnoreFields(&sports.Event{}, "status")); diff != "" {
+			t.Errorf("%s: List() mismatch (-want +got):\n%s", step.name, diff)
+		}
+		if calls := repo.callCount(); calls != step.wantCalls {
+			t.Errorf("%s: repository called %d times, want %d", step.name, calls, step.wantCalls)
+		}
+	}
+}
+
+func TestCachedEventsRepo_List_RecomputesStatus(t *testing.T) {
+	repo := &countingEventsRepo{}
+	cache, now := newTestCache(repo, time.Hour)
+	repo.events = []*sports.Event{{
+		Id:                  1,
+		Status:              sports.EventStatus_OPEN,
+		AdvertisedStartTime: timestamppb.New(now.Add(time.Second)),
+	}}
+
+	// The event is cached as open, then its start time passes
+	steps := []struct {
+		advance time.Duration
+		want    sports.EventStatus
+	}{
+		{0, sports.EventStatus_OPEN},
+		{time.Second, sports.EventStatus_OPEN},
+		{time.Nanosecond, sports.EventStatus_CLOSED},
+	}
+	for i, step := range steps {
+		*now = now.Add(step.advance)
+
+		got, err := cache.List(nil)
+		if err != nil {
+			t.Fatalf("List() failed: %v", err)
+		}
+		if got[0].Status != step.want {
+			t.Errorf("List() call %d status = %v, want %v", i, got[0].Status, step.want)
+		}
+
+		// Callers must not be able to modify the cached events
+		got[0].Name = "modified"
+	}
+
+	if repo.events[0].Name != "" {
+		t.Errorf("cached event was modified through a returned value")
+	}
+}
+
+func TestCachedEventsRepo_List_OpenOnly(t *testing.T) {
+	repo := &countingEventsRepo{}
+	cache, now := newTestCache(repo, time.Hour)
+	repo.events = []*sports.Event{
+		{Id: 1, AdvertisedStartTime: timestamppb.New(now.Add(time.Second))},
+		{Id: 2, AdvertisedStartTime: timestamppb.New(now.Add(time.Minute))},
+	}
+	open := true
+	filter := &sports.ListEventsRequestFilter{OpenOnly: &open}
+
+	// The first event starts while cached, so it drops out of the open events
+	for i, want := range [][]int64{{1, 2}, {2}} {
+		got, err := cache.List(filter)
+		if err != nil {
+			t.Fatalf("List() failed: %v", err)
+		}
+		var ids []int64
+		for _, event := range got {
+			ids = append(ids, event.Id)
+		}
+		if diff := cmp.Diff(want, ids); diff != "" {
+			t.Errorf("List() call %d event IDs mismatch (-want +got):\n%s", i, diff)
+		}
+		*now = now.Add(2 * time.Second)
+	}
+
+	if repo.callCount() != 1 {
+		t.Errorf("repository called %d times, want 1", repo.callCount())
+	}
+}
+
+func TestCachedEventsRepo_List_ErrorsAreNotCached(t *testing.T) {
+	repo := &countingEventsRepo{err: errors.New("database is locked")}
+	cache, _ := newTestCache(repo, time.Hour)
+
+	for i := 0; i < 2; i++ {
+		if _, err := cache.List(nil); err == nil {
+			t.Fatalf("List() call %d returned no error", i)
+		}
+	}
+	if calls := repo.callCount(); calls != 2 {
+		t.Errorf("repository called %d times, want 2", calls)
+	}
+}
+
+func TestCachedEventsRepo_Invalidate(t *testing.T) {
+	repo := &countingEventsRepo{}
+	cache, _ := newTestCache(repo, time.Hour)
+
+	cache.List(nil)
+	cache.Invalidate()
+	cache.List(nil)
+
+	if calls := repo.callCount(); calls != 2 {
+		t.Errorf("repository called %d times after invalidation, want 2", calls)
+	}
+}
+
+func TestCachedEventsRepo_SetSuspended_Invalidates(t *testing.T) {
+	repo := &countingEventsRepo{}
+	cache, _ := newTestCache(repo, time.Hour)
+
+	cache.List(nil)
+	if err := cache.SetSuspended(1, true, "trader-1"); err != nil {
+		t.Fatalf("SetSuspended() failed: %v", err)
+	}
+	cache.List(nil)
+
+	if calls := repo.callCount(); calls != 2 {
+		t.Errorf("repository called %d times after suspending an event, want 2", calls)
+	}
+}
+
+func TestCachedEventsRepo_Import_Invalidates(t *testing.T) {
+	tests := []struct {
+		name      string
+		dryRun    bool
+		wantCalls int
+	}{
+		{name: "import", wantCalls: 2},
+		{name: "dry run", dryRun: true, wantCalls: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &countingEventsRepo{}
+			cache, _ := newTestCache(repo, time.Hour)
+
+			cache.List(nil)
+			if _, _, err := cache.Import([]*sports.ImportEventsRequest{{SourceId: "feed-1"}}, "trader-1", tt.dryRun); err != nil {
+				t.Fatalf("Import() failed: %v", err)
+			}
+			cache.List(nil)
+
+			if calls := repo.callCount(); calls != tt.wantCalls {
+				t.Errorf("repository called %d times after the import, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestCachedEventsRepo_Invalidate_DuringQuery(t *testing.T) {
+	repo := &countingEventsRepo{release: make(chan struct{})}
+	cache, _ := newTestCache(repo, time.Hour)
+
+	done := make(chan struct{})
+	go func() {
+		cache.List(nil)
+		close(done)
+	}()
+
+	waitForCalls(t, repo, 1)
+	cache.Invalidate()
+	close(repo.release)
+	<-done
+
+	// The result read before the invalidation must not have been cached
+	cache.List(nil)
+	if calls := repo.callCount(); calls != 2 {
+		t.Errorf("repository called %d times, want 2", calls)
+	}
+}
+
+func TestCachedEventsRepo_List_CoalescesConcurrentMisses(t *testing.T) {
+	repo := &countingEventsRepo{
+		events:  []*sports.Event{{Id: 1, AdvertisedStartTime: timestamppb.Now()}},
+		release: make(chan struct{}),
+	}
+	cache, _ := newTestCache(repo, time.Hour)
+
+	const callers = 10
+	var wg sync.WaitGroup
+	results := make([][]*sports.Event, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], _ = cache.List(&sports.ListEventsRequestFilter{})
+		}(i)
+	}
+
+	waitForCalls(t, repo, 1)
+	// Give the remaining callers time to join the in-flight query
+	time.Sleep(50 * time.Millisecond)
+	close(repo.release)
+	wg.Wait()
+
+	if calls := repo.callCount(); calls != 1 {
+		t.Errorf("repository called %d times for concurrent misses, want 1", calls)
+	}
+	for i, got := range results {
+		if len(got) != 1 {
+			t.Errorf("caller %d got %d events, want 1", i, len(got))
+		}
+	}
+}
+
+// waitForCalls waits until the repository has been called n times
+func waitForCalls(t *testing.T, repo *countingEventsRepo, n int) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for repo.callCount() < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("repository was called %d times, want %d", repo.callCount(), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -1,36 +1,232 @@
 package db
 
 import (
-	"time"
+	"database/sql"
+	"math"
+	"strconv"
+	"strings"
 
 	"syreclabs.com/go/faker"
+
+	"git.neds.sh/matty/entain/sports/proto/sports"
 )
 
 func (r *eventsRepo) seed() error {
-	statement, err := r.db.Prepare(`CREATE TABLE IF NOT EXISTS events (id INTEGER PRIMARY KEY, name TEXT, advertised_start_time DATETIME, sport_type TEXT, venue TEXT, visible INTEGER)`)
-	if err == nil {
-		_, err = statement.Exec()
+	// Events are seeded around the time of the repository clock
+	_, err := Seed(r.db, DefaultSeedOptions(r.clock.Now()))
+	return err
+}
+
+// CreateTables creates the tables events are stored in, without the dummy events
+// Init seeds them with, for events to be imported into an empty database.
+func CreateTables(db *sql.DB) error {
+	return createEventsTable(db)
+}
+
+// createEventsTable creates the events table, the venues and hierarchy
+// tables events reference and the tables of their history, and adds the
+// columns older databases lack.
+func createEventsTable(db *sql.DB) error {
+	if err := seedVenues(db); err != nil {
+		return err
+	}
+	if err := createHierarchyTables(db); err != nil {
+		return err
+	}
+	if err := createHistoryTables(db); err != nil {
+		return err
+	}
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS events (id INTEGER PRIMARY KEY, name TEXT, advertised_start_time DATETIME, sport_type TEXT, venue TEXT, visible INTEGER, suspended INTEGER NOT NULL DEFAULT 0, competition_id INTEGER, season_id INTEGER, home_team_id INTEGER, away_team_id INTEGER, venue_id INTEGER, source_id TEXT)`); err != nil {
+		return err
+	}
+
+	// Databases created before events could be suspended, had a competition,
+	// teams and a venue, or could be imported, lack the columns
+	for _, column := range [][2]string{
+		{"suspended", "INTEGER NOT NULL DEFAULT 0"},
+		{"competition_id", "INTEGER"},
+		{"season_id", "INTEGER"},
+		{"home_team_id", "INTEGER"},
+		{"away_team_id", "INTEGER"},
+		{"venue_id", "INTEGER"},
+		{"source_id", "TEXT"},
+	} {
+		if err := addColumn(db, "events", column[0], column[1]); err != nil {
+			return err
+		}
+	}
+
+	_, err := db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS events_source_id ON events (source_id)`)
+	return err
+}
+
+// seedVenues creates the venues table and adds the venues the seeded events
+// are held at.
+func seedVenues(db *sql.DB) error {
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS venues (id INTEGER PRIMARY KEY, name TEXT UNIQUE, time_zone TEXT, country TEXT, latitude REAL, longitude REAL)`); err != nil {
+		return err
+	}
+
+	venues := []struct {
+		name, zone, country string
+		latitude, longitude float64
+	}{
+		{"Stadium A", "Australia/Melbourne", "AU", -37.8200, 144.9834},
+		{"Arena B", "America/New_York", "US", 40.7505, -73.9934},
+		{"Court C", "Europe/London", "GB", 51.4343, -0.2141},
+		{"Field D", "Australia/Perth", "AU", -31.9512, 115.8891},
+		{"Dome E", "Asia/Tokyo", "JP", 35.7056, 139.7519},
+	}
+	for i, venue := range venues {
+		if _, err := db.Exec(`INSERT OR IGNORE INTO venues(id, name, time_zone, country, latitude, longitude) VALUES (?,?,?,?,?,?)`,
+			i+1, venue.name, venue.zone, venue.country, venue.latitude, venue.longitude); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// seed creates the market tables and, when there are no markets yet, offers a
+// head to head market on every seeded event between the two teams in its name.
+func (r *marketsRepo) seed() error {
+	for _, query := range []string{
+		`CREATE TABLE IF NOT EXISTS markets (id INTEGER PRIMARY KEY AUTOINCREMENT, event_id INTEGER, type INTEGER, line REAL, status INTEGER)`,
+		`CREATE TABLE IF NOT EXISTS selections (id INTEGER PRIMARY KEY AUTOINCREMENT, market_id INTEGER, name TEXT, price REAL)`,
+	} {
+		if _, err := r.db.Exec(query); err != nil {
+			return err
+		}
+	}
+
+	var markets int
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM markets`).Scan(&markets); err != nil {
+		return err
+	}
+	if markets > 0 {
+		return nil
+	}
+
+	rows, err := r.db.Query(`SELECT id, name FROM events ORDER BY id`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var seeded []*sports.Market
+	for rows.Next() {
+		var (
+			eventID int64
+			name    string
+		)
+		if err := rows.Scan(&eventID, &name); err != nil {
+			return err
+		}
+
+		home, away, ok := parseTeams(name)
+		if !ok {
+			continue
+		}
+
+		// Price the teams off a rough 105% book
+		homePrice := float64(between(120, 420)) / 100
+		awayPrice := math.Round(100/(1.05-1/homePrice)) / 100
+		seeded = append(seeded, &sports.Market{
+			EventId: eventID,
+			Type:    sports.MarketType_HEAD_TO_HEAD,
+			Selections: []*sports.Selection{
+				{Name: home, Price: homePrice},
+				{Name: away, Price: math.Max(awayPrice, sports.MinPrice)},
+			},
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, market := range seeded {
+		if _, err := r.Save(market); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// competitionNames are the competitions the seeded events are backfilled
+// into, by sport type.
+var competitionNames = map[string]string{
+	"football":   "National Football League",
+	"basketball": "National Basketball League",
+	"tennis":     "ATP Tour",
+	"soccer":     "A-League",
+	"baseball":   "Major League Baseball",
+	"hockey":     "National Hockey League",
+}
+
+// competitionName returns the competition events of a sport type are
+// backfilled into.
+func competitionName(sportType string) string {
+	if name, ok := competitionNames[sportType]; ok {
+		return name
 	}
+	return strings.Title(sportType) + " League"
+}
 
-	// Sample sport types and venues
-	sportTypes := []string{"football", "basketball", "tennis", "soccer", "baseball", "hockey"}
-	venues := []string{"Stadium A", "Arena B", "Court C", "Field D", "Dome E"}
+// seed creates the sport, competition, season and team tables.
+func (r *hierarchyRepo) seed() error {
+	return createHierarchyTables(r.db)
+}
 
-	for i := 1; i <= 100; i++ {
-		statement, err = r.db.Prepare(`INSERT OR IGNORE INTO events(id, name, advertised_start_time, sport_type, venue, visible) VALUES (?,?,?,?,?,?)`)
-		if err == nil {
-			sportIndex := i % len(sportTypes)
-			venueIndex := i % len(venues)
-			_, err = statement.Exec(
-				i,
-				faker.Team().Name()+" vs "+faker.Team().Name(), // Create match-style names
-				faker.Time().Between(time.Now().AddDate(0, 0, -1), time.Now().AddDate(0, 0, 2)).Format(time.RFC3339),
-				sportTypes[sportIndex],
-				venues[venueIndex],
-				i%2, // Alternate between visible/not visible
-			)
+// createHierarchyTables creates the sports, competitions, seasons and teams
+// tables.
+func createHierarchyTables(db *sql.DB) error {
+	for _, query := range []string{
+		`CREATE TABLE IF NOT EXISTS sports (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT UNIQUE)`,
+		`CREATE TABLE IF NOT EXISTS competitions (id INTEGER PRIMARY KEY AUTOINCREMENT, sport_id INTEGER, name TEXT, UNIQUE (sport_id, name))`,
+		`CREATE TABLE IF NOT EXISTS seasons (id INTEGER PRIMARY KEY AUTOINCREMENT, competition_id INTEGER, name TEXT, UNIQUE (competition_id, name))`,
+		`CREATE TABLE IF NOT EXISTS teams (id INTEGER PRIMARY KEY AUTOINCREMENT, sport_id INTEGER, name TEXT, UNIQUE (sport_id, name))`,
+	} {
+		if _, err := db.Exec(query); err != nil {
+			return err
 		}
 	}
 
+	return nil
+}
+
+// between returns a random number from min to max inclusive.
+func between(min, max int) int {
+	n, _ := strconv.Atoi(faker.Number().Between(min, max))
+	return n
+}
+
+// addColumn adds a column to an existing table unless it is already there.
+func addColumn(db *sql.DB, table, column, definition string) error {
+	rows, err := db.Query(`PRAGMA table_info(` + table + `)`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid          int
+			name, kind   string
+			notNull, pk  int
+			defaultValue sql.NullString
+		)
+		if err := rows.Scan(&cid, &name, &kind, &notNull, &defaultValue, &pk); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`ALTER TABLE ` + table + ` ADD COLUMN ` + column + ` ` + definition)
 	return err
 }
@@ -2,7 +2,9 @@ package db
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -10,9 +12,13 @@ import (
 	"github.com/golang/protobuf/ptypes"
 	_ "github.com/mattn/go-sqlite3"
 
+	"git.neds.sh/matty/entain/sports/internal/clock"
 	"git.neds.sh/matty/entain/sports/proto/sports"
 )
 
+// ErrNotFound is wrapped by the errors returned for events that do not exist.
+var ErrNotFound = errors.New("not found")
+
 // EventsRepo provides repository access to sports events.
 type EventsRepo interface {
 	// Init will initialise our events repository.
@@ -21,18 +27,46 @@ type EventsRepo interface {
 	// List will return a list of events.
 	List(filter *sports.ListEventsRequestFilter) ([]*sports.Event, error)
 
+	// Stream will call send with each event List would return, as it is read,
+	// so that long lists are never held in memory. It stops at the first
+	// error send returns.
+	Stream(filter *sports.ListEventsRequestFilter, send func(*sports.Event) error) error
+
 	// GetByID will return a single event by its ID.
 	GetByID(id int64) (*sports.Event, error)
+
+	// GetByIDs will return the events with the given IDs, in the order of ids.
+	// IDs that match no event are skipped.
+	GetByIDs(ids []int64) ([]*sports.Event, error)
+
+	// SetSuspended will suspend or resume betting on an event, recording the
+	// change in its history as made by actor.
+	SetSuspended(id int64, suspended bool, actor string) error
+
+	// Import will create or update events by their source ID in a single
+	// transaction, assigning them to the hierarchy, and return how many were
+	// created and updated. The changes are recorded in the history of the
+	// events, made by actor. With dryRun the transaction is rolled back.
+	Import(events []*sports.ImportEventsRequest, actor string, dryRun bool) (created, updated int, err error)
+
+	// History will return the recorded changes to an event, oldest first,
+	// only those to field if it is not empty.
+	History(eventID int64, field string) ([]*sports.EventChange, error)
 }
 
 type eventsRepo struct {
-	db   *sql.DB
-	init sync.Once
+	db    *sql.DB
+	clock clock.Clock
+	init  sync.Once
 }
 
-// NewEventsRepo creates a new events repository.
-func NewEventsRepo(db *sql.DB) EventsRepo {
-	return &eventsRepo{db: db}
+// NewEventsRepo creates a new events repository. Event status is derived from
+// clk, which defaults to the system clock when nil.
+func NewEventsRepo(db *sql.DB, clk clock.Clock) EventsRepo {
+	if clk == nil {
+		clk = clock.System
+	}
+	return &eventsRepo{db: db, clock: clk}
 }
 
 // Init prepares the event repository dummy data.
@@ -48,26 +82,70 @@ func (r *eventsRepo) Init() error {
 }
 
 // List retrieves events from the database based on the provided filter.
-// It supports filtering by sport types and visibility status.
-// Results are ordered by advertised_start_time ASC by default, or by the specified sort field and direction.
+// It supports filtering by sport types, competitions, seasons, teams, venues,
+// visibility and open status, and by events starting today at their venue.
+// Results are ordered by advertised_start_time ASC by default, or by the specified sort field and direction,
+// and cut to the filter's limit.
 func (r *eventsRepo) List(filter *sports.ListEventsRequestFilter) ([]*sports.Event, error) {
-	var (
-		err   error
-		query string
-		args  []interface{}
-	)
+	var events []*sports.Event
+	err := r.Stream(filter, func(event *sports.Event) error {
+		events = append(events, event)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
 
-	query = getEventQueries()[eventsList]
+	return events, nil
+}
 
-	query, args = r.applyFilter(query, filter)
+// Stream reads the events List returns one row at a time, passing each event
+// to send as it is read.
+func (r *eventsRepo) Stream(filter *sports.ListEventsRequestFilter, send func(*sports.Event) error) error {
+	query, args := r.applyFilter(getEventQueries()[eventsList], filter)
 	query = r.applySorting(query, filter)
 
+	// SQLite has no time zone rules, so events starting today at their venue
+	// are picked as they are read, and the limit is counted there too
+	var zones map[int64]string
+	if filter.GetTodayAtVenue() {
+		var err error
+		if zones, err = venueZones(r.db); err != nil {
+			return err
+		}
+	} else if filter.GetLimit() > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.GetLimit())
+	}
+
 	rows, err := r.db.Query(query, args...)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	defer rows.Close()
+
+	now := r.clock.Now()
+	sent := 0
+	for rows.Next() {
+		event, err := r.scanEvent(rows)
+		if err != nil {
+			return err
+		}
+		if zones != nil && !startsToday(event.AdvertisedStartTime.AsTime(), now, zones[event.VenueId]) {
+			continue
+		}
+
+		if err := send(event); err != nil {
+			return err
+		}
+		// A limit of zero is no limit, and sent is never zero here
+		sent++
+		if sent == int(filter.GetLimit()) {
+			break
+		}
 	}
 
-	return r.scanEvents(rows)
+	return rows.Err()
 }
 
 // GetByID retrieves a single event from the database by its ID.
@@ -79,11 +157,13 @@ func (r *eventsRepo) GetByID(id int64) (*sports.Event, error) {
 
 	var event sports.Event
 	var advertisedStart time.Time
+	var zone string
 
-	err := row.Scan(&event.Id, &event.Name, &advertisedStart, &event.SportType, &event.Venue, &event.Visible)
+	err := row.Scan(&event.Id, &event.Name, &advertisedStart, &event.SportType, &event.Venue, &event.Visible, &event.Suspended,
+		&event.CompetitionId, &event.SeasonId, &event.HomeTeamId, &event.AwayTeamId, &event.VenueId, &zone)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("event with ID %d not found", id)
+			return nil, fmt.Errorf("event with ID %d %w", id, ErrNotFound)
 		}
 		return nil, err
 	}
@@ -94,13 +174,168 @@ func (r *eventsRepo) GetByID(id int64) (*sports.Event, error) {
 	}
 
 	event.AdvertisedStartTime = ts
+	event.AdvertisedStartLocal = localStartTime(advertisedStart, zone)
 
 	// Set event status based on advertised start time
-	setEventStatus(&event, advertisedStart)
+	setEventStatus(&event, advertisedStart, r.clock.Now())
 
 	return &event, nil
 }
 
+// GetByIDs retrieves the events with the given IDs with a single query.
+// The events are returned in the order of ids; IDs without an event are skipped.
+func (r *eventsRepo) GetByIDs(ids []int64) ([]*sports.Event, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	placeholders := strings.Repeat("?,", len(ids)-1) + "?"
+	query := fmt.Sprintf(getEventQueries()[eventsGetByIDs], placeholders)
+
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	found, err := r.scanEvents(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int64]*sports.Event, len(found))
+	for _, event := range found {
+		byID[event.Id] = event
+	}
+
+	events := make([]*sports.Event, 0, len(found))
+	for _, id := range ids {
+		if event, ok := byID[id]; ok {
+			events = append(events, event)
+		}
+	}
+
+	return events, nil
+}
+
+// SetSuspended updates the suspended flag of an event, and records it in the
+// history of the event if it changed. Returns an error wrapping ErrNotFound if
+// there is no such event.
+func (r *eventsRepo) SetSuspended(id int64, suspended bool, actor string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var wasSuspended bool
+	if err := tx.QueryRow(getEventQueries()[eventsSuspended], id).Scan(&wasSuspended); err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("event with ID %d %w", id, ErrNotFound)
+		}
+		return err
+	}
+
+	if _, err := tx.Exec(getEventQueries()[eventsSuspend], suspended, id); err != nil {
+		return err
+	}
+
+	before := []fieldValue{{"suspended", strconv.FormatBool(wasSuspended)}}
+	after := []fieldValue{{"suspended", strconv.FormatBool(suspended)}}
+	if err := recordChange(tx, id, actor, sports.ChangeType_CHANGE_UPDATED, r.clock.Now(), diffFields(before, after)); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Import upserts events by their source ID. Events are updated in place, so
+// they keep their ID, markets and suspension. Every imported event is
+// assigned to the hierarchy in the same transaction, from its new sport type,
+// start time and name. Every event created, and every event updated with
+// different values, gets a change in its history.
+func (r *eventsRepo) Import(events []*sports.ImportEventsRequest, actor string, dryRun bool) (created, updated int, err error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+
+	get, err := tx.Prepare(getEventQueries()[eventsImportGet])
+	if err != nil {
+		return 0, 0, err
+	}
+	defer get.Close()
+
+	update, err := tx.Prepare(getEventQueries()[eventsImportUpdate])
+	if err != nil {
+		return 0, 0, err
+	}
+	defer update.Close()
+
+	insert, err := tx.Prepare(getEventQueries()[eventsImportInsert])
+	if err != nil {
+		return 0, 0, err
+	}
+	defer insert.Close()
+
+	now := r.clock.Now()
+	for _, event := range events {
+		startTime := event.AdvertisedStartTime.AsTime()
+		start := startTime.UTC().Format(time.RFC3339)
+		after := importedFields(event.Name, event.SportType, event.VenueId, event.Visible, startTime)
+
+		var (
+			id              int64
+			name, sportType string
+			venueID         int64
+			visible         bool
+			advertisedStart time.Time
+		)
+		err := get.QueryRow(event.SourceId).Scan(&id, &name, &sportType, &venueID, &visible, &advertisedStart)
+		switch {
+		case err == nil:
+			if _, err := update.Exec(event.Name, event.SportType, event.Visible, start, event.VenueId, event.VenueId, event.SourceId); err != nil {
+				return 0, 0, fmt.Errorf("failed to update event %q: %w", event.SourceId, err)
+			}
+			before := importedFields(name, sportType, venueID, visible, advertisedStart)
+			if err := recordChange(tx, id, actor, sports.ChangeType_CHANGE_UPDATED, now, diffFields(before, after)); err != nil {
+				return 0, 0, fmt.Errorf("failed to record update of event %q: %w", event.SourceId, err)
+			}
+			updated++
+		case errors.Is(err, sql.ErrNoRows):
+			res, err := insert.Exec(event.SourceId, event.Name, event.SportType, event.Visible, start, event.VenueId, event.VenueId)
+			if err != nil {
+				return 0, 0, fmt.Errorf("failed to create event %q: %w", event.SourceId, err)
+			}
+			if id, err = res.LastInsertId(); err != nil {
+				return 0, 0, fmt.Errorf("failed to create event %q: %w", event.SourceId, err)
+			}
+			if err := recordChange(tx, id, actor, sports.ChangeType_CHANGE_CREATED, now, diffFields(nil, after)); err != nil {
+				return 0, 0, fmt.Errorf("failed to record creation of event %q: %w", event.SourceId, err)
+			}
+			created++
+		default:
+			return 0, 0, fmt.Errorf("failed to get event %q: %w", event.SourceId, err)
+		}
+
+		if err := assignHierarchy(tx, id, event.Name, event.SportType, startTime); err != nil {
+			return 0, 0, fmt.Errorf("failed to assign event %q: %w", event.SourceId, err)
+		}
+	}
+
+	if dryRun {
+		return created, updated, nil
+	}
+
+	return created, updated, tx.Commit()
+}
+
 // applyFilter modifies the base query to include WHERE clauses based on the filter.
 // It returns the modified query string and the corresponding arguments for parameterized queries.
 func (r *eventsRepo) applyFilter(query string, filter *sports.ListEventsRequestFilter) (string, []interface{}) {
@@ -122,10 +357,36 @@ func (r *eventsRepo) applyFilter(query string, filter *sports.ListEventsRequestF
 		}
 	}
 
+	if len(filter.CompetitionIds) > 0 {
+		clauses = append(clauses, "competition_id IN ("+idPlaceholders(filter.CompetitionIds)+")")
+		args = appendIDs(args, filter.CompetitionIds)
+	}
+
+	if len(filter.SeasonIds) > 0 {
+		clauses = append(clauses, "season_id IN ("+idPlaceholders(filter.SeasonIds)+")")
+		args = appendIDs(args, filter.SeasonIds)
+	}
+
+	if len(filter.TeamIds) > 0 {
+		placeholders := idPlaceholders(filter.TeamIds)
+		clauses = append(clauses, "(home_team_id IN ("+placeholders+") OR away_team_id IN ("+placeholders+"))")
+		args = appendIDs(appendIDs(args, filter.TeamIds), filter.TeamIds)
+	}
+
+	if len(filter.VenueIds) > 0 {
+		clauses = append(clauses, "venue_id IN ("+idPlaceholders(filter.VenueIds)+")")
+		args = appendIDs(args, filter.VenueIds)
+	}
+
 	if filter.VisibleOnly != nil && *filter.VisibleOnly {
 		clauses = append(clauses, "visible = 1")
 	}
 
+	if filter.GetOpenOnly() {
+		clauses = append(clauses, "advertised_start_time >= ?")
+		args = append(args, openFrom(r.clock.Now()))
+	}
+
 	if len(clauses) > 0 {
 		query += " WHERE " + strings.Join(clauses, " AND ")
 	}
@@ -133,6 +394,19 @@ func (r *eventsRepo) applyFilter(query string, filter *sports.ListEventsRequestF
 	return query, args
 }
 
+// idPlaceholders returns the placeholders of an IN clause for ids.
+func idPlaceholders(ids []int64) string {
+	return strings.Repeat("?,", len(ids)-1) + "?"
+}
+
+// appendIDs appends ids to the arguments of a query.
+func appendIDs(args []interface{}, ids []int64) []interface{} {
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	return args
+}
+
 // applySorting adds ORDER BY clause to the query based on the filter's sort preferences.
 // Defaults to ORDER BY advertised_start_time ASC if no sort field is specified.
 func (r *eventsRepo) applySorting(query string, filter *sports.ListEventsRequestFilter) string {
@@ -171,38 +445,59 @@ func (r *eventsRepo) scanEvents(
 	var events []*sports.Event
 
 	for rows.Next() {
-		var event sports.Event
-		var advertisedStart time.Time
-
-		if err := rows.Scan(&event.Id, &event.Name, &advertisedStart, &event.SportType, &event.Venue, &event.Visible); err != nil {
-			if err == sql.ErrNoRows {
-				return nil, nil
-			}
-
-			return nil, err
-		}
-
-		ts, err := ptypes.TimestampProto(advertisedStart)
+		event, err := r.scanEvent(rows)
 		if err != nil {
 			return nil, err
 		}
 
-		event.AdvertisedStartTime = ts
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+// scanEvent reads the event in the current row of rows.
+func (r *eventsRepo) scanEvent(rows *sql.Rows) (*sports.Event, error) {
+	var event sports.Event
+	var advertisedStart time.Time
+	var zone string
 
-		// Set event status based on advertised start time
-		setEventStatus(&event, advertisedStart)
+	if err := rows.Scan(&event.Id, &event.Name, &advertisedStart, &event.SportType, &event.Venue, &event.Visible, &event.Suspended,
+		&event.CompetitionId, &event.SeasonId, &event.HomeTeamId, &event.AwayTeamId, &event.VenueId, &zone); err != nil {
+		return nil, err
+	}
 
-		events = append(events, &event)
+	ts, err := ptypes.TimestampProto(advertisedStart)
+	if err != nil {
+		return nil, err
 	}
 
-	return events, nil
+	event.AdvertisedStartTime = ts
+	event.AdvertisedStartLocal = localStartTime(advertisedStart, zone)
+
+	// Set event status based on advertised start time
+	setEventStatus(&event, advertisedStart, r.clock.Now())
+
+	return &event, nil
 }
 
 // setEventStatus sets the event status based on the advertised start time.
-// Events with advertised start time in the past are marked as CLOSED, others as OPEN.
-func setEventStatus(event *sports.Event, advertisedStart time.Time) {
+// Events with advertised start time before now are marked as CLOSED, others,
+// including events starting exactly now, as OPEN.
+func setEventStatus(event *sports.Event, advertisedStart, now time.Time) {
 	event.Status = sports.EventStatus_OPEN
-	if advertisedStart.Before(time.Now()) {
+	if advertisedStart.Before(now) {
 		event.Status = sports.EventStatus_CLOSED
 	}
 }
+
+// openFrom returns the earliest advertised start time of an event open at
+// now, formatted as start times are stored. They are stored to the second, so
+// now is rounded up to one.
+func openFrom(now time.Time) string {
+	from := now.Truncate(time.Second)
+	if from.Before(now) {
+		from = from.Add(time.Second)
+	}
+	return from.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,169 @@
+package db
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"git.neds.sh/matty/entain/sports/internal/clock"
+	"git.neds.sh/matty/entain/sports/proto/sports"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestEventsRepo_List_StatusLogic(t *testing.T) {
+	db := setupVenuesDB(t)
+	if _, err := db.Exec(`DELETE FROM events`); err != nil {
+		t.Fatalf("failed to clear events: %v", err)
+	}
+
+	// Start times are stored to the second, so the clock is fixed on one
+	now := time.Date(2026, 10, 18, 5, 0, 0, 0, time.UTC)
+	starts := []time.Time{
+		now.Add(-time.Hour),
+		now.Add(-time.Second),
+		now,
+		now.Add(time.Second),
+		now.Add(time.Hour),
+	}
+	for i, start := range starts {
+		if _, err := db.Exec(`INSERT INTO events (id, name, advertised_start_time, sport_type, venue, visible, venue_id) VALUES (?, 'Team A vs Team B', ?, 'football', 'Stadium A', 1, 1)`,
+			i+1, start.Format(time.RFC3339)); err != nil {
+			t.Fatalf("failed to insert event %d: %v", i+1, err)
+		}
+	}
+
+	events, err := NewEventsRepo(db, clock.Fixed(now)).List(nil)
+	if err != nil {
+		t.Fatalf("List() failed: %v", err)
+	}
+
+	var got []sports.EventStatus
+	for _, event := range events {
+		got = append(got, event.Status)
+	}
+	// An event starting exactly now is still open
+	want := []sports.EventStatus{
+		sports.EventStatus_CLOSED,
+		sports.EventStatus_CLOSED,
+		sports.EventStatus_OPEN,
+		sports.EventStatus_OPEN,
+		sports.EventStatus_OPEN,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("List() statuses mismatch (-want +got):\n%s", diff)
+	}
+
+	// Open events are those starting now or later, even within the second
+	open := true
+	for _, tt := range []struct {
+		now   time.Time
+		limit int32
+		want  []int64
+	}{
+		{now: now, limit: 2, want: []int64{3, 4}},
+		{now: now.Add(500 * time.Millisecond), limit: 2, want: []int64{4, 5}},
+		{now: now.Add(500 * time.Millisecond), limit: 10, want: []int64{4, 5}},
+	} {
+		events, err := NewEventsRepo(db, clock.Fixed(tt.now)).List(&sports.ListEventsRequestFilter{OpenOnly: &open, Limit: &tt.limit})
+		if err != nil {
+			t.Fatalf("List() of open events failed: %v", err)
+		}
+		var ids []int64
+		for _, event := range events {
+			ids = append(ids, event.Id)
+		}
+		if diff := cmp.Diff(tt.want, ids); diff != "" {
+			t.Errorf("List() of %d open events at %v mismatch (-want +got):\n%s", tt.limit, tt.now, diff)
+		}
+	}
+
+	event, err := NewEventsRepo(db, clock.Fixed(now)).GetByID(3)
+	if err != nil {
+		t.Fatalf("GetByID() failed: %v", err)
+	}
+	if event.Status != sports.EventStatus_OPEN {
+		t.Errorf("GetByID() status = %v, want %v", event.Status, sports.EventStatus_OPEN)
+	}
+}
+
+func TestEventsRepo_Import(t *testing.T) {
+	db := setupVenuesDB(t)
+	if err := createEventsTable(db); err != nil {
+		t.Fatalf("createEventsTable() failed: %v", err)
+	}
+	repo := NewEventsRepo(db, nil)
+
+	start := time.Date(2026, 11, 3, 8, 0, 0, 0, time.UTC)
+	final := &sports.ImportEventsRequest{SourceId: "feed-9", Name: "Lions vs Tigers", SportType: "football", VenueId: 5, Visible: true, AdvertisedStartTime: timestamppb.New(start)}
+
+	// A dry run reports what would change without saving it
+	created, updated, err := repo.Import([]*sports.ImportEventsRequest{final}, "trader-1", true)
+	if err != nil {
+		t.Fatalf("Import() dry run failed: %v", err)
+	}
+	if created != 1 || updated != 0 {
+		t.Errorf("Import() dry run = %d created, %d updated, want 1, 0", created, updated)
+	}
+	if events, _ := repo.GetByIDs([]int64{4}); len(events) != 0 {
+		t.Fatalf("Import() dry run saved event %v", events[0])
+	}
+
+	if _, _, err := repo.Import([]*sports.ImportEventsRequest{final}, "trader-1", false); err != nil {
+		t.Fatalf("Import() failed: %v", err)
+	}
+
+	// The import assigns the event to the hierarchy without a backfill
+	before, err := repo.GetByID(4)
+	if err != nil || before.Venue != "Dome E" || before.CompetitionId == 0 || before.HomeTeamId == 0 || before.AwayTeamId == 0 {
+		t.Fatalf("GetByID() = %v, %v, want the imported event at Dome E in a competition with teams", before, err)
+	}
+
+	// Importing the event again updates it in place and reassigns its teams
+	final.Name = "Lions vs Bears"
+	final.VenueId = 0
+	created, updated, err = repo.Import([]*sports.ImportEventsRequest{final}, "trader-1", false)
+	if err != nil {
+		t.Fatalf("Import() update failed: %v", err)
+	}
+	if created != 0 || updated != 1 {
+		t.Errorf("Import() update = %d created, %d updated, want 0, 1", created, updated)
+	}
+
+	got, err := repo.GetByID(4)
+	if err != nil {
+		t.Fatalf("GetByID() failed: %v", err)
+	}
+	if got.Name != final.Name || got.VenueId != 0 || got.Venue != "" || !got.AdvertisedStartTime.AsTime().Equal(start) {
+		t.Errorf("imported event = %v, want the updated fields of %v and no venue", got, final)
+	}
+	if got.CompetitionId != before.CompetitionId || got.SeasonId != before.SeasonId || got.HomeTeamId != before.HomeTeamId ||
+		got.AwayTeamId == 0 || got.AwayTeamId == before.AwayTeamId {
+		t.Errorf("imported event = %v, want the competition, season and home team of %v and a new away team", got, before)
+	}
+}
+
+func TestCreateEventsTable_AddsSourceID(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	// Databases created before imports have no source ID column
+	if _, err := db.Exec(`CREATE TABLE events (id INTEGER PRIMARY KEY, name TEXT, advertised_start_time DATETIME, sport_type TEXT, venue TEXT, visible INTEGER)`); err != nil {
+		t.Fatalf("failed to create events: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := createEventsTable(db); err != nil {
+			t.Fatalf("createEventsTable() call %d failed: %v", i, err)
+		}
+	}
+
+	if _, err := db.Exec(`INSERT INTO events (id, source_id) VALUES (1, 'feed-1'), (2, NULL), (3, NULL)`); err != nil {
+		t.Fatalf("failed to insert events: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO events (id, source_id) VALUES (4, 'feed-1')`); err == nil {
+		t.Errorf("inserted a duplicate source ID, want a unique constraint error")
+	}
+}
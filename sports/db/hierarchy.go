@@ -0,0 +1,313 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"git.neds.sh/matty/entain/sports/proto/sports"
+)
+
+// HierarchyRepo provides repository access to the sports, competitions,
+// seasons and teams that events belong to.
+type HierarchyRepo interface {
+	// Init will initialise our hierarchy repository.
+	Init() error
+
+	// Backfill will assign a competition, season and teams to every event
+	// without a competition, from its sport type, start time and name, and
+	// return how many events were assigned.
+	Backfill() (int, error)
+
+	// ListSports will return every sport, by name.
+	ListSports() ([]*sports.Sport, error)
+
+	// ListCompetitions will return the competitions of a sport with their
+	// seasons, by name.
+	ListCompetitions(sportID int64) ([]*sports.Competition, error)
+
+	// GetCompetition will return a single competition with its seasons.
+	GetCompetition(id int64) (*sports.Competition, error)
+
+	// ListTeams will return the teams of a sport, by name.
+	ListTeams(sportID int64) ([]*sports.Team, error)
+}
+
+type hierarchyRepo struct {
+	db   *sql.DB
+	init sync.Once
+}
+
+// NewHierarchyRepo creates a new hierarchy repository.
+func NewHierarchyRepo(db *sql.DB) HierarchyRepo {
+	return &hierarchyRepo{db: db}
+}
+
+// Init creates the hierarchy tables. They are filled by Backfill, and by
+// imports of events.
+func (r *hierarchyRepo) Init() error {
+	var err error
+
+	r.init.Do(func() {
+		err = r.seed()
+	})
+
+	return err
+}
+
+// Backfill assigns events in a single transaction. Sports, competitions,
+// seasons and teams are created as they are first needed, so running it
+// again only assigns events added since.
+//
+// Events are put in the main competition of their sport, in the season of
+// the year they start. Their teams are parsed from names of the form
+// "Home vs Away"; events with other names get no teams.
+func (r *hierarchyRepo) Backfill() (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	type unassigned struct {
+		id              int64
+		name, sportType string
+		advertisedStart time.Time
+	}
+
+	rows, err := tx.Query(getHierarchyQueries()[hierarchyUnassignedEvents])
+	if err != nil {
+		return 0, err
+	}
+	var events []unassigned
+	for rows.Next() {
+		var event unassigned
+		if err := rows.Scan(&event.id, &event.name, &event.advertisedStart, &event.sportType); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		events = append(events, event)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, event := range events {
+		if err := assignHierarchy(tx, event.id, event.name, event.sportType, event.advertisedStart); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(events), tx.Commit()
+}
+
+// assignHierarchy puts an event in the main competition of its sport and the
+// season of the year it starts, with the teams parsed from its name, creating
+// them as needed. Any earlier assignment of the event is replaced.
+func assignHierarchy(tx *sql.Tx, eventID int64, name, sportType string, advertisedStart time.Time) error {
+	sportID, err := ensure(tx, "sports", []string{"name"}, sportType)
+	if err != nil {
+		return fmt.Errorf("failed to create sport %q: %w", sportType, err)
+	}
+
+	competition := competitionName(sportType)
+	competitionID, err := ensure(tx, "competitions", []string{"sport_id", "name"}, sportID, competition)
+	if err != nil {
+		return fmt.Errorf("failed to create competition %q: %w", competition, err)
+	}
+
+	season := strconv.Itoa(advertisedStart.Year())
+	seasonID, err := ensure(tx, "seasons", []string{"competition_id", "name"}, competitionID, season)
+	if err != nil {
+		return fmt.Errorf("failed to create season %q: %w", season, err)
+	}
+
+	var homeID, awayID sql.NullInt64
+	if home, away, ok := parseTeams(name); ok {
+		if homeID.Int64, err = ensure(tx, "teams", []string{"sport_id", "name"}, sportID, home); err != nil {
+			return fmt.Errorf("failed to create team %q: %w", home, err)
+		}
+		if awayID.Int64, err = ensure(tx, "teams", []string{"sport_id", "name"}, sportID, away); err != nil {
+			return fmt.Errorf("failed to create team %q: %w", away, err)
+		}
+		homeID.Valid, awayID.Valid = true, true
+	}
+
+	if _, err := tx.Exec(getHierarchyQueries()[hierarchyAssignEvent], competitionID, seasonID, homeID, awayID, eventID); err != nil {
+		return fmt.Errorf("failed to assign event %d: %w", eventID, err)
+	}
+	return nil
+}
+
+// ListSports retrieves every sport.
+func (r *hierarchyRepo) ListSports() ([]*sports.Sport, error) {
+	rows, err := r.db.Query(getHierarchyQueries()[hierarchySports])
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []*sports.Sport{}
+	for rows.Next() {
+		var sport sports.Sport
+		if err := rows.Scan(&sport.Id, &sport.Name); err != nil {
+			return nil, err
+		}
+		list = append(list, &sport)
+	}
+
+	return list, rows.Err()
+}
+
+// ListCompetitions retrieves the competitions of a sport and their seasons
+// with two queries. Returns an error wrapping ErrNotFound if there is no
+// such sport.
+func (r *hierarchyRepo) ListCompetitions(sportID int64) ([]*sports.Competition, error) {
+	if err := r.sportExists(sportID); err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(getHierarchyQueries()[hierarchyCompetitions], sportID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	competitions := []*sports.Competition{}
+	byID := make(map[int64]*sports.Competition)
+	for rows.Next() {
+		var competition sports.Competition
+		if err := rows.Scan(&competition.Id, &competition.SportId, &competition.Name); err != nil {
+			return nil, err
+		}
+		competitions = append(competitions, &competition)
+		byID[competition.Id] = &competition
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	seasons, err := r.seasons(hierarchySeasons, sportID)
+	if err != nil {
+		return nil, err
+	}
+	for _, season := range seasons {
+		if competition, ok := byID[season.CompetitionId]; ok {
+			competition.Seasons = append(competition.Seasons, season)
+		}
+	}
+
+	return competitions, nil
+}
+
+// GetCompetition retrieves a competition by its ID with its seasons.
+// Returns an error wrapping ErrNotFound if there is no such competition.
+func (r *hierarchyRepo) GetCompetition(id int64) (*sports.Competition, error) {
+	var competition sports.Competition
+	err := r.db.QueryRow(getHierarchyQueries()[hierarchyCompetition], id).Scan(&competition.Id, &competition.SportId, &competition.Name)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("competition with ID %d %w", id, ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if competition.Seasons, err = r.seasons(hierarchyCompetitionSeasons, id); err != nil {
+		return nil, err
+	}
+
+	return &competition, nil
+}
+
+// ListTeams retrieves the teams of a sport. Returns an error wrapping
+// ErrNotFound if there is no such sport.
+func (r *hierarchyRepo) ListTeams(sportID int64) ([]*sports.Team, error) {
+	if err := r.sportExists(sportID); err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(getHierarchyQueries()[hierarchyTeams], sportID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	teams := []*sports.Team{}
+	for rows.Next() {
+		var team sports.Team
+		if err := rows.Scan(&team.Id, &team.SportId, &team.Name); err != nil {
+			return nil, err
+		}
+		teams = append(teams, &team)
+	}
+
+	return teams, rows.Err()
+}
+
+// sportExists returns an error wrapping ErrNotFound if there is no sport with
+// the given ID.
+func (r *hierarchyRepo) sportExists(id int64) error {
+	var count int
+	if err := r.db.QueryRow(getHierarchyQueries()[hierarchySportExists], id).Scan(&count); err != nil {
+		return err
+	}
+	if count == 0 {
+		return fmt.Errorf("sport with ID %d %w", id, ErrNotFound)
+	}
+	return nil
+}
+
+// seasons runs one of the season queries with arg.
+func (r *hierarchyRepo) seasons(query string, arg int64) ([]*sports.Season, error) {
+	rows, err := r.db.Query(getHierarchyQueries()[query], arg)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var seasons []*sports.Season
+	for rows.Next() {
+		var season sports.Season
+		if err := rows.Scan(&season.Id, &season.CompetitionId, &season.Name); err != nil {
+			return nil, err
+		}
+		seasons = append(seasons, &season)
+	}
+
+	return seasons, rows.Err()
+}
+
+// ensure returns the ID of the row of table with the given values of
+// columns, inserting it first if there is none. The columns must be a unique
+// key of the table.
+func ensure(tx *sql.Tx, table string, columns []string, values ...interface{}) (int64, error) {
+	var id int64
+	query := fmt.Sprintf(`SELECT id FROM %s WHERE %s = ?`, table, strings.Join(columns, " = ? AND "))
+	err := tx.QueryRow(query, values...).Scan(&id)
+	if err != sql.ErrNoRows {
+		return id, err
+	}
+
+	placeholders := strings.Repeat("?,", len(columns)-1) + "?"
+	insert := fmt.Sprintf(`INSERT INTO %s (%s) VALUES (%s)`, table, strings.Join(columns, ", "), placeholders)
+	res, err := tx.Exec(insert, values...)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// parseTeams splits an event name of the form "Home vs Away".
+func parseTeams(name string) (home, away string, ok bool) {
+	parts := strings.SplitN(name, " vs ", 2)
+	if len(parts) != 2 {
+		return "", "", false
+	}
+
+	home, away = strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+	return home, away, home != "" && away != ""
+}
@@ -0,0 +1,169 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	"git.neds.sh/matty/entain/sports/proto/sports"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+)
+
+// setupHierarchyDB creates an in-memory SQLite database with three events
+// that have not been backfilled, the hierarchy tables and the venues
+func setupHierarchyDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("setupHierarchyDB() failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	for _, query := range []string{
+		`CREATE TABLE events (id INTEGER PRIMARY KEY, name TEXT, advertised_start_time DATETIME, sport_type TEXT, venue TEXT, visible INTEGER, suspended INTEGER NOT NULL DEFAULT 0, competition_id INTEGER, season_id INTEGER, home_team_id INTEGER, away_team_id INTEGER, venue_id INTEGER)`,
+		`INSERT INTO events (id, name, advertised_start_time, sport_type, venue, visible, venue_id) VALUES
+			(1, 'Team A vs Team B', '2026-10-18T09:00:00Z', 'football', 'Stadium A', 1, 1),
+			(2, 'Team B vs Team C', '2027-03-01T09:00:00Z', 'football', 'Stadium A', 1, 1),
+			(3, 'Wimbledon Final', '2026-07-12T14:00:00Z', 'tennis', 'Court C', 1, 3)`,
+	} {
+		if _, err := db.Exec(query); err != nil {
+			t.Fatalf("setupHierarchyDB() failed: %v", err)
+		}
+	}
+
+	if err := seedVenues(db); err != nil {
+		t.Fatalf("setupHierarchyDB() failed to seed venues: %v", err)
+	}
+
+	if err := NewHierarchyRepo(db).Init(); err != nil {
+		t.Fatalf("setupHierarchyDB() failed to init hierarchy: %v", err)
+	}
+
+	return db
+}
+
+func TestHierarchyRepo_Backfill(t *testing.T) {
+	db := setupHierarchyDB(t)
+	repo := NewHierarchyRepo(db)
+
+	n, err := repo.Backfill()
+	if err != nil {
+		t.Fatalf("Backfill() failed: %v", err)
+	}
+	if n != 3 {
+		t.Errorf("Backfill() assigned %d events, want 3", n)
+	}
+
+	if n, err := repo.Backfill(); err != nil || n != 0 {
+		t.Errorf("second Backfill() = %d, %v; want 0, nil", n, err)
+	}
+
+	gotSports, err := repo.ListSports()
+	if err != nil {
+		t.Fatalf("ListSports() failed: %v", err)
+	}
+	wantSports := []*sports.Sport{{Id: 1, Name: "football"}, {Id: 2, Name: "tennis"}}
+	if diff := cmp.Diff(wantSports, gotSports, protocmp.Transform()); diff != "" {
+		t.Errorf("ListSports() mismatch (-want +got):\n%s", diff)
+	}
+
+	competitions, err := repo.ListCompetitions(1)
+	if err != nil {
+		t.Fatalf("ListCompetitions() failed: %v", err)
+	}
+	wantCompetitions := []*sports.Competition{{
+		Id:      1,
+		SportId: 1,
+		Name:    "National Football League",
+		Seasons: []*sports.Season{{Id: 1, CompetitionId: 1, Name: "2026"}, {Id: 2, CompetitionId: 1, Name: "2027"}},
+	}}
+	if diff := cmp.Diff(wantCompetitions, competitions, protocmp.Transform()); diff != "" {
+		t.Errorf("ListCompetitions() mismatch (-want +got):\n%s", diff)
+	}
+
+	competition, err := repo.GetCompetition(1)
+	if err != nil {
+		t.Fatalf("GetCompetition() failed: %v", err)
+	}
+	if diff := cmp.Diff(wantCompetitions[0], competition, protocmp.Transform()); diff != "" {
+		t.Errorf("GetCompetition() mismatch (-want +got):\n%s", diff)
+	}
+
+	teams, err := repo.ListTeams(1)
+	if err != nil {
+		t.Fatalf("ListTeams() failed: %v", err)
+	}
+	wantTeams := []*sports.Team{{Id: 1, SportId: 1, Name: "Team A"}, {Id: 2, SportId: 1, Name: "Team B"}, {Id: 3, SportId: 1, Name: "Team C"}}
+	if diff := cmp.Diff(wantTeams, teams, protocmp.Transform()); diff != "" {
+		t.Errorf("ListTeams() mismatch (-want +got):\n%s", diff)
+	}
+
+	// Events without teams in their name only get a competition and season
+	events, err := NewEventsRepo(db, nil).GetByIDs([]int64{1, 2, 3})
+	if err != nil {
+		t.Fatalf("GetByIDs() failed: %v", err)
+	}
+	type assignment struct{ competition, season, home, away int64 }
+	want := []assignment{{1, 1, 1, 2}, {1, 2, 2, 3}, {2, 3, 0, 0}}
+	for i, event := range events {
+		got := assignment{event.CompetitionId, event.SeasonId, event.HomeTeamId, event.AwayTeamId}
+		if got != want[i] {
+			t.Errorf("event %d assigned %+v, want %+v", event.Id, got, want[i])
+		}
+	}
+}
+
+func TestHierarchyRepo_NotFound(t *testing.T) {
+	repo := NewHierarchyRepo(setupHierarchyDB(t))
+	if _, err := repo.Backfill(); err != nil {
+		t.Fatalf("Backfill() failed: %v", err)
+	}
+
+	if _, err := repo.ListCompetitions(9); !errors.Is(err, ErrNotFound) {
+		t.Errorf("ListCompetitions() of an unknown sport error = %v, want %v", err, ErrNotFound)
+	}
+	if _, err := repo.ListTeams(9); !errors.Is(err, ErrNotFound) {
+		t.Errorf("ListTeams() of an unknown sport error = %v, want %v", err, ErrNotFound)
+	}
+	if _, err := repo.GetCompetition(9); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetCompetition() of an unknown competition error = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestEventsRepo_List_HierarchyFilters(t *testing.T) {
+	db := setupHierarchyDB(t)
+	if _, err := NewHierarchyRepo(db).Backfill(); err != nil {
+		t.Fatalf("Backfill() failed: %v", err)
+	}
+	repo := NewEventsRepo(db, nil)
+
+	tests := []struct {
+		name   string
+		filter *sports.ListEventsRequestFilter
+		want   []int64
+	}{
+		{name: "competition", filter: &sports.ListEventsRequestFilter{CompetitionIds: []int64{1}}, want: []int64{1, 2}},
+		{name: "season", filter: &sports.ListEventsRequestFilter{SeasonIds: []int64{2, 3}}, want: []int64{3, 2}},
+		{name: "home or away team", filter: &sports.ListEventsRequestFilter{TeamIds: []int64{2}}, want: []int64{1, 2}},
+		{name: "away team", filter: &sports.ListEventsRequestFilter{TeamIds: []int64{3}}, want: []int64{2}},
+		{name: "competition and team", filter: &sports.ListEventsRequestFilter{CompetitionIds: []int64{2}, TeamIds: []int64{1}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, err := repo.List(tt.filter)
+			if err != nil {
+				t.Fatalf("List() failed: %v", err)
+			}
+			var got []int64
+			for _, event := range events {
+				got = append(got, event.Id)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("List() IDs mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
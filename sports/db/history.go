@@ -0,0 +1,130 @@
+package db
+
+import (
+	"database/sql"
+	"strconv"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"git.neds.sh/matty/entain/sports/proto/sports"
+)
+
+// createHistoryTables creates the tables of the event history. Rows are only
+// ever added to them: triggers reject updates and deletes.
+func createHistoryTables(db *sql.DB) error {
+	for _, query := range []string{
+		`CREATE TABLE IF NOT EXISTS event_changes (id INTEGER PRIMARY KEY AUTOINCREMENT, event_id INTEGER NOT NULL, actor TEXT NOT NULL, type INTEGER NOT NULL, changed_time DATETIME NOT NULL)`,
+		`CREATE INDEX IF NOT EXISTS event_changes_event_id ON event_changes (event_id)`,
+		`CREATE TABLE IF NOT EXISTS event_change_fields (change_id INTEGER NOT NULL REFERENCES event_changes (id), field TEXT NOT NULL, old_value TEXT NOT NULL, new_value TEXT NOT NULL, PRIMARY KEY (change_id, field))`,
+	} {
+		if _, err := db.Exec(query); err != nil {
+			return err
+		}
+	}
+
+	for _, table := range []string{"event_changes", "event_change_fields"} {
+		for _, op := range []string{"UPDATE", "DELETE"} {
+			if _, err := db.Exec(`CREATE TRIGGER IF NOT EXISTS ` + table + `_no_` + op + ` BEFORE ` + op + ` ON ` + table +
+				` BEGIN SELECT RAISE(ABORT, 'event history is append-only'); END`); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// fieldValue is a recorded field of an event and its value as text.
+type fieldValue struct {
+	field, value string
+}
+
+// importedFields returns the recorded fields of an event that imports set, in
+// the order of sports.EventHistoryFields.
+func importedFields(name, sportType string, venueID int64, visible bool, start time.Time) []fieldValue {
+	return []fieldValue{
+		{"name", name},
+		{"sport_type", sportType},
+		{"venue_id", strconv.FormatInt(venueID, 10)},
+		{"visible", strconv.FormatBool(visible)},
+		{"advertised_start_time", start.UTC().Format(time.RFC3339)},
+	}
+}
+
+// diffFields returns the fields whose value differs between before and after,
+// which hold the same fields in the same order. A nil before is an event being
+// created, so every field is returned.
+func diffFields(before, after []fieldValue) []*sports.FieldChange {
+	var changes []*sports.FieldChange
+	for i, field := range after {
+		change := &sports.FieldChange{Field: field.field, After: field.value}
+		if before != nil {
+			if before[i].value == field.value {
+				continue
+			}
+			change.Before = before[i].value
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// recordChange adds a change to the history of an event, unless it changes no
+// field.
+func recordChange(tx *sql.Tx, eventID int64, actor string, changeType sports.ChangeType, changedTime time.Time, fields []*sports.FieldChange) error {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	res, err := tx.Exec(getHistoryQueries()[historyInsertChange], eventID, actor, changeType, changedTime.UTC().Format(time.RFC3339))
+	if err != nil {
+		return err
+	}
+	changeID, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	for _, field := range fields {
+		if _, err := tx.Exec(getHistoryQueries()[historyInsertField], changeID, field.Field, field.Before, field.After); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// History returns the recorded changes to an event, oldest first. With a
+// field, only the changes to that field are returned, with only that field.
+func (r *eventsRepo) History(eventID int64, field string) ([]*sports.EventChange, error) {
+	rows, err := r.db.Query(getHistoryQueries()[historyList], eventID, field, field)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes []*sports.EventChange
+	for rows.Next() {
+		var (
+			change      sports.EventChange
+			changedTime time.Time
+			fieldChange sports.FieldChange
+		)
+		if err := rows.Scan(&change.Id, &change.EventId, &change.Actor, &change.Type, &changedTime,
+			&fieldChange.Field, &fieldChange.Before, &fieldChange.After); err != nil {
+			return nil, err
+		}
+
+		// Rows of the same change are consecutive
+		if n := len(changes); n > 0 && changes[n-1].Id == change.Id {
+			changes[n-1].Fields = append(changes[n-1].Fields, &fieldChange)
+			continue
+		}
+		change.ChangedTime = timestamppb.New(changedTime)
+		change.Fields = []*sports.FieldChange{&fieldChange}
+		changes = append(changes, &change)
+	}
+
+	return changes, rows.Err()
+}
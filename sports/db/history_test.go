@@ -0,0 +1,133 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"git.neds.sh/matty/entain/sports/internal/clock"
+	"git.neds.sh/matty/entain/sports/proto/sports"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestEventsRepo_History(t *testing.T) {
+	db := setupVenuesDB(t)
+	if err := createEventsTable(db); err != nil {
+		t.Fatalf("createEventsTable() failed: %v", err)
+	}
+
+	now := time.Date(2026, 11, 1, 9, 30, 0, 0, time.UTC)
+	repo := NewEventsRepo(db, clock.Func(func() time.Time { return now }))
+
+	start := time.Date(2026, 11, 3, 8, 0, 0, 0, time.UTC)
+	final := &sports.ImportEventsRequest{SourceId: "feed-9", Name: "Lions v Tigers", SportType: "football", VenueId: 5, Visible: true, AdvertisedStartTime: timestamppb.New(start)}
+
+	// A dry run records nothing
+	if _, _, err := repo.Import([]*sports.ImportEventsRequest{final}, "trader-1", true); err != nil {
+		t.Fatalf("Import() dry run failed: %v", err)
+	}
+	if _, _, err := repo.Import([]*sports.ImportEventsRequest{final}, "cli", false); err != nil {
+		t.Fatalf("Import() failed: %v", err)
+	}
+
+	// Suspending an event twice records one change
+	now = now.Add(time.Hour)
+	for i := 0; i < 2; i++ {
+		if err := repo.SetSuspended(4, true, "trader-1"); err != nil {
+			t.Fatalf("SetSuspended() failed: %v", err)
+		}
+	}
+
+	now = now.Add(time.Hour)
+	final.Name = "Lions vs Tigers"
+	if _, _, err := repo.Import([]*sports.ImportEventsRequest{final}, "trader-2", false); err != nil {
+		t.Fatalf("Import() update failed: %v", err)
+	}
+
+	created := &sports.EventChange{
+		Id:          1,
+		EventId:     4,
+		Actor:       "cli",
+		ChangedTime: timestamppb.New(time.Date(2026, 11, 1, 9, 30, 0, 0, time.UTC)),
+		Type:        sports.ChangeType_CHANGE_CREATED,
+		Fields: []*sports.FieldChange{
+			{Field: "name", After: "Lions v Tigers"},
+			{Field: "sport_type", After: "football"},
+			{Field: "venue_id", After: "5"},
+			{Field: "visible", After: "true"},
+			{Field: "advertised_start_time", After: "2026-11-03T08:00:00Z"},
+		},
+	}
+	suspended := &sports.EventChange{
+		Id:          2,
+		EventId:     4,
+		Actor:       "trader-1",
+		ChangedTime: timestamppb.New(time.Date(2026, 11, 1, 10, 30, 0, 0, time.UTC)),
+		Type:        sports.ChangeType_CHANGE_UPDATED,
+		Fields:      []*sports.FieldChange{{Field: "suspended", Before: "false", After: "true"}},
+	}
+	renamed := &sports.EventChange{
+		Id:          3,
+		EventId:     4,
+		Actor:       "trader-2",
+		ChangedTime: timestamppb.New(now),
+		Type:        sports.ChangeType_CHANGE_UPDATED,
+		Fields:      []*sports.FieldChange{{Field: "name", Before: "Lions v Tigers", After: "Lions vs Tigers"}},
+	}
+
+	tests := []struct {
+		name  string
+		field string
+		want  []*sports.EventChange
+	}{
+		{name: "every change", want: []*sports.EventChange{created, suspended, renamed}},
+		{name: "suspensions", field: "suspended", want: []*sports.EventChange{suspended}},
+		{
+			name:  "name changes",
+			field: "name",
+			want: []*sports.EventChange{
+				{Id: 1, EventId: 4, Actor: "cli", ChangedTime: created.ChangedTime, Type: sports.ChangeType_CHANGE_CREATED,
+					Fields: []*sports.FieldChange{created.Fields[0]}},
+				renamed,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := repo.History(4, tt.field)
+			if err != nil {
+				t.Fatalf("History() failed: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got, protocmp.Transform()); diff != "" {
+				t.Errorf("History() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	if got, err := repo.History(1, ""); err != nil || len(got) != 0 {
+		t.Errorf("History() of an event without changes = %v, %v, want none", got, err)
+	}
+}
+
+func TestHistoryTables_AppendOnly(t *testing.T) {
+	db := setupVenuesDB(t)
+	if err := createEventsTable(db); err != nil {
+		t.Fatalf("createEventsTable() failed: %v", err)
+	}
+	if err := NewEventsRepo(db, nil).SetSuspended(1, true, "trader-1"); err != nil {
+		t.Fatalf("SetSuspended() failed: %v", err)
+	}
+
+	for _, query := range []string{
+		`UPDATE event_changes SET actor = 'someone else'`,
+		`DELETE FROM event_changes`,
+		`UPDATE event_change_fields SET new_value = ''`,
+		`DELETE FROM event_change_fields`,
+	} {
+		if _, err := db.Exec(query); err == nil {
+			t.Errorf("%s succeeded, want the history to be append-only", query)
+		}
+	}
+}
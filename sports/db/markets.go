@@ -0,0 +1,250 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+
+	"git.neds.sh/matty/entain/sports/proto/sports"
+)
+
+// ErrSettled is wrapped by the errors returned when changing a settled market.
+var ErrSettled = errors.New("is settled")
+
+// MarketsRepo provides repository access to the betting markets of events
+// and their selections.
+type MarketsRepo interface {
+	// Init will initialise our markets repository.
+	Init() error
+
+	// List will return the markets of an event, oldest first, with their
+	// selections. Events without markets give an empty list.
+	List(eventID int64) ([]*sports.Market, error)
+
+	// Save will create the market if its ID is zero, or replace it otherwise,
+	// in a single transaction, and return it as stored. Selections with an ID
+	// are updated, those without one are created and those left out are
+	// removed. Settled markets cannot be changed.
+	Save(market *sports.Market) (*sports.Market, error)
+}
+
+type marketsRepo struct {
+	db   *sql.DB
+	init sync.Once
+}
+
+// NewMarketsRepo creates a new markets repository.
+func NewMarketsRepo(db *sql.DB) MarketsRepo {
+	return &marketsRepo{db: db}
+}
+
+// Init prepares the markets repository dummy data.
+func (r *marketsRepo) Init() error {
+	var err error
+
+	r.init.Do(func() {
+		// For test/example purposes, we seed the DB with a market per dummy event.
+		err = r.seed()
+	})
+
+	return err
+}
+
+// List retrieves the markets of an event and their selections with two queries.
+func (r *marketsRepo) List(eventID int64) ([]*sports.Market, error) {
+	return listMarkets(r.db, eventID)
+}
+
+// Save inserts or updates a market and its selections. Either the whole
+// market is saved or nothing is.
+func (r *marketsRepo) Save(market *sports.Market) (*sports.Market, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	id, err := saveMarket(tx, market)
+	if err != nil {
+		return nil, err
+	}
+
+	markets, err := listMarkets(tx, market.EventId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	for _, saved := range markets {
+		if saved.Id == id {
+			return saved, nil
+		}
+	}
+	return nil, fmt.Errorf("market with ID %d %w", id, ErrNotFound)
+}
+
+// queryer is implemented by both *sql.DB and *sql.Tx.
+type queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// listMarkets reads the markets of an event and attaches their selections.
+func listMarkets(q queryer, eventID int64) ([]*sports.Market, error) {
+	rows, err := q.Query(getMarketQueries()[marketsList], eventID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	markets := []*sports.Market{}
+	byID := make(map[int64]*sports.Market)
+	for rows.Next() {
+		var (
+			market sports.Market
+			line   sql.NullFloat64
+		)
+		if err := rows.Scan(&market.Id, &market.EventId, &market.Type, &line, &market.Status); err != nil {
+			return nil, err
+		}
+		if line.Valid {
+			market.Line = &line.Float64
+		}
+
+		markets = append(markets, &market)
+		byID[market.Id] = &market
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(markets) == 0 {
+		return markets, nil
+	}
+
+	rows, err = q.Query(getMarketQueries()[marketsSelections], eventID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			marketID  int64
+			selection sports.Selection
+		)
+		if err := rows.Scan(&selection.Id, &marketID, &selection.Name, &selection.Price); err != nil {
+			return nil, err
+		}
+
+		if market, ok := byID[marketID]; ok {
+			market.Selections = append(market.Selections, &selection)
+		}
+	}
+
+	return markets, rows.Err()
+}
+
+// saveMarket writes market and its selections in tx and returns its ID.
+func saveMarket(tx *sql.Tx, market *sports.Market) (int64, error) {
+	var line sql.NullFloat64
+	if market.Line != nil {
+		line = sql.NullFloat64{Float64: *market.Line, Valid: true}
+	}
+
+	id := market.Id
+	if id == 0 {
+		res, err := tx.Exec(getMarketQueries()[marketsInsert], market.EventId, market.Type, line, market.Status)
+		if err != nil {
+			return 0, err
+		}
+		if id, err = res.LastInsertId(); err != nil {
+			return 0, err
+		}
+	} else {
+		var status sports.MarketStatus
+		err := tx.QueryRow(getMarketQueries()[marketsStatus], id, market.EventId).Scan(&status)
+		if err == sql.ErrNoRows {
+			return 0, fmt.Errorf("market with ID %d %w", id, ErrNotFound)
+		}
+		if err != nil {
+			return 0, err
+		}
+		if status == sports.MarketStatus_MARKET_SETTLED {
+			return 0, fmt.Errorf("market with ID %d %w", id, ErrSettled)
+		}
+
+		if _, err := tx.Exec(getMarketQueries()[marketsUpdate], market.Type, line, market.Status, id); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := saveSelections(tx, id, market.Selections); err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// saveSelections makes selections the only selections of a market.
+func saveSelections(tx *sql.Tx, marketID int64, selections []*sports.Selection) error {
+	existing, err := selectionIDs(tx, marketID)
+	if err != nil {
+		return err
+	}
+
+	kept := make(map[int64]bool, len(selections))
+	for _, selection := range selections {
+		if selection.Id == 0 {
+			if _, err := tx.Exec(getMarketQueries()[marketsInsertSelection], marketID, selection.Name, selection.Price); err != nil {
+				return err
+			}
+			continue
+		}
+
+		res, err := tx.Exec(getMarketQueries()[marketsUpdateSelection], selection.Name, selection.Price, selection.Id, marketID)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			return fmt.Errorf("selection with ID %d of market %d %w", selection.Id, marketID, ErrNotFound)
+		}
+		kept[selection.Id] = true
+	}
+
+	for _, id := range existing {
+		if kept[id] {
+			continue
+		}
+		if _, err := tx.Exec(getMarketQueries()[marketsDeleteSelection], id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// selectionIDs returns the IDs of the selections of a market.
+func selectionIDs(tx *sql.Tx, marketID int64) ([]int64, error) {
+	rows, err := tx.Query(getMarketQueries()[marketsSelectionIDs], marketID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
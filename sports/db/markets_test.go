@@ -0,0 +1,248 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	"git.neds.sh/matty/entain/sports/proto/sports"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+)
+
+// setupMarketsDB creates an in-memory SQLite database with the market and
+// selection tables and no markets
+func setupMarketsDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("setupMarketsDB() failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	for _, query := range []string{
+		`CREATE TABLE markets (id INTEGER PRIMARY KEY AUTOINCREMENT, event_id INTEGER, type INTEGER, line REAL, status INTEGER)`,
+		`CREATE TABLE selections (id INTEGER PRIMARY KEY AUTOINCREMENT, market_id INTEGER, name TEXT, price REAL)`,
+	} {
+		if _, err := db.Exec(query); err != nil {
+			t.Fatalf("setupMarketsDB() failed: %v", err)
+		}
+	}
+
+	return db
+}
+
+func TestMarketsRepo_Save(t *testing.T) {
+	repo := NewMarketsRepo(setupMarketsDB(t))
+	line := func(v float64) *float64 { return &v }
+
+	created, err := repo.Save(&sports.Market{
+		EventId: 1,
+		Type:    sports.MarketType_LINE,
+		Line:    line(-6.5),
+		Selections: []*sports.Selection{
+			{Name: "Home -6.5", Price: 1.9},
+			{Name: "Away +6.5", Price: 1.9},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Save() failed to create market: %v", err)
+	}
+	want := &sports.Market{
+		Id:      1,
+		EventId: 1,
+		Type:    sports.MarketType_LINE,
+		Line:    line(-6.5),
+		Selections: []*sports.Selection{
+			{Id: 1, Name: "Home -6.5", Price: 1.9},
+			{Id: 2, Name: "Away +6.5", Price: 1.9},
+		},
+	}
+	if diff := cmp.Diff(want, created, protocmp.Transform()); diff != "" {
+		t.Errorf("Save() created market mismatch (-want +got):\n%s", diff)
+	}
+
+	// Move the line, replacing the away selection
+	updated, err := repo.Save(&sports.Market{
+		Id:      1,
+		EventId: 1,
+		Type:    sports.MarketType_LINE,
+		Line:    line(-7.5),
+		Status:  sports.MarketStatus_MARKET_SUSPENDED,
+		Selections: []*sports.Selection{
+			{Id: 1, Name: "Home -7.5", Price: 1.95},
+			{Name: "Away +7.5", Price: 1.85},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Save() failed to update market: %v", err)
+	}
+	want = &sports.Market{
+		Id:      1,
+		EventId: 1,
+		Type:    sports.MarketType_LINE,
+		Line:    line(-7.5),
+		Status:  sports.MarketStatus_MARKET_SUSPENDED,
+		Selections: []*sports.Selection{
+			{Id: 1, Name: "Home -7.5", Price: 1.95},
+			{Id: 3, Name: "Away +7.5", Price: 1.85},
+		},
+	}
+	if diff := cmp.Diff(want, updated, protocmp.Transform()); diff != "" {
+		t.Errorf("Save() updated market mismatch (-want +got):\n%s", diff)
+	}
+
+	markets, err := repo.List(1)
+	if err != nil {
+		t.Fatalf("List() failed: %v", err)
+	}
+	if diff := cmp.Diff([]*sports.Market{want}, markets, protocmp.Transform()); diff != "" {
+		t.Errorf("List() mismatch (-want +got):\n%s", diff)
+	}
+
+	markets, err = repo.List(2)
+	if err != nil {
+		t.Fatalf("List() failed: %v", err)
+	}
+	if len(markets) != 0 {
+		t.Errorf("List() returned %d markets for an event without any, want 0", len(markets))
+	}
+}
+
+func TestMarketsRepo_Save_Errors(t *testing.T) {
+	repo := NewMarketsRepo(setupMarketsDB(t))
+
+	selections := []*sports.Selection{{Name: "Home", Price: 1.9}, {Name: "Away", Price: 1.9}}
+	if _, err := repo.Save(&sports.Market{EventId: 1, Selections: selections}); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+	settled, err := repo.Save(&sports.Market{EventId: 1, Status: sports.MarketStatus_MARKET_SETTLED, Selections: selections})
+	if err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		market  *sports.Market
+		wantErr error
+	}{
+		{
+			name:    "unknown market",
+			market:  &sports.Market{Id: 42, EventId: 1, Selections: selections},
+			wantErr: ErrNotFound,
+		},
+		{
+			name:    "market of another event",
+			market:  &sports.Market{Id: 1, EventId: 2, Selections: selections},
+			wantErr: ErrNotFound,
+		},
+		{
+			name: "selection of another market",
+			market: &sports.Market{Id: 1, EventId: 1, Selections: []*sports.Selection{
+				{Id: settled.Selections[0].Id, Name: "Home", Price: 2},
+				{Name: "Away", Price: 1.8},
+			}},
+			wantErr: ErrNotFound,
+		},
+		{
+			name:    "settled market",
+			market:  &sports.Market{Id: settled.Id, EventId: 1, Selections: selections},
+			wantErr: ErrSettled,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before, err := repo.List(1)
+			if err != nil {
+				t.Fatalf("List() failed: %v", err)
+			}
+
+			if _, err := repo.Save(tt.market); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Save() error = %v, want %v", err, tt.wantErr)
+			}
+
+			after, err := repo.List(1)
+			if err != nil {
+				t.Fatalf("List() failed: %v", err)
+			}
+			if diff := cmp.Diff(before, after, protocmp.Transform()); diff != "" {
+				t.Errorf("failed Save() changed the markets (-before +after):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestMarketsRepo_Init(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	if err := NewEventsRepo(db, nil).Init(); err != nil {
+		t.Fatalf("events Init() failed: %v", err)
+	}
+	repo := NewMarketsRepo(db)
+	if err := repo.Init(); err != nil {
+		t.Fatalf("Init() failed: %v", err)
+	}
+
+	event, err := NewEventsRepo(db, nil).GetByID(1)
+	if err != nil {
+		t.Fatalf("GetByID() failed: %v", err)
+	}
+	markets, err := repo.List(1)
+	if err != nil {
+		t.Fatalf("List() failed: %v", err)
+	}
+	if len(markets) != 1 {
+		t.Fatalf("seeded %d markets for event 1, want 1", len(markets))
+	}
+
+	market := markets[0]
+	if market.Type != sports.MarketType_HEAD_TO_HEAD || len(market.Selections) != 2 {
+		t.Fatalf("seeded market %v, want a head to head with 2 selections", market)
+	}
+	if got := market.Selections[0].Name + " vs " + market.Selections[1].Name; got != event.Name {
+		t.Errorf("seeded selections %q, want the teams of %q", got, event.Name)
+	}
+	for _, selection := range market.Selections {
+		if selection.Price < sports.MinPrice {
+			t.Errorf("selection %q: seeded price %g below the minimum", selection.Name, selection.Price)
+		}
+	}
+}
+
+func TestEventsRepo_SetSuspended(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	// A table created before events could be suspended is migrated
+	if _, err := db.Exec(`CREATE TABLE events (id INTEGER PRIMARY KEY, name TEXT, advertised_start_time DATETIME, sport_type TEXT, venue TEXT, visible INTEGER)`); err != nil {
+		t.Fatalf("failed to create events table: %v", err)
+	}
+	repo := NewEventsRepo(db, nil)
+	if err := repo.Init(); err != nil {
+		t.Fatalf("Init() failed: %v", err)
+	}
+
+	if err := repo.SetSuspended(1, true, "trader-1"); err != nil {
+		t.Fatalf("SetSuspended() failed: %v", err)
+	}
+	event, err := repo.GetByID(1)
+	if err != nil {
+		t.Fatalf("GetByID() failed: %v", err)
+	}
+	if !event.Suspended {
+		t.Error("event not suspended after SetSuspended(1, true)")
+	}
+
+	if err := repo.SetSuspended(1000, true, "trader-1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("SetSuspended() of an unknown event error = %v, want %v", err, ErrNotFound)
+	}
+}
@@ -1,8 +1,14 @@
 package db
 
 const (
-	eventsList    = "list"
-	eventsGetByID = "getByID"
+	eventsList         = "list"
+	eventsGetByID      = "getByID"
+	eventsGetByIDs     = "getByIDs"
+	eventsSuspend      = "suspend"
+	eventsImportUpdate = "importUpdate"
+	eventsImportInsert = "importInsert"
+	eventsImportGet    = "importGet"
+	eventsSuspended    = "suspended"
 )
 
 func getEventQueries() map[string]string {
@@ -14,7 +20,14 @@ func getEventQueries() map[string]string {
 				advertised_start_time,
 				sport_type,
 				venue,
-				visible
+				visible,
+				suspended,
+				COALESCE(competition_id, 0),
+				COALESCE(season_id, 0),
+				COALESCE(home_team_id, 0),
+				COALESCE(away_team_id, 0),
+				COALESCE(venue_id, 0),
+				COALESCE((SELECT time_zone FROM venues WHERE venues.id = events.venue_id), '')
 			FROM events
 		`,
 		eventsGetByID: `
@@ -24,9 +37,272 @@ func getEventQueries() map[string]string {
 				advertised_start_time,
 				sport_type,
 				venue,
-				visible
+				visible,
+				suspended,
+				COALESCE(competition_id, 0),
+				COALESCE(season_id, 0),
+				COALESCE(home_team_id, 0),
+				COALESCE(away_team_id, 0),
+				COALESCE(venue_id, 0),
+				COALESCE((SELECT time_zone FROM venues WHERE venues.id = events.venue_id), '')
 			FROM events 
 			WHERE id = ?
 		`,
+		eventsGetByIDs: `
+			SELECT 
+				id, 
+				name, 
+				advertised_start_time,
+				sport_type,
+				venue,
+				visible,
+				suspended,
+				COALESCE(competition_id, 0),
+				COALESCE(season_id, 0),
+				COALESCE(home_team_id, 0),
+				COALESCE(away_team_id, 0),
+				COALESCE(venue_id, 0),
+				COALESCE((SELECT time_zone FROM venues WHERE venues.id = events.venue_id), '')
+			FROM events 
+			WHERE id IN (%s)
+		`,
+		eventsSuspend: `
+			UPDATE events 
+			SET suspended = ? 
+			WHERE id = ?
+		`,
+		eventsImportUpdate: `
+			UPDATE events 
+			SET name = ?, sport_type = ?, visible = ?, advertised_start_time = ?, 
+				venue_id = NULLIF(?, 0), 
+				venue = COALESCE((SELECT name FROM venues WHERE venues.id = ?), '') 
+			WHERE source_id = ?
+		`,
+		eventsImportInsert: `
+			INSERT INTO events (source_id, name, sport_type, visible, advertised_start_time, venue_id, venue) 
+			VALUES (?, ?, ?, ?, ?, NULLIF(?, 0), COALESCE((SELECT name FROM venues WHERE venues.id = ?), ''))
+		`,
+		eventsImportGet: `
+			SELECT id, name, sport_type, COALESCE(venue_id, 0), visible, advertised_start_time 
+			FROM events 
+			WHERE source_id = ?
+		`,
+		eventsSuspended: `
+			SELECT suspended 
+			FROM events 
+			WHERE id = ?
+		`,
+	}
+}
+
+const (
+	historyInsertChange = "insertChange"
+	historyInsertField  = "insertField"
+	historyList         = "list"
+)
+
+func getHistoryQueries() map[string]string {
+	return map[string]string{
+		historyInsertChange: `
+			INSERT INTO event_changes (event_id, actor, type, changed_time) 
+			VALUES (?, ?, ?, ?)
+		`,
+		historyInsertField: `
+			INSERT INTO event_change_fields (change_id, field, old_value, new_value) 
+			VALUES (?, ?, ?, ?)
+		`,
+		historyList: `
+			SELECT c.id, c.event_id, c.actor, c.type, c.changed_time, f.field, f.old_value, f.new_value 
+			FROM event_changes c 
+			JOIN event_change_fields f ON f.change_id = c.id 
+			WHERE c.event_id = ? AND (? = '' OR f.field = ?) 
+			ORDER BY c.id, f.rowid
+		`,
+	}
+}
+
+const (
+	marketsList            = "list"
+	marketsSelections      = "selections"
+	marketsStatus          = "status"
+	marketsInsert          = "insert"
+	marketsUpdate          = "update"
+	marketsSelectionIDs    = "selectionIDs"
+	marketsInsertSelection = "insertSelection"
+	marketsUpdateSelection = "updateSelection"
+	marketsDeleteSelection = "deleteSelection"
+)
+
+func getMarketQueries() map[string]string {
+	return map[string]string{
+		marketsList: `
+			SELECT 
+				id, 
+				event_id, 
+				type, 
+				line, 
+				status 
+			FROM markets 
+			WHERE event_id = ? 
+			ORDER BY id
+		`,
+		marketsSelections: `
+			SELECT 
+				s.id, 
+				s.market_id, 
+				s.name, 
+				s.price 
+			FROM selections s 
+			JOIN markets m ON m.id = s.market_id 
+			WHERE m.event_id = ? 
+			ORDER BY s.id
+		`,
+		marketsStatus: `
+			SELECT status 
+			FROM markets 
+			WHERE id = ? AND event_id = ?
+		`,
+		marketsInsert: `
+			INSERT INTO markets (event_id, type, line, status) 
+			VALUES (?, ?, ?, ?)
+		`,
+		marketsUpdate: `
+			UPDATE markets 
+			SET type = ?, line = ?, status = ? 
+			WHERE id = ?
+		`,
+		marketsSelectionIDs: `
+			SELECT id 
+			FROM selections 
+			WHERE market_id = ?
+		`,
+		marketsInsertSelection: `
+			INSERT INTO selections (market_id, name, price) 
+			VALUES (?, ?, ?)
+		`,
+		marketsUpdateSelection: `
+			UPDATE selections 
+			SET name = ?, price = ? 
+			WHERE id = ? AND market_id = ?
+		`,
+		marketsDeleteSelection: `
+			DELETE FROM selections 
+			WHERE id = ?
+		`,
+	}
+}
+
+const (
+	hierarchySports             = "sports"
+	hierarchySportExists        = "sportExists"
+	hierarchyCompetitions       = "competitions"
+	hierarchyCompetition        = "competition"
+	hierarchySeasons            = "seasons"
+	hierarchyCompetitionSeasons = "competitionSeasons"
+	hierarchyTeams              = "teams"
+	hierarchyUnassignedEvents   = "unassignedEvents"
+	hierarchyAssignEvent        = "assignEvent"
+)
+
+func getHierarchyQueries() map[string]string {
+	return map[string]string{
+		hierarchySports: `
+			SELECT 
+				id, 
+				name 
+			FROM sports 
+			ORDER BY name
+		`,
+		hierarchySportExists: `
+			SELECT COUNT(*) 
+			FROM sports 
+			WHERE id = ?
+		`,
+		hierarchyCompetitions: `
+			SELECT 
+				id, 
+				sport_id, 
+				name 
+			FROM competitions 
+			WHERE sport_id = ? 
+			ORDER BY name
+		`,
+		hierarchyCompetition: `
+			SELECT 
+				id, 
+				sport_id, 
+				name 
+			FROM competitions 
+			WHERE id = ?
+		`,
+		hierarchySeasons: `
+			SELECT 
+				s.id, 
+				s.competition_id, 
+				s.name 
+			FROM seasons s 
+			JOIN competitions c ON c.id = s.competition_id 
+			WHERE c.sport_id = ? 
+			ORDER BY s.name
+		`,
+		hierarchyCompetitionSeasons: `
+			SELECT 
+				id, 
+				competition_id, 
+				name 
+			FROM seasons 
+			WHERE competition_id = ? 
+			ORDER BY name
+		`,
+		hierarchyTeams: `
+			SELECT 
+				id, 
+				sport_id, 
+				name 
+			FROM teams 
+			WHERE sport_id = ? 
+			ORDER BY name
+		`,
+		hierarchyUnassignedEvents: `
+			SELECT 
+				id, 
+				name, 
+				advertised_start_time, 
+				sport_type 
+			FROM events 
+			WHERE competition_id IS NULL
+		`,
+		hierarchyAssignEvent: `
+			UPDATE events 
+			SET competition_id = ?, season_id = ?, home_team_id = ?, away_team_id = ? 
+			WHERE id = ?
+		`,
+	}
+}
+
+const (
+	venuesList  = "list"
+	venuesZones = "zones"
+)
+
+func getVenueQueries() map[string]string {
+	return map[string]string{
+		venuesList: `
+			SELECT 
+				id, 
+				name, 
+				time_zone, 
+				country, 
+				latitude, 
+				longitude 
+			FROM venues 
+			ORDER BY name
+		`,
+		venuesZones: `
+			SELECT 
+				id, 
+				time_zone 
+			FROM venues
+		`,
 	}
 }
@@ -0,0 +1,226 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"syreclabs.com/go/faker"
+)
+
+// seedBatchSize is the number of rows inserted by each statement of Seed.
+const seedBatchSize = 500
+
+// sportTypes are the sports events are generated for.
+var sportTypes = []string{"football", "basketball", "tennis", "soccer", "baseball", "hockey"}
+
+// SeedOptions control the events generated by Seed.
+type SeedOptions struct {
+	// RandSeed seeds the random generator, so the same options always
+	// generate the same events.
+	RandSeed int64
+	// EventsPerSport is the number of events of each sport type, spread over
+	// the seeded venues.
+	EventsPerSport int
+	// Start and Window bound the advertised start times of the events.
+	Start  time.Time
+	Window time.Duration
+	// VisibleRatio is the share of events that are visible, from 0 to 1.
+	VisibleRatio float64
+	// Reset deletes the existing events and markets first. Otherwise
+	// existing events keep their data.
+	Reset bool
+}
+
+// DefaultSeedOptions returns the options of the dummy events seeded at
+// startup: 17 events of each sport starting from a day before now to two days
+// after, half of them visible.
+func DefaultSeedOptions(now time.Time) SeedOptions {
+	return SeedOptions{
+		RandSeed:       1,
+		EventsPerSport: 17,
+		Start:          now.AddDate(0, 0, -1),
+		Window:         72 * time.Hour,
+		VisibleRatio:   0.5,
+	}
+}
+
+// Validate checks the options for counts and ratios out of range.
+func (o SeedOptions) Validate() error {
+	switch {
+	case o.EventsPerSport < 1:
+		return errors.New("events per sport must be at least 1")
+	case o.Window <= 0:
+		return errors.New("window must be positive")
+	case o.VisibleRatio < 0 || o.VisibleRatio > 1:
+		return errors.New("visible ratio must be from 0 to 1")
+	}
+	return nil
+}
+
+// Seed creates the event tables and generates the events described by opts in
+// a single transaction. It returns the number of events generated.
+//
+// Names come from faker, whose package-wide generator Seed reseeds, so Seed
+// must not run alongside other users of faker.
+func Seed(db *sql.DB, opts SeedOptions) (int, error) {
+	if err := opts.Validate(); err != nil {
+		return 0, fmt.Errorf("invalid seed options: %w", err)
+	}
+	if err := createEventsTable(db); err != nil {
+		return 0, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if opts.Reset {
+		if err := clearTables(tx, "events", "markets", "selections"); err != nil {
+			return 0, err
+		}
+	}
+
+	venues, err := venueNames(tx)
+	if err != nil {
+		return 0, err
+	}
+
+	rng := rand.New(rand.NewSource(opts.RandSeed))
+	faker.Seed(opts.RandSeed)
+
+	events := newBatchInserter(tx, `INSERT OR IGNORE INTO events(id, name, advertised_start_time, sport_type, venue, visible, venue_id)`, 7)
+
+	total := opts.EventsPerSport * len(sportTypes)
+	for id := 1; id <= total; id++ {
+		venue := venues[id%len(venues)]
+		start := opts.Start.Add(time.Duration(rng.Int63n(int64(opts.Window))))
+		if err := events.add(
+			id,
+			faker.Team().Name()+" vs "+faker.Team().Name(), // Create match-style names
+			start.UTC().Format(time.RFC3339),
+			sportTypes[id%len(sportTypes)],
+			venue.name,
+			rng.Float64() < opts.VisibleRatio,
+			venue.id,
+		); err != nil {
+			return 0, err
+		}
+	}
+	if err := events.flush(); err != nil {
+		return 0, err
+	}
+
+	// Link events created before they had a venue ID to the venue they name
+	if _, err := tx.Exec(`UPDATE events SET venue_id = (SELECT id FROM venues WHERE venues.name = events.venue) WHERE venue_id IS NULL`); err != nil {
+		return 0, err
+	}
+
+	return total, tx.Commit()
+}
+
+type venueName struct {
+	id   int64
+	name string
+}
+
+// venueNames returns the IDs and names of the venues in order of their ID.
+func venueNames(tx *sql.Tx) ([]venueName, error) {
+	rows, err := tx.Query(`SELECT id, name FROM venues ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var venues []venueName
+	for rows.Next() {
+		var venue venueName
+		if err := rows.Scan(&venue.id, &venue.name); err != nil {
+			return nil, err
+		}
+		venues = append(venues, venue)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(venues) == 0 {
+		return nil, errors.New("no venues to hold events at")
+	}
+
+	return venues, nil
+}
+
+// clearTables deletes all rows of the tables that exist.
+func clearTables(tx *sql.Tx, tables ...string) error {
+	for _, table := range tables {
+		var n int
+		if err := tx.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`, table).Scan(&n); err != nil {
+			return err
+		}
+		if n == 0 {
+			continue
+		}
+		if _, err := tx.Exec(`DELETE FROM ` + table); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// batchInserter inserts rows with multi-row INSERT statements of up to
+// seedBatchSize rows, reusing the statement prepared for full batches.
+type batchInserter struct {
+	tx      *sql.Tx
+	insert  string
+	columns int
+	full    *sql.Stmt
+	args    []interface{}
+}
+
+func newBatchInserter(tx *sql.Tx, insert string, columns int) *batchInserter {
+	return &batchInserter{tx: tx, insert: insert, columns: columns}
+}
+
+// add queues a row, inserting the batch once it is full.
+func (b *batchInserter) add(values ...interface{}) error {
+	b.args = append(b.args, values...)
+	if len(b.args) < seedBatchSize*b.columns {
+		return nil
+	}
+
+	if b.full == nil {
+		stmt, err := b.tx.Prepare(b.statement(seedBatchSize))
+		if err != nil {
+			return err
+		}
+		b.full = stmt
+	}
+	_, err := b.full.Exec(b.args...)
+	b.args = b.args[:0]
+	return err
+}
+
+// flush inserts the queued rows and releases the prepared statement.
+func (b *batchInserter) flush() error {
+	if b.full != nil {
+		defer b.full.Close()
+	}
+	if len(b.args) == 0 {
+		return nil
+	}
+
+	_, err := b.tx.Exec(b.statement(len(b.args)/b.columns), b.args...)
+	b.args = b.args[:0]
+	return err
+}
+
+// statement returns the INSERT statement for rows rows.
+func (b *batchInserter) statement(rows int) string {
+	row := "(" + strings.Repeat("?,", b.columns-1) + "?)"
+	return b.insert + " VALUES " + strings.Repeat(row+",", rows-1) + row
+}
@@ -0,0 +1,193 @@
+package db
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"git.neds.sh/matty/entain/sports/proto/sports"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// openSeedDB opens an empty in-memory SQLite database
+func openSeedDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestSeed(t *testing.T) {
+	start := time.Date(2026, 11, 3, 0, 0, 0, 0, time.UTC)
+	opts := SeedOptions{
+		RandSeed:       42,
+		EventsPerSport: 3,
+		Start:          start,
+		Window:         12 * time.Hour,
+		VisibleRatio:   0.5,
+	}
+
+	seeded := func() []*sports.Event {
+		db := openSeedDB(t)
+		n, err := Seed(db, opts)
+		if err != nil {
+			t.Fatalf("Seed() failed: %v", err)
+		}
+		if want := 3 * len(sportTypes); n != want {
+			t.Errorf("Seed() = %d events, want %d", n, want)
+		}
+
+		events, err := NewEventsRepo(db, nil).List(nil)
+		if err != nil {
+			t.Fatalf("List() failed: %v", err)
+		}
+		return events
+	}
+
+	events := seeded()
+	if diff := cmp.Diff(events, seeded(), protocmp.Transform(), protocmp.IgnoreFields(&sports.Event{}, "status")); diff != "" {
+		t.Errorf("Seed() is not reproducible (-first +second):\n%s", diff)
+	}
+
+	perSport := make(map[string]int)
+	for _, event := range events {
+		perSport[event.SportType]++
+		if got := event.AdvertisedStartTime.AsTime(); got.Before(start) || !got.Before(start.Add(opts.Window)) {
+			t.Errorf("event %d starts at %v, outside the window", event.Id, got)
+		}
+		if event.VenueId == 0 || !strings.Contains(event.Name, " vs ") {
+			t.Errorf("event %d %q is at venue %d", event.Id, event.Name, event.VenueId)
+		}
+	}
+	for _, sportType := range sportTypes {
+		if perSport[sportType] != 3 {
+			t.Errorf("%d %s events, want 3", perSport[sportType], sportType)
+		}
+	}
+}
+
+func TestSeed_VisibleRatio(t *testing.T) {
+	for _, ratio := range []float64{0, 1} {
+		db := openSeedDB(t)
+		opts := DefaultSeedOptions(time.Now())
+		opts.VisibleRatio = ratio
+		n, err := Seed(db, opts)
+		if err != nil {
+			t.Fatalf("Seed() failed: %v", err)
+		}
+
+		var visible int
+		if err := db.QueryRow(`SELECT COUNT(*) FROM events WHERE visible = 1`).Scan(&visible); err != nil {
+			t.Fatalf("failed to count visible events: %v", err)
+		}
+		if want := int(ratio * float64(n)); visible != want {
+			t.Errorf("ratio %v: %d visible events, want %d", ratio, visible, want)
+		}
+	}
+}
+
+func TestSeed_Reset(t *testing.T) {
+	db := openSeedDB(t)
+	opts := DefaultSeedOptions(time.Now())
+	if err := NewEventsRepo(db, nil).Init(); err != nil {
+		t.Fatalf("events Init() failed: %v", err)
+	}
+	if err := NewMarketsRepo(db).Init(); err != nil {
+		t.Fatalf("markets Init() failed: %v", err)
+	}
+
+	// Without a reset the existing events are kept
+	opts.EventsPerSport = 1
+	opts.RandSeed = 2
+	if _, err := Seed(db, opts); err != nil {
+		t.Fatalf("Seed() failed: %v", err)
+	}
+	countRows(t, db, "events", 17*len(sportTypes))
+
+	opts.Reset = true
+	if _, err := Seed(db, opts); err != nil {
+		t.Fatalf("Seed() with reset failed: %v", err)
+	}
+	countRows(t, db, "events", len(sportTypes))
+	countRows(t, db, "markets", 0)
+	countRows(t, db, "selections", 0)
+}
+
+func countRows(t *testing.T, db *sql.DB, table string, want int) {
+	t.Helper()
+
+	var n int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM ` + table).Scan(&n); err != nil {
+		t.Fatalf("failed to count %s: %v", table, err)
+	}
+	if n != want {
+		t.Errorf("%d %s, want %d", n, table, want)
+	}
+}
+
+func TestSeed_InvalidOptions(t *testing.T) {
+	valid := DefaultSeedOptions(time.Now())
+	tests := []struct {
+		name    string
+		modify  func(o *SeedOptions)
+		wantErr string
+	}{
+		{"no events", func(o *SeedOptions) { o.EventsPerSport = 0 }, "events per sport"},
+		{"empty window", func(o *SeedOptions) { o.Window = -time.Hour }, "window"},
+		{"negative ratio", func(o *SeedOptions) { o.VisibleRatio = -0.1 }, "visible ratio"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := valid
+			tt.modify(&opts)
+
+			_, err := Seed(openSeedDB(t), opts)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Seed() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func BenchmarkSeed(b *testing.B) {
+	opts := DefaultSeedOptions(time.Now())
+	opts.EventsPerSport = 100000 / len(sportTypes)
+
+	for i := 0; i < b.N; i++ {
+		db, err := sql.Open("sqlite3", ":memory:")
+		if err != nil {
+			b.Fatalf("failed to open database: %v", err)
+		}
+		if _, err := Seed(db, opts); err != nil {
+			b.Fatalf("Seed() failed: %v", err)
+		}
+		db.Close()
+	}
+}
+
+func TestCreateTables_SeedsNoEvents(t *testing.T) {
+	db := openSeedDB(t)
+	if err := CreateTables(db); err != nil {
+		t.Fatalf("CreateTables() failed: %v", err)
+	}
+
+	// An import into a fresh database stores the feed and nothing else
+	if _, _, err := NewEventsRepo(db, nil).Import([]*sports.ImportEventsRequest{&sports.ImportEventsRequest{SourceId: "feed-9", Name: "Lions vs Tigers", SportType: "football", VenueId: 5, Visible: true, AdvertisedStartTime: timestamppb.Now()}}, "trader-1", false); err != nil {
+		t.Fatalf("Import() failed: %v", err)
+	}
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM events`).Scan(&count); err != nil {
+		t.Fatalf("failed to count events: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("got %d events, want the imported one only", count)
+	}
+}
@@ -0,0 +1,143 @@
+package db
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"git.neds.sh/matty/entain/sports/proto/sports"
+	"github.com/google/go-cmp/cmp"
+)
+
+// setupVenuesDB creates an in-memory SQLite database with the seeded venues
+// and an event starting now at Stadium A, one starting in two days at Dome E
+// and one starting now at a venue that is not in the venues table
+func setupVenuesDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("setupVenuesDB() failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if _, err := db.Exec(`CREATE TABLE events (id INTEGER PRIMARY KEY, name TEXT, advertised_start_time DATETIME, sport_type TEXT, venue TEXT, visible INTEGER, suspended INTEGER NOT NULL DEFAULT 0, competition_id INTEGER, season_id INTEGER, home_team_id INTEGER, away_team_id INTEGER, venue_id INTEGER)`); err != nil {
+		t.Fatalf("setupVenuesDB() failed to create events: %v", err)
+	}
+	now := time.Now().UTC()
+	for _, event := range []struct {
+		id      int64
+		venue   string
+		venueID interface{}
+		start   time.Time
+	}{
+		{1, "Stadium A", 1, now},
+		{2, "Dome E", 5, now.AddDate(0, 0, 2)},
+		{3, "Paddock Z", nil, now},
+	} {
+		if _, err := db.Exec(`INSERT INTO events (id, name, advertised_start_time, sport_type, venue, visible, venue_id) VALUES (?, 'Team A vs Team B', ?, 'football', ?, 1, ?)`,
+			event.id, event.start.Format(time.RFC3339), event.venue, event.venueID); err != nil {
+			t.Fatalf("setupVenuesDB() failed to insert event %d: %v", event.id, err)
+		}
+	}
+
+	if err := NewVenuesRepo(db).Init(); err != nil {
+		t.Fatalf("setupVenuesDB() failed to init venues: %v", err)
+	}
+
+	return db
+}
+
+func TestVenuesRepo_List(t *testing.T) {
+	venues, err := NewVenuesRepo(setupVenuesDB(t)).List()
+	if err != nil {
+		t.Fatalf("List() failed: %v", err)
+	}
+
+	var names []string
+	for _, venue := range venues {
+		names = append(names, venue.Name)
+		if _, err := time.LoadLocation(venue.TimeZone); err != nil {
+			t.Errorf("venue %q has an invalid time zone %q: %v", venue.Name, venue.TimeZone, err)
+		}
+	}
+	want := []string{"Arena B", "Court C", "Dome E", "Field D", "Stadium A"}
+	if diff := cmp.Diff(want, names); diff != "" {
+		t.Errorf("List() names mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestEventsRepo_AdvertisedStartLocal(t *testing.T) {
+	repo := NewEventsRepo(setupVenuesDB(t), nil)
+
+	events, err := repo.GetByIDs([]int64{1, 3})
+	if err != nil {
+		t.Fatalf("GetByIDs() failed: %v", err)
+	}
+
+	melbourne, _ := time.LoadLocation("Australia/Melbourne")
+	want := events[0].AdvertisedStartTime.AsTime().In(melbourne).Format(time.RFC3339)
+	if events[0].VenueId != 1 || events[0].AdvertisedStartLocal != want {
+		t.Errorf("event 1 venue %d starting %q, want venue 1 starting %q", events[0].VenueId, events[0].AdvertisedStartLocal, want)
+	}
+	if events[1].VenueId != 0 || events[1].AdvertisedStartLocal != "" {
+		t.Errorf("event 3 venue %d starting %q, want no venue or local start", events[1].VenueId, events[1].AdvertisedStartLocal)
+	}
+}
+
+func TestEventsRepo_List_VenueFilters(t *testing.T) {
+	repo := NewEventsRepo(setupVenuesDB(t), nil)
+	today := true
+
+	tests := []struct {
+		name   string
+		filter *sports.ListEventsRequestFilter
+		want   []int64
+	}{
+		{name: "venues", filter: &sports.ListEventsRequestFilter{VenueIds: []int64{1, 5}}, want: []int64{1, 2}},
+		{name: "today at venue", filter: &sports.ListEventsRequestFilter{TodayAtVenue: &today}, want: []int64{1}},
+		{name: "today at another venue", filter: &sports.ListEventsRequestFilter{VenueIds: []int64{5}, TodayAtVenue: &today}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, err := repo.List(tt.filter)
+			if err != nil {
+				t.Fatalf("List() failed: %v", err)
+			}
+			var got []int64
+			for _, event := range events {
+				got = append(got, event.Id)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("List() IDs mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestStartsToday(t *testing.T) {
+	// 23:30 UTC on 17 October is already 18 October in Melbourne
+	now := time.Date(2026, 10, 17, 23, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		start time.Time
+		zone  string
+		want  bool
+	}{
+		{name: "same local day", start: time.Date(2026, 10, 18, 5, 0, 0, 0, time.UTC), zone: "Australia/Melbourne", want: true},
+		{name: "same UTC day, previous local day", start: time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC), zone: "Australia/Melbourne"},
+		{name: "same UTC day", start: time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC), zone: "America/New_York", want: true},
+		{name: "unknown zone", start: now, zone: ""},
+		{name: "invalid zone", start: now, zone: "Mars/Olympus_Mons"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := startsToday(tt.start, now, tt.zone); got != tt.want {
+				t.Errorf("startsToday() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
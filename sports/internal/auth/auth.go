@@ -0,0 +1,189 @@
+package auth
+
+import (
+	"context"
+	"strings"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Metadata keys set by the API gateway after it has validated a bearer token.
+// Backends must only be reachable through the gateway (see mutual TLS) for
+// these to be trusted.
+const (
+	MetadataSubject = "x-auth-subject"
+	MetadataRoles   = "x-auth-roles"
+)
+
+// Roles known to the service.
+const (
+	// RoleAnonymous is required by methods that anyone may call.
+	RoleAnonymous = ""
+	// RoleTrader may see hidden events and call write RPCs.
+	RoleTrader = "trader"
+)
+
+// HealthMethodRoles opens the gRPC health checking protocol to everyone, so
+// that load balancers can probe the server without credentials.
+var HealthMethodRoles = map[string]string{
+	"/grpc.health.v1.Health/Check": RoleAnonymous,
+	"/grpc.health.v1.Health/Watch": RoleAnonymous,
+}
+
+// ReflectionMethodRoles opens the server reflection service to everyone. It
+// is only registered when enabled in the configuration.
+var ReflectionMethodRoles = map[string]string{
+	"/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo": RoleAnonymous,
+}
+
+// MergeMethodRoles combines method role maps into a new map.
+func MergeMethodRoles(maps ...map[string]string) map[string]string {
+	merged := make(map[string]string)
+	for _, m := range maps {
+		for method, role := range m {
+			merged[method] = role
+		}
+	}
+	return merged
+}
+
+// Identity describes the caller of an RPC.
+type Identity struct {
+	Subject string
+	Roles   []string
+}
+
+// IsAnonymous reports whether the caller did not authenticate.
+func (i Identity) IsAnonymous() bool {
+	return i.Subject == ""
+}
+
+// HasRole reports whether the caller holds role. Every caller holds RoleAnonymous.
+func (i Identity) HasRole(role string) bool {
+	if role == RoleAnonymous {
+		return true
+	}
+	for _, r := range i.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+type identityKey struct{}
+
+// NewContext returns a copy of ctx carrying the identity.
+func NewContext(ctx context.Context, id Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, id)
+}
+
+// FromContext returns the identity stored in ctx, or an anonymous identity.
+func FromContext(ctx context.Context) Identity {
+	id, _ := ctx.Value(identityKey{}).(Identity)
+	return id
+}
+
+// FromMetadata reads the identity forwarded by the gateway from incoming metadata.
+// Roles may be sent as repeated values, comma-separated, or both.
+func FromMetadata(ctx context.Context) Identity {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return Identity{}
+	}
+
+	var id Identity
+	if subjects := md.Get(MetadataSubject); len(subjects) > 0 {
+		id.Subject = strings.TrimSpace(subjects[0])
+	}
+	if id.Subject == "" {
+		return Identity{}
+	}
+
+	for _, value := range md.Get(MetadataRoles) {
+		for _, role := range strings.Split(value, ",") {
+			if role = strings.TrimSpace(role); role != "" {
+				id.Roles = append(id.Roles, role)
+			}
+		}
+	}
+
+	return id
+}
+
+// UnaryServerInterceptor attaches the caller identity to the request context and
+// enforces methodRoles, which maps full method names such as
+// "/sports.Sports/ListEvents" to the role required to call them.
+// Methods missing from the map are denied.
+func UnaryServerInterceptor(methodRoles map[string]string, logger *zap.Logger) grpc.UnaryServerInterceptor {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		id := FromMetadata(ctx)
+		if err := authorize(id, info.FullMethod, methodRoles); err != nil {
+			logger.Warn("Request denied",
+				zap.String("method", info.FullMethod),
+				zap.String("subject", id.Subject),
+				zap.Strings("roles", id.Roles),
+			)
+			return nil, err
+		}
+
+		return handler(NewContext(ctx, id), req)
+	}
+}
+
+// StreamServerInterceptor is the streaming counterpart of UnaryServerInterceptor.
+func StreamServerInterceptor(methodRoles map[string]string, logger *zap.Logger) grpc.StreamServerInterceptor {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		id := FromMetadata(ss.Context())
+		if err := authorize(id, info.FullMethod, methodRoles); err != nil {
+			logger.Warn("Stream denied",
+				zap.String("method", info.FullMethod),
+				zap.String("subject", id.Subject),
+				zap.Strings("roles", id.Roles),
+			)
+			return err
+		}
+
+		return handler(srv, &identityStream{ServerStream: ss, ctx: NewContext(ss.Context(), id)})
+	}
+}
+
+// authorize checks that id may call method.
+func authorize(id Identity, method string, methodRoles map[string]string) error {
+	required, ok := methodRoles[method]
+	if !ok {
+		return status.Errorf(codes.PermissionDenied, "method %s is not allowed", method)
+	}
+
+	if id.HasRole(required) {
+		return nil
+	}
+
+	if id.IsAnonymous() {
+		return status.Errorf(codes.Unauthenticated, "method %s requires authentication", method)
+	}
+
+	return status.Errorf(codes.PermissionDenied, "method %s requires role %q", method, required)
+}
+
+// identityStream overrides the context of a server stream.
+type identityStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *identityStream) Context() context.Context {
+	return s.ctx
+}
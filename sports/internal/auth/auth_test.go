@@ -0,0 +1,154 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestFromMetadata(t *testing.T) {
+	tests := []struct {
+		name string
+		md   metadata.MD
+		want Identity
+	}{
+		{
+			name: "no metadata",
+			want: Identity{},
+		},
+		{
+			name: "roles without subject are ignored",
+			md:   metadata.Pairs(MetadataRoles, RoleTrader),
+			want: Identity{},
+		},
+		{
+			name: "subject without roles",
+			md:   metadata.Pairs(MetadataSubject, "user-1"),
+			want: Identity{Subject: "user-1"},
+		},
+		{
+			name: "comma separated and repeated roles",
+			md: metadata.Pairs(
+				MetadataSubject, " user-1 ",
+				MetadataRoles, "trader, admin",
+				MetadataRoles, "auditor",
+				MetadataRoles, " , ",
+			),
+			want: Identity{Subject: "user-1", Roles: []string{"trader", "admin", "auditor"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.md != nil {
+				ctx = metadata.NewIncomingContext(ctx, tt.md)
+			}
+
+			if diff := cmp.Diff(tt.want, FromMetadata(ctx)); diff != "" {
+				t.Errorf("FromMetadata() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestIdentity_HasRole(t *testing.T) {
+	trader := Identity{Subject: "user-1", Roles: []string{RoleTrader}}
+
+	tests := []struct {
+		name string
+		id   Identity
+		role string
+		want bool
+	}{
+		{"anonymous holds anonymous role", Identity{}, RoleAnonymous, true},
+		{"anonymous lacks trader role", Identity{}, RoleTrader, false},
+		{"trader holds trader role", trader, RoleTrader, true},
+		{"trader holds anonymous role", trader, RoleAnonymous, true},
+		{"trader lacks other roles", trader, "admin", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.id.HasRole(tt.role); got != tt.want {
+				t.Errorf("HasRole(%q) = %t, want %t", tt.role, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	methodRoles := map[string]string{
+		"/sports.Sports/ListEvents":  RoleAnonymous,
+		"/sports.Sports/UpdateEvent": RoleTrader,
+	}
+
+	tests := []struct {
+		name     string
+		method   string
+		md       metadata.MD
+		wantCode codes.Code
+		wantID   Identity
+	}{
+		{
+			name:     "anonymous read",
+			method:   "/sports.Sports/ListEvents",
+			wantCode: codes.OK,
+		},
+		{
+			name:     "anonymous write",
+			method:   "/sports.Sports/UpdateEvent",
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name:     "authenticated without role",
+			method:   "/sports.Sports/UpdateEvent",
+			md:       metadata.Pairs(MetadataSubject, "user-1"),
+			wantCode: codes.PermissionDenied,
+		},
+		{
+			name:     "trader write",
+			method:   "/sports.Sports/UpdateEvent",
+			md:       metadata.Pairs(MetadataSubject, "user-1", MetadataRoles, RoleTrader),
+			wantCode: codes.OK,
+			wantID:   Identity{Subject: "user-1", Roles: []string{RoleTrader}},
+		},
+		{
+			name:     "unknown method",
+			method:   "/sports.Sports/DeleteEverything",
+			md:       metadata.Pairs(MetadataSubject, "user-1", MetadataRoles, RoleTrader),
+			wantCode: codes.PermissionDenied,
+		},
+	}
+
+	interceptor := UnaryServerInterceptor(methodRoles, nil)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.md != nil {
+				ctx = metadata.NewIncomingContext(ctx, tt.md)
+			}
+
+			var gotID Identity
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				gotID = FromContext(ctx)
+				return "ok", nil
+			}
+
+			_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: tt.method}, handler)
+			if got := status.Code(err); got != tt.wantCode {
+				t.Fatalf("interceptor() code = %v, want %v (err: %v)", got, tt.wantCode, err)
+			}
+
+			if diff := cmp.Diff(tt.wantID, gotID); diff != "" {
+				t.Errorf("handler identity mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
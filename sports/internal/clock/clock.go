@@ -0,0 +1,47 @@
+package clock
+
+import (
+	"fmt"
+	"time"
+)
+
+// Clock tells the current time. Everything that derives state from the time,
+// such as event status, reads it from a Clock so that tests can stop it at an
+// exact instant and staging can replay another day.
+type Clock interface {
+	Now() time.Time
+}
+
+// Func adapts an ordinary function to the Clock interface.
+type Func func() time.Time
+
+// Now returns f().
+func (f Func) Now() time.Time {
+	return f()
+}
+
+// System is the clock of the host.
+var System Clock = Func(time.Now)
+
+// Fixed returns a clock that is stopped at t.
+func Fixed(t time.Time) Clock {
+	return Func(func() time.Time { return t })
+}
+
+// Offset returns a clock that runs at the speed of base but reads d later.
+func Offset(base Clock, d time.Duration) Clock {
+	return Func(func() time.Time { return base.Now().Add(d) })
+}
+
+// ParseOffset parses a fake time setting into the offset of a clock from now.
+// The setting is either the RFC 3339 time the clock should read now, such as
+// "2026-10-18T12:00:00+11:00", or a signed duration such as "-24h".
+func ParseOffset(value string, now time.Time) (time.Duration, error) {
+	if start, err := time.Parse(time.RFC3339, value); err == nil {
+		return start.Sub(now), nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return d, nil
+	}
+	return 0, fmt.Errorf("%q is neither an RFC 3339 time nor a duration", value)
+}
@@ -0,0 +1,416 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"git.neds.sh/matty/entain/sports/internal/clock"
+	"git.neds.sh/matty/entain/sports/internal/logger"
+	"github.com/BurntSushi/toml"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/yaml.v3"
+)
+
+// Environment variable names for service configuration.
+// LOG_LEVEL and ENVIRONMENT are shared with the logger package.
+const (
+	EnvConfigFile           = "CONFIG_FILE"
+	EnvGRPCEndpoint         = "GRPC_ENDPOINT"
+	EnvGRPCReflection       = "GRPC_REFLECTION"
+	EnvDatabaseDSN          = "DB_DSN"
+	EnvTLSEnabled           = "TLS_ENABLED"
+	EnvTLSCertFile          = "TLS_CERT_FILE"
+	EnvTLSKeyFile           = "TLS_KEY_FILE"
+	EnvTLSClientCAFile      = "TLS_CLIENT_CA_FILE"
+	EnvTLSRequireClientCert = "TLS_REQUIRE_CLIENT_CERT"
+	EnvTLSReloadInterval    = "TLS_RELOAD_INTERVAL"
+	EnvConnectionTimeout    = "CONNECTION_TIMEOUT"
+	EnvShutdownTimeout      = "SHUTDOWN_TIMEOUT"
+	EnvCacheTTL             = "CACHE_TTL"
+	EnvFakeTime             = "FAKE_TIME"
+)
+
+// Config is the complete, typed configuration of the sports service.
+type Config struct {
+	GRPC     GRPCConfig     `yaml:"grpc"`
+	Database DatabaseConfig `yaml:"database"`
+	TLS      TLSConfig      `yaml:"tls"`
+	Timeouts TimeoutsConfig `yaml:"timeouts"`
+	Cache    CacheConfig    `yaml:"cache"`
+	Clock    ClockConfig    `yaml:"clock"`
+	Log      LogConfig      `yaml:"log"`
+}
+
+// GRPCConfig holds the gRPC listener settings.
+type GRPCConfig struct {
+	// Endpoint is the host:port the gRPC server listens on.
+	Endpoint string `yaml:"endpoint"`
+	// Reflection registers the server reflection service, so that tools
+	// such as grpcurl can list and call methods without the protos.
+	Reflection bool `yaml:"reflection"`
+}
+
+// DatabaseConfig holds the database connection settings.
+type DatabaseConfig struct {
+	// DSN is the sqlite3 data source name, usually a file path.
+	DSN string `yaml:"dsn"`
+}
+
+// TLSConfig holds the transport security settings of the gRPC listener.
+type TLSConfig struct {
+	Enabled  bool   `yaml:"enabled"`
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+	// ClientCAFile is a PEM bundle used to verify client certificates.
+	ClientCAFile string `yaml:"client_ca_file"`
+	// RequireClientCert turns on mutual TLS.
+	RequireClientCert bool `yaml:"require_client_cert"`
+	// ReloadInterval is how often the files are checked for rotation.
+	ReloadInterval time.Duration `yaml:"reload_interval"`
+}
+
+// TimeoutsConfig holds the server timeouts.
+type TimeoutsConfig struct {
+	// Connection bounds the connection handshake of new clients.
+	Connection time.Duration `yaml:"connection"`
+	// Shutdown bounds how long in-flight RPCs may run after a stop signal.
+	Shutdown time.Duration `yaml:"shutdown"`
+}
+
+// CacheConfig holds the list query cache settings.
+type CacheConfig struct {
+	// TTL is how long list results are reused. Zero disables the cache.
+	TTL time.Duration `yaml:"ttl"`
+}
+
+// ClockConfig holds the settings of the service clock.
+type ClockConfig struct {
+	// FakeTime runs the clock from another time, for replaying a day in
+	// staging: either the RFC 3339 time the clock reads at startup, or a
+	// signed offset from the real time such as "-24h". Empty uses the real
+	// time.
+	FakeTime string `yaml:"fake_time"`
+}
+
+// LogConfig holds the logger settings.
+type LogConfig struct {
+	Level       string `yaml:"level"`
+	Environment string `yaml:"environment"`
+}
+
+// Default returns the configuration used when nothing else is specified.
+func Default() Config {
+	return Config{
+		GRPC: GRPCConfig{
+			Endpoint: "localhost:9001",
+		},
+		Database: DatabaseConfig{
+			DSN: "./db/sports.db",
+		},
+		TLS: TLSConfig{
+			ReloadInterval: 30 * time.Second,
+		},
+		Timeouts: TimeoutsConfig{
+			Connection: 120 * time.Second,
+			Shutdown:   10 * time.Second,
+		},
+		Cache: CacheConfig{
+			TTL: 2 * time.Second,
+		},
+		Log: LogConfig{
+			Level:       string(logger.InfoLevel),
+			Environment: string(logger.Production),
+		},
+	}
+}
+
+// Load builds the effective configuration by layering, in increasing order of
+// precedence: the defaults, the YAML or TOML file named by --config or CONFIG_FILE,
+// environment variables and finally command-line flags that were explicitly set.
+// The result is validated before it is returned.
+func Load(args []string) (Config, error) {
+	return load(args, os.LookupEnv)
+}
+
+func load(args []string, lookupEnv func(string) (string, bool)) (Config, error) {
+	cfg := Default()
+
+	fs := flag.NewFlagSet("sports", flag.ContinueOnError)
+	configFile := fs.String("config", "", "Path to a YAML or TOML configuration file")
+	registerFlags(fs, cfg)
+	if err := fs.Parse(args); err != nil {
+		return Config{}, err
+	}
+
+	path := *configFile
+	if path == "" {
+		path, _ = lookupEnv(EnvConfigFile)
+	}
+	if path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return Config{}, err
+		}
+	}
+
+	if err := cfg.applyEnv(lookupEnv); err != nil {
+		return Config{}, err
+	}
+
+	cfg.applyFlags(fs)
+
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+
+	return cfg, nil
+}
+
+// registerFlags defines one flag per setting. Defaults are only shown in
+// --help; applyFlags copies values for flags that were actually set.
+func registerFlags(fs *flag.FlagSet, def Config) {
+	fs.String("grpc-endpoint", def.GRPC.Endpoint, "gRPC server endpoint")
+	fs.Bool("grpc-reflection", def.GRPC.Reflection, "Register the gRPC server reflection service")
+	fs.String("db-dsn", def.Database.DSN, "Database data source name")
+	fs.Bool("tls-enabled", def.TLS.Enabled, "Serve gRPC over TLS")
+	fs.String("tls-cert-file", def.TLS.CertFile, "TLS certificate file (PEM)")
+	fs.String("tls-key-file", def.TLS.KeyFile, "TLS private key file (PEM)")
+	fs.String("tls-client-ca-file", def.TLS.ClientCAFile, "CA bundle used to verify client certificates (PEM)")
+	fs.Bool("tls-require-client-cert", def.TLS.RequireClientCert, "Require and verify client certificates (mTLS)")
+	fs.Duration("tls-reload-interval", def.TLS.ReloadInterval, "How often TLS files are checked for rotation")
+	fs.Duration("connection-timeout", def.Timeouts.Connection, "Timeout for new connection handshakes")
+	fs.Duration("shutdown-timeout", def.Timeouts.Shutdown, "Grace period for in-flight RPCs on shutdown")
+	fs.Duration("cache-ttl", def.Cache.TTL, "How long list results are cached; 0 disables caching")
+	fs.String("fake-time", def.Clock.FakeTime, "Run the clock from this RFC 3339 time, or offset it by a duration such as -24h")
+	fs.String("log-level", def.Log.Level, "Log level: debug, info, warn or error")
+	fs.String("environment", def.Log.Environment, "Deployment environment: development, production or testing")
+}
+
+// loadFile overlays the YAML file at path onto the configuration, or the TOML
+// file if path ends in .toml. Unknown keys are rejected so that typos do not
+// go unnoticed.
+func (c *Config) loadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open config file: %w", err)
+	}
+	defer f.Close()
+
+	var in io.Reader = f
+	if strings.EqualFold(filepath.Ext(path), ".toml") {
+		// TOML is read as the YAML document it maps to, so that both formats
+		// share the keys, duration parsing and unknown key check
+		var doc map[string]interface{}
+		if _, err := toml.DecodeReader(f, &doc); err != nil {
+			return fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+		b, err := yaml.Marshal(doc)
+		if err != nil {
+			return fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+		in = bytes.NewReader(b)
+	}
+
+	dec := yaml.NewDecoder(in)
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// applyEnv overlays environment variables onto the configuration.
+func (c *Config) applyEnv(lookupEnv func(string) (string, bool)) error {
+	stringVars := map[string]*string{
+		EnvGRPCEndpoint:       &c.GRPC.Endpoint,
+		EnvDatabaseDSN:        &c.Database.DSN,
+		EnvTLSCertFile:        &c.TLS.CertFile,
+		EnvTLSKeyFile:         &c.TLS.KeyFile,
+		EnvTLSClientCAFile:    &c.TLS.ClientCAFile,
+		EnvFakeTime:           &c.Clock.FakeTime,
+		logger.EnvLogLevel:    &c.Log.Level,
+		logger.EnvEnvironment: &c.Log.Environment,
+	}
+	for name, field := range stringVars {
+		if v, ok := lookupEnv(name); ok {
+			*field = v
+		}
+	}
+
+	boolVars := map[string]*bool{
+		EnvGRPCReflection:       &c.GRPC.Reflection,
+		EnvTLSEnabled:           &c.TLS.Enabled,
+		EnvTLSRequireClientCert: &c.TLS.RequireClientCert,
+	}
+	for name, field := range boolVars {
+		if v, ok := lookupEnv(name); ok {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return fmt.Errorf("invalid %s: %w", name, err)
+			}
+			*field = b
+		}
+	}
+
+	durationVars := map[string]*time.Duration{
+		EnvTLSReloadInterval: &c.TLS.ReloadInterval,
+		EnvConnectionTimeout: &c.Timeouts.Connection,
+		EnvShutdownTimeout:   &c.Timeouts.Shutdown,
+		EnvCacheTTL:          &c.Cache.TTL,
+	}
+	for name, field := range durationVars {
+		if v, ok := lookupEnv(name); ok {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return fmt.Errorf("invalid %s: %w", name, err)
+			}
+			*field = d
+		}
+	}
+
+	return nil
+}
+
+// applyFlags copies the values of explicitly set flags onto the configuration.
+func (c *Config) applyFlags(fs *flag.FlagSet) {
+	fs.Visit(func(f *flag.Flag) {
+		value := f.Value.(flag.Getter).Get()
+		switch f.Name {
+		case "grpc-endpoint":
+			c.GRPC.Endpoint = value.(string)
+		case "grpc-reflection":
+			c.GRPC.Reflection = value.(bool)
+		case "db-dsn":
+			c.Database.DSN = value.(string)
+		case "tls-enabled":
+			c.TLS.Enabled = value.(bool)
+		case "tls-cert-file":
+			c.TLS.CertFile = value.(string)
+		case "tls-key-file":
+			c.TLS.KeyFile = value.(string)
+		case "tls-client-ca-file":
+			c.TLS.ClientCAFile = value.(string)
+		case "tls-require-client-cert":
+			c.TLS.RequireClientCert = value.(bool)
+		case "tls-reload-interval":
+			c.TLS.ReloadInterval = value.(time.Duration)
+		case "connection-timeout":
+			c.Timeouts.Connection = value.(time.Duration)
+		case "shutdown-timeout":
+			c.Timeouts.Shutdown = value.(time.Duration)
+		case "cache-ttl":
+			c.Cache.TTL = value.(time.Duration)
+		case "fake-time":
+			c.Clock.FakeTime = value.(string)
+		case "log-level":
+			c.Log.Level = value.(string)
+		case "environment":
+			c.Log.Environment = value.(string)
+		}
+	})
+}
+
+// Validate checks the configuration for missing or inconsistent settings.
+func (c Config) Validate() error {
+	var problems []string
+
+	if _, _, err := net.SplitHostPort(c.GRPC.Endpoint); err != nil {
+		problems = append(problems, fmt.Sprintf("grpc.endpoint %q is not a valid host:port", c.GRPC.Endpoint))
+	}
+
+	if strings.TrimSpace(c.Database.DSN) == "" {
+		problems = append(problems, "database.dsn must not be empty")
+	}
+
+	if c.TLS.Enabled {
+		if c.TLS.CertFile == "" || c.TLS.KeyFile == "" {
+			problems = append(problems, "tls.cert_file and tls.key_file are required when tls is enabled")
+		}
+		if c.TLS.RequireClientCert && c.TLS.ClientCAFile == "" {
+			problems = append(problems, "tls.client_ca_file is required when tls.require_client_cert is set")
+		}
+		if c.TLS.ReloadInterval <= 0 {
+			problems = append(problems, "tls.reload_interval must be positive")
+		}
+	} else if c.TLS.RequireClientCert {
+		problems = append(problems, "tls.require_client_cert needs tls.enabled")
+	}
+
+	if c.Timeouts.Connection < 0 {
+		problems = append(problems, "timeouts.connection must not be negative")
+	}
+	if c.Timeouts.Shutdown < 0 {
+		problems = append(problems, "timeouts.shutdown must not be negative")
+	}
+	if c.Cache.TTL < 0 {
+		problems = append(problems, "cache.ttl must not be negative")
+	}
+	if c.Clock.FakeTime != "" {
+		if _, err := clock.ParseOffset(c.Clock.FakeTime, time.Now()); err != nil {
+			problems = append(problems, "clock.fake_time: "+err.Error())
+		}
+	}
+
+	if _, err := logger.ParseLevel(c.Log.Level); err != nil {
+		problems = append(problems, "log.level: "+err.Error())
+	}
+	if _, err := logger.ParseEnvironment(c.Log.Environment); err != nil {
+		problems = append(problems, "log.environment: "+err.Error())
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid configuration: %s", strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+// NewClock returns the service clock: the system clock, offset when a fake time
+// is set. The offset is fixed when NewClock is called, so a fake start time is
+// the time at startup. It assumes Validate has passed.
+func (c Config) NewClock() clock.Clock {
+	if c.Clock.FakeTime == "" {
+		return clock.System
+	}
+	offset, _ := clock.ParseOffset(c.Clock.FakeTime, time.Now())
+	return clock.Offset(clock.System, offset)
+}
+
+// Logger returns the logger configuration. It assumes Validate has passed.
+func (c Config) Logger() logger.Config {
+	level, _ := logger.ParseLevel(c.Log.Level)
+	env, _ := logger.ParseEnvironment(c.Log.Environment)
+	return logger.Config{
+		Level:       level,
+		Environment: env,
+	}
+}
+
+// MarshalLogObject renders the effective configuration for structured logging.
+func (c Config) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("grpc.endpoint", c.GRPC.Endpoint)
+	enc.AddBool("grpc.reflection", c.GRPC.Reflection)
+	enc.AddString("database.dsn", c.Database.DSN)
+	enc.AddBool("tls.enabled", c.TLS.Enabled)
+	enc.AddString("tls.cert_file", c.TLS.CertFile)
+	enc.AddString("tls.key_file", c.TLS.KeyFile)
+	enc.AddString("tls.client_ca_file", c.TLS.ClientCAFile)
+	enc.AddBool("tls.require_client_cert", c.TLS.RequireClientCert)
+	enc.AddDuration("tls.reload_interval", c.TLS.ReloadInterval)
+	enc.AddDuration("timeouts.connection", c.Timeouts.Connection)
+	enc.AddDuration("timeouts.shutdown", c.Timeouts.Shutdown)
+	enc.AddDuration("cache.ttl", c.Cache.TTL)
+	enc.AddString("clock.fake_time", c.Clock.FakeTime)
+	enc.AddString("log.level", c.Log.Level)
+	enc.AddString("log.environment", c.Log.Environment)
+	return nil
+}
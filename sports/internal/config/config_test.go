@@ -0,0 +1,354 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// envMap returns a lookup function backed by the given map
+func envMap(env map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+}
+
+// writeConfigFile writes a YAML config file into a temp dir and returns its path
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "sports.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("writeConfigFile() failed: %v", err)
+	}
+	return path
+}
+
+// writeTOMLConfigFile writes a TOML config file into a temp dir and returns its path
+func writeTOMLConfigFile(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "sports.toml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("writeTOMLConfigFile() failed: %v", err)
+	}
+	return path
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	got, err := load(nil, envMap(nil))
+	if err != nil {
+		t.Fatalf("load() failed: %v", err)
+	}
+
+	if diff := cmp.Diff(Default(), got); diff != "" {
+		t.Errorf("load() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestLoad_Layering(t *testing.T) {
+	path := writeConfigFile(t, `
+grpc:
+  endpoint: file-host:9100
+database:
+  dsn: /var/lib/sports/file.db
+timeouts:
+  shutdown: 30s
+log:
+  level: warn
+`)
+
+	tests := []struct {
+		name string
+		args []string
+		env  map[string]string
+		want func(c *Config)
+	}{
+		{
+			name: "file overrides defaults",
+			args: []string{"--config", path},
+			want: func(c *Config) {
+				c.GRPC.Endpoint = "file-host:9100"
+				c.Database.DSN = "/var/lib/sports/file.db"
+				c.Timeouts.Shutdown = 30 * time.Second
+				c.Log.Level = "warn"
+			},
+		},
+		{
+			name: "config file can be named by environment",
+			env:  map[string]string{EnvConfigFile: path},
+			want: func(c *Config) {
+				c.GRPC.Endpoint = "file-host:9100"
+				c.Database.DSN = "/var/lib/sports/file.db"
+				c.Timeouts.Shutdown = 30 * time.Second
+				c.Log.Level = "warn"
+			},
+		},
+		{
+			name: "env overrides file",
+			args: []string{"--config", path},
+			env: map[string]string{
+				EnvGRPCEndpoint:    "env-host:9200",
+				EnvShutdownTimeout: "5s",
+				"LOG_LEVEL":        "debug",
+			},
+			want: func(c *Config) {
+				c.GRPC.Endpoint = "env-host:9200"
+				c.Database.DSN = "/var/lib/sports/file.db"
+				c.Timeouts.Shutdown = 5 * time.Second
+				c.Log.Level = "debug"
+			},
+		},
+		{
+			name: "flags override env and file",
+			args: []string{"--config", path, "--grpc-endpoint", "flag-host:9300", "--db-dsn", ":memory:"},
+			env:  map[string]string{EnvGRPCEndpoint: "env-host:9200"},
+			want: func(c *Config) {
+				c.GRPC.Endpoint = "flag-host:9300"
+				c.Database.DSN = ":memory:"
+				c.Timeouts.Shutdown = 30 * time.Second
+				c.Log.Level = "warn"
+			},
+		},
+		{
+			name: "reflection flag overrides env",
+			args: []string{"--grpc-reflection=false"},
+			env:  map[string]string{EnvGRPCReflection: "true"},
+			want: func(c *Config) {},
+		},
+		{
+			name: "reflection from env",
+			env:  map[string]string{EnvGRPCReflection: "true"},
+			want: func(c *Config) {
+				c.GRPC.Reflection = true
+			},
+		},
+		{
+			name: "fake time flag overrides env",
+			args: []string{"--fake-time", "2026-10-18T12:00:00+11:00"},
+			env:  map[string]string{EnvFakeTime: "-24h"},
+			want: func(c *Config) {
+				c.Clock.FakeTime = "2026-10-18T12:00:00+11:00"
+			},
+		},
+		{
+			name: "unset flags do not clobber env",
+			args: []string{"--log-level", "error"},
+			env:  map[string]string{EnvGRPCEndpoint: "env-host:9200"},
+			want: func(c *Config) {
+				c.GRPC.Endpoint = "env-host:9200"
+				c.Log.Level = "error"
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := load(tt.args, envMap(tt.env))
+			if err != nil {
+				t.Fatalf("load(%v) failed: %v", tt.args, err)
+			}
+
+			want := Default()
+			tt.want(&want)
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("load(%v) mismatch (-want +got):\n%s", tt.args, diff)
+			}
+		})
+	}
+}
+
+func TestLoad_TOML(t *testing.T) {
+	path := writeTOMLConfigFile(t, `
+[grpc]
+endpoint = "file-host:9100"
+
+[database]
+dsn = "/var/lib/sports/file.db"
+
+[timeouts]
+shutdown = "30s"
+
+[log]
+level = "warn"
+`)
+
+	got, err := load([]string{"--config", path}, envMap(nil))
+	if err != nil {
+		t.Fatalf("load() failed: %v", err)
+	}
+
+	want := Default()
+	want.GRPC.Endpoint = "file-host:9100"
+	want.Database.DSN = "/var/lib/sports/file.db"
+	want.Timeouts.Shutdown = 30 * time.Second
+	want.Log.Level = "warn"
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("load() mismatch (-want +got):\n%s", diff)
+	}
+
+	// Unknown keys are rejected as in YAML files
+	_, err = load([]string{"--config", writeTOMLConfigFile(t, "[grpc]\nendpiont = \"localhost:9000\"\n")}, envMap(nil))
+	if err == nil || !strings.Contains(err.Error(), "failed to parse config file") {
+		t.Errorf("load() error = %v, want an unknown key error", err)
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		env     map[string]string
+		file    string
+		wantErr string
+	}{
+		{
+			name:    "missing config file",
+			args:    []string{"--config", "/does/not/exist.yaml"},
+			wantErr: "failed to open config file",
+		},
+		{
+			name:    "unknown key in config file",
+			file:    "grpc:\n  endpiont: localhost:9001\n",
+			wantErr: "failed to parse config file",
+		},
+		{
+			name:    "invalid duration in env",
+			env:     map[string]string{EnvShutdownTimeout: "soon"},
+			wantErr: "invalid SHUTDOWN_TIMEOUT",
+		},
+		{
+			name:    "invalid bool in env",
+			env:     map[string]string{EnvTLSEnabled: "maybe"},
+			wantErr: "invalid TLS_ENABLED",
+		},
+		{
+			name:    "unknown flag",
+			args:    []string{"--no-such-flag"},
+			wantErr: "flag provided but not defined",
+		},
+		{
+			name:    "invalid fake time",
+			env:     map[string]string{EnvFakeTime: "yesterday"},
+			wantErr: "clock.fake_time",
+		},
+		{
+			name:    "invalid endpoint",
+			args:    []string{"--grpc-endpoint", "localhost"},
+			wantErr: "grpc.endpoint",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := tt.args
+			if tt.file != "" {
+				args = append(args, "--config", writeConfigFile(t, tt.file))
+			}
+
+			_, err := load(args, envMap(tt.env))
+			if err == nil {
+				t.Fatalf("load(%v) returned no error, want error containing %q", args, tt.wantErr)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("load(%v) error = %q, want containing %q", args, err.Error(), tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(c *Config)
+		wantErr string
+	}{
+		{
+			name:   "defaults are valid",
+			modify: func(c *Config) {},
+		},
+		{
+			name:    "empty dsn",
+			modify:  func(c *Config) { c.Database.DSN = " " },
+			wantErr: "database.dsn",
+		},
+		{
+			name:    "tls enabled without key pair",
+			modify:  func(c *Config) { c.TLS.Enabled = true },
+			wantErr: "tls.cert_file and tls.key_file",
+		},
+		{
+			name: "mtls without client ca",
+			modify: func(c *Config) {
+				c.TLS = TLSConfig{Enabled: true, CertFile: "c.pem", KeyFile: "k.pem", RequireClientCert: true}
+			},
+			wantErr: "tls.client_ca_file",
+		},
+		{
+			name:    "mtls without tls",
+			modify:  func(c *Config) { c.TLS.RequireClientCert = true },
+			wantErr: "needs tls.enabled",
+		},
+		{
+			name:    "negative timeout",
+			modify:  func(c *Config) { c.Timeouts.Shutdown = -time.Second },
+			wantErr: "timeouts.shutdown",
+		},
+		{
+			name:    "negative cache ttl",
+			modify:  func(c *Config) { c.Cache.TTL = -time.Second },
+			wantErr: "cache.ttl",
+		},
+		{
+			name:    "unknown log level",
+			modify:  func(c *Config) { c.Log.Level = "verbose" },
+			wantErr: "log.level",
+		},
+		{
+			name:    "environment alias is accepted",
+			modify:  func(c *Config) { c.Log.Environment = "dev" },
+			wantErr: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Default()
+			tt.modify(&cfg)
+
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConfig_NewClock(t *testing.T) {
+	cfg := Default()
+	if got := cfg.NewClock().Now(); time.Since(got) > time.Minute || time.Since(got) < -time.Minute {
+		t.Errorf("NewClock().Now() = %v without a fake time, want the real time", got)
+	}
+
+	cfg.Clock.FakeTime = "2026-10-18T12:00:00+11:00"
+	start := time.Date(2026, 10, 18, 1, 0, 0, 0, time.UTC)
+	if got := cfg.NewClock().Now(); got.Sub(start) < 0 || got.Sub(start) > time.Minute {
+		t.Errorf("NewClock().Now() = %v, want the fake time %v", got, start)
+	}
+
+	cfg.Clock.FakeTime = "-24h"
+	if got := time.Since(cfg.NewClock().Now()); got < 24*time.Hour || got > 24*time.Hour+time.Minute {
+		t.Errorf("NewClock() runs %v behind, want 24h", got)
+	}
+}
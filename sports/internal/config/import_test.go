@@ -0,0 +1,64 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLoadImport(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		env  map[string]string
+		want ImportConfig
+	}{
+		{
+			name: "defaults",
+			args: []string{"card.csv"},
+			want: ImportConfig{DSN: "./db/sports.db", File: "card.csv", Actor: DefaultImportActor},
+		},
+		{
+			name: "flags override env",
+			args: []string{"--db-dsn", "/tmp/flag.db", "--format", "ndjson", "--dry-run", "--actor", "ops@example.com", "-"},
+			env:  map[string]string{EnvDatabaseDSN: "/tmp/env.db"},
+			want: ImportConfig{DSN: "/tmp/flag.db", File: "-", Format: "ndjson", DryRun: true, Actor: "ops@example.com"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := loadImport(tt.args, envMap(tt.env))
+			if err != nil {
+				t.Fatalf("loadImport() failed: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("loadImport() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestLoadImport_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{name: "no file", wantErr: "feed file is required"},
+		{name: "two files", args: []string{"a.csv", "b.csv"}, wantErr: "expected one feed file"},
+		{name: "stdin without format", args: []string{"-"}, wantErr: "format is required"},
+		{name: "empty dsn", args: []string{"--db-dsn", " ", "card.csv"}, wantErr: "db-dsn"},
+		{name: "empty actor", args: []string{"--actor", "", "card.csv"}, wantErr: "actor"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadImport(tt.args, envMap(nil))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("loadImport(%v) error = %v, want containing %q", tt.args, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"git.neds.sh/matty/entain/sports/internal/clock"
+)
+
+// SeedConfig is the configuration of the seed subcommand, which fills a
+// database with reproducible events.
+type SeedConfig struct {
+	// DSN is the database to seed, DB_DSN or the service default when unset.
+	DSN string
+	// RandSeed makes the generated events reproducible.
+	RandSeed int64
+	// EventsPerSport is the number of events generated for each sport.
+	EventsPerSport int
+	// Start is the earliest start time, either RFC 3339 or an offset from now
+	// such as "-24h". Window is how far the start times spread from it.
+	Start  string
+	Window time.Duration
+	// VisibleRatio is the share of visible events, from 0 to 1.
+	VisibleRatio float64
+	// Reset replaces existing events instead of keeping them.
+	Reset bool
+}
+
+// LoadSeed parses the arguments of the seed subcommand.
+func LoadSeed(args []string) (SeedConfig, error) {
+	return loadSeed(args, os.LookupEnv)
+}
+
+func loadSeed(args []string, lookupEnv func(string) (string, bool)) (SeedConfig, error) {
+	dsn := Default().Database.DSN
+	if v, ok := lookupEnv(EnvDatabaseDSN); ok {
+		dsn = v
+	}
+
+	var cfg SeedConfig
+	fs := flag.NewFlagSet("sports seed", flag.ContinueOnError)
+	fs.StringVar(&cfg.DSN, "db-dsn", dsn, "Database data source name")
+	fs.Int64Var(&cfg.RandSeed, "rand-seed", 1, "Seed of the random generator; the same seed generates the same events")
+	fs.IntVar(&cfg.EventsPerSport, "events-per-sport", 17, "Number of events of each sport")
+	fs.StringVar(&cfg.Start, "start", "-24h", "Earliest start time, as an RFC 3339 time or an offset from now")
+	fs.DurationVar(&cfg.Window, "window", 72*time.Hour, "How far start times spread from --start")
+	fs.Float64Var(&cfg.VisibleRatio, "visible-ratio", 0.5, "Share of visible events, from 0 to 1")
+	fs.BoolVar(&cfg.Reset, "reset", false, "Delete existing events and markets first")
+	if err := fs.Parse(args); err != nil {
+		return SeedConfig{}, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return SeedConfig{}, err
+	}
+
+	return cfg, nil
+}
+
+// Validate checks the settings that are not validated by the seeder itself.
+func (c SeedConfig) Validate() error {
+	var problems []string
+
+	if strings.TrimSpace(c.DSN) == "" {
+		problems = append(problems, "db-dsn must not be empty")
+	}
+	if _, err := clock.ParseOffset(c.Start, time.Now()); err != nil {
+		problems = append(problems, "start: "+err.Error())
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid seed configuration: %s", strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+// StartTime returns the earliest start time relative to now. It assumes
+// Validate has passed.
+func (c SeedConfig) StartTime(now time.Time) time.Time {
+	offset, _ := clock.ParseOffset(c.Start, now)
+	return now.Add(offset)
+}
@@ -0,0 +1,263 @@
+// Package feed reads events from CSV and NDJSON feeds and imports them. It is
+// shared by the ImportEvents RPC and the import command.
+package feed
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"git.neds.sh/matty/entain/sports/db"
+	"git.neds.sh/matty/entain/sports/proto/sports"
+)
+
+// Format is the encoding of a feed.
+type Format string
+
+// Supported feed formats.
+const (
+	// CSV feeds have a header row naming the columns, in any order.
+	CSV Format = "csv"
+	// NDJSON feeds have one ImportEventsRequest in protobuf JSON per line.
+	NDJSON Format = "ndjson"
+)
+
+// ParseFormat returns the format called name.
+func ParseFormat(name string) (Format, error) {
+	switch strings.ToLower(name) {
+	case "csv":
+		return CSV, nil
+	case "ndjson", "jsonl":
+		return NDJSON, nil
+	}
+	return "", fmt.Errorf("unknown feed format %q: must be csv or ndjson", name)
+}
+
+// FormatOf returns the format of the feed at path from its extension.
+func FormatOf(path string) (Format, error) {
+	ext := strings.TrimPrefix(filepath.Ext(path), ".")
+	if ext == "" {
+		return "", fmt.Errorf("feed %s has no extension to tell its format from", path)
+	}
+	return ParseFormat(ext)
+}
+
+// Record is an event read from a feed, or the error that kept it from being read.
+type Record struct {
+	// Row is the position of the record in the feed, from 1, not counting a
+	// CSV header or blank lines.
+	Row   int
+	Event *sports.ImportEventsRequest
+	Err   error
+}
+
+// CSV columns. The venue_id and visible columns are optional and default to
+// no venue and false.
+const (
+	columnSourceID  = "source_id"
+	columnName      = "name"
+	columnSportType = "sport_type"
+	columnVenueID   = "venue_id"
+	columnVisible   = "visible"
+	columnStart     = "advertised_start_time"
+)
+
+var requiredColumns = []string{columnSourceID, columnName, columnSportType, columnStart}
+
+// ReadEvents reads the events of a feed. Records that cannot be parsed are
+// returned with their error; an error is only returned for an unreadable
+// feed or one with more than sports.MaxImportEvents records.
+func ReadEvents(r io.Reader, format Format) ([]Record, error) {
+	switch format {
+	case CSV:
+		return readCSV(r)
+	case NDJSON:
+		return readNDJSON(r)
+	}
+	return nil, fmt.Errorf("unknown feed format %q", format)
+}
+
+func readCSV(r io.Reader) ([]Record, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch name {
+		case columnSourceID, columnName, columnSportType, columnVenueID, columnVisible, columnStart:
+		default:
+			return nil, fmt.Errorf("unknown CSV column %q", name)
+		}
+		if _, ok := columns[name]; ok {
+			return nil, fmt.Errorf("duplicate CSV column %q", name)
+		}
+		columns[name] = i
+	}
+	for _, name := range requiredColumns {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("missing CSV column %q", name)
+		}
+	}
+
+	var records []Record
+	for {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		if len(records) == sports.MaxImportEvents {
+			return nil, fmt.Errorf("feed has more than %d events", sports.MaxImportEvents)
+		}
+
+		record := Record{Row: len(records) + 1}
+		var parseErr *csv.ParseError
+		switch {
+		case errors.As(err, &parseErr):
+			record.Err = parseErr.Err
+		case err != nil:
+			return nil, fmt.Errorf("failed to read CSV row %d: %w", record.Row, err)
+		default:
+			record.Event, record.Err = parseCSVEvent(columns, fields)
+		}
+		records = append(records, record)
+	}
+}
+
+// parseCSVEvent converts the fields of a CSV row into an event.
+func parseCSVEvent(columns map[string]int, fields []string) (*sports.ImportEventsRequest, error) {
+	field := func(name string) string {
+		if i, ok := columns[name]; ok {
+			return strings.TrimSpace(fields[i])
+		}
+		return ""
+	}
+
+	event := &sports.ImportEventsRequest{
+		SourceId:  field(columnSourceID),
+		Name:      field(columnName),
+		SportType: field(columnSportType),
+	}
+
+	var err error
+	if venueID := field(columnVenueID); venueID != "" {
+		if event.VenueId, err = strconv.ParseInt(venueID, 10, 64); err != nil {
+			return event, &sports.FieldError{Field: columnVenueID, Description: fmt.Sprintf("invalid venue ID %q", venueID)}
+		}
+	}
+	if visible := field(columnVisible); visible != "" {
+		if event.Visible, err = strconv.ParseBool(visible); err != nil {
+			return event, &sports.FieldError{Field: columnVisible, Description: fmt.Sprintf("invalid visible flag %q", visible)}
+		}
+	}
+	if start := field(columnStart); start != "" {
+		t, err := time.Parse(time.RFC3339, start)
+		if err != nil {
+			return event, &sports.FieldError{Field: columnStart, Description: fmt.Sprintf("invalid advertised start time %q: must be RFC 3339", start)}
+		}
+		event.AdvertisedStartTime = timestamppb.New(t)
+	}
+
+	return event, nil
+}
+
+func readNDJSON(r io.Reader) ([]Record, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var records []Record
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if len(records) == sports.MaxImportEvents {
+			return nil, fmt.Errorf("feed has more than %d events", sports.MaxImportEvents)
+		}
+
+		record := Record{Row: len(records) + 1, Event: &sports.ImportEventsRequest{}}
+		if err := protojson.Unmarshal([]byte(line), record.Event); err != nil {
+			record.Err = fmt.Errorf("invalid JSON: %w", err)
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read NDJSON feed: %w", err)
+	}
+
+	return records, nil
+}
+
+// Import validates records with the rules of sports.ImportEventsRequest and
+// upserts the valid events in one transaction. Source IDs must be unique
+// within the feed. The changes are recorded in the history of the events as
+// made by actor, and the events are assigned to the hierarchy in the same
+// transaction. Nothing is saved on a dry run or when any record failed, but
+// the counts still say what the import would do.
+func Import(repo db.EventsRepo, records []Record, actor string, dryRun bool) (*sports.ImportEventsResponse, error) {
+	resp := &sports.ImportEventsResponse{}
+
+	var events []*sports.ImportEventsRequest
+	firstRows := make(map[string]int)
+	for _, record := range records {
+		err := record.Err
+		if err == nil {
+			err = record.Event.Validate()
+		}
+		if err == nil {
+			if row, ok := firstRows[record.Event.SourceId]; ok {
+				err = &sports.FieldError{Field: "source_id", Description: fmt.Sprintf("duplicate source ID, first seen on row %d", row)}
+			}
+		}
+		if err != nil {
+			resp.Errors = append(resp.Errors, importError(record, err))
+			continue
+		}
+
+		firstRows[record.Event.SourceId] = record.Row
+		events = append(events, record.Event)
+	}
+
+	commit := !dryRun && len(resp.Errors) == 0
+	created, updated, err := repo.Import(events, actor, !commit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to import events: %w", err)
+	}
+
+	resp.Created = int32(created)
+	resp.Updated = int32(updated)
+	resp.Committed = commit
+	return resp, nil
+}
+
+// importError reports why a record failed.
+func importError(record Record, err error) *sports.ImportError {
+	importErr := &sports.ImportError{Row: int32(record.Row), Message: err.Error()}
+	if record.Event != nil {
+		importErr.SourceId = record.Event.SourceId
+	}
+
+	var fieldErr *sports.FieldError
+	if errors.As(err, &fieldErr) {
+		importErr.Field = fieldErr.Field
+	}
+
+	return importErr
+}
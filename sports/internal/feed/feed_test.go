@@ -0,0 +1,162 @@
+package feed
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"git.neds.sh/matty/entain/sports/proto/sports"
+)
+
+func TestReadEvents(t *testing.T) {
+	start := timestamppb.New(time.Date(2026, 11, 3, 8, 0, 0, 0, time.UTC))
+	final := &sports.ImportEventsRequest{SourceId: "feed-9", Name: "Lions vs Tigers", SportType: "football", VenueId: 1, Visible: true, AdvertisedStartTime: start}
+
+	tests := []struct {
+		name       string
+		feed       string
+		format     Format
+		want       []*sports.ImportEventsRequest
+		wantErrors map[int]string
+	}{
+		{
+			name:   "csv with columns in any order",
+			format: CSV,
+			feed: "sport_type,name,source_id,advertised_start_time,venue_id,visible\n" +
+				"football,Lions vs Tigers,feed-9,2026-11-03T19:00:00+11:00,1,true\n",
+			want: []*sports.ImportEventsRequest{final},
+		},
+		{
+			name:   "csv without optional columns",
+			format: CSV,
+			feed: "source_id,name,sport_type,advertised_start_time\n" +
+				"feed-1,Nadal vs Federer,tennis,2026-11-03T08:00:00Z\n",
+			want: []*sports.ImportEventsRequest{{SourceId: "feed-1", Name: "Nadal vs Federer", SportType: "tennis", AdvertisedStartTime: start}},
+		},
+		{
+			name:   "csv with invalid fields",
+			format: CSV,
+			feed: "source_id,name,sport_type,advertised_start_time,venue_id\n" +
+				"feed-1,Game,soccer,2026-11-03T08:00:00Z,home\n" +
+				"feed-2,Game,soccer,tomorrow,1\n" +
+				"feed-3,\"Game,soccer\n",
+			want: []*sports.ImportEventsRequest{
+				{SourceId: "feed-1", Name: "Game", SportType: "soccer"},
+				{SourceId: "feed-2", Name: "Game", SportType: "soccer", VenueId: 1},
+				nil,
+			},
+			wantErrors: map[int]string{1: "invalid venue ID", 2: "must be RFC 3339", 3: "quote"},
+		},
+		{
+			name:   "ndjson skipping blank lines",
+			format: NDJSON,
+			feed: `{"sourceId":"feed-9","name":"Lions vs Tigers","sportType":"football","venueId":"1","visible":true,"advertisedStartTime":"2026-11-03T08:00:00Z"}` + "\n\n" +
+				`{"sourceId":` + "\n",
+			want:       []*sports.ImportEventsRequest{final, {}},
+			wantErrors: map[int]string{2: "invalid JSON"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := ReadEvents(strings.NewReader(tt.feed), tt.format)
+			if err != nil {
+				t.Fatalf("ReadEvents() failed: %v", err)
+			}
+
+			var got []*sports.ImportEventsRequest
+			for i, record := range records {
+				if record.Row != i+1 {
+					t.Errorf("record %d has row %d", i, record.Row)
+				}
+				got = append(got, record.Event)
+
+				wantErr := tt.wantErrors[record.Row]
+				if (wantErr == "") != (record.Err == nil) || record.Err != nil && !strings.Contains(record.Err.Error(), wantErr) {
+					t.Errorf("row %d error = %v, want containing %q", record.Row, record.Err, wantErr)
+				}
+			}
+			if diff := cmp.Diff(tt.want, got, protocmp.Transform()); diff != "" {
+				t.Errorf("ReadEvents() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestReadEvents_InvalidHeader(t *testing.T) {
+	tests := map[string]string{
+		"unknown column":   "source_id,name,sport_type,advertised_start_time,meeting_id\n",
+		"duplicate column": "source_id,name,sport_type,advertised_start_time,name\n",
+		"missing column":   "source_id,name,advertised_start_time\n",
+	}
+
+	for name, feed := range tests {
+		if _, err := ReadEvents(strings.NewReader(feed), CSV); err == nil {
+			t.Errorf("ReadEvents() with %s succeeded, want an error", name)
+		}
+	}
+}
+
+// testRepo is a db.EventsRepo importing every event as new, or failing with err
+type testRepo struct {
+	err    error
+	actor  string
+	dryRun bool
+}
+
+func (r *testRepo) Init() error                                                   { return nil }
+func (r *testRepo) List(*sports.ListEventsRequestFilter) ([]*sports.Event, error) { return nil, nil }
+func (r *testRepo) Stream(*sports.ListEventsRequestFilter, func(*sports.Event) error) error {
+	return nil
+}
+func (r *testRepo) GetByID(int64) (*sports.Event, error)      { return nil, nil }
+func (r *testRepo) GetByIDs([]int64) ([]*sports.Event, error) { return nil, nil }
+func (r *testRepo) SetSuspended(int64, bool, string) error    { return nil }
+
+func (r *testRepo) Import(events []*sports.ImportEventsRequest, actor string, dryRun bool) (int, int, error) {
+	r.actor, r.dryRun = actor, dryRun
+	return len(events), 0, r.err
+}
+
+func (r *testRepo) History(int64, string) ([]*sports.EventChange, error) { return nil, nil }
+
+func TestImport(t *testing.T) {
+	start := timestamppb.New(time.Date(2026, 11, 3, 8, 0, 0, 0, time.UTC))
+	event := func(sourceID string) *sports.ImportEventsRequest {
+		return &sports.ImportEventsRequest{SourceId: sourceID, Name: "Lions vs Tigers", SportType: "football", AdvertisedStartTime: start}
+	}
+
+	records := []Record{{Row: 1, Event: event("feed-1")}, {Row: 2, Event: event("feed-2")}}
+
+	repo := &testRepo{}
+	resp, err := Import(repo, records, "trader-1", false)
+	if err != nil {
+		t.Fatalf("Import() failed: %v", err)
+	}
+	if !resp.Committed || resp.Created != 2 || repo.dryRun {
+		t.Errorf("Import() = %v with dry run %t, want 2 events committed", resp, repo.dryRun)
+	}
+	if repo.actor != "trader-1" {
+		t.Errorf("Import() recorded the events as imported by %q, want trader-1", repo.actor)
+	}
+
+	// A failed record turns the import into a dry run of the others
+	records = append(records, Record{Row: 3, Event: event("feed-1")})
+	resp, err = Import(repo, records, "trader-1", false)
+	if err != nil {
+		t.Fatalf("Import() failed: %v", err)
+	}
+	if resp.Committed || !repo.dryRun || len(resp.Errors) != 1 || resp.Errors[0].Field != "source_id" {
+		t.Errorf("Import() = %v with dry run %t, want the duplicate of row 3 reported and nothing committed", resp, repo.dryRun)
+	}
+
+	repo.err = errors.New("database is locked")
+	if _, err := Import(repo, records[:2], "trader-1", false); err == nil || !strings.Contains(err.Error(), "database is locked") {
+		t.Errorf("Import() error = %v, want the repository error", err)
+	}
+}
@@ -0,0 +1,185 @@
+package tlsutil
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Reloader holds a server certificate and an optional client CA bundle in memory
+// and swaps them when the files on disk change, so certificates can be rotated
+// without restarting the service.
+type Reloader struct {
+	certFile     string
+	keyFile      string
+	clientCAFile string
+	logger       *zap.Logger
+
+	mu       sync.RWMutex
+	cert     *tls.Certificate
+	clientCA *x509.CertPool
+	modTimes []time.Time
+}
+
+// NewReloader loads the key pair and, when clientCAFile is not empty, the client
+// CA bundle. It fails if any of the files cannot be loaded.
+func NewReloader(certFile, keyFile, clientCAFile string, logger *zap.Logger) (*Reloader, error) {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	r := &Reloader{
+		certFile:     certFile,
+		keyFile:      keyFile,
+		clientCAFile: clientCAFile,
+		logger:       logger,
+	}
+
+	if _, err := r.Reload(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Reload re-reads the files if any of their modification times changed.
+// It reports whether new material was loaded. On error the previously
+// loaded certificates stay in use.
+func (r *Reloader) Reload() (bool, error) {
+	modTimes, err := r.statFiles()
+	if err != nil {
+		return false, err
+	}
+
+	r.mu.RLock()
+	unchanged := sameTimes(modTimes, r.modTimes)
+	r.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return false, fmt.Errorf("failed to load key pair: %w", err)
+	}
+
+	var clientCA *x509.CertPool
+	if r.clientCAFile != "" {
+		clientCA, err = loadCertPool(r.clientCAFile)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	r.mu.Lock()
+	r.cert = &cert
+	r.clientCA = clientCA
+	r.modTimes = modTimes
+	r.mu.Unlock()
+
+	return true, nil
+}
+
+// Watch polls the files every interval and reloads them when they change,
+// until ctx is cancelled. Reload failures are logged and retried on the next tick.
+func (r *Reloader) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reloaded, err := r.Reload()
+			if err != nil {
+				r.logger.Error("Failed to reload TLS certificates", zap.Error(err))
+				continue
+			}
+			if reloaded {
+				r.logger.Info("Reloaded TLS certificates", zap.String("cert_file", r.certFile))
+			}
+		}
+	}
+}
+
+// ServerConfig returns a TLS config that always presents the most recently
+// loaded certificate. With requireClientCert the handshake fails unless the
+// client presents a certificate signed by the client CA (mutual TLS); otherwise
+// a client certificate is verified only if one is offered and a CA is configured.
+func (r *Reloader) ServerConfig(requireClientCert bool) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			r.mu.RLock()
+			defer r.mu.RUnlock()
+
+			cfg := &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*r.cert},
+				NextProtos:   []string{"h2"},
+				ClientCAs:    r.clientCA,
+			}
+			switch {
+			case requireClientCert:
+				cfg.ClientAuth = tls.RequireAndVerifyClientCert
+			case r.clientCA != nil:
+				cfg.ClientAuth = tls.VerifyClientCertIfGiven
+			}
+
+			return cfg, nil
+		},
+	}
+}
+
+// statFiles returns the modification times of all configured files.
+func (r *Reloader) statFiles() ([]time.Time, error) {
+	files := []string{r.certFile, r.keyFile}
+	if r.clientCAFile != "" {
+		files = append(files, r.clientCAFile)
+	}
+
+	modTimes := make([]time.Time, 0, len(files))
+	for _, name := range files {
+		info, err := os.Stat(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat %s: %w", name, err)
+		}
+		modTimes = append(modTimes, info.ModTime())
+	}
+
+	return modTimes, nil
+}
+
+// loadCertPool reads a PEM bundle into a certificate pool.
+func loadCertPool(name string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in CA bundle %s", name)
+	}
+
+	return pool, nil
+}
+
+func sameTimes(a, b []time.Time) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,331 @@
+package tlsutil
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCA is an in-memory certificate authority used to issue test certificates
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+// newTestCA creates a self-signed CA
+func newTestCA(t *testing.T, name string) *testCA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("newTestCA() failed to generate key: %v", err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("newTestCA() failed to create certificate: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+
+	return &testCA{
+		cert: cert,
+		key:  key,
+		pem:  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	}
+}
+
+// issue signs a leaf certificate for localhost and returns the PEM encoded pair
+func (ca *testCA) issue(t *testing.T, serial int64, usage x509.ExtKeyUsage) (certPEM, keyPEM []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("issue() failed to generate key: %v", err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("issue() failed to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("issue() failed to marshal key: %v", err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+// writeFile writes data to dir/name and stamps it with the given modification time
+func writeFile(t *testing.T, dir, name string, data []byte, modTime time.Time) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("writeFile(%s) failed: %v", name, err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatalf("writeFile(%s) failed to set times: %v", name, err)
+	}
+	return path
+}
+
+// handshake runs a TLS handshake over a loopback connection and returns the
+// server certificate seen by the client, or the first handshake error.
+func handshake(t *testing.T, serverCfg, clientCfg *tls.Config) (*x509.Certificate, error) {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("handshake() failed to listen: %v", err)
+	}
+	defer lis.Close()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		conn, err := lis.Accept()
+		if err != nil {
+			serverErr <- err
+			return
+		}
+		defer conn.Close()
+		serverErr <- tls.Server(conn, serverCfg).Handshake()
+	}()
+
+	conn, err := net.Dial("tcp", lis.Addr().String())
+	if err != nil {
+		t.Fatalf("handshake() failed to dial: %v", err)
+	}
+	client := tls.Client(conn, clientCfg)
+	clientErr := client.Handshake()
+	conn.Close()
+
+	// TLS 1.3 client handshakes complete before the server has verified
+	// the client certificate, so the server's verdict decides.
+	if err := <-serverErr; err != nil && clientErr == nil {
+		return nil, err
+	}
+	if clientErr != nil {
+		return nil, clientErr
+	}
+
+	return client.ConnectionState().PeerCertificates[0], nil
+}
+
+func TestReloader_ServerConfig_ClientAuth(t *testing.T) {
+	ca := newTestCA(t, "test-ca")
+	otherCA := newTestCA(t, "other-ca")
+	dir := t.TempDir()
+	now := time.Now()
+
+	serverCert, serverKey := ca.issue(t, 10, x509.ExtKeyUsageServerAuth)
+	certFile := writeFile(t, dir, "server.pem", serverCert, now)
+	keyFile := writeFile(t, dir, "server-key.pem", serverKey, now)
+	caFile := writeFile(t, dir, "ca.pem", ca.pem, now)
+
+	clientCertPEM, clientKeyPEM := ca.issue(t, 20, x509.ExtKeyUsageClientAuth)
+	clientCert, err := tls.X509KeyPair(clientCertPEM, clientKeyPEM)
+	if err != nil {
+		t.Fatalf("X509KeyPair() failed: %v", err)
+	}
+	rogueCertPEM, rogueKeyPEM := otherCA.issue(t, 30, x509.ExtKeyUsageClientAuth)
+	rogueCert, err := tls.X509KeyPair(rogueCertPEM, rogueKeyPEM)
+	if err != nil {
+		t.Fatalf("X509KeyPair() failed: %v", err)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+
+	tests := []struct {
+		name              string
+		clientCAFile      string
+		requireClientCert bool
+		clientCerts       []tls.Certificate
+		wantErr           bool
+	}{
+		{
+			name: "plain TLS without client certificate",
+		},
+		{
+			name:              "mutual TLS with valid client certificate",
+			clientCAFile:      caFile,
+			requireClientCert: true,
+			clientCerts:       []tls.Certificate{clientCert},
+		},
+		{
+			name:              "mutual TLS without client certificate",
+			clientCAFile:      caFile,
+			requireClientCert: true,
+			wantErr:           true,
+		},
+		{
+			name:              "mutual TLS with certificate from unknown CA",
+			clientCAFile:      caFile,
+			requireClientCert: true,
+			clientCerts:       []tls.Certificate{rogueCert},
+			wantErr:           true,
+		},
+		{
+			name:         "optional verification without client certificate",
+			clientCAFile: caFile,
+		},
+		{
+			name:         "optional verification rejects untrusted certificate",
+			clientCAFile: caFile,
+			clientCerts:  []tls.Certificate{rogueCert},
+			wantErr:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := NewReloader(certFile, keyFile, tt.clientCAFile, nil)
+			if err != nil {
+				t.Fatalf("NewReloader() failed: %v", err)
+			}
+
+			_, err = handshake(t, r.ServerConfig(tt.requireClientCert), &tls.Config{
+				RootCAs:    roots,
+				ServerName: "localhost",
+				// Always offer the configured certificate, even when its issuer
+				// is not in the server's list of acceptable CAs.
+				GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+					if len(tt.clientCerts) == 0 {
+						return &tls.Certificate{}, nil
+					}
+					return &tt.clientCerts[0], nil
+				},
+			})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("handshake() error = %v, wantErr %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestReloader_Reload(t *testing.T) {
+	ca := newTestCA(t, "test-ca")
+	dir := t.TempDir()
+	start := time.Now().Add(-time.Minute)
+
+	certPEM, keyPEM := ca.issue(t, 100, x509.ExtKeyUsageServerAuth)
+	certFile := writeFile(t, dir, "server.pem", certPEM, start)
+	keyFile := writeFile(t, dir, "server-key.pem", keyPEM, start)
+
+	r, err := NewReloader(certFile, keyFile, "", nil)
+	if err != nil {
+		t.Fatalf("NewReloader() failed: %v", err)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	clientCfg := &tls.Config{RootCAs: roots, ServerName: "localhost"}
+	serverCfg := r.ServerConfig(false)
+
+	got, err := handshake(t, serverCfg, clientCfg)
+	if err != nil {
+		t.Fatalf("handshake() failed: %v", err)
+	}
+	if got.SerialNumber.Int64() != 100 {
+		t.Fatalf("handshake() serial = %d, want 100", got.SerialNumber.Int64())
+	}
+
+	reloaded, err := r.Reload()
+	if err != nil || reloaded {
+		t.Fatalf("Reload() with unchanged files = (%t, %v), want (false, nil)", reloaded, err)
+	}
+
+	// Rotate to a new certificate on disk.
+	certPEM, keyPEM = ca.issue(t, 200, x509.ExtKeyUsageServerAuth)
+	writeFile(t, dir, "server.pem", certPEM, start.Add(time.Second))
+	writeFile(t, dir, "server-key.pem", keyPEM, start.Add(time.Second))
+
+	reloaded, err = r.Reload()
+	if err != nil || !reloaded {
+		t.Fatalf("Reload() with rotated files = (%t, %v), want (true, nil)", reloaded, err)
+	}
+
+	got, err = handshake(t, serverCfg, clientCfg)
+	if err != nil {
+		t.Fatalf("handshake() after reload failed: %v", err)
+	}
+	if got.SerialNumber.Int64() != 200 {
+		t.Errorf("handshake() after reload serial = %d, want 200", got.SerialNumber.Int64())
+	}
+
+	// A broken rotation keeps serving the last good certificate.
+	writeFile(t, dir, "server-key.pem", []byte("not a key"), start.Add(2*time.Second))
+	if _, err := r.Reload(); err == nil {
+		t.Errorf("Reload() with invalid key returned no error")
+	}
+
+	got, err = handshake(t, serverCfg, clientCfg)
+	if err != nil {
+		t.Fatalf("handshake() after failed reload failed: %v", err)
+	}
+	if got.SerialNumber.Int64() != 200 {
+		t.Errorf("handshake() after failed reload serial = %d, want 200", got.SerialNumber.Int64())
+	}
+}
+
+func TestNewReloader_Errors(t *testing.T) {
+	ca := newTestCA(t, "test-ca")
+	dir := t.TempDir()
+	now := time.Now()
+
+	certPEM, keyPEM := ca.issue(t, 1, x509.ExtKeyUsageServerAuth)
+	certFile := writeFile(t, dir, "server.pem", certPEM, now)
+	keyFile := writeFile(t, dir, "server-key.pem", keyPEM, now)
+	emptyCA := writeFile(t, dir, "empty-ca.pem", []byte("no certificates here"), now)
+
+	tests := []struct {
+		name         string
+		certFile     string
+		keyFile      string
+		clientCAFile string
+	}{
+		{"missing certificate", filepath.Join(dir, "missing.pem"), keyFile, ""},
+		{"missing client CA", certFile, keyFile, filepath.Join(dir, "missing-ca.pem")},
+		{"client CA without certificates", certFile, keyFile, emptyCA},
+		{"mismatched key pair", keyFile, certFile, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewReloader(tt.certFile, tt.keyFile, tt.clientCAFile, nil); err == nil {
+				t.Errorf("NewReloader(%q, %q, %q) returned no error", tt.certFile, tt.keyFile, tt.clientCAFile)
+			}
+		})
+	}
+}
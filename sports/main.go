@@ -1,33 +1,75 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"flag"
+	"fmt"
 	"net"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"git.neds.sh/matty/entain/sports/db"
+	"git.neds.sh/matty/entain/sports/internal/auth"
+	"git.neds.sh/matty/entain/sports/internal/config"
+	"git.neds.sh/matty/entain/sports/internal/feed"
 	"git.neds.sh/matty/entain/sports/internal/logger"
+	"git.neds.sh/matty/entain/sports/internal/tlsutil"
 	"git.neds.sh/matty/entain/sports/proto/sports"
 	"git.neds.sh/matty/entain/sports/service"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
-)
-
-var (
-	grpcEndpoint = flag.String("grpc-endpoint", "localhost:9001", "gRPC server endpoint")
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
 )
 
 func main() {
-	flag.Parse()
+	// The seed and import subcommands fill the database and exit
+	if len(os.Args) > 1 {
+		var (
+			err    error
+			action string
+		)
+		switch os.Args[1] {
+		case "seed":
+			action, err = "seed database", seed(os.Args[2:])
+		case "import":
+			action, err = "import events", importFeed(os.Args[2:])
+		}
+		if action != "" {
+			if errors.Is(err, flag.ErrHelp) {
+				return
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to %s: %v\n", action, err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
+	cfg, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
+		os.Exit(1)
+	}
 
-	if err := run(); err != nil {
+	if err := run(cfg); err != nil {
 		panic(err)
 	}
 }
 
-func run() error {
+func run(cfg config.Config) error {
 	// Initialize logger
-	loggerConfig := logger.NewFromEnv()
+	loggerConfig := cfg.Logger()
 	log, err := logger.New(loggerConfig)
 	if err != nil {
 		return err
@@ -35,39 +77,254 @@ func run() error {
 	defer log.Sync()
 
 	log.Info("Starting sports service",
-		zap.String("grpc_endpoint", *grpcEndpoint))
+		zap.String("grpc_endpoint", cfg.GRPC.Endpoint))
+	log.Debug("Effective configuration", zap.Object("config", cfg))
 
 	// Initialize database connection
-	database, err := sql.Open("sqlite3", "./db/sports.db")
+	database, err := sql.Open("sqlite3", cfg.Database.DSN)
 	if err != nil {
 		log.Error("Failed to open database", zap.Error(err))
 		return err
 	}
 	defer database.Close()
 
-	// Initialize repository
-	eventsRepo := db.NewEventsRepo(database)
+	// Event status and seeded start times read this clock
+	clk := cfg.NewClock()
+	if cfg.Clock.FakeTime != "" {
+		log.Warn("Using a fake clock", zap.String("fake_time", cfg.Clock.FakeTime), zap.Time("now", clk.Now()))
+	}
+
+	// Initialize repository. Events reference venues, so venues come first
+	venuesRepo := db.NewVenuesRepo(database)
+	if err := venuesRepo.Init(); err != nil {
+		log.Error("Failed to initialize venues repository", zap.Error(err))
+		return err
+	}
+
+	eventsRepo := db.NewEventsRepo(database, clk)
 	if err := eventsRepo.Init(); err != nil {
 		log.Error("Failed to initialize events repository", zap.Error(err))
 		return err
 	}
 
+	// Events get their competition, season and teams from the hierarchy,
+	// which is backfilled from the sport type and name of new events
+	hierarchyRepo := db.NewHierarchyRepo(database)
+	if err := hierarchyRepo.Init(); err != nil {
+		log.Error("Failed to initialize hierarchy repository", zap.Error(err))
+		return err
+	}
+	backfilled, err := hierarchyRepo.Backfill()
+	if err != nil {
+		log.Error("Failed to backfill event hierarchy", zap.Error(err))
+		return err
+	}
+	if backfilled > 0 {
+		log.Info("Backfilled event hierarchy", zap.Int("events", backfilled))
+	}
+
+	if cfg.Cache.TTL > 0 {
+		log.Info("Caching event lists", zap.Duration("ttl", cfg.Cache.TTL))
+		eventsRepo = db.NewCachedEventsRepo(eventsRepo, cfg.Cache.TTL, clk)
+	}
+
+	marketsRepo := db.NewMarketsRepo(database)
+	if err := marketsRepo.Init(); err != nil {
+		log.Error("Failed to initialize markets repository", zap.Error(err))
+		return err
+	}
+
 	// Initialize service
 	sportsService := &service.SportsServer{
-		Service: service.NewSportsService(eventsRepo, log),
+		Service: service.NewSportsService(eventsRepo, marketsRepo, hierarchyRepo, venuesRepo, log),
 	}
 
 	// Setup gRPC server
-	lis, err := net.Listen("tcp", *grpcEndpoint)
+	lis, err := net.Listen("tcp", cfg.GRPC.Endpoint)
 	if err != nil {
 		log.Error("Failed to listen", zap.Error(err))
 		return err
 	}
 
-	grpcServer := grpc.NewServer()
+	methodRoles := auth.MergeMethodRoles(service.MethodRoles, auth.HealthMethodRoles)
+	if cfg.GRPC.Reflection {
+		methodRoles = auth.MergeMethodRoles(methodRoles, auth.ReflectionMethodRoles)
+	}
+	serverOpts := []grpc.ServerOption{
+		grpc.ConnectionTimeout(cfg.Timeouts.Connection),
+		grpc.ChainUnaryInterceptor(
+			auth.UnaryServerInterceptor(methodRoles, log),
+			service.UnaryErrorInterceptor(),
+		),
+		grpc.ChainStreamInterceptor(
+			auth.StreamServerInterceptor(methodRoles, log),
+			service.StreamErrorInterceptor(),
+		),
+	}
+
+	if cfg.TLS.Enabled {
+		reloader, err := tlsutil.NewReloader(cfg.TLS.CertFile, cfg.TLS.KeyFile, cfg.TLS.ClientCAFile, log)
+		if err != nil {
+			log.Error("Failed to load TLS certificates", zap.Error(err))
+			return err
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go reloader.Watch(ctx, cfg.TLS.ReloadInterval)
+
+		serverOpts = append(serverOpts, grpc.Creds(credentials.NewTLS(reloader.ServerConfig(cfg.TLS.RequireClientCert))))
+		log.Info("TLS enabled", zap.Bool("mutual_tls", cfg.TLS.RequireClientCert))
+	} else {
+		log.Warn("TLS disabled, serving plaintext gRPC")
+	}
+
+	grpcServer := grpc.NewServer(serverOpts...)
 	sports.RegisterSportsServer(grpcServer, sportsService)
 
-	log.Info("gRPC server listening", zap.String("address", *grpcEndpoint))
+	// Load balancers stop sending traffic to replicas that are not SERVING
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
+
+	if cfg.GRPC.Reflection {
+		reflection.Register(grpcServer)
+		log.Info("gRPC server reflection enabled")
+	}
+
+	log.Info("gRPC server listening", zap.String("address", cfg.GRPC.Endpoint))
+
+	return serve(grpcServer, healthServer, lis, cfg.Timeouts.Shutdown, log)
+}
+
+// serve runs the gRPC server until it fails or the process receives SIGINT/SIGTERM.
+// On a signal, in-flight RPCs are given the shutdown timeout to finish before
+// the server is stopped forcefully.
+func serve(grpcServer *grpc.Server, healthServer *health.Server, lis net.Listener, shutdownTimeout time.Duration, log *zap.Logger) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- grpcServer.Serve(lis)
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Info("Shutting down gRPC server", zap.Duration("timeout", shutdownTimeout))
+
+	// Report NOT_SERVING first so that clients balancing across replicas
+	// move new calls elsewhere while in-flight ones drain
+	healthServer.Shutdown()
+
+	stopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(shutdownTimeout):
+		log.Warn("Graceful shutdown timed out, forcing stop")
+		grpcServer.Stop()
+	}
+
+	return nil
+}
+
+// seed generates the events described by the seed subcommand arguments.
+func seed(args []string) error {
+	cfg, err := config.LoadSeed(args)
+	if err != nil {
+		return err
+	}
+
+	database, err := sql.Open("sqlite3", cfg.DSN)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer database.Close()
+
+	started := time.Now()
+	events, err := db.Seed(database, db.SeedOptions{
+		RandSeed:       cfg.RandSeed,
+		EventsPerSport: cfg.EventsPerSport,
+		Start:          cfg.StartTime(started),
+		Window:         cfg.Window,
+		VisibleRatio:   cfg.VisibleRatio,
+		Reset:          cfg.Reset,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Seeded %d events into %s in %s\n", events, cfg.DSN, time.Since(started).Round(time.Millisecond))
+	return nil
+}
+
+// importFeed loads the events of the feed named by the import subcommand
+// arguments, printing the rows that could not be imported.
+func importFeed(args []string) error {
+	cfg, err := config.LoadImport(args)
+	if err != nil {
+		return err
+	}
+
+	var format feed.Format
+	if cfg.Format != "" {
+		format, err = feed.ParseFormat(cfg.Format)
+	} else {
+		format, err = feed.FormatOf(cfg.File)
+	}
+	if err != nil {
+		return err
+	}
+
+	in := os.Stdin
+	if cfg.File != "-" {
+		if in, err = os.Open(cfg.File); err != nil {
+			return fmt.Errorf("failed to open feed: %w", err)
+		}
+		defer in.Close()
+	}
+
+	records, err := feed.ReadEvents(in, format)
+	if err != nil {
+		return err
+	}
+
+	database, err := sql.Open("sqlite3", cfg.DSN)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer database.Close()
 
-	return grpcServer.Serve(lis)
+	// Init would seed dummy events alongside the feed
+	if err := db.CreateTables(database); err != nil {
+		return fmt.Errorf("failed to create tables: %w", err)
+	}
+
+	resp, err := feed.Import(db.NewEventsRepo(database, nil), records, cfg.Actor, cfg.DryRun)
+	if err != nil {
+		return err
+	}
+
+	for _, importErr := range resp.Errors {
+		fmt.Fprintf(os.Stderr, "row %d (%s): %s\n", importErr.Row, importErr.SourceId, importErr.Message)
+	}
+
+	verb := "Imported"
+	if !resp.Committed {
+		verb = "Would import"
+	}
+	fmt.Printf("%s %d events from %s into %s: %d created, %d updated\n", verb, resp.Created+resp.Updated, cfg.File, cfg.DSN, resp.Created, resp.Updated)
+
+	if len(resp.Errors) > 0 {
+		return fmt.Errorf("%d of %d rows are invalid, nothing was saved", len(resp.Errors), len(records))
+	}
+	return nil
 }
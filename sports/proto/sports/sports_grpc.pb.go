@@ -20,8 +20,45 @@ const _ = grpc.SupportPackageIsVersion7
 type SportsClient interface {
 	// ListEvents will return a collection of all sports events.
 	ListEvents(ctx context.Context, in *ListEventsRequest, opts ...grpc.CallOption) (*ListEventsResponse, error)
+	// StreamEvents will return the sports events ListEvents would, one event
+	// per message, so that lists of any size stay within the message size limit.
+	StreamEvents(ctx context.Context, in *ListEventsRequest, opts ...grpc.CallOption) (Sports_StreamEventsClient, error)
 	// GetEvent will return a single sports event by its ID.
 	GetEvent(ctx context.Context, in *GetEventRequest, opts ...grpc.CallOption) (*GetEventResponse, error)
+	// BatchGetEvents will return the sports events with the given IDs in the
+	// order they were requested. IDs that match no event are reported in
+	// missing_ids rather than failing the call.
+	BatchGetEvents(ctx context.Context, in *BatchGetEventsRequest, opts ...grpc.CallOption) (*BatchGetEventsResponse, error)
+	// ListMarkets will return the betting markets of a sports event, with their
+	// selections.
+	ListMarkets(ctx context.Context, in *ListMarketsRequest, opts ...grpc.CallOption) (*ListMarketsResponse, error)
+	// UpdateMarket will create a market under a sports event, or replace one of
+	// its markets. Settled markets can no longer be changed.
+	UpdateMarket(ctx context.Context, in *UpdateMarketRequest, opts ...grpc.CallOption) (*UpdateMarketResponse, error)
+	// SuspendEvent will suspend or resume betting on a sports event. While the
+	// event is suspended, its open markets are reported as suspended.
+	SuspendEvent(ctx context.Context, in *SuspendEventRequest, opts ...grpc.CallOption) (*SuspendEventResponse, error)
+	// ListSports will return every sport, by name.
+	ListSports(ctx context.Context, in *ListSportsRequest, opts ...grpc.CallOption) (*ListSportsResponse, error)
+	// ListCompetitions will return the competitions of a sport, with their
+	// seasons.
+	ListCompetitions(ctx context.Context, in *ListCompetitionsRequest, opts ...grpc.CallOption) (*ListCompetitionsResponse, error)
+	// ListCompetitionEvents will return the events of a competition, optionally
+	// of a single season, ordered by advertised start time.
+	ListCompetitionEvents(ctx context.Context, in *ListCompetitionEventsRequest, opts ...grpc.CallOption) (*ListCompetitionEventsResponse, error)
+	// ListTeams will return the teams of a sport, by name.
+	ListTeams(ctx context.Context, in *ListTeamsRequest, opts ...grpc.CallOption) (*ListTeamsResponse, error)
+	// ListVenues will return every venue events take place at.
+	ListVenues(ctx context.Context, in *ListVenuesRequest, opts ...grpc.CallOption) (*ListVenuesResponse, error)
+	// ImportEvents will create or update a stream of events from an external
+	// feed, one event per message, by their source ID. The import is all or
+	// nothing: it is only saved when every record is valid and dry_run is not
+	// set. Saved events get their competition and teams backfilled.
+	ImportEvents(ctx context.Context, opts ...grpc.CallOption) (Sports_ImportEventsClient, error)
+	// ListEventHistory will return the recorded changes to a sports event,
+	// oldest first, with who made each change, when, and the values of the
+	// fields before and after it.
+	ListEventHistory(ctx context.Context, in *ListEventHistoryRequest, opts ...grpc.CallOption) (*ListEventHistoryResponse, error)
 }
 
 type sportsClient struct {
@@ -41,6 +78,38 @@ func (c *sportsClient) ListEvents(ctx context.Context, in *ListEventsRequest, op
 	return out, nil
 }
 
+func (c *sportsClient) StreamEvents(ctx context.Context, in *ListEventsRequest, opts ...grpc.CallOption) (Sports_StreamEventsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Sports_ServiceDesc.Streams[0], "/sports.Sports/StreamEvents", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &sportsStreamEventsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Sports_StreamEventsClient interface {
+	Recv() (*Event, error)
+	grpc.ClientStream
+}
+
+type sportsStreamEventsClient struct {
+	grpc.ClientStream
+}
+
+func (x *sportsStreamEventsClient) Recv() (*Event, error) {
+	m := new(Event)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 func (c *sportsClient) GetEvent(ctx context.Context, in *GetEventRequest, opts ...grpc.CallOption) (*GetEventResponse, error) {
 	out := new(GetEventResponse)
 	err := c.cc.Invoke(ctx, "/sports.Sports/GetEvent", in, out, opts...)
@@ -50,14 +119,175 @@ func (c *sportsClient) GetEvent(ctx context.Context, in *GetEventRequest, opts .
 	return out, nil
 }
 
+func (c *sportsClient) BatchGetEvents(ctx context.Context, in *BatchGetEventsRequest, opts ...grpc.CallOption) (*BatchGetEventsResponse, error) {
+	out := new(BatchGetEventsResponse)
+	err := c.cc.Invoke(ctx, "/sports.Sports/BatchGetEvents", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sportsClient) ListMarkets(ctx context.Context, in *ListMarketsRequest, opts ...grpc.CallOption) (*ListMarketsResponse, error) {
+	out := new(ListMarketsResponse)
+	err := c.cc.Invoke(ctx, "/sports.Sports/ListMarkets", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sportsClient) UpdateMarket(ctx context.Context, in *UpdateMarketRequest, opts ...grpc.CallOption) (*UpdateMarketResponse, error) {
+	out := new(UpdateMarketResponse)
+	err := c.cc.Invoke(ctx, "/sports.Sports/UpdateMarket", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sportsClient) SuspendEvent(ctx context.Context, in *SuspendEventRequest, opts ...grpc.CallOption) (*SuspendEventResponse, error) {
+	out := new(SuspendEventResponse)
+	err := c.cc.Invoke(ctx, "/sports.Sports/SuspendEvent", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sportsClient) ListSports(ctx context.Context, in *ListSportsRequest, opts ...grpc.CallOption) (*ListSportsResponse, error) {
+	out := new(ListSportsResponse)
+	err := c.cc.Invoke(ctx, "/sports.Sports/ListSports", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sportsClient) ListCompetitions(ctx context.Context, in *ListCompetitionsRequest, opts ...grpc.CallOption) (*ListCompetitionsResponse, error) {
+	out := new(ListCompetitionsResponse)
+	err := c.cc.Invoke(ctx, "/sports.Sports/ListCompetitions", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sportsClient) ListCompetitionEvents(ctx context.Context, in *ListCompetitionEventsRequest, opts ...grpc.CallOption) (*ListCompetitionEventsResponse, error) {
+	out := new(ListCompetitionEventsResponse)
+	err := c.cc.Invoke(ctx, "/sports.Sports/ListCompetitionEvents", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sportsClient) ListTeams(ctx context.Context, in *ListTeamsRequest, opts ...grpc.CallOption) (*ListTeamsResponse, error) {
+	out := new(ListTeamsResponse)
+	err := c.cc.Invoke(ctx, "/sports.Sports/ListTeams", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sportsClient) ListVenues(ctx context.Context, in *ListVenuesRequest, opts ...grpc.CallOption) (*ListVenuesResponse, error) {
+	out := new(ListVenuesResponse)
+	err := c.cc.Invoke(ctx, "/sports.Sports/ListVenues", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sportsClient) ImportEvents(ctx context.Context, opts ...grpc.CallOption) (Sports_ImportEventsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Sports_ServiceDesc.Streams[1], "/sports.Sports/ImportEvents", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &sportsImportEventsClient{stream}
+	return x, nil
+}
+
+type Sports_ImportEventsClient interface {
+	Send(*ImportEventsRequest) error
+	CloseAndRecv() (*ImportEventsResponse, error)
+	grpc.ClientStream
+}
+
+type sportsImportEventsClient struct {
+	grpc.ClientStream
+}
+
+func (x *sportsImportEventsClient) Send(m *ImportEventsRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *sportsImportEventsClient) CloseAndRecv() (*ImportEventsResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(ImportEventsResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *sportsClient) ListEventHistory(ctx context.Context, in *ListEventHistoryRequest, opts ...grpc.CallOption) (*ListEventHistoryResponse, error) {
+	out := new(ListEventHistoryResponse)
+	err := c.cc.Invoke(ctx, "/sports.Sports/ListEventHistory", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // SportsServer is the server API for Sports service.
 // All implementations must embed UnimplementedSportsServer
 // for forward compatibility
 type SportsServer interface {
 	// ListEvents will return a collection of all sports events.
 	ListEvents(context.Context, *ListEventsRequest) (*ListEventsResponse, error)
+	// StreamEvents will return the sports events ListEvents would, one event
+	// per message, so that lists of any size stay within the message size limit.
+	StreamEvents(*ListEventsRequest, Sports_StreamEventsServer) error
 	// GetEvent will return a single sports event by its ID.
 	GetEvent(context.Context, *GetEventRequest) (*GetEventResponse, error)
+	// BatchGetEvents will return the sports events with the given IDs in the
+	// order they were requested. IDs that match no event are reported in
+	// missing_ids rather than failing the call.
+	BatchGetEvents(context.Context, *BatchGetEventsRequest) (*BatchGetEventsResponse, error)
+	// ListMarkets will return the betting markets of a sports event, with their
+	// selections.
+	ListMarkets(context.Context, *ListMarketsRequest) (*ListMarketsResponse, error)
+	// UpdateMarket will create a market under a sports event, or replace one of
+	// its markets. Settled markets can no longer be changed.
+	UpdateMarket(context.Context, *UpdateMarketRequest) (*UpdateMarketResponse, error)
+	// SuspendEvent will suspend or resume betting on a sports event. While the
+	// event is suspended, its open markets are reported as suspended.
+	SuspendEvent(context.Context, *SuspendEventRequest) (*SuspendEventResponse, error)
+	// ListSports will return every sport, by name.
+	ListSports(context.Context, *ListSportsRequest) (*ListSportsResponse, error)
+	// ListCompetitions will return the competitions of a sport, with their
+	// seasons.
+	ListCompetitions(context.Context, *ListCompetitionsRequest) (*ListCompetitionsResponse, error)
+	// ListCompetitionEvents will return the events of a competition, optionally
+	// of a single season, ordered by advertised start time.
+	ListCompetitionEvents(context.Context, *ListCompetitionEventsRequest) (*ListCompetitionEventsResponse, error)
+	// ListTeams will return the teams of a sport, by name.
+	ListTeams(context.Context, *ListTeamsRequest) (*ListTeamsResponse, error)
+	// ListVenues will return every venue events take place at.
+	ListVenues(context.Context, *ListVenuesRequest) (*ListVenuesResponse, error)
+	// ImportEvents will create or update a stream of events from an external
+	// feed, one event per message, by their source ID. The import is all or
+	// nothing: it is only saved when every record is valid and dry_run is not
+	// set. Saved events get their competition and teams backfilled.
+	ImportEvents(Sports_ImportEventsServer) error
+	// ListEventHistory will return the recorded changes to a sports event,
+	// oldest first, with who made each change, when, and the values of the
+	// fields before and after it.
+	ListEventHistory(context.Context, *ListEventHistoryRequest) (*ListEventHistoryResponse, error)
 	mustEmbedUnimplementedSportsServer()
 }
 
@@ -68,9 +298,45 @@ type UnimplementedSportsServer struct {
 func (UnimplementedSportsServer) ListEvents(context.Context, *ListEventsRequest) (*ListEventsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ListEvents not implemented")
 }
+func (UnimplementedSportsServer) StreamEvents(*ListEventsRequest, Sports_StreamEventsServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamEvents not implemented")
+}
 func (UnimplementedSportsServer) GetEvent(context.Context, *GetEventRequest) (*GetEventResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetEvent not implemented")
 }
+func (UnimplementedSportsServer) BatchGetEvents(context.Context, *BatchGetEventsRequest) (*BatchGetEventsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BatchGetEvents not implemented")
+}
+func (UnimplementedSportsServer) ListMarkets(context.Context, *ListMarketsRequest) (*ListMarketsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListMarkets not implemented")
+}
+func (UnimplementedSportsServer) UpdateMarket(context.Context, *UpdateMarketRequest) (*UpdateMarketResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateMarket not implemented")
+}
+func (UnimplementedSportsServer) SuspendEvent(context.Context, *SuspendEventRequest) (*SuspendEventResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SuspendEvent not implemented")
+}
+func (UnimplementedSportsServer) ListSports(context.Context, *ListSportsRequest) (*ListSportsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListSports not implemented")
+}
+func (UnimplementedSportsServer) ListCompetitions(context.Context, *ListCompetitionsRequest) (*ListCompetitionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListCompetitions not implemented")
+}
+func (UnimplementedSportsServer) ListCompetitionEvents(context.Context, *ListCompetitionEventsRequest) (*ListCompetitionEventsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListCompetitionEvents not implemented")
+}
+func (UnimplementedSportsServer) ListTeams(context.Context, *ListTeamsRequest) (*ListTeamsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListTeams not implemented")
+}
+func (UnimplementedSportsServer) ListVenues(context.Context, *ListVenuesRequest) (*ListVenuesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListVenues not implemented")
+}
+func (UnimplementedSportsServer) ImportEvents(Sports_ImportEventsServer) error {
+	return status.Errorf(codes.Unimplemented, "method ImportEvents not implemented")
+}
+func (UnimplementedSportsServer) ListEventHistory(context.Context, *ListEventHistoryRequest) (*ListEventHistoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListEventHistory not implemented")
+}
 func (UnimplementedSportsServer) mustEmbedUnimplementedSportsServer() {}
 
 // UnsafeSportsServer may be embedded to opt out of forward compatibility for this service.
@@ -102,6 +368,27 @@ func _Sports_ListEvents_Handler(srv interface{}, ctx context.Context, dec func(i
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Sports_StreamEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ListEventsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(SportsServer).StreamEvents(m, &sportsStreamEventsServer{stream})
+}
+
+type Sports_StreamEventsServer interface {
+	Send(*Event) error
+	grpc.ServerStream
+}
+
+type sportsStreamEventsServer struct {
+	grpc.ServerStream
+}
+
+func (x *sportsStreamEventsServer) Send(m *Event) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 func _Sports_GetEvent_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(GetEventRequest)
 	if err := dec(in); err != nil {
@@ -120,6 +407,212 @@ func _Sports_GetEvent_Handler(srv interface{}, ctx context.Context, dec func(int
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Sports_BatchGetEvents_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchGetEventsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SportsServer).BatchGetEvents(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/sports.Sports/BatchGetEvents",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SportsServer).BatchGetEvents(ctx, req.(*BatchGetEventsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sports_ListMarkets_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListMarketsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SportsServer).ListMarkets(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/sports.Sports/ListMarkets",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SportsServer).ListMarkets(ctx, req.(*ListMarketsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sports_UpdateMarket_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateMarketRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SportsServer).UpdateMarket(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/sports.Sports/UpdateMarket",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SportsServer).UpdateMarket(ctx, req.(*UpdateMarketRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sports_SuspendEvent_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SuspendEventRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SportsServer).SuspendEvent(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/sports.Sports/SuspendEvent",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SportsServer).SuspendEvent(ctx, req.(*SuspendEventRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sports_ListSports_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListSportsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SportsServer).ListSports(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/sports.Sports/ListSports",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SportsServer).ListSports(ctx, req.(*ListSportsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sports_ListCompetitions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListCompetitionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SportsServer).ListCompetitions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/sports.Sports/ListCompetitions",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SportsServer).ListCompetitions(ctx, req.(*ListCompetitionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sports_ListCompetitionEvents_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListCompetitionEventsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SportsServer).ListCompetitionEvents(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/sports.Sports/ListCompetitionEvents",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SportsServer).ListCompetitionEvents(ctx, req.(*ListCompetitionEventsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sports_ListTeams_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListTeamsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SportsServer).ListTeams(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/sports.Sports/ListTeams",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SportsServer).ListTeams(ctx, req.(*ListTeamsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sports_ListVenues_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListVenuesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SportsServer).ListVenues(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/sports.Sports/ListVenues",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SportsServer).ListVenues(ctx, req.(*ListVenuesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sports_ImportEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(SportsServer).ImportEvents(&sportsImportEventsServer{stream})
+}
+
+type Sports_ImportEventsServer interface {
+	SendAndClose(*ImportEventsResponse) error
+	Recv() (*ImportEventsRequest, error)
+	grpc.ServerStream
+}
+
+type sportsImportEventsServer struct {
+	grpc.ServerStream
+}
+
+func (x *sportsImportEventsServer) SendAndClose(m *ImportEventsResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *sportsImportEventsServer) Recv() (*ImportEventsRequest, error) {
+	m := new(ImportEventsRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _Sports_ListEventHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListEventHistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SportsServer).ListEventHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/sports.Sports/ListEventHistory",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SportsServer).ListEventHistory(ctx, req.(*ListEventHistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Sports_ServiceDesc is the grpc.ServiceDesc for Sports service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -135,7 +628,58 @@ var Sports_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetEvent",
 			Handler:    _Sports_GetEvent_Handler,
 		},
+		{
+			MethodName: "BatchGetEvents",
+			Handler:    _Sports_BatchGetEvents_Handler,
+		},
+		{
+			MethodName: "ListMarkets",
+			Handler:    _Sports_ListMarkets_Handler,
+		},
+		{
+			MethodName: "UpdateMarket",
+			Handler:    _Sports_UpdateMarket_Handler,
+		},
+		{
+			MethodName: "SuspendEvent",
+			Handler:    _Sports_SuspendEvent_Handler,
+		},
+		{
+			MethodName: "ListSports",
+			Handler:    _Sports_ListSports_Handler,
+		},
+		{
+			MethodName: "ListCompetitions",
+			Handler:    _Sports_ListCompetitions_Handler,
+		},
+		{
+			MethodName: "ListCompetitionEvents",
+			Handler:    _Sports_ListCompetitionEvents_Handler,
+		},
+		{
+			MethodName: "ListTeams",
+			Handler:    _Sports_ListTeams_Handler,
+		},
+		{
+			MethodName: "ListVenues",
+			Handler:    _Sports_ListVenues_Handler,
+		},
+		{
+			MethodName: "ListEventHistory",
+			Handler:    _Sports_ListEventHistory_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamEvents",
+			Handler:       _Sports_StreamEvents_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "ImportEvents",
+			Handler:       _Sports_ImportEvents_Handler,
+			ClientStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "sports/sports.proto",
 }
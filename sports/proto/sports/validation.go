@@ -2,6 +2,7 @@ package sports
 
 import (
 	"fmt"
+	"math"
 	"strings"
 )
 
@@ -10,13 +11,304 @@ const (
 	MaxSportTypes = 50
 	// MaxSportTypeLength defines the maximum length for a sport type string
 	MaxSportTypeLength = 100
+	// MaxBatchIDs defines the maximum number of event IDs allowed in a single
+	// batch get, the same limit racing applies to meeting IDs
+	MaxBatchIDs = 100
+	// MaxFilterIDs defines the maximum number of competition, season or team
+	// IDs allowed in a single filter
+	MaxFilterIDs = 100
+	// MaxSelectionNameLength defines the maximum length for a selection name
+	MaxSelectionNameLength = 100
+	// MinPrice defines the shortest decimal odds a selection may be priced at
+	MinPrice = 1.01
+	// MaxImportEvents defines the maximum number of events in a single import
+	MaxImportEvents = 10000
+	// MaxSourceIDLength defines the maximum length for the source ID of an imported event
+	MaxSourceIDLength = 100
+	// MaxEventNameLength defines the maximum length for the name of an imported event
+	MaxEventNameLength = 200
+	// MaxListLimit defines the largest limit of a single list
+	MaxListLimit = 1000
 )
 
+// EventHistoryFields are the fields of an event whose changes are recorded in
+// its history, in the order they are listed in a change.
+var EventHistoryFields = []string{"name", "sport_type", "venue_id", "visible", "advertised_start_time", "suspended"}
+
+// FieldError is a validation failure of a single request field. Validation
+// errors wrap it, so callers can find the offending field with errors.As.
+type FieldError struct {
+	// Field is the path of the field in the request, e.g. "filter.sport_types".
+	Field string
+	// Description says what is wrong with the field.
+	Description string
+}
+
+func (e *FieldError) Error() string {
+	return e.Description
+}
+
+// fieldErrorf returns a FieldError for field with a formatted description.
+func fieldErrorf(field, format string, args ...interface{}) error {
+	return &FieldError{Field: field, Description: fmt.Sprintf(format, args...)}
+}
+
 // Validate validates the GetEvent request
 func (r *GetEventRequest) Validate() error {
 	if r.Id <= 0 {
-		return fmt.Errorf("invalid event ID: %d (must be positive)", r.Id)
+		return fieldErrorf("id", "invalid event ID: %d (must be positive)", r.Id)
+	}
+	return nil
+}
+
+// Validate validates the ListSports request, which has no fields yet
+func (r *ListSportsRequest) Validate() error {
+	return nil
+}
+
+// Validate validates the ListVenues request, which has no fields yet
+func (r *ListVenuesRequest) Validate() error {
+	return nil
+}
+
+// Validate validates the sport ID
+func (r *ListCompetitionsRequest) Validate() error {
+	if r.SportId <= 0 {
+		return fieldErrorf("sport_id", "invalid sport ID: %d (must be positive)", r.SportId)
+	}
+	return nil
+}
+
+// Validate validates the competition and season IDs
+func (r *ListCompetitionEventsRequest) Validate() error {
+	if r.CompetitionId <= 0 {
+		return fieldErrorf("competition_id", "invalid competition ID: %d (must be positive)", r.CompetitionId)
+	}
+	if r.SeasonId != nil && *r.SeasonId <= 0 {
+		return fieldErrorf("season_id", "invalid season ID: %d (must be positive)", *r.SeasonId)
+	}
+	return nil
+}
+
+// Validate validates the sport ID
+func (r *ListTeamsRequest) Validate() error {
+	if r.SportId <= 0 {
+		return fieldErrorf("sport_id", "invalid sport ID: %d (must be positive)", r.SportId)
+	}
+	return nil
+}
+
+// Validate validates the event ID
+func (r *ListMarketsRequest) Validate() error {
+	if r.EventId <= 0 {
+		return fieldErrorf("event_id", "invalid event ID: %d (must be positive)", r.EventId)
+	}
+	return nil
+}
+
+// Validate validates the event ID
+func (r *SuspendEventRequest) Validate() error {
+	if r.Id <= 0 {
+		return fieldErrorf("id", "invalid event ID: %d (must be positive)", r.Id)
+	}
+	return nil
+}
+
+// Validate validates the event ID and the field the history is limited to
+func (r *ListEventHistoryRequest) Validate() error {
+	if r.EventId <= 0 {
+		return fieldErrorf("event_id", "invalid event ID: %d (must be positive)", r.EventId)
+	}
+	if r.Field == "" {
+		return nil
+	}
+	for _, field := range EventHistoryFields {
+		if r.Field == field {
+			return nil
+		}
+	}
+	return fieldErrorf("field", "invalid field: %q (must be one of %s)", r.Field, strings.Join(EventHistoryFields, ", "))
+}
+
+// Validate validates an event of an import feed
+func (r *ImportEventsRequest) Validate() error {
+	if strings.TrimSpace(r.SourceId) == "" {
+		return fieldErrorf("source_id", "source ID is required")
+	}
+
+	if len(r.SourceId) > MaxSourceIDLength {
+		return fieldErrorf("source_id", "source ID too long: %d characters (max: %d)",
+			len(r.SourceId), MaxSourceIDLength)
+	}
+
+	if strings.TrimSpace(r.Name) == "" {
+		return fieldErrorf("name", "name is required")
+	}
+
+	if len(r.Name) > MaxEventNameLength {
+		return fieldErrorf("name", "name too long: %d characters (max: %d)", len(r.Name), MaxEventNameLength)
+	}
+
+	// Sport types follow the rules of the list filter
+	if strings.TrimSpace(r.SportType) == "" {
+		return fieldErrorf("sport_type", "sport type is required")
+	}
+
+	if len(r.SportType) > MaxSportTypeLength {
+		return fieldErrorf("sport_type", "sport type too long: %d characters (max: %d)",
+			len(r.SportType), MaxSportTypeLength)
+	}
+
+	if r.VenueId < 0 {
+		return fieldErrorf("venue_id", "invalid venue ID: %d (must not be negative)", r.VenueId)
+	}
+
+	if r.AdvertisedStartTime == nil {
+		return fieldErrorf("advertised_start_time", "advertised start time is required")
+	}
+
+	if err := r.AdvertisedStartTime.CheckValid(); err != nil {
+		return fieldErrorf("advertised_start_time", "invalid advertised start time: %v", err)
+	}
+
+	return nil
+}
+
+// Validate validates the event ID and the market to save
+func (r *UpdateMarketRequest) Validate() error {
+	if r.EventId <= 0 {
+		return fieldErrorf("event_id", "invalid event ID: %d (must be positive)", r.EventId)
+	}
+
+	if r.Market == nil {
+		return fieldErrorf("market", "market is required")
+	}
+
+	if err := r.validateMarket(); err != nil {
+		return fmt.Errorf("market validation failed: %w", err)
+	}
+	return nil
+}
+
+// validateMarket validates the market type, line, status and selections
+func (r *UpdateMarketRequest) validateMarket() error {
+	m := r.Market
+
+	if m.Id < 0 {
+		return fieldErrorf("market.id", "invalid market ID: %d (must not be negative)", m.Id)
+	}
+
+	if m.EventId != 0 && m.EventId != r.EventId {
+		return fieldErrorf("market.event_id", "market event ID %d does not match event ID %d", m.EventId, r.EventId)
+	}
+
+	if _, ok := MarketType_name[int32(m.Type)]; !ok {
+		return fieldErrorf("market.type", "invalid market type: %v", m.Type)
+	}
+
+	if _, ok := MarketStatus_name[int32(m.Status)]; !ok {
+		return fieldErrorf("market.status", "invalid market status: %v", m.Status)
+	}
+
+	switch {
+	case m.Type == MarketType_HEAD_TO_HEAD && m.Line != nil:
+		return fieldErrorf("market.line", "head to head markets have no line")
+	case m.Type != MarketType_HEAD_TO_HEAD && m.Line == nil:
+		return fieldErrorf("market.line", "%v markets need a line", m.Type)
+	case m.Line != nil && (math.IsNaN(*m.Line) || math.IsInf(*m.Line, 0)):
+		return fieldErrorf("market.line", "line must be a finite number")
+	case m.Type == MarketType_OVER_UNDER && *m.Line <= 0:
+		return fieldErrorf("market.line", "over/under line must be positive: %g", *m.Line)
+	}
+
+	return r.validateSelections()
+}
+
+// validateSelections validates the selection count, names and prices. Every
+// market has two selections, except that a head to head market may have a
+// third for the draw.
+func (r *UpdateMarketRequest) validateSelections() error {
+	m := r.Market
+
+	maxSelections := 2
+	if m.Type == MarketType_HEAD_TO_HEAD {
+		maxSelections = 3
+	}
+	if len(m.Selections) < 2 || len(m.Selections) > maxSelections {
+		return fieldErrorf("market.selections", "%v markets need 2 to %d selections, got %d",
+			m.Type, maxSelections, len(m.Selections))
 	}
+
+	seenIDs := make(map[int64]bool)
+	seenNames := make(map[string]bool)
+	for i, selection := range m.Selections {
+		field := fmt.Sprintf("market.selections[%d]", i)
+
+		if selection.Id < 0 {
+			return fieldErrorf(field+".id", "invalid selection ID: %d (must not be negative)", selection.Id)
+		}
+		if selection.Id != 0 {
+			if seenIDs[selection.Id] {
+				return fieldErrorf(field+".id", "duplicate selection ID: %d", selection.Id)
+			}
+			seenIDs[selection.Id] = true
+		}
+
+		name := strings.TrimSpace(selection.Name)
+		if name == "" {
+			return fieldErrorf(field+".name", "selection name is required")
+		}
+		if len(name) > MaxSelectionNameLength {
+			return fieldErrorf(field+".name", "selection name too long: %d characters (max: %d)",
+				len(name), MaxSelectionNameLength)
+		}
+		if seenNames[strings.ToLower(name)] {
+			return fieldErrorf(field+".name", "duplicate selection name: %q", name)
+		}
+		seenNames[strings.ToLower(name)] = true
+
+		if math.IsNaN(selection.Price) || math.IsInf(selection.Price, 0) {
+			return fieldErrorf(field+".price", "price must be a finite number")
+		}
+		if selection.Price < MinPrice {
+			return fieldErrorf(field+".price", "price too short: %g (min: %g)", selection.Price, MinPrice)
+		}
+	}
+
+	return nil
+}
+
+// Validate validates the requested event IDs
+func (r *BatchGetEventsRequest) Validate() error {
+	if err := r.validateIds(); err != nil {
+		return fmt.Errorf("ids validation failed: %w", err)
+	}
+	return nil
+}
+
+// validateIds validates the event IDs constraints
+func (r *BatchGetEventsRequest) validateIds() error {
+	if len(r.Ids) == 0 {
+		return fieldErrorf("ids", "at least one ID is required")
+	}
+
+	if len(r.Ids) > MaxBatchIDs {
+		return fieldErrorf("ids", "too many IDs: got %d, max allowed %d",
+			len(r.Ids), MaxBatchIDs)
+	}
+
+	seen := make(map[int64]bool)
+	for i, id := range r.Ids {
+		if id <= 0 {
+			return fieldErrorf("ids", "invalid event ID at position %d: %d (must be positive)", i, id)
+		}
+
+		if seen[id] {
+			return fieldErrorf("ids", "duplicate event ID: %d", id)
+		}
+		seen[id] = true
+	}
+
 	return nil
 }
 
@@ -34,6 +326,22 @@ func (f *ListEventsRequestFilter) Validate() error {
 		return fmt.Errorf("sport_types validation failed: %w", err)
 	}
 
+	if err := validateFilterIDs("filter.competition_ids", "competition", f.CompetitionIds); err != nil {
+		return fmt.Errorf("competition_ids validation failed: %w", err)
+	}
+
+	if err := validateFilterIDs("filter.season_ids", "season", f.SeasonIds); err != nil {
+		return fmt.Errorf("season_ids validation failed: %w", err)
+	}
+
+	if err := validateFilterIDs("filter.team_ids", "team", f.TeamIds); err != nil {
+		return fmt.Errorf("team_ids validation failed: %w", err)
+	}
+
+	if err := validateFilterIDs("filter.venue_ids", "venue", f.VenueIds); err != nil {
+		return fmt.Errorf("venue_ids validation failed: %w", err)
+	}
+
 	if err := f.validateVisibleOnly(); err != nil {
 		return fmt.Errorf("visible_only validation failed: %w", err)
 	}
@@ -42,13 +350,25 @@ func (f *ListEventsRequestFilter) Validate() error {
 		return fmt.Errorf("sorting validation failed: %w", err)
 	}
 
+	if err := validateLimit(f.Limit); err != nil {
+		return fmt.Errorf("limit validation failed: %w", err)
+	}
+
+	return nil
+}
+
+// validateLimit validates the limit of a list, if set
+func validateLimit(limit *int32) error {
+	if limit != nil && (*limit < 1 || *limit > MaxListLimit) {
+		return fieldErrorf("filter.limit", "invalid limit: %d (must be between 1 and %d)", *limit, MaxListLimit)
+	}
 	return nil
 }
 
 // validateSportTypes validates sport types constraints
 func (f *ListEventsRequestFilter) validateSportTypes() error {
 	if len(f.SportTypes) > MaxSportTypes {
-		return fmt.Errorf("too many sport types: got %d, max allowed %d",
+		return fieldErrorf("filter.sport_types", "too many sport types: got %d, max allowed %d",
 			len(f.SportTypes), MaxSportTypes)
 	}
 
@@ -57,16 +377,16 @@ func (f *ListEventsRequestFilter) validateSportTypes() error {
 		sportType = strings.TrimSpace(sportType)
 		
 		if sportType == "" {
-			return fmt.Errorf("empty sport type at position %d", i)
+			return fieldErrorf("filter.sport_types", "empty sport type at position %d", i)
 		}
 
 		if len(sportType) > MaxSportTypeLength {
-			return fmt.Errorf("sport type too long at position %d: %d characters (max: %d)",
+			return fieldErrorf("filter.sport_types", "sport type too long at position %d: %d characters (max: %d)",
 				i, len(sportType), MaxSportTypeLength)
 		}
 
 		if seen[sportType] {
-			return fmt.Errorf("duplicate sport type: %s", sportType)
+			return fieldErrorf("filter.sport_types", "duplicate sport type: %s", sportType)
 		}
 		seen[sportType] = true
 	}
@@ -74,6 +394,28 @@ func (f *ListEventsRequestFilter) validateSportTypes() error {
 	return nil
 }
 
+// validateFilterIDs validates that the IDs of a filter field are positive,
+// unique and not too many
+func validateFilterIDs(field, kind string, ids []int64) error {
+	if len(ids) > MaxFilterIDs {
+		return fieldErrorf(field, "too many %s IDs: got %d, max allowed %d", kind, len(ids), MaxFilterIDs)
+	}
+
+	seen := make(map[int64]bool)
+	for i, id := range ids {
+		if id <= 0 {
+			return fieldErrorf(field, "invalid %s ID at position %d: %d (must be positive)", kind, i, id)
+		}
+
+		if seen[id] {
+			return fieldErrorf(field, "duplicate %s ID: %d", kind, id)
+		}
+		seen[id] = true
+	}
+
+	return nil
+}
+
 // validateVisibleOnly validates visible_only field (future extensibility)
 func (f *ListEventsRequestFilter) validateVisibleOnly() error {
 	return nil
@@ -87,7 +429,7 @@ func (f *ListEventsRequestFilter) validateSorting() error {
 		case SortField_ADVERTISED_START_TIME, SortField_NAME, SortField_SPORT_TYPE:
 			// Valid sort fields
 		default:
-			return fmt.Errorf("invalid sort field: %v", *f.SortField)
+			return fieldErrorf("filter.sort_field", "invalid sort field: %v", *f.SortField)
 		}
 	}
 
@@ -97,7 +439,7 @@ func (f *ListEventsRequestFilter) validateSorting() error {
 		case SortDirection_ASC, SortDirection_DESC:
 			// Valid sort directions
 		default:
-			return fmt.Errorf("invalid sort direction: %v", *f.SortDirection)
+			return fieldErrorf("filter.sort_direction", "invalid sort direction: %v", *f.SortDirection)
 		}
 	}
 
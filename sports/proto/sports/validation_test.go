@@ -1,8 +1,14 @@
 package sports
 
 import (
+	"errors"
+	"fmt"
+	"math"
 	"strings"
 	"testing"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 func TestGetEventRequest_Validate(t *testing.T) {
@@ -56,6 +62,62 @@ func TestGetEventRequest_Validate(t *testing.T) {
 	}
 }
 
+func TestBatchGetEventsRequest_Validate(t *testing.T) {
+	tooMany := make([]int64, MaxBatchIDs+1)
+	for i := range tooMany {
+		tooMany[i] = int64(i + 1)
+	}
+
+	tests := []struct {
+		name    string
+		ids     []int64
+		wantErr bool
+		errMsg  string
+	}{
+		{
+			name: "valid ids",
+			ids:  []int64{3, 1, 2},
+		},
+		{
+			name:    "no ids",
+			wantErr: true,
+			errMsg:  "at least one ID is required",
+		},
+		{
+			name:    "too many ids",
+			ids:     tooMany,
+			wantErr: true,
+			errMsg:  "too many IDs: got 101, max allowed 100",
+		},
+		{
+			name:    "negative id",
+			ids:     []int64{1, -2},
+			wantErr: true,
+			errMsg:  "invalid event ID at position 1: -2",
+		},
+		{
+			name:    "duplicate ids",
+			ids:     []int64{4, 2, 4},
+			wantErr: true,
+			errMsg:  "duplicate event ID: 4",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := (&BatchGetEventsRequest{Ids: tt.ids}).Validate()
+
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+					t.Errorf("Validate() error = %v, want error containing %q", err, tt.errMsg)
+				}
+			} else if err != nil {
+				t.Errorf("Validate() error = %v, want nil", err)
+			}
+		})
+	}
+}
+
 func TestListEventsRequest_Validate(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -77,6 +139,49 @@ func TestListEventsRequest_Validate(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "valid hierarchy filter",
+			request: &ListEventsRequest{
+				Filter: &ListEventsRequestFilter{
+					CompetitionIds: []int64{1, 2},
+					SeasonIds:      []int64{3},
+					TeamIds:        []int64{4, 5, 6},
+					VenueIds:       []int64{1, 3},
+					TodayAtVenue:   boolPtr(true),
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "open events with a limit",
+			request: &ListEventsRequest{
+				Filter: &ListEventsRequestFilter{
+					OpenOnly: boolPtr(true),
+					Limit:    int32Ptr(10),
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "negative limit",
+			request: &ListEventsRequest{
+				Filter: &ListEventsRequestFilter{
+					Limit: int32Ptr(-1),
+				},
+			},
+			wantErr: true,
+			errMsg:  "invalid limit: -1",
+		},
+		{
+			name: "duplicate team",
+			request: &ListEventsRequest{
+				Filter: &ListEventsRequestFilter{
+					TeamIds: []int64{4, 4},
+				},
+			},
+			wantErr: true,
+			errMsg:  "duplicate team ID: 4",
+		},
 		{
 			name: "invalid filter",
 			request: &ListEventsRequest{
@@ -305,7 +410,170 @@ func TestListEventsRequestFilter_ValidateComplete(t *testing.T) {
 	}
 }
 
+func TestUpdateMarketRequest_Validate(t *testing.T) {
+	line := func(v float64) *float64 { return &v }
+	selections := func(prices ...float64) []*Selection {
+		var out []*Selection
+		for i, price := range prices {
+			out = append(out, &Selection{Name: fmt.Sprintf("Selection %d", i+1), Price: price})
+		}
+		return out
+	}
+
+	tests := []struct {
+		name    string
+		request *UpdateMarketRequest
+		wantErr bool
+		errMsg  string
+	}{
+		{
+			name:    "valid head to head",
+			request: &UpdateMarketRequest{EventId: 1, Market: &Market{Selections: selections(1.9, 1.9)}},
+		},
+		{
+			name:    "valid head to head with draw",
+			request: &UpdateMarketRequest{EventId: 1, Market: &Market{Id: 4, EventId: 1, Selections: selections(2.1, 3.4, 3.2)}},
+		},
+		{
+			name:    "valid line",
+			request: &UpdateMarketRequest{EventId: 1, Market: &Market{Type: MarketType_LINE, Line: line(-6.5), Selections: selections(1.9, 1.9)}},
+		},
+		{
+			name:    "valid over under",
+			request: &UpdateMarketRequest{EventId: 1, Market: &Market{Type: MarketType_OVER_UNDER, Line: line(180.5), Status: MarketStatus_MARKET_SUSPENDED, Selections: selections(1.87, 1.93)}},
+		},
+		{
+			name:    "zero event ID",
+			request: &UpdateMarketRequest{Market: &Market{Selections: selections(1.9, 1.9)}},
+			wantErr: true,
+			errMsg:  "must be positive",
+		},
+		{
+			name:    "missing market",
+			request: &UpdateMarketRequest{EventId: 1},
+			wantErr: true,
+			errMsg:  "market is required",
+		},
+		{
+			name:    "negative market ID",
+			request: &UpdateMarketRequest{EventId: 1, Market: &Market{Id: -1, Selections: selections(1.9, 1.9)}},
+			wantErr: true,
+			errMsg:  "must not be negative",
+		},
+		{
+			name:    "other event",
+			request: &UpdateMarketRequest{EventId: 1, Market: &Market{EventId: 2, Selections: selections(1.9, 1.9)}},
+			wantErr: true,
+			errMsg:  "does not match",
+		},
+		{
+			name:    "invalid type",
+			request: &UpdateMarketRequest{EventId: 1, Market: &Market{Type: MarketType(42), Selections: selections(1.9, 1.9)}},
+			wantErr: true,
+			errMsg:  "invalid market type",
+		},
+		{
+			name:    "invalid status",
+			request: &UpdateMarketRequest{EventId: 1, Market: &Market{Status: MarketStatus(42), Selections: selections(1.9, 1.9)}},
+			wantErr: true,
+			errMsg:  "invalid market status",
+		},
+		{
+			name:    "head to head with line",
+			request: &UpdateMarketRequest{EventId: 1, Market: &Market{Line: line(1.5), Selections: selections(1.9, 1.9)}},
+			wantErr: true,
+			errMsg:  "have no line",
+		},
+		{
+			name:    "line without line",
+			request: &UpdateMarketRequest{EventId: 1, Market: &Market{Type: MarketType_LINE, Selections: selections(1.9, 1.9)}},
+			wantErr: true,
+			errMsg:  "need a line",
+		},
+		{
+			name:    "infinite line",
+			request: &UpdateMarketRequest{EventId: 1, Market: &Market{Type: MarketType_LINE, Line: line(math.Inf(1)), Selections: selections(1.9, 1.9)}},
+			wantErr: true,
+			errMsg:  "finite",
+		},
+		{
+			name:    "negative over under line",
+			request: &UpdateMarketRequest{EventId: 1, Market: &Market{Type: MarketType_OVER_UNDER, Line: line(-1), Selections: selections(1.9, 1.9)}},
+			wantErr: true,
+			errMsg:  "must be positive",
+		},
+		{
+			name:    "single selection",
+			request: &UpdateMarketRequest{EventId: 1, Market: &Market{Selections: selections(1.9)}},
+			wantErr: true,
+			errMsg:  "need 2 to 3 selections",
+		},
+		{
+			name:    "line with three selections",
+			request: &UpdateMarketRequest{EventId: 1, Market: &Market{Type: MarketType_LINE, Line: line(2.5), Selections: selections(1.9, 1.9, 1.9)}},
+			wantErr: true,
+			errMsg:  "need 2 to 2 selections",
+		},
+		{
+			name: "duplicate selection ID",
+			request: &UpdateMarketRequest{EventId: 1, Market: &Market{Selections: []*Selection{
+				{Id: 3, Name: "Home", Price: 1.9},
+				{Id: 3, Name: "Away", Price: 1.9},
+			}}},
+			wantErr: true,
+			errMsg:  "duplicate selection ID",
+		},
+		{
+			name: "duplicate selection name",
+			request: &UpdateMarketRequest{EventId: 1, Market: &Market{Selections: []*Selection{
+				{Name: "Home", Price: 1.9},
+				{Name: " home ", Price: 1.9},
+			}}},
+			wantErr: true,
+			errMsg:  "duplicate selection name",
+		},
+		{
+			name: "blank selection name",
+			request: &UpdateMarketRequest{EventId: 1, Market: &Market{Selections: []*Selection{
+				{Name: "Home", Price: 1.9},
+				{Name: "  ", Price: 1.9},
+			}}},
+			wantErr: true,
+			errMsg:  "selection name is required",
+		},
+		{
+			name:    "price too short",
+			request: &UpdateMarketRequest{EventId: 1, Market: &Market{Selections: selections(1.9, 1)}},
+			wantErr: true,
+			errMsg:  "price too short",
+		},
+		{
+			name:    "NaN price",
+			request: &UpdateMarketRequest{EventId: 1, Market: &Market{Selections: selections(math.NaN(), 1.9)}},
+			wantErr: true,
+			errMsg:  "finite",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.request.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("UpdateMarketRequest.Validate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr && !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("UpdateMarketRequest.Validate() error = %v, want error containing %q", err, tt.errMsg)
+			}
+		})
+	}
+}
+
 // Helper function to create bool pointer
+func int32Ptr(n int32) *int32 {
+	return &n
+}
+
 func boolPtr(b bool) *bool {
 	return &b
 }
@@ -332,4 +600,161 @@ func BenchmarkListEventsRequest_Validate(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		_ = req.Validate()
 	}
-}
\ No newline at end of file
+}
+func TestImportEventsRequest_Validate(t *testing.T) {
+	valid := func() *ImportEventsRequest {
+		return &ImportEventsRequest{
+			SourceId:            "feed-1",
+			Name:                "Lions vs Tigers",
+			SportType:           "football",
+			AdvertisedStartTime: timestamppb.New(time.Date(2026, 11, 3, 8, 0, 0, 0, time.UTC)),
+		}
+	}
+
+	tests := []struct {
+		name      string
+		modify    func(r *ImportEventsRequest)
+		wantField string
+		errMsg    string
+	}{
+		{name: "valid event", modify: func(r *ImportEventsRequest) {}},
+		{name: "valid event at a venue", modify: func(r *ImportEventsRequest) { r.VenueId = 3 }},
+		{name: "missing source id", modify: func(r *ImportEventsRequest) { r.SourceId = " " }, wantField: "source_id", errMsg: "source ID is required"},
+		{name: "long source id", modify: func(r *ImportEventsRequest) { r.SourceId = strings.Repeat("x", MaxSourceIDLength+1) }, wantField: "source_id", errMsg: "source ID too long"},
+		{name: "missing name", modify: func(r *ImportEventsRequest) { r.Name = "" }, wantField: "name", errMsg: "name is required"},
+		{name: "long name", modify: func(r *ImportEventsRequest) { r.Name = strings.Repeat("x", MaxEventNameLength+1) }, wantField: "name", errMsg: "name too long"},
+		{name: "missing sport type", modify: func(r *ImportEventsRequest) { r.SportType = "" }, wantField: "sport_type", errMsg: "sport type is required"},
+		{name: "long sport type", modify: func(r *ImportEventsRequest) { r.SportType = strings.Repeat("x", MaxSportTypeLength+1) }, wantField: "sport_type", errMsg: "sport type too long"},
+		{name: "negative venue", modify: func(r *ImportEventsRequest) { r.VenueId = -1 }, wantField: "venue_id", errMsg: "invalid venue ID: -1"},
+		{name: "missing start time", modify: func(r *ImportEventsRequest) { r.AdvertisedStartTime = nil }, wantField: "advertised_start_time", errMsg: "advertised start time is required"},
+		{name: "invalid start time", modify: func(r *ImportEventsRequest) { r.AdvertisedStartTime.Nanos = -1 }, wantField: "advertised_start_time", errMsg: "invalid advertised start time"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := valid()
+			tt.modify(req)
+
+			err := req.Validate()
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v, want nil", err)
+				}
+				return
+			}
+
+			var fieldErr *FieldError
+			if !errors.As(err, &fieldErr) || fieldErr.Field != tt.wantField || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Validate() error = %v, want a %s error containing %q", err, tt.wantField, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestValidate_FieldErrors(t *testing.T) {
+	invalidSort := SortField(42)
+
+	tests := []struct {
+		name      string
+		validate  func() error
+		wantField string
+	}{
+		{
+			name:      "event id",
+			validate:  (&GetEventRequest{}).Validate,
+			wantField: "id",
+		},
+		{
+			name:      "sport types",
+			validate:  (&ListEventsRequest{Filter: &ListEventsRequestFilter{SportTypes: []string{" "}}}).Validate,
+			wantField: "filter.sport_types",
+		},
+		{
+			name:      "sort field",
+			validate:  (&ListEventsRequest{Filter: &ListEventsRequestFilter{SortField: &invalidSort}}).Validate,
+			wantField: "filter.sort_field",
+		},
+		{
+			name:      "batch ids",
+			validate:  (&BatchGetEventsRequest{}).Validate,
+			wantField: "ids",
+		},
+		{
+			name:      "competition ids",
+			validate:  (&ListEventsRequest{Filter: &ListEventsRequestFilter{CompetitionIds: []int64{1, 0}}}).Validate,
+			wantField: "filter.competition_ids",
+		},
+		{
+			name:      "season ids",
+			validate:  (&ListEventsRequest{Filter: &ListEventsRequestFilter{SeasonIds: []int64{2, 2}}}).Validate,
+			wantField: "filter.season_ids",
+		},
+		{
+			name:      "team ids",
+			validate:  (&ListEventsRequest{Filter: &ListEventsRequestFilter{TeamIds: make([]int64, MaxFilterIDs+1)}}).Validate,
+			wantField: "filter.team_ids",
+		},
+		{
+			name:      "venue ids",
+			validate:  (&ListEventsRequest{Filter: &ListEventsRequestFilter{VenueIds: []int64{-1}}}).Validate,
+			wantField: "filter.venue_ids",
+		},
+		{
+			name:      "competitions sport id",
+			validate:  (&ListCompetitionsRequest{}).Validate,
+			wantField: "sport_id",
+		},
+		{
+			name:      "teams sport id",
+			validate:  (&ListTeamsRequest{SportId: -2}).Validate,
+			wantField: "sport_id",
+		},
+		{
+			name:      "competition id",
+			validate:  (&ListCompetitionEventsRequest{}).Validate,
+			wantField: "competition_id",
+		},
+		{
+			name:      "season id",
+			validate:  (&ListCompetitionEventsRequest{CompetitionId: 1, SeasonId: new(int64)}).Validate,
+			wantField: "season_id",
+		},
+		{
+			name:      "market event id",
+			validate:  (&ListMarketsRequest{}).Validate,
+			wantField: "event_id",
+		},
+		{
+			name:      "suspended event id",
+			validate:  (&SuspendEventRequest{Id: -1}).Validate,
+			wantField: "id",
+		},
+		{
+			name:      "selection price",
+			validate:  (&UpdateMarketRequest{EventId: 1, Market: &Market{Selections: []*Selection{{Name: "Home", Price: 1.9}, {Name: "Away"}}}}).Validate,
+			wantField: "market.selections[1].price",
+		},
+		{
+			name:      "history event id",
+			validate:  (&ListEventHistoryRequest{Field: "name"}).Validate,
+			wantField: "event_id",
+		},
+		{
+			name:      "history field",
+			validate:  (&ListEventHistoryRequest{EventId: 1, Field: "status"}).Validate,
+			wantField: "field",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fieldErr *FieldError
+			if err := tt.validate(); !errors.As(err, &fieldErr) {
+				t.Fatalf("Validate() error = %v, want a FieldError", err)
+			}
+			if fieldErr.Field != tt.wantField {
+				t.Errorf("FieldError.Field = %q, want %q", fieldErr.Field, tt.wantField)
+			}
+		})
+	}
+}
@@ -2,13 +2,40 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 
 	"git.neds.sh/matty/entain/sports/db"
+	"git.neds.sh/matty/entain/sports/internal/auth"
+	"git.neds.sh/matty/entain/sports/internal/feed"
 	"git.neds.sh/matty/entain/sports/proto/sports"
 	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
 )
 
+// MethodRoles maps each Sports RPC to the role required to call it.
+// Reads are open to everyone, but callers without auth.RoleTrader only ever
+// see visible events.
+var MethodRoles = map[string]string{
+	"/sports.Sports/ListEvents":     auth.RoleAnonymous,
+	"/sports.Sports/StreamEvents":   auth.RoleAnonymous,
+	"/sports.Sports/GetEvent":       auth.RoleAnonymous,
+	"/sports.Sports/BatchGetEvents": auth.RoleAnonymous,
+	"/sports.Sports/ListMarkets":    auth.RoleAnonymous,
+	"/sports.Sports/UpdateMarket":   auth.RoleTrader,
+	"/sports.Sports/SuspendEvent":   auth.RoleTrader,
+	"/sports.Sports/ImportEvents":   auth.RoleTrader,
+	// The history names who changed an event, and lists hidden events too
+	"/sports.Sports/ListEventHistory": auth.RoleTrader,
+
+	"/sports.Sports/ListSports":            auth.RoleAnonymous,
+	"/sports.Sports/ListCompetitions":      auth.RoleAnonymous,
+	"/sports.Sports/ListCompetitionEvents": auth.RoleAnonymous,
+	"/sports.Sports/ListTeams":             auth.RoleAnonymous,
+	"/sports.Sports/ListVenues":            auth.RoleAnonymous,
+}
+
 // Sports defines the interface for sports-related operations.
 // It provides methods to interact with sports event data and retrieve event information.
 type Sports interface {
@@ -18,26 +45,78 @@ type Sports interface {
 	// Returns a response with the filtered events or an error if the operation fails.
 	ListEvents(ctx context.Context, in *sports.ListEventsRequest) (*sports.ListEventsResponse, error)
 
+	// StreamEvents sends the events ListEvents would return one at a time, as
+	// they are read, so that lists of any size can be exported.
+	StreamEvents(in *sports.ListEventsRequest, stream sports.Sports_StreamEventsServer) error
+
 	// GetEvent retrieves a single event by its ID.
 	// It accepts a context for request lifecycle management and cancellation,
 	// and a request containing the event ID to retrieve.
 	// Returns a response with the event or an error if the operation fails.
 	GetEvent(ctx context.Context, in *sports.GetEventRequest) (*sports.GetEventResponse, error)
+
+	// BatchGetEvents retrieves several events by ID with a single query.
+	// Events are returned in the order of the requested IDs, and IDs that match
+	// no event are listed in the response's missing IDs instead of failing the call.
+	BatchGetEvents(ctx context.Context, in *sports.BatchGetEventsRequest) (*sports.BatchGetEventsResponse, error)
+
+	// ListMarkets retrieves the betting markets of an event with their selections.
+	// Markets of a suspended event are reported as suspended.
+	ListMarkets(ctx context.Context, in *sports.ListMarketsRequest) (*sports.ListMarketsResponse, error)
+
+	// UpdateMarket creates a market on an event, or replaces one of its markets.
+	// Settled markets cannot be changed.
+	UpdateMarket(ctx context.Context, in *sports.UpdateMarketRequest) (*sports.UpdateMarketResponse, error)
+
+	// SuspendEvent suspends or resumes betting on an event and all its markets.
+	SuspendEvent(ctx context.Context, in *sports.SuspendEventRequest) (*sports.SuspendEventResponse, error)
+
+	// ListSports retrieves every sport.
+	ListSports(ctx context.Context, in *sports.ListSportsRequest) (*sports.ListSportsResponse, error)
+
+	// ListCompetitions retrieves the competitions of a sport with their seasons.
+	ListCompetitions(ctx context.Context, in *sports.ListCompetitionsRequest) (*sports.ListCompetitionsResponse, error)
+
+	// ListCompetitionEvents retrieves the events of a competition, or of one of
+	// its seasons. Callers without auth.RoleTrader only see visible events.
+	ListCompetitionEvents(ctx context.Context, in *sports.ListCompetitionEventsRequest) (*sports.ListCompetitionEventsResponse, error)
+
+	// ListTeams retrieves the teams of a sport.
+	ListTeams(ctx context.Context, in *sports.ListTeamsRequest) (*sports.ListTeamsResponse, error)
+
+	// ListVenues retrieves every venue with its time zone.
+	ListVenues(ctx context.Context, in *sports.ListVenuesRequest) (*sports.ListVenuesResponse, error)
+
+	// ImportEvents creates or updates the events streamed by the caller by
+	// their source ID. Every record is validated, and the events are saved in
+	// one transaction only when all of them are valid and it is not a dry run.
+	// Invalid records are reported in the response rather than failing the call.
+	ImportEvents(stream sports.Sports_ImportEventsServer) error
+
+	// ListEventHistory retrieves the recorded changes to an event, oldest
+	// first, optionally only those to a single field.
+	ListEventHistory(ctx context.Context, in *sports.ListEventHistoryRequest) (*sports.ListEventHistoryResponse, error)
 }
 
 type sportsService struct {
-	eventsRepo db.EventsRepo
-	logger     *zap.Logger
+	eventsRepo    db.EventsRepo
+	marketsRepo   db.MarketsRepo
+	hierarchyRepo db.HierarchyRepo
+	venuesRepo    db.VenuesRepo
+	logger        *zap.Logger
 }
 
 // NewSportsService creates a new sports service with injected logger
-func NewSportsService(eventsRepo db.EventsRepo, logger *zap.Logger) Sports {
+func NewSportsService(eventsRepo db.EventsRepo, marketsRepo db.MarketsRepo, hierarchyRepo db.HierarchyRepo, venuesRepo db.VenuesRepo, logger *zap.Logger) Sports {
 	if logger == nil {
 		logger = zap.NewNop()
 	}
 	return &sportsService{
-		eventsRepo: eventsRepo,
-		logger:     logger,
+		eventsRepo:    eventsRepo,
+		marketsRepo:   marketsRepo,
+		hierarchyRepo: hierarchyRepo,
+		venuesRepo:    venuesRepo,
+		logger:        logger,
 	}
 }
 
@@ -79,10 +158,16 @@ func (s *sportsService) ListEvents(ctx context.Context, in *sports.ListEventsReq
 		return nil, fmt.Errorf("invalid request: %w", err)
 	}
 
+	// Hidden events are only visible to traders, whatever the filter asks for
+	filter := in.Filter
+	if !auth.FromContext(ctx).HasRole(auth.RoleTrader) {
+		filter = visibleOnly(filter)
+	}
+
 	reqLogger.Debug("Calling repository")
 
 	// Call repository
-	events, err := s.eventsRepo.List(in.Filter)
+	events, err := s.eventsRepo.List(filter)
 	if err != nil {
 		reqLogger.Error("Repository call failed",
 			zap.Error(err),
@@ -93,6 +178,70 @@ func (s *sportsService) ListEvents(ctx context.Context, in *sports.ListEventsReq
 	return &sports.ListEventsResponse{Events: events}, nil
 }
 
+func (s *sportsService) StreamEvents(in *sports.ListEventsRequest, stream sports.Sports_StreamEventsServer) error {
+	reqLogger := s.logger.With(
+		zap.String("method", "StreamEvents"),
+	)
+
+	reqLogger.Debug("Request started", zap.Any("filter", in.GetFilter()))
+
+	ctx := stream.Context()
+
+	// Check if context is cancelled
+	select {
+	case <-ctx.Done():
+		reqLogger.Warn("Request cancelled",
+			zap.Error(ctx.Err()),
+		)
+		return fmt.Errorf("request cancelled: %w", ctx.Err())
+	default:
+		// Continue processing
+	}
+
+	// Input validation
+	if in == nil {
+		reqLogger.Warn("Request validation failed: nil request")
+		return fmt.Errorf("request cannot be nil")
+	}
+
+	// Validate request using proto validation
+	if err := in.Validate(); err != nil {
+		reqLogger.Warn("Request validation failed",
+			zap.Error(err),
+		)
+		return fmt.Errorf("invalid request: %w", err)
+	}
+
+	// Hidden events are only visible to traders, whatever the filter asks for
+	filter := in.Filter
+	if !auth.FromContext(ctx).HasRole(auth.RoleTrader) {
+		filter = visibleOnly(filter)
+	}
+
+	reqLogger.Debug("Calling repository")
+
+	// Each event is sent as it is read; a failed send ends the read
+	sent := 0
+	err := s.eventsRepo.Stream(filter, func(event *sports.Event) error {
+		if err := stream.Send(event); err != nil {
+			return err
+		}
+		sent++
+		return nil
+	})
+	if err != nil {
+		reqLogger.Error("Repository call failed",
+			zap.Int("sent", sent),
+			zap.Error(err),
+		)
+		return fmt.Errorf("failed to stream events: %w", err)
+	}
+
+	reqLogger.Debug("Events streamed", zap.Int("sent", sent))
+
+	return nil
+}
+
 func (s *sportsService) GetEvent(ctx context.Context, in *sports.GetEventRequest) (*sports.GetEventResponse, error) {
 	reqLogger := s.logger.With(
 		zap.String("method", "GetEvent"),
@@ -143,21 +292,816 @@ func (s *sportsService) GetEvent(ctx context.Context, in *sports.GetEventRequest
 		return nil, fmt.Errorf("failed to retrieve event: %w", err)
 	}
 
-	return &sports.GetEventResponse{Event: event}, nil
+	// Report hidden events as missing so their existence is not leaked
+	if !event.Visible && !auth.FromContext(ctx).HasRole(auth.RoleTrader) {
+		reqLogger.Debug("Hidden event requested without trader role")
+		return nil, fmt.Errorf("failed to retrieve event: event with ID %d %w", in.Id, db.ErrNotFound)
+	}
+
+	resp := &sports.GetEventResponse{Event: event}
+	if in.IncludeMarkets {
+		markets, err := s.marketsRepo.List(in.Id)
+		if err != nil {
+			reqLogger.Error("Repository call failed",
+				zap.Error(err),
+			)
+			return nil, fmt.Errorf("failed to retrieve markets: %w", err)
+		}
+		resp.Markets = withEventSuspension(event, markets)
+	}
+
+	return resp, nil
 }
 
-// SportsServer is a gRPC server wrapper that embeds the required UnimplementedSportsServer
-type SportsServer struct {
-	sports.UnimplementedSportsServer
-	Service Sports
+func (s *sportsService) BatchGetEvents(ctx context.Context, in *sports.BatchGetEventsRequest) (*sports.BatchGetEventsResponse, error) {
+	reqLogger := s.logger.With(
+		zap.String("method", "BatchGetEvents"),
+		zap.Int("id_count", len(in.GetIds())),
+	)
+
+	reqLogger.Debug("Request started")
+
+	// Context validation
+	if ctx == nil {
+		reqLogger.Error("Context validation failed: nil context")
+		return nil, fmt.Errorf("context cannot be nil")
+	}
+
+	// Check if context is cancelled
+	select {
+	case <-ctx.Done():
+		reqLogger.Warn("Request cancelled",
+			zap.Error(ctx.Err()),
+		)
+		return nil, fmt.Errorf("request cancelled: %w", ctx.Err())
+	default:
+		// Continue processing
+	}
+
+	// Input validation
+	if in == nil {
+		reqLogger.Warn("Request validation failed: nil request")
+		return nil, fmt.Errorf("request cannot be nil")
+	}
+
+	// Validate request using proto validation
+	if err := in.Validate(); err != nil {
+		reqLogger.Warn("Request validation failed",
+			zap.Error(err),
+		)
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+
+	reqLogger.Debug("Calling repository")
+
+	// Call repository
+	events, err := s.eventsRepo.GetByIDs(in.Ids)
+	if err != nil {
+		reqLogger.Error("Repository call failed",
+			zap.Error(err),
+		)
+		return nil, fmt.Errorf("failed to retrieve events: %w", err)
+	}
+
+	// Hidden events are reported as missing, as GetEvent does
+	isTrader := auth.FromContext(ctx).HasRole(auth.RoleTrader)
+	found := make(map[int64]bool, len(events))
+	resp := &sports.BatchGetEventsResponse{}
+	for _, event := range events {
+		if !event.Visible && !isTrader {
+			continue
+		}
+		found[event.Id] = true
+		resp.Events = append(resp.Events, event)
+	}
+	for _, id := range in.Ids {
+		if !found[id] {
+			resp.MissingIds = append(resp.MissingIds, id)
+		}
+	}
+
+	return resp, nil
 }
 
-// ListEvents implements the gRPC SportsServer interface
-func (s *SportsServer) ListEvents(ctx context.Context, req *sports.ListEventsRequest) (*sports.ListEventsResponse, error) {
-	return s.Service.ListEvents(ctx, req)
+func (s *sportsService) ListMarkets(ctx context.Context, in *sports.ListMarketsRequest) (*sports.ListMarketsResponse, error) {
+	reqLogger := s.logger.With(
+		zap.String("method", "ListMarkets"),
+		zap.Int64("event_id", in.GetEventId()),
+	)
+
+	reqLogger.Debug("Request started")
+
+	// Context validation
+	if ctx == nil {
+		reqLogger.Error("Context validation failed: nil context")
+		return nil, fmt.Errorf("context cannot be nil")
+	}
+
+	// Check if context is cancelled
+	select {
+	case <-ctx.Done():
+		reqLogger.Warn("Request cancelled",
+			zap.Error(ctx.Err()),
+		)
+		return nil, fmt.Errorf("request cancelled: %w", ctx.Err())
+	default:
+		// Continue processing
+	}
+
+	// Input validation
+	if in == nil {
+		reqLogger.Warn("Request validation failed: nil request")
+		return nil, fmt.Errorf("request cannot be nil")
+	}
+
+	// Validate request using proto validation
+	if err := in.Validate(); err != nil {
+		reqLogger.Warn("Request validation failed",
+			zap.Error(err),
+		)
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+
+	reqLogger.Debug("Calling repository")
+
+	event, err := s.eventsRepo.GetByID(in.EventId)
+	if err != nil {
+		reqLogger.Error("Repository call failed",
+			zap.Error(err),
+		)
+		return nil, fmt.Errorf("failed to retrieve event: %w", err)
+	}
+
+	// Markets of hidden events are as hidden as the event itself
+	if !event.Visible && !auth.FromContext(ctx).HasRole(auth.RoleTrader) {
+		reqLogger.Debug("Markets of hidden event requested without trader role")
+		return nil, fmt.Errorf("failed to retrieve event: event with ID %d %w", in.EventId, db.ErrNotFound)
+	}
+
+	markets, err := s.marketsRepo.List(in.EventId)
+	if err != nil {
+		reqLogger.Error("Repository call failed",
+			zap.Error(err),
+		)
+		return nil, fmt.Errorf("failed to retrieve markets: %w", err)
+	}
+
+	return &sports.ListMarketsResponse{Markets: withEventSuspension(event, markets)}, nil
 }
 
-// GetEvent implements the gRPC SportsServer interface
-func (s *SportsServer) GetEvent(ctx context.Context, req *sports.GetEventRequest) (*sports.GetEventResponse, error) {
-	return s.Service.GetEvent(ctx, req)
+func (s *sportsService) UpdateMarket(ctx context.Context, in *sports.UpdateMarketRequest) (*sports.UpdateMarketResponse, error) {
+	reqLogger := s.logger.With(
+		zap.String("method", "UpdateMarket"),
+		zap.Int64("event_id", in.GetEventId()),
+		zap.Int64("market_id", in.GetMarket().GetId()),
+	)
+
+	reqLogger.Debug("Request started")
+
+	// Context validation
+	if ctx == nil {
+		reqLogger.Error("Context validation failed: nil context")
+		return nil, fmt.Errorf("context cannot be nil")
+	}
+
+	// Check if context is cancelled
+	select {
+	case <-ctx.Done():
+		reqLogger.Warn("Request cancelled",
+			zap.Error(ctx.Err()),
+		)
+		return nil, fmt.Errorf("request cancelled: %w", ctx.Err())
+	default:
+		// Continue processing
+	}
+
+	// Input validation
+	if in == nil {
+		reqLogger.Warn("Request validation failed: nil request")
+		return nil, fmt.Errorf("request cannot be nil")
+	}
+
+	// Validate request using proto validation
+	if err := in.Validate(); err != nil {
+		reqLogger.Warn("Request validation failed",
+			zap.Error(err),
+		)
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+
+	reqLogger.Debug("Calling repository")
+
+	// The event must exist; only traders may call this method, so hidden events get markets too
+	event, err := s.eventsRepo.GetByID(in.EventId)
+	if err != nil {
+		reqLogger.Warn("Repository call failed",
+			zap.Error(err),
+		)
+		return nil, fmt.Errorf("failed to retrieve event: %w", err)
+	}
+
+	market := proto.Clone(in.Market).(*sports.Market)
+	market.EventId = in.EventId
+
+	saved, err := s.marketsRepo.Save(market)
+	if err != nil {
+		reqLogger.Warn("Repository call failed",
+			zap.Error(err),
+		)
+		return nil, fmt.Errorf("failed to save market: %w", err)
+	}
+
+	reqLogger.Info("Market saved", zap.Int64("saved_market_id", saved.Id))
+
+	return &sports.UpdateMarketResponse{Market: withEventSuspension(event, []*sports.Market{saved})[0]}, nil
+}
+
+func (s *sportsService) SuspendEvent(ctx context.Context, in *sports.SuspendEventRequest) (*sports.SuspendEventResponse, error) {
+	reqLogger := s.logger.With(
+		zap.String("method", "SuspendEvent"),
+		zap.Int64("event_id", in.GetId()),
+		zap.Bool("suspended", in.GetSuspended()),
+	)
+
+	reqLogger.Debug("Request started")
+
+	// Context validation
+	if ctx == nil {
+		reqLogger.Error("Context validation failed: nil context")
+		return nil, fmt.Errorf("context cannot be nil")
+	}
+
+	// Check if context is cancelled
+	select {
+	case <-ctx.Done():
+		reqLogger.Warn("Request cancelled",
+			zap.Error(ctx.Err()),
+		)
+		return nil, fmt.Errorf("request cancelled: %w", ctx.Err())
+	default:
+		// Continue processing
+	}
+
+	// Input validation
+	if in == nil {
+		reqLogger.Warn("Request validation failed: nil request")
+		return nil, fmt.Errorf("request cannot be nil")
+	}
+
+	// Validate request using proto validation
+	if err := in.Validate(); err != nil {
+		reqLogger.Warn("Request validation failed",
+			zap.Error(err),
+		)
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+
+	reqLogger.Debug("Calling repository")
+
+	if err := s.eventsRepo.SetSuspended(in.Id, in.Suspended, auth.FromContext(ctx).Subject); err != nil {
+		reqLogger.Warn("Repository call failed",
+			zap.Error(err),
+		)
+		return nil, fmt.Errorf("failed to suspend event: %w", err)
+	}
+
+	event, err := s.eventsRepo.GetByID(in.Id)
+	if err != nil {
+		reqLogger.Error("Repository call failed",
+			zap.Error(err),
+		)
+		return nil, fmt.Errorf("failed to retrieve event: %w", err)
+	}
+
+	reqLogger.Info("Event suspension changed")
+
+	return &sports.SuspendEventResponse{Event: event}, nil
+}
+
+func (s *sportsService) ListSports(ctx context.Context, in *sports.ListSportsRequest) (*sports.ListSportsResponse, error) {
+	reqLogger := s.logger.With(
+		zap.String("method", "ListSports"),
+	)
+
+	reqLogger.Debug("Request started")
+
+	// Context validation
+	if ctx == nil {
+		reqLogger.Error("Context validation failed: nil context")
+		return nil, fmt.Errorf("context cannot be nil")
+	}
+
+	// Check if context is cancelled
+	select {
+	case <-ctx.Done():
+		reqLogger.Warn("Request cancelled",
+			zap.Error(ctx.Err()),
+		)
+		return nil, fmt.Errorf("request cancelled: %w", ctx.Err())
+	default:
+		// Continue processing
+	}
+
+	// Input validation
+	if in == nil {
+		reqLogger.Warn("Request validation failed: nil request")
+		return nil, fmt.Errorf("request cannot be nil")
+	}
+
+	// Validate request using proto validation
+	if err := in.Validate(); err != nil {
+		reqLogger.Warn("Request validation failed",
+			zap.Error(err),
+		)
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+
+	reqLogger.Debug("Calling repository")
+
+	list, err := s.hierarchyRepo.ListSports()
+	if err != nil {
+		reqLogger.Error("Repository call failed",
+			zap.Error(err),
+		)
+		return nil, fmt.Errorf("failed to retrieve sports: %w", err)
+	}
+
+	return &sports.ListSportsResponse{Sports: list}, nil
+}
+
+func (s *sportsService) ListCompetitions(ctx context.Context, in *sports.ListCompetitionsRequest) (*sports.ListCompetitionsResponse, error) {
+	reqLogger := s.logger.With(
+		zap.String("method", "ListCompetitions"),
+		zap.Int64("sport_id", in.GetSportId()),
+	)
+
+	reqLogger.Debug("Request started")
+
+	// Context validation
+	if ctx == nil {
+		reqLogger.Error("Context validation failed: nil context")
+		return nil, fmt.Errorf("context cannot be nil")
+	}
+
+	// Check if context is cancelled
+	select {
+	case <-ctx.Done():
+		reqLogger.Warn("Request cancelled",
+			zap.Error(ctx.Err()),
+		)
+		return nil, fmt.Errorf("request cancelled: %w", ctx.Err())
+	default:
+		// Continue processing
+	}
+
+	// Input validation
+	if in == nil {
+		reqLogger.Warn("Request validation failed: nil request")
+		return nil, fmt.Errorf("request cannot be nil")
+	}
+
+	// Validate request using proto validation
+	if err := in.Validate(); err != nil {
+		reqLogger.Warn("Request validation failed",
+			zap.Error(err),
+		)
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+
+	reqLogger.Debug("Calling repository")
+
+	competitions, err := s.hierarchyRepo.ListCompetitions(in.SportId)
+	if err != nil {
+		reqLogger.Warn("Repository call failed",
+			zap.Error(err),
+		)
+		return nil, fmt.Errorf("failed to retrieve competitions: %w", err)
+	}
+
+	return &sports.ListCompetitionsResponse{Competitions: competitions}, nil
+}
+
+func (s *sportsService) ListCompetitionEvents(ctx context.Context, in *sports.ListCompetitionEventsRequest) (*sports.ListCompetitionEventsResponse, error) {
+	reqLogger := s.logger.With(
+		zap.String("method", "ListCompetitionEvents"),
+		zap.Int64("competition_id", in.GetCompetitionId()),
+	)
+
+	reqLogger.Debug("Request started")
+
+	// Context validation
+	if ctx == nil {
+		reqLogger.Error("Context validation failed: nil context")
+		return nil, fmt.Errorf("context cannot be nil")
+	}
+
+	// Check if context is cancelled
+	select {
+	case <-ctx.Done():
+		reqLogger.Warn("Request cancelled",
+			zap.Error(ctx.Err()),
+		)
+		return nil, fmt.Errorf("request cancelled: %w", ctx.Err())
+	default:
+		// Continue processing
+	}
+
+	// Input validation
+	if in == nil {
+		reqLogger.Warn("Request validation failed: nil request")
+		return nil, fmt.Errorf("request cannot be nil")
+	}
+
+	// Validate request using proto validation
+	if err := in.Validate(); err != nil {
+		reqLogger.Warn("Request validation failed",
+			zap.Error(err),
+		)
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+
+	reqLogger.Debug("Calling repository")
+
+	competition, err := s.hierarchyRepo.GetCompetition(in.CompetitionId)
+	if err != nil {
+		reqLogger.Warn("Repository call failed",
+			zap.Error(err),
+		)
+		return nil, fmt.Errorf("failed to retrieve competition: %w", err)
+	}
+
+	filter := &sports.ListEventsRequestFilter{CompetitionIds: []int64{in.CompetitionId}}
+	if in.SeasonId != nil {
+		if !hasSeason(competition, *in.SeasonId) {
+			reqLogger.Warn("Season is not of the competition", zap.Int64("season_id", *in.SeasonId))
+			return nil, fmt.Errorf("failed to retrieve season: season with ID %d of competition %d %w",
+				*in.SeasonId, in.CompetitionId, db.ErrNotFound)
+		}
+		filter.SeasonIds = []int64{*in.SeasonId}
+	}
+
+	// Hidden events are only visible to traders, as in ListEvents
+	if !auth.FromContext(ctx).HasRole(auth.RoleTrader) {
+		filter = visibleOnly(filter)
+	}
+
+	events, err := s.eventsRepo.List(filter)
+	if err != nil {
+		reqLogger.Error("Repository call failed",
+			zap.Error(err),
+		)
+		return nil, fmt.Errorf("failed to retrieve events: %w", err)
+	}
+
+	return &sports.ListCompetitionEventsResponse{Events: events}, nil
+}
+
+func (s *sportsService) ListTeams(ctx context.Context, in *sports.ListTeamsRequest) (*sports.ListTeamsResponse, error) {
+	reqLogger := s.logger.With(
+		zap.String("method", "ListTeams"),
+		zap.Int64("sport_id", in.GetSportId()),
+	)
+
+	reqLogger.Debug("Request started")
+
+	// Context validation
+	if ctx == nil {
+		reqLogger.Error("Context validation failed: nil context")
+		return nil, fmt.Errorf("context cannot be nil")
+	}
+
+	// Check if context is cancelled
+	select {
+	case <-ctx.Done():
+		reqLogger.Warn("Request cancelled",
+			zap.Error(ctx.Err()),
+		)
+		return nil, fmt.Errorf("request cancelled: %w", ctx.Err())
+	default:
+		// Continue processing
+	}
+
+	// Input validation
+	if in == nil {
+		reqLogger.Warn("Request validation failed: nil request")
+		return nil, fmt.Errorf("request cannot be nil")
+	}
+
+	// Validate request using proto validation
+	if err := in.Validate(); err != nil {
+		reqLogger.Warn("Request validation failed",
+			zap.Error(err),
+		)
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+
+	reqLogger.Debug("Calling repository")
+
+	teams, err := s.hierarchyRepo.ListTeams(in.SportId)
+	if err != nil {
+		reqLogger.Warn("Repository call failed",
+			zap.Error(err),
+		)
+		return nil, fmt.Errorf("failed to retrieve teams: %w", err)
+	}
+
+	return &sports.ListTeamsResponse{Teams: teams}, nil
+}
+
+func (s *sportsService) ListVenues(ctx context.Context, in *sports.ListVenuesRequest) (*sports.ListVenuesResponse, error) {
+	reqLogger := s.logger.With(
+		zap.String("method", "ListVenues"),
+	)
+
+	reqLogger.Debug("Request started")
+
+	// Context validation
+	if ctx == nil {
+		reqLogger.Error("Context validation failed: nil context")
+		return nil, fmt.Errorf("context cannot be nil")
+	}
+
+	// Check if context is cancelled
+	select {
+	case <-ctx.Done():
+		reqLogger.Warn("Request cancelled",
+			zap.Error(ctx.Err()),
+		)
+		return nil, fmt.Errorf("request cancelled: %w", ctx.Err())
+	default:
+		// Continue processing
+	}
+
+	// Input validation
+	if in == nil {
+		reqLogger.Warn("Request validation failed: nil request")
+		return nil, fmt.Errorf("request cannot be nil")
+	}
+
+	// Validate request using proto validation
+	if err := in.Validate(); err != nil {
+		reqLogger.Warn("Request validation failed",
+			zap.Error(err),
+		)
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+
+	reqLogger.Debug("Calling repository")
+
+	list, err := s.venuesRepo.List()
+	if err != nil {
+		reqLogger.Error("Repository call failed",
+			zap.Error(err),
+		)
+		return nil, fmt.Errorf("failed to retrieve venues: %w", err)
+	}
+
+	return &sports.ListVenuesResponse{Venues: list}, nil
+}
+
+// hasSeason reports whether seasonID is a season of competition.
+func (s *sportsService) ImportEvents(stream sports.Sports_ImportEventsServer) error {
+	reqLogger := s.logger.With(
+		zap.String("method", "ImportEvents"),
+	)
+
+	reqLogger.Debug("Request started")
+
+	ctx := stream.Context()
+
+	var (
+		records []feed.Record
+		dryRun  bool
+	)
+	for {
+		in, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			reqLogger.Warn("Failed to receive event",
+				zap.Error(err),
+			)
+			return fmt.Errorf("failed to receive event: %w", err)
+		}
+
+		if len(records) == sports.MaxImportEvents {
+			reqLogger.Warn("Request validation failed: too many events")
+			return &sports.FieldError{Field: "events", Description: fmt.Sprintf("too many events: more than %d", sports.MaxImportEvents)}
+		}
+
+		// The dry run flag of the first event applies to the whole import
+		if len(records) == 0 {
+			dryRun = in.DryRun
+		}
+		records = append(records, feed.Record{Row: len(records) + 1, Event: in})
+	}
+
+	// Check if context is cancelled
+	select {
+	case <-ctx.Done():
+		reqLogger.Warn("Request cancelled",
+			zap.Error(ctx.Err()),
+		)
+		return fmt.Errorf("request cancelled: %w", ctx.Err())
+	default:
+		// Continue processing
+	}
+
+	reqLogger.Debug("Calling repository",
+		zap.Int("event_count", len(records)),
+		zap.Bool("dry_run", dryRun),
+	)
+
+	resp, err := feed.Import(s.eventsRepo, records, auth.FromContext(ctx).Subject, dryRun)
+	if err != nil {
+		reqLogger.Error("Repository call failed",
+			zap.Error(err),
+		)
+		return err
+	}
+
+	reqLogger.Info("Events imported",
+		zap.String("subject", auth.FromContext(ctx).Subject),
+		zap.Int32("created", resp.Created),
+		zap.Int32("updated", resp.Updated),
+		zap.Int("errors", len(resp.Errors)),
+		zap.Bool("committed", resp.Committed),
+	)
+
+	return stream.SendAndClose(resp)
+}
+
+func (s *sportsService) ListEventHistory(ctx context.Context, in *sports.ListEventHistoryRequest) (*sports.ListEventHistoryResponse, error) {
+	reqLogger := s.logger.With(
+		zap.String("method", "ListEventHistory"),
+		zap.Int64("event_id", in.GetEventId()),
+	)
+
+	reqLogger.Debug("Request started", zap.String("field", in.GetField()))
+
+	// Context validation
+	if ctx == nil {
+		reqLogger.Error("Context validation failed: nil context")
+		return nil, fmt.Errorf("context cannot be nil")
+	}
+
+	// Check if context is cancelled
+	select {
+	case <-ctx.Done():
+		reqLogger.Warn("Request cancelled",
+			zap.Error(ctx.Err()),
+		)
+		return nil, fmt.Errorf("request cancelled: %w", ctx.Err())
+	default:
+		// Continue processing
+	}
+
+	// Input validation
+	if in == nil {
+		reqLogger.Warn("Request validation failed: nil request")
+		return nil, fmt.Errorf("request cannot be nil")
+	}
+
+	// Validate request using proto validation
+	if err := in.Validate(); err != nil {
+		reqLogger.Warn("Request validation failed",
+			zap.Error(err),
+		)
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+
+	reqLogger.Debug("Calling repository")
+
+	// The event must exist, so that an unknown event is not reported as unchanged
+	if _, err := s.eventsRepo.GetByID(in.EventId); err != nil {
+		reqLogger.Error("Repository call failed",
+			zap.Error(err),
+		)
+		return nil, fmt.Errorf("failed to retrieve event: %w", err)
+	}
+
+	changes, err := s.eventsRepo.History(in.EventId, in.Field)
+	if err != nil {
+		reqLogger.Error("Repository call failed",
+			zap.Error(err),
+		)
+		return nil, fmt.Errorf("failed to retrieve event history: %w", err)
+	}
+
+	return &sports.ListEventHistoryResponse{Changes: changes}, nil
+}
+
+func hasSeason(competition *sports.Competition, seasonID int64) bool {
+	for _, season := range competition.Seasons {
+		if season.Id == seasonID {
+			return true
+		}
+	}
+	return false
+}
+
+// withEventSuspension reports the open markets of a suspended event as
+// suspended. The markets keep their own status, so resuming the event
+// reopens exactly the markets that were open before.
+func withEventSuspension(event *sports.Event, markets []*sports.Market) []*sports.Market {
+	if !event.Suspended {
+		return markets
+	}
+
+	for _, market := range markets {
+		if market.Status == sports.MarketStatus_MARKET_OPEN {
+			market.Status = sports.MarketStatus_MARKET_SUSPENDED
+		}
+	}
+	return markets
+}
+
+// visibleOnly returns a copy of filter restricted to visible events.
+func visibleOnly(filter *sports.ListEventsRequestFilter) *sports.ListEventsRequestFilter {
+	restricted := &sports.ListEventsRequestFilter{}
+	if filter != nil {
+		restricted = proto.Clone(filter).(*sports.ListEventsRequestFilter)
+	}
+
+	visible := true
+	restricted.VisibleOnly = &visible
+
+	return restricted
+}
+
+// SportsServer is a gRPC server wrapper that embeds the required UnimplementedSportsServer
+type SportsServer struct {
+	sports.UnimplementedSportsServer
+	Service Sports
+}
+
+// ListEvents implements the gRPC SportsServer interface
+func (s *SportsServer) ListEvents(ctx context.Context, req *sports.ListEventsRequest) (*sports.ListEventsResponse, error) {
+	return s.Service.ListEvents(ctx, req)
+}
+
+// StreamEvents implements the gRPC SportsServer interface
+func (s *SportsServer) StreamEvents(req *sports.ListEventsRequest, stream sports.Sports_StreamEventsServer) error {
+	return s.Service.StreamEvents(req, stream)
+}
+
+// GetEvent implements the gRPC SportsServer interface
+func (s *SportsServer) GetEvent(ctx context.Context, req *sports.GetEventRequest) (*sports.GetEventResponse, error) {
+	return s.Service.GetEvent(ctx, req)
+}
+
+// BatchGetEvents implements the gRPC SportsServer interface
+func (s *SportsServer) BatchGetEvents(ctx context.Context, req *sports.BatchGetEventsRequest) (*sports.BatchGetEventsResponse, error) {
+	return s.Service.BatchGetEvents(ctx, req)
+}
+
+// ListMarkets implements the gRPC SportsServer interface
+func (s *SportsServer) ListMarkets(ctx context.Context, req *sports.ListMarketsRequest) (*sports.ListMarketsResponse, error) {
+	return s.Service.ListMarkets(ctx, req)
+}
+
+// UpdateMarket implements the gRPC SportsServer interface
+func (s *SportsServer) UpdateMarket(ctx context.Context, req *sports.UpdateMarketRequest) (*sports.UpdateMarketResponse, error) {
+	return s.Service.UpdateMarket(ctx, req)
+}
+
+// SuspendEvent implements the gRPC SportsServer interface
+func (s *SportsServer) SuspendEvent(ctx context.Context, req *sports.SuspendEventRequest) (*sports.SuspendEventResponse, error) {
+	return s.Service.SuspendEvent(ctx, req)
+}
+
+// ListSports implements the gRPC SportsServer interface
+func (s *SportsServer) ListSports(ctx context.Context, req *sports.ListSportsRequest) (*sports.ListSportsResponse, error) {
+	return s.Service.ListSports(ctx, req)
+}
+
+// ListCompetitions implements the gRPC SportsServer interface
+func (s *SportsServer) ListCompetitions(ctx context.Context, req *sports.ListCompetitionsRequest) (*sports.ListCompetitionsResponse, error) {
+	return s.Service.ListCompetitions(ctx, req)
+}
+
+// ListCompetitionEvents implements the gRPC SportsServer interface
+func (s *SportsServer) ListCompetitionEvents(ctx context.Context, req *sports.ListCompetitionEventsRequest) (*sports.ListCompetitionEventsResponse, error) {
+	return s.Service.ListCompetitionEvents(ctx, req)
+}
+
+// ListTeams implements the gRPC SportsServer interface
+func (s *SportsServer) ListTeams(ctx context.Context, req *sports.ListTeamsRequest) (*sports.ListTeamsResponse, error) {
+	return s.Service.ListTeams(ctx, req)
+}
+
+// ListVenues implements the gRPC SportsServer interface
+func (s *SportsServer) ListVenues(ctx context.Context, req *sports.ListVenuesRequest) (*sports.ListVenuesResponse, error) {
+	return s.Service.ListVenues(ctx, req)
+}
+
+// ImportEvents implements the gRPC SportsServer interface
+func (s *SportsServer) ImportEvents(stream sports.Sports_ImportEventsServer) error {
+	return s.Service.ImportEvents(stream)
+}
+
+// ListEventHistory implements the gRPC SportsServer interface
+func (s *SportsServer) ListEventHistory(ctx context.Context, req *sports.ListEventHistoryRequest) (*sports.ListEventHistoryResponse, error) {
+	return s.Service.ListEventHistory(ctx, req)
 }
@@ -3,15 +3,23 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
+	"io"
+	"net"
 	"strings"
 	"testing"
+	"time"
 
 	"git.neds.sh/matty/entain/sports/db"
+	"git.neds.sh/matty/entain/sports/internal/auth"
 	"git.neds.sh/matty/entain/sports/proto/sports"
 	"github.com/google/go-cmp/cmp"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zaptest"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 // testEventsRepo is a simple mock implementation for testing
@@ -20,6 +28,9 @@ type testEventsRepo struct {
 	err        error
 	lastFilter *sports.ListEventsRequestFilter
 	initCalled bool
+	imported   []*sports.ImportEventsRequest
+	changedBy  string
+	changes    []*sports.EventChange
 }
 
 // GetByID implements the db.EventsRepo interface for testing.
@@ -32,6 +43,22 @@ func (t *testEventsRepo) GetByID(id int64) (*sports.Event, error) {
 	return nil, errors.New("event not found")
 }
 
+// GetByIDs implements the db.EventsRepo interface for testing.
+func (t *testEventsRepo) GetByIDs(ids []int64) ([]*sports.Event, error) {
+	if t.err != nil {
+		return nil, t.err
+	}
+	var events []*sports.Event
+	for _, id := range ids {
+		for _, event := range t.events {
+			if event.Id == id {
+				events = append(events, event)
+			}
+		}
+	}
+	return events, nil
+}
+
 // List implements the db.EventsRepo interface for testing.
 func (t *testEventsRepo) List(filter *sports.ListEventsRequestFilter) ([]*sports.Event, error) {
 	t.lastFilter = filter
@@ -41,16 +68,87 @@ func (t *testEventsRepo) List(filter *sports.ListEventsRequestFilter) ([]*sports
 	return t.events, nil
 }
 
+// Stream implements the db.EventsRepo interface for testing.
+func (t *testEventsRepo) Stream(filter *sports.ListEventsRequestFilter, send func(*sports.Event) error) error {
+	t.lastFilter = filter
+	if t.err != nil {
+		return t.err
+	}
+	for _, event := range t.events {
+		if err := send(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (t *testEventsRepo) Init() error {
 	t.initCalled = true
 	return t.err
 }
 
+// SetSuspended implements the db.EventsRepo interface for testing.
+func (t *testEventsRepo) SetSuspended(id int64, suspended bool, actor string) error {
+	if t.err != nil {
+		return t.err
+	}
+	for _, event := range t.events {
+		if event.Id == id {
+			event.Suspended = suspended
+			t.changedBy = actor
+			return nil
+		}
+	}
+	return fmt.Errorf("event with ID %d %w", id, db.ErrNotFound)
+}
+
+// Import implements the db.EventsRepo interface for testing, recording the
+// events unless it is a dry run.
+func (t *testEventsRepo) Import(events []*sports.ImportEventsRequest, actor string, dryRun bool) (int, int, error) {
+	if t.err != nil {
+		return 0, 0, t.err
+	}
+	if !dryRun {
+		t.imported = append(t.imported, events...)
+		t.changedBy = actor
+	}
+	return len(events), 0, nil
+}
+
+// History implements the db.EventsRepo interface for testing, returning the
+// changes to the event that set field.
+func (t *testEventsRepo) History(eventID int64, field string) ([]*sports.EventChange, error) {
+	if t.err != nil {
+		return nil, t.err
+	}
+	var changes []*sports.EventChange
+	for _, change := range t.changes {
+		if change.EventId != eventID {
+			continue
+		}
+		for _, fieldChange := range change.Fields {
+			if field == "" || fieldChange.Field == field {
+				changes = append(changes, change)
+				break
+			}
+		}
+	}
+	return changes, nil
+}
+
 // Helper function to create bool pointer
 func boolPtr(b bool) *bool {
 	return &b
 }
 
+// traderContext returns a context carrying an identity with the trader role
+func traderContext() context.Context {
+	return auth.NewContext(context.Background(), auth.Identity{
+		Subject: "trader-1",
+		Roles:   []string{auth.RoleTrader},
+	})
+}
+
 // Helper function to create test repository
 func newTestEventsRepo(events []*sports.Event, err error) db.EventsRepo {
 	return &testEventsRepo{
@@ -79,7 +177,7 @@ func TestNewSportsService(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			service := NewSportsService(tt.repo, tt.logger)
+			service := NewSportsService(tt.repo, nil, nil, nil, tt.logger)
 			if service == nil {
 				t.Error("NewSportsService() = nil, want non-nil service")
 			}
@@ -107,7 +205,7 @@ func TestSportsService_ListEvents_Success(t *testing.T) {
 
 	repo := newTestEventsRepo(testEvents, nil)
 	logger := zaptest.NewLogger(t)
-	service := NewSportsService(repo, logger)
+	service := NewSportsService(repo, nil, nil, nil, logger)
 
 	request := &sports.ListEventsRequest{
 		Filter: &sports.ListEventsRequestFilter{
@@ -131,6 +229,79 @@ func TestSportsService_ListEvents_Success(t *testing.T) {
 	}
 }
 
+func TestSportsService_ListEvents_VisibilityByRole(t *testing.T) {
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		filter     *sports.ListEventsRequestFilter
+		wantFilter *sports.ListEventsRequestFilter
+	}{
+		{
+			name:       "anonymous with nil filter only sees visible events",
+			ctx:        context.Background(),
+			filter:     nil,
+			wantFilter: &sports.ListEventsRequestFilter{VisibleOnly: boolPtr(true)},
+		},
+		{
+			name:       "anonymous cannot opt out of visible only",
+			ctx:        context.Background(),
+			filter:     &sports.ListEventsRequestFilter{SportTypes: []string{"football"}, VisibleOnly: boolPtr(false)},
+			wantFilter: &sports.ListEventsRequestFilter{SportTypes: []string{"football"}, VisibleOnly: boolPtr(true)},
+		},
+		{
+			name:       "trader sees hidden events",
+			ctx:        traderContext(),
+			filter:     &sports.ListEventsRequestFilter{VisibleOnly: boolPtr(false)},
+			wantFilter: &sports.ListEventsRequestFilter{VisibleOnly: boolPtr(false)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &testEventsRepo{}
+			service := NewSportsService(repo, nil, nil, nil, zaptest.NewLogger(t))
+
+			request := &sports.ListEventsRequest{Filter: tt.filter}
+			if _, err := service.ListEvents(tt.ctx, request); err != nil {
+				t.Fatalf("ListEvents() failed: %v", err)
+			}
+
+			if diff := cmp.Diff(tt.wantFilter, repo.lastFilter, protocmp.Transform()); diff != "" {
+				t.Errorf("ListEvents() repository filter mismatch (-want +got):\n%s", diff)
+			}
+
+			// The caller's request must not be modified
+			if diff := cmp.Diff(tt.filter, request.Filter, protocmp.Transform()); diff != "" {
+				t.Errorf("ListEvents() modified request filter (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSportsService_GetEvent_HiddenEvent(t *testing.T) {
+	hiddenEvent := &sports.Event{
+		Id:        7,
+		Name:      "Team E vs Team F",
+		SportType: "football",
+		Visible:   false,
+	}
+
+	service := NewSportsService(newTestEventsRepo([]*sports.Event{hiddenEvent}, nil), nil, nil, nil, zaptest.NewLogger(t))
+	request := &sports.GetEventRequest{Id: 7}
+
+	if _, err := service.GetEvent(context.Background(), request); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("GetEvent() anonymous error = %v, want not found", err)
+	}
+
+	response, err := service.GetEvent(traderContext(), request)
+	if err != nil {
+		t.Fatalf("GetEvent() trader error = %v, want nil", err)
+	}
+	if diff := cmp.Diff(hiddenEvent, response.Event, protocmp.Transform()); diff != "" {
+		t.Errorf("GetEvent() trader event mismatch (-want +got):\n%s", diff)
+	}
+}
+
 func TestSportsService_GetEvent_Success(t *testing.T) {
 	testEvent := &sports.Event{
 		Id:        1,
@@ -142,7 +313,7 @@ func TestSportsService_GetEvent_Success(t *testing.T) {
 
 	repo := newTestEventsRepo([]*sports.Event{testEvent}, nil)
 	logger := zaptest.NewLogger(t)
-	service := NewSportsService(repo, logger)
+	service := NewSportsService(repo, nil, nil, nil, logger)
 
 	request := &sports.GetEventRequest{Id: 1}
 
@@ -165,7 +336,7 @@ func TestSportsService_GetEvent_Success(t *testing.T) {
 func TestSportsService_GetEvent_NotFound(t *testing.T) {
 	repo := newTestEventsRepo([]*sports.Event{}, nil)
 	logger := zaptest.NewLogger(t)
-	service := NewSportsService(repo, logger)
+	service := NewSportsService(repo, nil, nil, nil, logger)
 
 	request := &sports.GetEventRequest{Id: 999}
 
@@ -189,7 +360,7 @@ func TestSportsService_GetEvent_NotFound(t *testing.T) {
 func TestSportsService_ListEvents_NilRequest(t *testing.T) {
 	repo := newTestEventsRepo(nil, nil)
 	logger := zaptest.NewLogger(t)
-	service := NewSportsService(repo, logger)
+	service := NewSportsService(repo, nil, nil, nil, logger)
 
 	response, err := service.ListEvents(context.Background(), nil)
 
@@ -211,7 +382,7 @@ func TestSportsService_ListEvents_NilRequest(t *testing.T) {
 func TestSportsService_GetEvent_InvalidID(t *testing.T) {
 	repo := newTestEventsRepo(nil, nil)
 	logger := zaptest.NewLogger(t)
-	service := NewSportsService(repo, logger)
+	service := NewSportsService(repo, nil, nil, nil, logger)
 
 	tests := []struct {
 		name string
@@ -260,7 +431,7 @@ func BenchmarkSportsService_ListEvents(b *testing.B) {
 
 	repo := newTestEventsRepo(events, nil)
 	logger := zap.NewNop() // Use no-op logger for benchmarks
-	service := NewSportsService(repo, logger)
+	service := NewSportsService(repo, nil, nil, nil, logger)
 	request := &sports.ListEventsRequest{
 		Filter: &sports.ListEventsRequestFilter{
 			VisibleOnly: boolPtr(true),
@@ -275,3 +446,774 @@ func BenchmarkSportsService_ListEvents(b *testing.B) {
 		}
 	}
 }
+
+func TestSportsService_BatchGetEvents(t *testing.T) {
+	events := []*sports.Event{
+		{Id: 1, Name: "Team A vs Team B", Visible: true},
+		{Id: 2, Name: "Team C vs Team D", Visible: false},
+		{Id: 3, Name: "Team E vs Team F", Visible: true},
+	}
+
+	tests := []struct {
+		name        string
+		ctx         context.Context
+		ids         []int64
+		repoErr     error
+		wantIDs     []int64
+		wantMissing []int64
+		wantErr     string
+	}{
+		{
+			name:    "request order is preserved",
+			ctx:     traderContext(),
+			ids:     []int64{3, 1, 2},
+			wantIDs: []int64{3, 1, 2},
+		},
+		{
+			name:        "missing ids are reported",
+			ctx:         traderContext(),
+			ids:         []int64{4, 1, 5},
+			wantIDs:     []int64{1},
+			wantMissing: []int64{4, 5},
+		},
+		{
+			name:        "hidden events are missing for anonymous callers",
+			ctx:         context.Background(),
+			ids:         []int64{2, 3},
+			wantIDs:     []int64{3},
+			wantMissing: []int64{2},
+		},
+		{
+			name:    "invalid ids",
+			ctx:     context.Background(),
+			ids:     []int64{1, 1},
+			wantErr: "duplicate event ID",
+		},
+		{
+			name:    "repository error",
+			ctx:     context.Background(),
+			ids:     []int64{1},
+			repoErr: errors.New("database is locked"),
+			wantErr: "failed to retrieve events",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewSportsService(newTestEventsRepo(events, tt.repoErr), nil, nil, nil, zaptest.NewLogger(t))
+
+			response, err := service.BatchGetEvents(tt.ctx, &sports.BatchGetEventsRequest{Ids: tt.ids})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("BatchGetEvents() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("BatchGetEvents() error = %v, want nil", err)
+			}
+
+			var gotIDs []int64
+			for _, event := range response.Events {
+				gotIDs = append(gotIDs, event.Id)
+			}
+			if diff := cmp.Diff(tt.wantIDs, gotIDs); diff != "" {
+				t.Errorf("BatchGetEvents() event IDs mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.wantMissing, response.MissingIds); diff != "" {
+				t.Errorf("BatchGetEvents() missing IDs mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// testMarketsRepo is an in-memory db.MarketsRepo keyed by event ID
+type testMarketsRepo struct {
+	markets map[int64][]*sports.Market
+	err     error
+	saved   *sports.Market
+}
+
+// Init implements the db.MarketsRepo interface for testing.
+func (t *testMarketsRepo) Init() error {
+	return t.err
+}
+
+// List implements the db.MarketsRepo interface for testing.
+func (t *testMarketsRepo) List(eventID int64) ([]*sports.Market, error) {
+	if t.err != nil {
+		return nil, t.err
+	}
+	markets := []*sports.Market{}
+	for _, market := range t.markets[eventID] {
+		markets = append(markets, proto.Clone(market).(*sports.Market))
+	}
+	return markets, nil
+}
+
+// Save implements the db.MarketsRepo interface for testing.
+func (t *testMarketsRepo) Save(market *sports.Market) (*sports.Market, error) {
+	if t.err != nil {
+		return nil, t.err
+	}
+	t.saved = proto.Clone(market).(*sports.Market)
+	if t.saved.Id == 0 {
+		t.saved.Id = 100
+	}
+	return proto.Clone(t.saved).(*sports.Market), nil
+}
+
+// newTestMarketsRepo returns an open and a settled market on event 1, and an
+// open market on hidden event 2
+func newTestMarketsRepo() *testMarketsRepo {
+	return &testMarketsRepo{markets: map[int64][]*sports.Market{
+		1: {
+			{Id: 1, EventId: 1, Selections: []*sports.Selection{{Id: 1, Name: "Team A", Price: 1.8}, {Id: 2, Name: "Team B", Price: 2.05}}},
+			{Id: 2, EventId: 1, Status: sports.MarketStatus_MARKET_SETTLED, Selections: []*sports.Selection{{Id: 3, Name: "Yes", Price: 1.5}, {Id: 4, Name: "No", Price: 2.6}}},
+		},
+		2: {
+			{Id: 3, EventId: 2, Selections: []*sports.Selection{{Id: 5, Name: "Team C", Price: 1.9}, {Id: 6, Name: "Team D", Price: 1.9}}},
+		},
+	}}
+}
+
+func TestSportsService_ListMarkets(t *testing.T) {
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		eventID    int64
+		suspended  bool
+		wantErr    string
+		wantStatus []sports.MarketStatus
+	}{
+		{
+			name:       "markets of an event",
+			ctx:        context.Background(),
+			eventID:    1,
+			wantStatus: []sports.MarketStatus{sports.MarketStatus_MARKET_OPEN, sports.MarketStatus_MARKET_SETTLED},
+		},
+		{
+			name:       "suspended event suspends its open markets",
+			ctx:        context.Background(),
+			eventID:    1,
+			suspended:  true,
+			wantStatus: []sports.MarketStatus{sports.MarketStatus_MARKET_SUSPENDED, sports.MarketStatus_MARKET_SETTLED},
+		},
+		{
+			name:    "hidden event",
+			ctx:     context.Background(),
+			eventID: 2,
+			wantErr: "not found",
+		},
+		{
+			name:       "hidden event for traders",
+			ctx:        traderContext(),
+			eventID:    2,
+			wantStatus: []sports.MarketStatus{sports.MarketStatus_MARKET_OPEN},
+		},
+		{
+			name:    "invalid event ID",
+			ctx:     context.Background(),
+			wantErr: "must be positive",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := []*sports.Event{
+				{Id: 1, Name: "Team A vs Team B", Visible: true, Suspended: tt.suspended},
+				{Id: 2, Name: "Team C vs Team D", Visible: false},
+			}
+			service := NewSportsService(newTestEventsRepo(events, nil), newTestMarketsRepo(), nil, nil, zaptest.NewLogger(t))
+
+			response, err := service.ListMarkets(tt.ctx, &sports.ListMarketsRequest{EventId: tt.eventID})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ListMarkets() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ListMarkets() error = %v, want nil", err)
+			}
+
+			var got []sports.MarketStatus
+			for _, market := range response.Markets {
+				got = append(got, market.Status)
+			}
+			if diff := cmp.Diff(tt.wantStatus, got); diff != "" {
+				t.Errorf("ListMarkets() statuses mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSportsService_GetEvent_IncludeMarkets(t *testing.T) {
+	events := []*sports.Event{{Id: 1, Name: "Team A vs Team B", Visible: true, Suspended: true}}
+	markets := newTestMarketsRepo()
+	service := NewSportsService(newTestEventsRepo(events, nil), markets, nil, nil, zaptest.NewLogger(t))
+
+	response, err := service.GetEvent(context.Background(), &sports.GetEventRequest{Id: 1})
+	if err != nil {
+		t.Fatalf("GetEvent() error = %v, want nil", err)
+	}
+	if len(response.Markets) != 0 {
+		t.Errorf("GetEvent() returned %d markets without include_markets, want 0", len(response.Markets))
+	}
+
+	response, err = service.GetEvent(context.Background(), &sports.GetEventRequest{Id: 1, IncludeMarkets: true})
+	if err != nil {
+		t.Fatalf("GetEvent() error = %v, want nil", err)
+	}
+	want, _ := markets.List(1)
+	want[0].Status = sports.MarketStatus_MARKET_SUSPENDED
+	if diff := cmp.Diff(want, response.Markets, protocmp.Transform()); diff != "" {
+		t.Errorf("GetEvent() markets mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSportsService_UpdateMarket(t *testing.T) {
+	selections := []*sports.Selection{{Name: "Team A", Price: 1.8}, {Name: "Team B", Price: 2.05}}
+
+	tests := []struct {
+		name      string
+		req       *sports.UpdateMarketRequest
+		repoErr   error
+		wantErr   string
+		wantField string
+		want      *sports.Market
+	}{
+		{
+			name: "market is created on the event",
+			req:  &sports.UpdateMarketRequest{EventId: 2, Market: &sports.Market{Selections: selections}},
+			want: &sports.Market{Id: 100, EventId: 2, Selections: selections},
+		},
+		{
+			name: "open market of a suspended event is reported suspended",
+			req:  &sports.UpdateMarketRequest{EventId: 3, Market: &sports.Market{Id: 4, Selections: selections}},
+			want: &sports.Market{Id: 4, EventId: 3, Status: sports.MarketStatus_MARKET_SUSPENDED, Selections: selections},
+		},
+		{
+			name:      "invalid market",
+			req:       &sports.UpdateMarketRequest{EventId: 2, Market: &sports.Market{Selections: selections[:1]}},
+			wantErr:   "need 2 to 3 selections",
+			wantField: "market.selections",
+		},
+		{
+			name:    "unknown event",
+			req:     &sports.UpdateMarketRequest{EventId: 9, Market: &sports.Market{Selections: selections}},
+			wantErr: "failed to retrieve event",
+		},
+		{
+			name:    "settled market",
+			req:     &sports.UpdateMarketRequest{EventId: 2, Market: &sports.Market{Id: 2, Selections: selections}},
+			repoErr: fmt.Errorf("market with ID 2 %w", db.ErrSettled),
+			wantErr: "is settled",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := []*sports.Event{
+				{Id: 2, Name: "Team A vs Team B", Visible: false},
+				{Id: 3, Name: "Team A vs Team B", Visible: true, Suspended: true},
+			}
+			markets := &testMarketsRepo{err: tt.repoErr}
+			service := NewSportsService(newTestEventsRepo(events, nil), markets, nil, nil, zaptest.NewLogger(t))
+
+			response, err := service.UpdateMarket(traderContext(), tt.req)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("UpdateMarket() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				var fieldErr *sports.FieldError
+				if tt.wantField != "" && (!errors.As(err, &fieldErr) || fieldErr.Field != tt.wantField) {
+					t.Errorf("UpdateMarket() error = %v, want a FieldError for %q", err, tt.wantField)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("UpdateMarket() error = %v, want nil", err)
+			}
+
+			if diff := cmp.Diff(tt.want, response.Market, protocmp.Transform()); diff != "" {
+				t.Errorf("UpdateMarket() market mismatch (-want +got):\n%s", diff)
+			}
+			if markets.saved.Status != tt.req.Market.Status {
+				t.Errorf("UpdateMarket() stored status %v, want %v", markets.saved.Status, tt.req.Market.Status)
+			}
+		})
+	}
+}
+
+func TestSportsService_SuspendEvent(t *testing.T) {
+	events := []*sports.Event{{Id: 1, Name: "Team A vs Team B", Visible: true}}
+	repo := &testEventsRepo{events: events}
+	service := NewSportsService(repo, newTestMarketsRepo(), nil, nil, zaptest.NewLogger(t))
+
+	response, err := service.SuspendEvent(traderContext(), &sports.SuspendEventRequest{Id: 1, Suspended: true})
+	if err != nil {
+		t.Fatalf("SuspendEvent() error = %v, want nil", err)
+	}
+	if !response.Event.Suspended {
+		t.Error("SuspendEvent() returned an event that is not suspended")
+	}
+	if repo.changedBy != "trader-1" {
+		t.Errorf("SuspendEvent() recorded the change as made by %q, want the caller", repo.changedBy)
+	}
+
+	markets, err := service.ListMarkets(context.Background(), &sports.ListMarketsRequest{EventId: 1})
+	if err != nil {
+		t.Fatalf("ListMarkets() error = %v, want nil", err)
+	}
+	if status := markets.Markets[0].Status; status != sports.MarketStatus_MARKET_SUSPENDED {
+		t.Errorf("market status after suspending the event = %v, want MARKET_SUSPENDED", status)
+	}
+
+	response, err = service.SuspendEvent(traderContext(), &sports.SuspendEventRequest{Id: 1})
+	if err != nil {
+		t.Fatalf("SuspendEvent() error = %v, want nil", err)
+	}
+	if response.Event.Suspended {
+		t.Error("SuspendEvent() returned an event that is still suspended")
+	}
+
+	if _, err := service.SuspendEvent(traderContext(), &sports.SuspendEventRequest{Id: 9, Suspended: true}); !errors.Is(err, db.ErrNotFound) {
+		t.Errorf("SuspendEvent() of an unknown event error = %v, want %v", err, db.ErrNotFound)
+	}
+}
+
+// testHierarchyRepo is a db.HierarchyRepo serving football, with one
+// competition of two seasons and two teams
+type testHierarchyRepo struct{}
+
+// Init implements the db.HierarchyRepo interface for testing.
+func (testHierarchyRepo) Init() error { return nil }
+
+// Backfill implements the db.HierarchyRepo interface for testing.
+func (testHierarchyRepo) Backfill() (int, error) { return 0, nil }
+
+// ListSports implements the db.HierarchyRepo interface for testing.
+func (testHierarchyRepo) ListSports() ([]*sports.Sport, error) {
+	return []*sports.Sport{{Id: 1, Name: "football"}}, nil
+}
+
+// ListCompetitions implements the db.HierarchyRepo interface for testing.
+func (r testHierarchyRepo) ListCompetitions(sportID int64) ([]*sports.Competition, error) {
+	if sportID != 1 {
+		return nil, fmt.Errorf("sport with ID %d %w", sportID, db.ErrNotFound)
+	}
+	competition, _ := r.GetCompetition(1)
+	return []*sports.Competition{competition}, nil
+}
+
+// GetCompetition implements the db.HierarchyRepo interface for testing.
+func (testHierarchyRepo) GetCompetition(id int64) (*sports.Competition, error) {
+	if id != 1 {
+		return nil, fmt.Errorf("competition with ID %d %w", id, db.ErrNotFound)
+	}
+	return &sports.Competition{
+		Id:      1,
+		SportId: 1,
+		Name:    "National Football League",
+		Seasons: []*sports.Season{{Id: 1, CompetitionId: 1, Name: "2026"}, {Id: 2, CompetitionId: 1, Name: "2027"}},
+	}, nil
+}
+
+// ListTeams implements the db.HierarchyRepo interface for testing.
+func (testHierarchyRepo) ListTeams(sportID int64) ([]*sports.Team, error) {
+	if sportID != 1 {
+		return nil, fmt.Errorf("sport with ID %d %w", sportID, db.ErrNotFound)
+	}
+	return []*sports.Team{{Id: 1, SportId: 1, Name: "Team A"}, {Id: 2, SportId: 1, Name: "Team B"}}, nil
+}
+
+func TestSportsService_ListCompetitionEvents(t *testing.T) {
+	seasonID := func(id int64) *int64 { return &id }
+
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		req        *sports.ListCompetitionEventsRequest
+		wantErr    error
+		wantFilter *sports.ListEventsRequestFilter
+	}{
+		{
+			name:       "competition",
+			ctx:        context.Background(),
+			req:        &sports.ListCompetitionEventsRequest{CompetitionId: 1},
+			wantFilter: &sports.ListEventsRequestFilter{CompetitionIds: []int64{1}, VisibleOnly: boolPtr(true)},
+		},
+		{
+			name:       "season for traders",
+			ctx:        traderContext(),
+			req:        &sports.ListCompetitionEventsRequest{CompetitionId: 1, SeasonId: seasonID(2)},
+			wantFilter: &sports.ListEventsRequestFilter{CompetitionIds: []int64{1}, SeasonIds: []int64{2}},
+		},
+		{
+			name:    "season of another competition",
+			ctx:     context.Background(),
+			req:     &sports.ListCompetitionEventsRequest{CompetitionId: 1, SeasonId: seasonID(3)},
+			wantErr: db.ErrNotFound,
+		},
+		{
+			name:    "unknown competition",
+			ctx:     context.Background(),
+			req:     &sports.ListCompetitionEventsRequest{CompetitionId: 2},
+			wantErr: db.ErrNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &testEventsRepo{events: []*sports.Event{{Id: 1, CompetitionId: 1, Visible: true}}}
+			service := NewSportsService(repo, nil, testHierarchyRepo{}, nil, zaptest.NewLogger(t))
+
+			response, err := service.ListCompetitionEvents(tt.ctx, tt.req)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("ListCompetitionEvents() error = %v, want %v", err, tt.wantErr)
+				}
+				if repo.lastFilter != nil {
+					t.Errorf("ListCompetitionEvents() listed events with %v after an error", repo.lastFilter)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ListCompetitionEvents() error = %v, want nil", err)
+			}
+
+			if diff := cmp.Diff(tt.wantFilter, repo.lastFilter, protocmp.Transform()); diff != "" {
+				t.Errorf("ListCompetitionEvents() filter mismatch (-want +got):\n%s", diff)
+			}
+			if len(response.Events) != 1 {
+				t.Errorf("ListCompetitionEvents() returned %d events, want 1", len(response.Events))
+			}
+		})
+	}
+}
+
+func TestSportsService_Hierarchy(t *testing.T) {
+	service := NewSportsService(newTestEventsRepo(nil, nil), nil, testHierarchyRepo{}, nil, zaptest.NewLogger(t))
+	ctx := context.Background()
+
+	sportsResp, err := service.ListSports(ctx, &sports.ListSportsRequest{})
+	if err != nil || len(sportsResp.Sports) != 1 {
+		t.Fatalf("ListSports() = %v, %v; want one sport", sportsResp, err)
+	}
+
+	competitions, err := service.ListCompetitions(ctx, &sports.ListCompetitionsRequest{SportId: 1})
+	if err != nil || len(competitions.Competitions) != 1 || len(competitions.Competitions[0].Seasons) != 2 {
+		t.Fatalf("ListCompetitions() = %v, %v; want one competition with two seasons", competitions, err)
+	}
+
+	teams, err := service.ListTeams(ctx, &sports.ListTeamsRequest{SportId: 1})
+	if err != nil || len(teams.Teams) != 2 {
+		t.Fatalf("ListTeams() = %v, %v; want two teams", teams, err)
+	}
+
+	if _, err := service.ListCompetitions(ctx, &sports.ListCompetitionsRequest{SportId: 2}); !errors.Is(err, db.ErrNotFound) {
+		t.Errorf("ListCompetitions() of an unknown sport error = %v, want %v", err, db.ErrNotFound)
+	}
+	if _, err := service.ListTeams(ctx, &sports.ListTeamsRequest{}); err == nil || !strings.Contains(err.Error(), "must be positive") {
+		t.Errorf("ListTeams() without a sport error = %v, want a validation error", err)
+	}
+}
+
+// testVenuesRepo is a db.VenuesRepo serving a single venue, or failing with err
+type testVenuesRepo struct {
+	err error
+}
+
+// Init implements the db.VenuesRepo interface for testing.
+func (testVenuesRepo) Init() error { return nil }
+
+// List implements the db.VenuesRepo interface for testing.
+func (r testVenuesRepo) List() ([]*sports.Venue, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	return []*sports.Venue{{Id: 1, Name: "Stadium A", TimeZone: "Australia/Melbourne", Country: "AU"}}, nil
+}
+
+func TestSportsService_ListVenues(t *testing.T) {
+	service := NewSportsService(newTestEventsRepo(nil, nil), nil, nil, testVenuesRepo{}, zaptest.NewLogger(t))
+
+	response, err := service.ListVenues(context.Background(), &sports.ListVenuesRequest{})
+	if err != nil {
+		t.Fatalf("ListVenues() error = %v, want nil", err)
+	}
+	want := []*sports.Venue{{Id: 1, Name: "Stadium A", TimeZone: "Australia/Melbourne", Country: "AU"}}
+	if diff := cmp.Diff(want, response.Venues, protocmp.Transform()); diff != "" {
+		t.Errorf("ListVenues() mismatch (-want +got):\n%s", diff)
+	}
+
+	service = NewSportsService(newTestEventsRepo(nil, nil), nil, nil, testVenuesRepo{err: errors.New("database is locked")}, zaptest.NewLogger(t))
+	if _, err := service.ListVenues(context.Background(), &sports.ListVenuesRequest{}); err == nil || !strings.Contains(err.Error(), "failed to retrieve venues") {
+		t.Errorf("ListVenues() error = %v, want a repository error", err)
+	}
+}
+
+// testImportStream is a sports.Sports_ImportEventsServer receiving events and
+// keeping the response.
+type testImportStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	events []*sports.ImportEventsRequest
+	resp   *sports.ImportEventsResponse
+}
+
+func (s *testImportStream) Context() context.Context { return s.ctx }
+
+func (s *testImportStream) Recv() (*sports.ImportEventsRequest, error) {
+	if len(s.events) == 0 {
+		return nil, io.EOF
+	}
+	event := s.events[0]
+	s.events = s.events[1:]
+	return event, nil
+}
+
+func (s *testImportStream) SendAndClose(resp *sports.ImportEventsResponse) error {
+	s.resp = resp
+	return nil
+}
+
+func TestSportsService_ImportEvents(t *testing.T) {
+	start := timestamppb.New(time.Date(2026, 11, 3, 8, 0, 0, 0, time.UTC))
+	valid := func(sourceID string) *sports.ImportEventsRequest {
+		return &sports.ImportEventsRequest{SourceId: sourceID, Name: "Lions vs Tigers", SportType: "football", AdvertisedStartTime: start}
+	}
+	dryRun := valid("feed-1")
+	dryRun.DryRun = true
+	invalid := valid("feed-2")
+	invalid.SportType = ""
+
+	tests := []struct {
+		name         string
+		events       []*sports.ImportEventsRequest
+		want         *sports.ImportEventsResponse
+		wantImported int
+	}{
+		{
+			name:         "valid events are saved",
+			events:       []*sports.ImportEventsRequest{valid("feed-1"), valid("feed-2")},
+			want:         &sports.ImportEventsResponse{Created: 2, Committed: true},
+			wantImported: 2,
+		},
+		{
+			name:   "dry run of the first event applies to all",
+			events: []*sports.ImportEventsRequest{dryRun, valid("feed-2")},
+			want:   &sports.ImportEventsResponse{Created: 2},
+		},
+		{
+			name:   "invalid events are reported and nothing is saved",
+			events: []*sports.ImportEventsRequest{valid("feed-1"), invalid, valid("feed-1")},
+			want: &sports.ImportEventsResponse{Created: 1, Errors: []*sports.ImportError{
+				{Row: 2, SourceId: "feed-2", Field: "sport_type", Message: "sport type is required"},
+				{Row: 3, SourceId: "feed-1", Field: "source_id", Message: "duplicate source ID, first seen on row 1"},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &testEventsRepo{}
+			service := NewSportsService(repo, nil, testHierarchyRepo{}, nil, zaptest.NewLogger(t))
+
+			stream := &testImportStream{ctx: traderContext(), events: tt.events}
+			if err := service.ImportEvents(stream); err != nil {
+				t.Fatalf("ImportEvents() error = %v, want nil", err)
+			}
+
+			if diff := cmp.Diff(tt.want, stream.resp, protocmp.Transform()); diff != "" {
+				t.Errorf("ImportEvents() mismatch (-want +got):\n%s", diff)
+			}
+			if len(repo.imported) != tt.wantImported {
+				t.Errorf("ImportEvents() saved %d events, want %d", len(repo.imported), tt.wantImported)
+			}
+			if tt.wantImported > 0 && repo.changedBy != "trader-1" {
+				t.Errorf("ImportEvents() recorded the events as imported by %q, want the caller", repo.changedBy)
+			}
+		})
+	}
+}
+
+// testEventsStream is a sports.Sports_StreamEventsServer keeping the events
+// sent, and failing once it has sent failAfter of them if that is set.
+type testEventsStream struct {
+	grpc.ServerStream
+	ctx       context.Context
+	events    []*sports.Event
+	failAfter int
+}
+
+func (s *testEventsStream) Context() context.Context { return s.ctx }
+
+func (s *testEventsStream) Send(event *sports.Event) error {
+	if s.failAfter > 0 && len(s.events) == s.failAfter {
+		return errors.New("client went away")
+	}
+	s.events = append(s.events, event)
+	return nil
+}
+
+func TestSportsService_StreamEvents(t *testing.T) {
+	events := []*sports.Event{{Id: 1, Visible: true}, {Id: 2, Visible: true}, {Id: 3, Visible: true}}
+
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		req        *sports.ListEventsRequest
+		repoErr    error
+		failAfter  int
+		wantEvents int
+		wantFilter *sports.ListEventsRequestFilter
+		wantErr    string
+	}{
+		{
+			name:       "anonymous callers only get visible events",
+			ctx:        context.Background(),
+			req:        &sports.ListEventsRequest{Filter: &sports.ListEventsRequestFilter{SportTypes: []string{"tennis"}}},
+			wantEvents: 3,
+			wantFilter: &sports.ListEventsRequestFilter{SportTypes: []string{"tennis"}, VisibleOnly: boolPtr(true)},
+		},
+		{
+			name:       "traders get the filter as sent",
+			ctx:        traderContext(),
+			req:        &sports.ListEventsRequest{},
+			wantEvents: 3,
+			wantFilter: nil,
+		},
+		{
+			name:    "invalid filter",
+			ctx:     context.Background(),
+			req:     &sports.ListEventsRequest{Filter: &sports.ListEventsRequestFilter{SportTypes: []string{""}}},
+			wantErr: "invalid request",
+		},
+		{
+			name:       "repository error",
+			ctx:        context.Background(),
+			req:        &sports.ListEventsRequest{},
+			repoErr:    errors.New("database is locked"),
+			wantFilter: &sports.ListEventsRequestFilter{VisibleOnly: boolPtr(true)},
+			wantErr:    "failed to stream events: database is locked",
+		},
+		{
+			name:       "failed send stops the stream",
+			ctx:        context.Background(),
+			req:        &sports.ListEventsRequest{},
+			failAfter:  2,
+			wantEvents: 2,
+			wantFilter: &sports.ListEventsRequestFilter{VisibleOnly: boolPtr(true)},
+			wantErr:    "client went away",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &testEventsRepo{events: events, err: tt.repoErr}
+			service := NewSportsService(repo, nil, nil, nil, zaptest.NewLogger(t))
+
+			stream := &testEventsStream{ctx: tt.ctx, failAfter: tt.failAfter}
+			err := service.StreamEvents(tt.req, stream)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("StreamEvents() error = %v, want nil", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("StreamEvents() error = %v, want one containing %q", err, tt.wantErr)
+			}
+
+			if len(stream.events) != tt.wantEvents {
+				t.Errorf("StreamEvents() sent %d events, want %d", len(stream.events), tt.wantEvents)
+			}
+			if diff := cmp.Diff(tt.wantFilter, repo.lastFilter, protocmp.Transform()); diff != "" {
+				t.Errorf("StreamEvents() repository filter mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// TestSportsServer_StreamEvents streams through the wrapper registered by
+// main, so that an RPC missing from it shows up as Unimplemented.
+func TestSportsServer_StreamEvents(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	repo := &testEventsRepo{events: []*sports.Event{{Id: 1, Visible: true}, {Id: 2, Visible: true}}}
+	server := grpc.NewServer()
+	sports.RegisterSportsServer(server, &SportsServer{
+		Service: NewSportsService(repo, nil, nil, nil, zaptest.NewLogger(t)),
+	})
+	go server.Serve(lis)
+	defer server.Stop()
+
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer conn.Close()
+
+	stream, err := sports.NewSportsClient(conn).StreamEvents(context.Background(), &sports.ListEventsRequest{})
+	if err != nil {
+		t.Fatalf("StreamEvents() failed: %v", err)
+	}
+	var got []int64
+	for {
+		event, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Recv() failed: %v", err)
+		}
+		got = append(got, event.Id)
+	}
+	if diff := cmp.Diff([]int64{1, 2}, got); diff != "" {
+		t.Errorf("StreamEvents() events mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSportsService_ListEventHistory(t *testing.T) {
+	suspended := &sports.EventChange{Id: 2, EventId: 1, Actor: "trader-1", Type: sports.ChangeType_CHANGE_UPDATED, Fields: []*sports.FieldChange{
+		{Field: "suspended", Before: "false", After: "true"},
+	}}
+	renamed := &sports.EventChange{Id: 3, EventId: 1, Actor: "trader-2", Type: sports.ChangeType_CHANGE_UPDATED, Fields: []*sports.FieldChange{
+		{Field: "name", Before: "Lions v Tigers", After: "Lions vs Tigers"},
+	}}
+
+	tests := []struct {
+		name    string
+		req     *sports.ListEventHistoryRequest
+		want    []*sports.EventChange
+		wantErr string
+	}{
+		{name: "every change", req: &sports.ListEventHistoryRequest{EventId: 1}, want: []*sports.EventChange{suspended, renamed}},
+		{name: "suspensions", req: &sports.ListEventHistoryRequest{EventId: 1, Field: "suspended"}, want: []*sports.EventChange{suspended}},
+		{name: "unknown event", req: &sports.ListEventHistoryRequest{EventId: 9}, wantErr: "event not found"},
+		{name: "invalid field", req: &sports.ListEventHistoryRequest{EventId: 1, Field: "status"}, wantErr: "invalid field"},
+		{name: "invalid event id", req: &sports.ListEventHistoryRequest{}, wantErr: "invalid event ID"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &testEventsRepo{events: []*sports.Event{{Id: 1}}, changes: []*sports.EventChange{suspended, renamed}}
+			service := NewSportsService(repo, nil, nil, nil, zaptest.NewLogger(t))
+
+			response, err := service.ListEventHistory(traderContext(), tt.req)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("ListEventHistory() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ListEventHistory() error = %v, want nil", err)
+			}
+			if diff := cmp.Diff(tt.want, response.Changes, protocmp.Transform()); diff != "" {
+				t.Errorf("ListEventHistory() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}